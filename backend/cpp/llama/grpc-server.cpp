@@ -34,6 +34,12 @@
 #include <grpcpp/health_check_service_interface.h>
 #include <atomic>
 #include <signal.h>
+#include <sstream>
+#ifdef __linux__
+#include <sched.h>
+#include <dirent.h>
+#include <unistd.h>
+#endif
 
 using grpc::Server;
 using grpc::ServerBuilder;
@@ -183,6 +189,11 @@ struct llama_client_slot
     int32_t num_prompt_tokens           = 0;
     int32_t num_prompt_tokens_processed = 0;
 
+    // position in the batch of the first prompt token evaluated, and the tokens cached before it,
+    // to report the progress of the prompt evaluation
+    int32_t i_batch_prompt = -1;
+    int32_t n_past_prompt  = 0;
+
     json prompt;
     std::string generated_text;
     llama_token sampled;
@@ -237,6 +248,8 @@ struct llama_client_slot
         stopped_limit          = false;
         stopping_word          = "";
         n_past                 = 0;
+        i_batch_prompt         = -1;
+        n_past_prompt          = 0;
         sent_count             = 0;
         sent_token_probs_index = 0;
         infill                 = false;
@@ -645,6 +658,7 @@ struct llama_server_context
         slot->sparams.grammar           = json_value(data, "grammar",           default_sparams.grammar);
         slot->sparams.n_probs           = json_value(data, "n_probs",           default_sparams.n_probs);
         slot->sparams.min_keep          = json_value(data, "min_keep",          default_sparams.min_keep);
+        slot->params.return_progress    = json_value(data, "return_progress",   false);
 
         if (slot->n_predict > 0 && slot->params.n_predict > slot->n_predict) {
             // Might be better to reject the request with a 400 ?
@@ -1228,6 +1242,28 @@ struct llama_server_context
         queue_results.send(res);
     }
 
+    void send_progress(llama_client_slot &slot, int32_t n_past)
+    {
+        task_result res;
+        res.id = slot.task_id;
+        res.multitask_id = slot.multitask_id;
+        res.error = false;
+        res.stop = false;
+
+        res.result_json = json
+        {
+            {"content",         ""},
+            {"stop",            false},
+            {"slot_id",         slot.id},
+            {"prompt_progress", {
+                {"n_past",   n_past},
+                {"n_prompt", slot.num_prompt_tokens},
+            }},
+        };
+
+        queue_results.send(res);
+    }
+
     void send_final_response(llama_client_slot &slot)
     {
         task_result res;
@@ -1302,7 +1338,12 @@ struct llama_server_context
         }
         else
         {
-            const float *data = llama_get_embeddings(ctx);
+            // the embedding pooled over the tokens of the slot, or the one of the last token without pooling
+            const float *data = llama_get_embeddings_seq(ctx, slot.id);
+            if (data == nullptr)
+            {
+                data = llama_get_embeddings(ctx);
+            }
             std::vector<float> embedding(data, data + n_embd);
             res.result_json = json
             {
@@ -1804,6 +1845,9 @@ struct llama_server_context
 
                     int32_t slot_npast = slot.n_past_se > 0 ? slot.n_past_se : slot.n_past;
 
+                    slot.i_batch_prompt = batch.n_tokens;
+                    slot.n_past_prompt  = slot.n_past;
+
                     int32_t ga_i = slot.ga_i;
                     int32_t ga_n = slot.ga_n;
                     int32_t ga_w = slot.ga_w;
@@ -1917,6 +1961,21 @@ struct llama_server_context
                 continue;
             }
 
+            // report the progress of the prompts evaluated in chunks, the last chunk included
+            for (auto & slot : slots)
+            {
+                if (!slot.params.return_progress || slot.embedding || slot.n_decoded > 0 || slot.i_batch_prompt < 0)
+                {
+                    continue;
+                }
+                if (slot.i_batch_prompt >= (int) (i + n_tokens) || slot.i_batch < (int) i)
+                {
+                    continue;
+                }
+                const int32_t n_evaluated = std::min(slot.i_batch + 1, (int32_t) (i + n_tokens)) - slot.i_batch_prompt;
+                send_progress(slot, std::min(slot.n_past_prompt + n_evaluated, slot.num_prompt_tokens));
+            }
+
             for (auto & slot : slots)
             {
                 if (slot.i_batch < (int) i || slot.i_batch >= (int) (i + n_tokens))
@@ -2034,6 +2093,11 @@ inline void signal_handler(int signal) { shutdown_handler(signal); }
 /////////////////////////////////
 ////////////////////////////////
 
+// slot_cache_file is the file of the KV cache of the slot, in the directory of the cache of the model
+static std::string slot_cache_file(const std::string & dir, int slot_id) {
+    return dir + "/slot-" + std::to_string(slot_id) + ".bin";
+}
+
 bool loaded_model; // TODO: add a mutex for this, but happens only once loading the model
 
 // The class has a llama instance that is shared across all RPCs
@@ -2109,6 +2173,11 @@ json parse_options(bool streaming, const backend::PredictOptions* predict, llama
     data["seed"] = predict->seed();
     data["grammar"] = predict->grammar();
     data["prompt"] = predict->prompt();
+    // fill-in-the-middle: the prompt is the text before the completion
+    if (!predict->suffix().empty()) {
+        data["input_prefix"] = predict->prompt();
+        data["input_suffix"] = predict->suffix();
+    }
     data["ignore_eos"] = predict->ignoreeos();
     data["embeddings"] = predict->embeddings();
 
@@ -2123,6 +2192,11 @@ json parse_options(bool streaming, const backend::PredictOptions* predict, llama
     }
 
     data["stop"] = predict->stopprompts();
+    // the slot keeping the KV cache of the conversation, if it is available
+    if (predict->slot() > 0) {
+        data["slot_id"] = predict->slot() - 1;
+    }
+    data["return_progress"] = predict->promptprogress();
     // data["n_probs"] = predict->nprobs();
     //TODO: images,
 
@@ -2195,6 +2269,42 @@ json parse_options(bool streaming, const backend::PredictOptions* predict, llama
 //     }
 // }
 
+// pin_threads pins the threads of the backend to the CPUs of the list, as "0-15,32-47". The threads
+// started afterwards inherit the affinity of the thread starting them
+static bool pin_threads(const std::string & cpulist) {
+#ifdef __linux__
+    cpu_set_t set;
+    CPU_ZERO(&set);
+    std::stringstream ss(cpulist);
+    std::string range;
+    while (std::getline(ss, range, ',')) {
+        size_t dash = range.find('-');
+        int first = std::stoi(range.substr(0, dash));
+        int last = dash == std::string::npos ? first : std::stoi(range.substr(dash + 1));
+        for (int cpu = first; cpu <= last; cpu++) {
+            CPU_SET(cpu, &set);
+        }
+    }
+    DIR * tasks = opendir("/proc/self/task");
+    if (tasks == NULL) {
+        return false;
+    }
+    bool pinned = true;
+    while (struct dirent * task = readdir(tasks)) {
+        if (task->d_name[0] == '.') {
+            continue;
+        }
+        if (sched_setaffinity(atoi(task->d_name), sizeof(set), &set) != 0) {
+            pinned = false;
+        }
+    }
+    closedir(tasks);
+    return pinned;
+#else
+    return false;
+#endif
+}
+
 static void params_parse(const backend::ModelOptions* request,
                                 gpt_params & params) {
    
@@ -2211,6 +2321,16 @@ static void params_parse(const backend::ModelOptions* request,
     params.n_ctx = request->contextsize();
     //params.memory_f16 = request->f16memory();
     params.n_threads = request->threads();
+    if (request->threadsbatch() != 0) {
+        params.n_threads_batch = request->threadsbatch();
+    }
+    if (request->numastrategy() == "distribute" || (request->numastrategy().empty() && request->numa())) {
+        params.numa = GGML_NUMA_STRATEGY_DISTRIBUTE;
+    } else if (request->numastrategy() == "isolate") {
+        params.numa = GGML_NUMA_STRATEGY_ISOLATE;
+    } else if (request->numastrategy() == "numactl") {
+        params.numa = GGML_NUMA_STRATEGY_NUMACTL;
+    }
     params.n_gpu_layers = request->ngpulayers();
     params.n_batch = request->nbatch();
     // Set params.n_parallel by environment variable (LLAMA_PARALLEL), defaults to 1
@@ -2268,6 +2388,15 @@ static void params_parse(const backend::ModelOptions* request,
     params.no_kv_offload = request->nokvoffload();
 
     params.embedding = request->embeddings();
+    if (request->pooling() == "mean") {
+        params.pooling_type = LLAMA_POOLING_TYPE_MEAN;
+    } else if (request->pooling() == "cls") {
+        params.pooling_type = LLAMA_POOLING_TYPE_CLS;
+    } else if (request->pooling() == "last") {
+        params.pooling_type = LLAMA_POOLING_TYPE_LAST;
+    } else if (request->pooling() == "none") {
+        params.pooling_type = LLAMA_POOLING_TYPE_NONE;
+    }
 
     if (request->ropescaling() == "none")   { params.rope_scaling_type = LLAMA_ROPE_SCALING_TYPE_NONE; }
     else if (request->ropescaling() == "yarn")   { params.rope_scaling_type = LLAMA_ROPE_SCALING_TYPE_YARN; }
@@ -2293,6 +2422,15 @@ static void params_parse(const backend::ModelOptions* request,
 }
 
 
+// set_threads runs the next decodes with the threads of the request, as tuned by LocalAI for the load
+static void set_threads(const backend::PredictOptions* request) {
+    if (request->threads() <= 0 || llama.ctx == nullptr) {
+        return;
+    }
+    int32_t threads_batch = llama.params.n_threads_batch > 0 ? llama.params.n_threads_batch : request->threads();
+    llama_set_n_threads(llama.ctx, request->threads(), threads_batch);
+}
+
 // GRPC Server start
 class BackendServiceImpl final : public backend::Backend::Service {
 public:
@@ -2307,6 +2445,11 @@ public:
     gpt_params params;
     params_parse(request, params);
 
+    // Pinned before the threads of the model are started, so that they run on the CPUs too
+    if (!request->cpuaffinity().empty() && !pin_threads(request->cpuaffinity())) {
+        LOG_WARNING("failed to pin the threads to the CPUs", {{"cpus", request->cpuaffinity()}});
+    }
+
     llama_backend_init();
     llama_numa_init(params.numa);
 
@@ -2324,10 +2467,15 @@ public:
     return Status::OK;
   }
   grpc::Status PredictStream(grpc::ServerContext* context, const backend::PredictOptions* request, grpc::ServerWriter<backend::Reply>* writer) override {
+        const bool infill = !request->suffix().empty();
+        if (infill && llama_token_prefix(llama.model) < 0) {
+            return grpc::Status(grpc::StatusCode::INVALID_ARGUMENT, "the model has no fill-in-the-middle tokens, set the fim template of the model");
+        }
         json data = parse_options(true, request, llama);
+        set_threads(request);
         const int task_id = llama.queue_tasks.get_new_id();
         llama.queue_results.add_waiting_task_id(task_id);
-        llama.request_completion(task_id, data, false, false, -1);
+        llama.request_completion(task_id, data, infill, false, -1);
         while (true)
         {
             task_result result = llama.queue_results.recv(task_id);
@@ -2350,12 +2498,32 @@ public:
                 int32_t tokens_evaluated = result.result_json.value("tokens_evaluated", 0);
                 reply.set_prompt_tokens(tokens_evaluated);
 
+                // The progress of the prompt evaluation is sent before the first token
+                if (result.result_json.contains("prompt_progress")) {
+                    reply.set_progress_n_past(result.result_json.at("prompt_progress").value("n_past", 0));
+                    reply.set_progress_n_prompt(result.result_json.at("prompt_progress").value("n_prompt", 0));
+                }
+
+                // The timings are part of the final result only
+                if (result.result_json.contains("timings")) {
+                    double timing_prompt_processing = result.result_json.at("timings").value("prompt_ms", 0.0);
+                    reply.set_timing_prompt_processing(timing_prompt_processing);
+                    double timing_token_generation = result.result_json.at("timings").value("predicted_ms", 0.0);
+                    reply.set_timing_token_generation(timing_token_generation);
+                }
+
                 // Send the reply
                 writer->Write(reply);
 
                 if (result.stop) {
                     break;
                 }
+                // The client cancelled the generation: free the slot instead of generating the remaining tokens
+                if (context->IsCancelled()) {
+                    llama.request_cancel(task_id);
+                    llama.queue_results.remove_waiting_task_id(task_id);
+                    break;
+                }
             } else {
                 break;
             }
@@ -2366,10 +2534,15 @@ public:
 
 
     grpc::Status Predict(ServerContext* context, const backend::PredictOptions* request, backend::Reply* reply) {
+        const bool infill = !request->suffix().empty();
+        if (infill && llama_token_prefix(llama.model) < 0) {
+            return grpc::Status(grpc::StatusCode::INVALID_ARGUMENT, "the model has no fill-in-the-middle tokens, set the fim template of the model");
+        }
         json data = parse_options(false, request, llama);
+        set_threads(request);
         const int task_id = llama.queue_tasks.get_new_id();
         llama.queue_results.add_waiting_task_id(task_id);
-        llama.request_completion(task_id, data, false, false, -1);
+        llama.request_completion(task_id, data, infill, false, -1);
         std::string completion_text;
         task_result result = llama.queue_results.recv(task_id);
         if (!result.error && result.stop) {
@@ -2379,6 +2552,13 @@ public:
             reply->set_prompt_tokens(tokens_evaluated);
             reply->set_tokens(tokens_predicted);
             reply->set_message(completion_text);
+
+            if (result.result_json.contains("timings")) {
+                double timing_prompt_processing = result.result_json.at("timings").value("prompt_ms", 0.0);
+                reply->set_timing_prompt_processing(timing_prompt_processing);
+                double timing_token_generation = result.result_json.at("timings").value("predicted_ms", 0.0);
+                reply->set_timing_token_generation(timing_token_generation);
+            }
         }
         else
         {
@@ -2412,6 +2592,88 @@ public:
 
         return grpc::Status::OK;
     }
+
+    // Status reports the slots of the model and the ones processing a request, so that LocalAI sends as many
+    // requests at once as there are slots
+    grpc::Status Status(ServerContext* context, const backend::HealthMessage* request, backend::StatusResponse* response) override {
+        int busy = 0;
+        for (const auto & slot : llama.slots) {
+            if (slot.is_processing()) {
+                busy++;
+            }
+        }
+        if (!loaded_model) {
+            response->set_state(backend::StatusResponse::UNINITIALIZED);
+        } else if (busy > 0) {
+            response->set_state(backend::StatusResponse::BUSY);
+        } else {
+            response->set_state(backend::StatusResponse::READY);
+        }
+        response->set_slots(llama.slots.size());
+        response->set_slots_busy(busy);
+        return grpc::Status::OK;
+    }
+
+    // SaveCache writes the KV cache of each slot, with the tokens it holds, to a file of the directory, so that
+    // LoadCache restores the prompts evaluated once the model is loaded again
+    grpc::Status SaveCache(ServerContext* context, const backend::CacheRequest* request, backend::Result* response) override {
+        if (!loaded_model) {
+            response->set_success(false);
+            response->set_message("model not loaded");
+            return grpc::Status::OK;
+        }
+        int saved = 0;
+        for (auto & slot : llama.slots) {
+            if (slot.cache_tokens.empty()) {
+                continue;
+            }
+            std::vector<llama_token> tokens(llama.system_tokens);
+            tokens.insert(tokens.end(), slot.cache_tokens.begin(), slot.cache_tokens.end());
+            const std::string file = slot_cache_file(request->path(), slot.id);
+            if (llama_state_seq_save_file(llama.ctx, file.c_str(), slot.id, tokens.data(), tokens.size()) == 0) {
+                response->set_success(false);
+                response->set_message("failed saving the cache of the slot to " + file);
+                return grpc::Status::OK;
+            }
+            saved++;
+        }
+        response->set_success(true);
+        response->set_message("saved the cache of " + std::to_string(saved) + " slots");
+        return grpc::Status::OK;
+    }
+
+    // LoadCache restores the KV cache of the slots saved by SaveCache. The caches of another model, or of
+    // another system prompt, are discarded
+    grpc::Status LoadCache(ServerContext* context, const backend::CacheRequest* request, backend::Result* response) override {
+        if (!loaded_model) {
+            response->set_success(false);
+            response->set_message("model not loaded");
+            return grpc::Status::OK;
+        }
+        int restored = 0;
+        for (auto & slot : llama.slots) {
+            const std::string file = slot_cache_file(request->path(), slot.id);
+            if (access(file.c_str(), R_OK) != 0) {
+                continue;
+            }
+            std::vector<llama_token> tokens(llama.n_ctx);
+            size_t n_tokens = 0;
+            if (llama_state_seq_load_file(llama.ctx, file.c_str(), slot.id, tokens.data(), tokens.size(), &n_tokens) == 0) {
+                LOG_WARNING("discarding the cache of the slot", {{"slot_id", slot.id}, {"file", file}});
+                continue;
+            }
+            tokens.resize(n_tokens);
+            if (n_tokens <= llama.system_tokens.size() || !std::equal(llama.system_tokens.begin(), llama.system_tokens.end(), tokens.begin())) {
+                llama_kv_cache_seq_rm(llama.ctx, slot.id, -1, -1);
+                continue;
+            }
+            slot.cache_tokens.assign(tokens.begin() + llama.system_tokens.size(), tokens.end());
+            restored++;
+        }
+        response->set_success(true);
+        response->set_message("restored the cache of " + std::to_string(restored) + " slots");
+        return grpc::Status::OK;
+    }
 };
 
 void RunServer(const std::string& server_address) {
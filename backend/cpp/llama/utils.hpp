@@ -96,6 +96,7 @@ struct slot_params
 {
     bool stream       = true;
     bool cache_prompt = false; // remember the prompt to avoid reprocessing all prompt
+    bool return_progress = false; // send the progress of the prompt evaluation before the first token
 
     uint32_t seed      = -1; // RNG seed
     int32_t  n_keep    =  0; // number of tokens to keep from initial prompt
@@ -3,9 +3,12 @@ package main
 // This is a wrapper to statisfy the GRPC service interface
 // It is meant to be used by the main executable that is the server for the specific backend type (falcon, gpt3, etc)
 import (
+	"fmt"
+
 	"github.com/mudler/LocalAI/pkg/grpc/base"
 	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
 	"github.com/mudler/LocalAI/pkg/stablediffusion"
+	"github.com/mudler/LocalAI/pkg/utils"
 )
 
 type Image struct {
@@ -21,13 +24,29 @@ func (image *Image) Load(opts *pb.ModelOptions) error {
 }
 
 func (image *Image) GenerateImage(opts *pb.GenerateImageRequest) error {
-	return image.stablediffusion.GenerateImage(
-		int(opts.Height),
-		int(opts.Width),
-		int(opts.Mode),
-		int(opts.Step),
-		int(opts.Seed),
-		opts.PositivePrompt,
-		opts.NegativePrompt,
-		opts.Dst)
+	// The guidance scale and the sampler are fixed by the ncnn implementation
+	if opts.CfgScale != 0 {
+		return fmt.Errorf("the stablediffusion backend does not support setting cfg_scale")
+	}
+	if opts.Scheduler != "" {
+		return fmt.Errorf("the stablediffusion backend does not support setting the scheduler")
+	}
+
+	// The images of a batch are generated one after the other, with consecutive seeds
+	for i := 0; i < max(int(opts.BatchCount), 1); i++ {
+		err := image.stablediffusion.GenerateImage(
+			int(opts.Height),
+			int(opts.Width),
+			int(opts.Mode),
+			int(opts.Step),
+			int(opts.Seed)+i,
+			opts.PositivePrompt,
+			opts.NegativePrompt,
+			utils.BatchFileName(opts.Dst, i),
+			opts.Upscaler)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
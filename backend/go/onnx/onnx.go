@@ -0,0 +1,392 @@
+package main
+
+// This is a wrapper to statisfy the GRPC service interface
+// It is meant to be used by the main executable that is the server for the specific backend type (falcon, gpt3, etc)
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mudler/LocalAI/pkg/grpc/base"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+)
+
+// defaultMaxLength is the length of the sequences of the models which do not set it, as the BERT models
+const defaultMaxLength = 512
+
+// Kinds of the models, from their architecture
+const (
+	kindEmbeddings = "embeddings"
+	kindClassifier = "classifier"
+	kindReranker   = "reranker"
+)
+
+// ONNX runs the BERT models exported to ONNX, as the sentence-transformers, the classifiers and the
+// cross-encoders, with ONNX Runtime on the CPU
+type ONNX struct {
+	base.SingleThread
+
+	session    *session
+	tokenizer  *wordPiece
+	kind       string
+	output     string
+	maxLength  int
+	labels     []string
+	multiLabel bool
+
+	pooling   string
+	normalize bool
+}
+
+// modelConfig are the fields of the config.json of the huggingface models read by the backend
+type modelConfig struct {
+	Architectures         []string          `json:"architectures"`
+	ID2Label              map[string]string `json:"id2label"`
+	ProblemType           string            `json:"problem_type"`
+	MaxPositionEmbeddings int               `json:"max_position_embeddings"`
+}
+
+// tokenizerConfig are the fields of the tokenizer_config.json of the huggingface models read by the backend
+type tokenizerConfig struct {
+	DoLowerCase    *bool   `json:"do_lower_case"`
+	ModelMaxLength float64 `json:"model_max_length"`
+}
+
+// Load loads the model of a directory, laid out as the huggingface repositories of the ONNX models: the
+// model in model.onnx or onnx/model.onnx, and the vocabulary of the tokenizer in vocab.txt
+func (o *ONNX) Load(opts *pb.ModelOptions) error {
+	dir, modelFile := opts.ModelFile, ""
+	if strings.HasSuffix(dir, ".onnx") {
+		dir, modelFile = filepath.Dir(dir), dir
+	} else {
+		for _, f := range []string{"model.onnx", filepath.Join("onnx", "model.onnx")} {
+			if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+				modelFile = filepath.Join(dir, f)
+				break
+			}
+		}
+		if modelFile == "" {
+			return fmt.Errorf("no model.onnx in %s", dir)
+		}
+	}
+
+	config := modelConfig{}
+	if err := readJSON(filepath.Join(dir, "config.json"), &config); err != nil {
+		return err
+	}
+	tokConfig := tokenizerConfig{}
+	if err := readJSON(filepath.Join(dir, "tokenizer_config.json"), &tokConfig); err != nil {
+		return err
+	}
+
+	lowerCase := tokConfig.DoLowerCase == nil || *tokConfig.DoLowerCase
+	tokenizer, err := newWordPiece(filepath.Join(dir, "vocab.txt"), lowerCase)
+	if err != nil {
+		return err
+	}
+
+	o.maxLength = defaultMaxLength
+	for _, length := range []int{config.MaxPositionEmbeddings, int(min(tokConfig.ModelMaxLength, math.MaxInt32))} {
+		if length > 0 && length < o.maxLength {
+			o.maxLength = length
+		}
+	}
+	if opts.ContextSize > 0 && int(opts.ContextSize) < o.maxLength {
+		o.maxLength = int(opts.ContextSize)
+	}
+
+	session, err := newSession(modelFile, int(opts.Threads))
+	if err != nil {
+		return err
+	}
+	if len(session.outputs) == 0 {
+		session.close()
+		return fmt.Errorf("%s has no output", modelFile)
+	}
+
+	o.session, o.tokenizer = session, tokenizer
+	o.output = session.outputs[0]
+	// the sentence-transformers exported with their pooling output the embeddings of the texts
+	for _, output := range session.outputs {
+		if output == "sentence_embedding" {
+			o.output = output
+		}
+	}
+	o.kind = kindEmbeddings
+	for _, architecture := range config.Architectures {
+		if strings.HasSuffix(architecture, "ForSequenceClassification") {
+			o.kind = kindClassifier
+			// the cross-encoders score the pairs of texts with a single label
+			if len(config.ID2Label) == 1 {
+				o.kind = kindReranker
+			}
+		}
+	}
+	o.labels = make([]string, len(config.ID2Label))
+	for id, label := range config.ID2Label {
+		if i, err := strconv.Atoi(id); err == nil && i >= 0 && i < len(o.labels) {
+			o.labels[i] = label
+		}
+	}
+	o.multiLabel = config.ProblemType == "multi_label_classification"
+	o.pooling = opts.Pooling
+	o.normalize = opts.NormalizeEmbeddings
+	return nil
+}
+
+func readJSON(file string, v interface{}) error {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+	return nil
+}
+
+// run runs the model on the sequences of tokens, padded to the longest, and returns its output with its
+// shape, and the attention mask of the sequences
+func (o *ONNX) run(ids, types [][]int64) ([]float32, []int64, []int64, error) {
+	length := 0
+	for _, seq := range ids {
+		length = max(length, len(seq))
+	}
+	inputIDs := make([]int64, 0, len(ids)*length)
+	mask := make([]int64, 0, len(ids)*length)
+	typeIDs := make([]int64, 0, len(ids)*length)
+	for i, seq := range ids {
+		for j := 0; j < length; j++ {
+			if j < len(seq) {
+				inputIDs, mask, typeIDs = append(inputIDs, seq[j]), append(mask, 1), append(typeIDs, types[i][j])
+			} else {
+				inputIDs, mask, typeIDs = append(inputIDs, o.tokenizer.pad), append(mask, 0), append(typeIDs, 0)
+			}
+		}
+	}
+
+	inputs := map[string][]int64{"input_ids": inputIDs, "attention_mask": mask}
+	if o.session.hasInput("token_type_ids") {
+		inputs["token_type_ids"] = typeIDs
+	}
+	output, shape, err := o.session.run(inputs, len(ids), length, o.output)
+	return output, shape, mask, err
+}
+
+func (o *ONNX) Embeddings(opts *pb.PredictOptions) ([]float32, error) {
+	if o.kind != kindEmbeddings {
+		return nil, fmt.Errorf("the model is a %s, not an embedding model", o.kind)
+	}
+
+	ids, types := o.tokenizer.encode(opts.Embeddings, "", o.maxLength)
+	if len(opts.EmbeddingTokens) > 0 {
+		ids = make([]int64, 0, len(opts.EmbeddingTokens))
+		for _, t := range opts.EmbeddingTokens {
+			ids = append(ids, int64(t))
+		}
+		types = make([]int64, len(ids))
+	}
+	output, shape, mask, err := o.run([][]int64{ids}, [][]int64{types})
+	if err != nil {
+		return nil, err
+	}
+
+	var embedding []float32
+	switch len(shape) {
+	case 2:
+		embedding = output
+	case 3:
+		embedding = pool(output, int(shape[1]), int(shape[2]), mask, o.pooling)
+	default:
+		return nil, fmt.Errorf("unexpected shape %v of the output %s", shape, o.output)
+	}
+	if o.normalize {
+		normalize(embedding)
+	}
+	return embedding, nil
+}
+
+// pool pools the hidden states of the tokens of a sequence: mean (the default) averages the tokens of the
+// mask, cls takes the first token and last the last one
+func pool(hidden []float32, length, size int, mask []int64, pooling string) []float32 {
+	embedding := make([]float32, size)
+	tokens := 0
+	for _, m := range mask {
+		tokens += int(m)
+	}
+	switch pooling {
+	case "cls":
+		copy(embedding, hidden[:size])
+	case "last":
+		copy(embedding, hidden[(tokens-1)*size:tokens*size])
+	default:
+		for t := 0; t < length; t++ {
+			if mask[t] == 0 {
+				continue
+			}
+			for i := range embedding {
+				embedding[i] += hidden[t*size+i]
+			}
+		}
+		for i := range embedding {
+			embedding[i] /= float32(tokens)
+		}
+	}
+	return embedding
+}
+
+func normalize(v []float32) {
+	norm := float32(0)
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		return
+	}
+	norm = float32(math.Sqrt(float64(norm)))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+func (o *ONNX) Rerank(req *pb.RerankRequest) (pb.RerankResult, error) {
+	if o.kind != kindReranker {
+		return pb.RerankResult{}, fmt.Errorf("the model is a %s, not a reranker", o.kind)
+	}
+
+	results := []*pb.DocumentResult{}
+	tokens := 0
+	for i, document := range req.Documents {
+		ids, types := o.tokenizer.encode(req.Query, document, o.maxLength)
+		tokens += len(ids)
+		logits, _, _, err := o.run([][]int64{ids}, [][]int64{types})
+		if err != nil {
+			return pb.RerankResult{}, err
+		}
+		results = append(results, &pb.DocumentResult{Index: int32(i), Text: document, RelevanceScore: sigmoid(logits[0])})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].RelevanceScore > results[j].RelevanceScore })
+	if req.TopN > 0 && int(req.TopN) < len(results) {
+		results = results[:req.TopN]
+	}
+	return pb.RerankResult{
+		Usage:   &pb.Usage{TotalTokens: int32(tokens), PromptTokens: int32(tokens)},
+		Results: results,
+	}, nil
+}
+
+func (o *ONNX) ClassifyText(req *pb.TextClassifyRequest) (pb.ClassifyResponse, error) {
+	if o.kind != kindClassifier {
+		return pb.ClassifyResponse{}, fmt.Errorf("the model is a %s, not a classifier", o.kind)
+	}
+
+	var classifications []*pb.Classification
+	var err error
+	if len(req.Labels) > 0 {
+		classifications, err = o.classifyZeroShot(req.Text, req.Labels)
+	} else {
+		classifications, err = o.classify(req.Text)
+	}
+	if err != nil {
+		return pb.ClassifyResponse{}, err
+	}
+
+	sort.SliceStable(classifications, func(i, j int) bool { return classifications[i].Score > classifications[j].Score })
+	if req.TopK > 0 && int(req.TopK) < len(classifications) {
+		classifications = classifications[:req.TopK]
+	}
+	return pb.ClassifyResponse{Classifications: classifications}, nil
+}
+
+// classify scores the labels of the model, with a softmax, or with a sigmoid for the multi-label models
+func (o *ONNX) classify(text string) ([]*pb.Classification, error) {
+	ids, types := o.tokenizer.encode(text, "", o.maxLength)
+	logits, _, _, err := o.run([][]int64{ids}, [][]int64{types})
+	if err != nil {
+		return nil, err
+	}
+
+	scores := softmax(logits)
+	if o.multiLabel {
+		for i, logit := range logits {
+			scores[i] = sigmoid(logit)
+		}
+	}
+	classifications := []*pb.Classification{}
+	for i, score := range scores {
+		label := strconv.Itoa(i)
+		if i < len(o.labels) && o.labels[i] != "" {
+			label = o.labels[i]
+		}
+		classifications = append(classifications, &pb.Classification{Label: label, Score: score})
+	}
+	return classifications, nil
+}
+
+// classifyZeroShot scores the candidate labels with a NLI model, by the entailment of the text and of the
+// hypothesis "This example is <label>."
+func (o *ONNX) classifyZeroShot(text string, labels []string) ([]*pb.Classification, error) {
+	entailment, contradiction := -1, -1
+	for i, label := range o.labels {
+		switch strings.ToLower(label) {
+		case "entailment":
+			entailment = i
+		case "contradiction":
+			contradiction = i
+		}
+	}
+	if entailment < 0 || contradiction < 0 {
+		return nil, fmt.Errorf("the candidate labels need a NLI model, with the entailment and contradiction labels")
+	}
+
+	classifications := []*pb.Classification{}
+	for _, label := range labels {
+		ids, types := o.tokenizer.encode(text, fmt.Sprintf("This example is %s.", label), o.maxLength)
+		logits, _, _, err := o.run([][]int64{ids}, [][]int64{types})
+		if err != nil {
+			return nil, err
+		}
+		score := softmax([]float32{logits[contradiction], logits[entailment]})[1]
+		classifications = append(classifications, &pb.Classification{Label: label, Score: score})
+	}
+	return classifications, nil
+}
+
+func (o *ONNX) TokenizeString(opts *pb.PredictOptions) (pb.TokenizationResponse, error) {
+	ids, _ := o.tokenizer.encode(opts.Prompt, "", math.MaxInt)
+	tokens := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		tokens = append(tokens, int32(id))
+	}
+	return pb.TokenizationResponse{Length: int32(len(tokens)), Tokens: tokens}, nil
+}
+
+func softmax(logits []float32) []float32 {
+	maxLogit := float32(math.Inf(-1))
+	for _, l := range logits {
+		maxLogit = max(maxLogit, l)
+	}
+	scores := make([]float32, len(logits))
+	sum := float32(0)
+	for i, l := range logits {
+		scores[i] = float32(math.Exp(float64(l - maxLogit)))
+		sum += scores[i]
+	}
+	for i := range scores {
+		scores[i] /= sum
+	}
+	return scores
+}
+
+func sigmoid(x float32) float32 {
+	return float32(1 / (1 + math.Exp(-float64(x))))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ONNX", func() {
+	// the hidden states of a sequence of 3 tokens of size 2, whose last token is padding
+	hidden := []float32{1, 2, 3, 4, 100, 100}
+	mask := []int64{1, 1, 0}
+
+	It("pools the hidden states of the tokens of the mask", func() {
+		Expect(pool(hidden, 3, 2, mask, "mean")).To(Equal([]float32{2, 3}))
+		Expect(pool(hidden, 3, 2, mask, "")).To(Equal([]float32{2, 3}))
+		Expect(pool(hidden, 3, 2, mask, "cls")).To(Equal([]float32{1, 2}))
+		Expect(pool(hidden, 3, 2, mask, "last")).To(Equal([]float32{3, 4}))
+	})
+
+	It("normalizes the embeddings", func() {
+		v := []float32{3, 4}
+		normalize(v)
+		Expect(v).To(Equal([]float32{0.6, 0.8}))
+
+		zero := []float32{0, 0}
+		normalize(zero)
+		Expect(zero).To(Equal([]float32{0, 0}))
+	})
+
+	It("turns the logits in scores", func() {
+		scores := softmax([]float32{1000, 1000})
+		Expect(scores).To(Equal([]float32{0.5, 0.5}))
+		Expect(sigmoid(0)).To(Equal(float32(0.5)))
+	})
+})
@@ -0,0 +1,291 @@
+package main
+
+// The binding of the C API of ONNX Runtime, whose functions are called through the OrtApi table
+
+/*
+#cgo LDFLAGS: -lonnxruntime
+#include <stdlib.h>
+#include <string.h>
+#include <onnxruntime_c_api.h>
+
+static const OrtApi *ort;
+
+// ort_error returns the message of the status, to be freed, and releases the status
+static char *ort_error(OrtStatus *status) {
+	if (status == NULL) {
+		return NULL;
+	}
+	char *msg = strdup(ort->GetErrorMessage(status));
+	ort->ReleaseStatus(status);
+	return msg;
+}
+
+static char *ort_init(OrtEnv **env) {
+	ort = OrtGetApiBase()->GetApi(ORT_API_VERSION);
+	if (ort == NULL) {
+		return strdup("the ONNX Runtime library is older than the API of the backend");
+	}
+	return ort_error(ort->CreateEnv(ORT_LOGGING_LEVEL_WARNING, "localai", env));
+}
+
+static char *ort_create_session(OrtEnv *env, const char *path, int threads, OrtSession **session) {
+	OrtSessionOptions *options;
+	OrtStatus *status = ort->CreateSessionOptions(&options);
+	if (status != NULL) {
+		return ort_error(status);
+	}
+	if (threads > 0) {
+		ort->SetIntraOpNumThreads(options, threads);
+	}
+	ort->SetSessionGraphOptimizationLevel(options, ORT_ENABLE_ALL);
+	status = ort->CreateSession(env, path, options, session);
+	ort->ReleaseSessionOptions(options);
+	return ort_error(status);
+}
+
+// ort_names returns the names of the inputs, or of the outputs, of the session, to be freed
+static char *ort_names(OrtSession *session, int outputs, char ***names, size_t *count) {
+	OrtAllocator *allocator;
+	OrtStatus *status = ort->GetAllocatorWithDefaultOptions(&allocator);
+	if (status == NULL) {
+		status = outputs ? ort->SessionGetOutputCount(session, count) : ort->SessionGetInputCount(session, count);
+	}
+	if (status != NULL) {
+		return ort_error(status);
+	}
+	*names = calloc(*count, sizeof(char *));
+	for (size_t i = 0; i < *count; i++) {
+		char *name;
+		status = outputs ? ort->SessionGetOutputName(session, i, allocator, &name) : ort->SessionGetInputName(session, i, allocator, &name);
+		if (status != NULL) {
+			return ort_error(status);
+		}
+		(*names)[i] = strdup(name);
+		allocator->Free(allocator, name);
+	}
+	return NULL;
+}
+
+// ort_run runs the session on the int64 inputs of shape [batch, length], and returns its float output
+// and the shape of the output, to be freed
+static char *ort_run(OrtSession *session, const char **input_names, int64_t **inputs, size_t count, int64_t batch, int64_t length,
+		const char *output_name, float **output, int64_t **shape, size_t *dims) {
+	OrtMemoryInfo *memory;
+	OrtStatus *status = ort->CreateCpuMemoryInfo(OrtArenaAllocator, OrtMemTypeDefault, &memory);
+	if (status != NULL) {
+		return ort_error(status);
+	}
+
+	int64_t input_shape[2] = {batch, length};
+	OrtValue **values = calloc(count, sizeof(OrtValue *));
+	OrtValue *result = NULL;
+	for (size_t i = 0; i < count && status == NULL; i++) {
+		status = ort->CreateTensorWithDataAsOrtValue(memory, inputs[i], batch * length * sizeof(int64_t), input_shape, 2,
+			ONNX_TENSOR_ELEMENT_DATA_TYPE_INT64, &values[i]);
+	}
+	if (status == NULL) {
+		status = ort->Run(session, NULL, input_names, (const OrtValue *const *)values, count, &output_name, 1, &result);
+	}
+
+	OrtTensorTypeAndShapeInfo *info = NULL;
+	if (status == NULL) {
+		status = ort->GetTensorTypeAndShape(result, &info);
+	}
+	if (status == NULL) {
+		ONNXTensorElementDataType type;
+		size_t elements;
+		float *data;
+		status = ort->GetTensorElementType(info, &type);
+		if (status == NULL && type != ONNX_TENSOR_ELEMENT_DATA_TYPE_FLOAT) {
+			status = ort->CreateStatus(ORT_INVALID_ARGUMENT, "the output of the model is not a float tensor");
+		}
+		if (status == NULL) {
+			status = ort->GetDimensionsCount(info, dims);
+		}
+		if (status == NULL) {
+			*shape = calloc(*dims, sizeof(int64_t));
+			status = ort->GetDimensions(info, *shape, *dims);
+		}
+		if (status == NULL) {
+			status = ort->GetTensorShapeElementCount(info, &elements);
+		}
+		if (status == NULL) {
+			status = ort->GetTensorMutableData(result, (void **)&data);
+		}
+		if (status == NULL) {
+			*output = malloc(elements * sizeof(float));
+			memcpy(*output, data, elements * sizeof(float));
+		}
+	}
+
+	if (info != NULL) {
+		ort->ReleaseTensorTypeAndShapeInfo(info);
+	}
+	if (result != NULL) {
+		ort->ReleaseValue(result);
+	}
+	for (size_t i = 0; i < count; i++) {
+		if (values[i] != NULL) {
+			ort->ReleaseValue(values[i]);
+		}
+	}
+	free(values);
+	ort->ReleaseMemoryInfo(memory);
+	return ort_error(status);
+}
+
+static void ort_release_session(OrtSession *session) {
+	ort->ReleaseSession(session);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+var (
+	ortEnv     *C.OrtEnv
+	ortInit    sync.Once
+	ortInitErr error
+)
+
+// ortError returns the error of the message returned by the binding, and frees it
+func ortError(msg *C.char) error {
+	if msg == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(msg))
+	return errors.New(C.GoString(msg))
+}
+
+// session is a model loaded by ONNX Runtime
+type session struct {
+	s       *C.OrtSession
+	inputs  []string
+	outputs []string
+}
+
+// newSession loads the ONNX model of the file, running on the threads when they are set
+func newSession(file string, threads int) (*session, error) {
+	ortInit.Do(func() {
+		ortInitErr = ortError(C.ort_init(&ortEnv))
+	})
+	if ortInitErr != nil {
+		return nil, fmt.Errorf("initializing ONNX Runtime: %w", ortInitErr)
+	}
+
+	path := C.CString(file)
+	defer C.free(unsafe.Pointer(path))
+	s := &session{}
+	if err := ortError(C.ort_create_session(ortEnv, path, C.int(threads), &s.s)); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", file, err)
+	}
+
+	var err error
+	if s.inputs, err = s.names(false); err != nil {
+		s.close()
+		return nil, err
+	}
+	if s.outputs, err = s.names(true); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *session) names(outputs bool) ([]string, error) {
+	var cNames **C.char
+	var count C.size_t
+	isOutputs := C.int(0)
+	if outputs {
+		isOutputs = 1
+	}
+	err := ortError(C.ort_names(s.s, isOutputs, &cNames, &count))
+	names := []string{}
+	if cNames != nil {
+		for _, name := range unsafe.Slice(cNames, count) {
+			if name != nil {
+				names = append(names, C.GoString(name))
+				C.free(unsafe.Pointer(name))
+			}
+		}
+		C.free(unsafe.Pointer(cNames))
+	}
+	return names, err
+}
+
+// hasInput tells if the model has the input, as token_type_ids which only some of the BERT models have
+func (s *session) hasInput(name string) bool {
+	for _, input := range s.inputs {
+		if input == name {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs the model on the inputs of batch sequences of length tokens, by their name, and returns its
+// output with its shape
+func (s *session) run(inputs map[string][]int64, batch, length int, output string) ([]float32, []int64, error) {
+	names := []string{}
+	for name := range inputs {
+		names = append(names, name)
+	}
+
+	// the inputs are copied to the C memory, the binding keeping pointers to them
+	cNames := make([]*C.char, len(names))
+	cInputs := (**C.int64_t)(C.malloc(C.size_t(len(names)) * C.size_t(unsafe.Sizeof((*C.int64_t)(nil)))))
+	defer C.free(unsafe.Pointer(cInputs))
+	for i, name := range names {
+		cNames[i] = C.CString(name)
+		defer C.free(unsafe.Pointer(cNames[i]))
+
+		values := inputs[name]
+		data := (*C.int64_t)(C.malloc(C.size_t(len(values)) * 8))
+		defer C.free(unsafe.Pointer(data))
+		copy(unsafe.Slice((*int64)(unsafe.Pointer(data)), len(values)), values)
+		unsafe.Slice(cInputs, len(names))[i] = data
+	}
+	cNamesArray := (**C.char)(C.malloc(C.size_t(len(names)) * C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+	defer C.free(unsafe.Pointer(cNamesArray))
+	copy(unsafe.Slice(cNamesArray, len(names)), cNames)
+
+	cOutput := C.CString(output)
+	defer C.free(unsafe.Pointer(cOutput))
+
+	var data *C.float
+	var shape *C.int64_t
+	var dims C.size_t
+	err := ortError(C.ort_run(s.s, cNamesArray, cInputs, C.size_t(len(names)), C.int64_t(batch), C.int64_t(length),
+		cOutput, &data, &shape, &dims))
+	if shape != nil {
+		defer C.free(unsafe.Pointer(shape))
+	}
+	if data != nil {
+		defer C.free(unsafe.Pointer(data))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outputShape := make([]int64, dims)
+	elements := int64(1)
+	for i, d := range unsafe.Slice((*int64)(unsafe.Pointer(shape)), dims) {
+		outputShape[i] = d
+		elements *= d
+	}
+	values := make([]float32, elements)
+	copy(values, unsafe.Slice((*float32)(unsafe.Pointer(data)), elements))
+	return values, outputShape, nil
+}
+
+func (s *session) close() {
+	if s.s != nil {
+		C.ort_release_session(s.s)
+		s.s = nil
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordChars is the length of the words above which they are unknown, as with the BERT tokenizers
+const maxWordChars = 100
+
+// wordPiece is the tokenizer of the BERT models, as the sentence-transformers and the cross-encoders: the
+// text is split on the whitespaces and the punctuation, and the words in the longest pieces of the vocabulary
+type wordPiece struct {
+	vocab     map[string]int64
+	lowerCase bool
+
+	cls, sep, pad, unk int64
+}
+
+// newWordPiece reads the vocabulary of the tokenizer from vocab.txt, with a token by line
+func newWordPiece(vocabFile string, lowerCase bool) (*wordPiece, error) {
+	f, err := os.Open(vocabFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &wordPiece{vocab: map[string]int64{}, lowerCase: lowerCase}
+	scanner := bufio.NewScanner(f)
+	for id := int64(0); scanner.Scan(); id++ {
+		t.vocab[strings.TrimRight(scanner.Text(), "\r")] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, special := range []struct {
+		token string
+		id    *int64
+	}{{"[CLS]", &t.cls}, {"[SEP]", &t.sep}, {"[PAD]", &t.pad}, {"[UNK]", &t.unk}} {
+		id, exists := t.vocab[special.token]
+		if !exists {
+			return nil, fmt.Errorf("%s has no %s token, only the WordPiece vocabularies of the BERT models are supported", vocabFile, special.token)
+		}
+		*special.id = id
+	}
+	return t, nil
+}
+
+// tokenize returns the ids of the tokens of the text, without the special tokens
+func (t *wordPiece) tokenize(text string) []int64 {
+	ids := []int64{}
+	for _, word := range t.words(text) {
+		ids = append(ids, t.pieces(word)...)
+	}
+	return ids
+}
+
+// words splits the text on the whitespaces, and the punctuation and the CJK characters in words
+func (t *wordPiece) words(text string) []string {
+	if t.lowerCase {
+		// the accents are removed from the lower case text
+		text = strings.ToLower(text)
+		decomposed := []rune{}
+		for _, r := range norm.NFD.String(text) {
+			if !unicode.Is(unicode.Mn, r) {
+				decomposed = append(decomposed, r)
+			}
+		}
+		text = string(decomposed)
+	}
+
+	words := []string{}
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+		case unicode.IsSpace(r):
+			flush()
+		case isPunctuation(r) || unicode.Is(unicode.Han, r):
+			flush()
+			words = append(words, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// pieces returns the ids of the longest pieces of the vocabulary the word is made of, the pieces after
+// the first being prefixed with ##, or the unknown token
+func (t *wordPiece) pieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return []int64{t.unk}
+	}
+
+	ids := []int64{}
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, exists := t.vocab[piece]; exists {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []int64{t.unk}
+		}
+		start = end
+	}
+	return ids
+}
+
+// encode returns the ids of the tokens of the text with the special tokens, as "[CLS] text [SEP]", and
+// the ones of the pair of texts when second is not empty, as "[CLS] first [SEP] second [SEP]", with their
+// token types. The longest text is truncated to fit in maxLength tokens
+func (t *wordPiece) encode(first, second string, maxLength int) (ids, types []int64) {
+	a := t.tokenize(first)
+	var b []int64
+	special := 2
+	if second != "" {
+		b = t.tokenize(second)
+		special = 3
+	}
+	for len(a)+len(b)+special > maxLength && len(a)+len(b) > 0 {
+		if len(a) >= len(b) {
+			a = a[:len(a)-1]
+		} else {
+			b = b[:len(b)-1]
+		}
+	}
+
+	ids = append(append([]int64{t.cls}, a...), t.sep)
+	types = make([]int64, len(ids))
+	if second != "" {
+		ids = append(append(ids, b...), t.sep)
+		for len(types) < len(ids) {
+			types = append(types, 1)
+		}
+	}
+	return ids, types
+}
+
+// isPunctuation tells if the character is a punctuation, the ASCII symbols as $ or ^ included
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WordPiece", func() {
+	var tokenizer *wordPiece
+
+	// the ids of the tokens are their lines in the vocabulary
+	vocab := []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "hello", "world", "play", "##ing", "##s", "cafe", ",", "!", "世", "界"}
+	id := func(token string) int64 {
+		for i, t := range vocab {
+			if t == token {
+				return int64(i)
+			}
+		}
+		Fail("no token " + token)
+		return -1
+	}
+
+	writeVocab := func(tokens []string) string {
+		file := filepath.Join(GinkgoT().TempDir(), "vocab.txt")
+		Expect(os.WriteFile(file, []byte(strings.Join(tokens, "\r\n")+"\n"), 0644)).To(Succeed())
+		return file
+	}
+
+	BeforeEach(func() {
+		var err error
+		tokenizer, err = newWordPiece(writeVocab(vocab), true)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("splits the words in the longest pieces of the vocabulary", func() {
+		Expect(tokenizer.tokenize("Hello, world!")).To(Equal([]int64{id("hello"), id(","), id("world"), id("!")}))
+		Expect(tokenizer.tokenize("playing plays")).To(Equal([]int64{id("play"), id("##ing"), id("play"), id("##s")}))
+	})
+
+	It("removes the accents of the lower case text", func() {
+		Expect(tokenizer.tokenize("Café")).To(Equal([]int64{id("cafe")}))
+	})
+
+	It("splits the CJK characters and ignores the control characters", func() {
+		Expect(tokenizer.tokenize("世界\x00")).To(Equal([]int64{id("世"), id("界")}))
+	})
+
+	It("marks the words which are not in the vocabulary as unknown", func() {
+		Expect(tokenizer.tokenize("hello xyz")).To(Equal([]int64{id("hello"), id("[UNK]")}))
+		Expect(tokenizer.tokenize(strings.Repeat("a", maxWordChars+1))).To(Equal([]int64{id("[UNK]")}))
+	})
+
+	It("encodes the texts and the pairs of texts with the special tokens", func() {
+		ids, types := tokenizer.encode("hello", "", 512)
+		Expect(ids).To(Equal([]int64{id("[CLS]"), id("hello"), id("[SEP]")}))
+		Expect(types).To(Equal([]int64{0, 0, 0}))
+
+		ids, types = tokenizer.encode("hello", "world", 512)
+		Expect(ids).To(Equal([]int64{id("[CLS]"), id("hello"), id("[SEP]"), id("world"), id("[SEP]")}))
+		Expect(types).To(Equal([]int64{0, 0, 0, 1, 1}))
+	})
+
+	It("truncates the longest text to the maximum length", func() {
+		ids, types := tokenizer.encode("hello world hello world", "hello", 6)
+		Expect(ids).To(Equal([]int64{id("[CLS]"), id("hello"), id("world"), id("[SEP]"), id("hello"), id("[SEP]")}))
+		Expect(types).To(Equal([]int64{0, 0, 0, 0, 1, 1}))
+	})
+
+	It("requires the special tokens of the BERT vocabularies", func() {
+		_, err := newWordPiece(writeVocab([]string{"hello", "world"}), true)
+		Expect(err).To(MatchError(ContainSubstring("has no [CLS] token")))
+	})
+})
@@ -0,0 +1,47 @@
+package main
+
+// The options of whisper.cpp which the bindings do not expose: the GPU the model runs on, and the beam
+// search and the no speech threshold of the decoding
+
+/*
+#include <stdlib.h>
+#include <whisper.h>
+
+static struct whisper_context *whisper_init_on_gpu(const char *path, bool use_gpu, int gpu_device) {
+	struct whisper_context_params params = whisper_context_default_params();
+	params.use_gpu = use_gpu;
+	params.gpu_device = gpu_device;
+	return whisper_init_from_file_with_params(path, params);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	whisperlib "github.com/ggerganov/whisper.cpp/bindings/go"
+)
+
+// initContext loads the model of the file, on the GPU if gpu is set and whisper.cpp is built with the
+// support of a GPU. It returns nil if the model can't be loaded
+func initContext(path string, gpu bool, gpuDevice int) *whisperlib.Context {
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	ctx := C.whisper_init_on_gpu(cPath, C.bool(gpu), C.int(gpuDevice))
+	if ctx == nil {
+		return nil
+	}
+	return (*whisperlib.Context)(unsafe.Pointer(ctx))
+}
+
+// setDecoding sets the size of the beam search of the parameters of the decoding, created with the beam
+// search strategy, and the no speech threshold when they are set
+func setDecoding(params *whisperlib.Params, beamSize int, noSpeechThreshold float32) {
+	p := (*C.struct_whisper_full_params)(unsafe.Pointer(params))
+	if beamSize > 1 {
+		p.beam_search.beam_size = C.int(beamSize)
+	}
+	if noSpeechThreshold > 0 {
+		p.no_speech_thold = C.float(noSpeechThreshold)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+const (
+	// ggmlMagic begins the ggml files of the whisper models, "lmgg"
+	ggmlMagic = 0x67676d6c
+	// ggufMagic begins the GGUF files, as the ones of llama.cpp, "GGUF"
+	ggufMagic = 0x46554747
+	// qntVersionFactor multiplies the version of the quantization stored in the ftype of the ggml files
+	qntVersionFactor = 1000
+)
+
+// ggmlFileTypes are the quantizations of the ggml files by their ftype
+var ggmlFileTypes = map[int32]string{
+	0: "f32", 1: "f16", 2: "q4_0", 3: "q4_1", 4: "q4_1", 7: "q8_0", 8: "q5_0", 9: "q5_1",
+	10: "q2_k", 11: "q3_k", 12: "q4_k", 13: "q5_k", 14: "q6_k",
+}
+
+// modelQuantization returns the quantization of the weights of the ggml file of a whisper model, read
+// from the ftype of its header, after the magic and the 10 other hyperparameters
+func modelQuantization(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var header struct {
+		Magic       uint32
+		Hyperparams [10]int32
+		FType       int32
+	}
+	if err := binary.Read(f, binary.LittleEndian, &header); err != nil {
+		return "", fmt.Errorf("reading the header of %s: %w", file, err)
+	}
+	switch header.Magic {
+	case ggmlMagic:
+	case ggufMagic:
+		return "", fmt.Errorf("%s is a GGUF file: whisper.cpp loads the ggml files of the whisper models, as ggml-base.en.bin", file)
+	default:
+		return "", fmt.Errorf("%s is not a ggml file of a whisper model", file)
+	}
+
+	if quantization, known := ggmlFileTypes[header.FType%qntVersionFactor]; known {
+		return quantization, nil
+	}
+	return fmt.Sprintf("ftype %d", header.FType), nil
+}
@@ -5,8 +5,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
-	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	whisperlib "github.com/ggerganov/whisper.cpp/bindings/go"
 	"github.com/go-audio/wav"
 	"github.com/mudler/LocalAI/core/schema"
 )
@@ -29,7 +32,7 @@ func audioToWav(src, dst string) error {
 	return nil
 }
 
-func Transcript(model whisper.Model, audiopath, language string, translate bool, threads uint) (schema.TranscriptionResult, error) {
+func Transcript(ctx *whisperlib.Context, options decodingOptions, audiopath, language, prompt string, translate, wordTimestamps bool, threads uint) (schema.TranscriptionResult, error) {
 	res := schema.TranscriptionResult{}
 
 	dir, err := os.MkdirTemp("", "whisper")
@@ -60,45 +63,131 @@ func Transcript(model whisper.Model, audiopath, language string, translate bool,
 
 	data := buf.AsFloat32Buffer().Data
 
-	// Process samples
-	context, err := model.NewContext()
-	if err != nil {
-		return res, err
+	if threads == 0 {
+		threads = uint(runtime.NumCPU())
+	}
 
+	// Process samples
+	strategy := whisperlib.SAMPLING_GREEDY
+	if options.beamSize > 1 {
+		strategy = whisperlib.SAMPLING_BEAM_SEARCH
+	}
+	params := ctx.Whisper_full_default_params(strategy)
+	params.SetPrintSpecial(false)
+	params.SetPrintProgress(false)
+	params.SetPrintRealtime(false)
+	params.SetPrintTimestamps(false)
+	params.SetNoContext(true)
+	params.SetThreads(int(threads))
+	setDecoding(&params, options.beamSize, options.noSpeechThreshold)
+
+	// The models which are not multilingual transcribe english only
+	if ctx.Whisper_is_multilingual() != 0 {
+		if language == "" || language == "auto" {
+			params.SetLanguage(-1)
+		} else if id := ctx.Whisper_lang_id(language); id >= 0 {
+			params.SetLanguage(id)
+		}
 	}
 
-	context.SetThreads(threads)
+	if translate {
+		params.SetTranslate(true)
+	}
 
-	if language != "" {
-		context.SetLanguage(language)
-	} else {
-		context.SetLanguage("auto")
+	if prompt != "" {
+		params.SetInitialPrompt(prompt)
 	}
 
-	if translate {
-		context.SetTranslate(true)
+	if wordTimestamps {
+		params.SetTokenTimestamps(true)
 	}
 
-	if err := context.Process(data, nil, nil); err != nil {
+	if err := ctx.Whisper_full(params, data, nil, nil, nil); err != nil {
 		return res, err
 	}
 
-	for {
-		s, err := context.NextSegment()
-		if err != nil {
-			break
+	// Detection runs on the mel spectrogram computed while processing the samples.
+	if language == "" || language == "auto" {
+		probs, err := ctx.Whisper_lang_auto_detect(0, int(threads))
+		if err == nil {
+			for id, p := range probs {
+				if p > res.LanguageProbability {
+					res.Language = whisperlib.Whisper_lang_str(id)
+					res.LanguageProbability = p
+				}
+			}
+		}
+	} else {
+		res.Language = language
+	}
+
+	// The segments without speech are dropped, as whisper tends to transcribe words in the silences
+	var detector *vad
+	if options.vadThreshold > 0 {
+		detector = newVAD(data, options.vadThreshold, options.vadFreqThreshold)
+	}
+
+	eot := ctx.Whisper_token_eot()
+	for n := 0; n < ctx.Whisper_full_n_segments(); n++ {
+		start := time.Duration(ctx.Whisper_full_get_segment_t0(n)) * time.Millisecond * 10
+		end := time.Duration(ctx.Whisper_full_get_segment_t1(n)) * time.Millisecond * 10
+		if detector != nil && !detector.speech(start, end) {
+			continue
 		}
 
 		var tokens []int
-		for _, t := range s.Tokens {
-			tokens = append(tokens, t.Id)
+		var segmentTokens []token
+		for i := 0; i < ctx.Whisper_full_n_tokens(n); i++ {
+			id := ctx.Whisper_full_get_token_id(n, i)
+			data := ctx.Whisper_full_get_token_data(n, i)
+			tokens = append(tokens, int(id))
+			segmentTokens = append(segmentTokens, token{
+				text:  ctx.Whisper_full_get_token_text(n, i),
+				p:     ctx.Whisper_full_get_token_p(n, i),
+				start: time.Duration(data.T0()) * time.Millisecond * 10,
+				end:   time.Duration(data.T1()) * time.Millisecond * 10,
+				// the special tokens, as the timestamps, follow the end of transcription token
+				isText: id < eot,
+			})
 		}
 
-		segment := schema.Segment{Id: s.Num, Text: s.Text, Start: s.Start, End: s.End, Tokens: tokens}
+		text := strings.TrimSpace(ctx.Whisper_full_get_segment_text(n))
+		segment := schema.Segment{Id: len(res.Segments), Text: text, Start: start, End: end, Tokens: tokens}
+		if wordTimestamps {
+			segment.Words = words(segmentTokens)
+		}
 		res.Segments = append(res.Segments, segment)
 
-		res.Text += s.Text
+		res.Text += text
 	}
 
 	return res, nil
 }
+
+// token is a token of a segment of the transcription
+type token struct {
+	text       string
+	p          float32
+	start, end time.Duration
+	isText     bool
+}
+
+// words groups the text tokens of a segment in words: whisper tokens are
+// sub-words, and a token starting with a space begins a new word.
+func words(tokens []token) []schema.Word {
+	var res []schema.Word
+	for _, t := range tokens {
+		if !t.isText {
+			continue
+		}
+		if len(res) == 0 || strings.HasPrefix(t.text, " ") {
+			res = append(res, schema.Word{Word: strings.TrimSpace(t.text), Start: t.start, End: t.end, Probability: t.p})
+			continue
+		}
+		w := &res[len(res)-1]
+		w.Word += t.text
+		w.End = t.end
+		w.Probability = min(w.Probability, t.p)
+	}
+	return res
+}
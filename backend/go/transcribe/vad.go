@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	whisperSampleRate = 16000
+	// defaultVADFreqThreshold is the frequency in Hz below which the audio is filtered out, as the noise
+	defaultVADFreqThreshold = 100
+)
+
+// vad detects the speech in the audio from its energy, as vad_simple of the examples of whisper.cpp: a
+// part of the audio has speech if its mean energy, once the low frequencies are filtered out, is above
+// threshold times the mean energy of the whole audio
+type vad struct {
+	energy    []float32
+	mean      float64
+	threshold float64
+}
+
+func newVAD(samples []float32, threshold, freqThreshold float32) *vad {
+	if freqThreshold == 0 {
+		freqThreshold = defaultVADFreqThreshold
+	}
+	v := &vad{energy: highPassFilter(samples, freqThreshold), threshold: float64(threshold)}
+	for i, s := range v.energy {
+		v.energy[i] = float32(math.Abs(float64(s)))
+		v.mean += float64(v.energy[i])
+	}
+	if len(v.energy) > 0 {
+		v.mean /= float64(len(v.energy))
+	}
+	return v
+}
+
+// highPassFilter returns the samples without the frequencies below cutoff
+func highPassFilter(samples []float32, cutoff float32) []float32 {
+	res := make([]float32, len(samples))
+	if len(samples) == 0 {
+		return res
+	}
+	rc := 1 / (2 * math.Pi * float64(cutoff))
+	dt := 1 / float64(whisperSampleRate)
+	alpha := float32(dt / (rc + dt))
+
+	y := samples[0]
+	res[0] = y
+	for i := 1; i < len(samples); i++ {
+		y = alpha * (y + samples[i] - samples[i-1])
+		res[i] = y
+	}
+	return res
+}
+
+// speech tells if the audio between start and end has speech
+func (v *vad) speech(start, end time.Duration) bool {
+	from := min(max(int(start.Seconds()*whisperSampleRate), 0), len(v.energy))
+	to := min(max(int(end.Seconds()*whisperSampleRate), from), len(v.energy))
+	if to == from {
+		return false
+	}
+	var sum float64
+	for _, e := range v.energy[from:to] {
+		sum += float64(e)
+	}
+	return sum/float64(to-from) > v.threshold*v.mean
+}
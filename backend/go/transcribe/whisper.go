@@ -3,7 +3,10 @@ package main
 // This is a wrapper to statisfy the GRPC service interface
 // It is meant to be used by the main executable that is the server for the specific backend type (falcon, gpt3, etc)
 import (
-	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"fmt"
+	"strings"
+
+	whisperlib "github.com/ggerganov/whisper.cpp/bindings/go"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/pkg/grpc/base"
 	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
@@ -11,16 +14,42 @@ import (
 
 type Whisper struct {
 	base.SingleThread
-	whisper whisper.Model
+	ctx     *whisperlib.Context
+	options decodingOptions
+}
+
+// decodingOptions are the options of the model for the decoding of the transcriptions
+type decodingOptions struct {
+	beamSize          int
+	noSpeechThreshold float32
+	vadThreshold      float32
+	vadFreqThreshold  float32
 }
 
 func (sd *Whisper) Load(opts *pb.ModelOptions) error {
 	// Note: the Model here is a path to a directory containing the model files
-	w, err := whisper.New(opts.ModelFile)
-	sd.whisper = w
-	return err
+	quantization, err := modelQuantization(opts.ModelFile)
+	if err != nil {
+		return err
+	}
+	if opts.WhisperQuantization != "" && !strings.EqualFold(opts.WhisperQuantization, quantization) {
+		return fmt.Errorf("%s is quantized in %s, and the model requires %s: download the %s file of the model", opts.ModelFile, quantization, opts.WhisperQuantization, opts.WhisperQuantization)
+	}
+
+	ctx := initContext(opts.ModelFile, opts.WhisperGPU, int(opts.WhisperGPUDevice))
+	if ctx == nil {
+		return fmt.Errorf("unable to load the whisper model %s", opts.ModelFile)
+	}
+	sd.ctx = ctx
+	sd.options = decodingOptions{
+		beamSize:          int(opts.WhisperBeamSize),
+		noSpeechThreshold: opts.WhisperNoSpeechThreshold,
+		vadThreshold:      opts.WhisperVADThreshold,
+		vadFreqThreshold:  opts.WhisperVADFreqThreshold,
+	}
+	return nil
 }
 
 func (sd *Whisper) AudioTranscription(opts *pb.TranscriptRequest) (schema.TranscriptionResult, error) {
-	return Transcript(sd.whisper, opts.Dst, opts.Language, opts.Translate, uint(opts.Threads))
+	return Transcript(sd.ctx, sd.options, opts.Dst, opts.Language, opts.Prompt, opts.Translate, opts.WordTimestamps, uint(opts.Threads))
 }
@@ -3,12 +3,17 @@ package main
 // This is a wrapper to statisfy the GRPC service interface
 // It is meant to be used by the main executable that is the server for the specific backend type (falcon, gpt3, etc)
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mudler/LocalAI/pkg/grpc/base"
 	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/sound"
 	piper "github.com/mudler/go-piper"
 )
 
@@ -28,7 +33,94 @@ func (sd *Piper) Load(opts *pb.ModelOptions) error {
 }
 
 func (sd *Piper) TTS(opts *pb.TTSRequest) error {
-	return sd.piper.TTS(opts.Text, opts.Model, opts.Dst)
+	if len(opts.Segments) == 0 {
+		return sd.piper.TTS(opts.Text, opts.Model, opts.Dst)
+	}
+	return sd.ssml(opts)
+}
+
+// ssml speaks the segments of the SSML of the request: piper has no control of the prosody, so each segment
+// is spoken apart, and its rate and pitch applied to its audio, followed by the silence of its break
+func (sd *Piper) ssml(opts *pb.TTSRequest) error {
+	dir, err := os.MkdirTemp("", "piper-ssml")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	segments := []sound.Segment{}
+	for i, s := range opts.Segments {
+		segment := sound.Segment{
+			Rate:  float64(s.Rate),
+			Pitch: float64(s.Pitch),
+			Break: time.Duration(s.BreakMs) * time.Millisecond,
+		}
+		if s.Text != "" {
+			segment.File = filepath.Join(dir, strconv.Itoa(i)+".wav")
+			if err := sd.piper.TTS(s.Text, opts.Model, segment.File); err != nil {
+				return err
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return sound.Assemble(opts.Dst, segments, voiceSampleRate(opts.Model))
+}
+
+// voiceSampleRate returns the sample rate of the voice, from its configuration, for the requests
+// which are only silences
+func voiceSampleRate(model string) int {
+	cfg := piperVoiceConfig{}
+	if dat, err := os.ReadFile(model + ".json"); err == nil && json.Unmarshal(dat, &cfg) == nil && cfg.Audio.SampleRate > 0 {
+		return int(cfg.Audio.SampleRate)
+	}
+	return 22050
+}
+
+// piperVoiceConfig is the subset of the .onnx.json file shipped with piper voices
+// that describes the voice
+type piperVoiceConfig struct {
+	Dataset string `json:"dataset"`
+	Audio   struct {
+		SampleRate int32 `json:"sample_rate"`
+	} `json:"audio"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+}
+
+// ListVoices returns the voices found next to the requested model: each piper voice
+// is an .onnx file with its .onnx.json configuration
+func (sd *Piper) ListVoices(opts *pb.ListVoicesRequest) (pb.ListVoicesResponse, error) {
+	res := pb.ListVoicesResponse{}
+
+	models, err := filepath.Glob(filepath.Join(filepath.Dir(opts.Model), "*.onnx"))
+	if err != nil {
+		return res, err
+	}
+
+	for _, m := range models {
+		id := filepath.Base(m)
+		voice := &pb.Voice{Id: id, Name: strings.TrimSuffix(id, ".onnx")}
+
+		dat, err := os.ReadFile(m + ".json")
+		if err == nil {
+			cfg := piperVoiceConfig{}
+			if err := json.Unmarshal(dat, &cfg); err != nil {
+				return res, fmt.Errorf("failed parsing %s.json: %w", m, err)
+			}
+			if cfg.Dataset != "" {
+				voice.Name = cfg.Dataset
+			}
+			if cfg.Language.Code != "" {
+				voice.Languages = []string{cfg.Language.Code}
+			}
+			voice.SampleRate = cfg.Audio.SampleRate
+		}
+
+		res.Voices = append(res.Voices, voice)
+	}
+
+	return res, nil
 }
 
 type PiperB struct {
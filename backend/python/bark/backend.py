@@ -16,6 +16,9 @@ from bark import SAMPLE_RATE, generate_audio, preload_models
 
 import grpc
 
+# Languages of the speaker presets shipped with bark
+BARK_LANGUAGES = ["de", "en", "es", "fr", "hi", "it", "ja", "ko", "pl", "pt", "ru", "tr", "zh"]
+
 
 _ONE_DAY_IN_SECONDS = 60 * 60 * 24
 
@@ -46,7 +49,12 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         print(request, file=sys.stderr)
         try:
             audio_array = None
-            if model != "":
+            if request.voice_sample != "":
+                # bark clones voices from speaker prompts only
+                if not request.voice_sample.endswith(".npz"):
+                    return backend_pb2.Result(success=False, message="bark only supports .npz speaker prompts as reference audio")
+                audio_array = generate_audio(request.text, history_prompt=request.voice_sample)
+            elif model != "":
                 audio_array = generate_audio(request.text, history_prompt=model)
             else:
                 audio_array = generate_audio(request.text)
@@ -60,6 +68,15 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
             return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
         return backend_pb2.Result(success=True)
 
+    def ListVoices(self, request, context):
+        # Bark voices are the history prompt presets shipped with the model
+        voices = []
+        for lang in BARK_LANGUAGES:
+            for speaker in range(10):
+                voice_id = f"v2/{lang}_speaker_{speaker}"
+                voices.append(backend_pb2.Voice(id=voice_id, name=voice_id, languages=[lang], sample_rate=SAMPLE_RATE))
+        return backend_pb2.ListVoicesResponse(voices=voices)
+
 def serve(address):
     server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
     backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
@@ -73,6 +73,11 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
             if self.tts.is_multi_lingual and lang is None:
                return backend_pb2.Result(success=False, message=f"Model is multi-lingual, but no language was provided")
 
+            # a registered voice is cloned from its reference audio
+            if request.voice_sample != "":
+                self.tts.tts_to_file(text=request.text, speaker_wav=request.voice_sample, language=lang, file_path=request.dst)
+                return backend_pb2.Result(success=True)
+
             # if model is multi-speaker, use speaker_wav or the speaker_id from request.voice
             if self.tts.is_multi_speaker and self.AudioPath is None and request.voice is None:
                 return backend_pb2.Result(success=False, message=f"Model is multi-speaker, but no speaker was provided")
@@ -85,6 +90,14 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
             return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
         return backend_pb2.Result(success=True)
 
+    def ListVoices(self, request, context):
+        # Multi-speaker models expose their speakers, others have a single voice
+        languages = self.tts.languages if self.tts.is_multi_lingual else []
+        sample_rate = self.tts.synthesizer.output_sample_rate
+        speakers = self.tts.speakers if self.tts.is_multi_speaker else ["default"]
+        voices = [backend_pb2.Voice(id=s, name=s, languages=languages, sample_rate=sample_rate) for s in speakers]
+        return backend_pb2.ListVoicesResponse(voices=voices)
+
 def serve(address):
     server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
     backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
@@ -0,0 +1,120 @@
+#!/usr/bin/env python3
+"""
+Extra gRPC server for speaker diarization models.
+"""
+from concurrent import futures
+
+import argparse
+import signal
+import sys
+import os
+
+import time
+import backend_pb2
+import backend_pb2_grpc
+
+import grpc
+import torch
+
+from pyannote.audio import Pipeline
+
+_ONE_DAY_IN_SECONDS = 60 * 60 * 24
+
+# If MAX_WORKERS are specified in the environment use it, otherwise default to 1
+MAX_WORKERS = int(os.environ.get('PYTHON_GRPC_MAX_WORKERS', '1'))
+
+# Implement the BackendServicer class with the service methods
+class BackendServicer(backend_pb2_grpc.BackendServicer):
+    """
+    A gRPC servicer for the backend service.
+
+    This class implements the gRPC methods for the backend service, including Health, LoadModel, and AudioTranscription.
+    """
+    def Health(self, request, context):
+        """
+        A gRPC method that returns the health status of the backend service.
+
+        Args:
+            request: A HealthRequest object that contains the request parameters.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A Reply object that contains the health status of the backend service.
+        """
+        return backend_pb2.Reply(message=bytes("OK", 'utf-8'))
+
+    def LoadModel(self, request, context):
+        """
+        A gRPC method that loads a model into memory.
+
+        Args:
+            request: A LoadModelRequest object that contains the request parameters.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A Result object that contains the result of the LoadModel operation.
+        """
+        try:
+            # Gated models (e.g. pyannote/speaker-diarization-3.1) need a Hugging Face token
+            self.pipeline = Pipeline.from_pretrained(request.Model, use_auth_token=os.environ.get("HF_TOKEN"))
+            if request.CUDA and torch.cuda.is_available():
+                self.pipeline.to(torch.device("cuda"))
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
+
+        return backend_pb2.Result(message="Model loaded successfully", success=True)
+
+    def AudioTranscription(self, request, context):
+        """
+        Segments the audio by speaker. The result has a segment per speaker turn,
+        labeled with the speaker and without text.
+        Timestamps are in nanoseconds, as the ones returned by the transcription backends.
+        """
+        if not request.diarize:
+            context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+            context.set_details("this backend only supports diarization")
+            return backend_pb2.TranscriptResult()
+
+        diarization = self.pipeline(request.dst)
+
+        segments = []
+        for i, (turn, _, speaker) in enumerate(diarization.itertracks(yield_label=True)):
+            segments.append(backend_pb2.TranscriptSegment(
+                id=i,
+                start=int(turn.start * 1e9),
+                end=int(turn.end * 1e9),
+                speaker=speaker,
+            ))
+        return backend_pb2.TranscriptResult(segments=segments)
+
+def serve(address):
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
+    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
+    server.add_insecure_port(address)
+    server.start()
+    print("Server started. Listening on: " + address, file=sys.stderr)
+
+    # Define the signal handler function
+    def signal_handler(sig, frame):
+        print("Received termination signal. Shutting down...")
+        server.stop(0)
+        sys.exit(0)
+
+    # Set the signal handlers for SIGINT and SIGTERM
+    signal.signal(signal.SIGINT, signal_handler)
+    signal.signal(signal.SIGTERM, signal_handler)
+
+    try:
+        while True:
+            time.sleep(_ONE_DAY_IN_SECONDS)
+    except KeyboardInterrupt:
+        server.stop(0)
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description="Run the gRPC server.")
+    parser.add_argument(
+        "--addr", default="localhost:50051", help="The address to bind the server to."
+    )
+    args = parser.parse_args()
+
+    serve(args.addr)
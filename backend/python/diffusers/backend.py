@@ -4,8 +4,12 @@ import traceback
 import argparse
 from collections import defaultdict
 from enum import Enum
+import inspect
+import io
+import queue
 import signal
 import sys
+import threading
 import time
 import os
 
@@ -19,7 +23,8 @@ import grpc
 
 from diffusers import StableDiffusion3Pipeline, StableDiffusionXLPipeline, StableDiffusionDepth2ImgPipeline, DPMSolverMultistepScheduler, StableDiffusionPipeline, DiffusionPipeline, \
     EulerAncestralDiscreteScheduler, FluxPipeline, FluxTransformer2DModel
-from diffusers import StableDiffusionImg2ImgPipeline, AutoPipelineForText2Image, ControlNetModel, StableVideoDiffusionPipeline
+from diffusers import StableDiffusionImg2ImgPipeline, AutoPipelineForText2Image, ControlNetModel, StableVideoDiffusionPipeline, \
+    StableDiffusionLatentUpscalePipeline
 from diffusers.pipelines.stable_diffusion import safety_checker
 from diffusers.utils import load_image, export_to_video
 from compel import Compel, ReturnedEmbeddingsType
@@ -36,6 +41,10 @@ CHUNK_SIZE = os.environ.get("CHUNK_SIZE", "8")
 FPS = os.environ.get("FPS", "7")
 DISABLE_CPU_OFFLOAD = os.environ.get("DISABLE_CPU_OFFLOAD", "0") == "1"
 FRAMES = os.environ.get("FRAMES", "64")
+LATENT_UPSCALER = os.environ.get("LATENT_UPSCALER", "stabilityai/sd-x2-latent-upscaler")
+
+# Upscalers of the generated images: "lanczos" resizes them 2x, "latent" upscales them 2x with the latent upscaler
+UPSCALERS = ["", "none", "lanczos", "latent"]
 
 if XPU:
     import intel_extension_for_pytorch as ipex
@@ -53,6 +62,34 @@ def sc(self, clip_input, images): return images, [False for i in images]
 # edit the StableDiffusionSafetyChecker class so that, when called, it just returns the images and an array of True values
 safety_checker.StableDiffusionSafetyChecker.forward = sc
 
+# Approximation of the RGB colors of the 4 channels of the Stable Diffusion latents, to preview the
+# images while they are generated without decoding the latents with the VAE
+LATENT_RGB_FACTORS = torch.tensor([
+    [0.298, 0.207, 0.208],
+    [0.187, 0.286, 0.173],
+    [-0.158, 0.189, 0.264],
+    [-0.184, -0.271, -0.473],
+])
+
+
+def batch_file_name(dst, index):
+    # The first image of a batch is written to dst, the next ones to dst suffixed by their index, as image_1.png
+    if index == 0:
+        return dst
+    stem, ext = os.path.splitext(dst)
+    return f"{stem}_{index}{ext}"
+
+
+def latents_preview(latents):
+    # Returns a low resolution PNG of the latents, or nothing for the latents of the other pipelines, as Flux
+    if latents is None or latents.ndim != 4 or latents.shape[1] != 4:
+        return b""
+    rgb = torch.einsum("chw,cr->hwr", latents[0].float().cpu(), LATENT_RGB_FACTORS)
+    rgb = ((rgb + 1) / 2).clamp(0, 1).mul(255).byte().numpy()
+    buf = io.BytesIO()
+    Image.fromarray(rgb).save(buf, format="PNG")
+    return buf.getvalue()
+
 from diffusers.schedulers import (
     DDIMScheduler,
     DPMSolverMultistepScheduler,
@@ -280,6 +317,9 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
             # TODO: this needs to be customized
             if request.SchedulerType != "":
                 self.pipe.scheduler = get_scheduler(request.SchedulerType, self.pipe.scheduler.config)
+            # The scheduler of the model, restored after the requests setting another one
+            self.scheduler = self.pipe.scheduler
+            self.latent_upscaler = None
 
             if COMPEL:
                 self.compel = Compel(
@@ -296,6 +336,13 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
                 self.pipe.controlnet = self.controlnet
             else:
                 self.controlnet = None
+            self.controlnet_name = request.ControlNet
+            # Pipelines of the controlnets requested by the generations, sharing the components of the model
+            self.controlnet_pipes = {}
+
+            self.ip_adapter = request.IPAdapter != ""
+            if self.ip_adapter:
+                self.pipe.load_ip_adapter(request.IPAdapter, subfolder=request.IPAdapterSubfolder, weight_name=request.IPAdapterWeightName)
             # Assume directory from request.ModelFile.
             # Only if request.LoraAdapter it's not an absolute path
             if request.LoraAdapter and request.ModelFile != "" and not os.path.isabs(request.LoraAdapter) and request.LoraAdapter:
@@ -381,6 +428,47 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
                 curr_layer.weight.data += multiplier * alpha * torch.mm(weight_up, weight_down)
 
     def GenerateImage(self, request, context):
+        return self.generate(request)
+
+    def GenerateImageStream(self, request, context):
+        steps = request.step if request.step != 0 else 1
+        updates = queue.Queue()
+
+        def on_step_end(pipe, step, timestep, callback_kwargs):
+            current = step + 1
+            preview = b""
+            if request.preview_interval > 0 and current % request.preview_interval == 0 and current < steps:
+                preview = latents_preview(callback_kwargs.get("latents"))
+            updates.put(backend_pb2.GenerateImageProgress(step=current, steps=steps, preview=preview))
+            return callback_kwargs
+
+        # The pipelines without step callbacks only report the start and the end of the generation
+        callback = None
+        if "callback_on_step_end" in inspect.signature(self.pipe.__call__).parameters:
+            callback = on_step_end
+
+        result = {}
+
+        def run():
+            try:
+                result["result"] = self.generate(request, callback)
+            except Exception as err:
+                result["result"] = backend_pb2.Result(message=f"Error generating image: {err}", success=False)
+            finally:
+                updates.put(None)
+
+        yield backend_pb2.GenerateImageProgress(step=0, steps=steps)
+        threading.Thread(target=run).start()
+        while True:
+            update = updates.get()
+            if update is None:
+                break
+            yield update
+        if callback is None and result["result"].success:
+            yield backend_pb2.GenerateImageProgress(step=steps, steps=steps)
+        yield backend_pb2.GenerateImageProgress(result=result["result"])
+
+    def generate(self, request, callback=None):
 
         prompt = request.positive_prompt
 
@@ -404,8 +492,20 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
             pose_image = load_image(request.src)
             options["image"] = pose_image
 
-        if CLIPSKIP and self.clip_skip != 0:
-            options["clip_skip"] = self.clip_skip
+        clip_skip = request.CLIPSkip if request.CLIPSkip != 0 else self.clip_skip
+        if CLIPSKIP and clip_skip != 0:
+            options["clip_skip"] = clip_skip
+
+        if request.upscaler not in UPSCALERS:
+            raise ValueError(f"Invalid upscaler '{request.upscaler}': expected one of {', '.join(UPSCALERS[1:])}")
+
+        cfg_scale = request.cfg_scale if request.cfg_scale > 0 else self.cfg_scale
+
+        # The sampler of the request replaces the one of the model for this generation only
+        if request.scheduler != "":
+            self.pipe.scheduler = get_scheduler(request.scheduler, dict(self.scheduler.config))
+        else:
+            self.pipe.scheduler = self.scheduler
 
         # Get the keys that we will build the args for our pipe for
         keys = options.keys()
@@ -432,44 +532,96 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
             kwargs["output_type"] = "pil"
             kwargs["generator"] = torch.Generator("cpu").manual_seed(0)
 
+        pipe = self.pipe
+        if request.controlnet != "":
+            pipe = self.controlnet_pipe(request.controlnet)
+            kwargs["image"] = load_image(request.conditioning_image)
+            if request.controlnet_scale > 0:
+                kwargs["controlnet_conditioning_scale"] = request.controlnet_scale
+
+        if self.ip_adapter:
+            # The pipelines with an IP-Adapter require an image prompt: without one, it is disabled with a blank image
+            if request.ip_adapter_image != "":
+                pipe.set_ip_adapter_scale(request.ip_adapter_scale if request.ip_adapter_scale > 0 else 1.0)
+                kwargs["ip_adapter_image"] = load_image(request.ip_adapter_image)
+            else:
+                pipe.set_ip_adapter_scale(0.0)
+                kwargs["ip_adapter_image"] = Image.new("RGB", (224, 224))
+        elif request.ip_adapter_image != "":
+            raise ValueError("the model has no IP-Adapter")
+
+        if callback is not None:
+            kwargs["callback_on_step_end"] = callback
+            kwargs["callback_on_step_end_tensor_inputs"] = ["latents"]
+
+        if request.batch_count > 1:
+            kwargs["num_images_per_prompt"] = request.batch_count
+
         if self.img2vid:
             # Load the conditioning image
             image = load_image(request.src)
             image = image.resize((1024, 576))
 
             generator = torch.manual_seed(request.seed)
-            frames = self.pipe(image, guidance_scale=self.cfg_scale, decode_chunk_size=CHUNK_SIZE, generator=generator).frames[0]
+            frames = self.pipe(image, guidance_scale=cfg_scale, decode_chunk_size=CHUNK_SIZE, generator=generator).frames[0]
             export_to_video(frames, request.dst, fps=FPS)
             return backend_pb2.Result(message="Media generated successfully", success=True)
 
         if self.txt2vid:
-            video_frames = self.pipe(prompt, guidance_scale=self.cfg_scale, num_inference_steps=steps, num_frames=int(FRAMES)).frames
+            video_frames = self.pipe(prompt, guidance_scale=cfg_scale, num_inference_steps=steps, num_frames=int(FRAMES)).frames
             export_to_video(video_frames, request.dst)
             return backend_pb2.Result(message="Media generated successfully", success=True)
 
-        image = {}
+        images = []
         if COMPEL:
             conditioning, pooled = self.compel.build_conditioning_tensor(prompt)
             kwargs["prompt_embeds"] = conditioning
             kwargs["pooled_prompt_embeds"] = pooled
             # pass the kwargs dictionary to the self.pipe method
-            image = self.pipe(
-                guidance_scale=self.cfg_scale,
+            images = pipe(
+                guidance_scale=cfg_scale,
                 **kwargs
-            ).images[0]
+            ).images
         else:
             # pass the kwargs dictionary to the self.pipe method
-            image = self.pipe(
+            images = pipe(
                 prompt,
-                guidance_scale=self.cfg_scale,
+                guidance_scale=cfg_scale,
                 **kwargs
-            ).images[0]
+            ).images
+
+        images = self.upscale(request.upscaler, images, prompt)
 
         # save the result
-        image.save(request.dst)
+        for i, image in enumerate(images):
+            image.save(batch_file_name(request.dst, i))
 
         return backend_pb2.Result(message="Media generated", success=True)
 
+    def controlnet_pipe(self, name):
+        if name not in self.controlnet_pipes:
+            controlnet = self.controlnet
+            if name != self.controlnet_name:
+                controlnet = ControlNetModel.from_pretrained(name, torch_dtype=self.pipe.dtype)
+                controlnet.to(self.pipe.device)
+            self.controlnet_pipes[name] = AutoPipelineForText2Image.from_pipe(self.pipe, controlnet=controlnet)
+        return self.controlnet_pipes[name]
+
+    def upscale(self, upscaler, images, prompt):
+        if upscaler == "lanczos":
+            return [image.resize((image.width * 2, image.height * 2), Image.LANCZOS) for image in images]
+        if upscaler == "latent":
+            if self.latent_upscaler is None:
+                self.latent_upscaler = StableDiffusionLatentUpscalePipeline.from_pretrained(LATENT_UPSCALER, torch_dtype=self.pipe.dtype)
+                self.latent_upscaler.to(self.pipe.device)
+            return self.latent_upscaler(
+                prompt=[prompt] * len(images),
+                image=images,
+                num_inference_steps=20,
+                guidance_scale=0,
+            ).images
+        return images
+
 
 def serve(address):
     server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
@@ -0,0 +1,169 @@
+#!/usr/bin/env python3
+"""
+Extra gRPC server for the post-processing of images: upscaling with ESRGAN like models and background removal.
+"""
+from concurrent import futures
+
+import argparse
+import signal
+import sys
+import os
+
+import time
+import backend_pb2
+import backend_pb2_grpc
+
+import grpc
+import numpy as np
+import torch
+
+from PIL import Image
+
+_ONE_DAY_IN_SECONDS = 60 * 60 * 24
+
+# If MAX_WORKERS are specified in the environment use it, otherwise default to 1
+MAX_WORKERS = int(os.environ.get('PYTHON_GRPC_MAX_WORKERS', '1'))
+
+UPSCALE = "upscale"
+REMOVE_BACKGROUND = "remove-background"
+
+# Implement the BackendServicer class with the service methods
+class BackendServicer(backend_pb2_grpc.BackendServicer):
+    """
+    A gRPC servicer for the backend service.
+
+    This class implements the gRPC methods for the backend service, including Health, LoadModel, and ImageTransform.
+    """
+    def Health(self, request, context):
+        """
+        A gRPC method that returns the health status of the backend service.
+
+        Args:
+            request: A HealthRequest object that contains the request parameters.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A Reply object that contains the health status of the backend service.
+        """
+        return backend_pb2.Reply(message=bytes("OK", 'utf-8'))
+
+    def LoadModel(self, request, context):
+        """
+        A gRPC method that loads a model into memory.
+
+        The models named as the rembg ones (u2net, isnet-general-use, birefnet-general...) remove the
+        background of the images, and are downloaded on their first use. The other models are the
+        weights of an upscaler (ESRGAN, Real-ESRGAN, SwinIR...), as .pth or .safetensors files.
+
+        Args:
+            request: A LoadModelRequest object that contains the request parameters.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A Result object that contains the result of the LoadModel operation.
+        """
+        self.session = None
+        self.upscaler = None
+        try:
+            from rembg.sessions import sessions_names
+            if request.Model in sessions_names:
+                from rembg import new_session
+                self.session = new_session(request.Model)
+            else:
+                from spandrel import ImageModelDescriptor, ModelLoader
+                self.device = "cuda" if request.CUDA and torch.cuda.is_available() else "cpu"
+                path = request.ModelFile if os.path.exists(request.ModelFile) else request.Model
+                model = ModelLoader().load_from_file(path)
+                if not isinstance(model, ImageModelDescriptor):
+                    return backend_pb2.Result(success=False, message=f"{request.Model} is not an image to image model")
+                self.upscaler = model.to(self.device).eval()
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
+
+        return backend_pb2.Result(message="Model loaded successfully", success=True)
+
+    def ImageTransform(self, request, context):
+        """
+        Upscales the image src, or removes its background, into the PNG image dst.
+        """
+        try:
+            image = Image.open(request.src)
+            if request.operation == UPSCALE:
+                if self.upscaler is None:
+                    return backend_pb2.Result(success=False, message="the model is not an upscaler")
+                image = self.upscale(image, request.scale)
+            elif request.operation == REMOVE_BACKGROUND:
+                if self.session is None:
+                    return backend_pb2.Result(success=False, message="the model does not remove backgrounds")
+                image = self.remove_background(image, request.only_mask, request.background_color)
+            else:
+                return backend_pb2.Result(success=False, message=f"unsupported operation {request.operation}")
+            image.save(request.dst, format="PNG")
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
+
+        return backend_pb2.Result(message="Image transformed", success=True)
+
+    def upscale(self, image, scale):
+        """
+        Upscales the image by the factor of the model, then resizes it to the requested factor if it is another one.
+        The transparency of the image is upscaled separately.
+        """
+        alpha = image.getchannel("A") if image.mode in ("RGBA", "LA") else None
+        pixels = torch.from_numpy(np.array(image.convert("RGB"))).permute(2, 0, 1).unsqueeze(0)
+        pixels = pixels.float().div(255).to(self.device)
+        with torch.no_grad():
+            pixels = self.upscaler(pixels)
+        pixels = pixels.squeeze(0).clamp(0, 1).mul(255).round().byte().permute(1, 2, 0).cpu().numpy()
+        upscaled = Image.fromarray(pixels)
+
+        size = upscaled.size
+        if scale and scale != self.upscaler.scale:
+            size = (image.width * scale, image.height * scale)
+            upscaled = upscaled.resize(size, Image.LANCZOS)
+        if alpha is not None:
+            upscaled.putalpha(alpha.resize(size, Image.LANCZOS))
+        return upscaled
+
+    def remove_background(self, image, only_mask, background_color):
+        """
+        Removes the background of the image, making it transparent or of background_color (#rrggbb)
+        """
+        from rembg import remove
+        bgcolor = None
+        if background_color:
+            color = background_color.lstrip("#")
+            bgcolor = (int(color[0:2], 16), int(color[2:4], 16), int(color[4:6], 16), 255)
+        return remove(image, session=self.session, only_mask=only_mask, bgcolor=bgcolor)
+
+def serve(address):
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
+    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
+    server.add_insecure_port(address)
+    server.start()
+    print("Server started. Listening on: " + address, file=sys.stderr)
+
+    # Define the signal handler function
+    def signal_handler(sig, frame):
+        print("Received termination signal. Shutting down...")
+        server.stop(0)
+        sys.exit(0)
+
+    # Set the signal handlers for SIGINT and SIGTERM
+    signal.signal(signal.SIGINT, signal_handler)
+    signal.signal(signal.SIGTERM, signal_handler)
+
+    try:
+        while True:
+            time.sleep(_ONE_DAY_IN_SECONDS)
+    except KeyboardInterrupt:
+        server.stop(0)
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description="Run the gRPC server.")
+    parser.add_argument(
+        "--addr", default="localhost:50051", help="The address to bind the server to."
+    )
+    args = parser.parse_args()
+
+    serve(args.addr)
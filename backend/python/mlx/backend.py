@@ -0,0 +1,246 @@
+#!/usr/bin/env python3
+from concurrent import futures
+import time
+import argparse
+import signal
+import sys
+import os
+
+import backend_pb2
+import backend_pb2_grpc
+
+import grpc
+
+import mlx.core as mx
+from mlx_lm import load, stream_generate
+
+_ONE_DAY_IN_SECONDS = 60 * 60 * 24
+
+# If MAX_WORKERS are specified in the environment use it, otherwise default to 1
+MAX_WORKERS = int(os.environ.get('PYTHON_GRPC_MAX_WORKERS', '1'))
+
+def metal_memory(name):
+    """
+    Returns the Metal memory of MLX in bytes, "active", "cache" or "peak", as reported by
+    mx.get_active_memory or, with the older versions of MLX, by mx.metal.get_active_memory.
+    """
+    getter = getattr(mx, f"get_{name}_memory", None) or getattr(mx.metal, f"get_{name}_memory", None)
+    return int(getter()) if getter else 0
+
+def make_sampling_args(request):
+    """
+    Returns the sampling arguments of stream_generate for the temperature and the top_p of the request:
+    a sampler with the recent versions of mlx-lm, or temp and top_p with the older ones.
+    """
+    try:
+        from mlx_lm.sample_utils import make_sampler
+        return {"sampler": make_sampler(temp=request.Temperature, top_p=request.TopP)}
+    except ImportError:
+        return {"temp": request.Temperature, "top_p": request.TopP}
+
+# Implement the BackendServicer class with the service methods
+class BackendServicer(backend_pb2_grpc.BackendServicer):
+    """
+    A gRPC servicer that implements the Backend service defined in backend.proto,
+    running the language models with MLX on the GPU of Apple Silicon.
+    """
+    def Health(self, request, context):
+        """
+        Returns a health check message.
+
+        Args:
+            request: The health check request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Reply: The health check reply.
+        """
+        return backend_pb2.Reply(message=bytes("OK", 'utf-8'))
+
+    def LoadModel(self, request, context):
+        """
+        Loads a language model, from a directory of the models path or from a repository of huggingface,
+        as mlx-community/Meta-Llama-3-8B-Instruct-4bit.
+
+        Args:
+            request: The load model request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Result: The load model result.
+        """
+        model = request.Model
+        if request.ModelFile != "" and os.path.exists(request.ModelFile):
+            model = request.ModelFile
+
+        tokenizer_config = {}
+        if request.TrustRemoteCode:
+            tokenizer_config["trust_remote_code"] = True
+
+        try:
+            self.model, self.tokenizer = load(model, tokenizer_config=tokenizer_config)
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
+
+        self.context_size = request.ContextSize
+        self.pooling = request.Pooling or "mean"
+        self.normalize = request.NormalizeEmbeddings
+        return backend_pb2.Result(message="Model loaded successfully", success=True)
+
+    def prompt(self, request):
+        """
+        Returns the prompt of the request, templated by the tokenizer when LocalAI gives the messages.
+        """
+        if not request.Prompt and request.UseTokenizerTemplate and request.Messages:
+            messages = [{"role": m.role, "content": m.content} for m in request.Messages]
+            return self.tokenizer.apply_chat_template(messages, tokenize=False, add_generation_prompt=True)
+        return request.Prompt
+
+    def generate(self, request):
+        """
+        Generates the text of the request, yielding its pieces until the max tokens or a stop word.
+        """
+        if request.Seed != 0:
+            mx.random.seed(request.Seed)
+
+        max_tokens = request.Tokens
+        if max_tokens == 0:
+            max_tokens = self.context_size if self.context_size > 0 else 2048
+
+        generated = ""
+        for response in stream_generate(self.model, self.tokenizer, self.prompt(request), max_tokens=max_tokens, **make_sampling_args(request)):
+            # stream_generate yields the text, or a GenerationResponse with the recent versions of mlx-lm
+            text = getattr(response, "text", response)
+            generated += text
+            stop = next((s for s in request.StopPrompts if s and s in generated), None)
+            if stop:
+                # the pieces before the stop word, which may begin in the previous pieces
+                yield text[:max(len(text) - (len(generated) - generated.index(stop)), 0)]
+                return
+            yield text
+
+    def Predict(self, request, context):
+        """
+        Generates text based on the given prompt and sampling parameters.
+
+        Args:
+            request: The predict request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Reply: The predict result.
+        """
+        try:
+            generated_text = "".join(self.generate(request))
+        except Exception as err:
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Unexpected {err=}, {type(err)=}")
+            return backend_pb2.Reply()
+        return backend_pb2.Reply(message=bytes(generated_text, encoding='utf-8'))
+
+    def PredictStream(self, request, context):
+        """
+        Generates text based on the given prompt and sampling parameters, and streams the results.
+
+        Args:
+            request: The predict stream request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Reply: The predict stream results.
+        """
+        for text in self.generate(request):
+            yield backend_pb2.Reply(message=bytes(text, encoding='utf-8'))
+
+    def Embedding(self, request, context):
+        """
+        Computes the embedding of the text with the hidden states of the model, pooled as the pooling
+        of the model: mean (the default), last or cls.
+
+        Args:
+            request: The embedding request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.EmbeddingResult: The embedding.
+        """
+        tokens = list(request.EmbeddingTokens) or self.tokenizer.encode(request.Embeddings)
+        hidden = self.model.model(mx.array([tokens]))[0]
+
+        if self.pooling == "last":
+            embedding = hidden[-1]
+        elif self.pooling == "cls":
+            embedding = hidden[0]
+        else:
+            embedding = mx.mean(hidden, axis=0)
+        if self.normalize:
+            embedding = embedding / mx.linalg.norm(embedding)
+
+        return backend_pb2.EmbeddingResult(embeddings=embedding.astype(mx.float32).tolist())
+
+    def TokenizeString(self, request, context):
+        """
+        Tokenizes the prompt of the request.
+
+        Args:
+            request: The predict request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.TokenizationResponse: The tokens.
+        """
+        tokens = self.tokenizer.encode(request.Prompt)
+        return backend_pb2.TokenizationResponse(length=len(tokens), tokens=tokens)
+
+    def Status(self, request, context):
+        """
+        Returns the state of the backend, with the Metal memory used by MLX.
+
+        Args:
+            request: The health check request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.StatusResponse: The status.
+        """
+        active, cache, peak = metal_memory("active"), metal_memory("cache"), metal_memory("peak")
+        state = backend_pb2.StatusResponse.READY if hasattr(self, "model") else backend_pb2.StatusResponse.UNINITIALIZED
+        return backend_pb2.StatusResponse(
+            state=state,
+            memory=backend_pb2.MemoryUsageData(
+                total=active + cache,
+                breakdown={"metal-active": active, "metal-cache": cache, "metal-peak": peak},
+            ),
+        )
+
+def serve(address):
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
+    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
+    server.add_insecure_port(address)
+    server.start()
+    print("Server started. Listening on: " + address, file=sys.stderr)
+
+    # Define the signal handler function
+    def signal_handler(sig, frame):
+        print("Received termination signal. Shutting down...")
+        server.stop(0)
+        sys.exit(0)
+
+    # Set the signal handlers for SIGINT and SIGTERM
+    signal.signal(signal.SIGINT, signal_handler)
+    signal.signal(signal.SIGTERM, signal_handler)
+
+    try:
+        while True:
+            time.sleep(_ONE_DAY_IN_SECONDS)
+    except KeyboardInterrupt:
+        server.stop(0)
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description="Run the gRPC server.")
+    parser.add_argument(
+        "--addr", default="localhost:50051", help="The address to bind the server to."
+    )
+    args = parser.parse_args()
+
+    serve(args.addr)
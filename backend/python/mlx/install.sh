@@ -0,0 +1,12 @@
+#!/bin/bash
+set -e
+
+# MLX runs on the GPUs of Apple Silicon only, with Metal
+if [ "$(uname -s)" != "Darwin" ] || [ "$(uname -m)" != "arm64" ]; then
+    echo "mlx can only be used on Apple Silicon"
+    exit 0
+fi
+
+source $(dirname $0)/../common/libbackend.sh
+
+installRequirements
@@ -0,0 +1,110 @@
+import unittest
+import subprocess
+import time
+
+import grpc
+import backend_pb2
+import backend_pb2_grpc
+
+MODEL = "mlx-community/Qwen2.5-0.5B-Instruct-4bit"
+
+class TestBackendServicer(unittest.TestCase):
+    """
+    TestBackendServicer is the class that tests the gRPC service.
+
+    This class contains methods to test the startup and shutdown of the gRPC service.
+    """
+    def setUp(self):
+        self.service = subprocess.Popen(["python", "backend.py", "--addr", "localhost:50051"])
+        time.sleep(10)
+
+    def tearDown(self) -> None:
+        self.service.terminate()
+        self.service.wait()
+
+    def test_server_startup(self):
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.Health(backend_pb2.HealthMessage())
+                self.assertEqual(response.message, b'OK')
+        except Exception as err:
+            print(err)
+            self.fail("Server failed to start")
+        finally:
+            self.tearDown()
+
+    def test_load_model(self):
+        """
+        This method tests if the model is loaded successfully
+        """
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.LoadModel(backend_pb2.ModelOptions(Model=MODEL))
+                self.assertTrue(response.success)
+                self.assertEqual(response.message, "Model loaded successfully")
+        except Exception as err:
+            print(err)
+            self.fail("LoadModel service failed")
+        finally:
+            self.tearDown()
+
+    def test_text(self):
+        """
+        This method tests if the text is generated successfully, and the stop words end it
+        """
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.LoadModel(backend_pb2.ModelOptions(Model=MODEL))
+                self.assertTrue(response.success)
+                resp = stub.Predict(backend_pb2.PredictOptions(Prompt="The capital of France is", Tokens=16, StopPrompts=["."]))
+                self.assertTrue(resp.message)
+                self.assertNotIn(b".", resp.message)
+        except Exception as err:
+            print(err)
+            self.fail("text service failed")
+        finally:
+            self.tearDown()
+
+    def test_embedding(self):
+        """
+        This method tests if the embeddings are generated successfully
+        """
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.LoadModel(backend_pb2.ModelOptions(Model=MODEL, NormalizeEmbeddings=True))
+                self.assertTrue(response.success)
+                resp = stub.Embedding(backend_pb2.PredictOptions(Embeddings="This is a test sentence."))
+                self.assertGreater(len(resp.embeddings), 0)
+        except Exception as err:
+            print(err)
+            self.fail("Embedding service failed")
+        finally:
+            self.tearDown()
+
+    def test_status(self):
+        """
+        This method tests if the Metal memory of the model is reported
+        """
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.LoadModel(backend_pb2.ModelOptions(Model=MODEL))
+                self.assertTrue(response.success)
+                status = stub.Status(backend_pb2.HealthMessage())
+                self.assertEqual(status.state, backend_pb2.StatusResponse.READY)
+                self.assertGreater(status.memory.total, 0)
+                self.assertIn("metal-active", status.memory.breakdown)
+        except Exception as err:
+            print(err)
+            self.fail("Status service failed")
+        finally:
+            self.tearDown()
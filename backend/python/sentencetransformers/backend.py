@@ -5,6 +5,7 @@ Extra gRPC server for HuggingFace SentenceTransformer models.
 from concurrent import futures
 
 import argparse
+import base64
 import signal
 import sys
 import os
@@ -15,6 +16,8 @@ import backend_pb2_grpc
 
 import grpc
 
+from io import BytesIO
+from PIL import Image
 from sentence_transformers import SentenceTransformer
 
 _ONE_DAY_IN_SECONDS = 60 * 60 * 24
@@ -56,6 +59,7 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         model_name = request.Model
         try:
             self.model = SentenceTransformer(model_name)
+            self.normalize = request.NormalizeEmbeddings
         except Exception as err:
             return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
 
@@ -74,10 +78,16 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         Returns:
             An EmbeddingResult object that contains the calculated embeddings.
         """
+        # The multimodal models, as CLIP, embed the images and the texts in the same space
+        if request.EmbeddingImage:
+            image = Image.open(BytesIO(base64.b64decode(request.EmbeddingImage)))
+            print("Calculated embeddings for an image", file=sys.stderr)
+            return backend_pb2.EmbeddingResult(embeddings=self.model.encode(image, normalize_embeddings=self.normalize))
+
         # Implement your logic here for the Embedding service
         # Replace this with your desired response
         print("Calculated embeddings for: " + request.Embeddings, file=sys.stderr)
-        sentence_embeddings = self.model.encode(request.Embeddings)
+        sentence_embeddings = self.model.encode(request.Embeddings, normalize_embeddings=self.normalize)
         return backend_pb2.EmbeddingResult(embeddings=sentence_embeddings)
 
 
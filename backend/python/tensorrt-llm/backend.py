@@ -0,0 +1,279 @@
+#!/usr/bin/env python3
+from concurrent import futures
+import time
+import argparse
+import signal
+import sys
+import os
+import re
+import json
+import glob
+
+import backend_pb2
+import backend_pb2_grpc
+
+import grpc
+
+import torch
+import tensorrt_llm
+from tensorrt_llm.runtime import ModelRunner
+from transformers import AutoTokenizer
+
+_ONE_DAY_IN_SECONDS = 60 * 60 * 24
+
+# If MAX_WORKERS are specified in the environment use it, otherwise default to 1
+MAX_WORKERS = int(os.environ.get('PYTHON_GRPC_MAX_WORKERS', '1'))
+
+# The error of TensorRT when the engine was built on a GPU of another architecture, as
+# "expecting compute 8.9 got compute 8.6"
+_INCOMPATIBLE_DEVICE = re.compile(r"expecting compute (\d+\.\d+) got compute (\d+\.\d+)")
+
+def gpu_name():
+    """
+    Returns the name and the compute capability of the GPU, as "NVIDIA A10G (compute capability 8.6)".
+    """
+    major, minor = torch.cuda.get_device_capability()
+    return f"{torch.cuda.get_device_name()} (compute capability {major}.{minor})"
+
+def check_engine(engine_dir):
+    """
+    Checks that the directory is a TensorRT-LLM engine which runs on this machine, and returns
+    its configuration, or raises an error telling how to build a compatible engine.
+    """
+    config_file = os.path.join(engine_dir, "config.json")
+    if not os.path.isdir(engine_dir) or not os.path.exists(config_file):
+        raise ValueError(f"{engine_dir} is not a TensorRT-LLM engine directory, with the config.json and the rank0.engine built by trtllm-build")
+    if not glob.glob(os.path.join(engine_dir, "rank*.engine")):
+        raise ValueError(f"{engine_dir} has no engine: build the checkpoint of the model with trtllm-build --checkpoint_dir <checkpoint> --output_dir {engine_dir}")
+
+    with open(config_file) as f:
+        config = json.load(f)
+
+    version = config.get("version")
+    if version and version != tensorrt_llm.__version__:
+        raise ValueError(f"the engine was built by TensorRT-LLM {version}, and the backend runs TensorRT-LLM {tensorrt_llm.__version__}: rebuild the engine with TensorRT-LLM {tensorrt_llm.__version__}")
+
+    world_size = config.get("pretrained_config", {}).get("mapping", {}).get("world_size", 1)
+    if world_size > 1:
+        raise ValueError(f"the engine is split on {world_size} GPUs, only the engines of a single GPU are supported: rebuild the engine with --tp_size 1 --pp_size 1")
+
+    if not torch.cuda.is_available():
+        raise ValueError("no CUDA GPU is available, TensorRT-LLM engines run on NVIDIA GPUs only")
+    return config
+
+def explain_engine_error(err):
+    """
+    Returns the error of loading the engine, explained when the engine was built for another GPU
+    architecture, as the engines only run on the GPUs of the architecture they were built on.
+    """
+    message = str(err)
+    match = _INCOMPATIBLE_DEVICE.search(message)
+    if match:
+        return f"the engine was built on a GPU of compute capability {match.group(1)}, and can't run on the {gpu_name()}: rebuild the engine with trtllm-build on this GPU"
+    if "incompatible device" in message or "Version tag does not match" in message:
+        return f"the engine was built for another GPU or TensorRT version than the {gpu_name()} and the TensorRT of the backend: rebuild the engine with trtllm-build on this machine ({message})"
+    return f"Unexpected {err=}, {type(err)=}"
+
+# Implement the BackendServicer class with the service methods
+class BackendServicer(backend_pb2_grpc.BackendServicer):
+    """
+    A gRPC servicer that implements the Backend service defined in backend.proto,
+    running the engines built by TensorRT-LLM on the NVIDIA GPUs.
+    """
+    def Health(self, request, context):
+        """
+        Returns a health check message.
+
+        Args:
+            request: The health check request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Reply: The health check reply.
+        """
+        return backend_pb2.Reply(message=bytes("OK", 'utf-8'))
+
+    def LoadModel(self, request, context):
+        """
+        Loads a TensorRT-LLM engine, from the directory built by trtllm-build, with the tokenizer of
+        the model, from the tokenizer option or from the engine directory.
+
+        Args:
+            request: The load model request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Result: The load model result.
+        """
+        engine_dir = request.Model
+        if request.ModelFile != "" and os.path.exists(request.ModelFile):
+            engine_dir = request.ModelFile
+
+        try:
+            config = check_engine(engine_dir)
+        except ValueError as err:
+            return backend_pb2.Result(success=False, message=str(err))
+
+        try:
+            self.tokenizer = AutoTokenizer.from_pretrained(request.Tokenizer or engine_dir, trust_remote_code=request.TrustRemoteCode)
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"loading the tokenizer, set it with the tokenizer of the model if the engine directory has none: {err}")
+
+        try:
+            self.runner = ModelRunner.from_dir(engine_dir=engine_dir, rank=0)
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=explain_engine_error(err))
+
+        build_config = config.get("build_config", {})
+        self.max_input_len = build_config.get("max_input_len", 0)
+        self.max_seq_len = build_config.get("max_seq_len", 0)
+        self.end_id = self.tokenizer.eos_token_id
+        self.pad_id = self.tokenizer.pad_token_id if self.tokenizer.pad_token_id is not None else self.end_id
+        return backend_pb2.Result(message="Model loaded successfully", success=True)
+
+    def prompt(self, request):
+        """
+        Returns the prompt of the request, templated by the tokenizer when LocalAI gives the messages.
+        """
+        if not request.Prompt and request.UseTokenizerTemplate and request.Messages:
+            messages = [{"role": m.role, "content": m.content} for m in request.Messages]
+            return self.tokenizer.apply_chat_template(messages, tokenize=False, add_generation_prompt=True)
+        return request.Prompt
+
+    def generate(self, request):
+        """
+        Generates the text of the request with the streaming of TensorRT-LLM, yielding its pieces
+        until the max tokens or a stop word.
+        """
+        input_ids = self.tokenizer.encode(self.prompt(request))
+        if self.max_input_len and len(input_ids) > self.max_input_len:
+            raise ValueError(f"the prompt has {len(input_ids)} tokens, more than the max_input_len {self.max_input_len} of the engine")
+
+        max_tokens = request.Tokens
+        if max_tokens == 0:
+            max_tokens = self.max_seq_len - len(input_ids) if self.max_seq_len else 2048
+        if self.max_seq_len:
+            max_tokens = min(max_tokens, self.max_seq_len - len(input_ids))
+
+        sampling = {}
+        if request.Temperature != 0:
+            sampling["temperature"] = request.Temperature
+        if request.TopK != 0:
+            sampling["top_k"] = request.TopK
+        if request.TopP != 0:
+            sampling["top_p"] = request.TopP
+        if request.Penalty != 0:
+            sampling["repetition_penalty"] = request.Penalty
+        if request.PresencePenalty != 0:
+            sampling["presence_penalty"] = request.PresencePenalty
+        if request.FrequencyPenalty != 0:
+            sampling["frequency_penalty"] = request.FrequencyPenalty
+        if request.Seed != 0:
+            sampling["random_seed"] = request.Seed
+
+        outputs = self.runner.generate(
+            [torch.tensor(input_ids, dtype=torch.int32)],
+            max_new_tokens=max_tokens,
+            end_id=self.end_id,
+            pad_id=self.pad_id,
+            streaming=True,
+            output_sequence_lengths=True,
+            return_dict=True,
+            **sampling,
+        )
+
+        generated = ""
+        for output in outputs:
+            length = int(output["sequence_lengths"][0][0])
+            tokens = output["output_ids"][0][0][len(input_ids):length].tolist()
+            text = self.tokenizer.decode(tokens, skip_special_tokens=True)
+            # the last token may be a part of a character, decoded with the next ones
+            if text.endswith("�") or len(text) <= len(generated):
+                continue
+            piece = text[len(generated):]
+            generated = text
+            stop = next((s for s in request.StopPrompts if s and s in generated), None)
+            if stop:
+                # the piece before the stop word, which may begin in the previous pieces
+                yield piece[:max(len(piece) - (len(generated) - generated.index(stop)), 0)]
+                return
+            yield piece
+
+    def Predict(self, request, context):
+        """
+        Generates text based on the given prompt and sampling parameters.
+
+        Args:
+            request: The predict request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Reply: The predict result.
+        """
+        try:
+            generated_text = "".join(self.generate(request))
+        except Exception as err:
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Unexpected {err=}, {type(err)=}")
+            return backend_pb2.Reply()
+        return backend_pb2.Reply(message=bytes(generated_text, encoding='utf-8'))
+
+    def PredictStream(self, request, context):
+        """
+        Generates text based on the given prompt and sampling parameters, and streams the results.
+
+        Args:
+            request: The predict stream request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.Reply: The predict stream results.
+        """
+        for text in self.generate(request):
+            yield backend_pb2.Reply(message=bytes(text, encoding='utf-8'))
+
+    def TokenizeString(self, request, context):
+        """
+        Tokenizes the prompt of the request.
+
+        Args:
+            request: The predict request.
+            context: The gRPC context.
+
+        Returns:
+            backend_pb2.TokenizationResponse: The tokens.
+        """
+        tokens = self.tokenizer.encode(request.Prompt)
+        return backend_pb2.TokenizationResponse(length=len(tokens), tokens=tokens)
+
+def serve(address):
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
+    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
+    server.add_insecure_port(address)
+    server.start()
+    print("Server started. Listening on: " + address, file=sys.stderr)
+
+    # Define the signal handler function
+    def signal_handler(sig, frame):
+        print("Received termination signal. Shutting down...")
+        server.stop(0)
+        sys.exit(0)
+
+    # Set the signal handlers for SIGINT and SIGTERM
+    signal.signal(signal.SIGINT, signal_handler)
+    signal.signal(signal.SIGTERM, signal_handler)
+
+    try:
+        while True:
+            time.sleep(_ONE_DAY_IN_SECONDS)
+    except KeyboardInterrupt:
+        server.stop(0)
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description="Run the gRPC server.")
+    parser.add_argument(
+        "--addr", default="localhost:50051", help="The address to bind the server to."
+    )
+    args = parser.parse_args()
+
+    serve(args.addr)
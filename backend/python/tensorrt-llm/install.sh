@@ -0,0 +1,9 @@
+#!/bin/bash
+set -e
+
+# the wheels of TensorRT-LLM are built for CUDA 12 only
+LIMIT_TARGETS="cublas12"
+
+source $(dirname $0)/../common/libbackend.sh
+
+installRequirements
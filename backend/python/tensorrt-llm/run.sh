@@ -0,0 +1,6 @@
+#!/bin/bash
+LIMIT_TARGETS="cublas12"
+
+source $(dirname $0)/../common/libbackend.sh
+
+startBackend $@
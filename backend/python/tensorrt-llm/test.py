@@ -0,0 +1,76 @@
+import unittest
+import subprocess
+import time
+import os
+import tempfile
+
+import grpc
+import backend_pb2
+import backend_pb2_grpc
+
+# The directory of an engine built with trtllm-build, with the tokenizer of its model
+ENGINE = os.environ.get("TENSORRT_LLM_ENGINE", "")
+
+class TestBackendServicer(unittest.TestCase):
+    """
+    TestBackendServicer is the class that tests the gRPC service.
+
+    This class contains methods to test the startup and shutdown of the gRPC service.
+    """
+    def setUp(self):
+        self.service = subprocess.Popen(["python", "backend.py", "--addr", "localhost:50051"])
+        time.sleep(10)
+
+    def tearDown(self) -> None:
+        self.service.terminate()
+        self.service.wait()
+
+    def test_server_startup(self):
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.Health(backend_pb2.HealthMessage())
+                self.assertEqual(response.message, b'OK')
+        except Exception as err:
+            print(err)
+            self.fail("Server failed to start")
+        finally:
+            self.tearDown()
+
+    def test_load_not_engine(self):
+        """
+        This method tests if loading a directory which is not an engine fails with a helpful error
+        """
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel, tempfile.TemporaryDirectory() as directory:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.LoadModel(backend_pb2.ModelOptions(Model=directory))
+                self.assertFalse(response.success)
+                self.assertIn("trtllm-build", response.message)
+        except Exception as err:
+            print(err)
+            self.fail("LoadModel service failed")
+        finally:
+            self.tearDown()
+
+    @unittest.skipIf(ENGINE == "", "TENSORRT_LLM_ENGINE is not set")
+    def test_text(self):
+        """
+        This method tests if the engine is loaded, and the text is generated successfully
+        """
+        try:
+            self.setUp()
+            with grpc.insecure_channel("localhost:50051") as channel:
+                stub = backend_pb2_grpc.BackendStub(channel)
+                response = stub.LoadModel(backend_pb2.ModelOptions(Model=ENGINE))
+                self.assertTrue(response.success)
+                self.assertEqual(response.message, "Model loaded successfully")
+                resp = stub.Predict(backend_pb2.PredictOptions(Prompt="The capital of France is", Tokens=16))
+                self.assertTrue(resp.message)
+        except Exception as err:
+            print(err)
+            self.fail("text service failed")
+        finally:
+            self.tearDown()
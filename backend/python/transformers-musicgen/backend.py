@@ -15,7 +15,7 @@ import backend_pb2_grpc
 
 import grpc
 
-from scipy.io.wavfile import write as write_wav
+from scipy.io.wavfile import read as read_wav, write as write_wav
 from transformers import AutoProcessor, MusicgenForConditionalGeneration
 
 _ONE_DAY_IN_SECONDS = 60 * 60 * 24
@@ -89,6 +89,50 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         return backend_pb2.Result(success=True)
 
 
+    def SoundGeneration(self, request, context):
+        model_name = request.model
+        if model_name == "":
+            return backend_pb2.Result(success=False, message="request.model is required")
+        try:
+            self.processor = AutoProcessor.from_pretrained(model_name)
+            self.model = MusicgenForConditionalGeneration.from_pretrained(model_name)
+
+            processor_args = {"padding": True, "return_tensors": "pt"}
+            if request.text != "":
+                processor_args["text"] = [request.text]
+            if request.HasField("src"):
+                # condition the generation on the given audio, e.g. a melody to continue
+                sample_rate, audio = read_wav(request.src)
+                if audio.ndim > 1:
+                    audio = audio.mean(axis=1)
+                processor_args["audio"] = audio
+                processor_args["sampling_rate"] = sample_rate
+
+            if "text" in processor_args or "audio" in processor_args:
+                inputs = self.processor(**processor_args)
+            else:
+                inputs = self.model.get_unconditional_inputs(num_samples=1)
+
+            # the audio encoder produces frame_rate tokens per second of audio
+            tokens = 256
+            if request.HasField("duration"):
+                tokens = int(request.duration * self.model.config.audio_encoder.frame_rate)
+
+            generate_args = {"max_new_tokens": tokens}
+            if request.HasField("temperature"):
+                generate_args["temperature"] = request.temperature
+            if request.HasField("sample"):
+                generate_args["do_sample"] = request.sample
+
+            audio_values = self.model.generate(**inputs, **generate_args)
+            print("[transformers-musicgen] SoundGeneration generated!", file=sys.stderr)
+            sampling_rate = self.model.config.audio_encoder.sampling_rate
+            write_wav(request.dst, rate=sampling_rate, data=audio_values[0, 0].numpy())
+            print("[transformers-musicgen] SoundGeneration saved to", request.dst, file=sys.stderr)
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
+        return backend_pb2.Result(success=True)
+
 def serve(address):
     server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
     backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
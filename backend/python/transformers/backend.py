@@ -186,6 +186,24 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
                                                                 export=True,
                                                                 device=device_map)
                 self.OV = True
+            elif request.Type == "AutoModelForSequenceClassification":
+                from transformers import AutoModelForSequenceClassification
+                self.model = AutoModelForSequenceClassification.from_pretrained(model_name,
+                                                                                trust_remote_code=request.TrustRemoteCode,
+                                                                                device_map=device_map,
+                                                                                torch_dtype=compute if self.CUDA else torch.float32)
+            elif request.Type == "AutoModelForSeq2SeqLM":
+                from transformers import AutoModelForSeq2SeqLM
+                self.model = AutoModelForSeq2SeqLM.from_pretrained(model_name,
+                                                                   trust_remote_code=request.TrustRemoteCode,
+                                                                   device_map=device_map,
+                                                                   torch_dtype=compute if self.CUDA else torch.float32)
+            elif request.Type == "AutoModelForTokenClassification":
+                from transformers import AutoModelForTokenClassification
+                self.model = AutoModelForTokenClassification.from_pretrained(model_name,
+                                                                             trust_remote_code=request.TrustRemoteCode,
+                                                                             device_map=device_map,
+                                                                             torch_dtype=compute if self.CUDA else torch.float32)
             else:
                 print("Automodel", file=sys.stderr)
                 self.model = AutoModel.from_pretrained(model_name, 
@@ -247,6 +265,85 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         sentence_embeddings = mean_pooling(model_output, encoded_input['attention_mask'])
         return backend_pb2.EmbeddingResult(embeddings=sentence_embeddings[0])
 
+    def ClassifyText(self, request, context):
+        """
+        A gRPC method that labels a text with a sequence classification model (type: AutoModelForSequenceClassification),
+        or with a zero-shot classifier, as the NLI models, among the candidate labels of the request.
+
+        Args:
+            request: A TextClassifyRequest object that contains the text, the candidate labels and top_k.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A ClassifyResponse object with the labels sorted by decreasing score.
+        """
+        from transformers import pipeline
+
+        try:
+            if request.labels:
+                classifier = pipeline("zero-shot-classification", model=self.model, tokenizer=self.tokenizer)
+                result = classifier(request.text, candidate_labels=list(request.labels))
+                labels = list(zip(result["labels"], result["scores"]))
+            else:
+                classifier = pipeline("text-classification", model=self.model, tokenizer=self.tokenizer)
+                result = classifier(request.text, top_k=None)
+                labels = sorted([(r["label"], r["score"]) for r in result], key=lambda r: r[1], reverse=True)
+        except Exception as err:
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Error classifying the text: {err}")
+            return backend_pb2.ClassifyResponse()
+
+        if request.top_k > 0:
+            labels = labels[:request.top_k]
+        return backend_pb2.ClassifyResponse(classifications=[backend_pb2.Classification(label=label, score=score) for label, score in labels])
+
+    def ExtractEntities(self, request, context):
+        """
+        A gRPC method that finds the named entities of a text with a token classification model (type: AutoModelForTokenClassification).
+
+        Args:
+            request: An EntitiesRequest object that contains the text.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            An EntitiesResponse object with the entities in the order of the text.
+        """
+        from transformers import pipeline
+
+        try:
+            ner = pipeline("token-classification", model=self.model, tokenizer=self.tokenizer, aggregation_strategy="simple")
+            result = ner(request.text)
+        except Exception as err:
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Error extracting the entities: {err}")
+            return backend_pb2.EntitiesResponse()
+
+        entities = [backend_pb2.Entity(label=e["entity_group"], text=e["word"], score=float(e["score"]), start=e["start"], end=e["end"]) for e in result]
+        return backend_pb2.EntitiesResponse(entities=entities)
+
+    def Translate(self, request, context):
+        """
+        A gRPC method that translates a text with a sequence to sequence model (type: AutoModelForSeq2SeqLM), as NLLB or M2M100.
+
+        Args:
+            request: A TranslateRequest object that contains the text, and the codes of the source and target languages.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A TranslateResponse object with the translated text.
+        """
+        from transformers import pipeline
+
+        try:
+            translator = pipeline("translation", model=self.model, tokenizer=self.tokenizer, src_lang=request.source, tgt_lang=request.target)
+            result = translator(request.text)
+        except Exception as err:
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Error translating the text: {err}")
+            return backend_pb2.TranslateResponse()
+
+        return backend_pb2.TranslateResponse(text=result[0]["translation_text"])
+
     async def _predict(self, request, context, streaming=False): 
         set_seed(request.Seed)
         if request.TopP < 0 or request.TopP > 1:
@@ -0,0 +1,186 @@
+#!/usr/bin/env python3
+"""
+Extra gRPC server for the computer vision models: object detection and image classification.
+"""
+from concurrent import futures
+
+import argparse
+import signal
+import sys
+import os
+
+import time
+import backend_pb2
+import backend_pb2_grpc
+
+import grpc
+import torch
+
+from PIL import Image
+
+_ONE_DAY_IN_SECONDS = 60 * 60 * 24
+
+# If MAX_WORKERS are specified in the environment use it, otherwise default to 1
+MAX_WORKERS = int(os.environ.get('PYTHON_GRPC_MAX_WORKERS', '1'))
+
+# Extensions of the models run by ultralytics, the other models are run by the transformers pipelines
+YOLO_EXTENSIONS = (".pt", ".onnx", ".torchscript", ".engine")
+
+# Labels returned by the classifiers when the request does not set top_k
+DEFAULT_TOP_K = 5
+
+# Implement the BackendServicer class with the service methods
+class BackendServicer(backend_pb2_grpc.BackendServicer):
+    """
+    A gRPC servicer for the backend service.
+
+    This class implements the gRPC methods for the backend service, including Health, LoadModel, Detect and Classify.
+    """
+    def Health(self, request, context):
+        """
+        A gRPC method that returns the health status of the backend service.
+
+        Args:
+            request: A HealthRequest object that contains the request parameters.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A Reply object that contains the health status of the backend service.
+        """
+        return backend_pb2.Reply(message=bytes("OK", 'utf-8'))
+
+    def LoadModel(self, request, context):
+        """
+        A gRPC method that loads a model into memory.
+
+        The YOLO models (.pt, .onnx...) are run by ultralytics, the other ones by the transformers pipeline
+        of their task: object-detection, zero-shot-object-detection, image-classification or
+        zero-shot-image-classification. The task is read from the model, unless it is set by its type.
+
+        Args:
+            request: A LoadModelRequest object that contains the request parameters.
+            context: A grpc.ServicerContext object that provides information about the RPC.
+
+        Returns:
+            A Result object that contains the result of the LoadModel operation.
+        """
+        self.yolo = None
+        self.pipe = None
+        try:
+            device = "cuda" if request.CUDA and torch.cuda.is_available() else "cpu"
+            path = request.ModelFile if os.path.exists(request.ModelFile) else request.Model
+            if path.endswith(YOLO_EXTENSIONS):
+                from ultralytics import YOLO
+                # The YOLO-World models are loaded as open vocabulary models
+                self.yolo = YOLO(path)
+                self.device = device
+            else:
+                from transformers import pipeline
+                self.pipe = pipeline(task=request.Type or None, model=request.Model, device=device)
+        except Exception as err:
+            return backend_pb2.Result(success=False, message=f"Unexpected {err=}, {type(err)=}")
+
+        return backend_pb2.Result(message="Model loaded successfully", success=True)
+
+    def Detect(self, request, context):
+        """
+        Returns the objects found in the image, with their box in pixels
+        """
+        labels = list(request.labels)
+        detections = []
+        if self.yolo is not None:
+            if labels and hasattr(self.yolo, "set_classes"):
+                self.yolo.set_classes(labels)
+            kwargs = {"conf": request.threshold} if request.threshold else {}
+            result = self.yolo.predict(request.src, device=self.device, verbose=False, **kwargs)[0]
+            if result.boxes is None:
+                return self.invalid(context, "the model is not a detection model", backend_pb2.DetectResponse())
+            boxes = result.boxes
+            for box, score, cls in zip(boxes.xyxy.tolist(), boxes.conf.tolist(), boxes.cls.tolist()):
+                detections.append(detection(result.names[int(cls)], score, *box))
+        else:
+            kwargs = {"threshold": request.threshold} if request.threshold else {}
+            if self.pipe.task == "zero-shot-object-detection":
+                if not labels:
+                    return self.invalid(context, "the model requires the labels to detect", backend_pb2.DetectResponse())
+                kwargs["candidate_labels"] = labels
+            elif self.pipe.task != "object-detection":
+                return self.invalid(context, "the model is not a detection model", backend_pb2.DetectResponse())
+            for r in self.pipe(Image.open(request.src).convert("RGB"), **kwargs):
+                box = r["box"]
+                detections.append(detection(r["label"], r["score"], box["xmin"], box["ymin"], box["xmax"], box["ymax"]))
+
+        # The models with a fixed vocabulary detect all their labels
+        if labels:
+            detections = [d for d in detections if d.label in labels]
+        return backend_pb2.DetectResponse(detections=detections)
+
+    def Classify(self, request, context):
+        """
+        Returns the labels of the image, sorted by decreasing score
+        """
+        labels = list(request.labels)
+        top_k = request.top_k
+        if self.yolo is not None:
+            result = self.yolo.predict(request.src, device=self.device, verbose=False)[0]
+            if result.probs is None:
+                return self.invalid(context, "the model is not a classification model", backend_pb2.ClassifyResponse())
+            classes = [(result.names[i], score) for i, score in enumerate(result.probs.data.tolist())]
+        else:
+            image = Image.open(request.src).convert("RGB")
+            if self.pipe.task == "zero-shot-image-classification":
+                if not labels:
+                    return self.invalid(context, "the model requires the candidate labels", backend_pb2.ClassifyResponse())
+                results = self.pipe(image, candidate_labels=labels)
+            elif self.pipe.task == "image-classification":
+                results = self.pipe(image, top_k=top_k or DEFAULT_TOP_K)
+            else:
+                return self.invalid(context, "the model is not a classification model", backend_pb2.ClassifyResponse())
+            classes = [(r["label"], r["score"]) for r in results]
+            if self.pipe.task == "zero-shot-image-classification" and not top_k:
+                top_k = len(classes)
+
+        classes.sort(key=lambda c: c[1], reverse=True)
+        return backend_pb2.ClassifyResponse(classifications=[
+            backend_pb2.Classification(label=label, score=score) for label, score in classes[:top_k or DEFAULT_TOP_K]
+        ])
+
+    def invalid(self, context, details, response):
+        context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+        context.set_details(details)
+        return response
+
+def detection(label, score, xmin, ymin, xmax, ymax):
+    return backend_pb2.Detection(label=label, score=score, x=xmin, y=ymin, width=xmax - xmin, height=ymax - ymin)
+
+def serve(address):
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
+    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
+    server.add_insecure_port(address)
+    server.start()
+    print("Server started. Listening on: " + address, file=sys.stderr)
+
+    # Define the signal handler function
+    def signal_handler(sig, frame):
+        print("Received termination signal. Shutting down...")
+        server.stop(0)
+        sys.exit(0)
+
+    # Set the signal handlers for SIGINT and SIGTERM
+    signal.signal(signal.SIGINT, signal_handler)
+    signal.signal(signal.SIGTERM, signal_handler)
+
+    try:
+        while True:
+            time.sleep(_ONE_DAY_IN_SECONDS)
+    except KeyboardInterrupt:
+        server.stop(0)
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description="Run the gRPC server.")
+    parser.add_argument(
+        "--addr", default="localhost:50051", help="The address to bind the server to."
+    )
+    args = parser.parse_args()
+
+    serve(args.addr)
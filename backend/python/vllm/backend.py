@@ -26,6 +26,10 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
     """
     A gRPC servicer that implements the Backend service defined in backend.proto.
     """
+    def __init__(self, engine_cli_args=None):
+        # vLLM engine arguments given on the command line, by the backend_args of the model
+        self.engine_cli_args = engine_cli_args or []
+
     def generate(self,prompt, max_new_tokens):
         """
         Generates text based on the given prompt and maximum number of new tokens.
@@ -102,6 +106,11 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         if request.MaxModelLen != 0:
             engine_args.max_model_len = request.MaxModelLen
 
+        try:
+            apply_engine_cli_args(engine_args, self.engine_cli_args)
+        except SystemExit:
+            return backend_pb2.Result(success=False, message=f"Invalid vLLM engine arguments: {' '.join(self.engine_cli_args)}")
+
         try:
             self.llm = AsyncLLMEngine.from_engine_args(engine_args)
         except Exception as err:
@@ -210,11 +219,24 @@ class BackendServicer(backend_pb2_grpc.BackendServicer):
         # Sending the final generated text
         yield backend_pb2.Reply(message=bytes(generated_text, encoding='utf-8'))
 
-async def serve(address):
+def apply_engine_cli_args(engine_args, argv):
+    """
+    Sets the vLLM engine arguments given on the command line, as "--max-num-seqs 16",
+    overriding the ones of the model options.
+    """
+    if not argv:
+        return
+    parser = AsyncEngineArgs.add_cli_args(argparse.ArgumentParser(add_help=False))
+    defaults = vars(parser.parse_args([]))
+    for name, value in vars(parser.parse_args(argv)).items():
+        if value != defaults.get(name) and hasattr(engine_args, name):
+            setattr(engine_args, name, value)
+
+async def serve(address, engine_cli_args):
     # Start asyncio gRPC server
     server = grpc.aio.server(migration_thread_pool=futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
     # Add the servicer to the server
-    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(), server)
+    backend_pb2_grpc.add_BackendServicer_to_server(BackendServicer(engine_cli_args), server)
     # Bind the server to the address
     server.add_insecure_port(address)
 
@@ -236,6 +258,7 @@ if __name__ == "__main__":
     parser.add_argument(
         "--addr", default="localhost:50051", help="The address to bind the server to."
     )
-    args = parser.parse_args()
+    # the other arguments are vLLM engine arguments
+    args, engine_cli_args = parser.parse_known_args()
 
-    asyncio.run(serve(args.addr))
\ No newline at end of file
+    asyncio.run(serve(args.addr, engine_cli_args))
\ No newline at end of file
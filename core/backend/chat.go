@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/functions"
+	"github.com/mudler/LocalAI/pkg/logging"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+// ChatPrompt renders the messages with the model templates, for the chat endpoint of the HTTP API, the gRPC
+// API and the pipelines alike. With shouldUseFn, the functions are rendered with the template of the functions.
+// When the model uses the tokenizer template, and no function is used, the prompt is left empty for the
+// messages to be templated by the backend.
+func ChatPrompt(ctx context.Context, messages []schema.Message, funcs functions.Functions, shouldUseFn bool, loader *model.ModelLoader, cfg config.BackendConfig, appConfig *config.ApplicationConfig) string {
+	var predInput string
+	l := logging.FromContext(ctx)
+
+	// A Jinja template renders the whole conversation, including the tools, unless a template is set for the functions
+	if cfg.TemplateConfig.Jinja != "" && !(shouldUseFn && cfg.TemplateConfig.Functions != "") {
+		var tools functions.Functions
+		if shouldUseFn {
+			tools = funcs
+		}
+		templated, err := JinjaChatPrompt(messages, tools, loader, cfg, appConfig)
+		if err == nil {
+			l.Debug().Msgf("Prompt (after templating): %s", templated)
+			return templated
+		}
+		l.Error().Err(err).Msg("error processing the messages with the jinja template, using the chat templates")
+	}
+
+	// If we are using the tokenizer template, we don't need to process the messages
+	// unless we are processing functions
+	if !cfg.TemplateConfig.UseTokenizerTemplate || shouldUseFn {
+		tokenizer := ModelTokenizer(loader, cfg, appConfig)
+		suppressConfigSystemPrompt := false
+		mess := []string{}
+		for messageIndex, i := range messages {
+			var content string
+			role := i.Role
+
+			// if function call, we might want to customize the role so we can display better that the "assistant called a json action"
+			// if an "assistant_function_call" role is defined, we use it, otherwise we use the role that is passed by in the request
+			if (i.FunctionCall != nil || i.ToolCalls != nil) && i.Role == "assistant" {
+				roleFn := "assistant_function_call"
+				r := cfg.Roles[roleFn]
+				if r != "" {
+					role = roleFn
+				}
+			}
+			r := cfg.Roles[role]
+			contentExists := i.Content != nil && i.StringContent != ""
+
+			fcall := i.FunctionCall
+			if len(i.ToolCalls) > 0 {
+				fcall = i.ToolCalls
+			}
+
+			// First attempt to populate content via a chat message specific template
+			if cfg.TemplateConfig.ChatMessage != "" {
+				chatMessageData := model.ChatMessageTemplateData{
+					SystemPrompt: cfg.SystemPrompt,
+					Role:         r,
+					RoleName:     role,
+					Content:      i.StringContent,
+					FunctionCall: fcall,
+					FunctionName: i.Name,
+					LastMessage:  messageIndex == (len(messages) - 1),
+					Function:     cfg.Grammar != "" && (messageIndex == (len(messages) - 1)),
+					MessageIndex: messageIndex,
+					Variables:    cfg.TemplateConfig.Variables,
+					Tokenizer:    tokenizer,
+				}
+				templatedChatMessage, err := loader.EvaluateTemplateForChatMessage(cfg.TemplateConfig.ChatMessage, chatMessageData)
+				if err != nil {
+					l.Error().Err(err).Interface("message", chatMessageData).Str("template", cfg.TemplateConfig.ChatMessage).Msg("error processing message with template, skipping")
+				} else {
+					if templatedChatMessage == "" {
+						l.Warn().Msgf("template \"%s\" produced blank output for %+v. Skipping!", cfg.TemplateConfig.ChatMessage, chatMessageData)
+						continue // TODO: This continue is here intentionally to skip over the line `mess = append(mess, content)` below, and to prevent the sprintf
+					}
+					l.Debug().Msgf("templated message for chat: %s", templatedChatMessage)
+					content = templatedChatMessage
+				}
+			}
+
+			marshalAnyRole := func(f any) {
+				j, err := json.Marshal(f)
+				if err == nil {
+					if contentExists {
+						content += "\n" + fmt.Sprint(r, " ", string(j))
+					} else {
+						content = fmt.Sprint(r, " ", string(j))
+					}
+				}
+			}
+			marshalAny := func(f any) {
+				j, err := json.Marshal(f)
+				if err == nil {
+					if contentExists {
+						content += "\n" + string(j)
+					} else {
+						content = string(j)
+					}
+				}
+			}
+			// If this model doesn't have such a template, or if that template fails to return a value, template at the message level.
+			if content == "" {
+				if r != "" {
+					if contentExists {
+						content = fmt.Sprint(r, i.StringContent)
+					}
+
+					if i.FunctionCall != nil {
+						marshalAnyRole(i.FunctionCall)
+					}
+					if i.ToolCalls != nil {
+						marshalAnyRole(i.ToolCalls)
+					}
+				} else {
+					if contentExists {
+						content = fmt.Sprint(i.StringContent)
+					}
+					if i.FunctionCall != nil {
+						marshalAny(i.FunctionCall)
+					}
+					if i.ToolCalls != nil {
+						marshalAny(i.ToolCalls)
+					}
+				}
+				// Special Handling: System. We care if it was printed at all, not the r branch, so check seperately
+				if contentExists && role == "system" {
+					suppressConfigSystemPrompt = true
+				}
+			}
+
+			mess = append(mess, content)
+		}
+
+		joinCharacter := "\n"
+		if cfg.TemplateConfig.JoinChatMessagesByCharacter != nil {
+			joinCharacter = *cfg.TemplateConfig.JoinChatMessagesByCharacter
+		}
+
+		predInput = strings.Join(mess, joinCharacter)
+		l.Debug().Msgf("Prompt (before templating): %s", predInput)
+
+		templateFile := ""
+
+		// A model can have a "file.bin.tmpl" file associated with a prompt template prefix
+		if loader.ExistsInModelPath(fmt.Sprintf("%s.tmpl", cfg.Model)) {
+			templateFile = cfg.Model
+		}
+
+		if cfg.TemplateConfig.Chat != "" && !shouldUseFn {
+			templateFile = cfg.TemplateConfig.Chat
+		}
+
+		if cfg.TemplateConfig.Functions != "" && shouldUseFn {
+			templateFile = cfg.TemplateConfig.Functions
+		}
+
+		if templateFile != "" {
+			templatedInput, err := loader.EvaluateTemplateForPrompt(model.ChatPromptTemplate, templateFile, model.PromptTemplateData{
+				SystemPrompt:         cfg.SystemPrompt,
+				SuppressSystemPrompt: suppressConfigSystemPrompt,
+				Input:                predInput,
+				Functions:            funcs,
+				Variables:            cfg.TemplateConfig.Variables,
+				Tokenizer:            tokenizer,
+			})
+			if err == nil {
+				predInput = templatedInput
+				l.Debug().Msgf("Template found, input modified to: %s", predInput)
+			} else {
+				l.Debug().Msgf("Template failed loading: %s", err.Error())
+			}
+		}
+
+		l.Debug().Msgf("Prompt (after templating): %s", predInput)
+		if shouldUseFn && cfg.Grammar != "" {
+			l.Debug().Msgf("Grammar: %+v", cfg.Grammar)
+		}
+	}
+	return predInput
+}
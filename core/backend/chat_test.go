@@ -0,0 +1,34 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatPromptFunctionCalls(t *testing.T) {
+	ml := model.NewModelLoader(t.TempDir())
+	appConfig := config.NewApplicationConfig()
+	cfg := config.BackendConfig{
+		Roles: map[string]string{
+			"user":                    "user: ",
+			"assistant":               "assistant: ",
+			"assistant_function_call": "call: ",
+		},
+	}
+	messages := []schema.Message{
+		{Role: "user", Content: "weather in Rome?", StringContent: "weather in Rome?"},
+		{Role: "assistant", FunctionCall: map[string]string{"name": "weather"}},
+	}
+
+	assert.Equal(t, "user: weather in Rome?\ncall:  {\"name\":\"weather\"}",
+		ChatPrompt(context.Background(), messages, nil, false, ml, cfg, appConfig))
+
+	// The messages are templated by the backend with the tokenizer template
+	cfg.TemplateConfig.UseTokenizerTemplate = true
+	assert.Empty(t, ChatPrompt(context.Background(), messages, nil, false, ml, cfg, appConfig))
+}
@@ -2,14 +2,54 @@ package backend
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/mudler/LocalAI/core/config"
 
 	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
 	model "github.com/mudler/LocalAI/pkg/model"
 )
 
 func ModelEmbedding(s string, tokens []int, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (func() ([]float32, error), error) {
+	return modelEmbedding(func(predictOptions *proto.PredictOptions) {
+		if len(tokens) > 0 {
+			embeds := []int32{}
+
+			for _, t := range tokens {
+				embeds = append(embeds, int32(t))
+			}
+			predictOptions.EmbeddingTokens = embeds
+			return
+		}
+		predictOptions.Embeddings = s
+	}, loader, backendConfig, appConfig)
+}
+
+// ModelImageEmbedding embeds the base64 encoded image with a multimodal embedding model, as CLIP
+func ModelImageEmbedding(image string, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (func() ([]float32, error), error) {
+	return modelEmbedding(func(predictOptions *proto.PredictOptions) {
+		predictOptions.EmbeddingImage = image
+	}, loader, backendConfig, appConfig)
+}
+
+// normalize scales the embedding to a unit L2 norm
+func normalize(embeds []float32) {
+	var sum float64
+	for _, v := range embeds {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range embeds {
+		embeds[i] /= norm
+	}
+}
+
+// modelEmbedding embeds the input set by setInput in the prediction options
+func modelEmbedding(setInput func(*proto.PredictOptions), loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (func() ([]float32, error), error) {
 	modelFile := backendConfig.Model
 
 	grpcOpts := gRPCModelOpts(backendConfig)
@@ -40,22 +80,7 @@ func ModelEmbedding(s string, tokens []int, loader *model.ModelLoader, backendCo
 	case grpc.Backend:
 		fn = func() ([]float32, error) {
 			predictOptions := gRPCPredictOpts(backendConfig, loader.ModelPath)
-			if len(tokens) > 0 {
-				embeds := []int32{}
-
-				for _, t := range tokens {
-					embeds = append(embeds, int32(t))
-				}
-				predictOptions.EmbeddingTokens = embeds
-
-				res, err := model.Embeddings(appConfig.Context, predictOptions)
-				if err != nil {
-					return nil, err
-				}
-
-				return res.Embeddings, nil
-			}
-			predictOptions.Embeddings = s
+			setInput(predictOptions)
 
 			res, err := model.Embeddings(appConfig.Context, predictOptions)
 			if err != nil {
@@ -83,6 +108,10 @@ func ModelEmbedding(s string, tokens []int, loader *model.ModelLoader, backendCo
 				break
 			}
 		}
+		// The backends normalizing the embeddings themselves get the same embeddings
+		if backendConfig.NormalizeEmbeddings {
+			normalize(embeds)
+		}
 		return embeds, nil
 	}, nil
 }
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"os"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/base"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+// fakeBackend is an embedded backend recording the requests it receives
+type fakeBackend struct {
+	base.Base
+	load    *pb.ModelOptions
+	predict *pb.PredictOptions
+	voices  *pb.ListVoicesRequest
+	tts     *pb.TTSRequest
+	sound   *pb.SoundGenerationRequest
+	// embeddings of the texts, and scores of the documents in the order the reranker returns them
+	embeddings map[string][]float32
+	rerank     []*pb.DocumentResult
+}
+
+func (f *fakeBackend) Load(opts *pb.ModelOptions) error {
+	f.load = opts
+	return nil
+}
+
+func (f *fakeBackend) Predict(opts *pb.PredictOptions) (string, error) {
+	f.predict = opts
+	return "Hello!", nil
+}
+
+func (f *fakeBackend) ListVoices(req *pb.ListVoicesRequest) (pb.ListVoicesResponse, error) {
+	f.voices = req
+	return pb.ListVoicesResponse{Voices: []*pb.Voice{{Id: "en-us.onnx", Name: "en-us", Languages: []string{"en"}}}}, nil
+}
+
+func (f *fakeBackend) TTS(req *pb.TTSRequest) error {
+	f.tts = req
+	return nil
+}
+
+func (f *fakeBackend) SoundGeneration(req *pb.SoundGenerationRequest) error {
+	f.sound = req
+	return os.WriteFile(req.Dst, []byte("RIFF"), 0600)
+}
+
+func (f *fakeBackend) Embeddings(opts *pb.PredictOptions) ([]float32, error) {
+	return f.embeddings[opts.Embeddings], nil
+}
+
+func (f *fakeBackend) Rerank(*pb.RerankRequest) (pb.RerankResult, error) {
+	return pb.RerankResult{Results: f.rerank}, nil
+}
+
+// provideBackend serves the fake backend under the given name, and returns a loader and a config using it
+func provideBackend(name string, llm grpc.LLM) (*model.ModelLoader, *config.ApplicationConfig) {
+	grpc.Provide(name, llm)
+	appConfig := config.NewApplicationConfig(
+		config.WithModelPath(GinkgoT().TempDir()),
+		config.WithAudioDir(GinkgoT().TempDir()),
+		config.WithExternalBackend(name, name),
+	)
+	return model.NewModelLoader(appConfig.ModelPath), appConfig
+}
+
+// defaultConfig returns the config of a model with the defaults set when the configs are loaded
+func defaultConfig(name string) config.BackendConfig {
+	cfg := config.BackendConfig{Name: name}
+	cfg.SetDefaults()
+	return cfg
+}
@@ -1,13 +1,47 @@
 package backend
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/mudler/LocalAI/core/config"
 
 	"github.com/mudler/LocalAI/pkg/grpc/proto"
 	model "github.com/mudler/LocalAI/pkg/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func ImageGeneration(height, width, mode, step, seed int, positive_prompt, negative_prompt, src, dst string, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (func() error, error) {
+// ImageParameters are the advanced parameters of the generation of the images. Their zero values keep
+// the ones of the model
+type ImageParameters struct {
+	CFGScale  float32
+	Scheduler string
+	ClipSkip  int
+	// BatchCount images are generated, at dst and at dst suffixed by their index (see utils.BatchFileName)
+	BatchCount int
+	Upscaler   string
+
+	// ControlNet guides the generation with ConditioningImage, a file as a pose, edges or a depth map
+	ControlNet        string
+	ConditioningImage string
+	ControlNetScale   float32
+	// IPAdapterImage is the file of the image prompt of the IP-Adapter of the model
+	IPAdapterImage string
+	IPAdapterScale float32
+}
+
+// ImageProgress reports the steps of the generation of an image, with its preview as PNG when the backend sends one
+type ImageProgress func(step, steps int, preview []byte)
+
+func ImageGeneration(height, width, mode, step, seed int, positive_prompt, negative_prompt, src, dst string, params ImageParameters, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (func() error, error) {
+	return ImageGenerationWithProgress(appConfig.Context, height, width, mode, step, seed, positive_prompt, negative_prompt, src, dst, params, 0, nil, loader, backendConfig, appConfig)
+}
+
+// ImageGenerationWithProgress generates the image as ImageGeneration until ctx is cancelled, reporting its steps
+// and a preview every previewInterval steps. The backends which do not stream the generation only report its
+// start and its end
+func ImageGenerationWithProgress(ctx context.Context, height, width, mode, step, seed int, positive_prompt, negative_prompt, src, dst string, params ImageParameters, previewInterval int, progress ImageProgress, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (func() error, error) {
 	threads := backendConfig.Threads
 	if *threads == 0 && appConfig.Threads != 0 {
 		threads = &appConfig.Threads
@@ -29,23 +63,61 @@ func ImageGeneration(height, width, mode, step, seed int, positive_prompt, negat
 		return nil, err
 	}
 
+	clipSkip := backendConfig.Diffusers.ClipSkip
+	if params.ClipSkip != 0 {
+		clipSkip = params.ClipSkip
+	}
+
 	fn := func() error {
-		_, err := inferenceModel.GenerateImage(
-			appConfig.Context,
-			&proto.GenerateImageRequest{
-				Height:           int32(height),
-				Width:            int32(width),
-				Mode:             int32(mode),
-				Step:             int32(step),
-				Seed:             int32(seed),
-				CLIPSkip:         int32(backendConfig.Diffusers.ClipSkip),
-				PositivePrompt:   positive_prompt,
-				NegativePrompt:   negative_prompt,
-				Dst:              dst,
-				Src:              src,
-				EnableParameters: backendConfig.Diffusers.EnableParameters,
-			})
-		return err
+		req := &proto.GenerateImageRequest{
+			Height:            int32(height),
+			Width:             int32(width),
+			Mode:              int32(mode),
+			Step:              int32(step),
+			Seed:              int32(seed),
+			CLIPSkip:          int32(clipSkip),
+			PositivePrompt:    positive_prompt,
+			NegativePrompt:    negative_prompt,
+			Dst:               dst,
+			Src:               src,
+			EnableParameters:  backendConfig.Diffusers.EnableParameters,
+			PreviewInterval:   int32(previewInterval),
+			CfgScale:          params.CFGScale,
+			Scheduler:         params.Scheduler,
+			BatchCount:        int32(params.BatchCount),
+			Upscaler:          params.Upscaler,
+			Controlnet:        params.ControlNet,
+			ConditioningImage: params.ConditioningImage,
+			ControlnetScale:   params.ControlNetScale,
+			IpAdapterImage:    params.IPAdapterImage,
+			IpAdapterScale:    params.IPAdapterScale,
+		}
+
+		if progress == nil {
+			_, err := inferenceModel.GenerateImage(ctx, req)
+			return err
+		}
+
+		res, err := inferenceModel.GenerateImageStream(ctx, req, func(p *proto.GenerateImageProgress) {
+			progress(int(p.Step), int(p.Steps), p.Preview)
+		})
+		if status.Code(err) == codes.Unimplemented {
+			// The backend does not stream the generation
+			progress(0, step, nil)
+			_, err = inferenceModel.GenerateImage(ctx, req)
+			if err == nil {
+				progress(step, step, nil)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		// The failures are sent in the result of the stream
+		if !res.Success {
+			return fmt.Errorf("could not generate the image: %s", res.Message)
+		}
+		return nil
 	}
 
 	return fn, nil
@@ -0,0 +1,47 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/mudler/LocalAI/core/config"
+
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+const defaultImageTransformBackend = "image-transform"
+
+// ImageTransform post-processes the image src into dst with the model, as upscaling it or removing its
+// background, as set by the operation of the request
+func ImageTransform(request *proto.ImageTransformRequest, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) error {
+	backend := backendConfig.Backend
+	if backend == "" {
+		backend = defaultImageTransformBackend
+	}
+
+	opts := modelOpts(backendConfig, appConfig, []model.Option{
+		model.WithBackendString(backend),
+		model.WithModel(backendConfig.Model),
+		model.WithContext(appConfig.Context),
+		model.WithAssetDir(appConfig.AssetsDestination),
+		model.WithLoadGRPCLoadModelOpts(gRPCModelOpts(backendConfig)),
+	})
+
+	transformModel, err := loader.BackendLoader(opts...)
+	if err != nil {
+		return err
+	}
+
+	if transformModel == nil {
+		return fmt.Errorf("could not load %s model", backend)
+	}
+
+	res, err := transformModel.ImageTransform(appConfig.Context, request)
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		return fmt.Errorf("could not transform the image: %s", res.Message)
+	}
+	return nil
+}
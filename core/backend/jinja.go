@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"encoding/json"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+
+	"github.com/mudler/LocalAI/pkg/functions"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+// JinjaChatPrompt renders the conversation with the Jinja chat template of the model. The messages and
+// the tools are passed to the template as the HuggingFace tokenizers do, and the generation prompt is added.
+// The custom variables of the model are also available
+func JinjaChatPrompt(messages []schema.Message, funcs functions.Functions, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (string, error) {
+	jinjaMessages := make([]interface{}, 0, len(messages))
+	for _, m := range messages {
+		jinjaMessages = append(jinjaMessages, jinjaMessage(m))
+	}
+
+	in := map[string]interface{}{}
+	for k, v := range backendConfig.TemplateConfig.Variables {
+		in[k] = v
+	}
+	in["messages"] = jinjaMessages
+	in["add_generation_prompt"] = true
+
+	if len(funcs) > 0 {
+		tools := functions.Tools{}
+		for _, f := range funcs {
+			tools = append(tools, functions.Tool{Type: "function", Function: f})
+		}
+		// The templates access the tools as dictionaries
+		var jinjaTools []interface{}
+		if err := remarshal(tools, &jinjaTools); err != nil {
+			return "", err
+		}
+		in["tools"] = jinjaTools
+	}
+
+	return loader.EvaluateJinjaTemplate(backendConfig.TemplateConfig.Jinja, in, ModelTokenizer(loader, backendConfig, appConfig))
+}
+
+func jinjaMessage(m schema.Message) map[string]interface{} {
+	content := m.StringContent
+	if s, ok := m.Content.(string); ok && content == "" {
+		content = s
+	}
+	message := map[string]interface{}{
+		"role":    m.Role,
+		"content": content,
+	}
+	if m.Name != "" {
+		message["name"] = m.Name
+	}
+
+	calls := []schema.FunctionCall{}
+	ids := []string{}
+	for _, tc := range m.ToolCalls {
+		calls = append(calls, tc.FunctionCall)
+		ids = append(ids, tc.ID)
+	}
+	// Legacy function calls are rendered as tool calls
+	if len(calls) == 0 && m.FunctionCall != nil {
+		fc := schema.FunctionCall{}
+		if err := remarshal(m.FunctionCall, &fc); err == nil && fc.Name != "" {
+			calls = append(calls, fc)
+			ids = append(ids, "")
+		}
+	}
+
+	if len(calls) > 0 {
+		toolCalls := []interface{}{}
+		for i, c := range calls {
+			// The templates expect the arguments as a dictionary, to encode them in their own format
+			var arguments interface{} = c.Arguments
+			args := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(c.Arguments), &args); err == nil {
+				arguments = args
+			}
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":   ids[i],
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":      c.Name,
+					"arguments": arguments,
+				},
+			})
+		}
+		message["tool_calls"] = toolCalls
+	}
+
+	return message
+}
+
+func remarshal(in, out interface{}) error {
+	dat, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(dat, out)
+}
@@ -7,7 +7,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
-	"unicode/utf8"
+	"time"
 
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/core/schema"
@@ -27,30 +27,60 @@ type LLMResponse struct {
 type TokenUsage struct {
 	Prompt     int
 	Completion int
+
+	// Timings in milliseconds: prompt processing and token generation are reported
+	// by the backends supporting it, the time to first token is measured by LocalAI
+	TimingPromptProcessing float64
+	TimingTokenGeneration  float64
+	TimingTimeToFirstToken float64
 }
 
-func ModelInference(ctx context.Context, s string, messages []schema.Message, images []string, loader *model.ModelLoader, c config.BackendConfig, o *config.ApplicationConfig, tokenCallback func(string, TokenUsage) bool) (func() (LLMResponse, error), error) {
-	modelFile := c.Model
+// loadInferenceModel loads the model of the text generation backend of the config
+func loadInferenceModel(loader *model.ModelLoader, c config.BackendConfig, o *config.ApplicationConfig) (grpc.Backend, error) {
 	threads := c.Threads
 	if *threads == 0 && o.Threads != 0 {
 		threads = &o.Threads
 	}
+	if c.Backend == config.VLLMBackend {
+		if err := c.ValidateVLLM(); err != nil {
+			return nil, fmt.Errorf("invalid vLLM options of the model %s: %w", c.Name, err)
+		}
+	}
 	grpcOpts := gRPCModelOpts(c)
 
-	var inferenceModel grpc.Backend
-	var err error
-
 	opts := modelOpts(c, o, []model.Option{
 		model.WithLoadGRPCLoadModelOpts(grpcOpts),
 		model.WithThreads(uint32(*threads)), // some models uses this to allocate threads during startup
 		model.WithAssetDir(o.AssetsDestination),
-		model.WithModel(modelFile),
+		model.WithModel(c.Model),
 		model.WithContext(o.Context),
 	})
 
 	if c.Backend != "" {
 		opts = append(opts, model.WithBackendString(c.Backend))
+		return loader.BackendLoader(opts...)
 	}
+	return loader.GreedyLoader(opts...)
+}
+
+// ModelHealthCheck loads the model of the config, if it is not loaded, and checks that its backend answers
+func ModelHealthCheck(ctx context.Context, loader *model.ModelLoader, c config.BackendConfig, o *config.ApplicationConfig) error {
+	inferenceModel, err := loadInferenceModel(loader, c, o)
+	if err != nil {
+		return err
+	}
+	alive, err := inferenceModel.HealthCheck(ctx)
+	if err != nil {
+		return err
+	}
+	if !alive {
+		return fmt.Errorf("backend of model %s is not healthy", c.Name)
+	}
+	return nil
+}
+
+func ModelInference(ctx context.Context, s string, messages []schema.Message, images []string, loader *model.ModelLoader, c config.BackendConfig, o *config.ApplicationConfig, tokenCallback func(string, TokenUsage) bool) (func() (LLMResponse, error), error) {
+	modelFile := c.Model
 
 	// Check if the modelFile exists, if it doesn't try to load it from the gallery
 	if o.AutoloadGalleries { // experimental
@@ -64,12 +94,7 @@ func ModelInference(ctx context.Context, s string, messages []schema.Message, im
 		}
 	}
 
-	if c.Backend == "" {
-		inferenceModel, err = loader.GreedyLoader(opts...)
-	} else {
-		inferenceModel, err = loader.BackendLoader(opts...)
-	}
-
+	inferenceModel, err := loadInferenceModel(loader, c, o)
 	if err != nil {
 		return nil, err
 	}
@@ -95,12 +120,20 @@ func ModelInference(ctx context.Context, s string, messages []schema.Message, im
 	// in GRPC, the backend is supposed to answer to 1 single token if stream is not supported
 	fn := func() (LLMResponse, error) {
 		opts := gRPCPredictOpts(c, loader.ModelPath)
+		if threads := loader.Threads(c.Model); threads > 0 {
+			opts.Threads = int32(threads)
+		}
+		defer loader.Running(c.Model)()
+		if slot := loader.Slot(c.Model, model.AffinityKey(ctx)); slot >= 0 {
+			opts.Slot = int32(slot + 1)
+		}
 		opts.Prompt = s
 		opts.Messages = protoMessages
 		opts.UseTokenizerTemplate = c.TemplateConfig.UseTokenizerTemplate
 		opts.Images = images
 
 		tokenUsage := TokenUsage{}
+		start := time.Now()
 
 		// check the per-model feature flag for usage, since tokenCallback may have a cost.
 		// Defaults to off as for now it is still experimental
@@ -126,23 +159,43 @@ func ModelInference(ctx context.Context, s string, messages []schema.Message, im
 		if tokenCallback != nil {
 			ss := ""
 
-			var partialRune []byte
-			err := inferenceModel.PredictStream(ctx, opts, func(chars []byte) {
-				partialRune = append(partialRune, chars...)
+			progressCallback := promptProgressCallback(ctx)
+			opts.PromptProgress = progressCallback != nil
 
-				for len(partialRune) > 0 {
-					r, size := utf8.DecodeRune(partialRune)
-					if r == utf8.RuneError {
-						// incomplete rune, wait for more bytes
-						break
-					}
-
-					tokenCallback(string(r), tokenUsage)
-					ss += string(r)
+			// The tokens are sent once they form complete characters, and up to the stop words, which may be
+			// split across the tokens
+			stream := utils.NewTokenStream(c.StopWords)
+			var predictedTokens int32
+			err := inferenceModel.PredictStream(ctx, opts, func(reply *proto.Reply) {
+				if tokenUsage.TimingTimeToFirstToken == 0 && len(reply.Message) > 0 {
+					tokenUsage.TimingTimeToFirstToken = float64(time.Since(start).Microseconds()) / 1000
+				}
+				if reply.TimingPromptProcessing > 0 {
+					tokenUsage.TimingPromptProcessing = reply.TimingPromptProcessing
+					tokenUsage.TimingTokenGeneration = reply.TimingTokenGeneration
+				}
+				if tokenUsage.Prompt == 0 && reply.PromptTokens > 0 {
+					tokenUsage.Prompt = int(reply.PromptTokens)
+				}
+				if reply.Tokens > 0 {
+					predictedTokens = reply.Tokens
+				}
+				if progressCallback != nil && reply.ProgressNPrompt > 0 {
+					progressCallback(schema.PromptProgress{NPast: int(reply.ProgressNPast), NPrompt: int(reply.ProgressNPrompt)})
+				}
 
-					partialRune = partialRune[size:]
+				if text := stream.Write(reply.Message); text != "" {
+					tokenCallback(text, tokenUsage)
+					ss += text
 				}
 			})
+			if text := stream.Flush(); text != "" {
+				tokenCallback(text, tokenUsage)
+				ss += text
+			}
+			if tokenUsage.Completion == 0 {
+				tokenUsage.Completion = int(predictedTokens)
+			}
 			return LLMResponse{
 				Response: ss,
 				Usage:    tokenUsage,
@@ -159,6 +212,9 @@ func ModelInference(ctx context.Context, s string, messages []schema.Message, im
 			if tokenUsage.Completion == 0 {
 				tokenUsage.Completion = int(reply.Tokens)
 			}
+			tokenUsage.TimingPromptProcessing = reply.TimingPromptProcessing
+			tokenUsage.TimingTokenGeneration = reply.TimingTokenGeneration
+			tokenUsage.TimingTimeToFirstToken = float64(time.Since(start).Microseconds()) / 1000
 			return LLMResponse{
 				Response: string(reply.Message),
 				Usage:    tokenUsage,
@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"gopkg.in/yaml.v3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inference", func() {
+	It("sends the messages to the mlx models rendering them with the template of their tokenizer", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("mlx", llm)
+
+		// the configuration of the documentation of the backend
+		cfg := config.BackendConfig{}
+		Expect(yaml.Unmarshal([]byte(`
+name: qwen2.5
+backend: mlx
+parameters:
+  model: mlx-community/Qwen2.5-7B-Instruct-4bit
+context_size: 8192
+template:
+  use_tokenizer_template: true
+`), &cfg)).To(Succeed())
+		cfg.SetDefaults()
+
+		predict, err := ModelInference(context.Background(), "", []schema.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "Hi"},
+		}, nil, ml, cfg, appConfig, nil)
+		Expect(err).ToNot(HaveOccurred())
+		res, err := predict()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Response).To(Equal("Hello!"))
+
+		Expect(llm.load.Model).To(Equal("mlx-community/Qwen2.5-7B-Instruct-4bit"))
+		Expect(llm.load.ContextSize).To(Equal(int32(8192)))
+		Expect(llm.predict.UseTokenizerTemplate).To(BeTrue())
+		Expect(llm.predict.Prompt).To(BeEmpty())
+		Expect(llm.predict.Messages).To(HaveLen(2))
+		Expect(llm.predict.Messages[0].Role).To(Equal("system"))
+		Expect(llm.predict.Messages[1].Content).To(Equal("Hi"))
+	})
+
+	It("loads the engines of the tensorrt-llm models with their tokenizer, and sends the sampling options", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("tensorrt-llm", llm)
+		Expect(os.Mkdir(filepath.Join(appConfig.ModelPath, "llama-3-8b-engine"), 0750)).To(Succeed())
+
+		// the configuration of the documentation of the backend, with sampling options
+		cfg := config.BackendConfig{}
+		Expect(yaml.Unmarshal([]byte(`
+name: llama-3-8b
+backend: tensorrt-llm
+parameters:
+  model: llama-3-8b-engine
+  tokenizer: meta-llama/Meta-Llama-3-8B-Instruct
+  temperature: 0.2
+  top_p: 0.9
+  top_k: 40
+  max_tokens: 64
+  seed: 7
+stopwords:
+- "<|eot_id|>"
+template:
+  use_tokenizer_template: true
+`), &cfg)).To(Succeed())
+		cfg.SetDefaults()
+
+		predict, err := ModelInference(context.Background(), "", []schema.Message{{Role: "user", Content: "Hi"}}, nil, ml, cfg, appConfig, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = predict()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(llm.load.ModelFile).To(Equal(filepath.Join(appConfig.ModelPath, "llama-3-8b-engine")))
+		Expect(llm.load.Tokenizer).To(Equal("meta-llama/Meta-Llama-3-8B-Instruct"))
+		Expect(llm.predict.Temperature).To(BeNumerically("~", 0.2, 1e-6))
+		Expect(llm.predict.TopP).To(BeNumerically("~", 0.9, 1e-6))
+		Expect(llm.predict.TopK).To(Equal(int32(40)))
+		Expect(llm.predict.Tokens).To(Equal(int32(64)))
+		Expect(llm.predict.Seed).To(Equal(int32(7)))
+		Expect(llm.predict.StopPrompts).To(Equal([]string{"<|eot_id|>"}))
+		Expect(llm.predict.Messages).To(HaveLen(1))
+	})
+
+	It("loads the embedding models with their pooling and their normalization", func() {
+		embeddings := true
+		cfg := defaultConfig("qwen2.5-embeddings")
+		cfg.Embeddings = &embeddings
+		cfg.Pooling = config.PoolingLast
+		cfg.NormalizeEmbeddings = true
+
+		opts := gRPCModelOpts(cfg)
+		Expect(opts.Embeddings).To(BeTrue())
+		Expect(opts.Pooling).To(Equal(config.PoolingLast))
+		Expect(opts.NormalizeEmbeddings).To(BeTrue())
+	})
+})
@@ -0,0 +1,47 @@
+package backend
+
+import (
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// LoadModel loads the model of the configuration in memory, as the first request to the model would
+func LoadModel(loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) error {
+	opts := loadOpts(backendConfig, appConfig)
+
+	var err error
+	if backendConfig.Backend == "" {
+		_, err = loader.GreedyLoader(opts...)
+	} else {
+		opts = append(opts, model.WithBackendString(backendConfig.Backend))
+		_, err = loader.BackendLoader(opts...)
+	}
+	return err
+}
+
+// ReloadModel loads the model of the configuration again if it is loaded, to pick up its updated files.
+// The running backend serves the model until the new one is ready. The models without a backend are
+// stopped then loaded again, as the backend able to load them is not known
+func ReloadModel(loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) error {
+	if backendConfig.Backend == "" {
+		if !loader.IsLoaded(backendConfig.Model) {
+			return nil
+		}
+		if err := loader.ShutdownModel(backendConfig.Model); err != nil {
+			return err
+		}
+		return LoadModel(loader, backendConfig, appConfig)
+	}
+	opts := append(loadOpts(backendConfig, appConfig), model.WithBackendString(backendConfig.Backend))
+	return loader.BackendReloader(opts...)
+}
+
+func loadOpts(backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) []model.Option {
+	return modelOpts(backendConfig, appConfig, []model.Option{
+		model.WithLoadGRPCLoadModelOpts(gRPCModelOpts(backendConfig)),
+		model.WithThreads(uint32(*backendConfig.Threads)),
+		model.WithAssetDir(appConfig.AssetsDestination),
+		model.WithModel(backendConfig.Model),
+		model.WithContext(appConfig.Context),
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/mudler/LocalAI/core/config"
 	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
 	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
 	"github.com/rs/zerolog/log"
 )
 
@@ -32,6 +33,72 @@ func modelOpts(c config.BackendConfig, so *config.ApplicationConfig, opts []mode
 		opts = append(opts, model.WithExternalBackend(k, v))
 	}
 
+	if ttl, ok := c.IdleTimeout(); ok {
+		opts = append(opts, model.WithIdleTimeout(ttl))
+	}
+
+	if c.WatchDog.BusyActions != nil || c.WatchDog.IdleActions != nil {
+		opts = append(opts, model.WithWatchDogActions(c.WatchDog.BusyActions, c.WatchDog.IdleActions))
+	}
+
+	if limits, err := c.Resources.Limits(); err != nil {
+		log.Warn().Err(err).Str("model", c.Name).Msg("the resources of the backend are not limited")
+	} else if !limits.IsZero() {
+		opts = append(opts, model.WithResources(limits))
+	}
+
+	if c.GPU != "" {
+		opts = append(opts, model.WithGPU(c.GPU))
+	}
+
+	if env, args, err := c.BackendProcess(so.ModelPath); err != nil {
+		log.Warn().Err(err).Str("model", c.Name).Msg("the environment and the arguments of the backend are not set")
+	} else if len(env) > 0 || len(args) > 0 {
+		opts = append(opts, model.WithBackendProcess(env, args))
+	}
+
+	if c.KVCachePath != "" {
+		opts = append(opts, model.WithKVCache(filepath.Join(so.ModelPath, c.KVCachePath)))
+	}
+
+	if c.PrioritySlots > 0 {
+		opts = append(opts, model.WithPrioritySlots(c.PrioritySlots))
+	}
+
+	if c.Warmup.Enabled() {
+		opts = append(opts, model.WithWarmup(warmupPredictOpts(c, so.ModelPath), c.Warmup.Embeddings))
+	}
+
+	if c.ThreadsTuning.Enabled {
+		candidates := c.ThreadsTuning.Candidates
+		if len(candidates) == 0 {
+			candidates = model.DefaultThreadsCandidates()
+		}
+		request := warmupPredictOpts(c, so.ModelPath)
+		request.Tokens = 32
+		if c.ThreadsTuning.Tokens > 0 {
+			request.Tokens = int32(c.ThreadsTuning.Tokens)
+		}
+		opts = append(opts, model.WithThreadsTuning(request, candidates))
+	}
+
+	return opts
+}
+
+// warmupPredictOpts is the request of the warmup of the model, with the parameters of the model
+func warmupPredictOpts(c config.BackendConfig, modelPath string) *pb.PredictOptions {
+	prompt := c.Warmup.Prompt
+	if prompt == "" {
+		prompt = "warmup"
+	}
+	tokens := c.Warmup.Tokens
+	if tokens <= 0 {
+		tokens = 1
+	}
+	opts := gRPCPredictOpts(c, modelPath)
+	opts.Prompt = prompt
+	opts.Embeddings = prompt
+	opts.Tokens = int32(tokens)
 	return opts
 }
 
@@ -63,6 +130,9 @@ func gRPCModelOpts(c config.BackendConfig) *pb.ModelOptions {
 		CLIPSubfolder:        c.Diffusers.ClipSubFolder,
 		CLIPSkip:             int32(c.Diffusers.ClipSkip),
 		ControlNet:           c.Diffusers.ControlNet,
+		IPAdapter:            c.Diffusers.IPAdapter,
+		IPAdapterSubfolder:   c.Diffusers.IPAdapterSubFolder,
+		IPAdapterWeightName:  c.Diffusers.IPAdapterWeightName,
 		ContextSize:          int32(*c.ContextSize),
 		Seed:                 getSeed(c),
 		NBatch:               int32(b),
@@ -91,6 +161,11 @@ func gRPCModelOpts(c config.BackendConfig) *pb.ModelOptions {
 		Type:                 c.ModelType,
 		RopeFreqScale:        c.RopeFreqScale,
 		NUMA:                 c.NUMA,
+		NUMAStrategy:         c.NUMAPolicy(),
+		ThreadsBatch:         int32(c.ThreadsBatch),
+		CPUAffinity:          cpuAffinity(c),
+		Pooling:              c.Pooling,
+		NormalizeEmbeddings:  c.NormalizeEmbeddings,
 		Embeddings:           *c.Embeddings,
 		LowVRAM:              *c.LowVRAM,
 		NGPULayers:           int32(*c.NGPULayers),
@@ -98,6 +173,14 @@ func gRPCModelOpts(c config.BackendConfig) *pb.ModelOptions {
 		MainGPU:              c.MainGPU,
 		Threads:              int32(*c.Threads),
 		TensorSplit:          c.TensorSplit,
+		// whisper.cpp
+		WhisperGPU:               c.Whisper.UseGPU(),
+		WhisperGPUDevice:         int32(c.Whisper.GPUDevice),
+		WhisperQuantization:      c.Whisper.Quantization,
+		WhisperBeamSize:          int32(c.Whisper.BeamSize),
+		WhisperNoSpeechThreshold: c.Whisper.NoSpeechThreshold,
+		WhisperVADThreshold:      c.Whisper.VADThreshold,
+		WhisperVADFreqThreshold:  c.Whisper.VADFreqThreshold,
 		// AutoGPTQ
 		ModelBaseName:    c.AutoGPTQ.ModelBaseName,
 		Device:           c.AutoGPTQ.Device,
@@ -108,6 +191,20 @@ func gRPCModelOpts(c config.BackendConfig) *pb.ModelOptions {
 	}
 }
 
+// cpuAffinity returns the CPUs the threads of the backend are pinned to: the CPUs of its NUMA node, or its
+// cpu_affinity
+func cpuAffinity(c config.BackendConfig) string {
+	if c.NUMANode == nil {
+		return c.CPUAffinity
+	}
+	cpus, err := xsysinfo.NUMANodeCPUs(*c.NUMANode)
+	if err != nil {
+		log.Warn().Err(err).Str("model", c.Name).Msg("the threads of the backend are not pinned to the NUMA node")
+		return ""
+	}
+	return cpus
+}
+
 func gRPCPredictOpts(c config.BackendConfig, modelPath string) *pb.PredictOptions {
 	promptCachePath := ""
 	if c.PromptCachePath != "" {
@@ -133,6 +230,7 @@ func gRPCPredictOpts(c config.BackendConfig, modelPath string) *pb.PredictOption
 		F16KV:               *c.F16,
 		DebugMode:           *c.Debug,
 		Grammar:             c.Grammar,
+		Suffix:              c.Suffix,
 		NegativePromptScale: c.NegativePromptScale,
 		RopeFreqBase:        c.RopeFreqBase,
 		RopeFreqScale:       c.RopeFreqScale,
@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/store"
+)
+
+// PipelineInput is the input of a pipeline: the text of the request, and the path of its audio file for the
+// transcription steps
+type PipelineInput struct {
+	Text  string
+	Audio string
+}
+
+// PipelineOutput is the output of a step of a pipeline: its text, and the path of the audio of the tts
+// steps, whose text is the one spoken. Tokens are the tokens used by the chat steps
+type PipelineOutput struct {
+	Text   string
+	Audio  string
+	Tokens int
+}
+
+// String returns the text of the output, as the templates of the steps print it
+func (o PipelineOutput) String() string {
+	return o.Text
+}
+
+// pipelineTemplateData is the data of the templates of the steps
+type pipelineTemplateData struct {
+	Input string
+	Steps map[string]PipelineOutput
+}
+
+// RunPipeline runs the steps of the pipeline, stage by stage, the steps of a stage concurrently, and returns
+// their outputs by name. configs are the configurations of the models of the steps, by model name, and
+// storeLoader the loader of the stores of the retrieve steps. The tokens of the output step are passed to
+// tokenCallback when it is a chat step, to stream them
+func RunPipeline(ctx context.Context, p config.Pipeline, input PipelineInput, configs map[string]config.BackendConfig, loader, storeLoader *model.ModelLoader, appConfig *config.ApplicationConfig, tokenCallback func(string, TokenUsage) bool) (map[string]PipelineOutput, error) {
+	stages, err := p.Stages()
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]PipelineOutput{}
+	for _, stage := range stages {
+		results := make([]PipelineOutput, len(stage))
+		errs := make([]error, len(stage))
+		var wg sync.WaitGroup
+		for i, step := range stage {
+			var callback func(string, TokenUsage) bool
+			if step.Name == p.Output {
+				callback = tokenCallback
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = runPipelineStep(ctx, step, input, outputs, configs[step.Model], loader, storeLoader, appConfig, callback)
+			}()
+		}
+		wg.Wait()
+
+		for i, step := range stage {
+			if errs[i] != nil {
+				return outputs, fmt.Errorf("step %s: %w", step.Name, errs[i])
+			}
+			outputs[step.Name] = results[i]
+		}
+		if err := ctx.Err(); err != nil {
+			return outputs, err
+		}
+	}
+	return outputs, nil
+}
+
+// PipelineTokens returns the tokens used by the steps of a pipeline
+func PipelineTokens(outputs map[string]PipelineOutput) int {
+	tokens := 0
+	for _, o := range outputs {
+		tokens += o.Tokens
+	}
+	return tokens
+}
+
+func runPipelineStep(ctx context.Context, step config.PipelineStep, input PipelineInput, outputs map[string]PipelineOutput, cfg config.BackendConfig, loader, storeLoader *model.ModelLoader, appConfig *config.ApplicationConfig, tokenCallback func(string, TokenUsage) bool) (PipelineOutput, error) {
+	data := pipelineTemplateData{Input: input.Text, Steps: outputs}
+	text, err := pipelineStepInput(step, input, data)
+	if err != nil {
+		return PipelineOutput{}, err
+	}
+	if text == "" {
+		if step.Type == config.PipelineTranscription {
+			return PipelineOutput{}, errors.New("no audio to transcribe: upload an audio file, or transcribe the audio of a tts step")
+		}
+		return PipelineOutput{}, errors.New("the input of the step is empty")
+	}
+
+	switch step.Type {
+	case config.PipelineTranscription:
+		tr, err := ModelTranscription(ctx, text, step.Language, "", false, false, false, loader, cfg, appConfig)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		return PipelineOutput{Text: strings.TrimSpace(tr.Text)}, nil
+
+	case config.PipelineChat:
+		system, err := renderPipelineTemplate(step.Name, step.System, data)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		messages := []schema.Message{}
+		if system != "" {
+			messages = append(messages, schema.Message{Role: "system", Content: system, StringContent: system})
+		}
+		messages = append(messages, schema.Message{Role: "user", Content: text, StringContent: text})
+
+		prompt := ChatPrompt(ctx, messages, nil, false, loader, cfg, appConfig)
+		predFunc, err := ModelInference(ctx, prompt, messages, nil, loader, cfg, appConfig, tokenCallback)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		prediction, err := predFunc()
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		return PipelineOutput{Text: Finetune(cfg, prompt, prediction.Response), Tokens: prediction.Usage.Prompt + prediction.Usage.Completion}, nil
+
+	case config.PipelineRetrieve:
+		embedFn, err := ModelEmbedding(text, []int{}, loader, cfg, appConfig)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		embedding, err := embedFn()
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		sb, err := StoreBackend(storeLoader, appConfig, step.Store)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		topK := step.TopK
+		if topK == 0 {
+			topK = config.DefaultPipelineTopK
+		}
+		_, values, _, err := store.Find(ctx, sb, embedding, topK)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		texts := []string{}
+		for _, v := range values {
+			texts = append(texts, string(v))
+		}
+		return PipelineOutput{Text: strings.Join(texts, "\n\n")}, nil
+
+	case config.PipelineTranslation:
+		translated, err := Translation(text, step.Source, step.Language, loader, cfg, appConfig)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		return PipelineOutput{Text: translated}, nil
+
+	case config.PipelineTTS:
+		voice, language := cfg.Voice, cfg.Language
+		if step.Voice != "" {
+			voice = step.Voice
+		}
+		if step.Language != "" {
+			language = step.Language
+		}
+		audio, _, err := ModelTTS(cfg.Backend, text, cfg.Model, voice, language, loader, appConfig, cfg)
+		if err != nil {
+			return PipelineOutput{}, err
+		}
+		return PipelineOutput{Text: text, Audio: audio}, nil
+	}
+	return PipelineOutput{}, fmt.Errorf("unknown step type %q", step.Type)
+}
+
+// pipelineStepInput returns the input of the step: its template, or by default the output of the last step
+// it needs, or the input of the request. The input of the transcription steps is the path of an audio file
+func pipelineStepInput(step config.PipelineStep, input PipelineInput, data pipelineTemplateData) (string, error) {
+	if step.Input != "" {
+		return renderPipelineTemplate(step.Name, step.Input, data)
+	}
+	if len(step.Needs) > 0 {
+		last := data.Steps[step.Needs[len(step.Needs)-1]]
+		if step.Type == config.PipelineTranscription {
+			return last.Audio, nil
+		}
+		return last.Text, nil
+	}
+	if step.Type == config.PipelineTranscription {
+		return input.Audio, nil
+	}
+	return input.Text, nil
+}
+
+func renderPipelineTemplate(name, text string, data pipelineTemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,23 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/mudler/LocalAI/core/schema"
+)
+
+type promptProgressKey struct{}
+
+// WithPromptProgress returns a context whose streamed inferences report the progress of the prompt
+// evaluation to the callback, before the first token, with the backends supporting it
+func WithPromptProgress(ctx context.Context, callback func(schema.PromptProgress)) context.Context {
+	return context.WithValue(ctx, promptProgressKey{}, callback)
+}
+
+func promptProgressCallback(ctx context.Context) func(schema.PromptProgress) {
+	if ctx == nil {
+		return nil
+	}
+	callback, _ := ctx.Value(promptProgressKey{}).(func(schema.PromptProgress))
+	return callback
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+// Methods computing the similarity of a query and a text
+const (
+	// SimilarityCosine compares the embeddings of the query and of the texts, with a bi-encoder
+	SimilarityCosine = "cosine"
+	// SimilarityCrossEncoder scores the query and each text together, with a reranker
+	SimilarityCrossEncoder = "cross-encoder"
+)
+
+// DefaultSimilarityMethod is cosine for the embedding models, and cross-encoder for the others, as the rerankers
+func DefaultSimilarityMethod(c config.BackendConfig) string {
+	if c.Embeddings != nil && *c.Embeddings {
+		return SimilarityCosine
+	}
+	return SimilarityCrossEncoder
+}
+
+// Similarity returns the score of each text for the query, in the order of the texts
+func Similarity(method, query string, texts []string, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) ([]float32, error) {
+	switch method {
+	case SimilarityCosine:
+		return cosineScores(query, texts, loader, backendConfig, appConfig)
+	case SimilarityCrossEncoder:
+		res, err := Rerank(backendConfig.Backend, backendConfig.Model, &proto.RerankRequest{
+			Query:     query,
+			Documents: texts,
+			TopN:      int32(len(texts)),
+		}, loader, appConfig, backendConfig)
+		if err != nil {
+			return nil, err
+		}
+		// The reranker sorts the texts by score
+		scores := make([]float32, len(texts))
+		for _, r := range res.Results {
+			if int(r.Index) < 0 || int(r.Index) >= len(texts) {
+				return nil, fmt.Errorf("the reranker returned the unknown text %d", r.Index)
+			}
+			scores[r.Index] = r.RelevanceScore
+		}
+		return scores, nil
+	default:
+		return nil, fmt.Errorf("unknown similarity method %q, expected %s or %s", method, SimilarityCosine, SimilarityCrossEncoder)
+	}
+}
+
+func cosineScores(query string, texts []string, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) ([]float32, error) {
+	embed := func(s string) ([]float32, error) {
+		embedFn, err := ModelEmbedding(s, []int{}, loader, backendConfig, appConfig)
+		if err != nil {
+			return nil, err
+		}
+		return embedFn()
+	}
+
+	q, err := embed(query)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float32, len(texts))
+	for i, text := range texts {
+		t, err := embed(text)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = cosine(q, t)
+	}
+	return scores, nil
+}
+
+// cosine is the cosine similarity of the embeddings. The trailing zeros dropped from the embeddings are
+// ignored, as they do not change the similarity
+func cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	for _, v := range a {
+		normA += float64(v) * float64(v)
+	}
+	for _, v := range b {
+		normB += float64(v) * float64(v)
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Similarity", func() {
+	texts := []string{"a cat", "a dog", "a car"}
+
+	It("scores the texts with the cosine of their embeddings", func() {
+		llm := &fakeBackend{embeddings: map[string][]float32{
+			"kitten": {1, 0},
+			"a cat":  {2, 0},
+			"a dog":  {1, 1},
+			"a car":  {0, 3},
+		}}
+		ml, appConfig := provideBackend("similarity-cosine", llm)
+		cfg := defaultConfig("minilm")
+		cfg.Backend = "similarity-cosine"
+		cfg.Model = "minilm"
+
+		scores, err := Similarity(SimilarityCosine, "kitten", texts, ml, cfg, appConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scores).To(HaveLen(3))
+		Expect(scores[0]).To(BeNumerically("~", 1, 1e-6))
+		Expect(scores[1]).To(BeNumerically("~", 0.7071, 1e-4))
+		Expect(scores[2]).To(BeZero())
+	})
+
+	It("scores the texts with a cross-encoder in the order of the texts", func() {
+		llm := &fakeBackend{rerank: []*pb.DocumentResult{
+			{Index: 0, RelevanceScore: 0.9},
+			{Index: 2, RelevanceScore: 0.5},
+			{Index: 1, RelevanceScore: 0.1},
+		}}
+		ml, appConfig := provideBackend("similarity-rerank", llm)
+		cfg := defaultConfig("reranker")
+		cfg.Backend = "similarity-rerank"
+		cfg.Model = "reranker"
+
+		Expect(Similarity(SimilarityCrossEncoder, "kitten", texts, ml, cfg, appConfig)).To(Equal([]float32{0.9, 0.1, 0.5}))
+
+		llm.rerank = []*pb.DocumentResult{{Index: 3, RelevanceScore: 0.9}}
+		_, err := Similarity(SimilarityCrossEncoder, "kitten", texts, ml, cfg, appConfig)
+		Expect(err).To(MatchError("the reranker returned the unknown text 3"))
+	})
+
+	It("rejects the unknown methods", func() {
+		_, err := Similarity("euclidean", "kitten", texts, nil, defaultConfig("minilm"), nil)
+		Expect(err).To(MatchError(`unknown similarity method "euclidean", expected cosine or cross-encoder`))
+	})
+
+	It("uses the cosine for the embedding models by default", func() {
+		embeddings := true
+		cfg := defaultConfig("minilm")
+		cfg.Embeddings = &embeddings
+		Expect(DefaultSimilarityMethod(cfg)).To(Equal(SimilarityCosine))
+		Expect(DefaultSimilarityMethod(defaultConfig("reranker"))).To(Equal(SimilarityCrossEncoder))
+	})
+
+	It("ignores the trailing zeros dropped from the embeddings", func() {
+		Expect(cosine([]float32{1, 2, 0}, []float32{1, 2})).To(BeNumerically("~", 1, 1e-6))
+		Expect(cosine([]float32{0, 0}, []float32{1, 2})).To(BeZero())
+	})
+})
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sound generation", func() {
+	It("generates the audio in the audio directory", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("sound", llm)
+		duration := float32(5)
+		src := "/tmp/melody.wav"
+
+		filePath, res, err := SoundGeneration("sound", "musicgen", "drums", &duration, nil, nil, &src, ml, appConfig, defaultConfig("musicgen"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Success).To(BeTrue())
+		Expect(filepath.Dir(filePath)).To(Equal(appConfig.AudioDir))
+		Expect(os.ReadFile(filePath)).To(Equal([]byte("RIFF")))
+
+		Expect(llm.sound.Text).To(Equal("drums"))
+		Expect(llm.sound.Model).To(Equal("musicgen"))
+		Expect(*llm.sound.Duration).To(Equal(duration))
+		Expect(llm.sound.Temperature).To(BeNil())
+		Expect(*llm.sound.Src).To(Equal(src))
+	})
+
+	It("requires a backend", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("sound-no-backend", llm)
+
+		_, _, err := SoundGeneration("", "musicgen", "drums", nil, nil, nil, nil, ml, appConfig, defaultConfig("musicgen"))
+		Expect(err).To(MatchError("backend is a required parameter"))
+		Expect(llm.sound).To(BeNil())
+	})
+})
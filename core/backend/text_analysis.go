@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/mudler/LocalAI/core/config"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+const defaultTextAnalysisBackend = "transformers"
+
+// TextClassification returns the labels of the text by the sequence classification model, as a sentiment
+// analysis model, or by a zero-shot classifier among the candidate labels
+func TextClassification(request *proto.TextClassifyRequest, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*proto.ClassifyResponse, error) {
+	textModel, err := loadTextAnalysisModel(loader, backendConfig, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	return textModel.ClassifyText(appConfig.Context, request)
+}
+
+// EntityExtraction returns the named entities of the text found by the token classification model
+func EntityExtraction(request *proto.EntitiesRequest, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*proto.EntitiesResponse, error) {
+	textModel, err := loadTextAnalysisModel(loader, backendConfig, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	return textModel.ExtractEntities(appConfig.Context, request)
+}
+
+func loadTextAnalysisModel(loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (grpc.Backend, error) {
+	backend := backendConfig.Backend
+	if backend == "" {
+		backend = defaultTextAnalysisBackend
+	}
+
+	opts := modelOpts(backendConfig, appConfig, []model.Option{
+		model.WithBackendString(backend),
+		model.WithModel(backendConfig.Model),
+		model.WithContext(appConfig.Context),
+		model.WithAssetDir(appConfig.AssetsDestination),
+		model.WithLoadGRPCLoadModelOpts(gRPCModelOpts(backendConfig)),
+	})
+
+	textModel, err := loader.BackendLoader(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if textModel == nil {
+		return nil, fmt.Errorf("could not load %s model", backend)
+	}
+	return textModel, nil
+}
@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/rs/zerolog/log"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/templates"
+)
+
+// ModelTokenize returns the number of tokens of a prompt with the tokenizer of the model
+func ModelTokenize(s string, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (int, error) {
+	var inferenceModel grpc.Backend
+	var err error
+
+	opts := modelOpts(backendConfig, appConfig, []model.Option{
+		model.WithLoadGRPCLoadModelOpts(gRPCModelOpts(backendConfig)),
+		model.WithThreads(uint32(*backendConfig.Threads)),
+		model.WithAssetDir(appConfig.AssetsDestination),
+		model.WithModel(backendConfig.Model),
+		model.WithContext(appConfig.Context),
+	})
+
+	if backendConfig.Backend == "" {
+		inferenceModel, err = loader.GreedyLoader(opts...)
+	} else {
+		opts = append(opts, model.WithBackendString(backendConfig.Backend))
+		inferenceModel, err = loader.BackendLoader(opts...)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	predictOptions := gRPCPredictOpts(backendConfig, loader.ModelPath)
+	predictOptions.Prompt = s
+
+	res, err := inferenceModel.TokenizeString(appConfig.Context, predictOptions)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.Length), nil
+}
+
+// ModelTokenizer returns the tokenizer of the model for the prompt templates. The tokens are
+// estimated if the backend of the model cannot tokenize
+func ModelTokenizer(loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) templates.Tokenizer {
+	return func(s string) (int, error) {
+		n, err := ModelTokenize(s, loader, backendConfig, appConfig)
+		if err != nil {
+			log.Debug().Err(err).Msgf("could not tokenize with %s, estimating the tokens", backendConfig.Name)
+			return templates.EstimateTokens(s)
+		}
+		return n, nil
+	}
+}
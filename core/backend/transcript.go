@@ -11,7 +11,12 @@ import (
 	model "github.com/mudler/LocalAI/pkg/model"
 )
 
-func ModelTranscription(audio, language string, translate bool, ml *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*schema.TranscriptionResult, error) {
+const defaultDiarizationBackend = "diarization"
+
+func ModelTranscription(ctx context.Context, audio, language, prompt string, translate, wordTimestamps, diarize bool, ml *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*schema.TranscriptionResult, error) {
+	if err := backendConfig.ValidateWhisper(); err != nil {
+		return nil, fmt.Errorf("invalid whisper options of the model %s: %w", backendConfig.Name, err)
+	}
 
 	opts := modelOpts(backendConfig, appConfig, []model.Option{
 		model.WithBackendString(model.WhisperBackend),
@@ -30,10 +35,82 @@ func ModelTranscription(audio, language string, translate bool, ml *model.ModelL
 		return nil, fmt.Errorf("could not load whisper model")
 	}
 
-	return whisperModel.AudioTranscription(context.Background(), &proto.TranscriptRequest{
-		Dst:       audio,
-		Language:  language,
-		Translate: translate,
-		Threads:   uint32(*backendConfig.Threads),
+	tr, err := whisperModel.AudioTranscription(ctx, &proto.TranscriptRequest{
+		Dst:            audio,
+		Language:       language,
+		Prompt:         prompt,
+		Translate:      translate,
+		WordTimestamps: wordTimestamps,
+		Threads:        uint32(*backendConfig.Threads),
+	})
+	if err != nil || !diarize {
+		return tr, err
+	}
+
+	turns, err := modelDiarization(ctx, audio, ml, backendConfig, appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed diarization: %w", err)
+	}
+	assignSpeakers(tr, turns)
+
+	return tr, nil
+}
+
+// modelDiarization runs the speaker segmentation model of the transcription model.
+// It returns the speaker turns as segments without text.
+func modelDiarization(ctx context.Context, audio string, ml *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) ([]schema.Segment, error) {
+	if backendConfig.Diarization.Model == "" {
+		return nil, fmt.Errorf("no diarization model configured for %s", backendConfig.Name)
+	}
+
+	backend := backendConfig.Diarization.Backend
+	if backend == "" {
+		backend = defaultDiarizationBackend
+	}
+
+	opts := modelOpts(backendConfig, appConfig, []model.Option{
+		model.WithBackendString(backend),
+		model.WithModel(backendConfig.Diarization.Model),
+		model.WithContext(appConfig.Context),
+		model.WithAssetDir(appConfig.AssetsDestination),
+	})
+
+	diarizationModel, err := ml.BackendLoader(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if diarizationModel == nil {
+		return nil, fmt.Errorf("could not load diarization model")
+	}
+
+	res, err := diarizationModel.AudioTranscription(ctx, &proto.TranscriptRequest{
+		Dst:     audio,
+		Diarize: true,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Segments, nil
+}
+
+// assignSpeakers labels each segment with the speaker talking the most during it
+func assignSpeakers(tr *schema.TranscriptionResult, turns []schema.Segment) {
+	for i, s := range tr.Segments {
+		talked := map[string]int64{}
+		for _, t := range turns {
+			overlap := min(s.End, t.End) - max(s.Start, t.Start)
+			if overlap > 0 {
+				talked[t.Speaker] += int64(overlap)
+			}
+		}
+
+		var most int64
+		for speaker, d := range talked {
+			if d > most || (d == most && speaker < tr.Segments[i].Speaker) {
+				tr.Segments[i].Speaker = speaker
+				most = d
+			}
+		}
+	}
 }
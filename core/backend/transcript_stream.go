@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/vad"
+	"github.com/rs/zerolog/log"
+)
+
+// StreamSampleRate is the sample rate of the audio sent to a TranscriptionStream
+const StreamSampleRate = 16000
+
+// TranscriptionStream transcribes live audio. The audio is split in utterances on
+// silences, and each utterance is transcribed as soon as it ends: segments are
+// returned with timestamps relative to the start of the stream.
+type TranscriptionStream struct {
+	language  string
+	onSegment func(schema.Segment)
+
+	ml            *model.ModelLoader
+	backendConfig config.BackendConfig
+	appConfig     *config.ApplicationConfig
+
+	segmenter  *vad.Segmenter
+	utterances chan vad.Utterance
+	wg         sync.WaitGroup
+	partial    []byte
+	nextID     int
+
+	sync.Mutex
+	err error
+}
+
+func NewTranscriptionStream(language string, onSegment func(schema.Segment), ml *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) *TranscriptionStream {
+	ts := &TranscriptionStream{
+		language:      language,
+		onSegment:     onSegment,
+		ml:            ml,
+		backendConfig: backendConfig,
+		appConfig:     appConfig,
+		utterances:    make(chan vad.Utterance, 16),
+	}
+	ts.segmenter = vad.NewSegmenter(StreamSampleRate, func(u vad.Utterance) {
+		ts.utterances <- u
+	})
+
+	ts.wg.Add(1)
+	go ts.transcribe()
+
+	return ts
+}
+
+// Write feeds the stream with mono, 16kHz, signed 16-bit little endian PCM audio.
+// It returns the error of a previous transcription, if any.
+func (ts *TranscriptionStream) Write(pcm []byte) error {
+	if err := ts.error(); err != nil {
+		return err
+	}
+
+	// Keep the odd byte of a sample split across writes
+	pcm = append(ts.partial, pcm...)
+	ts.partial = append([]byte{}, pcm[len(pcm)-len(pcm)%2:]...)
+	ts.segmenter.Write(vad.PCM16ToFloat32(pcm[:len(pcm)-len(pcm)%2]))
+	return nil
+}
+
+// Close transcribes the audio left and waits for all the segments to be returned
+func (ts *TranscriptionStream) Close() error {
+	ts.segmenter.Flush()
+	close(ts.utterances)
+	ts.wg.Wait()
+	return ts.error()
+}
+
+func (ts *TranscriptionStream) error() error {
+	ts.Lock()
+	defer ts.Unlock()
+	return ts.err
+}
+
+func (ts *TranscriptionStream) transcribe() {
+	defer ts.wg.Done()
+
+	dir, err := os.MkdirTemp("", "whisper-stream")
+	if err != nil {
+		ts.Lock()
+		ts.err = err
+		ts.Unlock()
+	}
+	defer os.RemoveAll(dir)
+
+	for u := range ts.utterances {
+		// Drain the utterances after a failure so writers don't block
+		if ts.error() != nil {
+			continue
+		}
+
+		if err := ts.transcribeUtterance(filepath.Join(dir, "utterance.wav"), u); err != nil {
+			log.Error().Err(err).Msg("failed transcribing audio stream")
+			ts.Lock()
+			ts.err = err
+			ts.Unlock()
+		}
+	}
+}
+
+func (ts *TranscriptionStream) transcribeUtterance(dst string, u vad.Utterance) error {
+	if err := writeWav(dst, u.Samples); err != nil {
+		return fmt.Errorf("failed writing audio: %w", err)
+	}
+
+	tr, err := ModelTranscription(ts.appConfig.Context, dst, ts.language, "", false, false, false, ts.ml, ts.backendConfig, ts.appConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range tr.Segments {
+		s.Id = ts.nextID
+		s.Start += u.Start
+		s.End += u.Start
+		ts.nextID++
+		ts.onSegment(s)
+	}
+	return nil
+}
+
+func writeWav(dst string, samples []float32) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data := make([]int, len(samples))
+	for i, s := range samples {
+		data[i] = int(s * 32767)
+	}
+
+	enc := wav.NewEncoder(f, StreamSampleRate, 16, 1, 1)
+	if err := enc.Write(&audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 1, SampleRate: StreamSampleRate},
+		Data:           data,
+		SourceBitDepth: 16,
+	}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
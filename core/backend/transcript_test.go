@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diarization", func() {
+	It("labels each segment with the speaker talking the most during it", func() {
+		tr := &schema.TranscriptionResult{
+			Segments: []schema.Segment{
+				{Start: 0, End: 4 * time.Second},
+				{Start: 4 * time.Second, End: 6 * time.Second},
+				{Start: 10 * time.Second, End: 12 * time.Second},
+			},
+		}
+		turns := []schema.Segment{
+			{Start: 0, End: 1 * time.Second, Speaker: "SPEAKER_01"},
+			{Start: 1 * time.Second, End: 5 * time.Second, Speaker: "SPEAKER_00"},
+			{Start: 5 * time.Second, End: 8 * time.Second, Speaker: "SPEAKER_01"},
+		}
+
+		assignSpeakers(tr, turns)
+
+		Expect(tr.Segments[0].Speaker).To(Equal("SPEAKER_00"))
+		// ties go to the first speaker by name, for stable labels
+		Expect(tr.Segments[1].Speaker).To(Equal("SPEAKER_00"))
+		// nobody talks during the last segment
+		Expect(tr.Segments[2].Speaker).To(BeEmpty())
+	})
+
+	It("requires a diarization model in the config of the transcription model", func() {
+		_, err := modelDiarization(context.Background(), "audio.wav", model.NewModelLoader(GinkgoT().TempDir()),
+			config.BackendConfig{Name: "whisper"}, config.NewApplicationConfig())
+		Expect(err).To(MatchError("no diarization model configured for whisper"))
+	})
+})
@@ -0,0 +1,32 @@
+package backend
+
+import (
+	"github.com/mudler/LocalAI/core/config"
+
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+// Translation translates the text from the source language to the target language with the translation
+// model, as NLLB or M2M100. The languages are mapped to the codes of the model
+func Translation(text, source, target string, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (string, error) {
+	textModel, err := loadTextAnalysisModel(loader, backendConfig, appConfig)
+	if err != nil {
+		return "", err
+	}
+	res, err := textModel.Translate(appConfig.Context, &proto.TranslateRequest{
+		Text:   text,
+		Source: backendConfig.Translation.Code(source),
+		Target: backendConfig.Translation.Code(target),
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// LanguageDetection returns the languages of the text by decreasing score, with the text classification model
+// whose labels are the languages
+func LanguageDetection(text string, topK int, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*proto.ClassifyResponse, error) {
+	return TextClassification(&proto.TextClassifyRequest{Text: text, TopK: int32(topK)}, loader, backendConfig, appConfig)
+}
@@ -2,6 +2,7 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/mudler/LocalAI/pkg/grpc/proto"
 	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/ssml"
 	"github.com/mudler/LocalAI/pkg/utils"
 )
 
@@ -29,6 +31,48 @@ func generateUniqueFileName(dir, baseName, ext string) string {
 	}
 }
 
+// ttsModelPath returns the model passed to the TTS backends: if the model file is
+// not empty, it is joined with the model path
+func ttsModelPath(modelFile string, loader *model.ModelLoader, appConfig *config.ApplicationConfig) (string, error) {
+	if modelFile == "" {
+		return "", nil
+	}
+
+	// Checking first that it exists and is not outside ModelPath
+	// TODO: we should actually first check if the modelFile is looking like
+	// a FS path
+	mp := filepath.Join(loader.ModelPath, modelFile)
+	if _, err := os.Stat(mp); err == nil {
+		if err := utils.VerifyPath(mp, appConfig.ModelPath); err != nil {
+			return "", err
+		}
+		return mp, nil
+	}
+	return modelFile, nil
+}
+
+// ttsSegments parses the text when it is SSML, and returns its text without the markup, for the backends
+// which ignore the markup, with its segments
+func ttsSegments(text string) (string, []*proto.TTSSegment, error) {
+	if !ssml.IsSSML(text) {
+		return text, nil, nil
+	}
+	doc, err := ssml.Parse(text)
+	if err != nil {
+		return "", nil, err
+	}
+	segments := []*proto.TTSSegment{}
+	for _, s := range doc.Segments {
+		segments = append(segments, &proto.TTSSegment{
+			Text:    s.Text,
+			BreakMs: int32(s.Break.Milliseconds()),
+			Rate:    float32(s.Rate),
+			Pitch:   float32(s.Pitch),
+		})
+	}
+	return doc.Text(), segments, nil
+}
+
 func ModelTTS(
 	backend,
 	text,
@@ -44,6 +88,11 @@ func ModelTTS(
 		bb = model.PiperBackend
 	}
 
+	text, segments, err := ttsSegments(text)
+	if err != nil {
+		return "", nil, err
+	}
+
 	grpcOpts := gRPCModelOpts(backendConfig)
 
 	opts := modelOpts(config.BackendConfig{}, appConfig, []model.Option{
@@ -69,22 +118,19 @@ func ModelTTS(
 	fileName := generateUniqueFileName(appConfig.AudioDir, "tts", ".wav")
 	filePath := filepath.Join(appConfig.AudioDir, fileName)
 
-	// If the model file is not empty, we pass it joined with the model path
-	modelPath := ""
-	if modelFile != "" {
-		// If the model file is not empty, we pass it joined with the model path
-		// Checking first that it exists and is not outside ModelPath
-		// TODO: we should actually first check if the modelFile is looking like
-		// a FS path
-		mp := filepath.Join(loader.ModelPath, modelFile)
-		if _, err := os.Stat(mp); err == nil {
-			if err := utils.VerifyPath(mp, appConfig.ModelPath); err != nil {
-				return "", nil, err
-			}
-			modelPath = mp
-		} else {
-			modelPath = modelFile
+	modelPath, err := ttsModelPath(modelFile, loader, appConfig)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Voices registered with a reference audio are cloned by the backend
+	voiceSample := ""
+	if voice != "" {
+		sample, err := ClonedVoice(voice, appConfig)
+		if err != nil && !errors.Is(err, ErrVoiceNotFound) {
+			return "", nil, err
 		}
+		voiceSample = sample
 	}
 
 	res, err := ttsModel.TTS(context.Background(), &proto.TTSRequest{
@@ -93,6 +139,8 @@ func ModelTTS(
 		Voice: voice,
 		Dst:   filePath,
 		Language: &language,
+		VoiceSample: voiceSample,
+		Segments: segments,
 	})
 
 	// return RPC error if any
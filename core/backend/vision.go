@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/mudler/LocalAI/core/config"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+const defaultVisionBackend = "vision"
+
+// Detection returns the objects found in the image by the detection model, as YOLO
+func Detection(request *proto.DetectRequest, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*proto.DetectResponse, error) {
+	visionModel, err := loadVisionModel(loader, backendConfig, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	return visionModel.Detect(appConfig.Context, request)
+}
+
+// Classification returns the labels of the image by the classification model, as CLIP
+func Classification(request *proto.ClassifyRequest, loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (*proto.ClassifyResponse, error) {
+	visionModel, err := loadVisionModel(loader, backendConfig, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	return visionModel.Classify(appConfig.Context, request)
+}
+
+func loadVisionModel(loader *model.ModelLoader, backendConfig config.BackendConfig, appConfig *config.ApplicationConfig) (grpc.Backend, error) {
+	backend := backendConfig.Backend
+	if backend == "" {
+		backend = defaultVisionBackend
+	}
+
+	opts := modelOpts(backendConfig, appConfig, []model.Option{
+		model.WithBackendString(backend),
+		model.WithModel(backendConfig.Model),
+		model.WithContext(appConfig.Context),
+		model.WithAssetDir(appConfig.AssetsDestination),
+		model.WithLoadGRPCLoadModelOpts(gRPCModelOpts(backendConfig)),
+	})
+
+	visionModel, err := loader.BackendLoader(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if visionModel == nil {
+		return nil, fmt.Errorf("could not load %s model", backend)
+	}
+	return visionModel, nil
+}
@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/utils"
+)
+
+// ErrVoiceNotFound is returned when a cloned voice is not registered
+var ErrVoiceNotFound = errors.New("voice not found")
+
+// ClonedVoicesDir is where the reference audio of the cloned voices is persisted
+func ClonedVoicesDir(appConfig *config.ApplicationConfig) string {
+	return filepath.Join(appConfig.AudioDir, "voices")
+}
+
+func clonedVoiceName(name string) (string, error) {
+	// Names are used as file names, without the extension of the reference audio
+	n := utils.SanitizeFileName(name)
+	if n == "" || n != name || strings.Contains(n, ".") {
+		return "", fmt.Errorf("invalid voice name %q", name)
+	}
+	return n, nil
+}
+
+// SaveClonedVoice registers a voice with the given name from its reference audio.
+// The extension of the reference audio is kept, as backends rely on it to read the sample
+// (e.g. bark expects .npz speaker prompts)
+func SaveClonedVoice(name, ext string, r io.Reader, appConfig *config.ApplicationConfig) (string, error) {
+	n, err := clonedVoiceName(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir := ClonedVoicesDir(appConfig)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed creating voices directory: %w", err)
+	}
+
+	// A voice has a single reference audio, replace any previous one
+	if err := DeleteClonedVoice(n, appConfig); err != nil && !errors.Is(err, ErrVoiceNotFound) {
+		return "", err
+	}
+
+	dst := filepath.Join(dir, n+strings.ToLower(ext))
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
+
+// ClonedVoice returns the path of the reference audio of a registered voice
+func ClonedVoice(name string, appConfig *config.ApplicationConfig) (string, error) {
+	n, err := clonedVoiceName(name)
+	if err != nil {
+		return "", ErrVoiceNotFound
+	}
+
+	matches, err := filepath.Glob(filepath.Join(ClonedVoicesDir(appConfig), n+".*"))
+	if err != nil {
+		return "", err
+	}
+	for _, m := range matches {
+		if strings.TrimSuffix(filepath.Base(m), filepath.Ext(m)) == n {
+			return m, nil
+		}
+	}
+	return "", ErrVoiceNotFound
+}
+
+// ListClonedVoices returns the names of the registered voices
+func ListClonedVoices(appConfig *config.ApplicationConfig) ([]string, error) {
+	entries, err := os.ReadDir(ClonedVoicesDir(appConfig))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	voices := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		voices = append(voices, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+	}
+	sort.Strings(voices)
+	return voices, nil
+}
+
+// DeleteClonedVoice removes a registered voice
+func DeleteClonedVoice(name string, appConfig *config.ApplicationConfig) error {
+	sample, err := ClonedVoice(name, appConfig)
+	if err != nil {
+		return err
+	}
+	return os.Remove(sample)
+}
@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cloned voices", func() {
+	var appConfig *config.ApplicationConfig
+
+	BeforeEach(func() {
+		appConfig = config.NewApplicationConfig(config.WithAudioDir(GinkgoT().TempDir()))
+	})
+
+	It("registers, lists and deletes the voices", func() {
+		sample, err := SaveClonedVoice("alice", ".WAV", strings.NewReader("RIFF"), appConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sample).To(Equal(filepath.Join(ClonedVoicesDir(appConfig), "alice.wav")))
+		_, err = SaveClonedVoice("bob", ".npz", strings.NewReader("npz"), appConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ListClonedVoices(appConfig)).To(Equal([]string{"alice", "bob"}))
+		Expect(ClonedVoice("alice", appConfig)).To(Equal(sample))
+
+		Expect(DeleteClonedVoice("alice", appConfig)).To(Succeed())
+		Expect(ListClonedVoices(appConfig)).To(Equal([]string{"bob"}))
+		_, err = ClonedVoice("alice", appConfig)
+		Expect(err).To(MatchError(ErrVoiceNotFound))
+		Expect(DeleteClonedVoice("alice", appConfig)).To(MatchError(ErrVoiceNotFound))
+	})
+
+	It("replaces the reference audio of a voice", func() {
+		_, err := SaveClonedVoice("alice", ".wav", strings.NewReader("RIFF"), appConfig)
+		Expect(err).ToNot(HaveOccurred())
+		sample, err := SaveClonedVoice("alice", ".npz", strings.NewReader("npz"), appConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ListClonedVoices(appConfig)).To(Equal([]string{"alice"}))
+		Expect(ClonedVoice("alice", appConfig)).To(Equal(sample))
+		Expect(os.ReadFile(sample)).To(Equal([]byte("npz")))
+	})
+
+	It("rejects the names which are not file names", func() {
+		for _, name := range []string{"", "../alice", "alice.wav", "a/b"} {
+			_, err := SaveClonedVoice(name, ".wav", strings.NewReader("RIFF"), appConfig)
+			Expect(err).To(HaveOccurred(), name)
+		}
+		Expect(ListClonedVoices(appConfig)).To(BeEmpty())
+	})
+
+	It("sends the reference audio of a cloned voice to the TTS backend", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("tts-clone", llm)
+		sample, err := SaveClonedVoice("alice", ".wav", strings.NewReader("RIFF"), appConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = ModelTTS("tts-clone", "Hello", "model", "alice", "", ml, appConfig, defaultConfig("model"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.tts.Voice).To(Equal("alice"))
+		Expect(llm.tts.VoiceSample).To(Equal(sample))
+
+		_, _, err = ModelTTS("tts-clone", "Hello", "model", "en-us", "", ml, appConfig, defaultConfig("model"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.tts.Voice).To(Equal("en-us"))
+		Expect(llm.tts.VoiceSample).To(BeEmpty())
+	})
+})
@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mudler/LocalAI/core/config"
+
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+// ModelListVoices returns the voices available with a TTS model
+func ModelListVoices(backend, modelFile string, loader *model.ModelLoader, appConfig *config.ApplicationConfig, backendConfig config.BackendConfig) (*proto.ListVoicesResponse, error) {
+	bb := backend
+	if bb == "" {
+		bb = model.PiperBackend
+	}
+
+	opts := modelOpts(config.BackendConfig{}, appConfig, []model.Option{
+		model.WithBackendString(bb),
+		model.WithModel(modelFile),
+		model.WithContext(appConfig.Context),
+		model.WithAssetDir(appConfig.AssetsDestination),
+		model.WithLoadGRPCLoadModelOpts(gRPCModelOpts(backendConfig)),
+	})
+	ttsModel, err := loader.BackendLoader(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttsModel == nil {
+		return nil, fmt.Errorf("could not load %s model", bb)
+	}
+
+	modelPath, err := ttsModelPath(modelFile, loader, appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return ttsModel.ListVoices(context.Background(), &proto.ListVoicesRequest{Model: modelPath})
+}
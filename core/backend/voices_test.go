@@ -0,0 +1,33 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Voices", func() {
+	It("lists the voices of the backend for the model in the models directory", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("voices", llm)
+		Expect(os.WriteFile(filepath.Join(appConfig.ModelPath, "en-us.onnx"), []byte{}, 0600)).To(Succeed())
+
+		res, err := ModelListVoices("voices", "en-us.onnx", ml, appConfig, defaultConfig("en-us"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Voices).To(HaveLen(1))
+		Expect(res.Voices[0].Name).To(Equal("en-us"))
+		Expect(res.Voices[0].Languages).To(Equal([]string{"en"}))
+		Expect(llm.voices.Model).To(Equal(filepath.Join(appConfig.ModelPath, "en-us.onnx")))
+	})
+
+	It("passes the models which are not files as they are", func() {
+		llm := &fakeBackend{}
+		ml, appConfig := provideBackend("voices-remote", llm)
+
+		_, err := ModelListVoices("voices-remote", "suno/bark", ml, appConfig, defaultConfig("bark"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.voices.Model).To(Equal("suno/bark"))
+	})
+})
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cliContext "github.com/mudler/LocalAI/core/cli/context"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/rs/zerolog/log"
+	"github.com/schollz/progressbar/v3"
+)
+
+type BackendsCMDFlags struct {
+	BackendGalleries  string `env:"LOCALAI_BACKEND_GALLERIES,BACKEND_GALLERIES" help:"JSON list of galleries of prebuilt backends" group:"backends" default:"${backend_galleries}"`
+	BackendAssetsPath string `env:"LOCALAI_BACKEND_ASSETS_PATH,BACKEND_ASSETS_PATH" type:"path" default:"/tmp/localai/backend_data" help:"Path used to extract libraries that are required by some of the backends in runtime" group:"storage"`
+}
+
+type BackendsList struct {
+	BackendsCMDFlags `embed:""`
+}
+
+type BackendsInstall struct {
+	BackendArgs []string `arg:"" name:"backends" help:"Backends to install or upgrade, as <name> or <gallery>@<name>"`
+
+	BackendsCMDFlags `embed:""`
+}
+
+type BackendsUninstall struct {
+	BackendArgs []string `arg:"" name:"backends" help:"Backends to uninstall"`
+
+	BackendsCMDFlags `embed:""`
+}
+
+type BackendsCMD struct {
+	List      BackendsList      `cmd:"" help:"List the backends available in your backend galleries" default:"withargs"`
+	Install   BackendsInstall   `cmd:"" help:"Install or upgrade a backend from the backend galleries"`
+	Uninstall BackendsUninstall `cmd:"" help:"Uninstall a backend installed from the backend galleries"`
+}
+
+func (f BackendsCMDFlags) galleries() []config.Gallery {
+	var galleries []config.Gallery
+	if f.BackendGalleries == "" {
+		return galleries
+	}
+	if err := json.Unmarshal([]byte(f.BackendGalleries), &galleries); err != nil {
+		log.Error().Err(err).Msg("unable to load backend galleries")
+	}
+	return galleries
+}
+
+func (bl *BackendsList) Run(ctx *cliContext.Context) error {
+	backends, err := gallery.AvailableGalleryBackends(bl.galleries(), bl.BackendAssetsPath)
+	if err != nil {
+		return err
+	}
+	for _, b := range backends {
+		switch b.InstalledVersion {
+		case "":
+			fmt.Printf(" - %s %s\n", b.ID(), b.Version)
+		case b.Version:
+			fmt.Printf(" * %s %s (installed)\n", b.ID(), b.Version)
+		default:
+			fmt.Printf(" * %s %s (installed: %s)\n", b.ID(), b.Version, b.InstalledVersion)
+		}
+	}
+	return nil
+}
+
+func (bi *BackendsInstall) Run(ctx *cliContext.Context) error {
+	for _, backendName := range bi.BackendArgs {
+		progressBar := progressbar.NewOptions(
+			1000,
+			progressbar.OptionSetDescription(fmt.Sprintf("downloading backend %s", backendName)),
+			progressbar.OptionShowBytes(false),
+			progressbar.OptionClearOnFinish(),
+		)
+		progressCallback := func(fileName string, current string, total string, percentage float64) {
+			v := int(percentage * 10)
+			err := progressBar.Set(v)
+			if err != nil {
+				log.Error().Err(err).Str("filename", fileName).Int("value", v).Msg("error while updating progress bar")
+			}
+		}
+
+		installed, err := gallery.InstallBackendFromGallery(bi.galleries(), backendName, bi.BackendAssetsPath, progressCallback)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s %s in %s\n", installed.Name, installed.Version, installed.Run)
+	}
+	return nil
+}
+
+func (bu *BackendsUninstall) Run(ctx *cliContext.Context) error {
+	for _, backendName := range bu.BackendArgs {
+		if err := gallery.DeleteBackendFromSystem(bu.BackendAssetsPath, backendName); err != nil {
+			return err
+		}
+		fmt.Printf("Uninstalled %s\n", backendName)
+	}
+	return nil
+}
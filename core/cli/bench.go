@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mudler/LocalAI/core/backend"
+	cliContext "github.com/mudler/LocalAI/core/cli/context"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
+	"github.com/rs/zerolog/log"
+)
+
+type BenchCMD struct {
+	Model string `arg:"" help:"Model to benchmark"`
+
+	Concurrency       []int  `short:"c" default:"1" sep:"," help:"Number of concurrent requests, a comma separated list runs a benchmark for each value"`
+	Requests          int    `short:"n" default:"8" help:"Number of requests sent for each benchmark"`
+	PromptLength      []int  `short:"p" default:"128" sep:"," help:"Length of the prompts in words, a comma separated list runs a benchmark for each value"`
+	OutputLength      int    `short:"o" default:"128" help:"Number of tokens to generate for each request"`
+	Threads           int    `short:"t" default:"0" help:"Number of threads used for parallel computation, defaults to the model configuration"`
+	ContextSize       int    `default:"0" help:"Context size of the model, defaults to the model configuration"`
+	ParallelRequests  bool   `env:"LOCALAI_PARALLEL_REQUESTS,PARALLEL_REQUESTS" help:"Enable backends to handle multiple requests in parallel if they support it (e.g.: llama.cpp or vllm)" group:"backends"`
+	ModelsPath        string `env:"LOCALAI_MODELS_PATH,MODELS_PATH" type:"path" default:"${basepath}/models" help:"Path containing models used for inferencing" group:"storage"`
+	BackendAssetsPath string `env:"LOCALAI_BACKEND_ASSETS_PATH,BACKEND_ASSETS_PATH" type:"path" default:"/tmp/localai/backend_data" help:"Path used to extract libraries that are required by some of the backends in runtime" group:"storage"`
+}
+
+// benchResult is the outcome of a single request of a benchmark
+type benchResult struct {
+	ttft    time.Duration
+	latency time.Duration
+	tokens  int
+	err     error
+}
+
+// benchWords are repeated to build prompts of the requested length
+var benchWords = strings.Fields("the quick brown fox jumps over the lazy dog while a curious cat watches from the old wooden fence")
+
+func benchPrompt(words int) string {
+	prompt := make([]string, words)
+	for i := range prompt {
+		prompt[i] = benchWords[i%len(benchWords)]
+	}
+	return strings.Join(prompt, " ")
+}
+
+func (b *BenchCMD) Run(ctx *cliContext.Context) error {
+	opts := &config.ApplicationConfig{
+		ModelPath:         b.ModelsPath,
+		Context:           context.Background(),
+		AssetsDestination: b.BackendAssetsPath,
+		Threads:           b.Threads,
+		ContextSize:       b.ContextSize,
+	}
+	if b.ParallelRequests {
+		config.EnableParallelBackendRequests(opts)
+	}
+
+	cl := config.NewBackendConfigLoader(b.ModelsPath)
+	ml := model.NewModelLoader(opts.ModelPath)
+	if err := cl.LoadBackendConfigsFromPath(b.ModelsPath); err != nil {
+		return err
+	}
+
+	loadOpts := []config.ConfigLoaderOption{}
+	if b.Threads != 0 {
+		loadOpts = append(loadOpts, config.LoadOptionThreads(b.Threads))
+	}
+	if b.ContextSize != 0 {
+		loadOpts = append(loadOpts, config.LoadOptionContextSize(b.ContextSize))
+	}
+	cfg, err := cl.LoadBackendConfigFileByName(b.Model, b.ModelsPath, loadOpts...)
+	if err != nil {
+		return err
+	}
+	if b.Threads != 0 {
+		cfg.Threads = &b.Threads
+	}
+	cfg.Maxtokens = &b.OutputLength
+	// Generate the requested number of tokens, regardless of the end of sequence
+	cfg.IgnoreEOS = true
+
+	defer func() {
+		err := ml.StopAllGRPC()
+		if err != nil {
+			log.Error().Err(err).Msg("unable to stop all grpc processes")
+		}
+	}()
+
+	// Load the model before measuring, and check that it answers
+	if _, err := b.request(benchPrompt(8), ml, *cfg, opts); err != nil {
+		return fmt.Errorf("failed running the model: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONCURRENCY\tPROMPT\tREQUESTS\tERRORS\tTTFT P50\tTTFT P90\tTTFT P99\tTOKENS/S P50\tTOKENS/S P90\tTHROUGHPUT\tPEAK VRAM")
+	for _, concurrency := range b.Concurrency {
+		for _, promptLength := range b.PromptLength {
+			results, elapsed, vram := b.benchmark(concurrency, benchPrompt(promptLength), ml, *cfg, opts)
+			fmt.Fprintln(w, benchSummary(concurrency, promptLength, results, elapsed, vram))
+			w.Flush()
+		}
+	}
+	return nil
+}
+
+// benchmark sends the requests with the given concurrency, and returns their results, the
+// wall time of the benchmark and the peak GPU memory in use during the benchmark
+func (b *BenchCMD) benchmark(concurrency int, prompt string, ml *model.ModelLoader, cfg config.BackendConfig, opts *config.ApplicationConfig) ([]benchResult, time.Duration, uint64) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var peakVRAM uint64
+	done := make(chan struct{})
+	var monitor sync.WaitGroup
+	monitor.Add(1)
+	go func() {
+		defer monitor.Done()
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if used, err := xsysinfo.GPUMemoryUsed(); err == nil && used > peakVRAM {
+				peakVRAM = used
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	requests := make(chan struct{}, b.Requests)
+	for i := 0; i < b.Requests; i++ {
+		requests <- struct{}{}
+	}
+	close(requests)
+
+	results := make([]benchResult, 0, b.Requests)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				r, err := b.request(prompt, ml, cfg, opts)
+				r.err = err
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	close(done)
+	monitor.Wait()
+
+	return results, elapsed, peakVRAM
+}
+
+func (b *BenchCMD) request(prompt string, ml *model.ModelLoader, cfg config.BackendConfig, opts *config.ApplicationConfig) (benchResult, error) {
+	r := benchResult{}
+	chunks := 0
+	start := time.Now()
+
+	// Streaming, to measure the time to the first token
+	predFunc, err := backend.ModelInference(context.Background(), prompt, nil, nil, ml, cfg, opts, func(s string, usage backend.TokenUsage) bool {
+		if chunks == 0 {
+			r.ttft = time.Since(start)
+		}
+		chunks++
+		return true
+	})
+	if err != nil {
+		return r, err
+	}
+
+	res, err := predFunc()
+	if err != nil {
+		return r, err
+	}
+	r.latency = time.Since(start)
+
+	// Not all the backends report the number of generated tokens
+	r.tokens = res.Usage.Completion
+	if r.tokens == 0 {
+		r.tokens = chunks
+	}
+	return r, nil
+}
+
+func benchSummary(concurrency, promptLength int, results []benchResult, elapsed time.Duration, vram uint64) string {
+	ttfts := []float64{}
+	speeds := []float64{}
+	errors := 0
+	tokens := 0
+	for _, r := range results {
+		if r.err != nil {
+			errors++
+			continue
+		}
+		tokens += r.tokens
+		ttfts = append(ttfts, float64(r.ttft.Milliseconds()))
+		if generation := r.latency - r.ttft; generation > 0 {
+			speeds = append(speeds, float64(r.tokens)/generation.Seconds())
+		}
+	}
+
+	peakVRAM := "n/a"
+	if vram > 0 {
+		peakVRAM = fmt.Sprintf("%.1f GiB", float64(vram)/(1024*1024*1024))
+	}
+
+	return fmt.Sprintf("%d\t%d\t%d\t%d\t%.0fms\t%.0fms\t%.0fms\t%.1f\t%.1f\t%.1f tok/s\t%s",
+		concurrency, promptLength, len(results), errors,
+		percentile(ttfts, 50), percentile(ttfts, 90), percentile(ttfts, 99),
+		percentile(speeds, 50), percentile(speeds, 90),
+		float64(tokens)/elapsed.Seconds(), peakVRAM)
+}
+
+// percentile returns the p-th percentile of the values, with the nearest-rank method
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
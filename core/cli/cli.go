@@ -10,9 +10,16 @@ var CLI struct {
 
 	Run        RunCMD        `cmd:"" help:"Run LocalAI, this the default command if no other command is specified. Run 'local-ai run --help' for more information" default:"withargs"`
 	Federated  FederatedCLI  `cmd:"" help:"Run LocalAI in federated mode"`
+	Gateway    GatewayCMD    `cmd:"" help:"Run LocalAI as a gateway routing the requests to other instances by model"`
 	Models     ModelsCMD     `cmd:"" help:"Manage LocalAI models and definitions"`
+	Gallery    GalleryCMD    `cmd:"" help:"Author private galleries of models"`
+	Backends   BackendsCMD   `cmd:"" help:"Manage the backends installed at runtime"`
 	TTS        TTSCMD        `cmd:"" help:"Convert text to speech"`
 	Transcript TranscriptCMD `cmd:"" help:"Convert audio to text"`
+	Bench      BenchCMD      `cmd:"" help:"Benchmark a model"`
+	Eval       EvalCMD       `cmd:"" help:"Evaluate models on a dataset, optionally scored by a judge model"`
+	Doctor     DoctorCMD     `cmd:"" help:"Diagnose the environment LocalAI runs in"`
+	Config     ConfigCMD     `cmd:"" help:"Export and import the configuration of LocalAI instances"`
 	Worker     worker.Worker `cmd:"" help:"Run workers to distribute workload (llama.cpp-only)"`
 	Util       UtilCMD       `cmd:"" help:"Utility commands"`
 	Explorer   ExplorerCMD   `cmd:"" help:"Run p2p explorer"`
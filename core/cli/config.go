@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	cliContext "github.com/mudler/LocalAI/core/cli/context"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+type ConfigCMDFlags struct {
+	URL    string   `env:"LOCALAI_URL" default:"http://localhost:8080" help:"URL of the LocalAI instance"`
+	APIKey []string `env:"LOCALAI_API_KEY,API_KEY" help:"API key of the LocalAI instance, if it requires authentication"`
+}
+
+type ConfigExport struct {
+	Output string `arg:"" default:"localai-config.tar.gz" help:"Archive to write"`
+
+	ConfigCMDFlags `embed:""`
+}
+
+type ConfigImport struct {
+	Archive string `arg:"" type:"existingfile" help:"Archive exported by another instance"`
+	EnvFile string `help:"Write the runtime settings of the archive to this env file (e.g. localai.env), to apply them on the next start"`
+
+	ConfigCMDFlags `embed:""`
+}
+
+type ConfigCMD struct {
+	Export ConfigExport `cmd:"" help:"Export the settings, the model configurations and the models installed from galleries of a running instance"`
+	Import ConfigImport `cmd:"" help:"Import an exported configuration in a running instance, installing its models"`
+}
+
+func (f *ConfigCMDFlags) request(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(f.URL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.APIKey) > 0 {
+		req.Header.Set("Authorization", "Bearer "+f.APIKey[0])
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s %s failed with status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (e *ConfigExport) Run(ctx *cliContext.Context) error {
+	resp, err := e.request(http.MethodGet, "/config/export", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(e.Output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Configuration exported to %s", e.Output)
+	return nil
+}
+
+func (i *ConfigImport) Run(ctx *cliContext.Context) error {
+	archive, err := os.ReadFile(i.Archive)
+	if err != nil {
+		return err
+	}
+
+	if i.EnvFile != "" {
+		settings, err := services.ReadConfigArchiveSettings(bytes.NewReader(archive))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(i.EnvFile, settings, 0600); err != nil {
+			return err
+		}
+		log.Info().Msgf("Runtime settings written to %s, they are applied on the next start", i.EnvFile)
+	}
+
+	resp, err := i.request(http.MethodPost, "/config/import", bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	job := schema.GalleryResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return err
+	}
+
+	// Wait for the models to be installed
+	for {
+		time.Sleep(time.Second)
+
+		resp, err := i.request(http.MethodGet, "/models/jobs/"+job.ID, nil)
+		if err != nil {
+			return err
+		}
+		// The error of gallery.GalleryOpStatus cannot be decoded, it is reported in the message
+		status := struct {
+			Processed          bool    `json:"processed"`
+			Message            string  `json:"message"`
+			FileName           string  `json:"file_name"`
+			Progress           float64 `json:"progress"`
+			TotalFileSize      string  `json:"file_size"`
+			DownloadedFileSize string  `json:"downloaded_size"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if status.FileName != "" && !status.Processed {
+			utils.DisplayDownloadFunction(status.FileName, status.DownloadedFileSize, status.TotalFileSize, status.Progress)
+		}
+		if status.Processed {
+			if status.Message != "completed" {
+				return fmt.Errorf("import failed: %s", status.Message)
+			}
+			log.Info().Msg("Configuration imported")
+			return nil
+		}
+	}
+}
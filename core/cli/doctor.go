@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	cliContext "github.com/mudler/LocalAI/core/cli/context"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/assets"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
+
+	"github.com/klauspost/cpuid/v2"
+)
+
+type DoctorCMD struct {
+	Address              string   `env:"LOCALAI_ADDRESS,ADDRESS" default:":8080" help:"Bind address for the API server" group:"api"`
+	GRPCAddress          string   `env:"LOCALAI_GRPC_ADDRESS,GRPC_ADDRESS" help:"Bind address for the gRPC API server" group:"api"`
+	ModelsPath           string   `env:"LOCALAI_MODELS_PATH,MODELS_PATH" type:"path" default:"${basepath}/models" help:"Path containing models used for inferencing" group:"storage"`
+	BackendAssetsPath    string   `env:"LOCALAI_BACKEND_ASSETS_PATH,BACKEND_ASSETS_PATH" type:"path" default:"/tmp/localai/backend_data" help:"Path used to extract libraries that are required by some of the backends in runtime" group:"storage"`
+	ExternalGRPCBackends []string `env:"LOCALAI_EXTERNAL_GRPC_BACKENDS,EXTERNAL_GRPC_BACKENDS" help:"A list of external grpc backends" group:"backends"`
+	F16                  bool     `name:"f16" env:"LOCALAI_F16,F16" help:"Enable GPU acceleration" group:"performance"`
+}
+
+// doctorReport prints the findings of the diagnostics as they are made, and counts the problems
+type doctorReport struct {
+	problems int
+	warnings int
+}
+
+func (r *doctorReport) section(title string) {
+	fmt.Printf("\n%s\n", title)
+}
+
+func (r *doctorReport) ok(format string, a ...any) {
+	fmt.Printf("  [ok]    %s\n", fmt.Sprintf(format, a...))
+}
+
+func (r *doctorReport) info(format string, a ...any) {
+	fmt.Printf("  [info]  %s\n", fmt.Sprintf(format, a...))
+}
+
+func (r *doctorReport) warn(advice string, format string, a ...any) {
+	r.warnings++
+	fmt.Printf("  [warn]  %s\n", fmt.Sprintf(format, a...))
+	if advice != "" {
+		fmt.Printf("          -> %s\n", advice)
+	}
+}
+
+func (r *doctorReport) fail(advice string, format string, a ...any) {
+	r.problems++
+	fmt.Printf("  [fail]  %s\n", fmt.Sprintf(format, a...))
+	if advice != "" {
+		fmt.Printf("          -> %s\n", advice)
+	}
+}
+
+func (d *DoctorCMD) Run(ctx *cliContext.Context) error {
+	r := &doctorReport{}
+
+	fmt.Printf("LocalAI doctor (%s/%s)\n", runtime.GOOS, runtime.GOARCH)
+
+	d.checkCPU(r)
+	d.checkGPUs(r)
+	d.checkBackends(ctx, r)
+	d.checkAddresses(r)
+	d.checkModels(r)
+
+	fmt.Printf("\n%d problem(s), %d warning(s)\n", r.problems, r.warnings)
+	if r.problems > 0 {
+		return fmt.Errorf("%d problem(s) found", r.problems)
+	}
+	return nil
+}
+
+func (d *DoctorCMD) checkCPU(r *doctorReport) {
+	r.section("CPU")
+	r.info("%s, %d physical cores", cpuid.CPU.BrandName, xsysinfo.CPUPhysicalCores())
+
+	flags := []struct {
+		name string
+		id   cpuid.FeatureID
+	}{
+		{"AVX", cpuid.AVX},
+		{"AVX2", cpuid.AVX2},
+		{"AVX512", cpuid.AVX512F},
+		{"F16C", cpuid.F16C},
+		{"FMA", cpuid.FMA3},
+	}
+	supported := []string{}
+	missing := []string{}
+	for _, f := range flags {
+		if xsysinfo.HasCPUCaps(f.id) {
+			supported = append(supported, f.name)
+		} else {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(supported) > 0 {
+		r.ok("supported instruction sets: %s", strings.Join(supported, ", "))
+	}
+
+	if runtime.GOARCH != "amd64" {
+		return
+	}
+	switch {
+	case !xsysinfo.HasCPUCaps(cpuid.AVX):
+		r.warn("llama.cpp runs with its fallback variant, expect slow inference on this CPU", "missing instruction sets: %s", strings.Join(missing, ", "))
+	case !xsysinfo.HasCPUCaps(cpuid.AVX2):
+		r.warn("llama.cpp runs with its AVX variant, inference is slower than on CPUs supporting AVX2", "missing instruction sets: %s", strings.Join(missing, ", "))
+	}
+}
+
+func (d *DoctorCMD) checkGPUs(r *doctorReport) {
+	r.section("GPU")
+
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+		r.ok("Apple Silicon: Metal acceleration is available")
+		return
+	}
+
+	gpus, err := xsysinfo.GPUs()
+	if err != nil {
+		r.warn("", "could not list the GPUs: %s", err)
+		return
+	}
+
+	found := false
+	for _, gpu := range gpus {
+		card := strings.ToLower(gpu.String())
+		switch {
+		case strings.Contains(card, "nvidia"):
+			found = true
+			r.info("%s", gpu.String())
+			out, err := exec.Command("nvidia-smi", "--query-gpu=driver_version", "--format=csv,noheader").Output()
+			if err != nil {
+				r.fail("install the NVIDIA driver, and use a CUDA image (e.g. -cublas-cuda12) or a CUDA build (BUILD_TYPE=cublas)", "NVIDIA GPU found, but nvidia-smi is not working: %s", err)
+				continue
+			}
+			r.ok("CUDA: NVIDIA driver %s", strings.TrimSpace(strings.Split(string(out), "\n")[0]))
+		case strings.Contains(card, "amd"):
+			found = true
+			r.info("%s", gpu.String())
+			if _, err := exec.LookPath("rocminfo"); err != nil {
+				if _, err := os.Stat("/opt/rocm"); err != nil {
+					r.warn("install ROCm, and use a hipblas image or build (BUILD_TYPE=hipblas) for GPU acceleration", "AMD GPU found, but ROCm is not installed")
+					continue
+				}
+			}
+			r.ok("ROCm is installed")
+		case strings.Contains(card, "intel"):
+			found = true
+			r.info("%s", gpu.String())
+			if _, err := exec.LookPath("sycl-ls"); err != nil {
+				if _, err := os.Stat("/opt/intel/oneapi"); err != nil {
+					r.warn("install the Intel oneAPI base toolkit, and use a SYCL image or build (BUILD_TYPE=sycl_f16) for GPU acceleration", "Intel GPU found, but oneAPI (SYCL) is not installed")
+					continue
+				}
+			}
+			r.ok("SYCL: Intel oneAPI is installed")
+		}
+	}
+
+	if !found {
+		r.info("no GPU found, inference runs on the CPU")
+	}
+}
+
+func (d *DoctorCMD) checkBackends(ctx *cliContext.Context, r *doctorReport) {
+	r.section("Backends")
+
+	grpcDir := filepath.Join(d.BackendAssetsPath, "backend-assets", "grpc")
+	if _, err := os.Stat(grpcDir); err != nil {
+		if len(assets.ListFiles(ctx.BackendAssets)) > 0 {
+			r.warn("the assets are extracted when LocalAI starts, run 'local-ai run' once and check again", "backend assets are not extracted in %s", d.BackendAssetsPath)
+		} else if len(d.ExternalGRPCBackends) == 0 {
+			r.fail("build LocalAI with its backends (make build), or use a container image", "no backends are embedded in this binary, and no external backends are configured")
+		}
+	} else {
+		backends, err := model.AvailableBackends(d.BackendAssetsPath)
+		if err != nil {
+			r.fail("", "could not list the backends in %s: %s", grpcDir, err)
+		} else if len(backends) == 0 {
+			r.fail(fmt.Sprintf("remove %s to have the assets extracted again on startup", d.BackendAssetsPath), "no backends found in %s", grpcDir)
+		} else {
+			r.ok("available backends: %s", strings.Join(backends, ", "))
+		}
+
+		entries, _ := os.ReadDir(grpcDir)
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || e.IsDir() {
+				continue
+			}
+			if info.Mode()&0111 == 0 {
+				r.fail(fmt.Sprintf("make it executable with: chmod +x %s", filepath.Join(grpcDir, e.Name())), "backend %s is not executable", e.Name())
+			}
+		}
+
+		if variant := model.LlamaCPPVariant(d.BackendAssetsPath, d.F16); variant != "" {
+			r.ok("llama.cpp variant for this system: %s", variant)
+		}
+	}
+
+	for _, b := range d.ExternalGRPCBackends {
+		name, uri, found := strings.Cut(b, ":")
+		if !found {
+			r.fail("external backends are defined as <name>:<path or address>", "invalid external backend %q", b)
+			continue
+		}
+		if _, err := os.Stat(uri); err == nil {
+			r.ok("external backend %s: %s", name, uri)
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", uri, 2*time.Second)
+		if err != nil {
+			r.fail("check that the backend is running, or that the path to its script exists", "external backend %s is not reachable at %s: %s", name, uri, err)
+			continue
+		}
+		conn.Close()
+		r.ok("external backend %s: listening on %s", name, uri)
+	}
+}
+
+func (d *DoctorCMD) checkAddresses(r *doctorReport) {
+	r.section("Network")
+
+	for _, a := range []struct {
+		name, address, flag string
+	}{
+		{"API", d.Address, "--address"},
+		{"gRPC API", d.GRPCAddress, "--grpc-address"},
+	} {
+		if a.address == "" {
+			continue
+		}
+		l, err := net.Listen("tcp", a.address)
+		if err != nil {
+			r.fail(fmt.Sprintf("stop the process using it, or pick another address with %s", a.flag), "the %s address %s is not available: %s", a.name, a.address, err)
+			continue
+		}
+		l.Close()
+		r.ok("the %s address %s is available", a.name, a.address)
+	}
+}
+
+func (d *DoctorCMD) checkModels(r *doctorReport) {
+	r.section("Models")
+
+	entries, err := os.ReadDir(d.ModelsPath)
+	if err != nil {
+		r.warn("create the directory, or point --models-path to the directory containing the models", "could not read the models directory %s: %s", d.ModelsPath, err)
+		return
+	}
+	r.info("%d file(s) in %s", len(entries), d.ModelsPath)
+
+	backends := model.KnownBackends(d.BackendAssetsPath, d.externalBackends())
+	configErrors, err := config.CheckBackendConfigsFromPath(d.ModelsPath, backends)
+	if err != nil {
+		r.fail("", "%s", err)
+		return
+	}
+	for _, e := range configErrors {
+		r.fail("fix the model configuration, or remove the file", "%s", e)
+	}
+	if len(configErrors) == 0 {
+		r.ok("no errors in the model configurations")
+	}
+}
+
+func (d *DoctorCMD) externalBackends() map[string]string {
+	backends := map[string]string{}
+	for _, b := range d.ExternalGRPCBackends {
+		if name, uri, found := strings.Cut(b, ":"); found {
+			backends[name] = uri
+		}
+	}
+	return backends
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	cliContext "github.com/mudler/LocalAI/core/cli/context"
+	"github.com/mudler/LocalAI/core/config"
+	grpcAPI "github.com/mudler/LocalAI/core/grpc"
+	"github.com/mudler/LocalAI/core/services"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+type EvalCMD struct {
+	Dataset string `arg:"" type:"existingfile" help:"JSONL dataset, each line with a prompt (or messages), and the expected answer or the criteria for the judge"`
+
+	Models            []string `short:"m" required:"" sep:"," help:"Models to evaluate, as a comma separated list"`
+	Judge             string   `short:"j" help:"Model scoring the answers of the items with criteria"`
+	Output            string   `short:"o" type:"path" help:"Write the report with all the answers as JSON to this file"`
+	Concurrency       int      `short:"c" default:"1" help:"Number of concurrent requests for each model"`
+	MaxTokens         int      `default:"0" help:"Maximum number of tokens of the answers, defaults to the model configuration"`
+	Temperature       *float32 `help:"Temperature of the answers, defaults to the model configuration"`
+	Threads           int      `short:"t" default:"0" help:"Number of threads used for parallel computation, defaults to the model configuration"`
+	ContextSize       int      `default:"0" help:"Context size of the models, defaults to the model configuration"`
+	ParallelRequests  bool     `env:"LOCALAI_PARALLEL_REQUESTS,PARALLEL_REQUESTS" help:"Enable backends to handle multiple requests in parallel if they support it (e.g.: llama.cpp or vllm)" group:"backends"`
+	ModelsPath        string   `env:"LOCALAI_MODELS_PATH,MODELS_PATH" type:"path" default:"${basepath}/models" help:"Path containing models used for inferencing" group:"storage"`
+	BackendAssetsPath string   `env:"LOCALAI_BACKEND_ASSETS_PATH,BACKEND_ASSETS_PATH" type:"path" default:"/tmp/localai/backend_data" help:"Path used to extract libraries that are required by some of the backends in runtime" group:"storage"`
+}
+
+func (e *EvalCMD) Run(ctx *cliContext.Context) error {
+	items, err := services.ReadEvalDataset(e.Dataset)
+	if err != nil {
+		return fmt.Errorf("failed reading the dataset: %w", err)
+	}
+
+	opts := &config.ApplicationConfig{
+		ModelPath:         e.ModelsPath,
+		Context:           context.Background(),
+		AssetsDestination: e.BackendAssetsPath,
+		Threads:           e.Threads,
+		ContextSize:       e.ContextSize,
+	}
+	if e.ParallelRequests {
+		config.EnableParallelBackendRequests(opts)
+	}
+
+	cl := config.NewBackendConfigLoader(e.ModelsPath)
+	ml := model.NewModelLoader(opts.ModelPath)
+	if err := cl.LoadBackendConfigsFromPath(e.ModelsPath); err != nil {
+		return err
+	}
+
+	defer func() {
+		err := ml.StopAllGRPC()
+		if err != nil {
+			log.Error().Err(err).Msg("unable to stop all grpc processes")
+		}
+	}()
+
+	// The models answer through the chat API, so their templates are applied as for the clients
+	server := grpcAPI.NewServer(cl, ml, opts, services.NewAPIKeys(opts))
+	chat := func(ctx context.Context, modelName string, messages []services.EvalMessage) (string, error) {
+		req := &pb.ChatRequest{
+			Model:       modelName,
+			Temperature: e.Temperature,
+		}
+		if e.MaxTokens > 0 {
+			maxTokens := int32(e.MaxTokens)
+			req.MaxTokens = &maxTokens
+		}
+		for _, m := range messages {
+			req.Messages = append(req.Messages, &pb.ChatMessage{Role: m.Role, Content: m.Content})
+		}
+		res, err := server.ChatCompletion(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		return res.Content, nil
+	}
+
+	report, err := services.RunEval(opts.Context, items, services.EvalOptions{
+		Models:      e.Models,
+		Judge:       e.Judge,
+		Concurrency: e.Concurrency,
+	}, chat)
+	if err != nil {
+		return err
+	}
+	report.Dataset = e.Dataset
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tITEMS\tSCORED\tERRORS\tSCORE")
+	for _, m := range report.Models {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.3f\n", m.Model, len(m.Results), m.Scored, m.Errors, m.Score)
+	}
+	w.Flush()
+
+	if e.Output != "" {
+		dat, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(e.Output, dat, 0644); err != nil {
+			return fmt.Errorf("failed writing the report: %w", err)
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	cliContext "github.com/mudler/LocalAI/core/cli/context"
 	"github.com/mudler/LocalAI/core/explorer"
 	"github.com/mudler/LocalAI/core/http"
+	"github.com/mudler/LocalAI/pkg/ipfilter"
 )
 
 type ExplorerCMD struct {
@@ -14,10 +15,19 @@ type ExplorerCMD struct {
 	PoolDatabase             string `env:"LOCALAI_POOL_DATABASE,POOL_DATABASE" default:"explorer.json" help:"Path to the pool database" group:"api"`
 	ConnectionTimeout        string `env:"LOCALAI_CONNECTION_TIMEOUT,CONNECTION_TIMEOUT" default:"2m" help:"Connection timeout for the explorer" group:"api"`
 	ConnectionErrorThreshold int    `env:"LOCALAI_CONNECTION_ERROR_THRESHOLD,CONNECTION_ERROR_THRESHOLD" default:"3" help:"Connection failure threshold for the explorer" group:"api"`
+
+	AllowedIPs     []string `name:"allowed-ips" env:"LOCALAI_ALLOWED_IPS" help:"IP addresses and CIDR ranges of the clients allowed to connect, as 10.0.0.0/8. All the clients are allowed if empty" group:"api"`
+	DeniedIPs      []string `name:"denied-ips" env:"LOCALAI_DENIED_IPS" help:"IP addresses and CIDR ranges of the clients denied, even if allowed" group:"api"`
+	TrustedProxies []string `env:"LOCALAI_TRUSTED_PROXIES" help:"IP addresses and CIDR ranges of the reverse proxies whose X-Forwarded-For header gives the address of the clients" group:"api"`
 }
 
 func (e *ExplorerCMD) Run(ctx *cliContext.Context) error {
 
+	filter, err := ipfilter.New(e.AllowedIPs, e.DeniedIPs, e.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
 	db, err := explorer.NewDatabase(e.PoolDatabase)
 	if err != nil {
 		return err
@@ -30,7 +40,7 @@ func (e *ExplorerCMD) Run(ctx *cliContext.Context) error {
 	ds := explorer.NewDiscoveryServer(db, dur, e.ConnectionErrorThreshold)
 
 	go ds.Start(context.Background())
-	appHTTP := http.Explorer(db, ds)
+	appHTTP := http.Explorer(db, ds, filter)
 
 	return appHTTP.Listen(e.Address)
 }
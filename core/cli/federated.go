@@ -5,18 +5,32 @@ import (
 
 	cliContext "github.com/mudler/LocalAI/core/cli/context"
 	"github.com/mudler/LocalAI/core/p2p"
+	"github.com/mudler/LocalAI/pkg/ipfilter"
 )
 
 type FederatedCLI struct {
-	Address            string `env:"LOCALAI_ADDRESS,ADDRESS" default:":8080" help:"Bind address for the API server" group:"api"`
-	Peer2PeerToken     string `env:"LOCALAI_P2P_TOKEN,P2P_TOKEN,TOKEN" name:"p2ptoken" help:"Token for P2P mode (optional)" group:"p2p"`
-	LoadBalanced       bool   `env:"LOCALAI_LOAD_BALANCED,LOAD_BALANCED" default:"false" help:"Enable load balancing" group:"p2p"`
-	Peer2PeerNetworkID string `env:"LOCALAI_P2P_NETWORK_ID,P2P_NETWORK_ID" help:"Network ID for P2P mode, can be set arbitrarly by the user for grouping a set of instances." group:"p2p"`
+	Address            string   `env:"LOCALAI_ADDRESS,ADDRESS" default:":8080" help:"Bind address for the API server" group:"api"`
+	Peer2PeerToken     string   `env:"LOCALAI_P2P_TOKEN,P2P_TOKEN,TOKEN" name:"p2ptoken" help:"Token for P2P mode (optional)" group:"p2p"`
+	LoadBalanced       bool     `env:"LOCALAI_LOAD_BALANCED,LOAD_BALANCED" default:"false" help:"Enable load balancing" group:"p2p"`
+	Peer2PeerNetworkID string   `env:"LOCALAI_P2P_NETWORK_ID,P2P_NETWORK_ID" help:"Network ID for P2P mode, can be set arbitrarly by the user for grouping a set of instances." group:"p2p"`
+	WorkerSlots        int      `env:"LOCALAI_FEDERATED_WORKER_SLOTS" default:"0" help:"Requests each worker serves at a time. The other requests wait by priority (X-LocalAI-Priority header), and the high priority requests preempt the low priority ones not answered yet. 0 forwards all the requests right away" group:"p2p"`
+	PriorityIPs        []string `name:"priority-ips" env:"LOCALAI_FEDERATED_PRIORITY_IPS" help:"IP addresses and CIDR ranges of the clients allowed to send high priority requests, as 10.0.0.0/8. The high priority requests of the other clients are scheduled as normal ones" group:"p2p"`
+	AllowedIPs         []string `name:"allowed-ips" env:"LOCALAI_ALLOWED_IPS" help:"IP addresses and CIDR ranges of the clients allowed to connect, as 10.0.0.0/8. All the clients are allowed if empty" group:"api"`
+	DeniedIPs          []string `name:"denied-ips" env:"LOCALAI_DENIED_IPS" help:"IP addresses and CIDR ranges of the clients denied, even if allowed" group:"api"`
 }
 
 func (f *FederatedCLI) Run(ctx *cliContext.Context) error {
+	// The requests are proxied as they are, the clients are filtered by the address of their connection
+	filter, err := ipfilter.New(f.AllowedIPs, f.DeniedIPs, nil)
+	if err != nil {
+		return err
+	}
+	priorityClients, err := ipfilter.New(f.PriorityIPs, nil, nil)
+	if err != nil {
+		return err
+	}
 
-	fs := p2p.NewFederatedServer(f.Address, p2p.NetworkID(f.Peer2PeerNetworkID, p2p.FederatedID), f.Peer2PeerToken, f.LoadBalanced)
+	fs := p2p.NewFederatedServer(f.Address, p2p.NetworkID(f.Peer2PeerNetworkID, p2p.FederatedID), f.Peer2PeerToken, f.LoadBalanced, f.WorkerSlots, filter, priorityClients)
 
 	return fs.Start(context.Background())
 }
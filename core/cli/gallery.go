@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+
+	cliContext "github.com/mudler/LocalAI/core/cli/context"
+	"github.com/mudler/LocalAI/core/gallery"
+)
+
+type GalleryInit struct {
+	Path string `arg:"" type:"path" default:"." help:"Directory of the gallery"`
+}
+
+type GalleryAdd struct {
+	Path  string   `arg:"" type:"path" help:"Directory of the gallery"`
+	Files []string `arg:"" optional:"" name:"files" help:"Model files to add, relative to the directory of the gallery. All the model files of the directory are added by default"`
+
+	BaseURL     string   `env:"LOCALAI_GALLERY_BASE_URL" required:"" help:"URL the directory of the gallery is served at, as https://models.example.com/gallery"`
+	Template    string   `default:"base.yaml" help:"Configuration template of the models, relative to the directory of the gallery"`
+	Name        string   `help:"Name of the model, when adding a single file. Defaults to the name of the file"`
+	Description string   `help:"Description of the models"`
+	License     string   `help:"License of the models"`
+	Tags        []string `help:"Tags of the models"`
+}
+
+type GalleryValidate struct {
+	Path string `arg:"" type:"path" default:"." help:"Directory of the gallery"`
+
+	BaseURL string `env:"LOCALAI_GALLERY_BASE_URL" help:"URL the directory of the gallery is served at. The files served under it are checked in the directory"`
+	Hashes  bool   `help:"Check the sha256 of the files in the directory too, reading them entirely"`
+}
+
+type GalleryCMD struct {
+	Init     GalleryInit     `cmd:"" help:"Create a private gallery, with an empty index and a base configuration template"`
+	Add      GalleryAdd      `cmd:"" help:"Add the model files of the directory of a gallery to its index, with their hashes and sizes"`
+	Validate GalleryValidate `cmd:"" help:"Validate the index of a gallery, and the files it serves"`
+}
+
+func (gi *GalleryInit) Run(ctx *cliContext.Context) error {
+	if err := gallery.InitGallery(gi.Path); err != nil {
+		return err
+	}
+	fmt.Printf("Created the gallery in %s, add its models with local-ai gallery add\n", gi.Path)
+	return nil
+}
+
+func (ga *GalleryAdd) Run(ctx *cliContext.Context) error {
+	models, err := gallery.AddModels(ga.Path, ga.Files, gallery.AddModelOptions{
+		BaseURL:     ga.BaseURL,
+		Template:    ga.Template,
+		Name:        ga.Name,
+		Description: ga.Description,
+		License:     ga.License,
+		Tags:        ga.Tags,
+	})
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		for _, f := range m.AdditionalFiles {
+			fmt.Printf(" + %s: %s (%d bytes, sha256 %s)\n", m.Name, f.Filename, f.Size, f.SHA256)
+		}
+	}
+	return nil
+}
+
+func (gv *GalleryValidate) Run(ctx *cliContext.Context) error {
+	if err := gallery.ValidateGallery(gv.Path, gv.BaseURL, gv.Hashes); err != nil {
+		return err
+	}
+	fmt.Printf("The gallery in %s is valid\n", gv.Path)
+	return nil
+}
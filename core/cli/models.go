@@ -16,8 +16,10 @@ import (
 )
 
 type ModelsCMDFlags struct {
-	Galleries  string `env:"LOCALAI_GALLERIES,GALLERIES" help:"JSON list of galleries" group:"models" default:"${galleries}"`
-	ModelsPath string `env:"LOCALAI_MODELS_PATH,MODELS_PATH" type:"path" default:"${basepath}/models" help:"Path containing models used for inferencing" group:"storage"`
+	Galleries        string `env:"LOCALAI_GALLERIES,GALLERIES" help:"JSON list of galleries" group:"models" default:"${galleries}"`
+	GalleryOffline   bool   `env:"LOCALAI_GALLERY_OFFLINE" help:"Read the galleries from their cache only, without network, as in the air-gapped installations" group:"models"`
+	ModelsPath       string `env:"LOCALAI_MODELS_PATH,MODELS_PATH" type:"path" default:"${basepath}/models" help:"Path containing models used for inferencing" group:"storage"`
+	GalleryCachePath string `env:"LOCALAI_GALLERY_CACHE_PATH" type:"path" default:"${basepath}/cache/galleries" help:"Path caching the indexes of the galleries, revalidated at each fetch" group:"storage"`
 }
 
 type ModelsList struct {
@@ -26,6 +28,9 @@ type ModelsList struct {
 
 type ModelsInstall struct {
 	DisablePredownloadScan bool     `env:"LOCALAI_DISABLE_PREDOWNLOAD_SCAN" help:"If true, disables the best-effort security scanner before downloading any files." group:"hardening" default:"false"`
+	GatedLicenses          []string `env:"LOCALAI_GATED_LICENSES" default:"${gated_licenses}" help:"Licenses of the models installed only once acknowledged with --accepted-licenses" group:"models"`
+	AcceptedLicenses       []string `env:"LOCALAI_ACCEPTED_LICENSES" help:"Gated licenses acknowledged for the installations of their models" group:"models"`
+	ConfigPath             string   `env:"LOCALAI_CONFIG_PATH,CONFIG_PATH" default:"/tmp/localai/config" help:"Path where the acknowledgements of the gated licenses are recorded" group:"storage"`
 	ModelArgs              []string `arg:"" optional:"" name:"models" help:"Model configuration URLs to load"`
 
 	ModelsCMDFlags `embed:""`
@@ -37,6 +42,8 @@ type ModelsCMD struct {
 }
 
 func (ml *ModelsList) Run(ctx *cliContext.Context) error {
+	downloader.SetIndexCache(ml.GalleryCachePath, ml.GalleryOffline)
+
 	var galleries []config.Gallery
 	if err := json.Unmarshal([]byte(ml.Galleries), &galleries); err != nil {
 		log.Error().Err(err).Msg("unable to load galleries")
@@ -57,6 +64,9 @@ func (ml *ModelsList) Run(ctx *cliContext.Context) error {
 }
 
 func (mi *ModelsInstall) Run(ctx *cliContext.Context) error {
+	downloader.SetIndexCache(mi.GalleryCachePath, mi.GalleryOffline)
+	gallery.SetLicenseGate(mi.GatedLicenses, mi.AcceptedLicenses, mi.ConfigPath)
+
 	var galleries []config.Gallery
 	if err := json.Unmarshal([]byte(mi.Galleries), &galleries); err != nil {
 		log.Error().Err(err).Msg("unable to load galleries")
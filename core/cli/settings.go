@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/mudler/LocalAI/core/config"
+	"gopkg.in/yaml.v3"
+)
+
+// SettingsFileEnv is the environment variable with the path of the settings file
+const SettingsFileEnv = "LOCALAI_SETTINGS_FILE"
+
+// SettingsLoader reads a YAML settings file, with the flags as keys, either by their name
+// (models-path), in snake case (models_path) or by their environment variable (LOCALAI_MODELS_PATH).
+// ${NAME} references to the environment variables are expanded.
+//
+// The settings of the file take precedence over the defaults, but not over the environment variables
+// and the flags, so the flags set in the environment are not resolved from the file.
+func SettingsLoader(r io.Reader) (kong.Resolver, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(config.ExpandEnv(content), &values); err != nil {
+		return nil, fmt.Errorf("invalid settings file: %w", err)
+	}
+
+	var f kong.ResolverFunc = func(context *kong.Context, parent *kong.Path, flag *kong.Flag) (interface{}, error) {
+		for _, env := range flag.Envs {
+			if _, set := os.LookupEnv(env); set {
+				return nil, nil
+			}
+		}
+
+		keys := append([]string{flag.Name, strings.ReplaceAll(flag.Name, "-", "_")}, flag.Envs...)
+		for _, key := range keys {
+			raw, ok := values[key]
+			if !ok {
+				continue
+			}
+			// the string flags, as the durations parsed later, accept the YAML numbers and booleans too
+			switch raw.(type) {
+			case int, float64, bool:
+				if flag.Target.Kind() == reflect.String {
+					return fmt.Sprint(raw), nil
+				}
+			}
+			return raw, nil
+		}
+		return nil, nil
+	}
+	return f, nil
+}
+
+// settingSources tells where the value of each flag of the command comes from, by the first of the
+// environment variables of the flag
+func settingSources(kctx *kong.Context) map[string]string {
+	sources := map[string]string{}
+	for _, path := range kctx.Path {
+		if path.Flag == nil || len(path.Flag.Envs) == 0 {
+			continue
+		}
+		if path.Resolved {
+			sources[path.Flag.Envs[0]] = config.SourceSettingsFile
+		} else {
+			sources[path.Flag.Envs[0]] = config.SourceFlag
+		}
+	}
+	for _, flag := range kctx.Flags() {
+		if len(flag.Envs) == 0 {
+			continue
+		}
+		if _, set := sources[flag.Envs[0]]; set {
+			continue
+		}
+		sources[flag.Envs[0]] = config.SourceDefault
+		for _, env := range flag.Envs {
+			if _, set := os.LookupEnv(env); set {
+				sources[flag.Envs[0]] = config.SourceEnv
+				break
+			}
+		}
+	}
+	return sources
+}
@@ -19,6 +19,8 @@ type TranscriptCMD struct {
 	Model             string `short:"m" required:"" help:"Model name to run the TTS"`
 	Language          string `short:"l" help:"Language of the audio file"`
 	Translate         bool   `short:"c" help:"Translate the transcription to english"`
+	Prompt            string `short:"p" help:"Initial prompt to guide the transcription (e.g. spelling of names)"`
+	Diarize           bool   `short:"d" help:"Label the segments with the speakers, using the diarization model of the transcription model"`
 	Threads           int    `short:"t" default:"1" help:"Number of threads used for parallel computation"`
 	ModelsPath        string `env:"LOCALAI_MODELS_PATH,MODELS_PATH" type:"path" default:"${basepath}/models" help:"Path containing models used for inferencing" group:"storage"`
 	BackendAssetsPath string `env:"LOCALAI_BACKEND_ASSETS_PATH,BACKEND_ASSETS_PATH" type:"path" default:"/tmp/localai/backend_data" help:"Path used to extract libraries that are required by some of the backends in runtime" group:"storage"`
@@ -51,11 +53,15 @@ func (t *TranscriptCMD) Run(ctx *cliContext.Context) error {
 		}
 	}()
 
-	tr, err := backend.ModelTranscription(t.Filename, t.Language, t.Translate, ml, c, opts)
+	tr, err := backend.ModelTranscription(context.Background(), t.Filename, t.Language, t.Prompt, t.Translate, false, t.Diarize, ml, c, opts)
 	if err != nil {
 		return err
 	}
 	for _, segment := range tr.Segments {
+		if segment.Speaker != "" {
+			fmt.Println(segment.Start.String(), "-", segment.Speaker+":", segment.Text)
+			continue
+		}
 		fmt.Println(segment.Start.String(), "-", segment.Text)
 	}
 	return nil
@@ -4,8 +4,13 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/mudler/LocalAI/pkg/events"
+	"github.com/mudler/LocalAI/pkg/storage"
 	"github.com/mudler/LocalAI/pkg/xsysinfo"
 	"github.com/rs/zerolog/log"
 )
@@ -13,15 +18,42 @@ import (
 type ApplicationConfig struct {
 	Context                             context.Context
 	ConfigFile                          string
+	PresetsFile                         string
 	ModelPath                           string
 	LibPath                             string
 	UploadLimitMB, Threads, ContextSize int
+	JSONBodyLimitMB                     int
+	MaxMessages                         int
+	MaxImageSizeMB                      int
+	ReadTimeout                         time.Duration
+	WriteTimeout                        time.Duration
+	IdleTimeout                         time.Duration
+	MaxConnsPerIP                       int
+	AllowedIPs                          []string
+	DeniedIPs                           []string
+	TrustedProxies                      []string
 	DisableWebUI                        bool
 	F16                                 bool
 	Debug                               bool
 	ImageDir                            string
 	AudioDir                            string
 	UploadDir                           string
+	UploadStorage                       storage.Storage
+	UploadQuotaMB                       int
+	UploadRetention                     time.Duration
+	UploadDirMaxSizeMB                  int
+	GeneratedRetention                  time.Duration
+	ImageDirMaxSizeMB                   int
+	AudioDirMaxSizeMB                   int
+	JobRetention                        time.Duration
+	OutputStorage                       storage.Storage
+	OutputURLExpiry                     time.Duration
+	StorageEncryptionKey                []byte
+	ImageMetadata                       bool
+	StrictConfig                        bool
+	C2PAManifest                        string
+	C2PATool                            string
+	FineTuneCommand                     string
 	ConfigsDir                          string
 	DynamicConfigsDir                   string
 	DynamicConfigsDirPollInterval       time.Duration
@@ -31,14 +63,32 @@ type ApplicationConfig struct {
 	PreloadModelsFromPath               string
 	CORSAllowOrigins                    string
 	ApiKeys                             []string
+	APIKeyDailyTokens                   int
+	Tenants                             map[string]Tenant
+	ModelSchedules                      []ModelSchedule
+	Pipelines                           map[string]Pipeline
+	AgentFetchAllowlist                 []string
+	Tasks                               map[string]ScheduledTask
+	TasksDir                            string
+	TaskWebhookAllowlist                []string
 	EnforcePredownloadScans             bool
 	OpaqueErrors                        bool
+	EnablePprof                         bool
 	P2PToken                            string
 	P2PNetworkID                        string
 
 	ModelLibraryURL string
 
-	Galleries []Gallery
+	Galleries        []Gallery
+	BackendGalleries []Gallery
+	// The indexes of the galleries are cached in GalleryCacheDir, and only read from it when GalleryOffline
+	GalleryCacheDir string
+	GalleryOffline  bool
+
+	// The models with one of the GatedLicenses are installed once their license is acknowledged by the request,
+	// or accepted for all the installations with AcceptedLicenses
+	GatedLicenses    []string
+	AcceptedLicenses []string
 
 	BackendAssets     embed.FS
 	AssetsDestination string
@@ -57,16 +107,60 @@ type ApplicationConfig struct {
 	ModelsURL []string
 
 	WatchDogBusyTimeout, WatchDogIdleTimeout time.Duration
+	// Actions of the watchdog when a backend is busy or idle for too long, and the webhook of their events
+	WatchDogBusyActions, WatchDogIdleActions []string
+	WatchDogWebhook                          string
+
+	// The circuit breaker fails fast the requests to the models after CircuitBreakerFailures consecutive
+	// failures, probing them every CircuitBreakerCooldown. It is disabled when CircuitBreakerFailures is 0
+	CircuitBreakerFailures int
+	CircuitBreakerCooldown time.Duration
+
+	// Profile is the startup profile, the subsystems it disables are set below and in DisableWebUI
+	Profile                string
+	DisableGalleryEndpoint bool
+	DisableMetrics         bool
+	DisablePythonBackends  bool
+
+	// The loaded models are reloaded once their files stay unchanged for ModelReloadDelay after a change.
+	// It is disabled when 0
+	ModelReloadDelay time.Duration
+
+	// SettingSources tells where each setting, by its environment variable, comes from
+	SettingSources map[string]string
+
+	// EventSinks are the destinations of the events of the instance, published on Events
+	EventSinks EventSinks
+	Events     *events.Bus
+}
+
+// EventSinks are the webhooks and message brokers where the events, as the models loaded
+// and the gallery jobs completed, are published
+type EventSinks struct {
+	Webhooks    []string
+	NATSURL     string
+	NATSSubject string
+	MQTTURL     string
+	MQTTTopic   string
+	// Types filters the events published, all of them when empty
+	Types []string
+}
+
+// Enabled tells if the events are published anywhere
+func (s EventSinks) Enabled() bool {
+	return len(s.Webhooks) > 0 || s.NATSURL != "" || s.MQTTURL != ""
 }
 
 type AppOption func(*ApplicationConfig)
 
 func NewApplicationConfig(o ...AppOption) *ApplicationConfig {
 	opt := &ApplicationConfig{
-		Context:       context.Background(),
-		UploadLimitMB: 15,
-		ContextSize:   512,
-		Debug:         true,
+		Context:         context.Background(),
+		UploadLimitMB:   15,
+		OutputURLExpiry: time.Hour,
+		JobRetention:    24 * time.Hour,
+		ContextSize:     512,
+		Debug:           true,
 	}
 	for _, oo := range o {
 		oo(opt)
@@ -152,6 +246,32 @@ func SetWatchDogIdleTimeout(t time.Duration) AppOption {
 	}
 }
 
+func SetWatchDogActions(busy, idle []string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.WatchDogBusyActions = busy
+		o.WatchDogIdleActions = idle
+	}
+}
+
+func SetWatchDogWebhook(url string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.WatchDogWebhook = url
+	}
+}
+
+func WithCircuitBreaker(failures int, cooldown time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.CircuitBreakerFailures = failures
+		o.CircuitBreakerCooldown = cooldown
+	}
+}
+
+func WithEventSinks(sinks EventSinks) AppOption {
+	return func(o *ApplicationConfig) {
+		o.EventSinks = sinks
+	}
+}
+
 var EnableSingleBackend = func(o *ApplicationConfig) {
 	o.SingleBackend = true
 }
@@ -205,6 +325,37 @@ func WithStringGalleries(galls string) AppOption {
 	}
 }
 
+// WithGalleryCache caches the indexes of the galleries, and the configurations of their models, in dir.
+// In offline mode, the galleries are only read from the cache
+func WithGalleryCache(dir string, offline bool) AppOption {
+	return func(o *ApplicationConfig) {
+		o.GalleryCacheDir = dir
+		o.GalleryOffline = offline
+	}
+}
+
+// WithLicenseGate requires the acknowledgement of the gated licenses to install their models, unless accepted
+func WithLicenseGate(gated, accepted []string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.GatedLicenses = gated
+		o.AcceptedLicenses = accepted
+	}
+}
+
+func WithStringBackendGalleries(galls string) AppOption {
+	return func(o *ApplicationConfig) {
+		if galls == "" {
+			o.BackendGalleries = []Gallery{}
+			return
+		}
+		var galleries []Gallery
+		if err := json.Unmarshal([]byte(galls), &galleries); err != nil {
+			log.Error().Err(err).Msg("failed loading backend galleries")
+		}
+		o.BackendGalleries = append(o.BackendGalleries, galleries...)
+	}
+}
+
 func WithGalleries(galleries []Gallery) AppOption {
 	return func(o *ApplicationConfig) {
 		o.Galleries = append(o.Galleries, galleries...)
@@ -234,12 +385,74 @@ func WithConfigFile(configFile string) AppOption {
 	}
 }
 
+func WithPresetsFile(presetsFile string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.PresetsFile = presetsFile
+	}
+}
+
 func WithUploadLimitMB(limit int) AppOption {
 	return func(o *ApplicationConfig) {
 		o.UploadLimitMB = limit
 	}
 }
 
+// WithJSONBodyLimitMB limits the body of the requests other than the uploads. The upload limit applies
+// to all the requests if not set
+func WithJSONBodyLimitMB(limit int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.JSONBodyLimitMB = limit
+	}
+}
+
+// WithMaxMessages limits the number of messages of the chat requests
+func WithMaxMessages(max int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.MaxMessages = max
+	}
+}
+
+// WithMaxImageSizeMB limits the size of each image sent in the body of the requests, as base64
+func WithMaxImageSizeMB(max int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.MaxImageSizeMB = max
+	}
+}
+
+// WithServerTimeouts sets the time to read a request, headers and body, to write a response, streams
+// included, and to wait for the next request of a keep-alive connection. 0 disables a timeout, and the
+// idle timeout is the read timeout if not set
+func WithServerTimeouts(read, write, idle time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.ReadTimeout = read
+		o.WriteTimeout = write
+		o.IdleTimeout = idle
+	}
+}
+
+// WithMaxConnsPerIP limits the concurrent connections of each client address
+func WithMaxConnsPerIP(max int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.MaxConnsPerIP = max
+	}
+}
+
+// WithIPFilter allows the clients of the allowed addresses and ranges, all of them when allowed is empty,
+// except the denied ones
+func WithIPFilter(allowed, denied []string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.AllowedIPs = allowed
+		o.DeniedIPs = denied
+	}
+}
+
+// WithTrustedProxies sets the reverse proxies whose X-Forwarded-For header gives the address of the clients
+func WithTrustedProxies(proxies []string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.TrustedProxies = proxies
+	}
+}
+
 func WithThreads(threads int) AppOption {
 	return func(o *ApplicationConfig) {
 		if threads == 0 { // 0 is not allowed
@@ -285,6 +498,118 @@ func WithUploadDir(uploadDir string) AppOption {
 	}
 }
 
+// WithUploadStorage sets where the files uploaded with the files API are stored.
+// If not set, files are stored in the upload directory.
+func WithUploadStorage(s storage.Storage) AppOption {
+	return func(o *ApplicationConfig) {
+		o.UploadStorage = s
+	}
+}
+
+// WithUploadQuotaMB limits the storage each API key can use with the files API
+func WithUploadQuotaMB(quota int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.UploadQuotaMB = quota
+	}
+}
+
+// WithUploadRetention sets after how long uploaded files are deleted
+func WithUploadRetention(retention time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.UploadRetention = retention
+	}
+}
+
+// WithUploadDirMaxSizeMB limits the storage used by all the uploaded files: the oldest ones are deleted over it
+func WithUploadDirMaxSizeMB(size int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.UploadDirMaxSizeMB = size
+	}
+}
+
+// WithGeneratedRetention sets after how long the generated images and audio are deleted
+func WithGeneratedRetention(retention time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.GeneratedRetention = retention
+	}
+}
+
+// WithImageDirMaxSizeMB limits the size of the image directory: the oldest images are deleted over it
+func WithImageDirMaxSizeMB(size int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.ImageDirMaxSizeMB = size
+	}
+}
+
+// WithAudioDirMaxSizeMB limits the size of the audio directory: the oldest audio files are deleted over it.
+// The cloned voices are kept
+func WithAudioDirMaxSizeMB(size int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.AudioDirMaxSizeMB = size
+	}
+}
+
+// WithJobRetention sets for how long the finished jobs, and their results, are kept
+func WithJobRetention(retention time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.JobRetention = retention
+	}
+}
+
+// WithOutputStorage sets where the generated images are stored. If the storage
+// can sign URLs, responses link to it directly instead of to LocalAI.
+func WithOutputStorage(s storage.Storage) AppOption {
+	return func(o *ApplicationConfig) {
+		o.OutputStorage = s
+	}
+}
+
+// WithOutputURLExpiry sets for how long the signed URLs of the generated images are valid
+func WithOutputURLExpiry(expiry time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.OutputURLExpiry = expiry
+	}
+}
+
+// WithStorageEncryptionKey encrypts the generated audio at rest with the AES-256 key. The uploads and the
+// generated images are encrypted by their storages
+func WithStorageEncryptionKey(key []byte) AppOption {
+	return func(o *ApplicationConfig) {
+		o.StorageEncryptionKey = key
+	}
+}
+
+// WithImageMetadata embeds the generation parameters, as the prompt, the seed and the hash of the model, in the
+// metadata of the generated images
+func WithImageMetadata(enabled bool) AppOption {
+	return func(o *ApplicationConfig) {
+		o.ImageMetadata = enabled
+	}
+}
+
+// WithStrictConfig refuses to start when the configurations of the models have unknown fields, as typos,
+// or cannot be loaded, instead of skipping them
+func WithStrictConfig(strict bool) AppOption {
+	return func(o *ApplicationConfig) {
+		o.StrictConfig = strict
+	}
+}
+
+// WithC2PA signs the generated images with C2PA provenance manifests, with c2patool and its manifest definition
+func WithC2PA(manifest, tool string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.C2PAManifest = manifest
+		o.C2PATool = tool
+	}
+}
+
+// WithFineTuneCommand sets the shell command run by the fine-tuning jobs, fine-tuning is disabled without it
+func WithFineTuneCommand(command string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.FineTuneCommand = command
+	}
+}
+
 func WithConfigsDir(configsDir string) AppOption {
 	return func(o *ApplicationConfig) {
 		o.ConfigsDir = configsDir
@@ -309,12 +634,94 @@ func WithApiKeys(apiKeys []string) AppOption {
 	}
 }
 
+func WithAPIKeyDailyTokens(tokens int) AppOption {
+	return func(o *ApplicationConfig) {
+		o.APIKeyDailyTokens = tokens
+	}
+}
+
+func WithTenants(tenants map[string]Tenant) AppOption {
+	return func(o *ApplicationConfig) {
+		o.Tenants = tenants
+	}
+}
+
+func WithPipelines(pipelines map[string]Pipeline) AppOption {
+	return func(o *ApplicationConfig) {
+		o.Pipelines = pipelines
+	}
+}
+
+func WithTasks(tasks map[string]ScheduledTask) AppOption {
+	return func(o *ApplicationConfig) {
+		o.Tasks = tasks
+	}
+}
+
+// WithTasksDir sets the directory of the files written by the scheduled tasks
+func WithTasksDir(dir string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.TasksDir = dir
+	}
+}
+
+// WithTaskWebhookAllowlist sets the hosts the tasks of the tenants can post their output to, the tenants
+// cannot use webhooks without hosts
+func WithTaskWebhookAllowlist(hosts []string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.TaskWebhookAllowlist = hosts
+	}
+}
+
+// WithAgentFetchAllowlist sets the hosts the web_fetch tool of the agents can fetch, the tool is
+// disabled without hosts
+func WithAgentFetchAllowlist(hosts []string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.AgentFetchAllowlist = hosts
+	}
+}
+
+// WithProfile disables the subsystems which are not part of the startup profile
+func WithProfile(p Profile) AppOption {
+	return func(o *ApplicationConfig) {
+		o.Profile = p.Name
+		o.DisableWebUI = o.DisableWebUI || !p.WebUI
+		o.DisableGalleryEndpoint = !p.Galleries
+		o.DisableMetrics = !p.Metrics
+		o.DisablePythonBackends = !p.PythonBackends
+	}
+}
+
+func WithSettingSources(sources map[string]string) AppOption {
+	return func(o *ApplicationConfig) {
+		o.SettingSources = sources
+	}
+}
+
+func WithModelReload(delay time.Duration) AppOption {
+	return func(o *ApplicationConfig) {
+		o.ModelReloadDelay = delay
+	}
+}
+
+func WithModelSchedules(schedules []ModelSchedule) AppOption {
+	return func(o *ApplicationConfig) {
+		o.ModelSchedules = schedules
+	}
+}
+
 func WithEnforcedPredownloadScans(enforced bool) AppOption {
 	return func(o *ApplicationConfig) {
 		o.EnforcePredownloadScans = enforced
 	}
 }
 
+func WithPprof(enabled bool) AppOption {
+	return func(o *ApplicationConfig) {
+		o.EnablePprof = enabled
+	}
+}
+
 func WithOpaqueErrors(opaque bool) AppOption {
 	return func(o *ApplicationConfig) {
 		o.OpaqueErrors = opaque
@@ -334,7 +741,98 @@ func (o *ApplicationConfig) ToConfigLoaderOptions() []ConfigLoaderOption {
 		LoadOptionF16(o.F16),
 		LoadOptionThreads(o.Threads),
 		ModelPath(o.ModelPath),
+		LoadOptionStrict(o.StrictConfig),
+	}
+}
+
+// ToEnvironment returns the runtime settings as the environment variables read by `local-ai run`,
+// to be saved in an env file and applied to another instance. The paths are specific to each
+// instance and are not included, neither are the secrets as the API keys and the P2P token.
+func (o *ApplicationConfig) ToEnvironment() map[string]string {
+	env := map[string]string{
+		"LOCALAI_CONTEXT_SIZE":             strconv.Itoa(o.ContextSize),
+		"LOCALAI_F16":                      strconv.FormatBool(o.F16),
+		"LOCALAI_UPLOAD_LIMIT":             strconv.Itoa(o.UploadLimitMB),
+		"LOCALAI_JSON_BODY_LIMIT":          strconv.Itoa(o.JSONBodyLimitMB),
+		"LOCALAI_MAX_MESSAGES":             strconv.Itoa(o.MaxMessages),
+		"LOCALAI_MAX_IMAGE_SIZE":           strconv.Itoa(o.MaxImageSizeMB),
+		"LOCALAI_READ_TIMEOUT":             o.ReadTimeout.String(),
+		"LOCALAI_WRITE_TIMEOUT":            o.WriteTimeout.String(),
+		"LOCALAI_IDLE_TIMEOUT":             o.IdleTimeout.String(),
+		"LOCALAI_MAX_CONNS_PER_IP":         strconv.Itoa(o.MaxConnsPerIP),
+		"LOCALAI_UPLOAD_QUOTA":             strconv.Itoa(o.UploadQuotaMB),
+		"LOCALAI_CORS":                     strconv.FormatBool(o.CORS),
+		"LOCALAI_CSRF":                     strconv.FormatBool(o.CSRF),
+		"LOCALAI_DISABLE_WEBUI":            strconv.FormatBool(o.DisableWebUI),
+		"LOCALAI_AUTOLOAD_GALLERIES":       strconv.FormatBool(o.AutoloadGalleries),
+		"LOCALAI_PARALLEL_REQUESTS":        strconv.FormatBool(o.ParallelBackendRequests),
+		"LOCALAI_SINGLE_ACTIVE_BACKEND":    strconv.FormatBool(o.SingleBackend),
+		"LOCALAI_WATCHDOG_IDLE":            strconv.FormatBool(o.WatchDogIdle),
+		"LOCALAI_WATCHDOG_BUSY":            strconv.FormatBool(o.WatchDogBusy),
+		"LOCALAI_DISABLE_PREDOWNLOAD_SCAN": strconv.FormatBool(!o.EnforcePredownloadScans),
+		"LOCALAI_OPAQUE_ERRORS":            strconv.FormatBool(o.OpaqueErrors),
+		"LOCALAI_OUTPUT_URL_EXPIRY":        o.OutputURLExpiry.String(),
+		"LOCALAI_JOB_RETENTION":            o.JobRetention.String(),
+		"LOCALAI_IMAGE_METADATA":           strconv.FormatBool(o.ImageMetadata),
+		"LOCALAI_STRICT_CONFIG":            strconv.FormatBool(o.StrictConfig),
+	}
+	if o.Threads != 0 {
+		env["LOCALAI_THREADS"] = strconv.Itoa(o.Threads)
+	}
+	if o.CORSAllowOrigins != "" {
+		env["LOCALAI_CORS_ALLOW_ORIGINS"] = o.CORSAllowOrigins
+	}
+	if o.UploadRetention != 0 {
+		env["LOCALAI_UPLOAD_RETENTION"] = o.UploadRetention.String()
+	}
+	if o.GeneratedRetention != 0 {
+		env["LOCALAI_GENERATED_RETENTION"] = o.GeneratedRetention.String()
+	}
+	for name, size := range map[string]int{
+		"LOCALAI_UPLOAD_PATH_MAX_SIZE": o.UploadDirMaxSizeMB,
+		"LOCALAI_IMAGE_PATH_MAX_SIZE":  o.ImageDirMaxSizeMB,
+		"LOCALAI_AUDIO_PATH_MAX_SIZE":  o.AudioDirMaxSizeMB,
+	} {
+		if size != 0 {
+			env[name] = strconv.Itoa(size)
+		}
+	}
+	if o.WatchDogIdle {
+		env["LOCALAI_WATCHDOG_IDLE_TIMEOUT"] = o.WatchDogIdleTimeout.String()
+	}
+	if o.WatchDogBusy {
+		env["LOCALAI_WATCHDOG_BUSY_TIMEOUT"] = o.WatchDogBusyTimeout.String()
+	}
+	if len(o.WatchDogBusyActions) > 0 {
+		env["LOCALAI_WATCHDOG_BUSY_ACTIONS"] = strings.Join(o.WatchDogBusyActions, ",")
+	}
+	if len(o.WatchDogIdleActions) > 0 {
+		env["LOCALAI_WATCHDOG_IDLE_ACTIONS"] = strings.Join(o.WatchDogIdleActions, ",")
+	}
+	if o.ModelLibraryURL != "" {
+		env["LOCALAI_REMOTE_LIBRARY"] = o.ModelLibraryURL
+	}
+	if o.Profile != "" {
+		env["LOCALAI_PROFILE"] = o.Profile
+	}
+	if o.P2PNetworkID != "" {
+		env["LOCALAI_P2P_NETWORK_ID"] = o.P2PNetworkID
+	}
+	if len(o.Galleries) > 0 {
+		galleries, err := json.Marshal(o.Galleries)
+		if err == nil {
+			env["LOCALAI_GALLERIES"] = string(galleries)
+		}
+	}
+	if len(o.ExternalGRPCBackends) > 0 {
+		backends := []string{}
+		for name, uri := range o.ExternalGRPCBackends {
+			backends = append(backends, name+":"+uri)
+		}
+		sort.Strings(backends)
+		env["LOCALAI_EXTERNAL_GRPC_BACKENDS"] = strings.Join(backends, ",")
 	}
+	return env
 }
 
 // func WithMetrics(meter *metrics.Metrics) AppOption {
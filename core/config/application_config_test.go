@@ -0,0 +1,48 @@
+package config
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplicationConfig", func() {
+	It("exports the runtime settings as environment variables", func() {
+		appConfig := NewApplicationConfig(
+			WithContextSize(2048),
+			WithThreads(4),
+			WithApiKeys([]string{"secret"}),
+			WithExternalBackend("vllm", "127.0.0.1:50051"),
+			WithExternalBackend("bark", "/backends/bark/run.sh"),
+			WithGalleries([]Gallery{{Name: "localai", URL: "github:mudler/LocalAI/gallery/index.yaml@master"}}),
+			EnableWatchDog,
+			EnableWatchDogIdleCheck,
+			SetWatchDogIdleTimeout(10*time.Minute),
+			SetWatchDogActions([]string{"kill"}, []string{"log", "webhook"}),
+			SetWatchDogWebhook("https://hooks.example.com/secret"),
+			WithImageMetadata(true),
+			WithC2PA("/certs/manifest.json", "/usr/bin/c2patool"),
+			WithGeneratedRetention(72*time.Hour),
+			WithImageDirMaxSizeMB(2048),
+		)
+
+		env := appConfig.ToEnvironment()
+		Expect(env).To(HaveKeyWithValue("LOCALAI_CONTEXT_SIZE", "2048"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_THREADS", "4"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_EXTERNAL_GRPC_BACKENDS", "bark:/backends/bark/run.sh,vllm:127.0.0.1:50051"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_GALLERIES", `[{"url":"github:mudler/LocalAI/gallery/index.yaml@master","name":"localai"}]`))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_WATCHDOG_IDLE", "true"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_WATCHDOG_IDLE_TIMEOUT", "10m0s"))
+		Expect(env).ToNot(HaveKey("LOCALAI_WATCHDOG_BUSY_TIMEOUT"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_WATCHDOG_IDLE_ACTIONS", "log,webhook"))
+		Expect(env).ToNot(HaveKey("LOCALAI_WATCHDOG_WEBHOOK"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_IMAGE_METADATA", "true"))
+		Expect(env).ToNot(HaveKey("LOCALAI_C2PA_MANIFEST"))
+		Expect(env).ToNot(HaveKey("LOCALAI_API_KEY"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_GENERATED_RETENTION", "72h0m0s"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_IMAGE_PATH_MAX_SIZE", "2048"))
+		Expect(env).ToNot(HaveKey("LOCALAI_AUDIO_PATH_MAX_SIZE"))
+		Expect(env).To(HaveKeyWithValue("LOCALAI_JOB_RETENTION", "24h0m0s"))
+	})
+})
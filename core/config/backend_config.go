@@ -1,13 +1,19 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/pkg/downloader"
 	"github.com/mudler/LocalAI/pkg/functions"
+	"github.com/mudler/LocalAI/pkg/model"
 )
 
 const (
@@ -36,7 +42,9 @@ type BackendConfig struct {
 	TemplateConfig TemplateConfig    `yaml:"template"`
 
 	PromptStrings, InputStrings                []string               `yaml:"-"`
+	Suffix                                     string                 `yaml:"-"`
 	InputToken                                 [][]int                `yaml:"-"`
+	InputImages                                []string               `yaml:"-"`
 	functionCallString, functionCallNameString string                 `yaml:"-"`
 	ResponseFormat                             string                 `yaml:"-"`
 	ResponseFormatMap                          map[string]interface{} `yaml:"-"`
@@ -60,6 +68,62 @@ type BackendConfig struct {
 	// TTS specifics
 	TTSConfig `yaml:"tts"`
 
+	// Speaker diarization of transcriptions
+	Diarization Diarization `yaml:"diarization"`
+
+	// Options of the models run by whisper.cpp
+	Whisper Whisper `yaml:"whisper"`
+
+	// Retrieval augmented generation of the answers of /v1/rag/query
+	RAG RAG `yaml:"rag"`
+
+	// Shadow traffic to a candidate model
+	Shadow Shadow `yaml:"shadow"`
+
+	// Languages translated by the model, or detection of the language, for /v1/translate
+	Translation Translation `yaml:"translation"`
+
+	// Named generation presets, and the preset used when the requests do not select one
+	Presets map[string]Preset `yaml:"presets"`
+	Preset  string            `yaml:"preset"`
+
+	// Hard cap of max_tokens, also used when neither the request nor the model set it
+	MaxTokensLimit int `yaml:"max_tokens_limit"`
+
+	// Generations of the streamed json mode requests, retried while their output is not valid JSON. 3 by default
+	JSONAttempts int `yaml:"json_attempts"`
+
+	// Retries of the failed inferences of the model, then models answering in its place, in order, when
+	// they all fail. FallbackConfigs are the configurations of the fallback models for the request
+	Retry           RetryPolicy      `yaml:"retry"`
+	Fallback        []string         `yaml:"fallback"`
+	FallbackConfigs []*BackendConfig `yaml:"-"`
+
+	// Request run after loading the model
+	Warmup Warmup `yaml:"warmup"`
+
+	// Tuning of the threads of the requests to the model, measured after loading it
+	ThreadsTuning ThreadsTuning `yaml:"threads_tuning"`
+
+	// Time the model stays loaded once idle, as "5m" or a number of seconds. A negative ttl keeps
+	// the model loaded, 0 stops it as soon as it is idle. It overrides the idle watchdog
+	TTL string `yaml:"ttl"`
+
+	// Actions of the watchdog for the model, overriding the ones of the instance
+	WatchDog WatchDogActions `yaml:"watchdog"`
+
+	// Limits of the resources of the backend process of the model
+	Resources Resources `yaml:"resources"`
+
+	// GPUs of the backend of the model: auto places it on the GPUs with the most free memory, one by
+	// ratio of the tensor_split, or a list of GPU indexes as "0,1" selects them
+	GPU string `yaml:"gpu"`
+
+	// Environment variables and arguments of the backend process of the model, for the settings of the
+	// backends without a field. Their values are templates, see BackendProcessData
+	Environment map[string]string `yaml:"environment"`
+	BackendArgs []string          `yaml:"backend_args"`
+
 	// CUDA
 	// Explicitly enable CUDA or not (some backends might need it)
 	CUDA bool `yaml:"cuda"`
@@ -76,6 +140,135 @@ type File struct {
 	URI      downloader.URI `yaml:"uri" json:"uri"`
 }
 
+// Diarization configures the speaker segmentation model run alongside
+// a transcription model when diarization is requested
+type Diarization struct {
+	// Backend defaults to the diarization python backend
+	Backend string `yaml:"backend"`
+	Model   string `yaml:"model"`
+}
+
+// RAG retrieves the documents answering the queries from a vector store, to generate the answers of the
+// model with them
+type RAG struct {
+	// Store holds the embeddings of the documents by EmbeddingModel, as keys, and the documents, as values
+	Store          string `yaml:"store"`
+	EmbeddingModel string `yaml:"embedding_model"`
+	// RerankModel reorders the retrieved documents by relevance, when set
+	RerankModel string `yaml:"rerank_model"`
+	// RetrieveK documents are retrieved from the store, and the TopN most relevant of them are kept in the prompt
+	RetrieveK int `yaml:"retrieve_k"`
+	TopN      int `yaml:"top_n"`
+}
+
+// Shadow mirrors a share of the chat requests of the model to a candidate model in the
+// background, to validate it with the production traffic before switching to it. The
+// responses of the candidate are not returned to the clients
+type Shadow struct {
+	Model string `yaml:"model"`
+	// Percentage of the requests mirrored, from 0 to 100
+	Percentage float64 `yaml:"percentage"`
+	// LogResponses logs the responses of both models, to compare them
+	LogResponses bool `yaml:"log_responses"`
+}
+
+// Warmup is a request run right after loading the model, so that the first request of the
+// users does not wait for the backend to compile its graphs or to fill its caches
+type Warmup struct {
+	// Prompt predicted by the warmup, with the parameters of the model
+	Prompt string `yaml:"prompt"`
+	// Tokens to predict, 1 by default
+	Tokens int `yaml:"tokens"`
+	// Embeddings computes the embedding of the prompt, or of a dummy text, instead of a prediction
+	Embeddings bool `yaml:"embeddings"`
+}
+
+// Enabled tells if the warmup is configured
+func (w Warmup) Enabled() bool {
+	return w.Prompt != "" || w.Embeddings
+}
+
+// ThreadsTuning measures the tokens per second of the model with several numbers of threads after loading
+// it, with the prompt of the warmup, and runs each request with the fastest number of threads which fits in
+// the CPUs left by the other models running requests at the time
+type ThreadsTuning struct {
+	Enabled bool `yaml:"enabled"`
+	// Candidates are the numbers of threads measured, the powers of two up to the number of CPUs by default
+	Candidates []int `yaml:"candidates"`
+	// Tokens predicted with each number of threads, 32 by default
+	Tokens int `yaml:"tokens"`
+}
+
+// WatchDogActions are the actions taken when the backend of the model is busy or idle for too
+// long: log, webhook, restart or kill. Unset keeps the actions of the instance
+type WatchDogActions struct {
+	BusyActions []string `yaml:"busy_actions"`
+	IdleActions []string `yaml:"idle_actions"`
+}
+
+// NUMA strategies of llama.cpp
+const (
+	// NUMADistribute spreads the threads over the NUMA nodes
+	NUMADistribute = "distribute"
+	// NUMAIsolate runs the threads on the NUMA node where the backend started
+	NUMAIsolate = "isolate"
+	// NUMANumactl runs the threads on the CPUs the backend is pinned to, as with numactl
+	NUMANumactl = "numactl"
+)
+
+// Pooling of the token embeddings into the embedding of the input
+const (
+	// PoolingMean averages the embeddings of the tokens
+	PoolingMean = "mean"
+	// PoolingCLS takes the embedding of the first token, as the BERT models
+	PoolingCLS = "cls"
+	// PoolingLast takes the embedding of the last token, as the decoder models
+	PoolingLast = "last"
+	// PoolingNone returns the embedding of the last token without pooling
+	PoolingNone = "none"
+)
+
+// NUMAPolicy returns the NUMA strategy of llama.cpp: the numa_strategy, or numactl for the backends pinned
+// to a NUMA node, or distribute when numa is enabled
+func (c *LLMConfig) NUMAPolicy() string {
+	switch {
+	case c.NUMAStrategy != "":
+		return c.NUMAStrategy
+	case c.NUMANode != nil:
+		return NUMANumactl
+	case c.NUMA:
+		return NUMADistribute
+	}
+	return ""
+}
+
+// Resources limits the resources of the backend process of the model, so that a model can not starve
+// the host. The limits are applied when the backend is started, the external backends given by their
+// address are not limited
+type Resources struct {
+	// CPUs the backend runs on, as "0-3,8"
+	CPUSet string `yaml:"cpuset"`
+	// Nice level of the backend, from -20 (highest priority) to 19
+	Nice *int `yaml:"nice"`
+	// Memory limit of the backend, as "8GiB", enforced with cgroups v2 where available
+	Memory string `yaml:"memory"`
+	// GPUs visible to the backend, as "0,1", set as CUDA_VISIBLE_DEVICES
+	GPUs string `yaml:"gpus"`
+}
+
+// Limits returns the limits of the resources of the backend, or an error if they are invalid
+func (r Resources) Limits() (model.Resources, error) {
+	limits := model.Resources{CPUSet: r.CPUSet, Nice: r.Nice, GPUs: r.GPUs}
+	if r.Memory != "" {
+		memory, err := humanize.ParseBytes(r.Memory)
+		if err != nil {
+			return limits, fmt.Errorf("invalid memory limit %q, expected a size as \"8GiB\"", r.Memory)
+		}
+		limits.Memory = memory
+	}
+	return limits, limits.Validate()
+}
+
 type VallE struct {
 	AudioPath string `yaml:"audio_path"`
 }
@@ -103,6 +296,31 @@ type Diffusers struct {
 	ClipModel        string  `yaml:"clip_model"`        // Clip model to use
 	ClipSubFolder    string  `yaml:"clip_subfolder"`    // Subfolder to use for clip model
 	ControlNet       string  `yaml:"control_net"`
+	// ControlNets are the ControlNet models the requests can use, in addition to ControlNet
+	ControlNets []string `yaml:"control_nets"`
+	// IP-Adapter loaded with the model, to use images as prompts
+	IPAdapter           string `yaml:"ip_adapter"`
+	IPAdapterSubFolder  string `yaml:"ip_adapter_subfolder"`
+	IPAdapterWeightName string `yaml:"ip_adapter_weight_name"`
+}
+
+// AllowedControlNet returns the ControlNet model to use for a request asking for name: the default
+// ControlNet of the model, or the first of its ControlNets, when name is empty
+func (d Diffusers) AllowedControlNet(name string) (string, error) {
+	allowed := d.ControlNets
+	if d.ControlNet != "" {
+		allowed = append([]string{d.ControlNet}, allowed...)
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("the model has no controlnet")
+	}
+	if name == "" {
+		return allowed[0], nil
+	}
+	if !slices.Contains(allowed, name) {
+		return "", fmt.Errorf("controlnet %q is not allowed for the model: expected one of %s", name, strings.Join(allowed, ", "))
+	}
+	return name, nil
 }
 
 // LLMConfig is a struct that holds the configuration that are
@@ -116,6 +334,8 @@ type LLMConfig struct {
 	PromptCachePath string   `yaml:"prompt_cache_path"`
 	PromptCacheAll  bool     `yaml:"prompt_cache_all"`
 	PromptCacheRO   bool     `yaml:"prompt_cache_ro"`
+	KVCachePath     string   `yaml:"kv_cache_path"`  // llama.cpp: saves the KV cache of the slots on unload, restores it on load
+	PrioritySlots   int      `yaml:"priority_slots"` // slots of the backend kept for the priority requests, as the code completions
 	MirostatETA     *float64 `yaml:"mirostat_eta"`
 	MirostatTAU     *float64 `yaml:"mirostat_tau"`
 	Mirostat        *int     `yaml:"mirostat"`
@@ -131,6 +351,10 @@ type LLMConfig struct {
 
 	ContextSize          *int    `yaml:"context_size"`
 	NUMA                 bool    `yaml:"numa"`
+	NUMAStrategy         string  `yaml:"numa_strategy"` // llama.cpp: distribute, isolate or numactl
+	NUMANode             *int    `yaml:"numa_node"`     // llama.cpp: pins the threads to the CPUs of the node
+	ThreadsBatch         int     `yaml:"threads_batch"` // llama.cpp: threads of the prompt processing
+	CPUAffinity          string  `yaml:"cpu_affinity"`  // llama.cpp: pins the threads to the CPUs, as "0-15"
 	LoraAdapter          string  `yaml:"lora_adapter"`
 	LoraBase             string  `yaml:"lora_base"`
 	LoraScale            float32 `yaml:"lora_scale"`
@@ -156,6 +380,11 @@ type LLMConfig struct {
 	YarnAttnFactor float32 `yaml:"yarn_attn_factor"`
 	YarnBetaFast   float32 `yaml:"yarn_beta_fast"`
 	YarnBetaSlow   float32 `yaml:"yarn_beta_slow"`
+
+	// Pooling of the token embeddings of the embedding models, to match the model card: mean, cls, last or none
+	Pooling string `yaml:"pooling"`
+	// NormalizeEmbeddings L2-normalizes the embeddings, so that their dot product is the cosine similarity
+	NormalizeEmbeddings bool `yaml:"normalize_embeddings"`
 }
 
 // AutoGPTQ is a struct that holds the configuration specific to the AutoGPTQ backend
@@ -183,6 +412,21 @@ type TemplateConfig struct {
 	// Functions is the template used when tools are present in the client requests
 	Functions string `yaml:"function"`
 
+	// RAG is the template of the prompt of the retrieval augmented generation, with the query and the retrieved documents
+	RAG string `yaml:"rag"`
+
+	// FIM is the template of the fill-in-the-middle requests, with the text before (.Prefix) and after (.Suffix)
+	// the completion. Without it, the llama.cpp backend uses the infill tokens of the model
+	FIM string `yaml:"fim"`
+
+	// Jinja is a chat template in the Jinja format, as the chat_template of the HuggingFace tokenizers.
+	// It renders the whole conversation, and is used in place of the chat and chat_message templates
+	Jinja string `yaml:"jinja"`
+
+	// Variables are custom variables of the model, available as .Variables in the templates,
+	// and at the top level in the Jinja templates (e.g. to set bos_token and eos_token)
+	Variables map[string]interface{} `yaml:"variables"`
+
 	// UseTokenizerTemplate is a flag that indicates if the tokenizer template should be used.
 	// Note: this is mostly consumed for backends such as vllm and transformers
 	// that can use the tokenizers specified in the JSON config files of the models
@@ -252,6 +496,18 @@ func (c *BackendConfig) FunctionToCall() string {
 	return c.functionCallString
 }
 
+// LimitMaxTokens caps the number of tokens to generate to the limit of the model. Without a
+// number of tokens, the generation is unbounded and the limit is used instead
+func (cfg *BackendConfig) LimitMaxTokens() {
+	limit := cfg.MaxTokensLimit
+	if limit <= 0 {
+		return
+	}
+	if cfg.Maxtokens == nil || *cfg.Maxtokens <= 0 || *cfg.Maxtokens > limit {
+		cfg.Maxtokens = &limit
+	}
+}
+
 func (cfg *BackendConfig) SetDefaults(opts ...ConfigLoaderOption) {
 	lo := &LoadOptions{}
 	lo.Apply(opts...)
@@ -384,7 +640,7 @@ func (c *BackendConfig) Validate() bool {
 	for _, f := range c.DownloadFiles {
 		downloadedFileNames = append(downloadedFileNames, f.Filename)
 	}
-	validationTargets := []string{c.Backend, c.Model, c.MMProj}
+	validationTargets := []string{c.Backend, c.Model, c.MMProj, c.KVCachePath}
 	validationTargets = append(validationTargets, downloadedFileNames...)
 	// Simple validation to make sure the model can be correctly loaded
 	for _, n := range validationTargets {
@@ -406,6 +662,31 @@ func (c *BackendConfig) Validate() bool {
 	return true
 }
 
+// ParseTTL parses the ttl of the models and the keep_alive of the requests: a duration, as "5m",
+// or a number of seconds
+func ParseTTL(ttl string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(ttl, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q, expected a duration as \"5m\" or a number of seconds", ttl)
+	}
+	return d, nil
+}
+
+// IdleTimeout returns the time the model stays loaded once idle, if the model has a ttl
+func (c *BackendConfig) IdleTimeout() (time.Duration, bool) {
+	if c.TTL == "" {
+		return 0, false
+	}
+	d, err := ParseTTL(c.TTL)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
 func (c *BackendConfig) HasTemplate() bool {
-	return c.TemplateConfig.Completion != "" || c.TemplateConfig.Edit != "" || c.TemplateConfig.Chat != "" || c.TemplateConfig.ChatMessage != ""
+	return c.TemplateConfig.Completion != "" || c.TemplateConfig.Edit != "" || c.TemplateConfig.Chat != "" || c.TemplateConfig.ChatMessage != "" || c.TemplateConfig.Jinja != ""
 }
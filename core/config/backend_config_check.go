@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mudler/LocalAI/pkg/downloader"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/templates"
+	"github.com/mudler/LocalAI/pkg/utils"
+)
+
+// ConfigError is an error found in the configuration file of a model
+type ConfigError struct {
+	File string
+	Err  error
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Err)
+}
+
+// CheckBackendConfigsFromPath reads all the configurations of the models from a path, as
+// LoadBackendConfigsFromPath does, but returns the errors found in every file instead of
+// skipping the invalid ones. Besides being readable without unknown fields, the configurations must reference files
+// that exist in the path, templates that compile and, if backends is not nil, one of the backends
+func CheckBackendConfigsFromPath(path string, backends []string, opts ...ConfigLoaderOption) ([]ConfigError, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory '%s': %w", path, err)
+	}
+
+	configErrors := []ConfigError{}
+	names := map[string]string{}
+	for _, entry := range entries {
+		// Skip templates, YAML and .keep files
+		if entry.IsDir() ||
+			!strings.Contains(entry.Name(), ".yaml") && !strings.Contains(entry.Name(), ".yml") ||
+			strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			configErrors = append(configErrors, ConfigError{File: entry.Name(), Err: err})
+			continue
+		}
+		for _, err := range UnknownFields(data) {
+			configErrors = append(configErrors, ConfigError{File: entry.Name(), Err: err})
+		}
+		c, err := parseBackendConfig(data, opts...)
+		if err != nil {
+			configErrors = append(configErrors, ConfigError{File: entry.Name(), Err: err})
+			continue
+		}
+
+		for _, err := range c.check(path, backends) {
+			configErrors = append(configErrors, ConfigError{File: entry.Name(), Err: err})
+		}
+
+		if c.Name != "" {
+			if other, exists := names[c.Name]; exists {
+				configErrors = append(configErrors, ConfigError{File: entry.Name(), Err: fmt.Errorf("model name %q is already used by %s", c.Name, other)})
+			}
+			names[c.Name] = entry.Name()
+		}
+	}
+
+	sort.SliceStable(configErrors, func(i, j int) bool {
+		return configErrors[i].File < configErrors[j].File
+	})
+
+	return configErrors, nil
+}
+
+// modelFileExtensions are the extensions of the model files, which are loaded from the models path
+// while names without one can be repositories (e.g. for the transformers or vllm backends)
+var modelFileExtensions = []string{".gguf", ".ggml", ".bin", ".onnx", ".safetensors", ".pt", ".pth", ".ckpt"}
+
+// check returns all the errors of the configuration, while Validate only tells if it can be loaded
+func (c *BackendConfig) check(modelPath string, backends []string) []error {
+	errs := []error{}
+	if c.Name == "" {
+		errs = append(errs, errors.New("name is missing"))
+	}
+	if !c.Validate() {
+		// The other checks would be unsafe on paths escaping the models path
+		return append(errs, errors.New("backend and file names must be relative paths, and backend names can only contain letters, digits, '-' and '_'"))
+	}
+
+	if backends != nil && c.Backend != "" && !slices.Contains(backends, c.Backend) {
+		errs = append(errs, fmt.Errorf("unknown backend %q, available backends: %s", c.Backend, strings.Join(backends, ", ")))
+	}
+
+	// Models without a backend are tried with the backends loading files, as llama.cpp
+	if c.Model != "" && !c.IsModelURL() &&
+		(c.Backend == "" || strings.HasPrefix(c.Backend, "llama") || slices.Contains(modelFileExtensions, strings.ToLower(filepath.Ext(c.Model)))) &&
+		!utils.ExistsInPath(modelPath, c.Model) {
+		errs = append(errs, fmt.Errorf("model file %s does not exist", c.Model))
+	}
+	for _, f := range []struct {
+		name, file string
+	}{
+		{"mmproj", c.MMProj},
+		{"draft_model", c.DraftModel},
+	} {
+		if f.file != "" && !downloader.URI(f.file).LooksLikeURL() && !utils.ExistsInPath(modelPath, f.file) {
+			errs = append(errs, fmt.Errorf("%s file %s does not exist", f.name, f.file))
+		}
+	}
+
+	if c.Shadow.Percentage < 0 || c.Shadow.Percentage > 100 {
+		errs = append(errs, fmt.Errorf("shadow percentage %g must be between 0 and 100", c.Shadow.Percentage))
+	}
+	if c.Shadow.Model != "" && c.Shadow.Model == c.Name {
+		errs = append(errs, errors.New("shadow model must be another model"))
+	}
+
+	if err := c.Retry.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, fallback := range c.Fallback {
+		if fallback == "" || fallback == c.Name {
+			errs = append(errs, errors.New("fallback models must be other models"))
+			break
+		}
+	}
+
+	if c.TTL != "" {
+		if _, err := ParseTTL(c.TTL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, actions := range [][]string{c.WatchDog.BusyActions, c.WatchDog.IdleActions} {
+		if err := model.ValidateWatchDogActions(actions); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := c.Resources.Limits(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := c.BackendProcess(modelPath); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, c.checkBackendOptions()...)
+	switch c.NUMAStrategy {
+	case "", NUMADistribute, NUMAIsolate, NUMANumactl:
+	default:
+		errs = append(errs, fmt.Errorf("invalid numa_strategy %q: expected one of %s, %s or %s", c.NUMAStrategy, NUMADistribute, NUMAIsolate, NUMANumactl))
+	}
+	if c.PrioritySlots < 0 {
+		errs = append(errs, fmt.Errorf("priority_slots %d must be positive", c.PrioritySlots))
+	}
+	if err := c.Translation.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	switch c.Pooling {
+	case "", PoolingMean, PoolingCLS, PoolingLast, PoolingNone:
+	default:
+		errs = append(errs, fmt.Errorf("invalid pooling %q: expected one of %s, %s, %s or %s", c.Pooling, PoolingMean, PoolingCLS, PoolingLast, PoolingNone))
+	}
+	if c.NUMANode != nil && *c.NUMANode < 0 {
+		errs = append(errs, fmt.Errorf("invalid numa_node %d", *c.NUMANode))
+	}
+	if c.NUMANode != nil && c.CPUAffinity != "" {
+		errs = append(errs, errors.New("numa_node and cpu_affinity can not be both set"))
+	}
+	if _, err := model.ParseCPUSet(c.CPUAffinity); err != nil {
+		errs = append(errs, fmt.Errorf("invalid cpu_affinity: %w", err))
+	}
+	for _, threads := range c.ThreadsTuning.Candidates {
+		if threads <= 0 {
+			errs = append(errs, fmt.Errorf("invalid threads_tuning candidate %d", threads))
+			break
+		}
+	}
+	if c.ThreadsBatch < 0 {
+		errs = append(errs, fmt.Errorf("invalid threads_batch %d", c.ThreadsBatch))
+	}
+	if err := model.ValidateGPU(c.GPU); err != nil {
+		errs = append(errs, err)
+	}
+	if c.GPU != "" && c.Resources.GPUs != "" {
+		errs = append(errs, errors.New("gpu and resources.gpus can not be both set"))
+	}
+
+	tc := templates.NewTemplateCache(modelPath)
+	for i, t := range []struct {
+		name, template string
+	}{
+		{"chat", c.TemplateConfig.Chat},
+		{"chat_message", c.TemplateConfig.ChatMessage},
+		{"completion", c.TemplateConfig.Completion},
+		{"edit", c.TemplateConfig.Edit},
+		{"function", c.TemplateConfig.Functions},
+	} {
+		if t.template == "" {
+			continue
+		}
+		// Without any action, the template is meant to be a file rather than the template itself
+		if !strings.Contains(t.template, "{{") && !strings.Contains(t.template, "\n") &&
+			!utils.ExistsInPath(modelPath, t.template+".tmpl") {
+			errs = append(errs, fmt.Errorf("%s template file %s.tmpl does not exist", t.name, t.template))
+			continue
+		}
+		if err := tc.LoadTemplate(templates.TemplateType(i), t.template); err != nil {
+			errs = append(errs, fmt.Errorf("%s template does not compile: %w", t.name, err))
+		}
+	}
+
+	if j := c.TemplateConfig.Jinja; j != "" {
+		if !strings.ContainsAny(j, "\n{") && !utils.ExistsInPath(modelPath, j+".jinja") {
+			errs = append(errs, fmt.Errorf("jinja template file %s.jinja does not exist", j))
+		} else if err := tc.LoadJinjaTemplate(j); err != nil {
+			errs = append(errs, fmt.Errorf("jinja template does not compile: %w", err))
+		}
+	}
+
+	return errs
+}
@@ -20,7 +20,10 @@ import (
 
 type BackendConfigLoader struct {
 	configs   map[string]BackendConfig
+	presets   map[string]Preset
 	modelPath string
+	// preloadProgress is notified of the progress of the downloads of Preload
+	preloadProgress func(model string, percent float64)
 	sync.Mutex
 }
 
@@ -36,6 +39,7 @@ type LoadOptions struct {
 	debug            bool
 	threads, ctxSize int
 	f16              bool
+	strict           bool
 }
 
 func LoadOptionDebug(debug bool) ConfigLoaderOption {
@@ -56,6 +60,13 @@ func LoadOptionContextSize(ctxSize int) ConfigLoaderOption {
 	}
 }
 
+// LoadOptionStrict refuses the configurations with unknown fields, which are otherwise ignored
+func LoadOptionStrict(strict bool) ConfigLoaderOption {
+	return func(o *LoadOptions) {
+		o.strict = strict
+	}
+}
+
 func ModelPath(modelPath string) ConfigLoaderOption {
 	return func(o *LoadOptions) {
 		o.modelPath = modelPath
@@ -78,12 +89,18 @@ func (lo *LoadOptions) Apply(options ...ConfigLoaderOption) {
 
 // TODO: either in the next PR or the next commit, I want to merge these down into a single function that looks at the first few characters of the file to determine if we need to deserialize to []BackendConfig or BackendConfig
 func readMultipleBackendConfigsFromFile(file string, opts ...ConfigLoaderOption) ([]*BackendConfig, error) {
+	lo := &LoadOptions{}
+	lo.Apply(opts...)
+
 	c := &[]*BackendConfig{}
 	f, err := os.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read config file: %w", err)
 	}
-	if err := yaml.Unmarshal(f, c); err != nil {
+	if err := checkUnknownFields(file, f, lo.strict); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(ExpandEnv(f), c); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal config file: %w", err)
 	}
 
@@ -98,12 +115,35 @@ func readBackendConfigFromFile(file string, opts ...ConfigLoaderOption) (*Backen
 	lo := &LoadOptions{}
 	lo.Apply(opts...)
 
-	c := &BackendConfig{}
 	f, err := os.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read config file: %w", err)
 	}
-	if err := yaml.Unmarshal(f, c); err != nil {
+	if err := checkUnknownFields(file, f, lo.strict); err != nil {
+		return nil, err
+	}
+	return parseBackendConfig(f, opts...)
+}
+
+// checkUnknownFields logs the unknown fields of the configuration file, or returns them in strict mode
+func checkUnknownFields(file string, data []byte, strict bool) error {
+	errs := UnknownFields(data)
+	if len(errs) == 0 {
+		return nil
+	}
+	if strict {
+		return InvalidBackendConfigError{Errs: errs}
+	}
+	for _, err := range errs {
+		log.Warn().Msgf("%s: %s, the field is ignored", filepath.Base(file), err)
+	}
+	return nil
+}
+
+// parseBackendConfig reads a configuration as it is read from its file
+func parseBackendConfig(data []byte, opts ...ConfigLoaderOption) (*BackendConfig, error) {
+	c := &BackendConfig{}
+	if err := yaml.Unmarshal(ExpandEnv(data), c); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal config file: %w", err)
 	}
 
@@ -207,15 +247,19 @@ func (bcl *BackendConfigLoader) RemoveBackendConfig(m string) {
 	delete(bcl.configs, m)
 }
 
+// SetPreloadProgress sets the function notified of the progress of the downloads of the models, by the
+// name of their model file. The percentage is the one of the file being downloaded
+func (bcl *BackendConfigLoader) SetPreloadProgress(f func(model string, percent float64)) {
+	bcl.Lock()
+	defer bcl.Unlock()
+	bcl.preloadProgress = f
+}
+
 // Preload prepare models if they are not local but url or huggingface repositories
 func (bcl *BackendConfigLoader) Preload(modelPath string) error {
 	bcl.Lock()
 	defer bcl.Unlock()
 
-	status := func(fileName, current, total string, percent float64) {
-		utils.DisplayDownloadFunction(fileName, current, total, percent)
-	}
-
 	log.Info().Msgf("Preloading models from %s", modelPath)
 
 	renderMode := "dark"
@@ -233,6 +277,12 @@ func (bcl *BackendConfigLoader) Preload(modelPath string) error {
 	}
 
 	for i, config := range bcl.configs {
+		status := func(fileName, current, total string, percent float64) {
+			utils.DisplayDownloadFunction(fileName, current, total, percent)
+			if bcl.preloadProgress != nil {
+				bcl.preloadProgress(config.ModelFileName(), percent)
+			}
+		}
 
 		// Download files and verify their SHA
 		for i, file := range config.DownloadFiles {
@@ -304,6 +354,9 @@ func (bcl *BackendConfigLoader) Preload(modelPath string) error {
 func (bcl *BackendConfigLoader) LoadBackendConfigsFromPath(path string, opts ...ConfigLoaderOption) error {
 	bcl.Lock()
 	defer bcl.Unlock()
+	lo := &LoadOptions{}
+	lo.Apply(opts...)
+
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		return fmt.Errorf("cannot read directory '%s': %w", path, err)
@@ -316,6 +369,7 @@ func (bcl *BackendConfigLoader) LoadBackendConfigsFromPath(path string, opts ...
 		}
 		files = append(files, info)
 	}
+	var errs []error
 	for _, file := range files {
 		// Skip templates, YAML and .keep files
 		if !strings.Contains(file.Name(), ".yaml") && !strings.Contains(file.Name(), ".yml") ||
@@ -325,14 +379,21 @@ func (bcl *BackendConfigLoader) LoadBackendConfigsFromPath(path string, opts ...
 		c, err := readBackendConfigFromFile(filepath.Join(path, file.Name()), opts...)
 		if err != nil {
 			log.Error().Err(err).Msgf("cannot read config file: %s", file.Name())
+			if lo.strict {
+				errs = append(errs, ConfigError{File: file.Name(), Err: err})
+			}
 			continue
 		}
 		if c.Validate() {
 			bcl.configs[c.Name] = *c
 		} else {
 			log.Error().Err(err).Msgf("config is not valid")
+			if lo.strict {
+				errs = append(errs, ConfigError{File: file.Name(), Err: errors.New("config is not valid")})
+			}
 		}
 	}
 
-	return nil
+	// In strict mode the files which are skipped are errors
+	return errors.Join(errs...)
 }
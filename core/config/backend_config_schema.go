@@ -0,0 +1,249 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a field of a configuration file which is not a setting of the models, as a typo
+type FieldError struct {
+	Line, Column int
+	// Field is the path of the field, e.g. parameters.temprature
+	Field string
+	// Suggestion is the closest known field, if any
+	Suggestion string
+}
+
+func (e FieldError) Error() string {
+	msg := fmt.Sprintf("line %d: unknown field %q", e.Line, e.Field)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// UnknownFields returns the fields of the YAML configuration of a model, or of a list of them, which
+// are not settings of the models. The data which is not valid YAML has no unknown fields
+func UnknownFields(data []byte) []error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(ExpandEnv(data), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	t := reflect.TypeOf(BackendConfig{})
+	if doc.Content[0].Kind == yaml.SequenceNode {
+		t = reflect.TypeOf([]BackendConfig{})
+	}
+
+	errs := []error{}
+	for _, e := range unknownFields(doc.Content[0], t, "") {
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+func unknownFields(node *yaml.Node, t reflect.Type, path string) []FieldError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	errs := []FieldError{}
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields, anyKey := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			field, known := fields[key.Value]
+			if !known {
+				if !anyKey {
+					errs = append(errs, FieldError{Line: key.Line, Column: key.Column, Field: path + key.Value, Suggestion: suggestField(key.Value, fields, path)})
+				}
+				continue
+			}
+			errs = append(errs, unknownFields(value, field.Type, path+key.Value+".")...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, unknownFields(node.Content[i+1], t.Elem(), path+node.Content[i].Value+".")...)
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			errs = append(errs, unknownFields(item, t.Elem(), fmt.Sprintf("%s%d.", path, i))...)
+		}
+	}
+	return errs
+}
+
+// yamlFields returns the fields of the struct by their YAML key, as yaml.v3 decodes them. anyKey is true
+// if the struct has an inlined map, which accepts any key
+func yamlFields(t reflect.Type) (fields map[string]reflect.StructField, anyKey bool) {
+	fields = map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		if strings.Contains(flags, "inline") {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Map {
+				anyKey = true
+				continue
+			}
+			inlined, inlinedAnyKey := yamlFields(ft)
+			for k, v := range inlined {
+				fields[k] = v
+			}
+			anyKey = anyKey || inlinedAnyKey
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields, anyKey
+}
+
+// suggestField returns the known field closest to the unknown one: the same field without its
+// separators, as contextsize for context_size, or one with at most two different characters, one for
+// the short fields
+func suggestField(unknown string, fields map[string]reflect.StructField, path string) string {
+	normalize := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", min(3, len(unknown)/3+1)
+	for _, name := range names {
+		if normalize(name) == normalize(unknown) {
+			return path + name
+		}
+		if d := levenshtein(name, unknown); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return path + best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// BackendConfigJSONSchema returns the JSON schema of the YAML configuration of the models, generated
+// from the settings LocalAI reads, so that the editors can complete and check the files. The fields are
+// described by their description tag, and constrained by their jsonschema tag, as
+// `jsonschema:"minimum=0,maximum=1"` or `jsonschema:"enum=greedy|beam"`
+func BackendConfigJSONSchema() map[string]interface{} {
+	s := typeSchema(reflect.TypeOf(BackendConfig{}), map[reflect.Type]bool{})
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "LocalAI model configuration"
+	return s
+}
+
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		// the recursive types are not described further
+		if visiting[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		fields, anyKey := yamlFields(t)
+		properties := map[string]interface{}{}
+		for name, f := range fields {
+			properties[name] = fieldSchema(f, visiting)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": anyKey}
+	}
+	// interfaces accept any value
+	return map[string]interface{}{}
+}
+
+// fieldSchema returns the schema of the type of the field, with the description and the constraints of
+// its tags
+func fieldSchema(f reflect.StructField, visiting map[reflect.Type]bool) map[string]interface{} {
+	s := typeSchema(f.Type, visiting)
+	if description := f.Tag.Get("description"); description != "" {
+		s["description"] = description
+	}
+	for _, constraint := range strings.Split(f.Tag.Get("jsonschema"), ",") {
+		key, value, found := strings.Cut(constraint, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "enum":
+			values := []interface{}{}
+			for _, v := range strings.Split(value, "|") {
+				values = append(values, v)
+			}
+			s[key] = values
+		default:
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				s[key] = n
+			} else {
+				s[key] = value
+			}
+		}
+	}
+	return s
+}
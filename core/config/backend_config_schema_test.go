@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unknown fields", func() {
+	It("reports the unknown fields with their line and the closest known field", func() {
+		errs := UnknownFields([]byte(`name: llama
+contextsize: 4096
+parameters:
+  model: llama.gguf
+  temprature: 0.2
+template:
+  chat: chat
+  foo: bar
+`))
+		Expect(errs).To(HaveLen(3))
+		Expect(errs[0]).To(Equal(FieldError{Line: 2, Column: 1, Field: "contextsize", Suggestion: "context_size"}))
+		Expect(errs[0].Error()).To(Equal(`line 2: unknown field "contextsize", did you mean "context_size"?`))
+		Expect(errs[1]).To(Equal(FieldError{Line: 5, Column: 3, Field: "parameters.temprature", Suggestion: "parameters.temperature"}))
+		Expect(errs[2]).To(Equal(FieldError{Line: 8, Column: 3, Field: "template.foo"}))
+	})
+
+	It("accepts the known fields", func() {
+		Expect(UnknownFields([]byte(`name: llama
+backend: llama-cpp
+context_size: 4096
+gpu_layers: 99
+parameters:
+  model: llama.gguf
+  temperature: 0.2
+roles:
+  user: "USER:"
+presets:
+  precise:
+    temperature: 0.1
+download_files:
+- filename: llama.gguf
+  uri: https://example.com/llama.gguf
+`))).To(BeEmpty())
+	})
+
+	It("checks the lists of configurations", func() {
+		errs := UnknownFields([]byte("- name: a\n- name: b\n  treads: 4\n"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0]).To(Equal(FieldError{Line: 3, Column: 3, Field: "1.treads", Suggestion: "1.threads"}))
+	})
+
+	It("loads the configurations with unknown fields unless strict", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "llama.yaml"), []byte("name: llama\ncontextsize: 4096\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "phi.yaml"), []byte("name: phi\n"), 0600)).To(Succeed())
+
+		bcl := NewBackendConfigLoader(dir)
+		Expect(bcl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+		Expect(bcl.GetAllBackendConfigs()).To(HaveLen(2))
+
+		bcl = NewBackendConfigLoader(dir)
+		err := bcl.LoadBackendConfigsFromPath(dir, LoadOptionStrict(true))
+		Expect(err).To(MatchError(ContainSubstring(`llama.yaml: invalid model configuration: line 2: unknown field "contextsize"`)))
+		Expect(bcl.GetAllBackendConfigs()).To(HaveLen(1))
+
+		configErrors, err := CheckBackendConfigsFromPath(dir, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configErrors).To(HaveLen(1))
+		Expect(configErrors[0].File).To(Equal("llama.yaml"))
+	})
+})
+
+var _ = Describe("BackendConfigJSONSchema", func() {
+	It("describes the fields of the configuration", func() {
+		s := BackendConfigJSONSchema()
+		Expect(s["additionalProperties"]).To(BeFalse())
+		properties := s["properties"].(map[string]interface{})
+		Expect(properties["context_size"]).To(Equal(map[string]interface{}{"type": "integer"}))
+		Expect(properties["backend"]).To(Equal(map[string]interface{}{"type": "string"}))
+		Expect(properties["f16"]).To(Equal(map[string]interface{}{"type": "boolean"}))
+		Expect(properties).ToNot(HaveKey("contextsize"))
+
+		parameters := properties["parameters"].(map[string]interface{})
+		Expect(parameters["properties"]).To(HaveKeyWithValue("temperature", map[string]interface{}{"type": "number"}))
+		Expect(properties["stopwords"]).To(Equal(map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}))
+	})
+
+	It("describes and constrains the fields with their tags", func() {
+		whisper := BackendConfigJSONSchema()["properties"].(map[string]interface{})["whisper"].(map[string]interface{})
+		whisperProperties := whisper["properties"].(map[string]interface{})
+		Expect(whisperProperties["beam_size"]).To(Equal(map[string]interface{}{
+			"type":        "integer",
+			"description": "Size of the beam search of the decoding, greedy below 2",
+			"minimum":     0.0,
+			"maximum":     8.0,
+		}))
+		Expect(whisperProperties["quantization"]).To(HaveKeyWithValue("enum", ContainElements("f16", "q5_0", "q8_0")))
+	})
+})
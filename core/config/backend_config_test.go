@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -60,5 +61,82 @@ parameters:
 			Expect(config.Name).To(Equal("hermes-2-pro-mistral"))
 			Expect(config.Validate()).To(BeTrue())
 		})
+		It("Test CheckBackendConfigsFromPath", func() {
+			dir, err := os.MkdirTemp("", "models")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			files := map[string]string{
+				"valid.yaml":     "name: foo\nbackend: llama-cpp\nparameters:\n  model: foo.gguf",
+				"duplicate.yaml": "name: foo\nparameters:\n  model: bar.gguf",
+				"invalid.yaml":   "name: bar\nbackend: ../bar",
+				"broken.yaml":    "name: [baz",
+				"foo.gguf":       "",
+				"bar.gguf":       "",
+			}
+			for name, content := range files {
+				Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(Succeed())
+			}
+
+			configErrors, err := CheckBackendConfigsFromPath(dir, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configErrors).To(HaveLen(3))
+			Expect(configErrors[0].File).To(Equal("broken.yaml"))
+			Expect(configErrors[1].File).To(Equal("invalid.yaml"))
+			// the files are read in lexical order, the name is first used by duplicate.yaml
+			Expect(configErrors[2].File).To(Equal("valid.yaml"))
+		})
+		It("Test CheckBackendConfigsFromPath references", func() {
+			dir, err := os.MkdirTemp("", "models")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			files := map[string]string{
+				"model.yaml":   "name: model\nbackend: foo\nparameters:\n  model: missing.gguf\nmmproj: missing-mmproj.gguf\nshadow:\n  model: model\n  percentage: 150\ntemplate:\n  chat: chat\n  completion: missing\n  edit: \"{{.Input\"\n  jinja: \"{% for message in messages %}\"",
+				"chat.tmpl":    "{{.Input}}",
+				"chatml.jinja": "{% for message in messages %}{{ message.content }}{% endfor %}",
+				"repo.yaml":    "name: repo\nbackend: transformers\nparameters:\n  model: org/repo",
+				"url.yaml":     "name: url\nparameters:\n  model: huggingface://org/repo/model.gguf\ntemplate:\n  jinja: chatml",
+			}
+			for name, content := range files {
+				Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(Succeed())
+			}
+
+			configErrors, err := CheckBackendConfigsFromPath(dir, []string{"llama-cpp", "transformers"})
+			Expect(err).ToNot(HaveOccurred())
+			messages := []string{}
+			for _, e := range configErrors {
+				Expect(e.File).To(Equal("model.yaml"))
+				messages = append(messages, e.Err.Error())
+			}
+			Expect(messages).To(HaveLen(8))
+			Expect(messages[0]).To(ContainSubstring(`unknown backend "foo"`))
+			Expect(messages[1]).To(Equal("model file missing.gguf does not exist"))
+			Expect(messages[2]).To(Equal("mmproj file missing-mmproj.gguf does not exist"))
+			Expect(messages[3]).To(Equal("shadow percentage 150 must be between 0 and 100"))
+			Expect(messages[4]).To(Equal("shadow model must be another model"))
+			Expect(messages[5]).To(Equal("completion template file missing.tmpl does not exist"))
+			Expect(messages[6]).To(ContainSubstring("edit template does not compile"))
+			Expect(messages[7]).To(ContainSubstring("jinja template does not compile"))
+		})
+	})
+	Context("ControlNets", func() {
+		It("only allows the controlnets of the model", func() {
+			d := Diffusers{
+				ControlNet:  "lllyasviel/sd-controlnet-openpose",
+				ControlNets: []string{"lllyasviel/sd-controlnet-canny"},
+			}
+			name, err := d.AllowedControlNet("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("lllyasviel/sd-controlnet-openpose"))
+			name, err = d.AllowedControlNet("lllyasviel/sd-controlnet-canny")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("lllyasviel/sd-controlnet-canny"))
+			_, err = d.AllowedControlNet("lllyasviel/sd-controlnet-depth")
+			Expect(err).To(HaveOccurred())
+
+			_, err = Diffusers{}.AllowedControlNet("")
+			Expect(err).To(MatchError("the model has no controlnet"))
+		})
 	})
 })
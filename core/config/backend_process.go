@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// BackendProcessData is given to the templates of the environment variables and of the arguments of the
+// backend process of a model, as "--draft={{.ModelsPath}}/draft.gguf"
+type BackendProcessData struct {
+	// Name of the model
+	Name string
+	// ModelsPath is the models path, and ModelFile the path of the file of the model in it
+	ModelsPath, ModelFile string
+}
+
+// BackendProcess returns the environment variables, as KEY=value, and the arguments of the backend
+// process of the model, with their templates executed
+func (c BackendConfig) BackendProcess(modelPath string) (env []string, args []string, err error) {
+	data := BackendProcessData{Name: c.Name, ModelsPath: modelPath}
+	if c.Model != "" && !c.IsModelURL() {
+		data.ModelFile = filepath.Join(modelPath, c.Model)
+	}
+
+	keys := make([]string, 0, len(c.Environment))
+	for key := range c.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return nil, nil, fmt.Errorf("invalid environment variable name %q", key)
+		}
+		value, err := executeBackendProcessTemplate(c.Environment[key], data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("environment variable %s: %w", key, err)
+		}
+		env = append(env, key+"="+value)
+	}
+
+	for _, arg := range c.BackendArgs {
+		value, err := executeBackendProcessTemplate(arg, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("backend argument %q: %w", arg, err)
+		}
+		args = append(args, value)
+	}
+	return env, args, nil
+}
+
+func executeBackendProcessTemplate(text string, data BackendProcessData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
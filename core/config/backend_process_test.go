@@ -0,0 +1,35 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BackendProcess", func() {
+	It("executes the templates of the environment and of the arguments", func() {
+		c := BackendConfig{
+			Name:        "llama",
+			Environment: map[string]string{"VLLM_CACHE": "{{.ModelsPath}}/cache", "DEBUG": "1"},
+			BackendArgs: []string{"--max-num-seqs", "16", "--tokenizer={{.ModelFile}}", "--served-model-name={{.Name}}"},
+		}
+		c.Model = "llama/model.gguf"
+		env, args, err := c.BackendProcess("/models")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(env).To(Equal([]string{"DEBUG=1", "VLLM_CACHE=/models/cache"}))
+		Expect(args).To(Equal([]string{"--max-num-seqs", "16", "--tokenizer=/models/llama/model.gguf", "--served-model-name=llama"}))
+
+		env, args, err = BackendConfig{}.BackendProcess("/models")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(env).To(BeEmpty())
+		Expect(args).To(BeEmpty())
+	})
+
+	It("rejects the invalid variables and templates", func() {
+		_, _, err := BackendConfig{Environment: map[string]string{"A=B": "1"}}.BackendProcess("/models")
+		Expect(err).To(MatchError(ContainSubstring("invalid environment variable name")))
+		_, _, err = BackendConfig{BackendArgs: []string{"--model={{.Unknown}}"}}.BackendProcess("/models")
+		Expect(err).To(HaveOccurred())
+		_, _, err = BackendConfig{Environment: map[string]string{"A": "{{.Name"}}.BackendProcess("/models")
+		Expect(err).To(MatchError(ContainSubstring("environment variable A")))
+	})
+})
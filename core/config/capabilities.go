@@ -0,0 +1,76 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	CapabilityChat          = "chat"
+	CapabilityCompletion    = "completion"
+	CapabilityEmbeddings    = "embeddings"
+	CapabilityVision        = "vision"
+	CapabilityImage         = "image"
+	CapabilityTTS           = "tts"
+	CapabilityTranscription = "transcription"
+	CapabilityRerank        = "rerank"
+	CapabilityTranslation   = "translation"
+)
+
+var (
+	imageBackends         = []string{"diffusers", "stablediffusion", "tinydream"}
+	ttsBackends           = []string{"piper", "bark", "coqui", "parler-tts", "vall-e-x", "transformers-musicgen"}
+	transcriptionBackends = []string{"whisper"}
+	rerankBackends        = []string{"rerankers"}
+	embeddingsBackends    = []string{"bert-embeddings", "sentencetransformers"}
+)
+
+// Capabilities guesses what the model can be used for, from its backend and its configuration
+func (c *BackendConfig) Capabilities() []string {
+	backend := strings.ToLower(c.Backend)
+	switch {
+	case slices.Contains(imageBackends, backend):
+		return []string{CapabilityImage}
+	case slices.Contains(ttsBackends, backend):
+		return []string{CapabilityTTS}
+	case slices.Contains(transcriptionBackends, backend):
+		return []string{CapabilityTranscription}
+	case slices.Contains(rerankBackends, backend):
+		return []string{CapabilityRerank}
+	case slices.Contains(embeddingsBackends, backend):
+		return []string{CapabilityEmbeddings}
+	}
+
+	if len(c.Translation.Pairs) > 0 {
+		return []string{CapabilityTranslation}
+	}
+
+	capabilities := []string{}
+	t := c.TemplateConfig
+	if t.Chat != "" || t.ChatMessage != "" || t.Jinja != "" || t.UseTokenizerTemplate {
+		capabilities = append(capabilities, CapabilityChat)
+	}
+	capabilities = append(capabilities, CapabilityCompletion)
+	if c.MMProj != "" {
+		capabilities = append(capabilities, CapabilityVision)
+	}
+	if c.Embeddings != nil && *c.Embeddings {
+		capabilities = append(capabilities, CapabilityEmbeddings)
+	}
+	return capabilities
+}
+
+// quantizationPattern matches the quantization types in the names of the GGUF/GGML files,
+// as "Q4_K_M", "IQ3_XS" or "f16"
+var quantizationPattern = regexp.MustCompile(`(?i)(?:^|[-_.])(I?Q[1-8](?:_[0-9A-Z]+)*|BF16|F16|F32)(?:[-_.]|$)`)
+
+// GuessQuantization returns the quantization type found in the name of a model file, if any
+func GuessQuantization(file string) string {
+	m := quantizationPattern.FindStringSubmatch(filepath.Base(file))
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1])
+}
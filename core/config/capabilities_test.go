@@ -0,0 +1,35 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capabilities", func() {
+	It("guesses the capabilities of the models", func() {
+		trueV := true
+		Expect((&BackendConfig{Backend: "whisper"}).Capabilities()).To(Equal([]string{CapabilityTranscription}))
+		Expect((&BackendConfig{Backend: "diffusers"}).Capabilities()).To(Equal([]string{CapabilityImage}))
+		Expect((&BackendConfig{Backend: "piper"}).Capabilities()).To(Equal([]string{CapabilityTTS}))
+		Expect((&BackendConfig{Backend: "rerankers"}).Capabilities()).To(Equal([]string{CapabilityRerank}))
+		Expect((&BackendConfig{Backend: "llama-cpp"}).Capabilities()).To(Equal([]string{CapabilityCompletion}))
+		Expect((&BackendConfig{Backend: "transformers", Translation: Translation{Pairs: []string{"en-fr"}}}).Capabilities()).To(Equal([]string{CapabilityTranslation}))
+
+		cfg := &BackendConfig{
+			Backend:        "llama-cpp",
+			TemplateConfig: TemplateConfig{ChatMessage: "{{.Content}}"},
+			Embeddings:     &trueV,
+		}
+		cfg.MMProj = "mmproj.gguf"
+		Expect(cfg.Capabilities()).To(Equal([]string{CapabilityChat, CapabilityCompletion, CapabilityVision, CapabilityEmbeddings}))
+	})
+
+	It("guesses the quantization from the model files", func() {
+		Expect(GuessQuantization("mistral-7b-instruct-v0.2.Q4_K_M.gguf")).To(Equal("Q4_K_M"))
+		Expect(GuessQuantization("models/llama-3-8b-instruct-q8_0.gguf")).To(Equal("Q8_0"))
+		Expect(GuessQuantization("Phi-3-mini-4k-instruct-IQ3_XS.gguf")).To(Equal("IQ3_XS"))
+		Expect(GuessQuantization("ggml-model-f16.bin")).To(Equal("F16"))
+		Expect(GuessQuantization("qwen2-7b.gguf")).To(BeEmpty())
+		Expect(GuessQuantization("")).To(BeEmpty())
+	})
+})
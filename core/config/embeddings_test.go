@@ -0,0 +1,20 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Embeddings pooling", func() {
+	It("accepts the pooling of llama.cpp", func() {
+		for _, pooling := range []string{"", PoolingMean, PoolingCLS, PoolingLast, PoolingNone} {
+			c := &BackendConfig{Name: "embeddings", LLMConfig: LLMConfig{Pooling: pooling}}
+			Expect(c.check(GinkgoT().TempDir(), nil)).To(BeEmpty())
+		}
+	})
+
+	It("rejects the unknown pooling", func() {
+		c := &BackendConfig{Name: "embeddings", LLMConfig: LLMConfig{Pooling: "max"}}
+		Expect(c.check(GinkgoT().TempDir(), nil)).To(ContainElement(MatchError(ContainSubstring(`invalid pooling "max"`))))
+	})
+})
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"regexp"
+
+	"github.com/rs/zerolog/log"
+)
+
+// envReference matches ${NAME} and ${NAME:-default}, and $${NAME} which escapes the reference
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces the ${NAME} references in the content of a configuration file with the environment
+// variables, or with the default of ${NAME:-default} when the variable is not set or empty. The variables
+// which are not set, without a default, are replaced with an empty string. $${NAME} is kept as ${NAME}.
+// The other uses of $, as the variables of the templates, are left alone
+func ExpandEnv(content []byte) []byte {
+	return envReference.ReplaceAllFunc(content, func(ref []byte) []byte {
+		if ref[1] == '$' {
+			return ref[1:]
+		}
+		m := envReference.FindSubmatch(ref)
+		name := string(m[1])
+		if value := os.Getenv(name); value != "" {
+			return []byte(value)
+		}
+		if len(m[2]) > 0 {
+			return m[3]
+		}
+		log.Warn().Str("variable", name).Msg("environment variable referenced by a configuration file is not set")
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExpandEnv", func() {
+	BeforeEach(func() {
+		os.Setenv("LOCALAI_TEST_MODEL", "llama-3.gguf")
+		DeferCleanup(os.Unsetenv, "LOCALAI_TEST_MODEL")
+	})
+
+	It("replaces the references to the environment variables", func() {
+		Expect(string(ExpandEnv([]byte("model: ${LOCALAI_TEST_MODEL}")))).To(Equal("model: llama-3.gguf"))
+	})
+
+	It("uses the defaults of the variables which are not set", func() {
+		Expect(string(ExpandEnv([]byte("threads: ${LOCALAI_TEST_THREADS:-4}")))).To(Equal("threads: 4"))
+		Expect(string(ExpandEnv([]byte("model: ${LOCALAI_TEST_MODEL:-phi.gguf}")))).To(Equal("model: llama-3.gguf"))
+		Expect(string(ExpandEnv([]byte("threads: ${LOCALAI_TEST_THREADS}")))).To(Equal("threads: "))
+	})
+
+	It("keeps the escaped references and the variables of the templates", func() {
+		Expect(string(ExpandEnv([]byte("a: $${LOCALAI_TEST_MODEL}")))).To(Equal("a: ${LOCALAI_TEST_MODEL}"))
+		Expect(string(ExpandEnv([]byte("t: {{$role := .Role}}$HOME")))).To(Equal("t: {{$role := .Role}}$HOME"))
+	})
+
+	It("expands the configurations of the models", func() {
+		dir := GinkgoT().TempDir()
+		file := filepath.Join(dir, "llama.yaml")
+		Expect(os.WriteFile(file, []byte("name: llama\nparameters:\n  model: ${LOCALAI_TEST_MODEL}\n"), 0600)).To(Succeed())
+		c, err := readBackendConfigFromFile(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Model).To(Equal("llama-3.gguf"))
+	})
+})
+
+var _ = Describe("SettingSource", func() {
+	It("returns the source of the settings", func() {
+		appConfig := NewApplicationConfig(WithSettingSources(map[string]string{"LOCALAI_THREADS": SourceFlag}))
+		Expect(appConfig.SettingSource("LOCALAI_THREADS")).To(Equal(SourceFlag))
+		Expect(appConfig.SettingSource("LOCALAI_F16")).To(Equal(SourceDefault))
+	})
+
+	It("reports the settings overridden by the dynamic configuration directory", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "external_backends.json"), []byte("{}"), 0600)).To(Succeed())
+		appConfig := NewApplicationConfig(WithDynamicConfigDir(dir),
+			WithSettingSources(map[string]string{"LOCALAI_EXTERNAL_GRPC_BACKENDS": SourceEnv}))
+		Expect(appConfig.SettingSource("LOCALAI_EXTERNAL_GRPC_BACKENDS")).To(Equal(SourceDynamic))
+	})
+})
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mudler/LocalAI/pkg/utils"
+)
+
+var (
+	ErrBackendConfigExists   = errors.New("model configuration already exists")
+	ErrBackendConfigNotFound = errors.New("model configuration not found")
+)
+
+// InvalidBackendConfigError lists the errors of a configuration which was not saved
+type InvalidBackendConfigError struct {
+	Errs []error
+}
+
+func (e InvalidBackendConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid model configuration: " + strings.Join(msgs, "; ")
+}
+
+// BackendConfigFile returns the YAML file of the configuration of the model in the models path
+func (bcl *BackendConfigLoader) BackendConfigFile(name string) (string, error) {
+	bcl.Lock()
+	defer bcl.Unlock()
+	return bcl.backendConfigFile(name)
+}
+
+func (bcl *BackendConfigLoader) backendConfigFile(name string) (string, error) {
+	if _, exists := bcl.configs[name]; !exists || bcl.modelPath == "" {
+		return "", fmt.Errorf("%w: %s", ErrBackendConfigNotFound, name)
+	}
+	file, _, err := configFileInPath(bcl.modelPath, name)
+	if err != nil {
+		return "", err
+	}
+	if file == "" {
+		return "", fmt.Errorf("%w: %s is not configured by a file of the models path", ErrBackendConfigNotFound, name)
+	}
+	return file, nil
+}
+
+// SaveBackendConfig checks the YAML configuration of a model, writes it in the models path and loads
+// it, replacing the configuration of the model if any. When create is true the model must not exist,
+// otherwise it must exist in a file of the models path, which is replaced. The configuration must have
+// the name of the model, and no error for CheckBackendConfigsFromPath with the backends, unknown fields
+// included
+func (bcl *BackendConfigLoader) SaveBackendConfig(name string, data []byte, create bool, backends []string, opts ...ConfigLoaderOption) (*BackendConfig, error) {
+	bcl.Lock()
+	defer bcl.Unlock()
+
+	if bcl.modelPath == "" {
+		return nil, errors.New("the models path is not set")
+	}
+	// the fields which would be ignored are refused, as the typos
+	if errs := UnknownFields(data); len(errs) > 0 {
+		return nil, InvalidBackendConfigError{Errs: errs}
+	}
+	c, err := parseBackendConfig(data, opts...)
+	if err != nil {
+		return nil, InvalidBackendConfigError{Errs: []error{err}}
+	}
+	if name == "" {
+		name = c.Name
+	}
+	if c.Name != name {
+		return nil, InvalidBackendConfigError{Errs: []error{fmt.Errorf("name %q must be the name of the model %q", c.Name, name)}}
+	}
+	if errs := c.check(bcl.modelPath, backends); len(errs) > 0 {
+		return nil, InvalidBackendConfigError{Errs: errs}
+	}
+
+	var file string
+	if create {
+		if _, exists := bcl.configs[name]; exists {
+			return nil, fmt.Errorf("%w: %s", ErrBackendConfigExists, name)
+		}
+		file = filepath.Join(bcl.modelPath, name+".yaml")
+		if err := utils.VerifyPath(name+".yaml", bcl.modelPath); err != nil || filepath.Dir(file) != filepath.Clean(bcl.modelPath) {
+			return nil, InvalidBackendConfigError{Errs: []error{fmt.Errorf("name %q can not be used as a file name", name)}}
+		}
+		if _, err := os.Stat(file); err == nil {
+			return nil, fmt.Errorf("%w: the file %s exists", ErrBackendConfigExists, filepath.Base(file))
+		}
+	} else {
+		if file, err = bcl.backendConfigFile(name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeFileAtomic(file, data); err != nil {
+		return nil, err
+	}
+	bcl.configs[name] = *c
+	return c, nil
+}
+
+// DeleteBackendConfigFile removes the configuration of the model and its file from the models path,
+// and returns the removed configuration. The files of the model are left in the models path
+func (bcl *BackendConfigLoader) DeleteBackendConfigFile(name string) (*BackendConfig, error) {
+	bcl.Lock()
+	defer bcl.Unlock()
+
+	file, err := bcl.backendConfigFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(file); err != nil {
+		return nil, err
+	}
+	c := bcl.configs[name]
+	delete(bcl.configs, name)
+	return &c, nil
+}
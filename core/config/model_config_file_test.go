@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SaveBackendConfig", func() {
+	var dir string
+	var bcl *BackendConfigLoader
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		bcl = NewBackendConfigLoader(dir)
+		Expect(os.WriteFile(filepath.Join(dir, "model.gguf"), []byte{}, 0600)).To(Succeed())
+	})
+
+	It("creates the configuration and loads it", func() {
+		c, err := bcl.SaveBackendConfig("", []byte("name: llama\nbackend: llama-cpp\nparameters:\n  model: model.gguf\n"), true, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Name).To(Equal("llama"))
+
+		loaded, exists := bcl.GetBackendConfig("llama")
+		Expect(exists).To(BeTrue())
+		Expect(loaded.Model).To(Equal("model.gguf"))
+		Expect(filepath.Join(dir, "llama.yaml")).To(BeAnExistingFile())
+
+		file, err := bcl.BackendConfigFile("llama")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(file).To(Equal(filepath.Join(dir, "llama.yaml")))
+	})
+
+	It("refuses to create a configuration which exists", func() {
+		_, err := bcl.SaveBackendConfig("", []byte("name: llama\n"), true, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = bcl.SaveBackendConfig("", []byte("name: llama\n"), true, nil)
+		Expect(err).To(MatchError(ErrBackendConfigExists))
+	})
+
+	It("replaces the file of the configuration", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "assistant.yml"), []byte("name: llama\n"), 0600)).To(Succeed())
+		Expect(bcl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+
+		_, err := bcl.SaveBackendConfig("llama", []byte("name: llama\ncontext_size: 4096\n"), false, nil)
+		Expect(err).ToNot(HaveOccurred())
+		c, _ := bcl.GetBackendConfig("llama")
+		Expect(*c.ContextSize).To(Equal(4096))
+
+		data, err := os.ReadFile(filepath.Join(dir, "assistant.yml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("name: llama\ncontext_size: 4096\n"))
+		Expect(filepath.Join(dir, "llama.yaml")).ToNot(BeAnExistingFile())
+	})
+
+	It("refuses to update a configuration which does not exist", func() {
+		_, err := bcl.SaveBackendConfig("llama", []byte("name: llama\n"), false, nil)
+		Expect(err).To(MatchError(ErrBackendConfigNotFound))
+	})
+
+	It("refuses the invalid configurations", func() {
+		for _, data := range []string{
+			"name: [llama",
+			"backend: llama-cpp\n",
+			"name: llama\nbackend: unknown\n",
+			"name: llama\nparameters:\n  model: missing.gguf\n",
+			"name: ../llama\n",
+		} {
+			_, err := bcl.SaveBackendConfig("", []byte(data), true, []string{"llama-cpp"})
+			Expect(err).To(BeAssignableToTypeOf(InvalidBackendConfigError{}), data)
+		}
+
+		_, err := bcl.SaveBackendConfig("llama", []byte("name: other\n"), false, nil)
+		Expect(err).To(BeAssignableToTypeOf(InvalidBackendConfigError{}))
+		Expect(bcl.GetAllBackendConfigs()).To(BeEmpty())
+	})
+
+	It("deletes the configuration and its file", func() {
+		_, err := bcl.SaveBackendConfig("", []byte("name: llama\nparameters:\n  model: model.gguf\n"), true, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		c, err := bcl.DeleteBackendConfigFile("llama")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Model).To(Equal("model.gguf"))
+		_, exists := bcl.GetBackendConfig("llama")
+		Expect(exists).To(BeFalse())
+		Expect(filepath.Join(dir, "llama.yaml")).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(dir, "model.gguf")).To(BeAnExistingFile())
+
+		_, err = bcl.DeleteBackendConfigFile("llama")
+		Expect(err).To(MatchError(ErrBackendConfigNotFound))
+	})
+})
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetModelFile points the configuration of the model to another model file, and saves it in the YAML
+// file of the configuration in the models path, if any, so that it is kept after a restart
+func (bcl *BackendConfigLoader) SetModelFile(name, modelFile string) error {
+	bcl.Lock()
+	defer bcl.Unlock()
+	c, exists := bcl.configs[name]
+	if !exists {
+		return fmt.Errorf("model %s not found", name)
+	}
+
+	if bcl.modelPath != "" {
+		if err := setModelFileInPath(bcl.modelPath, name, modelFile); err != nil {
+			return err
+		}
+	}
+	c.Model = modelFile
+	bcl.configs[name] = c
+	return nil
+}
+
+// setModelFileInPath sets parameters.model in the YAML file of the configuration of the model, keeping
+// the rest of the file as it is. The configurations which are not in a file of the path are left alone
+func setModelFileInPath(path, name, modelFile string) error {
+	file, doc, err := configFileInPath(path, name)
+	if err != nil || file == "" {
+		return err
+	}
+	setModelFileNode(doc.Content[0], modelFile)
+	data, err := encodeConfigDocument(doc)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, data)
+}
+
+// CopyBackendConfig saves a copy of the YAML file of the configuration of the model, with another name and
+// model file, in the models path and loads it, as for the fine-tuned versions of the models
+func (bcl *BackendConfigLoader) CopyBackendConfig(name, newName, modelFile string, opts ...ConfigLoaderOption) (*BackendConfig, error) {
+	bcl.Lock()
+	if _, exists := bcl.configs[name]; !exists || bcl.modelPath == "" {
+		bcl.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrBackendConfigNotFound, name)
+	}
+	file, doc, err := configFileInPath(bcl.modelPath, name)
+	bcl.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return nil, fmt.Errorf("%w: %s is not configured by a file of the models path", ErrBackendConfigNotFound, name)
+	}
+
+	mappingValue(doc.Content[0], "name").Value = newName
+	setModelFileNode(doc.Content[0], modelFile)
+	data, err := encodeConfigDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	return bcl.SaveBackendConfig(newName, data, true, nil, opts...)
+}
+
+// setModelFileNode sets parameters.model in the YAML mapping of a configuration
+func setModelFileNode(root *yaml.Node, modelFile string) {
+	parameters := mappingValue(root, "parameters")
+	if parameters == nil {
+		parameters = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "parameters"}, parameters)
+	}
+	if m := mappingValue(parameters, "model"); m != nil {
+		m.Value = modelFile
+	} else {
+		parameters.Content = append(parameters.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "model"}, &yaml.Node{Kind: yaml.ScalarNode, Value: modelFile})
+	}
+}
+
+// encodeConfigDocument encodes the YAML document of a configuration, with the indentation of the files
+// written by LocalAI
+func encodeConfigDocument(doc *yaml.Node) ([]byte, error) {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// configFileInPath returns the YAML file of the configuration of the model in the path, and its
+// document. The file is empty if the configuration is not in a file of the path
+func configFileInPath(path, name string) (string, *yaml.Node, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") && !strings.HasSuffix(e.Name(), ".yml") ||
+			strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		file := filepath.Join(path, e.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", nil, err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		if n := mappingValue(doc.Content[0], "name"); n != nil && n.Value == name {
+			return file, &doc, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// writeFileAtomic replaces the file with the data, so that the file is never read half written.
+// The temporary file is hidden, as the loader skips the hidden files
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// mappingValue returns the value of the key in the YAML mapping, nil if it is not set
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SetModelFile", func() {
+	var dir string
+	var bcl *BackendConfigLoader
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		bcl = NewBackendConfigLoader(dir)
+	})
+
+	load := func(file, content string) {
+		Expect(os.WriteFile(filepath.Join(dir, file), []byte(content), 0600)).To(Succeed())
+		Expect(bcl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+	}
+
+	It("points the configuration to the new model file and saves it", func() {
+		load("other.yaml", "name: other\nparameters:\n  model: other.gguf\n")
+		load("llama.yaml", `# the assistant model
+name: llama
+backend: llama-cpp
+parameters:
+  model: llama-v1.gguf # first version
+  temperature: 0.2
+`)
+
+		Expect(bcl.SetModelFile("llama", "llama-v2.gguf")).To(Succeed())
+		c, _ := bcl.GetBackendConfig("llama")
+		Expect(c.Model).To(Equal("llama-v2.gguf"))
+
+		data, err := os.ReadFile(filepath.Join(dir, "llama.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(`# the assistant model
+name: llama
+backend: llama-cpp
+parameters:
+  model: llama-v2.gguf # first version
+  temperature: 0.2
+`))
+		data, err = os.ReadFile(filepath.Join(dir, "other.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("model: other.gguf"))
+	})
+
+	It("adds the parameters missing from the file", func() {
+		load("whisper.yaml", "name: whisper\nbackend: whisper\n")
+
+		Expect(bcl.SetModelFile("whisper", "ggml-base.bin")).To(Succeed())
+		Expect(bcl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+		c, _ := bcl.GetBackendConfig("whisper")
+		Expect(c.Model).To(Equal("ggml-base.bin"))
+	})
+
+	It("fails for the unknown models", func() {
+		Expect(bcl.SetModelFile("missing", "model.gguf")).ToNot(Succeed())
+	})
+
+	It("copies the configuration with another name and model file", func() {
+		load("llama.yaml", "name: llama\nbackend: llama-cpp\nparameters:\n  model: llama.gguf\n  temperature: 0.2\n")
+		Expect(os.WriteFile(filepath.Join(dir, "llama-support.gguf"), []byte("tuned"), 0600)).To(Succeed())
+
+		c, err := bcl.CopyBackendConfig("llama", "llama-support", "llama-support.gguf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Name).To(Equal("llama-support"))
+		Expect(c.Model).To(Equal("llama-support.gguf"))
+		Expect(*c.Temperature).To(Equal(0.2))
+
+		data, err := os.ReadFile(filepath.Join(dir, "llama-support.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("name: llama-support\nbackend: llama-cpp\nparameters:\n  model: llama-support.gguf\n  temperature: 0.2\n"))
+		llama, _ := bcl.GetBackendConfig("llama")
+		Expect(llama.Model).To(Equal("llama.gguf"))
+
+		_, err = bcl.CopyBackendConfig("llama", "llama-support", "llama-support.gguf")
+		Expect(err).To(MatchError(ErrBackendConfigExists))
+		_, err = bcl.CopyBackendConfig("missing", "other", "llama-support.gguf")
+		Expect(err).To(MatchError(ErrBackendConfigNotFound))
+	})
+})
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mudler/LocalAI/pkg/cron"
+	"gopkg.in/yaml.v3"
+)
+
+// ModelSchedule loads a model in memory and unloads it at the times of cron expressions, to have
+// it ready during the business hours without keeping it in memory all the time
+type ModelSchedule struct {
+	Model string `yaml:"model"`
+	// Load and Unload are cron expressions, in the time zone of the server
+	Load   string `yaml:"load"`
+	Unload string `yaml:"unload"`
+}
+
+// ReadModelSchedulesFile reads a YAML file with a list of model schedules
+func ReadModelSchedulesFile(file string) ([]ModelSchedule, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read model schedules file %q: %w", file, err)
+	}
+	schedules := []ModelSchedule{}
+	if err := yaml.Unmarshal(f, &schedules); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal model schedules file %q: %w", file, err)
+	}
+
+	for _, s := range schedules {
+		if s.Model == "" {
+			return nil, fmt.Errorf("model schedule without model")
+		}
+		if s.Load == "" && s.Unload == "" {
+			return nil, fmt.Errorf("model schedule of %s: load or unload is required", s.Model)
+		}
+		for _, expr := range []string{s.Load, s.Unload} {
+			if expr == "" {
+				continue
+			}
+			if _, err := cron.Parse(expr); err != nil {
+				return nil, fmt.Errorf("model schedule of %s: %w", s.Model, err)
+			}
+		}
+	}
+	return schedules, nil
+}
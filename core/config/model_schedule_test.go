@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Model schedules", func() {
+	writeSchedules := func(content string) string {
+		file := filepath.Join(GinkgoT().TempDir(), "schedules.yaml")
+		Expect(os.WriteFile(file, []byte(content), 0600)).To(Succeed())
+		return file
+	}
+
+	It("reads the schedules", func() {
+		schedules, err := ReadModelSchedulesFile(writeSchedules(`- model: mistral
+  load: "0 8 * * 1-5"
+  unload: "0 20 * * 1-5"
+- model: whisper
+  unload: "@daily"
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(schedules).To(Equal([]ModelSchedule{
+			{Model: "mistral", Load: "0 8 * * 1-5", Unload: "0 20 * * 1-5"},
+			{Model: "whisper", Unload: "@daily"},
+		}))
+	})
+
+	It("rejects the invalid schedules", func() {
+		for _, content := range []string{
+			`- load: "0 8 * * *"`,
+			`- model: mistral`,
+			`- {model: mistral, load: "0 25 * * *"}`,
+		} {
+			_, err := ReadModelSchedulesFile(writeSchedules(content))
+			Expect(err).To(HaveOccurred(), content)
+		}
+	})
+})
@@ -0,0 +1,16 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NUMA policy", func() {
+	It("selects the NUMA strategy of llama.cpp", func() {
+		Expect((&LLMConfig{}).NUMAPolicy()).To(BeEmpty())
+		Expect((&LLMConfig{NUMA: true}).NUMAPolicy()).To(Equal(NUMADistribute))
+		node := 1
+		Expect((&LLMConfig{NUMA: true, NUMANode: &node}).NUMAPolicy()).To(Equal(NUMANumactl))
+		Expect((&LLMConfig{NUMANode: &node, NUMAStrategy: NUMAIsolate}).NUMAPolicy()).To(Equal(NUMAIsolate))
+	})
+})
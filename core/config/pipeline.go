@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The types of the steps of the pipelines
+const (
+	// PipelineTranscription transcribes the audio of the request, or the audio of a tts step
+	PipelineTranscription = "transcription"
+	// PipelineChat answers the input as a user message, with the chat template of the model
+	PipelineChat = "chat"
+	// PipelineRetrieve embeds the input with an embedding model and returns the values of the closest
+	// keys of a store
+	PipelineRetrieve = "retrieve"
+	// PipelineTranslation translates the input from the source language to the language of the step
+	PipelineTranslation = "translation"
+	// PipelineTTS speaks the input
+	PipelineTTS = "tts"
+)
+
+var pipelineStepTypes = []string{PipelineTranscription, PipelineChat, PipelineRetrieve, PipelineTranslation, PipelineTTS}
+
+// DefaultPipelineTopK is the number of values returned by the retrieve steps without top_k
+const DefaultPipelineTopK = 3
+
+// Pipeline chains models into a single endpoint, as transcription -> chat -> tts. The steps run once the
+// steps they need are done, the independent ones concurrently
+type Pipeline struct {
+	Name        string         `yaml:"-"`
+	Description string         `yaml:"description"`
+	Steps       []PipelineStep `yaml:"steps"`
+	// Output is the name of the step whose output is the response, the last step by default
+	Output string `yaml:"output"`
+}
+
+// PipelineStep is a model call of a pipeline
+type PipelineStep struct {
+	Name  string `yaml:"name"`
+	Type  string `yaml:"type"`
+	Model string `yaml:"model"`
+	// Input is a template of the input of the step, with the text of the request as {{.Input}} and the
+	// outputs of the other steps as {{.Steps.name}}. By default it is the output of the last step it needs,
+	// or the input of the request. The input of the transcription steps is the path of an audio file, the
+	// audio of the request by default
+	Input string `yaml:"input"`
+	// System is the template of the system prompt of the chat steps
+	System string `yaml:"system"`
+	// Needs are the steps which run before the step, in addition to the ones its templates reference
+	Needs []string `yaml:"needs"`
+	// Store and TopK are the store and the number of values of the retrieve steps
+	Store string `yaml:"store"`
+	TopK  int    `yaml:"top_k"`
+	// Language is the language of the transcription and tts steps, and the target language of the
+	// translation steps, whose Source is the language of the input
+	Language string `yaml:"language"`
+	Source   string `yaml:"source"`
+	Voice    string `yaml:"voice"`
+}
+
+var pipelineName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// pipelineStepName is the name of a step, usable as a field in the templates
+var pipelineStepName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// pipelineStepReference matches the references to the outputs of the steps in the templates
+var pipelineStepReference = regexp.MustCompile(`\.Steps\.([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// ReadPipelinesFile reads a YAML file mapping the names of the pipelines to their steps
+func ReadPipelinesFile(file string) (map[string]Pipeline, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read pipelines file %q: %w", file, err)
+	}
+	pipelines := map[string]Pipeline{}
+	if err := yaml.Unmarshal(ExpandEnv(f), &pipelines); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal pipelines file %q: %w", file, err)
+	}
+
+	for name, p := range pipelines {
+		// The name is a path segment of the endpoint of the pipeline
+		if !pipelineName.MatchString(name) {
+			return nil, fmt.Errorf("invalid pipeline name %q, it can only contain letters, digits, '-' and '_'", name)
+		}
+		p.Name = name
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", name, err)
+		}
+		pipelines[name] = p
+	}
+	return pipelines, nil
+}
+
+// validate checks the steps, and adds the steps referenced by their templates to their needs
+func (p *Pipeline) validate() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+
+	names := map[string]bool{}
+	for _, s := range p.Steps {
+		if !pipelineStepName.MatchString(s.Name) {
+			return fmt.Errorf("invalid step name %q, it must be a letter followed by letters, digits or '_'", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("two steps are named %s", s.Name)
+		}
+		names[s.Name] = true
+	}
+
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		if !slices.Contains(pipelineStepTypes, s.Type) {
+			return fmt.Errorf("step %s: unknown type %q, expected one of %s", s.Name, s.Type, strings.Join(pipelineStepTypes, ", "))
+		}
+		if s.Model == "" {
+			return fmt.Errorf("step %s: model is required", s.Name)
+		}
+		if s.Type == PipelineTranslation && (s.Source == "" || s.Language == "") {
+			return fmt.Errorf("step %s: source and language are required by the translation steps", s.Name)
+		}
+		if s.TopK < 0 {
+			return fmt.Errorf("step %s: top_k must be positive", s.Name)
+		}
+
+		needs := slices.Clone(s.Needs)
+		for _, text := range []string{s.Input, s.System} {
+			if _, err := template.New(s.Name).Parse(text); err != nil {
+				return fmt.Errorf("step %s: invalid template: %w", s.Name, err)
+			}
+			for _, ref := range pipelineStepReference.FindAllStringSubmatch(text, -1) {
+				if !slices.Contains(needs, ref[1]) {
+					needs = append(needs, ref[1])
+				}
+			}
+		}
+		for _, n := range needs {
+			if !names[n] {
+				return fmt.Errorf("step %s needs the unknown step %s", s.Name, n)
+			}
+			if n == s.Name {
+				return fmt.Errorf("step %s needs itself", s.Name)
+			}
+		}
+		s.Needs = needs
+	}
+
+	if p.Output == "" {
+		p.Output = p.Steps[len(p.Steps)-1].Name
+	}
+	if !names[p.Output] {
+		return fmt.Errorf("the output step %s does not exist", p.Output)
+	}
+	if _, err := p.Stages(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stages returns the steps in the order they run: the steps of a stage only need the steps of the
+// previous stages, and run concurrently
+func (p Pipeline) Stages() ([][]PipelineStep, error) {
+	done := map[string]bool{}
+	stages := [][]PipelineStep{}
+	for len(done) < len(p.Steps) {
+		stage := []PipelineStep{}
+		for _, s := range p.Steps {
+			if done[s.Name] {
+				continue
+			}
+			if !slices.ContainsFunc(s.Needs, func(n string) bool { return !done[n] }) {
+				stage = append(stage, s)
+			}
+		}
+		if len(stage) == 0 {
+			pending := []string{}
+			for _, s := range p.Steps {
+				if !done[s.Name] {
+					pending = append(pending, s.Name)
+				}
+			}
+			return nil, fmt.Errorf("circular needs between the steps %s", strings.Join(pending, ", "))
+		}
+		for _, s := range stage {
+			done[s.Name] = true
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pipelines", func() {
+	writePipelines := func(content string) string {
+		file := filepath.Join(GinkgoT().TempDir(), "pipelines.yaml")
+		Expect(os.WriteFile(file, []byte(content), 0600)).To(Succeed())
+		return file
+	}
+
+	stepNames := func(stages [][]PipelineStep) [][]string {
+		names := [][]string{}
+		for _, stage := range stages {
+			stageNames := []string{}
+			for _, s := range stage {
+				stageNames = append(stageNames, s.Name)
+			}
+			names = append(names, stageNames)
+		}
+		return names
+	}
+
+	It("reads the pipelines and orders their steps", func() {
+		pipelines, err := ReadPipelinesFile(writePipelines(`voice-summary:
+  description: Summarizes a voice message
+  steps:
+    - name: transcript
+      type: transcription
+      model: whisper-1
+    - name: context
+      type: retrieve
+      model: bert
+      store: docs
+      input: "{{.Input}}"
+    - name: summary
+      type: chat
+      model: gpt-4
+      system: "Answer with {{.Steps.context}}"
+      input: "Summarize: {{.Steps.transcript}}"
+    - name: speech
+      type: tts
+      model: tts-1
+      needs: [summary]
+`))
+		Expect(err).ToNot(HaveOccurred())
+		p := pipelines["voice-summary"]
+		Expect(p.Name).To(Equal("voice-summary"))
+		Expect(p.Output).To(Equal("speech"))
+		Expect(p.Steps[2].Needs).To(Equal([]string{"transcript", "context"}))
+
+		stages, err := p.Stages()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stepNames(stages)).To(Equal([][]string{{"transcript", "context"}, {"summary"}, {"speech"}}))
+	})
+
+	It("refuses the invalid pipelines", func() {
+		for content, message := range map[string]string{
+			"p:\n  steps: []\n": "no steps",
+			"p/1:\n  steps: [{name: a, type: chat, model: m}]\n":                                                        "invalid pipeline name",
+			"p:\n  steps: [{name: a-b, type: chat, model: m}]\n":                                                        "invalid step name",
+			"p:\n  steps: [{name: a, type: chat, model: m}, {name: a, type: tts, model: m}]\n":                          "two steps are named a",
+			"p:\n  steps: [{name: a, type: image, model: m}]\n":                                                         "unknown type",
+			"p:\n  steps: [{name: a, type: chat}]\n":                                                                    "model is required",
+			"p:\n  steps: [{name: a, type: translation, model: m, language: fr}]\n":                                     "source and language are required",
+			"p:\n  steps: [{name: a, type: chat, model: m, input: '{{.Steps.b}}'}]\n":                                   "unknown step b",
+			"p:\n  steps: [{name: a, type: chat, model: m, input: '{{.Input'}]\n":                                       "invalid template",
+			"p:\n  output: b\n  steps: [{name: a, type: chat, model: m}]\n":                                             "output step b does not exist",
+			"p:\n  steps: [{name: a, type: chat, model: m, needs: [b]}, {name: b, type: chat, model: m, needs: [a]}]\n": "circular needs between the steps a, b",
+		} {
+			_, err := ReadPipelinesFile(writePipelines(content))
+			Expect(err).To(MatchError(ContainSubstring(message)), content)
+		}
+	})
+})
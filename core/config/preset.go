@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preset is a named set of generation parameters, as "creative" or "precise". Presets are defined
+// in the model configurations or in the presets file, and selected by the requests with the preset
+// field or by the models with their preset. The parameters set in the preset replace the ones of the
+// model, and the ones set in the request replace the ones of the preset
+type Preset struct {
+	Temperature      *float64 `yaml:"temperature"`
+	TopP             *float64 `yaml:"top_p"`
+	TopK             *int     `yaml:"top_k"`
+	Maxtokens        *int     `yaml:"max_tokens"`
+	RepeatPenalty    float64  `yaml:"repeat_penalty"`
+	FrequencyPenalty float64  `yaml:"frequency_penalty"`
+	PresencePenalty  float64  `yaml:"presence_penalty"`
+}
+
+// ReadPresetsFile reads a YAML file mapping the names of the presets to their parameters
+func ReadPresetsFile(file string) (map[string]Preset, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read presets file %q: %w", file, err)
+	}
+	presets := map[string]Preset{}
+	if err := yaml.Unmarshal(f, &presets); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal presets file %q: %w", file, err)
+	}
+	return presets, nil
+}
+
+// SetPresets sets the presets available to all the models
+func (bcl *BackendConfigLoader) SetPresets(presets map[string]Preset) {
+	bcl.Lock()
+	defer bcl.Unlock()
+	bcl.presets = presets
+}
+
+// Preset returns the preset of the model with the given name, or else the one of the presets file
+func (bcl *BackendConfigLoader) Preset(cfg *BackendConfig, name string) (Preset, bool) {
+	if p, ok := cfg.Presets[name]; ok {
+		return p, true
+	}
+	bcl.Lock()
+	defer bcl.Unlock()
+	p, ok := bcl.presets[name]
+	return p, ok
+}
+
+// ApplyPreset sets the parameters of the preset in the configuration
+func (cfg *BackendConfig) ApplyPreset(p Preset) {
+	if p.Temperature != nil {
+		cfg.Temperature = p.Temperature
+	}
+	if p.TopP != nil {
+		cfg.TopP = p.TopP
+	}
+	if p.TopK != nil {
+		cfg.TopK = p.TopK
+	}
+	if p.Maxtokens != nil {
+		cfg.Maxtokens = p.Maxtokens
+	}
+	if p.RepeatPenalty != 0 {
+		cfg.RepeatPenalty = p.RepeatPenalty
+	}
+	if p.FrequencyPenalty != 0 {
+		cfg.FrequencyPenalty = p.FrequencyPenalty
+	}
+	if p.PresencePenalty != 0 {
+		cfg.PresencePenalty = p.PresencePenalty
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Presets", func() {
+	It("resolves the presets of the model before the ones of the presets file", func() {
+		dir, err := os.MkdirTemp("", "models")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		Expect(os.WriteFile(filepath.Join(dir, "presets.yaml"), []byte(`creative:
+  temperature: 1.2
+  top_p: 0.98
+precise:
+  temperature: 0.1
+  repeat_penalty: 1.1
+`), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "model.yaml"), []byte(`name: model
+parameters:
+  model: model.gguf
+  temperature: 0.7
+presets:
+  creative:
+    temperature: 1.5
+`), 0600)).To(Succeed())
+
+		presets, err := ReadPresetsFile(filepath.Join(dir, "presets.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(presets).To(HaveLen(2))
+
+		cl := NewBackendConfigLoader(dir)
+		Expect(cl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+		cl.SetPresets(presets)
+		cfg, err := cl.LoadBackendConfigFileByName("model", dir)
+		Expect(err).ToNot(HaveOccurred())
+
+		p, ok := cl.Preset(cfg, "creative")
+		Expect(ok).To(BeTrue())
+		Expect(*p.Temperature).To(Equal(1.5))
+		Expect(p.TopP).To(BeNil())
+
+		p, ok = cl.Preset(cfg, "precise")
+		Expect(ok).To(BeTrue())
+		cfg.ApplyPreset(p)
+		Expect(*cfg.Temperature).To(Equal(0.1))
+		Expect(cfg.RepeatPenalty).To(Equal(1.1))
+		Expect(*cfg.TopP).To(Equal(0.95))
+
+		_, ok = cl.Preset(cfg, "missing")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports invalid presets files", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "presets.yaml")
+		Expect(os.WriteFile(file, []byte("creative: [1"), 0600)).To(Succeed())
+		_, err := ReadPresetsFile(file)
+		Expect(err).To(HaveOccurred())
+	})
+})
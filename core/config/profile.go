@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Startup profiles, enabling groups of the optional subsystems of LocalAI
+const (
+	// ProfileAllInOne enables everything
+	ProfileAllInOne = "all-in-one"
+	// ProfileAPIOnly serves the API without the WebUI and the p2p network
+	ProfileAPIOnly = "api-only"
+	// ProfileMinimal serves the inference API only, for the embedded and edge devices
+	ProfileMinimal = "minimal"
+)
+
+// Profile is the set of the optional subsystems enabled by a startup profile
+type Profile struct {
+	Name string
+	// WebUI serves the web interface
+	WebUI bool
+	// Galleries serves the endpoints installing the models and the backends of the galleries
+	Galleries bool
+	// P2P allows the p2p and federated modes
+	P2P bool
+	// Metrics collects the metrics of the API and serves them on /metrics
+	Metrics bool
+	// PythonBackends registers the external backends run from backend/python
+	PythonBackends bool
+}
+
+var profiles = map[string]Profile{
+	ProfileAllInOne: {Name: ProfileAllInOne, WebUI: true, Galleries: true, P2P: true, Metrics: true, PythonBackends: true},
+	ProfileAPIOnly:  {Name: ProfileAPIOnly, Galleries: true, Metrics: true, PythonBackends: true},
+	ProfileMinimal:  {Name: ProfileMinimal},
+}
+
+// GetProfile returns the startup profile with the name
+func GetProfile(name string) (Profile, error) {
+	p, exists := profiles[name]
+	if !exists {
+		return Profile{}, fmt.Errorf("unknown profile %q, available profiles: %s, %s and %s", name, ProfileAllInOne, ProfileAPIOnly, ProfileMinimal)
+	}
+	return p, nil
+}
+
+// IsPythonBackend tells if the external backend at the uri is one of the Python backends, run from
+// the backend/python directory
+func IsPythonBackend(uri string) bool {
+	return strings.Contains(filepath.ToSlash(uri), "backend/python/")
+}
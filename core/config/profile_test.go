@@ -0,0 +1,54 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Profiles", func() {
+	It("enables everything in the all-in-one profile", func() {
+		p, err := GetProfile(ProfileAllInOne)
+		Expect(err).ToNot(HaveOccurred())
+		appConfig := NewApplicationConfig(WithProfile(p))
+		Expect(appConfig.DisableWebUI).To(BeFalse())
+		Expect(appConfig.DisableGalleryEndpoint).To(BeFalse())
+		Expect(appConfig.DisableMetrics).To(BeFalse())
+		Expect(appConfig.DisablePythonBackends).To(BeFalse())
+	})
+
+	It("disables the WebUI in the api-only profile", func() {
+		p, err := GetProfile(ProfileAPIOnly)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.P2P).To(BeFalse())
+		appConfig := NewApplicationConfig(WithProfile(p))
+		Expect(appConfig.DisableWebUI).To(BeTrue())
+		Expect(appConfig.DisableGalleryEndpoint).To(BeFalse())
+		Expect(appConfig.ToEnvironment()).To(HaveKeyWithValue("LOCALAI_PROFILE", ProfileAPIOnly))
+	})
+
+	It("disables all the optional subsystems in the minimal profile", func() {
+		p, err := GetProfile(ProfileMinimal)
+		Expect(err).ToNot(HaveOccurred())
+		appConfig := NewApplicationConfig(WithProfile(p))
+		Expect(appConfig.DisableWebUI).To(BeTrue())
+		Expect(appConfig.DisableGalleryEndpoint).To(BeTrue())
+		Expect(appConfig.DisableMetrics).To(BeTrue())
+		Expect(appConfig.DisablePythonBackends).To(BeTrue())
+	})
+
+	It("keeps the WebUI disabled by the flag", func() {
+		p, _ := GetProfile(ProfileAllInOne)
+		Expect(NewApplicationConfig(DisableWebUI, WithProfile(p)).DisableWebUI).To(BeTrue())
+	})
+
+	It("rejects the unknown profiles", func() {
+		_, err := GetProfile("desktop")
+		Expect(err).To(MatchError(ContainSubstring("unknown profile")))
+	})
+
+	It("recognizes the Python backends", func() {
+		Expect(IsPythonBackend("/build/backend/python/vllm/run.sh")).To(BeTrue())
+		Expect(IsPythonBackend("127.0.0.1:50051")).To(BeFalse())
+		Expect(IsPythonBackend("/backends/bark/run.sh")).To(BeFalse())
+	})
+})
@@ -0,0 +1,29 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resources", func() {
+	It("parses the limits of the backends", func() {
+		nice := 5
+		limits, err := Resources{CPUSet: "0-3", Nice: &nice, Memory: "8GiB", GPUs: "0,1"}.Limits()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits.CPUSet).To(Equal("0-3"))
+		Expect(*limits.Nice).To(Equal(5))
+		Expect(limits.Memory).To(Equal(uint64(8 << 30)))
+		Expect(limits.GPUs).To(Equal("0,1"))
+
+		limits, err = Resources{}.Limits()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits.IsZero()).To(BeTrue())
+	})
+
+	It("rejects the invalid limits", func() {
+		_, err := Resources{Memory: "lots"}.Limits()
+		Expect(err).To(MatchError(ContainSubstring("invalid memory limit")))
+		_, err = Resources{CPUSet: "4-2"}.Limits()
+		Expect(err).To(MatchError(ContainSubstring("invalid cpuset")))
+	})
+})
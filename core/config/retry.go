@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Classes of the inference failures retried by the retry policies
+const (
+	// RetryOnCrash retries when the backend stopped or can not be reached
+	RetryOnCrash = "crash"
+	// RetryOnError retries the other errors of the backend
+	RetryOnError = "error"
+	// RetryOnEmpty retries the inferences returning an empty output
+	RetryOnEmpty = "empty"
+)
+
+const (
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy retries the failed inferences of a model. The waits between the attempts start at
+// Backoff and double after each attempt, up to MaxBackoff
+type RetryPolicy struct {
+	// MaxAttempts is the number of inferences run before failing, 1 (no retry) by default
+	MaxAttempts int    `yaml:"max_attempts"`
+	Backoff     string `yaml:"backoff"`
+	MaxBackoff  string `yaml:"max_backoff"`
+	// RetryOn are the classes of failures retried: crash, error or empty. Only the crashes by default
+	RetryOn []string `yaml:"retry_on"`
+}
+
+// Attempts returns the number of inferences run before failing
+func (r RetryPolicy) Attempts() int {
+	if r.MaxAttempts < 1 {
+		return 1
+	}
+	return r.MaxAttempts
+}
+
+// Retries returns true if the failures of the class are retried
+func (r RetryPolicy) Retries(class string) bool {
+	if len(r.RetryOn) == 0 {
+		return class == RetryOnCrash
+	}
+	return slices.Contains(r.RetryOn, class)
+}
+
+// Delay returns the wait before the given retry, counted from 1
+func (r RetryPolicy) Delay(retry int) time.Duration {
+	backoff, maxBackoff := defaultRetryBackoff, defaultRetryMaxBackoff
+	if d, err := time.ParseDuration(r.Backoff); err == nil {
+		backoff = d
+	}
+	if d, err := time.ParseDuration(r.MaxBackoff); err == nil {
+		maxBackoff = d
+	}
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// Validate returns an error if the policy has invalid values
+func (r RetryPolicy) Validate() error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts %d must be positive", r.MaxAttempts)
+	}
+	for name, d := range map[string]string{"backoff": r.Backoff, "max_backoff": r.MaxBackoff} {
+		if d == "" {
+			continue
+		}
+		if v, err := time.ParseDuration(d); err != nil || v < 0 {
+			return fmt.Errorf("invalid retry %s %q, expected a duration as \"500ms\"", name, d)
+		}
+	}
+	for _, class := range r.RetryOn {
+		if !slices.Contains([]string{RetryOnCrash, RetryOnError, RetryOnEmpty}, class) {
+			return fmt.Errorf("unknown retry_on class %q, expected %s, %s or %s", class, RetryOnCrash, RetryOnError, RetryOnEmpty)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	It("runs a single attempt and retries the crashes by default", func() {
+		r := RetryPolicy{}
+		Expect(r.Attempts()).To(Equal(1))
+		Expect(r.Retries(RetryOnCrash)).To(BeTrue())
+		Expect(r.Retries(RetryOnError)).To(BeFalse())
+		Expect(r.Retries(RetryOnEmpty)).To(BeFalse())
+	})
+
+	It("retries the classes of retry_on", func() {
+		r := RetryPolicy{MaxAttempts: 3, RetryOn: []string{RetryOnEmpty}}
+		Expect(r.Attempts()).To(Equal(3))
+		Expect(r.Retries(RetryOnEmpty)).To(BeTrue())
+		Expect(r.Retries(RetryOnCrash)).To(BeFalse())
+	})
+
+	It("doubles the backoff up to max_backoff", func() {
+		r := RetryPolicy{Backoff: "100ms", MaxBackoff: "350ms"}
+		Expect(r.Delay(1)).To(Equal(100 * time.Millisecond))
+		Expect(r.Delay(2)).To(Equal(200 * time.Millisecond))
+		Expect(r.Delay(3)).To(Equal(350 * time.Millisecond))
+		Expect(r.Delay(10)).To(Equal(350 * time.Millisecond))
+		Expect(RetryPolicy{}.Delay(1)).To(Equal(time.Second))
+	})
+
+	It("reports the invalid values", func() {
+		Expect(RetryPolicy{MaxAttempts: 2, Backoff: "1s", RetryOn: []string{RetryOnCrash, RetryOnError}}.Validate()).To(Succeed())
+		Expect(RetryPolicy{MaxAttempts: -1}.Validate()).ToNot(Succeed())
+		Expect(RetryPolicy{Backoff: "soon"}.Validate()).ToNot(Succeed())
+		Expect(RetryPolicy{RetryOn: []string{"timeout"}}.Validate()).ToNot(Succeed())
+	})
+})
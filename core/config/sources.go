@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The sources of the runtime settings, from the lowest to the highest precedence
+const (
+	SourceDefault      = "default"
+	SourceSettingsFile = "settings_file"
+	SourceEnv          = "env"
+	SourceFlag         = "flag"
+	// SourceDynamic is a file of the dynamic configuration directory, applied while the instance runs
+	SourceDynamic = "dynamic"
+)
+
+// dynamicSettings are the files of the dynamic configuration directory, by the setting they override
+var dynamicSettings = map[string]string{
+	"LOCALAI_EXTERNAL_GRPC_BACKENDS": "external_backends.json",
+}
+
+// SettingSource returns where the setting, by its environment variable, comes from
+func (o *ApplicationConfig) SettingSource(name string) string {
+	if file, dynamic := dynamicSettings[name]; dynamic && o.DynamicConfigsDir != "" {
+		if _, err := os.Stat(filepath.Join(o.DynamicConfigsDir, file)); err == nil {
+			return SourceDynamic
+		}
+	}
+	if source, exists := o.SettingSources[name]; exists {
+		return source
+	}
+	return SourceDefault
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/mudler/LocalAI/pkg/cron"
+	"gopkg.in/yaml.v3"
+)
+
+// ScheduledTask runs a prompt with a model, or a pipeline, at the times of a cron expression, as a nightly
+// summary of the reports, and delivers its output to a webhook or a file
+type ScheduledTask struct {
+	Name string `yaml:"-" json:"name"`
+	// Schedule is a cron expression, in the time zone of the server
+	Schedule string `yaml:"schedule" json:"schedule"`
+	// Model answers the Prompt, with the System prompt, with its chat template
+	Model  string `yaml:"model" json:"model,omitempty"`
+	System string `yaml:"system" json:"system,omitempty"`
+	Prompt string `yaml:"prompt" json:"prompt,omitempty"`
+	// Pipeline runs a pipeline of the pipelines file on the Input instead
+	Pipeline string `yaml:"pipeline" json:"pipeline,omitempty"`
+	Input    string `yaml:"input" json:"input,omitempty"`
+	// Webhook is a URL where the result of the runs is posted as JSON
+	Webhook string `yaml:"webhook" json:"webhook,omitempty"`
+	// File is the template of the path, in the tasks directory, of the file where the output of the
+	// runs is written, with the name of the task as {{.Name}} and the time of the run as {{.Time}},
+	// as reports/{{.Time.Format "2006-01-02"}}.md
+	File string `yaml:"file" json:"file,omitempty"`
+}
+
+// ReadTasksFile reads a YAML file mapping the names of the scheduled tasks to their schedule, prompt or
+// pipeline, and delivery. The pipelines of the tasks must be in pipelines
+func ReadTasksFile(file string, pipelines map[string]Pipeline) (map[string]ScheduledTask, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tasks file %q: %w", file, err)
+	}
+	tasks := map[string]ScheduledTask{}
+	if err := yaml.Unmarshal(ExpandEnv(f), &tasks); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal tasks file %q: %w", file, err)
+	}
+
+	for name, t := range tasks {
+		t.Name = name
+		if err := t.Validate(pipelines); err != nil {
+			return nil, fmt.Errorf("task %s: %w", name, err)
+		}
+		tasks[name] = t
+	}
+	return tasks, nil
+}
+
+// Validate checks the schedule, what the task runs and where its output is delivered
+func (t ScheduledTask) Validate(pipelines map[string]Pipeline) error {
+	// The name is a path segment of the endpoints of the task
+	if !pipelineName.MatchString(t.Name) {
+		return fmt.Errorf("invalid task name %q, it can only contain letters, digits, '-' and '_'", t.Name)
+	}
+	if t.Schedule == "" {
+		return fmt.Errorf("schedule is required")
+	}
+	if _, err := cron.Parse(t.Schedule); err != nil {
+		return err
+	}
+
+	switch {
+	case t.Pipeline != "" && (t.Model != "" || t.Prompt != ""):
+		return fmt.Errorf("a task runs either a pipeline or a prompt with a model, not both")
+	case t.Pipeline != "":
+		if _, exists := pipelines[t.Pipeline]; !exists {
+			return fmt.Errorf("unknown pipeline %s", t.Pipeline)
+		}
+	case t.Model == "" || t.Prompt == "":
+		return fmt.Errorf("model and prompt, or pipeline, are required")
+	}
+
+	if t.Webhook == "" && t.File == "" {
+		return fmt.Errorf("webhook or file is required")
+	}
+	if t.Webhook != "" {
+		u, err := url.Parse(t.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook %q, expected an http or https URL", t.Webhook)
+		}
+	}
+	if t.File != "" {
+		if filepath.IsAbs(t.File) {
+			return fmt.Errorf("the file %s must be relative to the tasks directory", t.File)
+		}
+		if _, err := template.New(t.Name).Parse(t.File); err != nil {
+			return fmt.Errorf("invalid file template: %w", err)
+		}
+	}
+	return nil
+}
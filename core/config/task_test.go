@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduled tasks", func() {
+	writeTasks := func(content string) string {
+		file := filepath.Join(GinkgoT().TempDir(), "tasks.yaml")
+		Expect(os.WriteFile(file, []byte(content), 0600)).To(Succeed())
+		return file
+	}
+	pipelines := map[string]Pipeline{"digest": {Name: "digest"}}
+
+	It("reads the tasks", func() {
+		tasks, err := ReadTasksFile(writeTasks(`nightly-summary:
+  schedule: "0 2 * * *"
+  model: mistral
+  system: You summarize reports
+  prompt: Summarize the reports of the day
+  file: reports/{{.Time.Format "2006-01-02"}}.md
+digest:
+  schedule: "@hourly"
+  pipeline: digest
+  input: What's new?
+  webhook: https://hooks.example.com/digest
+`), pipelines)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tasks).To(HaveLen(2))
+		Expect(tasks["nightly-summary"]).To(Equal(ScheduledTask{
+			Name:     "nightly-summary",
+			Schedule: "0 2 * * *",
+			Model:    "mistral",
+			System:   "You summarize reports",
+			Prompt:   "Summarize the reports of the day",
+			File:     `reports/{{.Time.Format "2006-01-02"}}.md`,
+		}))
+		Expect(tasks["digest"].Pipeline).To(Equal("digest"))
+	})
+
+	DescribeTable("rejects the invalid tasks",
+		func(task ScheduledTask, message string) {
+			Expect(task.Validate(pipelines)).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("invalid names", ScheduledTask{Name: "a/b", Schedule: "@daily", Model: "m", Prompt: "p", File: "out.txt"}, "invalid task name"),
+		Entry("missing schedules", ScheduledTask{Name: "t", Model: "m", Prompt: "p", File: "out.txt"}, "schedule is required"),
+		Entry("invalid schedules", ScheduledTask{Name: "t", Schedule: "* * *", Model: "m", Prompt: "p", File: "out.txt"}, "invalid cron expression"),
+		Entry("both a prompt and a pipeline", ScheduledTask{Name: "t", Schedule: "@daily", Model: "m", Prompt: "p", Pipeline: "digest", File: "out.txt"}, "not both"),
+		Entry("prompts without model", ScheduledTask{Name: "t", Schedule: "@daily", Prompt: "p", File: "out.txt"}, "model and prompt, or pipeline, are required"),
+		Entry("unknown pipelines", ScheduledTask{Name: "t", Schedule: "@daily", Pipeline: "news", File: "out.txt"}, "unknown pipeline news"),
+		Entry("no delivery", ScheduledTask{Name: "t", Schedule: "@daily", Model: "m", Prompt: "p"}, "webhook or file is required"),
+		Entry("invalid webhooks", ScheduledTask{Name: "t", Schedule: "@daily", Model: "m", Prompt: "p", Webhook: "ftp://example.com"}, "invalid webhook"),
+		Entry("absolute files", ScheduledTask{Name: "t", Schedule: "@daily", Model: "m", Prompt: "p", File: "/etc/cron.d/job"}, "relative to the tasks directory"),
+		Entry("invalid file templates", ScheduledTask{Name: "t", Schedule: "@daily", Model: "m", Prompt: "p", File: "{{.Time"}, "invalid file template"),
+	)
+})
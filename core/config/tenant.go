@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mudler/LocalAI/pkg/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// Tenant is a group of API keys isolated from the other tenants: the keys only see the models
+// of the tenant and the files uploaded by the tenant, and share its token quota and rate limit
+type Tenant struct {
+	Name    string   `yaml:"-"`
+	APIKeys []string `yaml:"api_keys"`
+	// Models visible to the tenant, as names or glob patterns. All the models are visible when empty
+	Models []string `yaml:"models"`
+	// Tokens the keys of the tenant can use per day (UTC), 0 disables the quota
+	DailyTokens int `yaml:"daily_tokens"`
+	// Requests the keys of the tenant can send per minute, 0 disables the limit
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	// Priority schedules the requests of the keys of the tenant before the other requests, as for the code
+	// completions of the editors
+	Priority bool `yaml:"priority"`
+}
+
+var tenantName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ReadTenantsFile reads a YAML file mapping the names of the tenants to their settings
+func ReadTenantsFile(file string) (map[string]Tenant, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tenants file %q: %w", file, err)
+	}
+	tenants := map[string]Tenant{}
+	if err := yaml.Unmarshal(f, &tenants); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal tenants file %q: %w", file, err)
+	}
+
+	keys := map[string]string{}
+	for name, t := range tenants {
+		// The name is used as the directory of the uploads of the tenant
+		if !tenantName.MatchString(name) {
+			return nil, fmt.Errorf("invalid tenant name %q, it can only contain letters, digits, '-' and '_'", name)
+		}
+		if len(t.APIKeys) == 0 {
+			return nil, fmt.Errorf("tenant %s has no API keys", name)
+		}
+		if t.APIKeys, err = secrets.ResolveAll(context.Background(), t.APIKeys); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", name, err)
+		}
+		for _, k := range t.APIKeys {
+			if other, exists := keys[k]; exists {
+				return nil, fmt.Errorf("tenants %s and %s share an API key", other, name)
+			}
+			keys[k] = name
+		}
+		for _, m := range t.Models {
+			if _, err := path.Match(m, ""); err != nil {
+				return nil, fmt.Errorf("tenant %s: invalid model pattern %q", name, m)
+			}
+		}
+		t.Name = name
+		tenants[name] = t
+	}
+	return tenants, nil
+}
+
+// CanUseModel tells if the model is visible to the tenant
+func (t *Tenant) CanUseModel(name string) bool {
+	if len(t.Models) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(t.Models, func(pattern string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	})
+}
+
+// TenantOwnerPrefix prefixes the name of the tenants owning resources, as the jobs, the scheduled tasks and
+// the uploaded files, and counting the tokens used in the quotas
+const TenantOwnerPrefix = "tenant:"
+
+// Owner identifies the tenant in the token quotas and as owner of the resources, separately from its API keys
+func (t *Tenant) Owner() string {
+	return TenantOwnerPrefix + t.Name
+}
+
+// APIKeyOwner identifies an API key in the token quotas and as owner of the resources: API keys are not
+// stored as-is
+func APIKeyOwner(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// TenantByOwner returns the tenant owning the resources of owner, or nil if they are owned by an API key of
+// the instance
+func (o *ApplicationConfig) TenantByOwner(owner string) *Tenant {
+	name, ok := strings.CutPrefix(owner, TenantOwnerPrefix)
+	if !ok {
+		return nil
+	}
+	t, exists := o.Tenants[name]
+	if !exists {
+		return nil
+	}
+	return &t
+}
+
+// TenantByAPIKey returns the tenant of the API key, or nil if the key does not belong to a tenant
+func (o *ApplicationConfig) TenantByAPIKey(apiKey string) *Tenant {
+	for _, t := range o.Tenants {
+		if slices.Contains(t.APIKeys, apiKey) {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tenants", func() {
+	writeTenants := func(content string) string {
+		file := filepath.Join(GinkgoT().TempDir(), "tenants.yaml")
+		Expect(os.WriteFile(file, []byte(content), 0600)).To(Succeed())
+		return file
+	}
+
+	It("reads the tenants and finds them by API key", func() {
+		tenants, err := ReadTenantsFile(writeTenants(`acme:
+  api_keys: [key-1, key-2]
+  models: [llama-*, whisper]
+  daily_tokens: 1000
+  requests_per_minute: 60
+globex:
+  api_keys: [key-3]
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tenants).To(HaveLen(2))
+
+		appConfig := NewApplicationConfig(WithTenants(tenants))
+		acme := appConfig.TenantByAPIKey("key-2")
+		Expect(acme).ToNot(BeNil())
+		Expect(acme.Name).To(Equal("acme"))
+		Expect(acme.DailyTokens).To(Equal(1000))
+		Expect(acme.RequestsPerMinute).To(Equal(60))
+		Expect(acme.CanUseModel("llama-3")).To(BeTrue())
+		Expect(acme.CanUseModel("whisper")).To(BeTrue())
+		Expect(acme.CanUseModel("mistral")).To(BeFalse())
+
+		globex := appConfig.TenantByAPIKey("key-3")
+		Expect(globex).ToNot(BeNil())
+		Expect(globex.CanUseModel("mistral")).To(BeTrue())
+
+		Expect(appConfig.TenantByAPIKey("unknown")).To(BeNil())
+
+		Expect(appConfig.TenantByOwner(TenantOwnerPrefix + "acme")).ToNot(BeNil())
+		Expect(appConfig.TenantByOwner(TenantOwnerPrefix + "initech")).To(BeNil())
+		Expect(appConfig.TenantByOwner("acme")).To(BeNil())
+	})
+
+	It("reads the API keys from the secrets", func() {
+		GinkgoT().Setenv("LOCALAI_TEST_TENANT_KEY", "key-from-env")
+		tenants, err := ReadTenantsFile(writeTenants(`acme:
+  api_keys: [env://LOCALAI_TEST_TENANT_KEY]
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tenants["acme"].APIKeys).To(Equal([]string{"key-from-env"}))
+
+		_, err = ReadTenantsFile(writeTenants(`acme:
+  api_keys: [env://LOCALAI_TEST_MISSING_TENANT_KEY]
+`))
+		Expect(err).To(MatchError(ContainSubstring("tenant acme")))
+	})
+
+	It("reports invalid tenants", func() {
+		_, err := ReadTenantsFile(writeTenants(`"../acme":
+  api_keys: [key-1]
+`))
+		Expect(err).To(MatchError(ContainSubstring("invalid tenant name")))
+
+		_, err = ReadTenantsFile(writeTenants(`acme:
+  models: [llama]
+`))
+		Expect(err).To(MatchError(ContainSubstring("tenant acme has no API keys")))
+
+		_, err = ReadTenantsFile(writeTenants(`acme:
+  api_keys: [key-1]
+globex:
+  api_keys: [key-1]
+`))
+		Expect(err).To(MatchError(ContainSubstring("share an API key")))
+
+		_, err = ReadTenantsFile(writeTenants(`acme:
+  api_keys: [key-1]
+  models: ["llama-["]
+`))
+		Expect(err).To(MatchError(ContainSubstring("invalid model pattern")))
+	})
+})
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// anyLanguage matches all the languages in the pairs of the translation models
+const anyLanguage = "*"
+
+// Translation selects the translation models, as NLLB or M2M100, by the languages they translate, and the
+// models detecting the language of the texts
+type Translation struct {
+	// Pairs are the source and target languages translated by the model, as "en-fr". "*" matches all the
+	// languages, as "*-en" for the models translating to English
+	Pairs []string `yaml:"pairs"`
+	// Languages maps the languages of the requests to the codes of the model, as "fr" to "fra_Latn" for NLLB.
+	// The languages missing are passed as-is
+	Languages map[string]string `yaml:"languages"`
+	// LanguageDetection marks the text classification model detecting the language of the texts, whose
+	// labels are the languages
+	LanguageDetection bool `yaml:"language_detection"`
+}
+
+// Translates tells if the model translates the text in the source language to the target language
+func (t Translation) Translates(source, target string) bool {
+	for _, pair := range t.Pairs {
+		from, to, _ := strings.Cut(pair, "-")
+		if (from == anyLanguage || strings.EqualFold(from, source)) && (to == anyLanguage || strings.EqualFold(to, target)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Code returns the code of the language for the model
+func (t Translation) Code(language string) string {
+	if code, exists := t.Languages[language]; exists {
+		return code
+	}
+	return language
+}
+
+// Validate checks the language pairs
+func (t Translation) Validate() error {
+	for _, pair := range t.Pairs {
+		from, to, found := strings.Cut(pair, "-")
+		if !found || from == "" || to == "" {
+			return fmt.Errorf("invalid translation pair %q: expected source-target, as en-fr", pair)
+		}
+	}
+	return nil
+}
+
+// TranslationModel returns the first model, by name, translating the source language to the target language
+func (bcl *BackendConfigLoader) TranslationModel(source, target string) (BackendConfig, bool) {
+	for _, c := range bcl.GetAllBackendConfigs() {
+		if c.Translation.Translates(source, target) {
+			return c, true
+		}
+	}
+	return BackendConfig{}, false
+}
+
+// LanguageDetectionModel returns the first model, by name, detecting the language of the texts
+func (bcl *BackendConfigLoader) LanguageDetectionModel() (BackendConfig, bool) {
+	for _, c := range bcl.GetAllBackendConfigs() {
+		if c.Translation.LanguageDetection {
+			return c, true
+		}
+	}
+	return BackendConfig{}, false
+}
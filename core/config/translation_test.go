@@ -0,0 +1,51 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Translation", func() {
+	It("matches the language pairs", func() {
+		t := Translation{Pairs: []string{"en-fr", "*-de"}}
+		Expect(t.Translates("en", "fr")).To(BeTrue())
+		Expect(t.Translates("EN", "FR")).To(BeTrue())
+		Expect(t.Translates("fr", "en")).To(BeFalse())
+		Expect(t.Translates("it", "de")).To(BeTrue())
+		Expect(t.Translates("it", "es")).To(BeFalse())
+	})
+
+	It("maps the languages to the codes of the model", func() {
+		t := Translation{Languages: map[string]string{"fr": "fra_Latn"}}
+		Expect(t.Code("fr")).To(Equal("fra_Latn"))
+		Expect(t.Code("en")).To(Equal("en"))
+	})
+
+	It("rejects the invalid pairs", func() {
+		Expect(Translation{Pairs: []string{"en-fr", "*-*"}}.Validate()).To(Succeed())
+		Expect(Translation{Pairs: []string{"en"}}.Validate()).To(MatchError(ContainSubstring(`invalid translation pair "en"`)))
+		Expect(Translation{Pairs: []string{"en-"}}.Validate()).To(HaveOccurred())
+	})
+
+	It("selects the models by language pair", func() {
+		bcl := NewBackendConfigLoader(GinkgoT().TempDir())
+		bcl.configs["nllb"] = BackendConfig{Name: "nllb", Translation: Translation{Pairs: []string{"*-*"}}}
+		bcl.configs["en-fr"] = BackendConfig{Name: "en-fr", Translation: Translation{Pairs: []string{"en-fr"}}}
+		bcl.configs["langid"] = BackendConfig{Name: "langid", Translation: Translation{LanguageDetection: true}}
+
+		c, exists := bcl.TranslationModel("en", "fr")
+		Expect(exists).To(BeTrue())
+		Expect(c.Name).To(Equal("en-fr"))
+		c, exists = bcl.TranslationModel("de", "it")
+		Expect(exists).To(BeTrue())
+		Expect(c.Name).To(Equal("nllb"))
+
+		c, exists = bcl.LanguageDetectionModel()
+		Expect(exists).To(BeTrue())
+		Expect(c.Name).To(Equal("langid"))
+
+		delete(bcl.configs, "nllb")
+		_, exists = bcl.TranslationModel("de", "it")
+		Expect(exists).To(BeFalse())
+	})
+})
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// VLLMBackend is the backend of the models run by vLLM
+const VLLMBackend = "vllm"
+
+// transformersQuantizations are the quantizations of the transformers backend, with bitsandbytes or on
+// Intel GPUs
+var transformersQuantizations = []string{"bnb_4bit", "bnb_8bit", "xpu_4bit", "xpu_8bit"}
+
+// vllmFields returns the engine options of vLLM which are set, by their YAML field
+func (c BackendConfig) vllmFields() []string {
+	fields := []string{}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"gpu_memory_utilization", c.GPUMemoryUtilization != 0},
+		{"enforce_eager", c.EnforceEager},
+		{"swap_space", c.SwapSpace != 0},
+		{"max_model_len", c.MaxModelLen != 0},
+		{"tensor_parallel_size", c.TensorParallelSize != 0},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// ValidateVLLM returns an error if the engine options of vLLM of the model are invalid. They are checked
+// before starting the backend, as vLLM reports them only once the model is being loaded on the GPUs
+func (c BackendConfig) ValidateVLLM() error {
+	errs := []error{}
+	if c.GPUMemoryUtilization < 0 || c.GPUMemoryUtilization > 1 {
+		errs = append(errs, fmt.Errorf("gpu_memory_utilization %g must be between 0 and 1", c.GPUMemoryUtilization))
+	}
+	if c.TensorParallelSize < 0 {
+		errs = append(errs, fmt.Errorf("tensor_parallel_size %d must be positive", c.TensorParallelSize))
+	}
+	if c.MaxModelLen < 0 {
+		errs = append(errs, fmt.Errorf("max_model_len %d must be positive", c.MaxModelLen))
+	}
+	if c.SwapSpace < 0 {
+		errs = append(errs, fmt.Errorf("swap_space %d must be positive", c.SwapSpace))
+	}
+	if slices.Contains(transformersQuantizations, c.Quantization) {
+		errs = append(errs, fmt.Errorf("quantization %q is a quantization of the transformers backend, vLLM reads the bitsandbytes models with \"bitsandbytes\"", c.Quantization))
+	}
+	return errors.Join(errs...)
+}
+
+// checkBackendOptions returns the errors of the options specific to a backend: the engine options of vLLM
+// and the options of whisper.cpp are validated, and refused for the other backends, which would ignore them
+func (c BackendConfig) checkBackendOptions() []error {
+	errs := c.checkWhisperOptions()
+	if c.Backend == VLLMBackend {
+		if err := c.ValidateVLLM(); err != nil {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+	// the models without a backend may be loaded by vLLM
+	if c.Backend == "" {
+		return errs
+	}
+	if fields := c.vllmFields(); len(fields) > 0 {
+		errs = append(errs, fmt.Errorf("%s only apply to the %s backend, not to %s", strings.Join(fields, ", "), VLLMBackend, c.Backend))
+	}
+	if c.Quantization != "" && c.Backend == "transformers" && !slices.Contains(transformersQuantizations, c.Quantization) {
+		errs = append(errs, fmt.Errorf("invalid quantization %q for the transformers backend: expected one of %s", c.Quantization, strings.Join(transformersQuantizations, ", ")))
+	}
+	return errs
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("vLLM options", func() {
+	It("validates the engine options", func() {
+		c := BackendConfig{Backend: VLLMBackend}
+		c.GPUMemoryUtilization = 0.8
+		c.TensorParallelSize = 2
+		c.MaxModelLen = 8192
+		c.Quantization = "awq"
+		Expect(c.ValidateVLLM()).To(Succeed())
+		Expect(c.checkBackendOptions()).To(BeEmpty())
+
+		c.GPUMemoryUtilization = 80
+		c.TensorParallelSize = -1
+		c.Quantization = "bnb_4bit"
+		err := c.ValidateVLLM()
+		Expect(err).To(MatchError(ContainSubstring("gpu_memory_utilization 80 must be between 0 and 1")))
+		Expect(err).To(MatchError(ContainSubstring("tensor_parallel_size -1 must be positive")))
+		Expect(err).To(MatchError(ContainSubstring(`vLLM reads the bitsandbytes models with "bitsandbytes"`)))
+		Expect(c.checkBackendOptions()).To(HaveLen(1))
+	})
+
+	It("refuses the engine options of vLLM for the other backends", func() {
+		c := BackendConfig{Backend: "llama-cpp"}
+		c.GPUMemoryUtilization = 0.8
+		c.MaxModelLen = 8192
+		Expect(c.checkBackendOptions()).To(ConsistOf(MatchError("gpu_memory_utilization, max_model_len only apply to the vllm backend, not to llama-cpp")))
+
+		c = BackendConfig{Backend: "transformers"}
+		c.Quantization = "awq"
+		Expect(c.checkBackendOptions()).To(ConsistOf(MatchError(ContainSubstring(`invalid quantization "awq" for the transformers backend`))))
+		c.Quantization = "bnb_4bit"
+		c.TrustRemoteCode = true
+		Expect(c.checkBackendOptions()).To(BeEmpty())
+
+		c = BackendConfig{}
+		c.TensorParallelSize = 2
+		Expect(c.checkBackendOptions()).To(BeEmpty())
+	})
+})
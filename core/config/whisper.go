@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WhisperBackend is the backend of the models run by whisper.cpp
+const WhisperBackend = "whisper"
+
+// whisperQuantizations are the types of the weights of the ggml files whisper.cpp loads
+var whisperQuantizations = []string{"f32", "f16", "q4_0", "q4_1", "q5_0", "q5_1", "q8_0", "q2_k", "q3_k", "q4_k", "q5_k", "q6_k"}
+
+// maxWhisperBeamSize is the number of decoders of whisper.cpp, which bounds the beam size
+const maxWhisperBeamSize = 8
+
+// Whisper holds the options of the models run by whisper.cpp
+type Whisper struct {
+	// GPU runs the model on the GPU, when whisper.cpp is built with the support of a GPU. True by default
+	GPU       *bool `yaml:"gpu" description:"Run the model on the GPU, when whisper.cpp is built for one. True by default"`
+	GPUDevice int   `yaml:"gpu_device" description:"Index of the GPU the model runs on" jsonschema:"minimum=0"`
+	// Quantization is the type the weights of the model file must have, to refuse the files downloaded with
+	// another quantization
+	Quantization string `yaml:"quantization" description:"Quantization the model file must have, any if empty" jsonschema:"enum=f32|f16|q4_0|q4_1|q5_0|q5_1|q8_0|q2_k|q3_k|q4_k|q5_k|q6_k"`
+	// BeamSize decodes with a beam search of this size, greedily below 2
+	BeamSize int `yaml:"beam_size" description:"Size of the beam search of the decoding, greedy below 2" jsonschema:"minimum=0,maximum=8"`
+	// NoSpeechThreshold is the probability of the no speech token above which whisper considers a segment silent
+	NoSpeechThreshold float32 `yaml:"no_speech_threshold" description:"Probability of no speech above which the segments are silent, 0.6 by default" jsonschema:"minimum=0,maximum=1"`
+	// VADThreshold drops the segments without speech, whose energy is below this ratio of the energy of the
+	// audio once the frequencies below VADFreqThreshold are filtered out. 0 disables the detection
+	VADThreshold     float32 `yaml:"vad_threshold" description:"Ratio of the energy of the audio below which the segments are dropped as silent, 0 disables the detection of the speech" jsonschema:"minimum=0,maximum=1"`
+	VADFreqThreshold float32 `yaml:"vad_freq_threshold" description:"Frequency in Hz below which the audio is filtered out by the detection of the speech, 100 by default" jsonschema:"minimum=0"`
+}
+
+// UseGPU tells if the model runs on the GPU
+func (w Whisper) UseGPU() bool {
+	return w.GPU == nil || *w.GPU
+}
+
+// whisperFields returns the options of whisper.cpp which are set, by their YAML field
+func (c BackendConfig) whisperFields() []string {
+	fields := []string{}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"whisper.gpu", c.Whisper.GPU != nil},
+		{"whisper.gpu_device", c.Whisper.GPUDevice != 0},
+		{"whisper.quantization", c.Whisper.Quantization != ""},
+		{"whisper.beam_size", c.Whisper.BeamSize != 0},
+		{"whisper.no_speech_threshold", c.Whisper.NoSpeechThreshold != 0},
+		{"whisper.vad_threshold", c.Whisper.VADThreshold != 0},
+		{"whisper.vad_freq_threshold", c.Whisper.VADFreqThreshold != 0},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// ValidateWhisper returns an error if the options of whisper.cpp of the model are invalid
+func (c BackendConfig) ValidateWhisper() error {
+	w := c.Whisper
+	errs := []error{}
+	if w.GPUDevice < 0 {
+		errs = append(errs, fmt.Errorf("whisper.gpu_device %d must be positive", w.GPUDevice))
+	}
+	if w.Quantization != "" && !slices.Contains(whisperQuantizations, strings.ToLower(w.Quantization)) {
+		errs = append(errs, fmt.Errorf("invalid whisper.quantization %q: expected one of %s", w.Quantization, strings.Join(whisperQuantizations, ", ")))
+	}
+	if w.BeamSize < 0 || w.BeamSize > maxWhisperBeamSize {
+		errs = append(errs, fmt.Errorf("whisper.beam_size %d must be between 0 and %d", w.BeamSize, maxWhisperBeamSize))
+	}
+	if w.NoSpeechThreshold < 0 || w.NoSpeechThreshold > 1 {
+		errs = append(errs, fmt.Errorf("whisper.no_speech_threshold %g must be between 0 and 1", w.NoSpeechThreshold))
+	}
+	if w.VADThreshold < 0 || w.VADThreshold > 1 {
+		errs = append(errs, fmt.Errorf("whisper.vad_threshold %g must be between 0 and 1", w.VADThreshold))
+	}
+	if w.VADFreqThreshold < 0 {
+		errs = append(errs, fmt.Errorf("whisper.vad_freq_threshold %g must be positive", w.VADFreqThreshold))
+	}
+	return errors.Join(errs...)
+}
+
+// checkWhisperOptions returns the errors of the options of whisper.cpp: they are validated for the whisper
+// models, and refused for the other backends, which would ignore them
+func (c BackendConfig) checkWhisperOptions() []error {
+	if c.Backend == WhisperBackend {
+		if err := c.ValidateWhisper(); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+	if fields := c.whisperFields(); c.Backend != "" && len(fields) > 0 {
+		return []error{fmt.Errorf("%s only apply to the %s backend, not to %s", strings.Join(fields, ", "), WhisperBackend, c.Backend)}
+	}
+	return nil
+}
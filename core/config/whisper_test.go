@@ -0,0 +1,37 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("whisper options", func() {
+	It("validates the options of whisper.cpp", func() {
+		gpu := false
+		c := BackendConfig{Backend: WhisperBackend}
+		Expect(c.Whisper.UseGPU()).To(BeTrue())
+		c.Whisper = Whisper{GPU: &gpu, Quantization: "q5_0", BeamSize: 5, NoSpeechThreshold: 0.6, VADThreshold: 0.6, VADFreqThreshold: 100}
+		Expect(c.Whisper.UseGPU()).To(BeFalse())
+		Expect(c.ValidateWhisper()).To(Succeed())
+		Expect(c.checkBackendOptions()).To(BeEmpty())
+
+		c.Whisper = Whisper{GPUDevice: -1, Quantization: "q4_k_m", BeamSize: 16, NoSpeechThreshold: 2, VADThreshold: -0.5}
+		err := c.ValidateWhisper()
+		Expect(err).To(MatchError(ContainSubstring("whisper.gpu_device -1 must be positive")))
+		Expect(err).To(MatchError(ContainSubstring(`invalid whisper.quantization "q4_k_m"`)))
+		Expect(err).To(MatchError(ContainSubstring("whisper.beam_size 16 must be between 0 and 8")))
+		Expect(err).To(MatchError(ContainSubstring("whisper.no_speech_threshold 2 must be between 0 and 1")))
+		Expect(err).To(MatchError(ContainSubstring("whisper.vad_threshold -0.5 must be between 0 and 1")))
+		Expect(c.checkBackendOptions()).To(HaveLen(1))
+	})
+
+	It("refuses the options of whisper.cpp for the other backends", func() {
+		c := BackendConfig{Backend: "llama-cpp"}
+		c.Whisper.BeamSize = 5
+		c.Whisper.VADThreshold = 0.6
+		Expect(c.checkBackendOptions()).To(ConsistOf(MatchError("whisper.beam_size, whisper.vad_threshold only apply to the whisper backend, not to llama-cpp")))
+
+		c.Backend = ""
+		Expect(c.checkBackendOptions()).To(BeEmpty())
+	})
+})
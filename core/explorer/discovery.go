@@ -3,6 +3,8 @@ package explorer
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -55,6 +57,30 @@ type Network struct {
 	Clusters []ClusterData
 }
 
+// Models returns the models served by the online workers of the network
+func (n Network) Models() []string {
+	models := []string{}
+	for _, c := range n.Clusters {
+		for _, m := range c.Models {
+			if !slices.Contains(models, m) {
+				models = append(models, m)
+			}
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// Serves tells if an online worker of the network serves the model
+func (n Network) Serves(model string) bool {
+	for _, c := range n.Clusters {
+		if slices.Contains(c.Models, model) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *DiscoveryServer) runBackground() {
 	if len(s.database.TokenList()) == 0 {
 		time.Sleep(5 * time.Second) // avoid busy loop
@@ -150,6 +176,8 @@ type ClusterData struct {
 	Workers   []string
 	Type      string
 	NetworkID string
+	// Models are the models served by the online workers of the cluster
+	Models []string
 }
 
 func (s *DiscoveryServer) retrieveNetworkData(c context.Context, ledger *blockchain.Ledger, networkData chan ClusterData) {
@@ -204,8 +232,14 @@ func (s *DiscoveryServer) retrieveNetworkData(c context.Context, ledger *blockch
 					if nd.IsOnline() {
 						atLeastOneWorker = true
 						(&cd).Workers = append(cd.Workers, nd.ID)
+						for _, m := range nd.Models {
+							if !slices.Contains(cd.Models, m) {
+								cd.Models = append(cd.Models, m)
+							}
+						}
 					}
 				}
+				sort.Strings(cd.Models)
 
 				if atLeastOneWorker {
 					clusters[d] = cd
@@ -0,0 +1,30 @@
+package explorer_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mudler/LocalAI/core/explorer"
+)
+
+var _ = Describe("Network", func() {
+	network := explorer.Network{
+		Clusters: []explorer.ClusterData{
+			{Type: "federated", Workers: []string{"a", "b"}, Models: []string{"phi-2", "llama-3"}},
+			{Type: "federated", NetworkID: "gpu", Workers: []string{"c"}, Models: []string{"llama-3", "whisper"}},
+			{Type: "worker", Workers: []string{"d"}},
+		},
+	}
+
+	It("lists the models of all the clusters once", func() {
+		Expect(network.Models()).To(Equal([]string{"llama-3", "phi-2", "whisper"}))
+		Expect(explorer.Network{}.Models()).To(BeEmpty())
+	})
+
+	It("tells if a cluster serves a model", func() {
+		Expect(network.Serves("whisper")).To(BeTrue())
+		Expect(network.Serves("phi-2")).To(BeTrue())
+		Expect(network.Serves("mistral")).To(BeFalse())
+		Expect(network.Serves("")).To(BeFalse())
+	})
+})
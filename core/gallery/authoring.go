@@ -0,0 +1,333 @@
+package gallery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// IndexFile is the name of the index of the galleries created with InitGallery
+	IndexFile = "index.yaml"
+	// BaseTemplate is the name of the configuration template the models are added with by default
+	BaseTemplate = "base.yaml"
+)
+
+// modelExtensions are the extensions of the files added as models when no file is given
+var modelExtensions = []string{".gguf", ".ggml", ".bin", ".onnx", ".safetensors"}
+
+var sha256Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+const indexHeader = `# The models of the gallery, added with local-ai gallery add
+[]
+`
+
+const baseTemplate = `# The base configuration of the models of the gallery. The model file of each model
+# is set by the overrides of its entry in the index
+name: base
+config_file: |
+  backend: llama-cpp
+  context_size: 4096
+  mmap: true
+  f16: true
+`
+
+// AddModelOptions are the details of the models added to a gallery with AddModels
+type AddModelOptions struct {
+	// BaseURL is the URL the directory of the gallery is served at
+	BaseURL string
+	// Template is the configuration template of the models, relative to the directory of the gallery
+	Template string
+	// Name is the name of the model, only when a single file is added. It defaults to the name of the file
+	Name        string
+	Description string
+	License     string
+	Tags        []string
+}
+
+// InitGallery creates an empty gallery in dir, with an index and the base configuration template of its models
+func InitGallery(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, IndexFile)); err == nil {
+		return fmt.Errorf("the gallery index %s already exists", filepath.Join(dir, IndexFile))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, BaseTemplate)); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(filepath.Join(dir, BaseTemplate), []byte(baseTemplate), 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, IndexFile), []byte(indexHeader), 0644)
+}
+
+// ReadGalleryIndex reads the index of the gallery in dir
+func ReadGalleryIndex(dir string) ([]GalleryModel, error) {
+	dat, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, err
+	}
+	var models []GalleryModel
+	if err := yaml.Unmarshal(dat, &models); err != nil {
+		return nil, fmt.Errorf("invalid gallery index: %w", err)
+	}
+	return models, nil
+}
+
+// WriteGalleryIndex writes the index of the gallery in dir
+func WriteGalleryIndex(dir string, models []GalleryModel) error {
+	dat, err := yaml.Marshal(models)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, IndexFile), dat, 0644)
+}
+
+// AddModels adds the model files of dir to the index of its gallery, with their hash and size, and returns the
+// models added. Without files, all the model files of dir are added. The models already in the index are updated
+func AddModels(dir string, files []string, o AddModelOptions) ([]GalleryModel, error) {
+	if o.BaseURL == "" {
+		return nil, fmt.Errorf("the base URL of the gallery is required")
+	}
+	if o.Template == "" {
+		o.Template = BaseTemplate
+	}
+	if _, err := os.Stat(filepath.Join(dir, o.Template)); err != nil {
+		return nil, fmt.Errorf("the configuration template %s is missing, run local-ai gallery init first: %w", o.Template, err)
+	}
+	templateURL, err := url.JoinPath(o.BaseURL, filepath.ToSlash(o.Template))
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := ReadGalleryIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		if files, err = modelFiles(dir); err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no model file found in %s", dir)
+		}
+	}
+	if o.Name != "" && len(files) > 1 {
+		return nil, fmt.Errorf("a name can only be given when adding a single file")
+	}
+
+	var added []GalleryModel
+	for _, f := range files {
+		file, err := NewGalleryFile(dir, f, o.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		name := o.Name
+		if name == "" {
+			name = strings.ToLower(strings.TrimSuffix(filepath.Base(file.Filename), filepath.Ext(file.Filename)))
+		}
+
+		model := GalleryModel{Name: name}
+		i := slices.IndexFunc(models, func(m GalleryModel) bool { return strings.EqualFold(m.Name, name) })
+		if i >= 0 {
+			model = models[i]
+		}
+		model.URL = templateURL
+		model.ConfigFile = nil
+		model.Overrides = map[string]interface{}{"parameters": map[string]interface{}{"model": file.Filename}}
+		model.AdditionalFiles = []File{file}
+		if o.Description != "" {
+			model.Description = o.Description
+		}
+		if o.License != "" {
+			model.License = o.License
+		}
+		if len(o.Tags) > 0 {
+			model.Tags = o.Tags
+		}
+
+		if i >= 0 {
+			models[i] = model
+		} else {
+			models = append(models, model)
+		}
+		added = append(added, model)
+	}
+
+	return added, WriteGalleryIndex(dir, models)
+}
+
+// NewGalleryFile returns the entry of a file of the gallery in dir, with its hash and size, served under baseURL
+func NewGalleryFile(dir, path, baseURL string) (File, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return File{}, fmt.Errorf("the file %s is not in the gallery directory %s", path, dir)
+	}
+	rel = filepath.ToSlash(rel)
+
+	sum, size, err := hashFile(filepath.Join(dir, rel))
+	if err != nil {
+		return File{}, err
+	}
+	uri, err := url.JoinPath(baseURL, rel)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Filename: rel, SHA256: sum, URI: uri, Size: size}, nil
+}
+
+// ValidateGallery checks the index of the gallery in dir, and that the files served under baseURL are in dir
+// with the size of their entry. Their hashes are checked as well with checkHashes, reading the whole files.
+// All the problems found are returned
+func ValidateGallery(dir, baseURL string, checkHashes bool) error {
+	models, err := ReadGalleryIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	names := map[string]bool{}
+	for i, m := range models {
+		if m.Name == "" {
+			errs = append(errs, fmt.Errorf("the model %d has no name", i+1))
+		} else if names[strings.ToLower(m.Name)] {
+			errs = append(errs, fmt.Errorf("the model %s is defined more than once", m.Name))
+		}
+		names[strings.ToLower(m.Name)] = true
+
+		switch {
+		case m.URL == "" && len(m.ConfigFile) == 0:
+			errs = append(errs, fmt.Errorf("the model %s has neither url nor config_file", m.Name))
+		case m.URL != "":
+			if path, local := localPath(dir, baseURL, m.URL); local {
+				if err := validateTemplate(path); err != nil {
+					errs = append(errs, fmt.Errorf("the configuration template of the model %s is invalid: %w", m.Name, err))
+				}
+			}
+		}
+
+		for _, f := range m.AdditionalFiles {
+			if err := validateFile(dir, baseURL, f, checkHashes); err != nil {
+				errs = append(errs, fmt.Errorf("the file %s of the model %s is invalid: %w", f.Filename, m.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateFile(dir, baseURL string, f File, checkHashes bool) error {
+	switch {
+	case f.Filename == "":
+		return fmt.Errorf("no filename")
+	case !filepath.IsLocal(filepath.FromSlash(f.Filename)):
+		return fmt.Errorf("the filename must be a relative path within the models directory")
+	case f.URI == "":
+		return fmt.Errorf("no uri")
+	case !sha256Regexp.MatchString(f.SHA256):
+		return fmt.Errorf("the sha256 %q is not a valid hash", f.SHA256)
+	}
+
+	path, local := localPath(dir, baseURL, f.URI)
+	if !local {
+		return nil
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if f.Size > 0 && st.Size() != f.Size {
+		return fmt.Errorf("the size is %d, expected %d", st.Size(), f.Size)
+	}
+	if checkHashes {
+		sum, _, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		if sum != f.SHA256 {
+			return fmt.Errorf("the sha256 is %s, expected %s", sum, f.SHA256)
+		}
+	}
+	return nil
+}
+
+func validateTemplate(path string) error {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var c Config
+	if err := yaml.Unmarshal(dat, &c); err != nil {
+		return err
+	}
+	if c.ConfigFile == "" {
+		return fmt.Errorf("no config_file")
+	}
+	return nil
+}
+
+// localPath returns the path in dir of a URL served under baseURL
+func localPath(dir, baseURL, u string) (string, bool) {
+	if baseURL == "" {
+		return "", false
+	}
+	prefix := strings.TrimSuffix(baseURL, "/") + "/"
+	if !strings.HasPrefix(u, prefix) {
+		return "", false
+	}
+	rel, err := url.PathUnescape(strings.TrimPrefix(u, prefix))
+	if err != nil || !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", false
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), true
+}
+
+// modelFiles returns the model files of dir, relative to it
+func modelFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() && slices.Contains(modelExtensions, strings.ToLower(filepath.Ext(path))) {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
@@ -0,0 +1,91 @@
+package gallery_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/mudler/LocalAI/core/gallery"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gallery authoring", func() {
+	const baseURL = "https://models.example.com/gallery"
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "gallery")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+
+		Expect(InitGallery(dir)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "llm"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "llm", "Phi-2.Q8_0.gguf"), []byte("phi"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644)).To(Succeed())
+	})
+
+	It("does not initialize a gallery twice", func() {
+		Expect(InitGallery(dir)).ToNot(Succeed())
+	})
+
+	It("adds the model files with their hashes and sizes", func() {
+		added, err := AddModels(dir, nil, AddModelOptions{BaseURL: baseURL, License: "mit"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(added).To(HaveLen(1))
+
+		models, err := ReadGalleryIndex(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(models).To(HaveLen(1))
+		m := models[0]
+		Expect(m.Name).To(Equal("phi-2.q8_0"))
+		Expect(m.License).To(Equal("mit"))
+		Expect(m.URL).To(Equal(baseURL + "/base.yaml"))
+		Expect(m.Overrides).To(HaveKeyWithValue("parameters", HaveKeyWithValue("model", "llm/Phi-2.Q8_0.gguf")))
+		Expect(m.AdditionalFiles).To(Equal([]File{{
+			Filename: "llm/Phi-2.Q8_0.gguf",
+			SHA256:   "7f754d3dbef2f9c8be86a085d505d9bf943e8a9d0c2d84c5015e92e97c53040d",
+			URI:      baseURL + "/llm/Phi-2.Q8_0.gguf",
+			Size:     3,
+		}}))
+
+		Expect(ValidateGallery(dir, baseURL, true)).To(Succeed())
+	})
+
+	It("updates the models already in the index", func() {
+		_, err := AddModels(dir, []string{"llm/Phi-2.Q8_0.gguf"}, AddModelOptions{BaseURL: baseURL, Name: "phi", Description: "Phi 2"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "llm", "Phi-2.Q8_0.gguf"), []byte("phi-2"), 0644)).To(Succeed())
+		_, err = AddModels(dir, []string{"llm/Phi-2.Q8_0.gguf"}, AddModelOptions{BaseURL: baseURL, Name: "phi"})
+		Expect(err).ToNot(HaveOccurred())
+
+		models, err := ReadGalleryIndex(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(models).To(HaveLen(1))
+		Expect(models[0].Description).To(Equal("Phi 2"))
+		Expect(models[0].AdditionalFiles[0].Size).To(Equal(int64(5)))
+	})
+
+	It("refuses the files outside of the gallery", func() {
+		_, err := AddModels(dir, []string{"../model.gguf"}, AddModelOptions{BaseURL: baseURL})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports the problems of the index", func() {
+		_, err := AddModels(dir, nil, AddModelOptions{BaseURL: baseURL})
+		Expect(err).ToNot(HaveOccurred())
+		models, err := ReadGalleryIndex(dir)
+		Expect(err).ToNot(HaveOccurred())
+		models = append(models, GalleryModel{Name: "PHI-2.Q8_0", URL: baseURL + "/base.yaml"}, GalleryModel{Name: "empty"})
+		Expect(WriteGalleryIndex(dir, models)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "llm", "Phi-2.Q8_0.gguf"), []byte("phi-3"), 0644)).To(Succeed())
+
+		err = ValidateGallery(dir, baseURL, false)
+		Expect(err).To(MatchError(ContainSubstring("defined more than once")))
+		Expect(err).To(MatchError(ContainSubstring("neither url nor config_file")))
+		Expect(err).To(MatchError(ContainSubstring("the size is 5, expected 3")))
+
+		// the files are not checked without the URL the gallery is served at
+		Expect(ValidateGallery(dir, "", false)).To(MatchError(Not(ContainSubstring("the size"))))
+	})
+})
@@ -0,0 +1,231 @@
+package gallery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/downloader"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// GalleryBackend is a prebuilt backend listed in a backend gallery. Its files are downloaded
+// in a directory of the backend, and Run is the gRPC server started to load the models
+type GalleryBackend struct {
+	Name        string `json:"name" yaml:"name"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Run is the executable of the backend, relative to its directory. It defaults to the name of the backend
+	Run   string `json:"run,omitempty" yaml:"run,omitempty"`
+	Files []File `json:"files" yaml:"files"`
+	// Gallery is a reference to the gallery which contains the backend
+	Gallery config.Gallery `json:"gallery,omitempty" yaml:"gallery,omitempty"`
+	// InstalledVersion is the version of the backend installed in the system, if any
+	InstalledVersion string `json:"installed_version,omitempty" yaml:"installed_version,omitempty"`
+}
+
+func (b GalleryBackend) ID() string {
+	return fmt.Sprintf("%s@%s", b.Gallery.Name, b.Name)
+}
+
+// InstalledBackend is a backend installed from a gallery, as recorded in its directory
+type InstalledBackend struct {
+	Name        string    `json:"name" yaml:"name"`
+	Version     string    `json:"version" yaml:"version"`
+	Gallery     string    `json:"gallery" yaml:"gallery"`
+	InstalledAt time.Time `json:"installed_at" yaml:"installed_at"`
+	// Run is the absolute path of the executable of the backend
+	Run string `json:"run" yaml:"-"`
+	// RelativeRun is the executable relative to the directory of the backend, as stored in its metadata
+	RelativeRun string `json:"-" yaml:"run"`
+}
+
+const backendMetadataFile = "backend.yaml"
+
+var backendName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// BackendsPath returns the directory of the backends installed at runtime in the backend assets directory
+func BackendsPath(assetDir string) string {
+	return filepath.Join(assetDir, "backends")
+}
+
+// AvailableGalleryBackends lists the backends of the backend galleries. Each gallery is a yaml
+// file with a list of backends, hosted on a remote server (for example github)
+func AvailableGalleryBackends(galleries []config.Gallery, assetDir string) ([]*GalleryBackend, error) {
+	installed, err := InstalledBackends(assetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backends []*GalleryBackend
+	for _, gallery := range galleries {
+		galleryBackends := []*GalleryBackend{}
+		uri := downloader.URI(gallery.URL)
+		err := uri.DownloadAndUnmarshal(assetDir, func(url string, d []byte) error {
+			return yaml.Unmarshal(d, &galleryBackends)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed reading backend gallery %s: %w", gallery.Name, err)
+		}
+		for _, b := range galleryBackends {
+			b.Gallery = gallery
+			for _, i := range installed {
+				if i.Name == b.Name {
+					b.InstalledVersion = i.Version
+				}
+			}
+		}
+		backends = append(backends, galleryBackends...)
+	}
+	return backends, nil
+}
+
+// FindBackend returns the backend with the given name, or <gallery>@<name>
+func FindBackend(backends []*GalleryBackend, name string) *GalleryBackend {
+	for _, b := range backends {
+		if strings.EqualFold(b.Name, name) || strings.EqualFold(b.ID(), name) {
+			return b
+		}
+	}
+	return nil
+}
+
+// InstallBackendFromGallery installs, or upgrades, the backend with the given name from the galleries
+func InstallBackendFromGallery(galleries []config.Gallery, name string, assetDir string, downloadStatus func(string, string, string, float64)) (*InstalledBackend, error) {
+	backends, err := AvailableGalleryBackends(galleries, assetDir)
+	if err != nil {
+		return nil, err
+	}
+	backend := FindBackend(backends, name)
+	if backend == nil {
+		return nil, fmt.Errorf("no backend found with name %q", name)
+	}
+	return InstallBackend(assetDir, backend, downloadStatus)
+}
+
+// InstallBackend downloads the files of the backend in its directory. The files are downloaded
+// in a staging directory first, which replaces the previous version of the backend once complete:
+// the models already running with the previous version are not affected until they are reloaded
+func InstallBackend(assetDir string, backend *GalleryBackend, downloadStatus func(string, string, string, float64)) (*InstalledBackend, error) {
+	if !backendName.MatchString(backend.Name) {
+		return nil, fmt.Errorf("invalid backend name %q", backend.Name)
+	}
+	if len(backend.Files) == 0 {
+		return nil, fmt.Errorf("backend %s has no files", backend.Name)
+	}
+
+	basePath := BackendsPath(assetDir)
+	if err := os.MkdirAll(basePath, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create backends path: %v", err)
+	}
+	staging, err := os.MkdirTemp(basePath, "."+backend.Name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	for i, file := range backend.Files {
+		if err := utils.VerifyPath(file.Filename, staging); err != nil {
+			return nil, err
+		}
+		uri := downloader.URI(file.URI)
+		if err := uri.DownloadFile(filepath.Join(staging, file.Filename), file.SHA256, i, len(backend.Files), downloadStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	run := backend.Run
+	if run == "" {
+		run = backend.Name
+	}
+	if err := utils.VerifyPath(run, staging); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(filepath.Join(staging, run), 0750); err != nil {
+		return nil, fmt.Errorf("backend %s has no executable %s: %w", backend.Name, run, err)
+	}
+
+	installed := InstalledBackend{
+		Name:        backend.Name,
+		Version:     backend.Version,
+		Gallery:     backend.Gallery.Name,
+		InstalledAt: time.Now(),
+		RelativeRun: run,
+	}
+	dat, err := yaml.Marshal(installed)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(staging, backendMetadataFile), dat, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backend metadata: %v", err)
+	}
+
+	// Swap the directories, so that the backend is never seen half installed
+	dir := filepath.Join(basePath, backend.Name)
+	previous := staging + ".previous"
+	if err := os.Rename(dir, previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to replace backend %s: %w", backend.Name, err)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return nil, fmt.Errorf("failed to install backend %s: %w", backend.Name, err)
+	}
+	if err := os.RemoveAll(previous); err != nil {
+		zlog.Warn().Err(err).Str("backend", backend.Name).Msg("failed removing the previous version of the backend")
+	}
+
+	installed.Run = filepath.Join(dir, run)
+	zlog.Info().Str("backend", backend.Name).Str("version", backend.Version).Msg("backend installed")
+	return &installed, nil
+}
+
+// InstalledBackends returns the backends installed from the galleries
+func InstalledBackends(assetDir string) ([]InstalledBackend, error) {
+	basePath := BackendsPath(assetDir)
+	entries, err := os.ReadDir(basePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backends []InstalledBackend
+	for _, e := range entries {
+		// The staging directories are hidden
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		dat, err := os.ReadFile(filepath.Join(basePath, e.Name(), backendMetadataFile))
+		if err != nil {
+			zlog.Warn().Err(err).Str("backend", e.Name()).Msg("skipping backend without metadata")
+			continue
+		}
+		var b InstalledBackend
+		if err := yaml.Unmarshal(dat, &b); err != nil {
+			zlog.Warn().Err(err).Str("backend", e.Name()).Msg("skipping backend with invalid metadata")
+			continue
+		}
+		b.Name = e.Name()
+		b.Run = filepath.Join(basePath, e.Name(), b.RelativeRun)
+		backends = append(backends, b)
+	}
+	return backends, nil
+}
+
+// DeleteBackendFromSystem removes an installed backend
+func DeleteBackendFromSystem(assetDir, name string) error {
+	if !backendName.MatchString(name) {
+		return fmt.Errorf("invalid backend name %q", name)
+	}
+	dir := filepath.Join(BackendsPath(assetDir), name)
+	if _, err := os.Stat(filepath.Join(dir, backendMetadataFile)); err != nil {
+		return fmt.Errorf("backend %s is not installed", name)
+	}
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,90 @@
+package gallery_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	. "github.com/mudler/LocalAI/core/gallery"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backend gallery", func() {
+	It("installs, upgrades and deletes backends", func() {
+		version := "v1"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/index.yaml":
+				fmt.Fprintf(w, `- name: fake-backend
+  version: %s
+  files:
+    - filename: fake-backend
+      uri: %s/fake-backend
+    - filename: lib/libfake.so
+      uri: %s/libfake.so
+`, version, "http://"+r.Host, "http://"+r.Host)
+			case "/fake-backend":
+				fmt.Fprintf(w, "#!/bin/sh\necho %s\n", version)
+			case "/libfake.so":
+				fmt.Fprint(w, "lib")
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+
+		assetDir := GinkgoT().TempDir()
+		galleries := []config.Gallery{{Name: "test", URL: server.URL + "/index.yaml"}}
+
+		backends, err := AvailableGalleryBackends(galleries, assetDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(backends).To(HaveLen(1))
+		Expect(backends[0].ID()).To(Equal("test@fake-backend"))
+		Expect(backends[0].InstalledVersion).To(BeEmpty())
+
+		installed, err := InstallBackendFromGallery(galleries, "test@fake-backend", assetDir, func(string, string, string, float64) {})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(installed.Run).To(Equal(filepath.Join(BackendsPath(assetDir), "fake-backend", "fake-backend")))
+		info, err := os.Stat(installed.Run)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm() & 0100).ToNot(BeZero())
+		_, err = os.Stat(filepath.Join(BackendsPath(assetDir), "fake-backend", "lib", "libfake.so"))
+		Expect(err).ToNot(HaveOccurred())
+
+		version = "v2"
+		backends, err = AvailableGalleryBackends(galleries, assetDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(backends[0].InstalledVersion).To(Equal("v1"))
+
+		_, err = InstallBackendFromGallery(galleries, "fake-backend", assetDir, func(string, string, string, float64) {})
+		Expect(err).ToNot(HaveOccurred())
+		dat, err := os.ReadFile(installed.Run)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(dat)).To(ContainSubstring("v2"))
+
+		list, err := InstalledBackends(assetDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Version).To(Equal("v2"))
+		Expect(list[0].Gallery).To(Equal("test"))
+		Expect(list[0].Run).To(Equal(installed.Run))
+
+		Expect(DeleteBackendFromSystem(assetDir, "fake-backend")).To(Succeed())
+		list, err = InstalledBackends(assetDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list).To(BeEmpty())
+		Expect(DeleteBackendFromSystem(assetDir, "fake-backend")).ToNot(Succeed())
+		Expect(DeleteBackendFromSystem(assetDir, "../models")).ToNot(Succeed())
+		Expect(DeleteBackendFromSystem(assetDir, "..")).ToNot(Succeed())
+		Expect(DeleteBackendFromSystem(assetDir, ".hidden")).ToNot(Succeed())
+
+		for _, name := range []string{".", "..", ".hidden"} {
+			_, err = InstallBackend(assetDir, &GalleryBackend{Name: name, Files: []File{{Filename: "run"}}}, func(string, string, string, float64) {})
+			Expect(err).To(MatchError(fmt.Sprintf("invalid backend name %q", name)))
+		}
+	})
+})
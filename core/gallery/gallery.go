@@ -10,11 +10,13 @@ import (
 	"dario.cat/mergo"
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/pkg/downloader"
+	"github.com/mudler/LocalAI/pkg/logging"
 	"github.com/mudler/LocalAI/pkg/utils"
-	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
 )
 
+var zlog = logging.Logger(logging.Gallery)
+
 // Installs a model from the gallery
 func InstallModelFromGallery(galleries []config.Gallery, name string, basePath string, req GalleryModel, downloadStatus func(string, string, string, float64), enforceScan bool) error {
 
@@ -56,6 +58,7 @@ func InstallModelFromGallery(galleries []config.Gallery, name string, basePath s
 		// Copy the model configuration from the request schema
 		config.URLs = append(config.URLs, model.URLs...)
 		config.Icon = model.Icon
+		config.Gallery = model.Gallery.Name
 		config.Files = append(config.Files, req.AdditionalFiles...)
 		config.Files = append(config.Files, model.AdditionalFiles...)
 
@@ -81,6 +84,10 @@ func InstallModelFromGallery(galleries []config.Gallery, name string, basePath s
 		return fmt.Errorf("no model found with name %q", name)
 	}
 
+	if err := acknowledgeLicense(model, req.AcceptLicense); err != nil {
+		return err
+	}
+
 	return applyModel(model)
 }
 
@@ -161,7 +168,7 @@ func getGalleryModels(gallery config.Gallery, basePath string) ([]*GalleryModel,
 	})
 	if err != nil {
 		if yamlErr, ok := err.(*yaml.TypeError); ok {
-			log.Debug().Msgf("YAML errors: %s\n\nwreckage of models: %+v", strings.Join(yamlErr.Errors, "\n"), models)
+			zlog.Debug().Msgf("YAML errors: %s\n\nwreckage of models: %+v", strings.Join(yamlErr.Errors, "\n"), models)
 		}
 		return models, err
 	}
@@ -203,7 +210,7 @@ func DeleteModelFromSystem(basePath string, name string, additionalFiles []strin
 	// read the model config
 	galleryconfig, err := ReadConfigFile(galleryFile)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to read gallery file %s", configFile)
+		zlog.Error().Err(err).Msgf("failed to read gallery file %s", configFile)
 	}
 
 	var filesToRemove []string
@@ -256,7 +263,7 @@ func SafetyScanGalleryModel(galleryModel *GalleryModel) error {
 	for _, file := range galleryModel.AdditionalFiles {
 		scanResults, err := downloader.HuggingFaceScan(downloader.URI(file.URI))
 		if err != nil && errors.Is(err, downloader.ErrUnsafeFilesFound) {
-			log.Error().Str("model", galleryModel.Name).Strs("clamAV", scanResults.ClamAVInfectedFiles).Strs("pickles", scanResults.DangerousPickles).Msg("Contains unsafe file(s)!")
+			zlog.Error().Str("model", galleryModel.Name).Strs("clamAV", scanResults.ClamAVInfectedFiles).Strs("pickles", scanResults.DangerousPickles).Msg("Contains unsafe file(s)!")
 			return err
 		}
 	}
@@ -0,0 +1,119 @@
+package gallery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LicenseAcknowledgementsFile is the file of the configs directory where the acknowledgements of the gated licenses
+// are recorded, one JSON object per line
+const LicenseAcknowledgementsFile = "license_acknowledgements.jsonl"
+
+// ErrLicenseNotAcknowledged is returned when installing a model with a gated license which was not acknowledged
+var ErrLicenseNotAcknowledged = errors.New("the license of the model must be acknowledged")
+
+// licenseGate holds the licenses whose models are only installed once acknowledged
+var licenseGate struct {
+	sync.RWMutex
+	gated    []string
+	accepted []string
+	auditDir string
+}
+
+// LicenseAcknowledgement is the record of the installation of a model with a gated license
+type LicenseAcknowledgement struct {
+	Time    time.Time `json:"time"`
+	Model   string    `json:"model"`
+	License string    `json:"license"`
+	// By is "request" when the request acknowledged the license, or "config" when it was accepted by the configuration
+	By string `json:"by"`
+}
+
+// SetLicenseGate requires an acknowledgement before installing the models with one of the gated licenses, or marked
+// as gated by their gallery. The accepted licenses are acknowledged for all the installations. The acknowledgements
+// are recorded in auditDir
+func SetLicenseGate(gated, accepted []string, auditDir string) {
+	licenseGate.Lock()
+	defer licenseGate.Unlock()
+	licenseGate.gated = normalizeLicenses(gated)
+	licenseGate.accepted = normalizeLicenses(accepted)
+	licenseGate.auditDir = auditDir
+}
+
+// LicenseGated returns whether the model is only installed once its license is acknowledged
+func LicenseGated(m *GalleryModel) bool {
+	licenseGate.RLock()
+	defer licenseGate.RUnlock()
+	return m.Gated || slices.Contains(licenseGate.gated, normalizeLicense(m.License))
+}
+
+// acknowledgeLicense checks that the license of a gated model is acknowledged, by the request or by the
+// configuration, and records the acknowledgement
+func acknowledgeLicense(m *GalleryModel, acknowledged bool) error {
+	if !LicenseGated(m) {
+		return nil
+	}
+
+	licenseGate.RLock()
+	accepted, auditDir := slices.Contains(licenseGate.accepted, normalizeLicense(m.License)), licenseGate.auditDir
+	licenseGate.RUnlock()
+
+	a := LicenseAcknowledgement{Time: time.Now().UTC(), Model: m.ID(), License: m.License}
+	switch {
+	case acknowledged:
+		a.By = "request"
+	case accepted:
+		a.By = "config"
+	default:
+		return fmt.Errorf("%w: %s has the license %q, install it with accept_license or add the license to the accepted licenses", ErrLicenseNotAcknowledged, m.ID(), m.License)
+	}
+
+	zlog.Info().Str("model", a.Model).Str("license", a.License).Str("by", a.By).Msg("license acknowledged")
+	if auditDir == "" {
+		return nil
+	}
+	return recordAcknowledgement(auditDir, a)
+}
+
+func recordAcknowledgement(dir string, a LicenseAcknowledgement) error {
+	dat, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	licenseGate.Lock()
+	defer licenseGate.Unlock()
+	f, err := os.OpenFile(filepath.Join(dir, LicenseAcknowledgementsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(dat, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func normalizeLicenses(licenses []string) []string {
+	var normalized []string
+	for _, l := range licenses {
+		if l = normalizeLicense(l); l != "" {
+			normalized = append(normalized, l)
+		}
+	}
+	return normalized
+}
+
+func normalizeLicense(l string) string {
+	return strings.ToLower(strings.TrimSpace(l))
+}
@@ -0,0 +1,90 @@
+package gallery_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/config"
+	. "github.com/mudler/LocalAI/core/gallery"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("License gate", func() {
+	var modelsPath, auditDir string
+	var galleries []config.Gallery
+
+	noProgress := func(string, string, string, float64) {}
+
+	acknowledgements := func() []LicenseAcknowledgement {
+		dat, err := os.ReadFile(filepath.Join(auditDir, LicenseAcknowledgementsFile))
+		Expect(err).ToNot(HaveOccurred())
+		var records []LicenseAcknowledgement
+		for _, line := range strings.Split(strings.TrimSpace(string(dat)), "\n") {
+			var a LicenseAcknowledgement
+			Expect(json.Unmarshal([]byte(line), &a)).To(Succeed())
+			records = append(records, a)
+		}
+		return records
+	}
+
+	BeforeEach(func() {
+		var err error
+		modelsPath, err = os.MkdirTemp("", "models")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, modelsPath)
+		auditDir = filepath.Join(modelsPath, "configs")
+
+		index := `
+- name: llama
+  license: llama3
+  config_file:
+    backend: llama-cpp
+- name: phi
+  license: mit
+  config_file:
+    backend: llama-cpp
+- name: internal
+  license: proprietary
+  gated: true
+  config_file:
+    backend: llama-cpp
+`
+		Expect(os.WriteFile(filepath.Join(modelsPath, "index.yaml"), []byte(index), 0644)).To(Succeed())
+		galleries = []config.Gallery{{Name: "test", URL: "file://" + filepath.Join(modelsPath, "index.yaml")}}
+
+		SetLicenseGate([]string{"Llama3"}, nil, auditDir)
+		DeferCleanup(SetLicenseGate, []string(nil), []string(nil), "")
+	})
+
+	It("requires the acknowledgement of the gated licenses", func() {
+		err := InstallModelFromGallery(galleries, "llama", modelsPath, GalleryModel{}, noProgress, false)
+		Expect(err).To(MatchError(ErrLicenseNotAcknowledged))
+		err = InstallModelFromGallery(galleries, "internal", modelsPath, GalleryModel{}, noProgress, false)
+		Expect(err).To(MatchError(ErrLicenseNotAcknowledged))
+		Expect(filepath.Join(modelsPath, "llama.yaml")).ToNot(BeAnExistingFile())
+
+		Expect(InstallModelFromGallery(galleries, "phi", modelsPath, GalleryModel{}, noProgress, false)).To(Succeed())
+		Expect(filepath.Join(auditDir, LicenseAcknowledgementsFile)).ToNot(BeAnExistingFile())
+	})
+
+	It("records the acknowledgements of the requests", func() {
+		Expect(InstallModelFromGallery(galleries, "llama", modelsPath, GalleryModel{AcceptLicense: true}, noProgress, false)).To(Succeed())
+		Expect(filepath.Join(modelsPath, "llama.yaml")).To(BeAnExistingFile())
+
+		records := acknowledgements()
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Model).To(Equal("test@llama"))
+		Expect(records[0].License).To(Equal("llama3"))
+		Expect(records[0].By).To(Equal("request"))
+	})
+
+	It("installs the models with an accepted license", func() {
+		SetLicenseGate([]string{"llama3"}, []string{"LLAMA3"}, auditDir)
+
+		Expect(InstallModelFromGallery(galleries, "llama", modelsPath, GalleryModel{}, noProgress, false)).To(Succeed())
+		Expect(acknowledgements()).To(ConsistOf(HaveField("By", "config")))
+	})
+})
@@ -11,7 +11,6 @@ import (
 	"github.com/mudler/LocalAI/pkg/downloader"
 	"github.com/mudler/LocalAI/pkg/utils"
 
-	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
 )
 
@@ -53,12 +52,16 @@ type Config struct {
 	ConfigFile      string           `yaml:"config_file"`
 	Files           []File           `yaml:"files"`
 	PromptTemplates []PromptTemplate `yaml:"prompt_templates"`
+	// Gallery is the name of the gallery the model was installed from
+	Gallery string `yaml:"gallery,omitempty"`
 }
 
 type File struct {
 	Filename string `yaml:"filename" json:"filename"`
 	SHA256   string `yaml:"sha256" json:"sha256"`
 	URI      string `yaml:"uri" json:"uri"`
+	// Size is in bytes, set by the galleries authored with local-ai gallery
+	Size int64 `yaml:"size,omitempty" json:"size,omitempty"`
 }
 
 type PromptTemplate struct {
@@ -73,7 +76,7 @@ func GetGalleryConfigFromURL(url string, basePath string) (Config, error) {
 		return yaml.Unmarshal(d, &config)
 	})
 	if err != nil {
-		log.Error().Err(err).Str("url", url).Msg("failed to get gallery config for url")
+		zlog.Error().Err(err).Str("url", url).Msg("failed to get gallery config for url")
 		return config, err
 	}
 	return config, nil
@@ -104,12 +107,12 @@ func InstallModel(basePath, nameOverride string, config *Config, configOverrides
 	}
 
 	if len(configOverrides) > 0 {
-		log.Debug().Msgf("Config overrides %+v", configOverrides)
+		zlog.Debug().Msgf("Config overrides %+v", configOverrides)
 	}
 
 	// Download files and verify their SHA
 	for i, file := range config.Files {
-		log.Debug().Msgf("Checking %q exists and matches SHA", file.Filename)
+		zlog.Debug().Msgf("Checking %q exists and matches SHA", file.Filename)
 
 		if err := utils.VerifyPath(file.Filename, basePath); err != nil {
 			return err
@@ -121,7 +124,7 @@ func InstallModel(basePath, nameOverride string, config *Config, configOverrides
 		if enforceScan {
 			scanResults, err := downloader.HuggingFaceScan(downloader.URI(file.URI))
 			if err != nil && errors.Is(err, downloader.ErrUnsafeFilesFound) {
-				log.Error().Str("model", config.Name).Strs("clamAV", scanResults.ClamAVInfectedFiles).Strs("pickles", scanResults.DangerousPickles).Msg("Contains unsafe file(s)!")
+				zlog.Error().Str("model", config.Name).Strs("clamAV", scanResults.ClamAVInfectedFiles).Strs("pickles", scanResults.DangerousPickles).Msg("Contains unsafe file(s)!")
 				return err
 			}
 		}
@@ -150,7 +153,7 @@ func InstallModel(basePath, nameOverride string, config *Config, configOverrides
 			return fmt.Errorf("failed to write prompt template %q: %v", template.Name, err)
 		}
 
-		log.Debug().Msgf("Prompt template %q written", template.Name)
+		zlog.Debug().Msgf("Prompt template %q written", template.Name)
 	}
 
 	name := config.Name
@@ -199,7 +202,7 @@ func InstallModel(basePath, nameOverride string, config *Config, configOverrides
 			return fmt.Errorf("failed to write updated config file: %v", err)
 		}
 
-		log.Debug().Msgf("Written config file %s", configFilePath)
+		zlog.Debug().Msgf("Written config file %s", configFilePath)
 	}
 
 	// Save the model gallery file for further reference
@@ -209,7 +212,7 @@ func InstallModel(basePath, nameOverride string, config *Config, configOverrides
 		return err
 	}
 
-	log.Debug().Msgf("Written gallery file %s", modelFile)
+	zlog.Debug().Msgf("Written gallery file %s", modelFile)
 
 	return os.WriteFile(modelFile, data, 0600)
 
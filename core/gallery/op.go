@@ -7,6 +7,12 @@ type GalleryOp struct {
 	GalleryModelName string
 	ConfigURL        string
 	Delete           bool
+	// ConfigArchive is an archive of the configuration of another instance to import
+	ConfigArchive []byte
+	// GalleryBackendName is the backend to install from the backend galleries, or to delete
+	GalleryBackendName string
+	// Owner is the API key, or the tenant, which requested the operation: it is only listed in its jobs
+	Owner string
 
 	Req       GalleryModel
 	Galleries []config.Gallery
@@ -22,4 +28,5 @@ type GalleryOpStatus struct {
 	TotalFileSize      string  `json:"file_size"`
 	DownloadedFileSize string  `json:"downloaded_size"`
 	GalleryModelName   string  `json:"gallery_model_name"`
+	GalleryBackendName string  `json:"gallery_backend_name,omitempty"`
 }
@@ -0,0 +1,21 @@
+package gallery
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// quantizationRegexp matches the quantization types in the file names of the models,
+// e.g. Q4_K_M, IQ3_XS, Q8_0, F16 or BF16
+var quantizationRegexp = regexp.MustCompile(`(?i)(?:^|[._-])((?:I?Q[1-8](?:_[0-9A-Z]+)*)|BF16|F16|F32)(?:$|[._-])`)
+
+// Quantization returns the quantization of a model file from its name, or an empty string if it is not known
+func Quantization(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	m := quantizationRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1])
+}
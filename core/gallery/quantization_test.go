@@ -0,0 +1,24 @@
+package gallery_test
+
+import (
+	. "github.com/mudler/LocalAI/core/gallery"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Quantization", func() {
+	It("returns the quantization of the model files", func() {
+		Expect(Quantization("mixtral-8x7b-v0.1.Q2_K.gguf")).To(Equal("Q2_K"))
+		Expect(Quantization("Meta-Llama-3.1-8B-Instruct-Q4_K_M.gguf")).To(Equal("Q4_K_M"))
+		Expect(Quantization("phi-2.q8_0.gguf")).To(Equal("Q8_0"))
+		Expect(Quantization("gemma-2-9b-it-IQ3_XS.gguf")).To(Equal("IQ3_XS"))
+		Expect(Quantization("qwen2-0_5b-instruct-bf16.gguf")).To(Equal("BF16"))
+		Expect(Quantization("models/ggml-whisper-base.f16.bin")).To(Equal("F16"))
+	})
+
+	It("returns an empty string when the quantization is not known", func() {
+		Expect(Quantization("ggml-whisper-base.bin")).To(BeEmpty())
+		Expect(Quantization("en-us-amy-low.onnx")).To(BeEmpty())
+		Expect(Quantization("Qwen2-VL-7B.gguf")).To(BeEmpty())
+	})
+})
@@ -18,6 +18,10 @@ type GalleryModel struct {
 	URLs        []string `json:"urls,omitempty" yaml:"urls,omitempty"`
 	Icon        string   `json:"icon,omitempty" yaml:"icon,omitempty"`
 	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Gated requires the license to be acknowledged before installing the model, as the gated licenses
+	Gated bool `json:"gated,omitempty" yaml:"gated,omitempty"`
+	// AcceptLicense acknowledges the license of the model in the installation requests
+	AcceptLicense bool `json:"accept_license,omitempty" yaml:"accept_license,omitempty"`
 	// config_file is read in the situation where URL is blank - and therefore this is a base config.
 	ConfigFile map[string]interface{} `json:"config_file,omitempty" yaml:"config_file,omitempty"`
 	// Overrides are used to override the configuration of the model located at URL
@@ -0,0 +1,445 @@
+// Package gateway routes the API requests to a set of LocalAI instances by the model requested and the
+// load of the instances, for the gateways holding no model themselves.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/registry"
+	"github.com/rs/zerolog/log"
+)
+
+// Worker is an instance the requests are routed to
+type Worker struct {
+	URL     string   `json:"url"`
+	Models  []string `json:"models"`
+	Healthy bool     `json:"healthy"`
+	// InFlight are the requests being proxied to the worker
+	InFlight int    `json:"in_flight"`
+	Error    string `json:"error,omitempty"`
+
+	proxy *httputil.ReverseProxy
+}
+
+// Gateway proxies the requests to the healthy worker serving their model with the fewest requests in
+// flight. The workers are the static ones and the instances of the registry, and their models are
+// refreshed from their /v1/models endpoint
+type Gateway struct {
+	sync.Mutex
+	workers map[string]*Worker
+	static  []string
+	reg     registry.Registry
+	service string
+	// workerAPIKey lists the models of the workers on refresh, apiKeys authenticate the state of the workers
+	workerAPIKey string
+	apiKeys      []string
+	maxBody      int64
+	// next rotates the workers of the same load
+	next int
+
+	// the connections to the workers are kept alive, and shared by the proxies
+	transport *http.Transport
+	client    *http.Client
+}
+
+// NewGateway returns the gateway of the workers URLs, as http://node1:8080, and of the instances of
+// service in reg, if not nil. workerAPIKey authenticates the listing of the models of the workers to route
+// the requests, the requests being proxied with their own API key. apiKeys are the API keys reading the
+// state of the workers, which is not served without them. The bodies larger than maxBody bytes are rejected
+func NewGateway(workers []string, reg registry.Registry, service, workerAPIKey string, apiKeys []string, maxBody int64) *Gateway {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 100
+	return &Gateway{
+		workers:      map[string]*Worker{},
+		static:       workers,
+		reg:          reg,
+		service:      service,
+		workerAPIKey: workerAPIKey,
+		apiKeys:      apiKeys,
+		maxBody:      maxBody,
+		transport:    transport,
+		client:       &http.Client{Transport: transport, Timeout: 10 * time.Second},
+	}
+}
+
+// Start refreshes the workers every interval, until ctx is done
+func (g *Gateway) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := g.Refresh(ctx); err != nil {
+			log.Error().Err(err).Msg("error refreshing the workers of the gateway")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh lists the workers, and the models they serve. The workers failing to list their models are
+// marked unhealthy until the next refresh
+func (g *Gateway) Refresh(ctx context.Context) error {
+	urls := slices.Clone(g.static)
+	var regErr error
+	if g.reg != nil {
+		services, err := g.reg.Services(ctx, g.service)
+		if err != nil {
+			// the workers of the registry are kept as they are until it is reachable again
+			regErr = fmt.Errorf("listing the workers of the registry: %w", err)
+			g.Lock()
+			for u := range g.workers {
+				if !slices.Contains(urls, u) {
+					urls = append(urls, u)
+				}
+			}
+			g.Unlock()
+		}
+		for _, s := range services {
+			urls = append(urls, "http://"+net.JoinHostPort(s.Address, strconv.Itoa(s.Port)))
+		}
+	}
+
+	type status struct {
+		models []string
+		err    error
+	}
+	statuses := make([]status, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i].models, statuses[i].err = g.listModels(ctx, u, bearer(g.workerAPIKey))
+		}()
+	}
+	wg.Wait()
+
+	g.Lock()
+	defer g.Unlock()
+	workers := map[string]*Worker{}
+	for i, u := range urls {
+		w, exists := g.workers[u]
+		if !exists {
+			target, err := url.Parse(u)
+			if err != nil {
+				log.Error().Err(err).Str("worker", u).Msg("invalid worker URL")
+				continue
+			}
+			w = &Worker{URL: u}
+			w.proxy = g.newProxy(w, target)
+		}
+		w.Healthy, w.Error, w.Models = statuses[i].err == nil, "", statuses[i].models
+		if statuses[i].err != nil {
+			w.Error = statuses[i].err.Error()
+		}
+		workers[u] = w
+	}
+	g.workers = workers
+	return regErr
+}
+
+// statusError is the status of a worker failing to list its models
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("listing the models returned status %d", int(e))
+}
+
+// bearer returns the Authorization header of the API key, if any
+func bearer(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	return "Bearer " + apiKey
+}
+
+// listModels lists the models of the worker at u, with the Authorization header auth
+func (g *Gateway) listModels(ctx context.Context, u, auth string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(u, "/")+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+	list := schema.ModelsDataResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	models := []string{}
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+func (g *Gateway) newProxy(w *Worker, target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = g.transport
+	// the streamed responses are sent as they come
+	proxy.FlushInterval = -1
+	proxy.ErrorHandler = func(rw http.ResponseWriter, r *http.Request, err error) {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		log.Error().Err(err).Str("worker", w.URL).Msg("error proxying the request")
+		// the worker is not picked again until it lists its models
+		g.Lock()
+		w.Healthy, w.Error = false, err.Error()
+		g.Unlock()
+		writeError(rw, http.StatusBadGateway, fmt.Sprintf("worker %s failed: %s", w.URL, err))
+	}
+	return proxy
+}
+
+// Workers returns the state of the workers
+func (g *Gateway) Workers() []Worker {
+	g.Lock()
+	defer g.Unlock()
+	workers := []Worker{}
+	for _, w := range g.workers {
+		workers = append(workers, Worker{URL: w.URL, Models: w.Models, Healthy: w.Healthy, InFlight: w.InFlight, Error: w.Error})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].URL < workers[j].URL })
+	return workers
+}
+
+// Models returns the models served by the healthy workers
+func (g *Gateway) Models() []string {
+	models := []string{}
+	for _, w := range g.Workers() {
+		if !w.Healthy {
+			continue
+		}
+		for _, m := range w.Models {
+			if !slices.Contains(models, m) {
+				models = append(models, m)
+			}
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// pick returns the healthy worker serving model, any worker if model is empty, with the fewest requests
+// in flight, and counts the request. It returns nil if no worker serves the model
+func (g *Gateway) pick(model string) *Worker {
+	g.Lock()
+	defer g.Unlock()
+	urls := []string{}
+	for u, w := range g.workers {
+		if w.Healthy && (model == "" || slices.Contains(w.Models, model)) {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	sort.Strings(urls)
+	g.next++
+	var picked *Worker
+	for i := range urls {
+		w := g.workers[urls[(g.next+i)%len(urls)]]
+		if picked == nil || w.InFlight < picked.InFlight {
+			picked = w
+		}
+	}
+	picked.InFlight++
+	return picked
+}
+
+func (g *Gateway) release(w *Worker) {
+	g.Lock()
+	defer g.Unlock()
+	w.InFlight--
+}
+
+func (g *Gateway) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz":
+		rw.WriteHeader(http.StatusOK)
+		return
+	case "/readyz":
+		if !slices.ContainsFunc(g.Workers(), func(w Worker) bool { return w.Healthy }) {
+			writeError(rw, http.StatusServiceUnavailable, "no healthy worker")
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+		return
+	case "/v1/models", "/models":
+		if r.Method == http.MethodGet {
+			g.serveModels(rw, r)
+			return
+		}
+	case "/gateway/workers":
+		if !g.authorized(r) {
+			writeError(rw, http.StatusUnauthorized, "the state of the workers needs an API key of the gateway")
+			return
+		}
+		writeJSON(rw, http.StatusOK, g.Workers())
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, g.maxBody))
+	if err != nil {
+		writeError(rw, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	model := requestModel(r, body)
+	w := g.pick(model)
+	if w == nil {
+		if model == "" {
+			writeError(rw, http.StatusServiceUnavailable, "no healthy worker")
+		} else {
+			writeError(rw, http.StatusNotFound, fmt.Sprintf("model %s not found", model))
+		}
+		return
+	}
+	defer g.release(w)
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	w.proxy.ServeHTTP(rw, r)
+}
+
+// serveModels lists the models of the healthy workers with the API key of the request, so that the
+// authentication of the workers, and the models allowed to the tenants, apply as on the workers
+func (g *Gateway) serveModels(rw http.ResponseWriter, r *http.Request) {
+	workers := slices.DeleteFunc(g.Workers(), func(w Worker) bool { return !w.Healthy })
+	auth := requestAuth(r)
+	type status struct {
+		models []string
+		err    error
+	}
+	statuses := make([]status, len(workers))
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i].models, statuses[i].err = g.listModels(r.Context(), w.URL, auth)
+		}()
+	}
+	wg.Wait()
+
+	models := []string{}
+	listed := false
+	var refused statusError
+	for i, s := range statuses {
+		var code statusError
+		if errors.As(s.err, &code) && (code == http.StatusUnauthorized || code == http.StatusForbidden) {
+			refused = code
+			continue
+		}
+		if s.err != nil {
+			log.Debug().Err(s.err).Str("worker", workers[i].URL).Msg("error listing the models of the worker")
+			continue
+		}
+		listed = true
+		for _, m := range s.models {
+			if !slices.Contains(models, m) {
+				models = append(models, m)
+			}
+		}
+	}
+	// the API keys refused by all the workers are refused by the gateway
+	if !listed && refused != 0 {
+		writeError(rw, int(refused), "the API key was refused by the workers")
+		return
+	}
+
+	sort.Strings(models)
+	list := schema.ModelsDataResponse{Object: "list", Data: []schema.OpenAIModel{}}
+	for _, m := range models {
+		list.Data = append(list.Data, schema.OpenAIModel{ID: m, Object: "model"})
+	}
+	writeJSON(rw, http.StatusOK, list)
+}
+
+// requestAuth returns the Authorization header of the request, with the API key of the xi-api-key and
+// x-api-key headers as LocalAI reads them
+func requestAuth(r *http.Request) string {
+	for _, header := range []string{"x-api-key", "xi-api-key"} {
+		if apiKey := r.Header.Get(header); apiKey != "" {
+			return bearer(apiKey)
+		}
+	}
+	return r.Header.Get("Authorization")
+}
+
+// authorized tells if the request has one of the API keys of the gateway, none being authorized without them
+func (g *Gateway) authorized(r *http.Request) bool {
+	apiKey, found := strings.CutPrefix(requestAuth(r), "Bearer ")
+	if !found || apiKey == "" {
+		return false
+	}
+	for _, k := range g.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(k)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// requestModel returns the model of the request: the model field of its JSON or multipart body, or the
+// model query parameter
+func requestModel(r *http.Request, body []byte) string {
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+		if err == nil {
+			defer form.RemoveAll()
+			if values := form.Value["model"]; len(values) > 0 {
+				return values[0]
+			}
+		}
+	case len(body) > 0:
+		var input struct {
+			Model string `json:"model"`
+		}
+		if json.Unmarshal(body, &input) == nil && input.Model != "" {
+			return input.Model
+		}
+	}
+	return r.URL.Query().Get("model")
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, code int, message string) {
+	writeJSON(rw, code, schema.ErrorResponse{Error: &schema.APIError{Message: message, Code: code}})
+}
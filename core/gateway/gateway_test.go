@@ -0,0 +1,216 @@
+package gateway_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/mudler/LocalAI/core/gateway"
+	"github.com/mudler/LocalAI/core/schema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// worker is a fake LocalAI instance serving models, answering the requests with its name. The requests
+// are held until release is closed
+type worker struct {
+	*httptest.Server
+	sync.Mutex
+	requests int
+	release  chan struct{}
+}
+
+func newWorker(name string, models ...string) *worker {
+	w := &worker{}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/models" {
+			list := schema.ModelsDataResponse{Object: "list"}
+			for _, m := range models {
+				list.Data = append(list.Data, schema.OpenAIModel{ID: m, Object: "model"})
+			}
+			json.NewEncoder(rw).Encode(list)
+			return
+		}
+		w.Lock()
+		w.requests++
+		release := w.release
+		w.Unlock()
+		if release != nil {
+			<-release
+		}
+		io.Copy(io.Discard, r.Body)
+		rw.Write([]byte(name))
+	}))
+	return w
+}
+
+func post(g http.Handler, path, contentType string, body []byte) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+func chat(g http.Handler, model string) *httptest.ResponseRecorder {
+	return post(g, "/v1/chat/completions", "application/json", []byte(`{"model": "`+model+`", "messages": []}`))
+}
+
+var _ = Describe("Gateway", func() {
+	var a, b *worker
+	var g *Gateway
+
+	BeforeEach(func() {
+		a = newWorker("a", "llama", "whisper-1")
+		b = newWorker("b", "llama", "flux")
+		g = NewGateway([]string{a.URL, b.URL}, nil, "localai", "", nil, 1<<20)
+		Expect(g.Refresh(context.Background())).To(Succeed())
+	})
+
+	AfterEach(func() {
+		a.Close()
+		b.Close()
+	})
+
+	It("lists the models of the workers", func() {
+		Expect(g.Models()).To(Equal([]string{"flux", "llama", "whisper-1"}))
+
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+		list := schema.ModelsDataResponse{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &list)).To(Succeed())
+		Expect(list.Data).To(HaveLen(3))
+	})
+
+	It("routes the requests to the workers serving their model", func() {
+		Expect(chat(g, "flux").Body.String()).To(Equal("b"))
+		Expect(chat(g, "whisper-1").Body.String()).To(Equal("a"))
+
+		rec := chat(g, "missing")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring("model missing not found"))
+	})
+
+	It("reads the model of the multipart requests", func() {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("model", "whisper-1")
+		fw, _ := mw.CreateFormFile("file", "audio.wav")
+		fw.Write([]byte("RIFF"))
+		mw.Close()
+		Expect(post(g, "/v1/audio/transcriptions", mw.FormDataContentType(), body.Bytes()).Body.String()).To(Equal("a"))
+	})
+
+	It("routes the requests to the worker with the fewest requests in flight", func() {
+		a.release = make(chan struct{})
+		done := make(chan string)
+		// a is busy with a request of the model only it serves
+		go func() { done <- chat(g, "whisper-1").Body.String() }()
+		Eventually(func() int { a.Lock(); defer a.Unlock(); return a.requests }).Should(Equal(1))
+
+		Expect(chat(g, "llama").Body.String()).To(Equal("b"))
+		Expect(chat(g, "llama").Body.String()).To(Equal("b"))
+
+		close(a.release)
+		Eventually(done).Should(Receive(Equal("a")))
+		Expect(g.Workers()[0].InFlight + g.Workers()[1].InFlight).To(Equal(0))
+	})
+
+	It("skips the workers down", func() {
+		b.Close()
+		Expect(g.Refresh(context.Background())).To(Succeed())
+		workers := g.Workers()
+		Expect(workers).To(HaveLen(2))
+		Expect(workers[0].Healthy != workers[1].Healthy).To(BeTrue())
+
+		Expect(g.Models()).To(Equal([]string{"llama", "whisper-1"}))
+		Expect(chat(g, "llama").Body.String()).To(Equal("a"))
+		Expect(chat(g, "flux").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("rejects the bodies too large", func() {
+		rec := chat(g, strings.Repeat("x", 2<<20))
+		Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+	})
+})
+
+// securedWorker is a fake LocalAI instance listing the models allowed to the API keys
+func securedWorker(models map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		allowed, exists := models[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		if !exists {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		list := schema.ModelsDataResponse{Object: "list"}
+		for _, m := range allowed {
+			list.Data = append(list.Data, schema.OpenAIModel{ID: m, Object: "model"})
+		}
+		json.NewEncoder(rw).Encode(list)
+	}))
+}
+
+var _ = Describe("Gateway authentication", func() {
+	var w *httptest.Server
+	var g *Gateway
+
+	get := func(path string, headers ...string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		g.ServeHTTP(rec, req)
+		return rec
+	}
+
+	models := func(rec *httptest.ResponseRecorder) []string {
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		list := schema.ModelsDataResponse{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &list)).To(Succeed())
+		ids := []string{}
+		for _, m := range list.Data {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		w = securedWorker(map[string][]string{"worker-key": {"llama", "flux"}, "team-key": {"llama"}})
+		g = NewGateway([]string{w.URL}, nil, "localai", "worker-key", []string{"gateway-key"}, 1<<20)
+		Expect(g.Refresh(context.Background())).To(Succeed())
+	})
+
+	AfterEach(func() {
+		w.Close()
+	})
+
+	It("lists the models with the API key of the request", func() {
+		Expect(g.Models()).To(Equal([]string{"flux", "llama"}))
+
+		Expect(get("/v1/models").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("/v1/models", "Authorization", "Bearer gateway-key").Code).To(Equal(http.StatusUnauthorized))
+		Expect(models(get("/v1/models", "Authorization", "Bearer team-key"))).To(Equal([]string{"llama"}))
+		Expect(models(get("/v1/models", "x-api-key", "team-key"))).To(Equal([]string{"llama"}))
+	})
+
+	It("serves the state of the workers to the API keys of the gateway only", func() {
+		Expect(get("/gateway/workers").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("/gateway/workers", "Authorization", "Bearer team-key").Code).To(Equal(http.StatusUnauthorized))
+		Expect(get("/gateway/workers", "Authorization", "Bearer worker-key").Code).To(Equal(http.StatusUnauthorized))
+
+		rec := get("/gateway/workers", "Authorization", "Bearer gateway-key")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(w.URL))
+
+		g = NewGateway([]string{w.URL}, nil, "localai", "worker-key", nil, 1<<20)
+		Expect(get("/gateway/workers", "Authorization", "Bearer gateway-key").Code).To(Equal(http.StatusUnauthorized))
+	})
+})
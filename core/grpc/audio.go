@@ -0,0 +1,189 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/schema"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	"github.com/mudler/LocalAI/pkg/sound"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) Transcription(ctx context.Context, in *pb.TranscriptionRequest) (*pb.TranscriptionResponse, error) {
+	if len(in.Audio) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "audio is required")
+	}
+
+	cfg, err := s.backendConfig(ctx, in.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "whisper")
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	filename := "audio.wav"
+	if in.Filename != "" {
+		filename = utils.SanitizeFileName(in.Filename)
+	}
+	dst := filepath.Join(dir, filename)
+	if err := os.WriteFile(dst, in.Audio, 0600); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	tr, err := backend.ModelTranscription(ctx, dst, in.Language, in.Prompt, in.Translate, false, false, s.ml, *cfg, s.appConfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	res := &pb.TranscriptionResponse{Text: tr.Text, Language: tr.Language}
+	for _, seg := range tr.Segments {
+		res.Segments = append(res.Segments, &pb.TranscriptionSegment{
+			Id:      int32(seg.Id),
+			StartMs: seg.Start.Milliseconds(),
+			EndMs:   seg.End.Milliseconds(),
+			Text:    seg.Text,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) TranscriptionStream(stream pb.LocalAI_TranscriptionStreamServer) error {
+	chunk, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg, err := s.backendConfig(stream.Context(), chunk.Model)
+	if err != nil {
+		return err
+	}
+
+	// Segments are sent by the transcription worker only, once at a time
+	var sendErr error
+	ts := backend.NewTranscriptionStream(chunk.Language, func(seg schema.Segment) {
+		if sendErr != nil {
+			return
+		}
+		sendErr = stream.Send(&pb.TranscriptionSegment{
+			Id:      int32(seg.Id),
+			StartMs: seg.Start.Milliseconds(),
+			EndMs:   seg.End.Milliseconds(),
+			Text:    seg.Text,
+		})
+	}, s.ml, *cfg, s.appConfig)
+
+	for {
+		if err := ts.Write(chunk.Audio); err != nil {
+			ts.Close()
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		chunk, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			ts.Close()
+			return err
+		}
+	}
+
+	if err := ts.Close(); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return sendErr
+}
+
+func (s *Server) TTS(ctx context.Context, in *pb.TTSRequest) (*pb.TTSResponse, error) {
+	cfg, err := s.backendConfig(ctx, in.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Backend != "" {
+		cfg.Backend = in.Backend
+	}
+
+	if in.Language != "" {
+		cfg.Language = in.Language
+	}
+
+	if in.Voice != "" {
+		cfg.Voice = in.Voice
+	}
+
+	filePath, _, err := backend.ModelTTS(cfg.Backend, in.Input, cfg.Model, cfg.Voice, cfg.Language, s.ml, s.appConfig, *cfg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	// The HTTP API serves the generated files, here we hand them back directly
+	defer os.Remove(filePath)
+
+	converted, err := sound.Convert(filePath, in.ResponseFormat, int(in.SampleRate))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer os.Remove(converted)
+
+	audio, err := os.ReadFile(converted)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.TTSResponse{Audio: audio, ContentType: sound.ContentType(in.ResponseFormat)}, nil
+}
+
+func (s *Server) SoundGeneration(ctx context.Context, in *pb.SoundGenerationRequest) (*pb.SoundGenerationResponse, error) {
+	cfg, err := s.backendConfig(ctx, in.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Backend != "" {
+		cfg.Backend = in.Backend
+	}
+
+	var src *string
+	if len(in.Audio) > 0 {
+		f, err := os.CreateTemp("", "sound-generation")
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(in.Audio)
+		f.Close()
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		name := f.Name()
+		src = &name
+	}
+
+	filePath, _, err := backend.SoundGeneration(cfg.Backend, cfg.Model, in.Text, in.Duration, in.Temperature, in.DoSample, src, s.ml, s.appConfig, *cfg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer os.Remove(filePath)
+
+	audio, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.SoundGenerationResponse{Audio: audio, ContentType: "audio/wav"}, nil
+}
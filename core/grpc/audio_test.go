@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/services"
+	backendgrpc "github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/base"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	backendpb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// soundBackend is an embedded backend generating the audio from the conditioning audio
+type soundBackend struct {
+	base.Base
+	src []byte
+}
+
+func (s *soundBackend) Load(*backendpb.ModelOptions) error {
+	return nil
+}
+
+func (s *soundBackend) SoundGeneration(req *backendpb.SoundGenerationRequest) error {
+	src, err := os.ReadFile(*req.Src)
+	if err != nil {
+		return err
+	}
+	s.src = src
+	return os.WriteFile(req.Dst, append([]byte("RIFF"), src...), 0600)
+}
+
+var _ = Describe("Audio", func() {
+	It("generates the sounds conditioned on the audio of the request", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "musicgen.yaml"), []byte("name: musicgen\nbackend: sound-generation\nparameters:\n  model: musicgen\n"), 0644)).To(Succeed())
+		appConfig := config.NewApplicationConfig(
+			config.WithModelPath(dir),
+			config.WithAudioDir(GinkgoT().TempDir()),
+			config.WithExternalBackend("sound-generation", "sound-generation"),
+		)
+		llm := &soundBackend{}
+		backendgrpc.Provide("sound-generation", llm)
+
+		cl := config.NewBackendConfigLoader(dir)
+		Expect(cl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+		server := NewServer(cl, model.NewModelLoader(dir), appConfig, services.NewAPIKeys(appConfig))
+
+		res, err := server.SoundGeneration(context.Background(), &pb.SoundGenerationRequest{Model: "musicgen", Text: "drums", Audio: []byte("melody")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.src).To(Equal([]byte("melody")))
+		Expect(res.Audio).To(Equal([]byte("RIFFmelody")))
+		Expect(res.ContentType).To(Equal("audio/wav"))
+		// the generated audio is only sent back
+		Expect(os.ReadDir(appConfig.AudioDir)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) ChatCompletion(ctx context.Context, in *pb.ChatRequest) (*pb.ChatResponse, error) {
+	if err := s.checkMessages(in); err != nil {
+		return nil, err
+	}
+	cfg, err := s.backendConfig(ctx, in.Model)
+	if err != nil {
+		return nil, err
+	}
+	updateChatConfig(cfg, in)
+
+	prompt, messages := s.chatPrompt(ctx, cfg, in.Messages)
+
+	predFunc, err := backend.ModelInference(ctx, prompt, messages, nil, s.ml, *cfg, s.appConfig, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	prediction, err := predFunc()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.recordTokens(ctx, prediction.Usage.Prompt+prediction.Usage.Completion)
+
+	return &pb.ChatResponse{
+		Id:           uuid.New().String(),
+		Model:        in.Model,
+		Content:      backend.Finetune(*cfg, prompt, prediction.Response),
+		FinishReason: "stop",
+		Usage:        usage(prediction.Usage),
+	}, nil
+}
+
+// ChatCompletionStream streams the tokens as they are produced by the backend.
+// Sending on the stream blocks when the client does not keep up, so the gRPC flow control
+// applies backpressure on the generation.
+func (s *Server) ChatCompletionStream(in *pb.ChatRequest, stream pb.LocalAI_ChatCompletionStreamServer) error {
+	if err := s.checkMessages(in); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	cfg, err := s.backendConfig(ctx, in.Model)
+	if err != nil {
+		return err
+	}
+	updateChatConfig(cfg, in)
+
+	prompt, messages := s.chatPrompt(ctx, cfg, in.Messages)
+
+	id := uuid.New().String()
+	var sendErr error
+
+	predFunc, err := backend.ModelInference(ctx, prompt, messages, nil, s.ml, *cfg, s.appConfig, func(token string, tokenUsage backend.TokenUsage) bool {
+		if sendErr != nil {
+			return false
+		}
+		sendErr = stream.Send(&pb.ChatResponse{
+			Id:      id,
+			Model:   in.Model,
+			Content: token,
+			Usage:   usage(tokenUsage),
+		})
+		if sendErr != nil {
+			log.Debug().Err(sendErr).Msg("Sending chunk failed")
+			cancel()
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	prediction, err := predFunc()
+	s.recordTokens(ctx, prediction.Usage.Prompt+prediction.Usage.Completion)
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return stream.Send(&pb.ChatResponse{
+		Id:           id,
+		Model:        in.Model,
+		FinishReason: "stop",
+		Usage:        usage(prediction.Usage),
+	})
+}
+
+// checkMessages limits the number of messages of the requests, as the HTTP API
+func (s *Server) checkMessages(in *pb.ChatRequest) error {
+	if s.appConfig.MaxMessages > 0 && len(in.Messages) > s.appConfig.MaxMessages {
+		return status.Errorf(codes.ResourceExhausted, "the request has %d messages, more than the limit of %d", len(in.Messages), s.appConfig.MaxMessages)
+	}
+	return nil
+}
+
+func updateChatConfig(cfg *config.BackendConfig, in *pb.ChatRequest) {
+	if in.Temperature != nil {
+		t := float64(*in.Temperature)
+		cfg.Temperature = &t
+	}
+	if in.TopP != nil {
+		p := float64(*in.TopP)
+		cfg.TopP = &p
+	}
+	if in.TopK != nil {
+		k := int(*in.TopK)
+		cfg.TopK = &k
+	}
+	if in.MaxTokens != nil {
+		m := int(*in.MaxTokens)
+		cfg.Maxtokens = &m
+	}
+	cfg.LimitMaxTokens()
+	if in.Seed != nil {
+		seed := int(*in.Seed)
+		cfg.Seed = &seed
+	}
+	if in.Grammar != "" {
+		cfg.Grammar = in.Grammar
+	}
+	cfg.StopWords = append(cfg.StopWords, in.Stop...)
+}
+
+// chatPrompt renders the messages with the model templates, see backend.ChatPrompt
+func (s *Server) chatPrompt(ctx context.Context, cfg *config.BackendConfig, in []*pb.ChatMessage) (string, []schema.Message) {
+	messages := make([]schema.Message, 0, len(in))
+	for _, m := range in {
+		messages = append(messages, schema.Message{Role: m.Role, Content: m.Content, StringContent: m.Content})
+	}
+	return backend.ChatPrompt(ctx, messages, nil, false, s.ml, *cfg, s.appConfig), messages
+}
+
+func usage(u backend.TokenUsage) *pb.Usage {
+	return &pb.Usage{
+		PromptTokens:     int32(u.Prompt),
+		CompletionTokens: int32(u.Completion),
+		TotalTokens:      int32(u.Prompt + u.Completion),
+	}
+}
@@ -0,0 +1,33 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/mudler/LocalAI/core/backend"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) Embedding(ctx context.Context, in *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	cfg, err := s.backendConfig(ctx, in.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.EmbeddingResponse{Model: in.Model}
+	for i, input := range in.Input {
+		embedFn, err := backend.ModelEmbedding(input, []int{}, s.ml, *cfg, s.appConfig)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		embeddings, err := embedFn()
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		res.Data = append(res.Data, &pb.Embedding{Index: int32(i), Values: embeddings})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,271 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/services"
+	backendgrpc "github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	"github.com/mudler/LocalAI/pkg/ipfilter"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PriorityMetadata is set to "high" by the clients sending latency-sensitive requests, as the
+// X-LocalAI-Priority header of the HTTP API
+const PriorityMetadata = "x-localai-priority"
+
+// Server exposes the LocalAI API over gRPC, next to the HTTP API.
+// It shares the backend config loader and the model loader with the HTTP server,
+// so models loaded by one are reused by the other. It shares the API keys with the HTTP
+// server as well, so the requests of both count in the same quotas and rate limits.
+type Server struct {
+	pb.UnimplementedLocalAIServer
+
+	cl        *config.BackendConfigLoader
+	ml        *model.ModelLoader
+	appConfig *config.ApplicationConfig
+	apiKeys   *services.APIKeys
+}
+
+func NewServer(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, apiKeys *services.APIKeys) *Server {
+	return &Server{
+		cl:        cl,
+		ml:        ml,
+		appConfig: appConfig,
+		apiKeys:   apiKeys,
+	}
+}
+
+// Serve starts listening on the given address and blocks until the listener fails
+// or the application context is canceled.
+func (s *Server) Serve(address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	srv, err := s.newGRPCServer()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-s.appConfig.Context.Done()
+		srv.GracefulStop()
+	}()
+
+	log.Info().Str("address", lis.Addr().String()).Msg("LocalAI gRPC API is listening")
+	return srv.Serve(lis)
+}
+
+// newGRPCServer applies the rules of the HTTP API to the gRPC requests: the clients are filtered by their
+// address, then authenticated by their API key within their quotas, and the size and the timeouts of the
+// requests are limited
+func (s *Server) newGRPCServer() (*grpc.Server, error) {
+	unary, stream, err := s.interceptors()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary),
+		grpc.ChainStreamInterceptor(stream),
+	}
+	// The audio of the requests is limited as the uploads of the HTTP API
+	if limit := max(s.appConfig.UploadLimitMB, s.appConfig.JSONBodyLimitMB); limit > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(limit*1024*1024))
+	}
+	// The slow clients are disconnected after the read timeout, as by the HTTP API
+	if s.appConfig.ReadTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(s.appConfig.ReadTimeout))
+	}
+	if s.appConfig.IdleTimeout > 0 {
+		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{MaxConnectionIdle: s.appConfig.IdleTimeout}))
+	}
+
+	srv := grpc.NewServer(opts...)
+	pb.RegisterLocalAIServer(srv, s)
+	return srv, nil
+}
+
+// interceptors filter the clients by their address, then authenticate them by their API key
+func (s *Server) interceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	filter, err := ipfilter.New(s.appConfig.AllowedIPs, s.appConfig.DeniedIPs, s.appConfig.TrustedProxies)
+	if err != nil {
+		return nil, nil, err
+	}
+	checks := []func(context.Context) (context.Context, error){
+		func(ctx context.Context) (context.Context, error) { return filterClient(ctx, filter) },
+		s.authenticate,
+	}
+	return unaryInterceptor(checks...), streamInterceptor(checks...), nil
+}
+
+// unaryInterceptor runs the checks on the context of the requests, in order
+func unaryInterceptor(checks ...func(context.Context) (context.Context, error)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		for _, check := range checks {
+			var err error
+			if ctx, err = check(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor runs the checks on the context of the streams, in order
+func streamInterceptor(checks ...func(context.Context) (context.Context, error)) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		for _, check := range checks {
+			var err error
+			if ctx, err = check(ctx); err != nil {
+				return err
+			}
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream is a stream whose context carries the caller of the request
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// filterClient resolves the address of the client behind the trusted proxies, from the "x-forwarded-for"
+// metadata, and denies the clients not allowed by the filter
+func filterClient(ctx context.Context, filter *ipfilter.Filter) (context.Context, error) {
+	if !filter.Enabled() {
+		return ctx, nil
+	}
+	var remote netip.Addr
+	if p, ok := peer.FromContext(ctx); ok {
+		if addr, err := netip.ParseAddrPort(p.Addr.String()); err == nil {
+			remote = addr.Addr()
+		}
+	}
+	client := filter.ClientIP(remote, strings.Join(metadata.ValueFromIncomingContext(ctx, "x-forwarded-for"), ","))
+	if !filter.Allowed(client) {
+		return nil, status.Errorf(codes.PermissionDenied, "client %s not allowed", client)
+	}
+	return ctx, nil
+}
+
+type callerKey struct{}
+
+// caller is the API key a request was authenticated with, and its tenant
+type caller struct {
+	apiKey string
+	tenant *config.Tenant
+}
+
+// authenticate checks the API key passed in the "authorization" metadata, within the quotas and the rate
+// limits of the key, following the same rules of the HTTP API: if no API key is set, no auth is required.
+// The requests with a high priority, and the ones of the tenants with priority, are scheduled first on the
+// slots of the backends
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	var tenant *config.Tenant
+	if s.apiKeys.Required() {
+		var authorization string
+		if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+		apiKey, err := services.BearerToken(authorization)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		tenant, err = s.apiKeys.Authenticate(apiKey)
+		switch {
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case err != nil:
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		ctx = context.WithValue(ctx, callerKey{}, &caller{apiKey: apiKey, tenant: tenant})
+	}
+
+	priority := metadata.ValueFromIncomingContext(ctx, PriorityMetadata)
+	if (tenant != nil && tenant.Priority) || (len(priority) > 0 && strings.EqualFold(priority[0], "high")) {
+		ctx = backendgrpc.WithPriority(ctx)
+	}
+	return ctx, nil
+}
+
+// tenantFromContext returns the tenant of the request, or nil if its API key does not belong to a tenant
+func tenantFromContext(ctx context.Context) *config.Tenant {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		return c.tenant
+	}
+	return nil
+}
+
+// recordTokens counts the tokens used by the request in the quotas of its API key and of its tenant
+func (s *Server) recordTokens(ctx context.Context, tokens int) {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		s.apiKeys.RecordTokens(c.apiKey, c.tenant, tokens)
+	}
+}
+
+// backendConfig resolves the model name to its backend configuration.
+// If no model is specified, it takes the first available, as the HTTP API does.
+// The models of the other tenants are reported as missing, not as forbidden.
+func (s *Server) backendConfig(ctx context.Context, modelName string) (*config.BackendConfig, error) {
+	tenant := tenantFromContext(ctx)
+	if modelName == "" {
+		models, _ := services.ListModels(s.cl, s.ml, "", true)
+		if tenant != nil {
+			models = slices.DeleteFunc(models, func(m string) bool { return !tenant.CanUseModel(m) })
+		}
+		if len(models) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "no model specified")
+		}
+		modelName = models[0]
+		log.Debug().Msgf("No model specified, using: %s", modelName)
+	}
+	if tenant != nil && !tenant.CanUseModel(modelName) {
+		return nil, status.Errorf(codes.NotFound, "model %s not found", modelName)
+	}
+
+	cfg, err := s.cl.LoadBackendConfigFileByName(modelName, s.ml.ModelPath, s.appConfig.ToConfigLoaderOptions()...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed loading model config: %s", err.Error())
+	}
+
+	if !cfg.Validate() {
+		return nil, status.Error(codes.InvalidArgument, "failed to validate config")
+	}
+
+	return cfg, nil
+}
+
+func (s *Server) ListModels(ctx context.Context, in *pb.ListModelsRequest) (*pb.ListModelsResponse, error) {
+	models, err := services.ListModels(s.cl, s.ml, in.Filter, true)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if tenant := tenantFromContext(ctx); tenant != nil {
+		models = slices.DeleteFunc(models, func(m string) bool { return !tenant.CanUseModel(m) })
+	}
+	return &pb.ListModelsResponse{Models: models}, nil
+}
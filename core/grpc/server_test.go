@@ -0,0 +1,156 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/services"
+	backendgrpc "github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/localai"
+	"github.com/mudler/LocalAI/pkg/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		appConfig *config.ApplicationConfig
+		apiKeys   *services.APIKeys
+		server    *Server
+		unary     grpc.UnaryServerInterceptor
+	)
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		for _, name := range []string{"phi", "llama"} {
+			Expect(os.WriteFile(filepath.Join(dir, name+".yaml"), []byte("name: "+name+"\nbackend: llama-cpp\nparameters:\n  model: "+name+".gguf\n"), 0644)).To(Succeed())
+		}
+		appConfig = config.NewApplicationConfig(config.WithApiKeys([]string{"admin"}), config.WithModelPath(dir))
+		appConfig.Tenants = map[string]config.Tenant{
+			"team": {Name: "team", APIKeys: []string{"team-key"}, Models: []string{"phi"}, DailyTokens: 10, Priority: true},
+		}
+	})
+
+	// start creates the server with the configuration of the spec
+	start := func() {
+		cl := config.NewBackendConfigLoader(appConfig.ModelPath)
+		Expect(cl.LoadBackendConfigsFromPath(appConfig.ModelPath)).To(Succeed())
+		apiKeys = services.NewAPIKeys(appConfig)
+		server = NewServer(cl, model.NewModelLoader(appConfig.ModelPath), appConfig, apiKeys)
+		var err error
+		unary, _, err = server.interceptors()
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// call sends a request from the address of the client, with the metadata pairs
+	call := func(client string, handler grpc.UnaryHandler, pairs ...string) (interface{}, error) {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(client), Port: 40000}})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+		return unary(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	}
+
+	listModels := func(apiKey string) ([]string, error) {
+		var pairs []string
+		if apiKey != "" {
+			pairs = []string{"authorization", "Bearer " + apiKey}
+		}
+		res, err := call("127.0.0.1", func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return server.ListModels(ctx, &pb.ListModelsRequest{})
+		}, pairs...)
+		if err != nil {
+			return nil, err
+		}
+		return res.(*pb.ListModelsResponse).Models, nil
+	}
+
+	It("requires a valid API key", func() {
+		start()
+		_, err := listModels("")
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		_, err = listModels("other")
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		_, err = call("127.0.0.1", nil, "authorization", "Basic admin")
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		Expect(listModels("admin")).To(ConsistOf("phi", "llama"))
+	})
+
+	It("requires no API key when none is set", func() {
+		appConfig.ApiKeys = nil
+		appConfig.Tenants = nil
+		start()
+		Expect(listModels("")).To(ConsistOf("phi", "llama"))
+	})
+
+	It("only shows the models of the tenants", func() {
+		start()
+		Expect(listModels("team-key")).To(ConsistOf("phi"))
+
+		_, err := call("127.0.0.1", func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return server.Embedding(ctx, &pb.EmbeddingRequest{Model: "llama", Input: []string{"hello"}})
+		}, "authorization", "Bearer team-key")
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("schedules the requests of the tenants with priority first", func() {
+		start()
+		priority := func(pairs ...string) bool {
+			var high bool
+			_, err := call("127.0.0.1", func(ctx context.Context, _ interface{}) (interface{}, error) {
+				high = backendgrpc.IsPriority(ctx)
+				return nil, nil
+			}, pairs...)
+			Expect(err).ToNot(HaveOccurred())
+			return high
+		}
+		Expect(priority("authorization", "Bearer team-key")).To(BeTrue())
+		Expect(priority("authorization", "Bearer admin")).To(BeFalse())
+		Expect(priority("authorization", "Bearer admin", PriorityMetadata, "high")).To(BeTrue())
+	})
+
+	It("enforces the token quotas shared with the HTTP API", func() {
+		start()
+		apiKeys.RecordTokens("team-key", appConfig.TenantByAPIKey("team-key"), 10)
+		_, err := listModels("team-key")
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		Expect(listModels("admin")).To(HaveLen(2))
+	})
+
+	It("limits the number of messages", func() {
+		appConfig.MaxMessages = 1
+		start()
+		_, err := call("127.0.0.1", func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return server.ChatCompletion(ctx, &pb.ChatRequest{Model: "phi", Messages: []*pb.ChatMessage{
+				{Role: "user", Content: "hello"}, {Role: "user", Content: "again"},
+			}})
+		}, "authorization", "Bearer admin")
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	})
+
+	It("filters the clients by their address", func() {
+		appConfig.DeniedIPs = []string{"127.0.0.0/8"}
+		start()
+		_, err := listModels("admin")
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("resolves the address of the clients behind the trusted proxies", func() {
+		appConfig.AllowedIPs = []string{"10.0.0.0/8"}
+		appConfig.TrustedProxies = []string{"127.0.0.1"}
+		start()
+		handler := func(ctx context.Context, _ interface{}) (interface{}, error) { return nil, nil }
+		_, err := call("127.0.0.1", handler, "x-forwarded-for", "10.1.2.3", "authorization", "Bearer admin")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = call("192.168.1.1", handler, "x-forwarded-for", "10.1.2.3", "authorization", "Bearer admin")
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = listModels("admin")
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+})
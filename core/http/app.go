@@ -3,11 +3,17 @@ package http
 import (
 	"embed"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mudler/LocalAI/pkg/utils"
 
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/http/endpoints/localai"
 	"github.com/mudler/LocalAI/core/http/endpoints/openai"
 	"github.com/mudler/LocalAI/core/http/routes"
@@ -15,6 +21,8 @@ import (
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/ipfilter"
+	"github.com/mudler/LocalAI/pkg/logging"
 	"github.com/mudler/LocalAI/pkg/model"
 
 	"github.com/gofiber/contrib/fiberzerolog"
@@ -24,8 +32,12 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/favicon"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
 
 	// swagger handler
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -65,17 +77,38 @@ var embedDirStatic embed.FS
 // @in header
 // @name Authorization
 
-func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) (*fiber.App, error) {
+func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, apiKeys *services.APIKeys) (*fiber.App, error) {
 
 	fiberCfg := fiber.Config{
-		Views:     renderEngine(),
-		BodyLimit: appConfig.UploadLimitMB * 1024 * 1024, // this is the default limit of 4MB
+		Views: renderEngine(),
+		// The uploads, and the JSON bodies, are limited below
+		BodyLimit: max(appConfig.UploadLimitMB, appConfig.JSONBodyLimitMB) * 1024 * 1024,
+		// Slow clients, sending their requests a byte at a time, are disconnected after the read timeout
+		ReadTimeout:  appConfig.ReadTimeout,
+		WriteTimeout: appConfig.WriteTimeout,
+		IdleTimeout:  appConfig.IdleTimeout,
 		// We disable the Fiber startup message as it does not conform to structured logging.
 		// We register a startup log line with connection information in the OnListen hook to keep things user friendly though
 		DisableStartupMessage: true,
 		// Override default error handler
 	}
 
+	// The last server errors are shown in the dashboard
+	recentErrors := services.NewRecentErrors(50)
+	recordError := func(ctx *fiber.Ctx, code int, err error) {
+		if code < fiber.StatusInternalServerError {
+			return
+		}
+		recentErrors.Record(schema.RecentError{
+			Time:      time.Now(),
+			RequestID: ctx.GetRespHeader(logging.RequestIDHeader),
+			Method:    ctx.Method(),
+			Path:      ctx.Path(),
+			Status:    code,
+			Message:   err.Error(),
+		})
+	}
+
 	if !appConfig.OpaqueErrors {
 		// Normally, return errors as JSON responses
 		fiberCfg.ErrorHandler = func(ctx *fiber.Ctx, err error) error {
@@ -88,6 +121,15 @@ func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *confi
 				code = e.Code
 			}
 
+			// The models with an open circuit are retried once probed again
+			var circuitErr *model.CircuitOpenError
+			if errors.As(err, &circuitErr) {
+				code = fiber.StatusServiceUnavailable
+				ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(circuitErr.RetryAfter.Seconds()))))
+			}
+
+			recordError(ctx, code, err)
+
 			// Send custom error page
 			return ctx.Status(code).JSON(
 				schema.ErrorResponse{
@@ -97,12 +139,14 @@ func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *confi
 		}
 	} else {
 		// If OpaqueErrors are required, replace everything with a blank 500.
-		fiberCfg.ErrorHandler = func(ctx *fiber.Ctx, _ error) error {
+		fiberCfg.ErrorHandler = func(ctx *fiber.Ctx, err error) error {
+			recordError(ctx, fiber.StatusInternalServerError, err)
 			return ctx.Status(500).SendString("")
 		}
 	}
 
 	app := fiber.New(fiberCfg)
+	app.Server().MaxConnsPerIP = appConfig.MaxConnsPerIP
 
 	app.Hooks().OnListen(func(listenData fiber.ListenData) error {
 		scheme := "http"
@@ -114,58 +158,89 @@ func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *confi
 	})
 
 	// Have Fiber use zerolog like the rest of the application rather than it's built-in logger
-	logger := log.Logger
+	// Each request gets an ID, unless the client sets one, returned in the X-Request-ID header
+	app.Use(requestid.New(requestid.Config{
+		Header:    logging.RequestIDHeader,
+		Generator: uuid.NewString,
+	}))
 	app.Use(fiberzerolog.New(fiberzerolog.Config{
-		Logger: &logger,
+		GetLogger: func(c *fiber.Ctx) zerolog.Logger {
+			return logging.Logger(logging.HTTP).With().Str(fiberzerolog.FieldIP, fiberContext.ClientIP(c)).Logger()
+		},
+		Fields:          []string{fiberzerolog.FieldLatency, fiberzerolog.FieldStatus, fiberzerolog.FieldMethod, fiberzerolog.FieldURL, fiberzerolog.FieldError, fiberzerolog.FieldRequestID},
+		FieldsSnakeCase: true,
 	}))
 
+	filter, err := ipfilter.New(appConfig.AllowedIPs, appConfig.DeniedIPs, appConfig.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	// The API and the web interface are both filtered, the clients not allowed get a 403
+	app.Use(ipFilter(filter))
+
+	// The limit of each request is set once its headers are read, so the bodies over the limit are not read
+	if appConfig.JSONBodyLimitMB > 0 {
+		app.Server().HeaderReceived = bodyLimit(appConfig)
+	}
+
 	// Default middleware config
 
 	if !appConfig.Debug {
 		app.Use(recover.New())
 	}
 
-	metricsService, err := services.NewLocalAIMetricsService()
-	if err != nil {
-		return nil, err
-	}
+	if !appConfig.DisableMetrics {
+		metricsService, err := services.NewLocalAIMetricsService()
+		if err != nil {
+			return nil, err
+		}
 
-	if metricsService != nil {
 		app.Use(localai.LocalAIMetricsAPIMiddleware(metricsService))
+		if err := metricsService.ObserveLoader(ml); err != nil {
+			return nil, err
+		}
+		if circuits := ml.CircuitBreaker(); circuits != nil {
+			if err := metricsService.ObserveCircuits(circuits); err != nil {
+				return nil, err
+			}
+		}
 		app.Hooks().OnShutdown(func() error {
 			return metricsService.Shutdown()
 		})
 	}
 
+	tokenQuotas := apiKeys.Quotas()
+
 	// Auth middleware checking if API key is valid. If no API key is set, no auth is required.
 	auth := func(c *fiber.Ctx) error {
-		if len(appConfig.ApiKeys) == 0 {
-			return c.Next()
-		}
-
-		if len(appConfig.ApiKeys) == 0 {
+		if !apiKeys.Required() {
 			return c.Next()
 		}
 
-		authHeader := readAuthHeader(c)
-		if authHeader == "" {
+		apiKey, err := services.BearerToken(readAuthHeader(c))
+		switch {
+		case errors.Is(err, services.ErrAuthorizationMissing):
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Authorization header missing"})
-		}
-
-		// If it's a bearer token
-		authHeaderParts := strings.Split(authHeader, " ")
-		if len(authHeaderParts) != 2 || authHeaderParts[0] != "Bearer" {
+		case err != nil:
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid Authorization header format"})
 		}
 
-		apiKey := authHeaderParts[1]
-		for _, key := range appConfig.ApiKeys {
-			if apiKey == key {
-				return c.Next()
-			}
+		tenant, err := apiKeys.Authenticate(apiKey)
+		switch {
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid API key"})
+		case err != nil:
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"message": err.Error()})
+		}
+		if tenant != nil {
+			fiberContext.SetTenant(c, tenant)
 		}
 
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid API key"})
+		fiberContext.SetAPIKey(c, apiKey)
+		fiberContext.SetTokenUsageRecorder(c, func(tokens int) {
+			apiKeys.RecordTokens(apiKey, tenant, tokens)
+		})
+		return c.Next()
 	}
 
 	if appConfig.CORS {
@@ -186,6 +261,8 @@ func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *confi
 
 	// Load config jsons
 	utils.LoadConfig(appConfig.UploadDir, openai.UploadedFilesFile, &openai.UploadedFiles)
+	utils.LoadConfig(appConfig.UploadDir, openai.UploadedFilesOwnersFile, &openai.UploadedFilesOwners)
+	openai.StartFilesRetention(appConfig.Context, appConfig, time.Minute)
 	utils.LoadConfig(appConfig.ConfigsDir, openai.AssistantsConfigFile, &openai.Assistants)
 	utils.LoadConfig(appConfig.ConfigsDir, openai.AssistantsFileConfigFile, &openai.AssistantFiles)
 
@@ -193,8 +270,12 @@ func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *confi
 	galleryService.Start(appConfig.Context, cl)
 
 	routes.RegisterElevenLabsRoutes(app, cl, ml, appConfig, auth)
-	routes.RegisterLocalAIRoutes(app, cl, ml, appConfig, galleryService, auth)
-	routes.RegisterOpenAIRoutes(app, cl, ml, appConfig, auth)
+	jobService := services.NewJobService(appConfig.Context, appConfig.JobRetention)
+	services.StartRetention(appConfig, jobService, time.Minute)
+	generationService := services.NewGenerationService()
+
+	routes.RegisterLocalAIRoutes(app, cl, ml, appConfig, galleryService, tokenQuotas, jobService, generationService, recentErrors, auth)
+	routes.RegisterOpenAIRoutes(app, cl, ml, appConfig, jobService, generationService, auth)
 	if !appConfig.DisableWebUI {
 		routes.RegisterUIRoutes(app, cl, ml, appConfig, galleryService, auth)
 	}
@@ -220,3 +301,29 @@ func App(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *confi
 
 	return app, nil
 }
+
+// bodyLimit limits the body of the requests to the JSON body limit, and the one of the uploads to the upload
+// limit. The requests over the limit get a 413 error from the error handler, without their body being read
+func bodyLimit(appConfig *config.ApplicationConfig) func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		limit := appConfig.JSONBodyLimitMB
+		if strings.HasPrefix(string(header.ContentType()), fiber.MIMEMultipartForm) {
+			limit = appConfig.UploadLimitMB
+		}
+		return fasthttp.RequestConfig{MaxRequestBodySize: limit * 1024 * 1024}
+	}
+}
+
+// ipFilter resolves the address of the clients behind the trusted proxies, and denies the clients not
+// allowed by the filter
+func ipFilter(filter *ipfilter.Filter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		remote, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+		client := filter.ClientIP(remote, c.Get(fiber.HeaderXForwardedFor))
+		fiberContext.SetClientIP(c, client.String())
+		if !filter.Allowed(client) {
+			return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("client %s not allowed", client))
+		}
+		return c.Next()
+	}
+}
@@ -16,12 +16,14 @@ import (
 	"github.com/mudler/LocalAI/core/config"
 	. "github.com/mudler/LocalAI/core/http"
 	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
 	"github.com/mudler/LocalAI/core/startup"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/core/gallery"
 	"github.com/mudler/LocalAI/pkg/downloader"
 	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/provenance"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"gopkg.in/yaml.v3"
@@ -264,7 +266,7 @@ var _ = Describe("API test", func() {
 					config.WithBackendAssetsOutput(backendAssetsDir))...)
 			Expect(err).ToNot(HaveOccurred())
 
-			app, err = App(bcl, ml, applicationConfig)
+			app, err = App(bcl, ml, applicationConfig, services.NewAPIKeys(applicationConfig))
 			Expect(err).ToNot(HaveOccurred())
 
 			go app.Listen("127.0.0.1:9090")
@@ -596,7 +598,7 @@ var _ = Describe("API test", func() {
 					config.WithBackendAssetsOutput(tmpdir))...,
 			)
 			Expect(err).ToNot(HaveOccurred())
-			app, err = App(bcl, ml, applicationConfig)
+			app, err = App(bcl, ml, applicationConfig, services.NewAPIKeys(applicationConfig))
 			Expect(err).ToNot(HaveOccurred())
 
 			go app.Listen("127.0.0.1:9090")
@@ -723,7 +725,7 @@ var _ = Describe("API test", func() {
 					config.WithModelPath(modelPath),
 				)...)
 			Expect(err).ToNot(HaveOccurred())
-			app, err = App(bcl, ml, applicationConfig)
+			app, err = App(bcl, ml, applicationConfig, services.NewAPIKeys(applicationConfig))
 			Expect(err).ToNot(HaveOccurred())
 			go app.Listen("127.0.0.1:9090")
 
@@ -995,7 +997,7 @@ var _ = Describe("API test", func() {
 					config.WithConfigFile(os.Getenv("CONFIG_FILE")))...,
 			)
 			Expect(err).ToNot(HaveOccurred())
-			app, err = App(bcl, ml, applicationConfig)
+			app, err = App(bcl, ml, applicationConfig, services.NewAPIKeys(applicationConfig))
 			Expect(err).ToNot(HaveOccurred())
 
 			go app.Listen("127.0.0.1:9090")
@@ -1043,4 +1045,160 @@ var _ = Describe("API test", func() {
 		})
 
 	})
+
+	Context("Request limits", func() {
+		BeforeEach(func() {
+			c, cancel = context.WithCancel(context.Background())
+
+			var err error
+			bcl, ml, applicationConfig, err = startup.Startup(
+				append(commonOpts,
+					config.WithContext(c),
+					config.WithModelPath(GinkgoT().TempDir()),
+					config.WithUploadLimitMB(2),
+					config.WithJSONBodyLimitMB(1))...,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			app, err = App(bcl, ml, applicationConfig, services.NewAPIKeys(applicationConfig))
+			Expect(err).ToNot(HaveOccurred())
+
+			go app.Listen("127.0.0.1:9090")
+			Eventually(func() error {
+				_, err := http.Get("http://127.0.0.1:9090/readyz")
+				return err
+			}, "2m").ShouldNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			cancel()
+			if app != nil {
+				Expect(app.Shutdown()).To(Succeed())
+			}
+		})
+
+		post := func(contentType string, size int) int {
+			resp, err := http.Post("http://127.0.0.1:9090/v1/chat/completions", contentType, bytes.NewReader(make([]byte, size)))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		It("rejects the bodies over the JSON body limit", func() {
+			Expect(post(fiber.MIMEApplicationJSON, 1536*1024)).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(post(fiber.MIMEApplicationJSON, 512)).ToNot(Equal(http.StatusRequestEntityTooLarge))
+		})
+
+		It("applies the upload limit to the multipart bodies", func() {
+			Expect(post(fiber.MIMEMultipartForm+"; boundary=x", 1536*1024)).ToNot(Equal(http.StatusRequestEntityTooLarge))
+			Expect(post(fiber.MIMEMultipartForm+"; boundary=x", 3*1024*1024)).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
+
+	Context("UI", func() {
+		var modelDir string
+
+		BeforeEach(func() {
+			modelDir = GinkgoT().TempDir()
+			for _, name := range []string{"phi", "llama"} {
+				Expect(os.WriteFile(filepath.Join(modelDir, name+".yaml"), []byte("name: "+name+"\nbackend: llama-cpp\nparameters:\n  model: "+name+".gguf\n"), 0644)).To(Succeed())
+			}
+		})
+
+		// start serves the UI with the models of modelDir
+		start := func(opts ...config.AppOption) {
+			c, cancel = context.WithCancel(context.Background())
+
+			var err error
+			bcl, ml, applicationConfig, err = startup.Startup(
+				append(append(commonOpts,
+					config.WithContext(c),
+					config.WithModelPath(modelDir)), opts...)...,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			app, err = App(bcl, ml, applicationConfig, services.NewAPIKeys(applicationConfig))
+			Expect(err).ToNot(HaveOccurred())
+
+			go app.Listen("127.0.0.1:9090")
+			Eventually(func() error {
+				_, err := http.Get("http://127.0.0.1:9090/readyz")
+				return err
+			}, "2m").ShouldNot(HaveOccurred())
+		}
+		AfterEach(func() {
+			cancel()
+			if app != nil {
+				Expect(app.Shutdown()).To(Succeed())
+			}
+		})
+
+		// page returns the status and the body of the page, without following the redirects
+		page := func(path string) (int, string) {
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			resp, err := client.Get("http://127.0.0.1:9090" + path)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			dat, err := io.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return resp.StatusCode, string(dat)
+		}
+
+		It("shows the playground with the model of the path, or the first model", func() {
+			start()
+
+			code, body := page("/playground/phi")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("<title>LocalAI - Playground with phi</title>"))
+			Expect(body).To(ContainSubstring("playground('phi')"))
+
+			code, body = page("/playground/")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("<title>LocalAI - Playground with llama</title>"))
+		})
+
+		It("redirects to the index when there is no model to try", func() {
+			modelDir = GinkgoT().TempDir()
+			start()
+
+			code, _ := page("/playground/")
+			Expect(code).To(Equal(http.StatusFound))
+			code, _ = page("/image-studio/")
+			Expect(code).To(Equal(http.StatusFound))
+			code, _ = page("/audio/")
+			Expect(code).To(Equal(http.StatusFound))
+		})
+
+		It("shows the audio tools with the first model", func() {
+			start()
+
+			code, body := page("/audio/")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("<title>LocalAI - Audio tools</title>"))
+			Expect(body).To(ContainSubstring("audioTools('llama')"))
+			Expect(body).To(ContainSubstring(`href="/audio/"`))
+		})
+
+		It("shows the image studio with the gallery of the generated images", func() {
+			imageDir := GinkgoT().TempDir()
+			start(config.WithImageDir(imageDir))
+
+			code, body := page("/image-studio/phi")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("<title>LocalAI - Image studio with phi</title>"))
+			Expect(body).To(ContainSubstring("imageStudio('phi')"))
+
+			file := filepath.Join(imageDir, "cat.png")
+			Expect(os.WriteFile(file, []byte("\x89PNG"), 0600)).To(Succeed())
+			Expect(services.SaveImageMetadata(file, provenance.Parameters{Prompt: "a cat", Model: "phi"})).To(Succeed())
+
+			code, body = page("/api/images")
+			Expect(code).To(Equal(http.StatusOK))
+			images := schema.GeneratedImageList{}
+			Expect(json.Unmarshal([]byte(body), &images)).To(Succeed())
+			Expect(images.Data).To(HaveLen(1))
+			Expect(images.Data[0].URL).To(Equal("http://127.0.0.1:9090/generated-images/cat.png"))
+
+			code, body = page("/generated-images/cat.png")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("\x89PNG"))
+		})
+	})
 })
@@ -1,14 +1,23 @@
 package fiberContext
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/logging"
 	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/storage"
 	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
 )
 
 // ModelFromContext returns the model from the context
@@ -24,9 +33,14 @@ func ModelFromContext(ctx *fiber.Ctx, cl *config.BackendConfigLoader, loader *mo
 	bearer := strings.TrimLeft(ctx.Get("authorization"), "Bearer ")
 	bearerExists := bearer != "" && loader.ExistsInModelPath(bearer)
 
+	tenant := TenantFromContext(ctx)
+
 	// If no model was specified, take the first available
 	if modelInput == "" && !bearerExists && firstModel {
 		models, _ := services.ListModels(cl, loader, "", true)
+		if tenant != nil {
+			models = slices.DeleteFunc(models, func(m string) bool { return !tenant.CanUseModel(m) })
+		}
 		if len(models) > 0 {
 			modelInput = models[0]
 			log.Debug().Msgf("No model specified, using: %s", modelInput)
@@ -41,5 +55,210 @@ func ModelFromContext(ctx *fiber.Ctx, cl *config.BackendConfigLoader, loader *mo
 		log.Debug().Msgf("Using model from bearer token: %s", bearer)
 		modelInput = bearer
 	}
+
+	// The models of the other tenants are reported as missing, not as forbidden
+	if tenant != nil && modelInput != "" && !tenant.CanUseModel(modelInput) {
+		return "", fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("model %s not found", modelInput))
+	}
 	return modelInput, nil
 }
+
+const apiKeyLocal = "apiKey"
+
+// SetAPIKey records the API key the request was authenticated with
+func SetAPIKey(ctx *fiber.Ctx, apiKey string) {
+	ctx.Locals(apiKeyLocal, apiKey)
+}
+
+// APIKeyFromContext returns the API key the request was authenticated with.
+// It is empty when API authentication is disabled.
+func APIKeyFromContext(ctx *fiber.Ctx) string {
+	apiKey, _ := ctx.Locals(apiKeyLocal).(string)
+	return apiKey
+}
+
+const tokenUsageLocal = "tokenUsage"
+
+// SetTokenUsageRecorder sets the function counting the tokens used by the request,
+// for the daily quota of its API key
+func SetTokenUsageRecorder(ctx *fiber.Ctx, record func(tokens int)) {
+	ctx.Locals(tokenUsageLocal, record)
+}
+
+// TokenUsageRecorder returns the function counting the tokens used by the request.
+// It does nothing when the API keys have no quota
+func TokenUsageRecorder(ctx *fiber.Ctx) func(tokens int) {
+	if record, ok := ctx.Locals(tokenUsageLocal).(func(int)); ok {
+		return record
+	}
+	return func(int) {}
+}
+
+const tenantLocal = "tenant"
+
+// SetTenant records the tenant of the API key the request was authenticated with
+func SetTenant(ctx *fiber.Ctx, tenant *config.Tenant) {
+	ctx.Locals(tenantLocal, tenant)
+}
+
+const (
+	generationsLocal      = "generations"
+	generationCancelLocal = "generationCancel"
+)
+
+// SetGenerations sets the service keeping the generations of the requests, to cancel them by their ID
+func SetGenerations(ctx *fiber.Ctx, generations *services.GenerationService) {
+	ctx.Locals(generationsLocal, generations)
+}
+
+// StartGeneration registers the generation of the request, whose context is cancelled by cancel, so
+// it can be cancelled by the request ID. It is unregistered once its context is done. It does nothing
+// on the routes without generations. The request IDs of the running generations of the same owner are
+// rejected with a 409 error
+func StartGeneration(c *fiber.Ctx, ctx context.Context, cancel context.CancelFunc, model string) error {
+	generations, ok := c.Locals(generationsLocal).(*services.GenerationService)
+	if !ok {
+		return nil
+	}
+	id, _ := c.Locals("requestid").(string)
+	if id == "" {
+		return nil
+	}
+	finish, err := generations.Start(id, Owner(c), model, cancel)
+	if err != nil {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	context.AfterFunc(ctx, finish)
+	c.Locals(generationCancelLocal, cancel)
+	return nil
+}
+
+// FinishGeneration ends the generation of the request, if it was started
+func FinishGeneration(c *fiber.Ctx) {
+	if cancel, ok := c.Locals(generationCancelLocal).(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+const clientIPLocal = "clientIP"
+
+// SetClientIP records the address of the client, resolved behind the trusted proxies
+func SetClientIP(ctx *fiber.Ctx, ip string) {
+	ctx.Locals(clientIPLocal, ip)
+}
+
+// ClientIP returns the address of the client, the one of the connection if it was not resolved
+func ClientIP(ctx *fiber.Ctx) string {
+	if ip, ok := ctx.Locals(clientIPLocal).(string); ok {
+		return ip
+	}
+	return ctx.IP()
+}
+
+const (
+	jobContextLocal  = "jobContext"
+	jobProgressLocal = "jobProgress"
+)
+
+// SetJob records the job running the request: the request is cancelled with the job, and reports its
+// progress to it
+func SetJob(ctx *fasthttp.RequestCtx, jobCtx context.Context, progress func(percent float64, message string)) {
+	ctx.SetUserValue(jobContextLocal, jobCtx)
+	ctx.SetUserValue(jobProgressLocal, progress)
+}
+
+// JobProgress returns the function reporting the progress of the request to its job, or nil if the
+// request is not run by a job
+func JobProgress(c *fiber.Ctx) func(percent float64, message string) {
+	progress, _ := c.Locals(jobProgressLocal).(func(float64, string))
+	return progress
+}
+
+// RequestContext returns the parent context carrying the ID of the API request, which is passed to the
+// backends and added to the log lines, and its priority on the slots of the backends. The requests run
+// by a job are cancelled with the job
+func RequestContext(c *fiber.Ctx, parent context.Context) context.Context {
+	if jobCtx, ok := c.Locals(jobContextLocal).(context.Context); ok {
+		var cancel context.CancelFunc
+		parent, cancel = context.WithCancel(parent)
+		context.AfterFunc(jobCtx, cancel)
+	}
+	id, _ := c.Locals("requestid").(string)
+	ctx := logging.WithRequestID(parent, id)
+	if Priority(c) {
+		ctx = grpc.WithPriority(ctx)
+	}
+	return ctx
+}
+
+// PriorityHeader is set to "high" by the clients sending latency-sensitive requests, as the code completions
+const PriorityHeader = "X-LocalAI-Priority"
+
+// Priority tells if the request is scheduled before the other requests on the slots of the backends: the
+// requests with a high priority header, and the ones of the tenants with priority
+func Priority(c *fiber.Ctx) bool {
+	if tenant := TenantFromContext(c); tenant != nil && tenant.Priority {
+		return true
+	}
+	return strings.EqualFold(c.Get(PriorityHeader), "high")
+}
+
+// AdminOnly rejects the requests authenticated with the API keys of the tenants, for the endpoints
+// administering the instance
+func AdminOnly(c *fiber.Ctx) error {
+	if TenantFromContext(c) != nil {
+		return fiber.NewError(fiber.StatusForbidden, "only the API keys of the instance can use this endpoint")
+	}
+	return c.Next()
+}
+
+// TenantFromContext returns the tenant of the request, or nil if its API key does not belong to a tenant
+func TenantFromContext(ctx *fiber.Ctx) *config.Tenant {
+	tenant, _ := ctx.Locals(tenantLocal).(*config.Tenant)
+	return tenant
+}
+
+// TenantKey identifies the tenant in the token quotas and as owner of the uploaded files,
+// separately from its API keys
+func TenantKey(tenant *config.Tenant) string {
+	return tenant.Owner()
+}
+
+// APIKeyOwner identifies an API key in the token quotas and as owner of the resources: API keys are not
+// stored as-is
+func APIKeyOwner(apiKey string) string {
+	return config.APIKeyOwner(apiKey)
+}
+
+// Owner returns the owner of the resources created by the request, as the uploaded files and the
+// jobs: API keys are not stored as-is. The resources of a tenant are owned by the tenant, and shared
+// by all its API keys
+func Owner(ctx *fiber.Ctx) string {
+	if tenant := TenantFromContext(ctx); tenant != nil {
+		return TenantKey(tenant)
+	}
+	apiKey := APIKeyFromContext(ctx)
+	if apiKey == "" {
+		return ""
+	}
+	return APIKeyOwner(apiKey)
+}
+
+// SendGeneratedFile sends a file generated by a backend, as the audio, as an attachment. With the encryption
+// at rest, the file, and the files it was converted from, are encrypted once read
+func SendGeneratedFile(ctx *fiber.Ctx, appConfig *config.ApplicationConfig, file string, sources ...string) error {
+	if appConfig.StorageEncryptionKey == nil {
+		return ctx.Download(file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	for _, f := range append(sources, file) {
+		if err := storage.EncryptFile(f, appConfig.StorageEncryptionKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("encrypting %s: %w", filepath.Base(f), err)
+		}
+	}
+	ctx.Attachment(file)
+	return ctx.Send(data)
+}
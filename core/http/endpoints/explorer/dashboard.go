@@ -39,33 +39,78 @@ type Network struct {
 	Token string `json:"token"`
 }
 
+// ShowNetworks lists the networks with online workers. The model query parameter keeps the networks
+// serving this model only
 func ShowNetworks(db *explorer.Database, ds *explorer.DiscoveryServer) func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		networkState := ds.NetworkState()
+		model := c.Query("model")
 		results := []Network{}
-		for token, network := range networkState.Networks {
-			networkData, exists := db.Get(token) // get the token data
-			hasWorkers := false
-			for _, cluster := range network.Clusters {
-				if len(cluster.Workers) > 0 {
-					hasWorkers = true
-					break
-				}
+		for _, network := range availableNetworks(db, ds) {
+			if model == "" || network.Serves(model) {
+				results = append(results, network)
 			}
-			if exists && hasWorkers {
-				results = append(results, Network{Network: network, TokenData: networkData, Token: token})
+		}
+		return c.JSON(results)
+	}
+}
+
+type ModelAvailability struct {
+	Model string `json:"model"`
+	// Networks are the names of the networks serving the model
+	Networks []string `json:"networks"`
+}
+
+// ShowModels lists the models served by the networks with online workers, with the networks serving them
+func ShowModels(db *explorer.Database, ds *explorer.DiscoveryServer) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		networks := map[string][]string{}
+		for _, network := range availableNetworks(db, ds) {
+			for _, m := range network.Models() {
+				networks[m] = append(networks[m], network.Name)
 			}
 		}
 
-		// order by number of clusters
+		results := []ModelAvailability{}
+		for m, n := range networks {
+			results = append(results, ModelAvailability{Model: m, Networks: n})
+		}
+		// order by number of networks, then by name
 		sort.Slice(results, func(i, j int) bool {
-			return len(results[i].Clusters) > len(results[j].Clusters)
+			if len(results[i].Networks) != len(results[j].Networks) {
+				return len(results[i].Networks) > len(results[j].Networks)
+			}
+			return results[i].Model < results[j].Model
 		})
 
 		return c.JSON(results)
 	}
 }
 
+// availableNetworks returns the networks of the database with online workers, by number of clusters
+func availableNetworks(db *explorer.Database, ds *explorer.DiscoveryServer) []Network {
+	networkState := ds.NetworkState()
+	results := []Network{}
+	for token, network := range networkState.Networks {
+		networkData, exists := db.Get(token) // get the token data
+		hasWorkers := false
+		for _, cluster := range network.Clusters {
+			if len(cluster.Workers) > 0 {
+				hasWorkers = true
+				break
+			}
+		}
+		if exists && hasWorkers {
+			results = append(results, Network{Network: network, TokenData: networkData, Token: token})
+		}
+	}
+
+	// order by number of clusters
+	sort.Slice(results, func(i, j int) bool {
+		return len(results[i].Clusters) > len(results[j].Clusters)
+	})
+	return results
+}
+
 func AddNetwork(db *explorer.Database) func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		request := new(AddNetworkRequest)
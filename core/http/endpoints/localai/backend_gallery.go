@@ -0,0 +1,113 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+)
+
+type BackendGalleryEndpointService struct {
+	galleries      []config.Gallery
+	assetDir       string
+	galleryApplier *services.GalleryService
+}
+
+type GalleryBackend struct {
+	ID string `json:"id"`
+}
+
+func CreateBackendGalleryEndpointService(galleries []config.Gallery, assetDir string, galleryApplier *services.GalleryService) BackendGalleryEndpointService {
+	return BackendGalleryEndpointService{
+		galleries:      galleries,
+		assetDir:       assetDir,
+		galleryApplier: galleryApplier,
+	}
+}
+
+// ApplyBackendEndpoint installs a backend from the backend galleries, or upgrades it if already installed
+// @Summary Install or upgrade a backend at runtime. The job status is returned by /models/jobs/{uuid}.
+// @Param request body GalleryBackend true "query params"
+// @Success 200 {object} schema.GalleryResponse "Response"
+// @Router /backends/apply [post]
+func (bgs *BackendGalleryEndpointService) ApplyBackendEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(GalleryBackend)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.ID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "the id of the backend is required")
+		}
+
+		uuid, err := uuid.NewUUID()
+		if err != nil {
+			return err
+		}
+		bgs.galleryApplier.C <- gallery.GalleryOp{
+			Id:                 uuid.String(),
+			GalleryBackendName: input.ID,
+			Galleries:          bgs.galleries,
+			Owner:              fiberContext.Owner(c),
+		}
+		return c.JSON(schema.GalleryResponse{ID: uuid.String(), StatusURL: c.BaseURL() + "/models/jobs/" + uuid.String()})
+	}
+}
+
+// DeleteBackendEndpoint removes an installed backend
+// @Summary Delete a backend installed at runtime.
+// @Param name	path string	true	"Backend name"
+// @Success 200 {object} schema.GalleryResponse "Response"
+// @Router /backends/delete/{name} [post]
+func (bgs *BackendGalleryEndpointService) DeleteBackendEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		uuid, err := uuid.NewUUID()
+		if err != nil {
+			return err
+		}
+		bgs.galleryApplier.C <- gallery.GalleryOp{
+			Id:                 uuid.String(),
+			Delete:             true,
+			GalleryBackendName: c.Params("name"),
+			Owner:              fiberContext.Owner(c),
+		}
+		return c.JSON(schema.GalleryResponse{ID: uuid.String(), StatusURL: c.BaseURL() + "/models/jobs/" + uuid.String()})
+	}
+}
+
+// ListBackendsEndpoint lists the backends installed at runtime
+// @Summary List the backends installed at runtime, with their versions.
+// @Success 200 {object} []gallery.InstalledBackend "Response"
+// @Router /backends [get]
+func (bgs *BackendGalleryEndpointService) ListBackendsEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		backends, err := gallery.InstalledBackends(bgs.assetDir)
+		if err != nil {
+			return err
+		}
+		if backends == nil {
+			backends = []gallery.InstalledBackend{}
+		}
+		return c.JSON(backends)
+	}
+}
+
+// ListAvailableBackendsEndpoint lists the backends of the backend galleries
+// @Summary List the backends that can be installed, with the version installed if any.
+// @Success 200 {object} []gallery.GalleryBackend "Response"
+// @Router /backends/available [get]
+func (bgs *BackendGalleryEndpointService) ListAvailableBackendsEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		backends, err := gallery.AvailableGalleryBackends(bgs.galleries, bgs.assetDir)
+		if err != nil {
+			return err
+		}
+		if backends == nil {
+			backends = []*gallery.GalleryBackend{}
+		}
+		return c.JSON(backends)
+	}
+}
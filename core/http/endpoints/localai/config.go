@@ -0,0 +1,129 @@
+package localai
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/secrets"
+)
+
+// ValidateConfigEndpoint checks the configurations of all the models
+// @Summary	Reports all the errors in the configurations of the models: files that cannot be parsed, missing model or template files, templates that do not compile and unknown backends.
+// @Success 200 {object} schema.ConfigValidationResponse "Response"
+// @Router /config/validate [get]
+func ValidateConfigEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		backends := model.KnownBackends(appConfig.AssetsDestination, appConfig.ExternalGRPCBackends)
+		configErrors, err := config.CheckBackendConfigsFromPath(appConfig.ModelPath, backends, appConfig.ToConfigLoaderOptions()...)
+		if err != nil {
+			return err
+		}
+
+		res := schema.ConfigValidationResponse{
+			Valid:  len(configErrors) == 0,
+			Errors: []schema.ConfigValidationError{},
+		}
+		for _, e := range configErrors {
+			res.Errors = append(res.Errors, schema.ConfigValidationError{File: e.File, Error: e.Err.Error()})
+		}
+		return c.JSON(res)
+	}
+}
+
+// ConfigSchemaEndpoint returns the JSON schema of the configurations of the models
+// @Summary	Returns the JSON schema of the YAML configurations of the models, with all their fields, for the editors to complete and check the files.
+// @Success 200 {object} map[string]interface{} "Response"
+// @Router /config/schema [get]
+func ConfigSchemaEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(config.BackendConfigJSONSchema(), "application/schema+json")
+	}
+}
+
+// EffectiveConfigEndpoint returns the runtime settings the instance runs with
+// @Summary	Returns the runtime settings resolved from the defaults, the settings file, the environment variables, the flags and the dynamic configuration directory, with the source of each of them.
+// @Success 200 {object} schema.EffectiveConfigResponse "Response"
+// @Router /config/effective [get]
+func EffectiveConfigEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		env := appConfig.ToEnvironment()
+		names := make([]string, 0, len(env))
+		for name := range env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		res := schema.EffectiveConfigResponse{Settings: []schema.EffectiveSetting{}}
+		for _, name := range names {
+			res.Settings = append(res.Settings, schema.EffectiveSetting{
+				Name:   name,
+				Value:  secrets.Redact(env[name]),
+				Source: appConfig.SettingSource(name),
+			})
+		}
+		return c.JSON(res)
+	}
+}
+
+// ExportConfigEndpoint exports the configuration of the instance
+// @Summary	Exports the runtime settings, the configurations of the models and the models installed from the galleries as a tar.gz archive, to import on another instance.
+// @Produce application/gzip
+// @Success 200 {file} binary "Response"
+// @Router /config/export [get]
+func ExportConfigEndpoint(cl *config.BackendConfigLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		var buf bytes.Buffer
+		if err := services.ExportConfigArchive(&buf, cl, appConfig); err != nil {
+			return err
+		}
+		c.Attachment("localai-config.tar.gz")
+		c.Set(fiber.HeaderContentType, "application/gzip")
+		return c.Send(buf.Bytes())
+	}
+}
+
+// ImportConfigEndpoint imports the configuration exported by another instance
+// @Summary	Imports an archive from /config/export: the models are installed, downloading the files of the ones installed from the galleries. The runtime settings are not applied, as they are read on startup.
+// @Param file formData file true "archive"
+// @Success 200 {object} schema.GalleryResponse "Response"
+// @Router /config/import [post]
+func ImportConfigEndpoint(galleryService *services.GalleryService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		// The archive is either uploaded as a file, or sent as the body
+		archive := c.Body()
+		if file, err := c.FormFile("file"); err == nil {
+			f, err := file.Open()
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if archive, err = io.ReadAll(f); err != nil {
+				return err
+			}
+		}
+		if len(archive) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "archive is required")
+		}
+
+		uuid, err := uuid.NewUUID()
+		if err != nil {
+			return err
+		}
+		galleryService.C <- gallery.GalleryOp{
+			Id: uuid.String(),
+			// The body is reused by fiber once the request is handled
+			ConfigArchive: bytes.Clone(archive),
+			Owner:         fiberContext.Owner(c),
+		}
+		return c.JSON(schema.GalleryResponse{ID: uuid.String(), StatusURL: c.BaseURL() + "/models/jobs/" + uuid.String()})
+	}
+}
@@ -0,0 +1,101 @@
+package localai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
+	"github.com/valyala/fasthttp"
+)
+
+// dashboardInterval is the interval between the states sent by the stream of the dashboard
+const dashboardInterval = 2 * time.Second
+
+// DashboardEndpoint returns the state of the instance shown in the dashboard
+// @Summary	Returns the loaded models, the requests running and waiting for a slot, the memory of the GPUs and the last errors.
+// @Success 200 {object} schema.DashboardResponse "Response"
+// @Router /api/dashboard [get]
+func DashboardEndpoint(ml *model.ModelLoader, generations *services.GenerationService, recentErrors *services.RecentErrors) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(dashboardState(ml, generations, recentErrors))
+	}
+}
+
+// DashboardStreamEndpoint sends the state of the instance as server-sent events, every two seconds
+// @Summary	Streams the state of the instance shown in the dashboard.
+// @Success 200 {object} schema.DashboardResponse "Response"
+// @Router /api/dashboard/stream [get]
+func DashboardStreamEndpoint(ml *model.ModelLoader, generations *services.GenerationService, recentErrors *services.RecentErrors) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		c.Context().SetContentType("text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("Transfer-Encoding", "chunked")
+
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			ticker := time.NewTicker(dashboardInterval)
+			defer ticker.Stop()
+			for {
+				dat, err := json.Marshal(dashboardState(ml, generations, recentErrors))
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "data: %s\n\n", dat)
+				// the client is gone once the events can not be sent
+				if err := w.Flush(); err != nil {
+					return
+				}
+				<-ticker.C
+			}
+		}))
+		return nil
+	}
+}
+
+func dashboardState(ml *model.ModelLoader, generations *services.GenerationService, recentErrors *services.RecentErrors) schema.DashboardResponse {
+	res := schema.DashboardResponse{
+		Timestamp:   time.Now(),
+		Models:      []schema.DashboardModel{},
+		Generations: generations.All(),
+		GPUs:        []schema.DashboardGPU{},
+		Errors:      recentErrors.List(),
+	}
+
+	lastUsed := map[string]time.Time{}
+	for _, s := range ml.Stats() {
+		lastUsed[s.Model] = s.LastUsed
+	}
+	slots := map[string]model.ModelSlots{}
+	for _, s := range ml.SlotsUsage() {
+		slots[s.Model] = s
+		res.InFlight += s.Busy
+		res.Queued += s.Waiting
+	}
+	for _, name := range ml.LoadedModels() {
+		m := schema.DashboardModel{
+			Name:    name,
+			Slots:   slots[name].Size,
+			Busy:    slots[name].Busy,
+			Waiting: slots[name].Waiting,
+		}
+		if t, ok := lastUsed[name]; ok && !t.IsZero() {
+			m.LastUsed = &t
+		}
+		res.Models = append(res.Models, m)
+	}
+
+	// The memory of the GPUs is only known with nvidia-smi
+	if gpus, err := xsysinfo.GPUsMemory(); err == nil {
+		for _, g := range gpus {
+			res.GPUs = append(res.GPUs, schema.DashboardGPU{Index: g.Index, Total: g.Total, Used: g.Total - g.Free})
+		}
+	}
+
+	return res
+}
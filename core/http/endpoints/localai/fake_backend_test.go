@@ -0,0 +1,108 @@
+package localai_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/base"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeBackend is an embedded backend recording the requests it receives
+type fakeBackend struct {
+	base.Base
+	detect   *pb.DetectRequest
+	classify *pb.ClassifyRequest
+	rerank   *pb.RerankRequest
+	text     *pb.TextClassifyRequest
+	entities *pb.EntitiesRequest
+	// image is the content of the image of the last request, removed once it is answered
+	image []byte
+}
+
+func (f *fakeBackend) Load(*pb.ModelOptions) error {
+	return nil
+}
+
+func (f *fakeBackend) Detect(req *pb.DetectRequest) (pb.DetectResponse, error) {
+	f.detect = req
+	f.image, _ = os.ReadFile(req.Src)
+	return pb.DetectResponse{Detections: []*pb.Detection{{Label: "cat", Score: 0.9, X: 1, Y: 2, Width: 3, Height: 4}}}, nil
+}
+
+func (f *fakeBackend) Classify(req *pb.ClassifyRequest) (pb.ClassifyResponse, error) {
+	f.classify = req
+	f.image, _ = os.ReadFile(req.Src)
+	return pb.ClassifyResponse{Classifications: []*pb.Classification{{Label: "cat", Score: 0.8}, {Label: "dog", Score: 0.2}}}, nil
+}
+
+func (f *fakeBackend) Rerank(req *pb.RerankRequest) (pb.RerankResult, error) {
+	f.rerank = req
+	return pb.RerankResult{Results: []*pb.DocumentResult{{Index: 1, RelevanceScore: 0.9}, {Index: 0, RelevanceScore: 0.2}}}, nil
+}
+
+func (f *fakeBackend) ClassifyText(req *pb.TextClassifyRequest) (pb.ClassifyResponse, error) {
+	f.text = req
+	return pb.ClassifyResponse{Classifications: []*pb.Classification{{Label: "positive", Score: 0.95}}}, nil
+}
+
+func (f *fakeBackend) ExtractEntities(req *pb.EntitiesRequest) (pb.EntitiesResponse, error) {
+	f.entities = req
+	return pb.EntitiesResponse{Entities: []*pb.Entity{{Label: "PER", Text: "Ada", Score: 0.99, Start: 0, End: 3}}}, nil
+}
+
+// endpoint is the state an endpoint is created with
+type endpoint struct {
+	cl        *config.BackendConfigLoader
+	ml        *model.ModelLoader
+	appConfig *config.ApplicationConfig
+}
+
+// newEndpoint serves the fake backend under the given name, as the backend of the model
+func newEndpoint(backend, modelName string, llm grpc.LLM) endpoint {
+	grpc.Provide(backend, llm)
+
+	dir := GinkgoT().TempDir()
+	Expect(os.WriteFile(filepath.Join(dir, modelName+".yaml"),
+		[]byte("name: "+modelName+"\nbackend: "+backend+"\nparameters:\n  model: "+modelName+"\n"), 0644)).To(Succeed())
+	cl := config.NewBackendConfigLoader(dir)
+	Expect(cl.LoadBackendConfigsFromPath(dir)).To(Succeed())
+
+	appConfig := config.NewApplicationConfig(
+		config.WithModelPath(dir),
+		config.WithExternalBackend(backend, backend),
+	)
+	return endpoint{cl: cl, ml: model.NewModelLoader(dir), appConfig: appConfig}
+}
+
+// post sends the JSON body to the handler, and returns the status and the body of the response
+func post(handler fiber.Handler, body any) (int, []byte) {
+	dat, err := json.Marshal(body)
+	Expect(err).ToNot(HaveOccurred())
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(dat))
+	Expect(err).ToNot(HaveOccurred())
+	req.Header.Set("Content-Type", "application/json")
+	return send(handler, req)
+}
+
+func send(handler fiber.Handler, req *http.Request) (int, []byte) {
+	app := fiber.New()
+	app.Add(req.Method, "/", handler)
+	res, err := app.Test(req, -1)
+	Expect(err).ToNot(HaveOccurred())
+	defer res.Body.Close()
+	dat, err := io.ReadAll(res.Body)
+	Expect(err).ToNot(HaveOccurred())
+	return res.StatusCode, dat
+}
@@ -1,21 +1,32 @@
 package localai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/core/gallery"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/downloader"
 	"github.com/rs/zerolog/log"
 )
 
+// galleryFileSizeTimeout bounds the requests getting the sizes of the files of a model
+const galleryFileSizeTimeout = 10 * time.Second
+
+// ModelGalleryEndpointService serves the galleries of the application config, which galleries.json of the
+// dynamic configuration directory changes at runtime
 type ModelGalleryEndpointService struct {
-	galleries      []config.Gallery
+	appConfig      *config.ApplicationConfig
 	modelPath      string
 	galleryApplier *services.GalleryService
 }
@@ -26,10 +37,10 @@ type GalleryModel struct {
 	gallery.GalleryModel
 }
 
-func CreateModelGalleryEndpointService(galleries []config.Gallery, modelPath string, galleryApplier *services.GalleryService) ModelGalleryEndpointService {
+func CreateModelGalleryEndpointService(appConfig *config.ApplicationConfig, galleryApplier *services.GalleryService) ModelGalleryEndpointService {
 	return ModelGalleryEndpointService{
-		galleries:      galleries,
-		modelPath:      modelPath,
+		appConfig:      appConfig,
+		modelPath:      appConfig.ModelPath,
 		galleryApplier: galleryApplier,
 	}
 }
@@ -75,12 +86,15 @@ func (mgs *ModelGalleryEndpointService) ApplyModelGalleryEndpoint() func(c *fibe
 		if err != nil {
 			return err
 		}
+		// the job can be followed as soon as it is returned, while it waits to be processed
+		mgs.galleryApplier.UpdateStatus(uuid.String(), &gallery.GalleryOpStatus{Message: "queued"})
 		mgs.galleryApplier.C <- gallery.GalleryOp{
 			Req:              input.GalleryModel,
 			Id:               uuid.String(),
 			GalleryModelName: input.ID,
-			Galleries:        mgs.galleries,
+			Galleries:        mgs.appConfig.Galleries,
 			ConfigURL:        input.ConfigURL,
+			Owner:            fiberContext.Owner(c),
 		}
 		return c.JSON(schema.GalleryResponse{ID: uuid.String(), StatusURL: c.BaseURL() + "/models/jobs/" + uuid.String()})
 	}
@@ -95,16 +109,19 @@ func (mgs *ModelGalleryEndpointService) DeleteModelGalleryEndpoint() func(c *fib
 	return func(c *fiber.Ctx) error {
 		modelName := c.Params("name")
 
-		mgs.galleryApplier.C <- gallery.GalleryOp{
-			Delete:           true,
-			GalleryModelName: modelName,
-		}
-
 		uuid, err := uuid.NewUUID()
 		if err != nil {
 			return err
 		}
 
+		mgs.galleryApplier.UpdateStatus(uuid.String(), &gallery.GalleryOpStatus{Message: "queued", Deletion: true})
+		mgs.galleryApplier.C <- gallery.GalleryOp{
+			Id:               uuid.String(),
+			Delete:           true,
+			GalleryModelName: modelName,
+			Owner:            fiberContext.Owner(c),
+		}
+
 		return c.JSON(schema.GalleryResponse{ID: uuid.String(), StatusURL: c.BaseURL() + "/models/jobs/" + uuid.String()})
 	}
 }
@@ -115,9 +132,9 @@ func (mgs *ModelGalleryEndpointService) DeleteModelGalleryEndpoint() func(c *fib
 // @Router /models/available [get]
 func (mgs *ModelGalleryEndpointService) ListModelFromGalleryEndpoint() func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		log.Debug().Msgf("Listing models from galleries: %+v", mgs.galleries)
+		log.Debug().Msgf("Listing models from galleries: %+v", mgs.appConfig.Galleries)
 
-		models, err := gallery.AvailableGalleryModels(mgs.galleries, mgs.modelPath)
+		models, err := gallery.AvailableGalleryModels(mgs.appConfig.Galleries, mgs.modelPath)
 		if err != nil {
 			return err
 		}
@@ -133,6 +150,88 @@ func (mgs *ModelGalleryEndpointService) ListModelFromGalleryEndpoint() func(c *f
 	}
 }
 
+// GetModelFromGalleryEndpoint returns the details of a model of the galleries
+// @Summary Returns the details of an installable model: its license, and the files it downloads with their size and quantization.
+// @Param id path string true "Model ID, as gallery@name"
+// @Success 200 {object} schema.GalleryModelDetails "Response"
+// @Router /models/available/{id} [get]
+func (mgs *ModelGalleryEndpointService) GetModelFromGalleryEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		id, err := url.PathUnescape(c.Params("id"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		models, err := gallery.AvailableGalleryModels(mgs.appConfig.Galleries, mgs.modelPath)
+		if err != nil {
+			return err
+		}
+		m := gallery.FindModel(models, id, mgs.modelPath)
+		if m == nil {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("model %q not found in the galleries", id))
+		}
+
+		details := schema.GalleryModelDetails{
+			ID:           m.ID(),
+			Name:         m.Name,
+			Description:  m.Description,
+			License:      m.License,
+			URLs:         m.URLs,
+			Icon:         m.Icon,
+			Tags:         m.Tags,
+			Installed:    m.Installed,
+			LicenseGated: gallery.LicenseGated(m),
+			Files:        []schema.GalleryModelFile{},
+		}
+
+		files := m.AdditionalFiles
+		if m.URL != "" {
+			// the files of the base configuration are downloaded too
+			if cfg, err := gallery.GetGalleryConfigFromURL(m.URL, mgs.modelPath); err == nil {
+				files = append(slices.Clone(cfg.Files), files...)
+				if details.License == "" {
+					details.License = cfg.License
+				}
+			} else {
+				log.Debug().Err(err).Msgf("could not read the configuration of %s", m.Name)
+			}
+		}
+		for _, f := range files {
+			details.Files = append(details.Files, schema.GalleryModelFile{
+				Filename:     f.Filename,
+				URI:          f.URI,
+				Quantization: gallery.Quantization(f.Filename),
+				Size:         f.Size,
+			})
+		}
+
+		// the sizes are best effort: the files not in the index nor reported by their server have no size
+		ctx, cancel := context.WithTimeout(c.Context(), galleryFileSizeTimeout)
+		defer cancel()
+		var wg sync.WaitGroup
+		for i := range details.Files {
+			if details.Files[i].Size > 0 {
+				continue
+			}
+			wg.Add(1)
+			go func(f *schema.GalleryModelFile) {
+				defer wg.Done()
+				size, err := downloader.URI(f.URI).ContentLength(ctx)
+				if err != nil {
+					log.Debug().Err(err).Msgf("could not get the size of %s", f.Filename)
+					return
+				}
+				f.Size = size
+			}(&details.Files[i])
+		}
+		wg.Wait()
+		for _, f := range details.Files {
+			details.Size += f.Size
+		}
+
+		return c.JSON(details)
+	}
+}
+
 // ListModelGalleriesEndpoint list the available galleries configured in LocalAI
 // @Summary List all Galleries
 // @Success 200 {object} []config.Gallery "Response"
@@ -140,8 +239,8 @@ func (mgs *ModelGalleryEndpointService) ListModelFromGalleryEndpoint() func(c *f
 // NOTE: This is different (and much simpler!) than above! This JUST lists the model galleries that have been loaded, not their contents!
 func (mgs *ModelGalleryEndpointService) ListModelGalleriesEndpoint() func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		log.Debug().Msgf("Listing model galleries %+v", mgs.galleries)
-		dat, err := json.Marshal(mgs.galleries)
+		log.Debug().Msgf("Listing model galleries %+v", mgs.appConfig.Galleries)
+		dat, err := json.Marshal(mgs.appConfig.Galleries)
 		if err != nil {
 			return err
 		}
@@ -161,17 +260,17 @@ func (mgs *ModelGalleryEndpointService) AddModelGalleryEndpoint() func(c *fiber.
 		if err := c.BodyParser(input); err != nil {
 			return err
 		}
-		if slices.ContainsFunc(mgs.galleries, func(gallery config.Gallery) bool {
+		if slices.ContainsFunc(mgs.appConfig.Galleries, func(gallery config.Gallery) bool {
 			return gallery.Name == input.Name
 		}) {
 			return fmt.Errorf("%s already exists", input.Name)
 		}
-		dat, err := json.Marshal(mgs.galleries)
+		dat, err := json.Marshal(mgs.appConfig.Galleries)
 		if err != nil {
 			return err
 		}
 		log.Debug().Msgf("Adding %+v to gallery list", *input)
-		mgs.galleries = append(mgs.galleries, *input)
+		mgs.appConfig.Galleries = append(mgs.appConfig.Galleries, *input)
 		return c.Send(dat)
 	}
 }
@@ -188,15 +287,15 @@ func (mgs *ModelGalleryEndpointService) RemoveModelGalleryEndpoint() func(c *fib
 		if err := c.BodyParser(input); err != nil {
 			return err
 		}
-		if !slices.ContainsFunc(mgs.galleries, func(gallery config.Gallery) bool {
+		if !slices.ContainsFunc(mgs.appConfig.Galleries, func(gallery config.Gallery) bool {
 			return gallery.Name == input.Name
 		}) {
 			return fmt.Errorf("%s is not currently registered", input.Name)
 		}
-		mgs.galleries = slices.DeleteFunc(mgs.galleries, func(gallery config.Gallery) bool {
+		mgs.appConfig.Galleries = slices.DeleteFunc(mgs.appConfig.Galleries, func(gallery config.Gallery) bool {
 			return gallery.Name == input.Name
 		})
-		dat, err := json.Marshal(mgs.galleries)
+		dat, err := json.Marshal(mgs.appConfig.Galleries)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,63 @@
+package localai_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/mudler/LocalAI/core/config"
+	. "github.com/mudler/LocalAI/core/http/endpoints/localai"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Model galleries", func() {
+	var (
+		localai   = config.Gallery{Name: "localai", URL: "github:mudler/LocalAI/gallery/index.yaml@master"}
+		team      = config.Gallery{Name: "team", URL: "https://example.com/index.yaml"}
+		appConfig *config.ApplicationConfig
+		mgs       ModelGalleryEndpointService
+	)
+
+	galleries := func() []config.Gallery {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		status, body := send(mgs.ListModelGalleriesEndpoint(), req)
+		Expect(status).To(Equal(http.StatusOK))
+		var list []config.Gallery
+		Expect(json.Unmarshal(body, &list)).To(Succeed())
+		return list
+	}
+
+	BeforeEach(func() {
+		appConfig = &config.ApplicationConfig{ModelPath: GinkgoT().TempDir(), Galleries: []config.Gallery{localai}}
+		mgs = CreateModelGalleryEndpointService(appConfig, nil)
+	})
+
+	It("lists the galleries reloaded at runtime", func() {
+		Expect(galleries()).To(Equal([]config.Gallery{localai}))
+
+		appConfig.Galleries = []config.Gallery{localai, team}
+		Expect(galleries()).To(Equal([]config.Gallery{localai, team}))
+	})
+
+	It("adds and removes the galleries of the application config", func() {
+		status, _ := post(mgs.AddModelGalleryEndpoint(), team)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{localai, team}))
+
+		status, _ = post(mgs.AddModelGalleryEndpoint(), team)
+		Expect(status).To(Equal(http.StatusInternalServerError))
+
+		dat, err := json.Marshal(localai)
+		Expect(err).ToNot(HaveOccurred())
+		req, err := http.NewRequest(http.MethodDelete, "/", bytes.NewReader(dat))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		status, _ = send(mgs.RemoveModelGalleryEndpoint(), req)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{team}))
+		Expect(galleries()).To(Equal([]config.Gallery{team}))
+	})
+})
@@ -0,0 +1,51 @@
+package localai
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+)
+
+// GenerationMiddleware makes the generations of the next handlers cancellable by the ID of their request.
+// The generations end when the handler returns, or when the streamed responses are finished
+func GenerationMiddleware(generations *services.GenerationService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		fiberContext.SetGenerations(c, generations)
+		err := c.Next()
+		if !c.Response().IsBodyStream() {
+			fiberContext.FinishGeneration(c)
+		}
+		return err
+	}
+}
+
+// ListGenerationsEndpoint lists the chat and completion requests being generated
+// @Summary Lists the chat and completion requests being generated, the oldest first.
+// @Success 200 {object} schema.GenerationList "Response"
+// @Router /v1/generations [get]
+func ListGenerationsEndpoint(generations *services.GenerationService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(schema.GenerationList{Object: "list", Data: generations.List(fiberContext.Owner(c))})
+	}
+}
+
+// CancelGenerationEndpoint stops a chat or completion request being generated
+// @Summary Stops a chat or completion request being generated. The streamed responses are finished, the others fail.
+// @Param id path string true "Request ID, returned in the X-Request-ID header"
+// @Success 200 {object} schema.Generation "Response"
+// @Router /v1/generations/{id}/cancel [post]
+func CancelGenerationEndpoint(generations *services.GenerationService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		generation, err := generations.Cancel(c.Params("id"), fiberContext.Owner(c))
+		if errors.Is(err, services.ErrGenerationNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		return c.JSON(generation)
+	}
+}
@@ -0,0 +1,27 @@
+package localai
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GoroutinesEndpoint dumps the stacks of the goroutines
+// @Summary	Dumps the stacks of all the goroutines, to find the ones leaked by the streaming requests or the loading of the models. With grouped=true the goroutines with the same stack are grouped, with their count.
+// @Param grouped query bool false "Group the goroutines by stack"
+// @Produce text/plain
+// @Success 200 {string} string "Response"
+// @Router /debug/goroutines [get]
+func GoroutinesEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		debug := 2
+		if c.QueryBool("grouped") {
+			debug = 1
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		fmt.Fprintf(c, "%d goroutines\n\n", runtime.NumGoroutine())
+		return pprof.Lookup("goroutine").WriteTo(c, debug)
+	}
+}
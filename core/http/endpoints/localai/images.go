@@ -0,0 +1,48 @@
+package localai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/storage"
+)
+
+// ListGeneratedImagesEndpoint lists the images generated by the instance
+// @Summary	Lists the images kept in the image directory with the parameters of their generation, the most recent first.
+// @Param limit query int false "maximum number of images (default 50)"
+// @Success 200 {object} schema.GeneratedImageList "Response"
+// @Router /api/images [get]
+func ListGeneratedImagesEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		images, err := services.ListGeneratedImages(appConfig.ImageDir, c.QueryInt("limit", 50))
+		if err != nil {
+			return err
+		}
+		for i := range images {
+			images[i].URL = c.BaseURL() + "/generated-images/" + images[i].Name
+		}
+		return c.JSON(schema.GeneratedImageList{Object: "list", Data: images})
+	}
+}
+
+// GeneratedFileEndpoint serves the generated files of a storage, decrypting them when it is encrypted
+func GeneratedFileEndpoint(st storage.Storage) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("*")
+		r, err := st.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return fiber.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c.Type(strings.TrimPrefix(filepath.Ext(name), "."))
+		return c.SendStream(r)
+	}
+}
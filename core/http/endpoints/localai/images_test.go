@@ -0,0 +1,40 @@
+package localai_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	. "github.com/mudler/LocalAI/core/http/endpoints/localai"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/provenance"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generated images", func() {
+	It("lists the images with their URL", func() {
+		appConfig := config.NewApplicationConfig(config.WithImageDir(GinkgoT().TempDir()))
+		for _, name := range []string{"cat.png", "dog.png"} {
+			file := filepath.Join(appConfig.ImageDir, name)
+			Expect(os.WriteFile(file, []byte("\x89PNG"), 0600)).To(Succeed())
+			Expect(services.SaveImageMetadata(file, provenance.Parameters{Prompt: name, Model: "sd"})).To(Succeed())
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "http://localai.io/?limit=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		code, body := send(ListGeneratedImagesEndpoint(appConfig), req)
+		Expect(code).To(Equal(http.StatusOK), string(body))
+
+		res := schema.GeneratedImageList{}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res.Object).To(Equal("list"))
+		Expect(res.Data).To(HaveLen(1))
+		Expect(res.Data[0].URL).To(Equal("http://localai.io/generated-images/" + res.Data[0].Name))
+		Expect(res.Data[0].Parameters.Prompt).To(Equal(res.Data[0].Name))
+	})
+})
@@ -0,0 +1,219 @@
+package localai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/valyala/fasthttp"
+)
+
+// jobEventsInterval is the interval between the checks of the status of the jobs streamed as events
+const jobEventsInterval = 500 * time.Millisecond
+
+// AsyncJobMiddleware runs the requests with the header "Prefer: respond-async" as jobs: the request is
+// answered right away with the job, and is run in the background by the next handlers of its route. The
+// handlers are cancelled with the job, and report their progress to it
+func AsyncJobMiddleware(jobs *services.JobService, jobType string) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if !strings.Contains(c.Get("Prefer"), "respond-async") {
+			return c.Next()
+		}
+
+		// The request is copied, as the context of fiber is reused once the handler returns
+		req := &fasthttp.Request{}
+		c.Request().CopyTo(req)
+		req.Header.Del("Prefer")
+		remoteAddr := c.Context().RemoteAddr()
+		handler := c.App().Handler()
+
+		job := jobs.Submit(jobType, fiberContext.Owner(c), func(ctx context.Context, progress func(float64, string)) (*services.JobResult, error) {
+			reqCtx := &fasthttp.RequestCtx{}
+			reqCtx.Init(req, remoteAddr, nil)
+			fiberContext.SetJob(reqCtx, ctx, progress)
+			handler(reqCtx)
+
+			resp := &reqCtx.Response
+			if resp.StatusCode() >= 400 {
+				return nil, errors.New(jobErrorMessage(resp))
+			}
+			return &services.JobResult{
+				StatusCode:  resp.StatusCode(),
+				ContentType: string(resp.Header.ContentType()),
+				Body:        append([]byte(nil), resp.Body()...),
+			}, nil
+		})
+
+		c.Location("/v1/jobs/" + job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
+
+// jobErrorMessage returns the message of the error responses of the API, or their body
+func jobErrorMessage(resp *fasthttp.Response) string {
+	var e schema.ErrorResponse
+	if err := json.Unmarshal(resp.Body(), &e); err == nil && e.Error != nil && e.Error.Message != "" {
+		return e.Error.Message
+	}
+	if body := strings.TrimSpace(string(resp.Body())); body != "" {
+		return body
+	}
+	return fmt.Sprintf("request failed with status %d", resp.StatusCode())
+}
+
+// withResultURL sets the URL of the result of the completed jobs
+func withResultURL(baseURL string, job schema.Job) schema.Job {
+	if job.Status == schema.JobStatusCompleted && job.Type != "gallery" {
+		job.ResultURL = baseURL + "/v1/jobs/" + job.ID + "/result"
+	}
+	return job
+}
+
+// ListJobsEndpoint lists the jobs of the API key, and the operations of the galleries
+// @Summary Lists the jobs run in the background: the async requests of the API key, and the installs and deletions of the galleries.
+// @Success 200 {object} schema.JobList "Response"
+// @Router /v1/jobs [get]
+func ListJobsEndpoint(jobs *services.JobService, galleryService *services.GalleryService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		list := schema.JobList{Object: "list", Data: []schema.Job{}}
+		for _, job := range jobs.List(fiberContext.Owner(c)) {
+			list.Data = append(list.Data, withResultURL(c.BaseURL(), job))
+		}
+		for id, op := range galleryService.GetAllOwnerStatus(fiberContext.Owner(c)) {
+			list.Data = append(list.Data, services.GalleryJob(id, op))
+		}
+		return c.JSON(list)
+	}
+}
+
+// GetJobEndpoint returns the status of a job
+// @Summary Returns the status and the progress of a job.
+// @Param id path string true "Job ID"
+// @Success 200 {object} schema.Job "Response"
+// @Router /v1/jobs/{id} [get]
+func GetJobEndpoint(jobs *services.JobService, galleryService *services.GalleryService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		job, err := jobs.Get(id, fiberContext.Owner(c))
+		if err != nil {
+			if op := galleryService.GetOwnerStatus(id, fiberContext.Owner(c)); op != nil {
+				return c.JSON(services.GalleryJob(id, op))
+			}
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return c.JSON(withResultURL(c.BaseURL(), job))
+	}
+}
+
+// JobEventsEndpoint streams the status of a job as server-sent events, until the job is finished
+// @Summary Streams the status and the progress of a job, as the downloads of the gallery installs, until it is finished.
+// @Param id path string true "Job ID"
+// @Success 200 {object} schema.Job "Response"
+// @Router /v1/jobs/{id}/events [get]
+func JobEventsEndpoint(jobs *services.JobService, galleryService *services.GalleryService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		owner := fiberContext.Owner(c)
+		baseURL := c.BaseURL()
+		status := func() (schema.Job, bool) {
+			if job, err := jobs.Get(id, owner); err == nil {
+				return withResultURL(baseURL, job), true
+			}
+			if op := galleryService.GetOwnerStatus(id, owner); op != nil {
+				return services.GalleryJob(id, op), true
+			}
+			return schema.Job{}, false
+		}
+		if _, ok := status(); !ok {
+			return fiber.NewError(fiber.StatusNotFound, services.ErrJobNotFound.Error())
+		}
+
+		c.Context().SetContentType("text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("Transfer-Encoding", "chunked")
+
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			ticker := time.NewTicker(jobEventsInterval)
+			defer ticker.Stop()
+			var last []byte
+			for {
+				job, ok := status()
+				if !ok {
+					return
+				}
+				dat, err := json.Marshal(job)
+				if err != nil {
+					return
+				}
+				// only the changes of the job are sent
+				if !bytes.Equal(dat, last) {
+					fmt.Fprintf(w, "data: %s\n\n", dat)
+					// the client is gone once the events can not be sent
+					if err := w.Flush(); err != nil {
+						return
+					}
+					last = dat
+				}
+				if job.Status != schema.JobStatusRunning {
+					return
+				}
+				<-ticker.C
+			}
+		}))
+		return nil
+	}
+}
+
+// GetJobResultEndpoint returns the response of the request of a completed job
+// @Summary Returns the response of the request of a completed job, as it would have been returned synchronously.
+// @Param id path string true "Job ID"
+// @Router /v1/jobs/{id}/result [get]
+func GetJobResultEndpoint(jobs *services.JobService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		result, err := jobs.Result(c.Params("id"), fiberContext.Owner(c))
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrJobNotFinished):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case err != nil:
+			return err
+		}
+		c.Set(fiber.HeaderContentType, result.ContentType)
+		return c.Status(result.StatusCode).Send(result.Body)
+	}
+}
+
+// CancelJobEndpoint cancels a running job
+// @Summary Cancels a running job. Its result is dropped. The jobs of the galleries cannot be cancelled.
+// @Param id path string true "Job ID"
+// @Success 200 {object} schema.Job "Response"
+// @Router /v1/jobs/{id} [delete]
+func CancelJobEndpoint(jobs *services.JobService, galleryService *services.GalleryService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		job, err := jobs.Cancel(id, fiberContext.Owner(c))
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			if galleryService.GetOwnerStatus(id, fiberContext.Owner(c)) != nil {
+				return fiber.NewError(fiber.StatusConflict, "the jobs of the galleries cannot be cancelled")
+			}
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrJobFinished):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case err != nil:
+			return err
+		}
+		return c.JSON(job)
+	}
+}
@@ -0,0 +1,37 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/pkg/logging"
+	"github.com/rs/zerolog/log"
+)
+
+// GetLogLevelEndpoint returns the settings of the logs
+// @Summary	Returns the level and the format of the logs, and the levels of the subsystems (http, grpc, p2p, gallery) logging at their own level.
+// @Success 200 {object} logging.Settings "Response"
+// @Router /admin/loglevel [get]
+func GetLogLevelEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(logging.Current())
+	}
+}
+
+// SetLogLevelEndpoint changes the settings of the logs
+// @Summary	Changes the level and the format of the logs without restarting. The fields which are not set are left unchanged, and the subsystems set to an empty level log again at the level of LocalAI. The API keys of the tenants cannot change them.
+// @Param request body logging.Settings true "query params"
+// @Success 200 {object} logging.Settings "Response"
+// @Router /admin/loglevel [post]
+func SetLogLevelEndpoint() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		var input logging.Settings
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if err := logging.Apply(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		settings := logging.Current()
+		log.Info().Str("level", settings.Level).Str("format", settings.Format).Interface("subsystems", settings.Subsystems).Msg("log settings changed")
+		return c.JSON(settings)
+	}
+}
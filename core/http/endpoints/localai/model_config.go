@@ -0,0 +1,143 @@
+package localai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// GetModelConfigEndpoint returns the YAML configuration of a model
+// @Summary Returns the YAML file of the configuration of a model, as it is in the models path.
+// @Param name path string true "Model name"
+// @Produce application/yaml
+// @Success 200 {string} string "Response"
+// @Router /models/config/{name} [get]
+func GetModelConfigEndpoint(cl *config.BackendConfigLoader) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		file, err := cl.BackendConfigFile(c.Params("name"))
+		if err != nil {
+			return modelConfigError(c, "", err)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, "application/yaml")
+		return c.Send(data)
+	}
+}
+
+// CreateModelConfigEndpoint creates the configuration of a model
+// @Summary Creates the configuration of a model from the YAML of the body, in the file <name>.yaml of the models path. The model is available right away.
+// @Param request body string true "YAML configuration"
+// @Success 201 {object} schema.ModelConfigResponse "Response"
+// @Failure 422 {object} schema.ConfigValidationResponse "Invalid configuration"
+// @Router /models/config [post]
+func CreateModelConfigEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return saveModelConfig(c, cl, ml, appConfig, "", true)
+	}
+}
+
+// UpdateModelConfigEndpoint replaces the configuration of a model
+// @Summary Replaces the configuration of a model with the YAML of the body, in its file of the models path. The model is unloaded, to be loaded with the new configuration by the next request.
+// @Param name path string true "Model name"
+// @Param request body string true "YAML configuration"
+// @Success 200 {object} schema.ModelConfigResponse "Response"
+// @Failure 422 {object} schema.ConfigValidationResponse "Invalid configuration"
+// @Router /models/config/{name} [put]
+func UpdateModelConfigEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return saveModelConfig(c, cl, ml, appConfig, c.Params("name"), false)
+	}
+}
+
+// DeleteModelConfigEndpoint deletes the configuration of a model
+// @Summary Deletes the configuration of a model and its file from the models path, and unloads the model. The files of the model are kept.
+// @Param name path string true "Model name"
+// @Success 200 {object} schema.ModelConfigResponse "Response"
+// @Router /models/config/{name} [delete]
+func DeleteModelConfigEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		file, err := cl.BackendConfigFile(name)
+		if err != nil {
+			return modelConfigError(c, "", err)
+		}
+		previous, err := cl.DeleteBackendConfigFile(name)
+		if err != nil {
+			return modelConfigError(c, "", err)
+		}
+		log.Info().Str("model", name).Msg("Model configuration deleted")
+		return c.JSON(schema.ModelConfigResponse{
+			Model:    name,
+			File:     filepath.Base(file),
+			Unloaded: unloadModelFile(ml, previous.Model),
+		})
+	}
+}
+
+func saveModelConfig(c *fiber.Ctx, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, name string, create bool) error {
+	if len(c.Body()) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "the YAML configuration is required")
+	}
+	previous, _ := cl.GetBackendConfig(name)
+
+	backends := model.KnownBackends(appConfig.AssetsDestination, appConfig.ExternalGRPCBackends)
+	cfg, err := cl.SaveBackendConfig(name, c.Body(), create, backends, appConfig.ToConfigLoaderOptions()...)
+	if err != nil {
+		return modelConfigError(c, name, err)
+	}
+	file, err := cl.BackendConfigFile(cfg.Name)
+	if err != nil {
+		return err
+	}
+
+	res := schema.ModelConfigResponse{Model: cfg.Name, File: filepath.Base(file)}
+	if create {
+		log.Info().Str("model", cfg.Name).Str("file", res.File).Msg("Model configuration created")
+		return c.Status(fiber.StatusCreated).JSON(res)
+	}
+	// The running model keeps the previous configuration until it is loaded again
+	res.Unloaded = unloadModelFile(ml, previous.Model)
+	log.Info().Str("model", cfg.Name).Str("file", res.File).Bool("unloaded", res.Unloaded).Msg("Model configuration updated")
+	return c.JSON(res)
+}
+
+// unloadModelFile unloads the model file once its running requests are done, and tells if it was loaded
+func unloadModelFile(ml *model.ModelLoader, modelFile string) bool {
+	if modelFile == "" || !ml.IsLoaded(modelFile) {
+		return false
+	}
+	ml.DrainModel(modelFile)
+	if err := ml.ShutdownModel(modelFile); err != nil {
+		log.Error().Err(err).Str("model", modelFile).Msg("error unloading the model")
+		return false
+	}
+	return true
+}
+
+// modelConfigError returns the errors of the configurations with their status, the validation errors
+// being reported as /config/validate does
+func modelConfigError(c *fiber.Ctx, name string, err error) error {
+	var invalid config.InvalidBackendConfigError
+	switch {
+	case errors.As(err, &invalid):
+		res := schema.ConfigValidationResponse{Errors: []schema.ConfigValidationError{}}
+		for _, e := range invalid.Errs {
+			res.Errors = append(res.Errors, schema.ConfigValidationError{File: name, Error: e.Error()})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(res)
+	case errors.Is(err, config.ErrBackendConfigNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, config.ErrBackendConfigExists):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return err
+}
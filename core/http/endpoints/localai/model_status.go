@@ -0,0 +1,29 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// ModelStatusEndpoint returns the progress of the download and of the loading of a model
+// @Summary Returns the progress of the download and of the loading of a model, with the percentage of each stage.
+// @Param name path string true "Model name"
+// @Success 200 {object} schema.ModelStatusResponse "Response"
+// @Router /models/{name}/status [get]
+func ModelStatusEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if tenant := fiberContext.TenantFromContext(c); tenant != nil && !tenant.CanUseModel(name) {
+			return fiber.NewError(fiber.StatusNotFound, "model "+name+" not found")
+		}
+
+		status, exists := services.GetModelStatus(cl, ml, name)
+		if !exists {
+			return fiber.NewError(fiber.StatusNotFound, "model "+name+" not found")
+		}
+		return c.JSON(status)
+	}
+}
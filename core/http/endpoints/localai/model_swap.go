@@ -0,0 +1,99 @@
+package localai
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// ModelSwapEndpoint swaps the model file of a model without downtime: the new version is loaded next to
+// the running one, the requests go to the new version once it is healthy, then the previous version is
+// unloaded once its running requests are done
+// @Summary Loads a new version of a model next to the running one, then shifts the requests to it and unloads the previous version.
+// @Param name path string true "Model name"
+// @Param request body schema.ModelSwapRequest true "query params"
+// @Success 200 {object} schema.ModelSwapResponse "Response"
+// @Router /models/{name}/swap [post]
+func ModelSwapEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	// The swaps run one at a time, as they both need the memory of two versions of a model
+	var swapping sync.Mutex
+	return func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if tenant := fiberContext.TenantFromContext(c); tenant != nil && !tenant.CanUseModel(name) {
+			return fiber.NewError(fiber.StatusNotFound, "model "+name+" not found")
+		}
+		input := new(schema.ModelSwapRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.Model == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "the model file of the new version is required")
+		}
+		if err := utils.VerifyPath(input.Model, appConfig.ModelPath); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if !ml.ExistsInModelPath(input.Model) {
+			return fiber.NewError(fiber.StatusBadRequest, "model file "+input.Model+" not found in the models path")
+		}
+
+		swapping.Lock()
+		defer swapping.Unlock()
+
+		cfg, exists := cl.GetBackendConfig(name)
+		if !exists {
+			return fiber.NewError(fiber.StatusNotFound, "model "+name+" not found")
+		}
+		if cfg.Model == input.Model {
+			return fiber.NewError(fiber.StatusBadRequest, "model "+name+" already uses "+input.Model)
+		}
+
+		// The running version serves the requests while the new one loads
+		next := cfg
+		next.Model = input.Model
+		log.Info().Str("model", name).Str("previous", cfg.Model).Str("current", next.Model).Msg("Loading the new version of the model")
+		err := backend.LoadModel(ml, next, appConfig)
+		if err == nil {
+			err = backend.ModelHealthCheck(c.Context(), ml, next, appConfig)
+		}
+		if err != nil {
+			if stopErr := ml.ShutdownModel(next.Model); stopErr != nil {
+				log.Debug().Err(stopErr).Str("model", name).Msg("the new version of the model is not loaded")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "failed loading the new version of model "+name+": "+err.Error())
+		}
+
+		// The new requests go to the new version
+		if err := cl.SetModelFile(name, next.Model); err != nil {
+			return err
+		}
+
+		resp := schema.ModelSwapResponse{Model: name, Previous: cfg.Model, Current: next.Model}
+		if ml.IsLoaded(cfg.Model) && !modelFileInUse(cl, cfg.Model) {
+			ml.DrainModel(cfg.Model)
+			if err := ml.ShutdownModel(cfg.Model); err != nil {
+				log.Error().Err(err).Str("model", name).Msg("error unloading the previous version of the model")
+			} else {
+				resp.Unloaded = true
+			}
+		}
+		log.Info().Str("model", name).Str("current", next.Model).Bool("unloaded", resp.Unloaded).Msg("Model swapped")
+		return c.JSON(resp)
+	}
+}
+
+// modelFileInUse tells if a model uses the model file
+func modelFileInUse(cl *config.BackendConfigLoader, modelFile string) bool {
+	for _, cfg := range cl.GetAllBackendConfigs() {
+		if cfg.Model == modelFile {
+			return true
+		}
+	}
+	return false
+}
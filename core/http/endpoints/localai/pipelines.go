@@ -0,0 +1,193 @@
+package localai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/sound"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+)
+
+// ListPipelinesEndpoint lists the pipelines of the pipelines file
+// @Summary	Lists the pipelines chaining models into a single endpoint.
+// @Success 200 {object} schema.PipelinesResponse "Response"
+// @Router /v1/pipelines [get]
+func ListPipelinesEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		response := schema.PipelinesResponse{Object: "list", Data: []schema.Pipeline{}}
+		for _, p := range appConfig.Pipelines {
+			pipeline := schema.Pipeline{Name: p.Name, Description: p.Description, Output: p.Output}
+			for _, s := range p.Steps {
+				pipeline.Steps = append(pipeline.Steps, schema.PipelineStep{Name: s.Name, Type: s.Type, Model: s.Model, Needs: s.Needs})
+			}
+			response.Data = append(response.Data, pipeline)
+		}
+		sort.Slice(response.Data, func(i, j int) bool { return response.Data[i].Name < response.Data[j].Name })
+		return c.JSON(response)
+	}
+}
+
+// PipelineEndpoint runs a pipeline of the pipelines file on the input of the request, and the audio uploaded as
+// file for the transcription steps. The pipelines whose output is a tts step return its audio
+// @Summary	Runs a pipeline chaining models, as transcription -> chat -> tts.
+// @Param name path string true "Pipeline name"
+// @Param request body schema.PipelineRequest true "query params"
+// @Success 200 {object} schema.PipelineResponse "Response"
+// @Router /v1/pipelines/{name} [post]
+func PipelineEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, sl *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		p, exists := appConfig.Pipelines[c.Params("name")]
+		if !exists {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("pipeline %s not found", c.Params("name")))
+		}
+
+		input := new(schema.PipelineRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+
+		outputStep := p.Steps[slices.IndexFunc(p.Steps, func(s config.PipelineStep) bool { return s.Name == p.Output })]
+		if input.Stream && outputStep.Type != config.PipelineChat {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("the output step %s of the pipeline is not a chat step, and can't be streamed", p.Output))
+		}
+
+		// the models are resolved first, so that a missing model fails the request before the steps run
+		configs := map[string]config.BackendConfig{}
+		for _, s := range p.Steps {
+			if _, resolved := configs[s.Model]; resolved {
+				continue
+			}
+			cfg, err := requiredModelConfig(c, cl, ml, appConfig, s.Model)
+			if err != nil {
+				return err
+			}
+			configs[s.Model] = *cfg
+		}
+
+		audio, err := pipelineAudio(c)
+		if err != nil {
+			return err
+		}
+		cleanup := func() {
+			if audio != "" {
+				os.RemoveAll(filepath.Dir(audio))
+			}
+		}
+		pipelineInput := backend.PipelineInput{Text: input.Input, Audio: audio}
+		recordUsage := fiberContext.TokenUsageRecorder(c)
+
+		if !input.Stream {
+			defer cleanup()
+			outputs, err := backend.RunPipeline(c.Context(), p, pipelineInput, configs, ml, sl, appConfig, nil)
+			recordUsage(backend.PipelineTokens(outputs))
+			if err != nil {
+				return err
+			}
+			if output := outputs[p.Output]; output.Audio != "" {
+				filePath, err := sound.Convert(output.Audio, input.ResponseFormat, input.SampleRate)
+				if err != nil {
+					return fiber.NewError(fiber.StatusBadRequest, err.Error())
+				}
+				if err := fiberContext.SendGeneratedFile(c, appConfig, filePath, output.Audio); err != nil {
+					return err
+				}
+				c.Set(fiber.HeaderContentType, sound.ContentType(input.ResponseFormat))
+				return nil
+			}
+			return c.JSON(pipelineResponse(p, outputs))
+		}
+
+		c.Context().SetContentType("text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("Transfer-Encoding", "chunked")
+
+		// the pipeline runs in the stream writer, after the handler returns
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			defer cleanup()
+			send := func(event schema.PipelineResponse) error {
+				dat, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "data: %s\n\n", dat); err != nil {
+					return err
+				}
+				return w.Flush()
+			}
+
+			outputs, err := backend.RunPipeline(appConfig.Context, p, pipelineInput, configs, ml, sl, appConfig, func(token string, _ backend.TokenUsage) bool {
+				// the client is gone once the tokens can not be sent
+				if err := send(schema.PipelineResponse{Pipeline: p.Name, Delta: token}); err != nil {
+					log.Debug().Err(err).Msg("Sending pipeline chunk failed")
+					return false
+				}
+				return true
+			})
+			recordUsage(backend.PipelineTokens(outputs))
+			if err != nil {
+				send(schema.PipelineResponse{Pipeline: p.Name, Error: err.Error()})
+			} else {
+				send(pipelineResponse(p, outputs))
+			}
+			w.WriteString("data: [DONE]\n\n")
+			w.Flush()
+		}))
+		return nil
+	}
+}
+
+func pipelineResponse(p config.Pipeline, outputs map[string]backend.PipelineOutput) schema.PipelineResponse {
+	response := schema.PipelineResponse{Pipeline: p.Name, Output: outputs[p.Output].Text, Steps: map[string]string{}}
+	for name, output := range outputs {
+		response.Steps[name] = output.Text
+	}
+	return response
+}
+
+// pipelineAudio saves the audio uploaded as file to a temporary directory, and returns its path, or an empty
+// path when there is no file
+func pipelineAudio(c *fiber.Ctx) (string, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return "", nil
+	}
+
+	dir, err := os.MkdirTemp("", "pipeline")
+	if err != nil {
+		return "", err
+	}
+	src, err := file.Open()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	defer src.Close()
+
+	dst := filepath.Join(dir, utils.SanitizeFileName(file.Filename))
+	f, err := os.Create(dst)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, src); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dst, nil
+}
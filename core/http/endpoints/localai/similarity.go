@@ -0,0 +1,56 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// SimilarityEndpoint scores the similarity of texts to a query, with the cosine of their embeddings or with a
+// cross-encoder, as a reranker
+// @Summary	Scores the similarity of texts to a query.
+// @Param request body schema.SimilarityRequest true "query params"
+// @Success 200 {object} schema.SimilarityResponse "Response"
+// @Router /v1/similarity [post]
+func SimilarityEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.SimilarityRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.Query == "" || len(input.Texts) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "query and texts are required")
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, false)
+		if err != nil {
+			return err
+		}
+		cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+		if err != nil {
+			return err
+		}
+
+		method := input.Method
+		if method == "" {
+			method = backend.DefaultSimilarityMethod(*cfg)
+		}
+		if method != backend.SimilarityCosine && method != backend.SimilarityCrossEncoder {
+			return fiber.NewError(fiber.StatusBadRequest, "method must be "+backend.SimilarityCosine+" or "+backend.SimilarityCrossEncoder)
+		}
+
+		scores, err := backend.Similarity(method, input.Query, input.Texts, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+
+		response := schema.SimilarityResponse{Model: input.Model, Method: method, Similarities: []schema.Similarity{}}
+		for i, text := range input.Texts {
+			response.Similarities = append(response.Similarities, schema.Similarity{Index: i, Text: text, Score: scores[i]})
+		}
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,54 @@
+package localai_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/mudler/LocalAI/core/http/endpoints/localai"
+	"github.com/mudler/LocalAI/core/schema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Similarity", func() {
+	It("scores the texts with the cross-encoder of the models without embeddings", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("similarity", "reranker", llm)
+
+		code, body := post(SimilarityEndpoint(e.cl, e.ml, e.appConfig), schema.SimilarityRequest{
+			Model: "reranker",
+			Query: "kitten",
+			Texts: []string{"a car", "a cat"},
+		})
+		Expect(code).To(Equal(http.StatusOK), string(body))
+
+		res := schema.SimilarityResponse{}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res).To(Equal(schema.SimilarityResponse{
+			Model:  "reranker",
+			Method: "cross-encoder",
+			Similarities: []schema.Similarity{
+				{Index: 0, Text: "a car", Score: 0.2},
+				{Index: 1, Text: "a cat", Score: 0.9},
+			},
+		}))
+		Expect(llm.rerank.Query).To(Equal("kitten"))
+		Expect(llm.rerank.TopN).To(Equal(int32(2)))
+	})
+
+	It("rejects the invalid requests", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("similarity-invalid", "reranker", llm)
+		similarity := SimilarityEndpoint(e.cl, e.ml, e.appConfig)
+
+		code, body := post(similarity, schema.SimilarityRequest{Model: "reranker", Query: "kitten"})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("query and texts are required"))
+
+		code, body = post(similarity, schema.SimilarityRequest{Model: "reranker", Query: "kitten", Texts: []string{"a cat"}, Method: "euclidean"})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("method must be cosine or cross-encoder"))
+		Expect(llm.rerank).To(BeNil())
+	})
+})
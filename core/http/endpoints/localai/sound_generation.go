@@ -0,0 +1,94 @@
+package localai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// SoundGenerationEndpoint generates sound effects or music from a text prompt
+// @Summary	Generates audio, such as sound effects or music, from the input text.
+// @Accept json
+// @Accept multipart/form-data
+// @Produce audio/x-wav
+// @Param request body schema.SoundGenerationRequest true "query params"
+// @Success 200 {string} binary "generated audio/wav file"
+// @Router /v1/sound-generation [post]
+func SoundGenerationEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.SoundGenerationRequest)
+
+		// Get input data from the request body
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, false)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+		if err != nil {
+			return err
+		}
+		modelFile = cfg.Model
+		log.Debug().Msgf("Sound generation request for model: %s", modelFile)
+
+		if input.Backend != "" {
+			cfg.Backend = input.Backend
+		}
+
+		// The conditioning audio is either uploaded, or base64 encoded in the JSON body
+		var src *string
+		audio, err := conditioningAudio(c, input)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if audio != nil {
+			f, err := os.CreateTemp("", "sound-generation")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(f.Name())
+			_, err = io.Copy(f, audio)
+			audio.Close()
+			f.Close()
+			if err != nil {
+				return err
+			}
+			name := f.Name()
+			src = &name
+		}
+
+		filePath, _, err := backend.SoundGeneration(cfg.Backend, modelFile, input.Text, input.Duration, input.Temperature, input.DoSample, src, ml, appConfig, *cfg)
+		if err != nil {
+			return err
+		}
+		return fiberContext.SendGeneratedFile(c, appConfig, filePath)
+	}
+}
+
+func conditioningAudio(c *fiber.Ctx, input *schema.SoundGenerationRequest) (io.ReadCloser, error) {
+	if file, err := c.FormFile("file"); err == nil {
+		return file.Open()
+	}
+
+	if input.Audio == "" {
+		return nil, nil
+	}
+	dat, err := base64.StdEncoding.DecodeString(input.Audio)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(dat)), nil
+}
@@ -0,0 +1,53 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// SystemInformationsEndpoint returns the backends and the models of the instance
+// @Summary	Returns the available backends, the loaded models, the circuits of the models which failed and the statistics of the loader.
+// @Success 200 {object} schema.SystemInformationResponse "Response"
+// @Router /system [get]
+func SystemInformationsEndpoint(ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		res := schema.SystemInformationResponse{
+			Backends:     model.KnownBackends(appConfig.AssetsDestination, appConfig.ExternalGRPCBackends),
+			LoadedModels: ml.LoadedModels(),
+			Circuits:     []schema.CircuitState{},
+			ModelStats:   []schema.ModelStats{},
+		}
+		if res.Backends == nil {
+			res.Backends = []string{}
+		}
+		for _, s := range ml.CircuitBreaker().States() {
+			state := schema.CircuitState{
+				Model:               s.Model,
+				State:               s.State,
+				ConsecutiveFailures: s.ConsecutiveFailures,
+				LastError:           s.LastError,
+			}
+			if !s.OpenedAt.IsZero() {
+				state.OpenedAt, state.RetryAt = &s.OpenedAt, &s.RetryAt
+			}
+			res.Circuits = append(res.Circuits, state)
+		}
+		for _, s := range ml.Stats() {
+			stats := schema.ModelStats{
+				Model:         s.Model,
+				Loaded:        s.Loaded,
+				LoadDuration:  s.LoadDuration.Seconds(),
+				Loads:         s.Loads,
+				Evictions:     s.Evictions,
+				WatchDogKills: s.WatchDogKills,
+			}
+			if !s.LoadedAt.IsZero() {
+				stats.LoadedAt, stats.LastUsed = &s.LoadedAt, &s.LastUsed
+			}
+			res.ModelStats = append(res.ModelStats, stats)
+		}
+		return c.JSON(res)
+	}
+}
@@ -0,0 +1,166 @@
+package localai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// TaskRunner runs the prompts of the scheduled tasks with their model, and their pipelines
+func TaskRunner(cl *config.BackendConfigLoader, ml *model.ModelLoader, sl *model.ModelLoader, appConfig *config.ApplicationConfig) services.TaskFunc {
+	modelConfig := func(name string) (config.BackendConfig, error) {
+		cfg, err := cl.LoadBackendConfigFileByName(name, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+		if err != nil {
+			return config.BackendConfig{}, fmt.Errorf("model %s: %w", name, err)
+		}
+		return *cfg, nil
+	}
+
+	return func(ctx context.Context, task config.ScheduledTask) (string, int, error) {
+		if task.Pipeline != "" {
+			p, exists := appConfig.Pipelines[task.Pipeline]
+			if !exists {
+				return "", 0, fmt.Errorf("pipeline %s not found", task.Pipeline)
+			}
+			configs := map[string]config.BackendConfig{}
+			for _, s := range p.Steps {
+				if _, resolved := configs[s.Model]; resolved {
+					continue
+				}
+				cfg, err := modelConfig(s.Model)
+				if err != nil {
+					return "", 0, err
+				}
+				configs[s.Model] = cfg
+			}
+			outputs, err := backend.RunPipeline(ctx, p, backend.PipelineInput{Text: task.Input}, configs, ml, sl, appConfig, nil)
+			if err != nil {
+				return "", 0, err
+			}
+			return outputs[p.Output].Text, backend.PipelineTokens(outputs), nil
+		}
+
+		cfg, err := modelConfig(task.Model)
+		if err != nil {
+			return "", 0, err
+		}
+		messages := []schema.Message{}
+		if task.System != "" {
+			messages = append(messages, schema.Message{Role: "system", Content: task.System, StringContent: task.System})
+		}
+		messages = append(messages, schema.Message{Role: "user", Content: task.Prompt, StringContent: task.Prompt})
+
+		prompt := backend.ChatPrompt(ctx, messages, nil, false, ml, cfg, appConfig)
+		predFunc, err := backend.ModelInference(ctx, prompt, messages, nil, ml, cfg, appConfig, nil)
+		if err != nil {
+			return "", 0, err
+		}
+		prediction, err := predFunc()
+		if err != nil {
+			return "", 0, err
+		}
+		return backend.Finetune(cfg, prompt, prediction.Response), prediction.Usage.Prompt + prediction.Usage.Completion, nil
+	}
+}
+
+// ListTasksEndpoint lists the scheduled tasks
+// @Summary Lists the scheduled tasks: the ones of the tasks file, and the ones created by the API key.
+// @Success 200 {object} schema.ScheduledTaskList "Response"
+// @Router /v1/tasks [get]
+func ListTasksEndpoint(tasks *services.TaskScheduler) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(schema.ScheduledTaskList{Object: "list", Data: tasks.List(fiberContext.Owner(c))})
+	}
+}
+
+// CreateTaskEndpoint schedules a task
+// @Summary Schedules a prompt or a pipeline at the times of a cron expression, whose output is posted to a webhook or written to a file.
+// @Param request body schema.ScheduledTask true "query params"
+// @Success 201 {object} schema.ScheduledTask "Response"
+// @Router /v1/tasks [post]
+func CreateTaskEndpoint(tasks *services.TaskScheduler) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.ScheduledTask)
+		if err := c.BodyParser(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed parsing request body: %s", err))
+		}
+		task, err := tasks.Add(config.ScheduledTask{
+			Name:     input.Name,
+			Schedule: input.Schedule,
+			Model:    input.Model,
+			System:   input.System,
+			Prompt:   input.Prompt,
+			Pipeline: input.Pipeline,
+			Input:    input.Input,
+			Webhook:  input.Webhook,
+			File:     input.File,
+		}, fiberContext.Owner(c))
+		switch {
+		case errors.Is(err, services.ErrTaskExists):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrTaskForbidden):
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		case err != nil:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return c.Status(fiber.StatusCreated).JSON(task)
+	}
+}
+
+// GetTaskEndpoint returns a scheduled task
+// @Summary Returns a scheduled task, with its next run and the job of its last run.
+// @Param name path string true "Task name"
+// @Success 200 {object} schema.ScheduledTask "Response"
+// @Router /v1/tasks/{name} [get]
+func GetTaskEndpoint(tasks *services.TaskScheduler) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		task, err := tasks.Get(c.Params("name"), fiberContext.Owner(c))
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return c.JSON(task)
+	}
+}
+
+// DeleteTaskEndpoint unschedules a task created with the API
+// @Summary Unschedules a task created with the API. The tasks of the tasks file cannot be deleted.
+// @Param name path string true "Task name"
+// @Router /v1/tasks/{name} [delete]
+func DeleteTaskEndpoint(tasks *services.TaskScheduler) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		err := tasks.Delete(c.Params("name"), fiberContext.Owner(c))
+		switch {
+		case errors.Is(err, services.ErrTaskNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrTaskReadOnly):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case err != nil:
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// RunTaskEndpoint runs a scheduled task right away
+// @Summary Runs a scheduled task right away, as a job whose result is the output of the task.
+// @Param name path string true "Task name"
+// @Success 202 {object} schema.Job "Response"
+// @Router /v1/tasks/{name}/run [post]
+func RunTaskEndpoint(tasks *services.TaskScheduler) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		job, err := tasks.Run(c.Params("name"), fiberContext.Owner(c))
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		c.Location("/v1/jobs/" + job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
@@ -0,0 +1,90 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// TextClassificationEndpoint labels a text with a sequence classification model, as a sentiment analysis
+// model, or with a zero-shot classifier among the candidate labels
+// @Summary	Classifies a text.
+// @Param request body schema.TextClassificationRequest true "query params"
+// @Success 200 {object} schema.ClassificationResponse "Response"
+// @Router /v1/classify [post]
+func TextClassificationEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.TextClassificationRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.Text == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "text is required")
+		}
+		if input.TopK < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "top_k must be positive")
+		}
+
+		cfg, err := requiredModelConfig(c, cl, ml, appConfig, input.Model)
+		if err != nil {
+			return err
+		}
+
+		res, err := backend.TextClassification(&proto.TextClassifyRequest{
+			Text:   input.Text,
+			Labels: input.Labels,
+			TopK:   int32(input.TopK),
+		}, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+
+		response := schema.ClassificationResponse{Model: input.Model, Classifications: []schema.Classification{}}
+		for _, class := range res.Classifications {
+			response.Classifications = append(response.Classifications, schema.Classification{Label: class.Label, Score: class.Score})
+		}
+		return c.JSON(response)
+	}
+}
+
+// EntitiesEndpoint finds the named entities of a text with a token classification model
+// @Summary	Extracts the named entities of a text.
+// @Param request body schema.EntitiesRequest true "query params"
+// @Success 200 {object} schema.EntitiesResponse "Response"
+// @Router /v1/ner [post]
+func EntitiesEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.EntitiesRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.Text == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "text is required")
+		}
+
+		cfg, err := requiredModelConfig(c, cl, ml, appConfig, input.Model)
+		if err != nil {
+			return err
+		}
+
+		res, err := backend.EntityExtraction(&proto.EntitiesRequest{Text: input.Text}, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+
+		response := schema.EntitiesResponse{Model: input.Model, Entities: []schema.Entity{}}
+		for _, e := range res.Entities {
+			response.Entities = append(response.Entities, schema.Entity{
+				Label: e.Label,
+				Text:  e.Text,
+				Score: e.Score,
+				Start: int(e.Start),
+				End:   int(e.End),
+			})
+		}
+		return c.JSON(response)
+	}
+}
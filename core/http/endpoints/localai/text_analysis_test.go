@@ -0,0 +1,75 @@
+package localai_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/mudler/LocalAI/core/http/endpoints/localai"
+	"github.com/mudler/LocalAI/core/schema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Text analysis", func() {
+	It("classifies the texts among the candidate labels", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("text-classify", "bart", llm)
+
+		code, body := post(TextClassificationEndpoint(e.cl, e.ml, e.appConfig), schema.TextClassificationRequest{
+			Model:  "bart",
+			Text:   "I love it",
+			Labels: []string{"positive", "negative"},
+			TopK:   1,
+		})
+		Expect(code).To(Equal(http.StatusOK), string(body))
+
+		res := schema.ClassificationResponse{}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res).To(Equal(schema.ClassificationResponse{
+			Model:           "bart",
+			Classifications: []schema.Classification{{Label: "positive", Score: 0.95}},
+		}))
+		Expect(llm.text.Text).To(Equal("I love it"))
+		Expect(llm.text.Labels).To(Equal([]string{"positive", "negative"}))
+		Expect(llm.text.TopK).To(Equal(int32(1)))
+	})
+
+	It("extracts the named entities of the texts", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("text-entities", "bert-ner", llm)
+
+		code, body := post(EntitiesEndpoint(e.cl, e.ml, e.appConfig), schema.EntitiesRequest{Model: "bert-ner", Text: "Ada wrote programs"})
+		Expect(code).To(Equal(http.StatusOK), string(body))
+
+		res := schema.EntitiesResponse{}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res).To(Equal(schema.EntitiesResponse{
+			Model:    "bert-ner",
+			Entities: []schema.Entity{{Label: "PER", Text: "Ada", Score: 0.99, Start: 0, End: 3}},
+		}))
+		Expect(llm.entities.Text).To(Equal("Ada wrote programs"))
+	})
+
+	It("rejects the invalid requests", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("text-invalid", "bart", llm)
+		classify := TextClassificationEndpoint(e.cl, e.ml, e.appConfig)
+		entities := EntitiesEndpoint(e.cl, e.ml, e.appConfig)
+
+		code, body := post(classify, schema.TextClassificationRequest{Model: "bart"})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("text is required"))
+
+		code, body = post(classify, schema.TextClassificationRequest{Model: "bart", Text: "I love it", TopK: -1})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("top_k must be positive"))
+
+		code, body = post(entities, schema.EntitiesRequest{Text: "Ada wrote programs"})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("model is required"))
+
+		Expect(llm.text).To(BeNil())
+		Expect(llm.entities).To(BeNil())
+	})
+})
@@ -0,0 +1,73 @@
+package localai
+
+import (
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+const transcriptionStreamConfig = "transcriptionStreamConfig"
+
+// TranscriptionStreamUpgrade resolves the model of a live transcription, before
+// upgrading the connection to a websocket handled by TranscriptionStreamEndpoint
+func TranscriptionStreamUpgrade(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, c.Query("model"), true)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+		if err != nil {
+			return err
+		}
+
+		c.Locals(transcriptionStreamConfig, cfg)
+		return c.Next()
+	}
+}
+
+// TranscriptionStreamEndpoint transcribes live audio sent over a websocket
+// @Summary Transcribes live audio. The client sends binary messages with mono, 16kHz, signed 16-bit little endian PCM audio, and a text message to end the stream. Segments are sent back as JSON messages as soon as they are transcribed.
+// @Param model query string false "model"
+// @Param language query string false "language"
+// @Success 101 {object} schema.TranscriptionStreamEvent "Response"
+// @Router /v1/audio/transcriptions/stream [get]
+func TranscriptionStreamEndpoint(ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *websocket.Conn) {
+	return func(c *websocket.Conn) {
+		cfg := c.Locals(transcriptionStreamConfig).(*config.BackendConfig)
+
+		// Segments are written by the transcription worker only, until the stream is closed
+		ts := backend.NewTranscriptionStream(c.Query("language"), func(s schema.Segment) {
+			segment := s
+			if err := c.WriteJSON(schema.TranscriptionStreamEvent{Type: "segment", Segment: &segment}); err != nil {
+				log.Debug().Err(err).Msg("failed sending transcription segment")
+			}
+		}, ml, *cfg, appConfig)
+
+		for {
+			messageType, msg, err := c.ReadMessage()
+			if err != nil || messageType != websocket.BinaryMessage {
+				break
+			}
+			if err := ts.Write(msg); err != nil {
+				break
+			}
+		}
+
+		if err := ts.Close(); err != nil {
+			c.WriteJSON(schema.TranscriptionStreamEvent{Type: "error", Error: err.Error()})
+			return
+		}
+		c.WriteJSON(schema.TranscriptionStreamEvent{Type: "done"})
+	}
+}
@@ -0,0 +1,116 @@
+package localai
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// TranslationEndpoint translates a text with a translation model, as NLLB or M2M100. Without a model, the
+// model translating the language pair is used, and without a source language it is detected
+// @Summary	Translates a text.
+// @Param request body schema.TranslationRequest true "query params"
+// @Success 200 {object} schema.TranslationResponse "Response"
+// @Router /v1/translate [post]
+func TranslationEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.TranslationRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.Text == "" || input.Target == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "text and target are required")
+		}
+
+		source := input.Source
+		if source == "" {
+			detector, exists := cl.LanguageDetectionModel()
+			if !exists {
+				return fiber.NewError(fiber.StatusBadRequest, "source is required: no model detects the language")
+			}
+			cfg, err := requiredModelConfig(c, cl, ml, appConfig, detector.Name)
+			if err != nil {
+				return err
+			}
+			res, err := backend.LanguageDetection(input.Text, 1, ml, *cfg, appConfig)
+			if err != nil {
+				return err
+			}
+			if len(res.Classifications) == 0 {
+				return fiber.NewError(fiber.StatusUnprocessableEntity, "the language of the text was not detected")
+			}
+			source = res.Classifications[0].Label
+		}
+
+		name := input.Model
+		if name == "" {
+			translator, exists := cl.TranslationModel(source, input.Target)
+			if !exists {
+				return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("no model translates %s to %s", source, input.Target))
+			}
+			name = translator.Name
+		}
+		cfg, err := requiredModelConfig(c, cl, ml, appConfig, name)
+		if err != nil {
+			return err
+		}
+
+		text, err := backend.Translation(input.Text, source, input.Target, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+		return c.JSON(schema.TranslationResponse{Model: name, Text: text, Source: source, Target: input.Target})
+	}
+}
+
+// LanguageDetectionEndpoint detects the language of a text with a text classification model whose labels are
+// the languages
+// @Summary	Detects the language of a text.
+// @Param request body schema.LanguageDetectionRequest true "query params"
+// @Success 200 {object} schema.LanguageDetectionResponse "Response"
+// @Router /v1/detect-language [post]
+func LanguageDetectionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.LanguageDetectionRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.Text == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "text is required")
+		}
+		if input.TopK < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "top_k must be positive")
+		}
+
+		name := input.Model
+		if name == "" {
+			detector, exists := cl.LanguageDetectionModel()
+			if !exists {
+				return fiber.NewError(fiber.StatusBadRequest, "model is required: no model is marked for the language detection")
+			}
+			name = detector.Name
+		}
+		cfg, err := requiredModelConfig(c, cl, ml, appConfig, name)
+		if err != nil {
+			return err
+		}
+
+		res, err := backend.LanguageDetection(input.Text, input.TopK, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+
+		response := schema.LanguageDetectionResponse{Model: name, Languages: []schema.DetectedLanguage{}}
+		for _, class := range res.Classifications {
+			response.Languages = append(response.Languages, schema.DetectedLanguage{Language: class.Label, Score: class.Score})
+		}
+		if len(response.Languages) > 0 {
+			response.Language = response.Languages[0].Language
+		}
+		return c.JSON(response)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/mudler/LocalAI/core/config"
 	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/sound"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/core/schema"
@@ -15,8 +16,11 @@ import (
 //	@Summary	Generates audio from the input text.
 //  @Accept json
 //  @Produce audio/x-wav
+//  @Produce audio/mpeg
+//  @Produce audio/ogg
+//  @Produce audio/flac
 //	@Param		request	body		schema.TTSRequest	true	"query params"
-//	@Success	200		{string}	binary				"generated audio/wav file"
+//	@Success	200		{string}	binary				"generated audio file"
 //	@Router		/v1/audio/speech [post]
 //	@Router		/tts [post]
 func TTSEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
@@ -31,8 +35,7 @@ func TTSEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfi
 
 		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, false)
 		if err != nil {
-			modelFile = input.Model
-			log.Warn().Msgf("Model not found in context: %s", input.Model)
+			return err
 		}
 
 		cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath,
@@ -67,6 +70,15 @@ func TTSEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfi
 		if err != nil {
 			return err
 		}
-		return c.Download(filePath)
+
+		converted, err := sound.Convert(filePath, input.ResponseFormat, input.SampleRate)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if err := fiberContext.SendGeneratedFile(c, appConfig, converted, filePath); err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, sound.ContentType(input.ResponseFormat))
+		return nil
 	}
 }
@@ -0,0 +1,33 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+)
+
+// UsageEndpoint returns the tokens used today by the API key of the request and by its tenant
+// @Summary Returns the tokens used today (UTC) by the API key of the request and by its tenant, with their daily limits.
+// @Success 200 {object} schema.UsageResponse "Response"
+// @Router /v1/usage [get]
+func UsageEndpoint(quotas *services.TokenQuotas, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		apiKey := fiberContext.APIKeyFromContext(c)
+		if apiKey == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "the usage is only counted for the API keys")
+		}
+
+		resp := schema.UsageResponse{
+			Tokens:      quotas.Used(fiberContext.APIKeyOwner(apiKey)),
+			DailyTokens: appConfig.APIKeyDailyTokens,
+		}
+		if tenant := fiberContext.TenantFromContext(c); tenant != nil {
+			resp.Tenant = tenant.Name
+			resp.TenantTokens = quotas.Used(fiberContext.TenantKey(tenant))
+			resp.TenantDailyTokens = tenant.DailyTokens
+		}
+		return c.JSON(resp)
+	}
+}
@@ -0,0 +1,171 @@
+package localai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// DetectionEndpoint finds the objects in an image with a detection model, as YOLO
+// @Summary	Detects the objects in an image.
+// @Accept json
+// @Accept multipart/form-data
+// @Param request body schema.DetectionRequest true "query params"
+// @Success 200 {object} schema.DetectionResponse "Response"
+// @Router /v1/detection [post]
+func DetectionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.DetectionRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+
+		cfg, err := requiredModelConfig(c, cl, ml, appConfig, input.Model)
+		if err != nil {
+			return err
+		}
+
+		src, err := visionImage(c, input.Image)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		defer os.Remove(src)
+
+		res, err := backend.Detection(&proto.DetectRequest{
+			Src:       src,
+			Threshold: input.Threshold,
+			Labels:    input.Labels,
+		}, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+
+		response := schema.DetectionResponse{Model: input.Model, Detections: []schema.Detection{}}
+		for _, d := range res.Detections {
+			response.Detections = append(response.Detections, schema.Detection{
+				Label: d.Label,
+				Score: d.Score,
+				Box:   schema.Box{X: d.X, Y: d.Y, Width: d.Width, Height: d.Height},
+			})
+		}
+		return c.JSON(response)
+	}
+}
+
+// ClassificationEndpoint labels an image with a classification model, as CLIP
+// @Summary	Classifies an image.
+// @Accept json
+// @Accept multipart/form-data
+// @Param request body schema.ClassificationRequest true "query params"
+// @Success 200 {object} schema.ClassificationResponse "Response"
+// @Router /v1/classification [post]
+func ClassificationEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.ClassificationRequest)
+		if err := c.BodyParser(input); err != nil {
+			return err
+		}
+		if input.TopK < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "top_k must be positive")
+		}
+
+		cfg, err := requiredModelConfig(c, cl, ml, appConfig, input.Model)
+		if err != nil {
+			return err
+		}
+
+		src, err := visionImage(c, input.Image)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		defer os.Remove(src)
+
+		res, err := backend.Classification(&proto.ClassifyRequest{
+			Src:    src,
+			Labels: input.Labels,
+			TopK:   int32(input.TopK),
+		}, ml, *cfg, appConfig)
+		if err != nil {
+			return err
+		}
+
+		response := schema.ClassificationResponse{Model: input.Model, Classifications: []schema.Classification{}}
+		for _, class := range res.Classifications {
+			response.Classifications = append(response.Classifications, schema.Classification{Label: class.Label, Score: class.Score})
+		}
+		return c.JSON(response)
+	}
+}
+
+// requiredModelConfig returns the configuration of the model of the request, which has no default model
+func requiredModelConfig(c *fiber.Ctx, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, input string) (*config.BackendConfig, error) {
+	if input == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "model is required")
+	}
+
+	modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input, false)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("Request for model: %s", cfg.Model)
+	return cfg, nil
+}
+
+// visionImage saves the image of the request to a temporary file. The image is uploaded as file, or set
+// in the body as an URL or base64
+func visionImage(c *fiber.Ctx, image string) (string, error) {
+	f, err := os.CreateTemp("", "vision")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if file, err := c.FormFile("file"); err == nil {
+		src, err := file.Open()
+		if err != nil {
+			os.Remove(f.Name())
+			return "", err
+		}
+		defer src.Close()
+		if _, err := io.Copy(f, src); err != nil {
+			os.Remove(f.Name())
+			return "", err
+		}
+		return f.Name(), nil
+	}
+
+	if image == "" {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("image is required")
+	}
+	// The URLs and the data URIs are read as base64, the rest is base64 already
+	if b64, err := utils.GetImageURLAsBase64(image); err == nil {
+		image = b64
+	}
+	data, err := base64.StdEncoding.DecodeString(image)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("invalid image: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,96 @@
+package localai_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+
+	. "github.com/mudler/LocalAI/core/http/endpoints/localai"
+	"github.com/mudler/LocalAI/core/schema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vision", func() {
+	image := []byte("\x89PNG")
+
+	It("detects the objects of the images in the body", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("vision-detect", "yolo", llm)
+
+		code, body := post(DetectionEndpoint(e.cl, e.ml, e.appConfig), schema.DetectionRequest{
+			Model:     "yolo",
+			Image:     base64.StdEncoding.EncodeToString(image),
+			Threshold: 0.5,
+			Labels:    []string{"cat"},
+		})
+		Expect(code).To(Equal(http.StatusOK), string(body))
+
+		res := schema.DetectionResponse{}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res).To(Equal(schema.DetectionResponse{
+			Model:      "yolo",
+			Detections: []schema.Detection{{Label: "cat", Score: 0.9, Box: schema.Box{X: 1, Y: 2, Width: 3, Height: 4}}},
+		}))
+		Expect(llm.image).To(Equal(image))
+		Expect(llm.detect.Threshold).To(Equal(float32(0.5)))
+		Expect(llm.detect.Labels).To(Equal([]string{"cat"}))
+		Expect(llm.detect.Src).ToNot(BeAnExistingFile())
+	})
+
+	It("classifies the uploaded images", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("vision-classify", "clip", llm)
+
+		buf := &bytes.Buffer{}
+		w := multipart.NewWriter(buf)
+		Expect(w.WriteField("model", "clip")).To(Succeed())
+		Expect(w.WriteField("top_k", "2")).To(Succeed())
+		f, err := w.CreateFormFile("file", "cat.png")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write(image)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		req, err := http.NewRequest(http.MethodPost, "/", buf)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", w.FormDataContentType())
+
+		code, body := send(ClassificationEndpoint(e.cl, e.ml, e.appConfig), req)
+		Expect(code).To(Equal(http.StatusOK), string(body))
+
+		res := schema.ClassificationResponse{}
+		Expect(json.Unmarshal(body, &res)).To(Succeed())
+		Expect(res.Model).To(Equal("clip"))
+		Expect(res.Classifications).To(Equal([]schema.Classification{{Label: "cat", Score: 0.8}, {Label: "dog", Score: 0.2}}))
+		Expect(llm.image).To(Equal(image))
+		Expect(llm.classify.TopK).To(Equal(int32(2)))
+	})
+
+	It("rejects the invalid requests", func() {
+		llm := &fakeBackend{}
+		e := newEndpoint("vision-invalid", "yolo", llm)
+		detect := DetectionEndpoint(e.cl, e.ml, e.appConfig)
+		classify := ClassificationEndpoint(e.cl, e.ml, e.appConfig)
+
+		code, body := post(detect, schema.DetectionRequest{Image: base64.StdEncoding.EncodeToString(image)})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("model is required"))
+
+		code, body = post(detect, schema.DetectionRequest{Model: "yolo"})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("image is required"))
+
+		code, _ = post(detect, schema.DetectionRequest{Model: "yolo", Image: "not base64!"})
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		code, body = post(classify, schema.ClassificationRequest{Model: "yolo", Image: base64.StdEncoding.EncodeToString(image), TopK: -1})
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(string(body)).To(Equal("top_k must be positive"))
+
+		Expect(llm.detect).To(BeNil())
+		Expect(llm.classify).To(BeNil())
+	})
+})
@@ -0,0 +1,84 @@
+package localai
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+)
+
+// VoiceCloneEndpoint registers a named voice from a reference audio
+// @Summary	Registers a voice from a reference audio, to be used as voice in TTS requests.
+// @accept multipart/form-data
+// @Param name formData string true "name of the voice"
+// @Param file formData file true "reference audio"
+// @Success 200 {object} schema.Voice "Response"
+// @Router /v1/audio/voice-clone [post]
+func VoiceCloneEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		name := c.FormValue("name")
+		if name == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "name is required")
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "reference audio is required")
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if _, err := backend.SaveClonedVoice(name, filepath.Ext(file.Filename), f, appConfig); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		return c.JSON(schema.Voice{ID: name, Name: name})
+	}
+}
+
+// ListClonedVoicesEndpoint lists the registered voices
+// @Summary	Lists the voices registered from a reference audio.
+// @Success 200 {object} schema.VoicesResponse "Response"
+// @Router /v1/audio/voice-clone [get]
+func ListClonedVoicesEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		names, err := backend.ListClonedVoices(appConfig)
+		if err != nil {
+			return err
+		}
+
+		voices := schema.VoicesResponse{Object: "list", Data: []schema.Voice{}}
+		for _, n := range names {
+			voices.Data = append(voices.Data, schema.Voice{ID: n, Name: n})
+		}
+		return c.JSON(voices)
+	}
+}
+
+// DeleteClonedVoiceEndpoint removes a registered voice
+// @Summary	Removes a voice registered from a reference audio.
+// @Success 200 {object} schema.DeleteResponseResponse "Response"
+// @Router /v1/audio/voice-clone/{name} [delete]
+func DeleteClonedVoiceEndpoint(appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if err := backend.DeleteClonedVoice(name, appConfig); err != nil {
+			if errors.Is(err, backend.ErrVoiceNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, err.Error())
+			}
+			return err
+		}
+		return c.JSON(schema.DeleteResponseResponse{
+			ID:      name,
+			Object:  "voice",
+			Deleted: true,
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package localai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+)
+
+// ListVoicesEndpoint lists the voices available with a TTS model
+// @Summary	Lists the voices available with a TTS model.
+// @Param model query string true "model"
+// @Param backend query string false "backend"
+// @Success 200 {object} schema.VoicesResponse "Response"
+// @Router /v1/audio/voices [get]
+func ListVoicesEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := c.Query("model")
+		if input == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "model is required")
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input, false)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+		if err != nil {
+			return err
+		}
+		modelFile = cfg.Model
+
+		if b := c.Query("backend"); b != "" {
+			cfg.Backend = b
+		}
+
+		res, err := backend.ModelListVoices(cfg.Backend, modelFile, ml, appConfig, *cfg)
+		if err != nil {
+			return err
+		}
+
+		voices := schema.VoicesResponse{
+			Object:  "list",
+			Model:   input,
+			Backend: cfg.Backend,
+			Data:    []schema.Voice{},
+		}
+		for _, v := range res.Voices {
+			voices.Data = append(voices.Data, schema.Voice{
+				ID:         v.Id,
+				Name:       v.Name,
+				Languages:  v.Languages,
+				Gender:     v.Gender,
+				SampleRate: int(v.SampleRate),
+			})
+		}
+		return c.JSON(voices)
+	}
+}
@@ -0,0 +1,352 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/agent"
+	"github.com/mudler/LocalAI/pkg/functions"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+const (
+	// defaultAgentMaxSteps model calls are run by default before the answer is forced, and maxAgentMaxSteps
+	// at most
+	defaultAgentMaxSteps = 5
+	maxAgentMaxSteps     = 20
+)
+
+// The built-in tools of the agents
+const (
+	agentCalculator   = "calculator"
+	agentWebFetch     = "web_fetch"
+	agentVectorSearch = "vector_search"
+)
+
+// agentTool is a built-in tool of the agents, run by the server when the model calls it
+type agentTool struct {
+	function functions.Function
+	call     func(ctx context.Context, arguments map[string]interface{}) (string, error)
+}
+
+// AgentEndpoint runs the tool calls of the model server-side: the built-in tools the model calls are run, and
+// their results are passed back to the model, until it answers or the step limit is reached
+// @Summary	Answers the messages with a model calling the built-in tools (calculator, web_fetch, vector_search) server-side.
+// @Param request body schema.AgentRequest true "query params"
+// @Success 200 {object} schema.AgentResponse "Response"
+// @Router /v1/agent [post]
+func AgentEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, sl *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.AgentRequest)
+		if err := c.BodyParser(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed parsing request body: %s", err))
+		}
+		if len(input.Messages) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "messages are required")
+		}
+		if err := checkRequestLimits(appConfig, input.Messages); err != nil {
+			return err
+		}
+		if input.MaxSteps < 0 || input.MaxSteps > maxAgentMaxSteps {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("max_steps must be between 0 and %d", maxAgentMaxSteps))
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, false)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(fiberContext.RequestContext(c, appConfig.Context))
+		defer cancel()
+		req := &schema.OpenAIRequest{PredictionOptions: input.PredictionOptions, Messages: input.Messages, Context: ctx, Cancel: cancel}
+		// Only one answer is generated
+		req.N = 1
+
+		cfg, req, err := mergeRequestWithConfig(modelFile, req, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+
+		tools, err := selectAgentTools(agentTools(cfg, cl, ml, sl, appConfig), input.Tools)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		response, tokenUsage, err := runAgent(req, cfg, ml, appConfig, tools, firstPositive(input.MaxSteps, defaultAgentMaxSteps))
+		fiberContext.TokenUsageRecorder(c)(tokenUsage.Prompt + tokenUsage.Completion)
+		if err != nil {
+			return err
+		}
+
+		response.ID = uuid.New().String()
+		response.Created = int(time.Now().Unix())
+		response.Model = input.Model
+		response.Usage = schema.OpenAIUsage{
+			PromptTokens:     tokenUsage.Prompt,
+			CompletionTokens: tokenUsage.Completion,
+			TotalTokens:      tokenUsage.Prompt + tokenUsage.Completion,
+		}
+		return c.JSON(response)
+	}
+}
+
+// runAgent calls the model with the tools until it answers, up to maxSteps times. The answer is then
+// generated without the tools
+func runAgent(req *schema.OpenAIRequest, cfg *config.BackendConfig, ml *model.ModelLoader, appConfig *config.ApplicationConfig, tools map[string]agentTool, maxSteps int) (schema.AgentResponse, backend.TokenUsage, error) {
+	response := schema.AgentResponse{Steps: []schema.AgentStep{}}
+	tokenUsage := backend.TokenUsage{}
+
+	names := []string{}
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	funcs := functions.Functions{}
+	for _, name := range names {
+		funcs = append(funcs, tools[name].function)
+	}
+	noAction := noActionFunction(cfg)
+	if !cfg.FunctionsConfig.DisableNoAction {
+		funcs = append(funcs, noAction)
+	}
+	req.Functions = funcs
+
+	toolsGrammar := ""
+	if !cfg.FunctionsConfig.GrammarConfig.NoGrammar {
+		g, err := funcs.ToJSONStructure(cfg.FunctionsConfig.FunctionNameKey, cfg.FunctionsConfig.FunctionNameKey).Grammar(cfg.FunctionsConfig.GrammarOptions()...)
+		if err == nil {
+			toolsGrammar = g
+		}
+	}
+
+	for step := 1; step <= maxSteps; step++ {
+		cfg.Grammar = toolsGrammar
+		prompt := chatPrompt(req, cfg, ml, appConfig, funcs, true)
+		result, usage, err := agentCompletion(req, prompt, cfg, ml, appConfig)
+		tokenUsage.Prompt += usage.Prompt
+		tokenUsage.Completion += usage.Completion
+		if err != nil {
+			return response, tokenUsage, err
+		}
+
+		content := functions.ParseTextContent(result, cfg.FunctionsConfig)
+		result = functions.CleanupLLMResult(result, cfg.FunctionsConfig)
+		calls := functions.ParseFunctionCall(result, cfg.FunctionsConfig)
+		if len(calls) == 0 || calls[0].Name == noAction.Name {
+			cfg.Grammar = ""
+			answer, err := handleQuestion(cfg, req, ml, appConfig, calls, result, prompt)
+			if err != nil {
+				return response, tokenUsage, err
+			}
+			response.Answer = answer
+			response.FinishReason = "stop"
+			return response, tokenUsage, nil
+		}
+
+		agentStep := schema.AgentStep{Step: step, Content: strings.TrimSpace(content), ToolCalls: []schema.AgentToolCall{}}
+		assistant := schema.Message{Role: "assistant"}
+		if agentStep.Content != "" {
+			assistant.Content = agentStep.Content
+			assistant.StringContent = agentStep.Content
+		}
+		results := []schema.Message{}
+		for i, call := range calls {
+			if call.Name == noAction.Name {
+				continue
+			}
+			requestLog(req).Debug().Msgf("Agent step %d calls %s with %s", step, call.Name, call.Arguments)
+			toolCall := schema.AgentToolCall{Tool: call.Name, Arguments: call.Arguments}
+			output, err := callAgentTool(req.Context, tools, call.Name, call.Arguments)
+			if err != nil {
+				toolCall.Error = err.Error()
+				// the model sees the error, to fix its call
+				output = "error: " + err.Error()
+			} else {
+				toolCall.Result = output
+			}
+			agentStep.ToolCalls = append(agentStep.ToolCalls, toolCall)
+
+			assistant.ToolCalls = append(assistant.ToolCalls, schema.ToolCall{
+				Index:        i,
+				ID:           fmt.Sprintf("call_%d_%d", step, i),
+				Type:         "function",
+				FunctionCall: schema.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+			results = append(results, schema.Message{Role: "tool", Name: call.Name, Content: output, StringContent: output})
+		}
+		req.Messages = append(req.Messages, assistant)
+		req.Messages = append(req.Messages, results...)
+		response.Steps = append(response.Steps, agentStep)
+	}
+
+	// The step limit is reached: the model answers with the results it has, without the tools
+	cfg.Grammar = ""
+	req.Functions = nil
+	prompt := chatPrompt(req, cfg, ml, appConfig, nil, false)
+	answer, usage, err := agentCompletion(req, prompt, cfg, ml, appConfig)
+	tokenUsage.Prompt += usage.Prompt
+	tokenUsage.Completion += usage.Completion
+	if err != nil {
+		return response, tokenUsage, err
+	}
+	response.Answer = answer
+	response.FinishReason = "max_steps"
+	return response, tokenUsage, nil
+}
+
+func agentCompletion(req *schema.OpenAIRequest, prompt string, cfg *config.BackendConfig, ml *model.ModelLoader, appConfig *config.ApplicationConfig) (string, backend.TokenUsage, error) {
+	choices, tokenUsage, err := ComputeChoices(req, prompt, cfg, appConfig, ml, func(s string, c *[]schema.Choice) {
+		*c = append(*c, schema.Choice{Text: s})
+	}, nil)
+	if err != nil || len(choices) == 0 {
+		return "", tokenUsage, err
+	}
+	return choices[0].Text, tokenUsage, nil
+}
+
+// agentTools returns the built-in tools available to the model by name: web_fetch needs the hosts it can
+// fetch, and vector_search the rag embedding model of the model
+func agentTools(cfg *config.BackendConfig, cl *config.BackendConfigLoader, ml *model.ModelLoader, sl *model.ModelLoader, appConfig *config.ApplicationConfig) map[string]agentTool {
+	tools := map[string]agentTool{
+		agentCalculator: {
+			function: functions.Function{
+				Name:        agentCalculator,
+				Description: "Evaluates an arithmetic expression, with + - * / % ^, parentheses, pi, e and the functions sqrt, abs, floor, ceil, round, ln, log, log2, exp, sin, cos, tan, pow, min and max",
+				Parameters:  agentParameters("expression", "The expression to evaluate, as (2 + 3) * sqrt(16)"),
+			},
+			call: func(_ context.Context, arguments map[string]interface{}) (string, error) {
+				expression, err := agentArgument(arguments, "expression")
+				if err != nil {
+					return "", err
+				}
+				v, err := agent.Calculate(expression)
+				if err != nil {
+					return "", err
+				}
+				return agent.FormatNumber(v), nil
+			},
+		},
+	}
+
+	if len(appConfig.AgentFetchAllowlist) > 0 {
+		fetcher := agent.Fetcher{Allowlist: appConfig.AgentFetchAllowlist}
+		tools[agentWebFetch] = agentTool{
+			function: functions.Function{
+				Name:        agentWebFetch,
+				Description: fmt.Sprintf("Fetches a web page and returns its text. Only the pages of these hosts can be fetched: %s", strings.Join(appConfig.AgentFetchAllowlist, ", ")),
+				Parameters:  agentParameters("url", "The http or https URL of the page"),
+			},
+			call: func(ctx context.Context, arguments map[string]interface{}) (string, error) {
+				u, err := agentArgument(arguments, "url")
+				if err != nil {
+					return "", err
+				}
+				return fetcher.Fetch(ctx, u)
+			},
+		}
+	}
+
+	if cfg.RAG.EmbeddingModel != "" {
+		tools[agentVectorSearch] = agentTool{
+			function: functions.Function{
+				Name:        agentVectorSearch,
+				Description: "Searches the documents of the knowledge base the most similar to a query",
+				Parameters:  agentParameters("query", "The text to search"),
+			},
+			call: func(ctx context.Context, arguments map[string]interface{}) (string, error) {
+				query, err := agentArgument(arguments, "query")
+				if err != nil {
+					return "", err
+				}
+				citations, err := ragRetrieve(ctx, query, firstPositive(cfg.RAG.TopN, defaultRAGTopN), cfg.RAG, cl, ml, sl, appConfig)
+				if err != nil {
+					return "", err
+				}
+				if len(citations) == 0 {
+					return "no documents found", nil
+				}
+				documents := []string{}
+				for i, c := range citations {
+					document := fmt.Sprintf("[%d] %s", i+1, c.Text)
+					if c.Source != "" {
+						document += fmt.Sprintf(" (source: %s)", c.Source)
+					}
+					documents = append(documents, document)
+				}
+				return strings.Join(documents, "\n\n"), nil
+			},
+		}
+	}
+	return tools
+}
+
+// selectAgentTools returns the tools of the request, all the available ones if it has none
+func selectAgentTools(available map[string]agentTool, names []string) (map[string]agentTool, error) {
+	if len(names) == 0 {
+		return available, nil
+	}
+	selected := map[string]agentTool{}
+	for _, name := range names {
+		tool, exists := available[name]
+		if !exists {
+			if !slices.Contains([]string{agentCalculator, agentWebFetch, agentVectorSearch}, name) {
+				return nil, fmt.Errorf("unknown tool %q, the built-in tools are %s, %s and %s", name, agentCalculator, agentWebFetch, agentVectorSearch)
+			}
+			return nil, fmt.Errorf("the tool %s is not available: %s needs --agent-fetch-allowlist, and %s a model with a rag embedding_model", name, agentWebFetch, agentVectorSearch)
+		}
+		selected[name] = tool
+	}
+	return selected, nil
+}
+
+// callAgentTool runs a tool called by the model with its JSON arguments
+func callAgentTool(ctx context.Context, tools map[string]agentTool, name, arguments string) (string, error) {
+	tool, exists := tools[name]
+	if !exists {
+		return "", fmt.Errorf("unknown tool %s", name)
+	}
+	args := map[string]interface{}{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("the arguments are not a JSON object: %w", err)
+		}
+	}
+	return tool.call(ctx, args)
+}
+
+// agentParameters is the JSON schema of the parameters of the tools, which take a single string
+func agentParameters(name, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			name: map[string]interface{}{
+				"type":        "string",
+				"description": description,
+			},
+		},
+		"required": []string{name},
+	}
+}
+
+func agentArgument(arguments map[string]interface{}, name string) (string, error) {
+	switch v := arguments[name].(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			return v, nil
+		}
+	case float64:
+		return agent.FormatNumber(v), nil
+	}
+	return "", fmt.Errorf("the %s argument is required", name)
+}
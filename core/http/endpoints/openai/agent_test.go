@@ -0,0 +1,48 @@
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentTools(t *testing.T) {
+	appConfig := config.NewApplicationConfig()
+	cfg := &config.BackendConfig{}
+
+	tools := agentTools(cfg, nil, nil, nil, appConfig)
+	assert.Len(t, tools, 1)
+	assert.Contains(t, tools, agentCalculator)
+
+	appConfig.AgentFetchAllowlist = []string{"example.com"}
+	cfg.RAG.EmbeddingModel = "bert"
+	tools = agentTools(cfg, nil, nil, nil, appConfig)
+	assert.Len(t, tools, 3)
+	assert.Contains(t, tools[agentWebFetch].function.Description, "example.com")
+
+	selected, err := selectAgentTools(tools, []string{agentCalculator})
+	assert.NoError(t, err)
+	assert.Len(t, selected, 1)
+
+	_, err = selectAgentTools(agentTools(&config.BackendConfig{}, nil, nil, nil, config.NewApplicationConfig()), []string{agentWebFetch})
+	assert.ErrorContains(t, err, "is not available")
+	_, err = selectAgentTools(tools, []string{"shell"})
+	assert.ErrorContains(t, err, `unknown tool "shell"`)
+}
+
+func TestCallAgentTool(t *testing.T) {
+	tools := agentTools(&config.BackendConfig{}, nil, nil, nil, config.NewApplicationConfig())
+
+	result, err := callAgentTool(context.Background(), tools, agentCalculator, `{"expression": "(2 + 3) * 4"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "20", result)
+
+	_, err = callAgentTool(context.Background(), tools, agentCalculator, `{}`)
+	assert.ErrorContains(t, err, "the expression argument is required")
+	_, err = callAgentTool(context.Background(), tools, agentCalculator, `not json`)
+	assert.ErrorContains(t, err, "not a JSON object")
+	_, err = callAgentTool(context.Background(), tools, agentWebFetch, `{"url": "https://example.com"}`)
+	assert.ErrorContains(t, err, "unknown tool web_fetch")
+}
@@ -3,6 +3,7 @@ package openai
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -12,10 +13,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/mudler/LocalAI/core/backend"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/pkg/functions"
 	model "github.com/mudler/LocalAI/pkg/model"
-	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
 )
 
@@ -29,7 +30,7 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 	id := uuid.New().String()
 	created := int(time.Now().Unix())
 
-	process := func(s string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) {
+	process := func(s string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) backend.TokenUsage {
 		initialMessage := schema.OpenAIResponse{
 			ID:      id,
 			Created: created,
@@ -39,7 +40,7 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 		}
 		responses <- initialMessage
 
-		ComputeChoices(req, s, config, startupOptions, loader, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
+		_, tokenUsage, _ := ComputeChoices(req, s, config, startupOptions, loader, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
 			resp := schema.OpenAIResponse{
 				ID:      id,
 				Created: created,
@@ -57,8 +58,55 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 			return true
 		})
 		close(responses)
+		return tokenUsage
 	}
-	processTools := func(noAction string, prompt string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) {
+	// processJSON streams the answers of the json mode requests once they are valid JSON documents: the
+	// generations are validated while they are generated, and retried up to the json_attempts of the model
+	processJSON := func(s string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) backend.TokenUsage {
+		initialMessage := schema.OpenAIResponse{
+			ID:      id,
+			Created: created,
+			Model:   req.Model, // we have to return what the user sent here, due to OpenAI spec.
+			Choices: []schema.Choice{{Delta: &schema.Message{Role: "assistant", Content: &textContentToReturn}}},
+			Object:  "chat.completion.chunk",
+		}
+		responses <- initialMessage
+
+		attempts := config.JSONAttempts
+		if attempts <= 0 {
+			attempts = defaultJSONAttempts
+		}
+		result, tokenUsage, err := generateJSON(req.Context, attempts, func(ctx context.Context, attempt int, token func(string)) (backend.TokenUsage, error) {
+			attemptReq := *req
+			attemptReq.Context = ctx
+			_, usage, err := ComputeChoices(&attemptReq, s, jsonAttemptConfig(config, attempt), startupOptions, loader, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
+				token(s)
+				return true
+			})
+			return usage, err
+		})
+
+		choice := schema.Choice{Delta: &schema.Message{Content: &result}, Index: 0}
+		if err != nil {
+			requestLog(req).Error().Err(err).Msg("json mode")
+			choice.FinishReason = "error"
+		}
+		responses <- schema.OpenAIResponse{
+			ID:      id,
+			Created: created,
+			Model:   req.Model, // we have to return what the user sent here, due to OpenAI spec.
+			Choices: []schema.Choice{choice},
+			Object:  "chat.completion.chunk",
+			Usage: schema.OpenAIUsage{
+				PromptTokens:     tokenUsage.Prompt,
+				CompletionTokens: tokenUsage.Completion,
+				TotalTokens:      tokenUsage.Prompt + tokenUsage.Completion,
+			},
+		}
+		close(responses)
+		return tokenUsage
+	}
+	processTools := func(noAction string, prompt string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) backend.TokenUsage {
 		result := ""
 		_, tokenUsage, _ := ComputeChoices(req, prompt, config, startupOptions, loader, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
 			result += s
@@ -70,7 +118,7 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 		textContentToReturn = functions.ParseTextContent(result, config.FunctionsConfig)
 		result = functions.CleanupLLMResult(result, config.FunctionsConfig)
 		results := functions.ParseFunctionCall(result, config.FunctionsConfig)
-		log.Debug().Msgf("Text content to return: %s", textContentToReturn)
+		requestLog(req).Debug().Msgf("Text content to return: %s", textContentToReturn)
 		noActionToRun := len(results) > 0 && results[0].Name == noAction || len(results) == 0
 
 		switch {
@@ -86,8 +134,8 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 
 			result, err := handleQuestion(config, req, ml, startupOptions, results, result, prompt)
 			if err != nil {
-				log.Error().Err(err).Msg("error handling question")
-				return
+				requestLog(req).Error().Err(err).Msg("error handling question")
+				return tokenUsage
 			}
 
 			resp := schema.OpenAIResponse{
@@ -156,6 +204,7 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 		}
 
 		close(responses)
+		return tokenUsage
 	}
 
 	return func(c *fiber.Ctx) error {
@@ -168,30 +217,11 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 		if err != nil {
 			return fmt.Errorf("failed reading parameters from request:%w", err)
 		}
-		log.Debug().Msgf("Configuration read: %+v", config)
-
-		funcs := input.Functions
-		shouldUseFn := len(input.Functions) > 0 && config.ShouldUseFunctions()
-		strictMode := false
-
-		for _, f := range input.Functions {
-			if f.Strict {
-				strictMode = true
-				break
-			}
-		}
+		requestLog(input).Debug().Msgf("Configuration read: %+v", config)
 
-		// Allow the user to set custom actions via config file
-		// to be "embedded" in each model
-		noActionName := "answer"
-		noActionDescription := "use this action to answer without performing any action"
-
-		if config.FunctionsConfig.NoActionFunctionName != "" {
-			noActionName = config.FunctionsConfig.NoActionFunctionName
-		}
-		if config.FunctionsConfig.NoActionDescriptionName != "" {
-			noActionDescription = config.FunctionsConfig.NoActionDescriptionName
-		}
+		funcs, shouldUseFn := chatFunctions(input, config)
+		noActionName := noActionFunction(config).Name
+		jsonMode := false
 
 		if config.ResponseFormatMap != nil {
 			d := schema.ChatCompletionResponseFormat{}
@@ -205,7 +235,9 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 			}
 			if d.Type == "json_object" {
 				input.Grammar = functions.JSONBNF
+				jsonMode = true
 			} else if d.Type == "json_schema" {
+				jsonMode = true
 				d := schema.JsonSchemaRequest{}
 				dat, err := json.Marshal(config.ResponseFormatMap)
 				if err != nil {
@@ -228,33 +260,11 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 		config.Grammar = input.Grammar
 
 		if shouldUseFn {
-			log.Debug().Msgf("Response needs to process functions")
+			requestLog(input).Debug().Msgf("Response needs to process functions")
 		}
 
 		switch {
-		case (!config.FunctionsConfig.GrammarConfig.NoGrammar || strictMode) && shouldUseFn:
-			noActionGrammar := functions.Function{
-				Name:        noActionName,
-				Description: noActionDescription,
-				Parameters: map[string]interface{}{
-					"properties": map[string]interface{}{
-						"message": map[string]interface{}{
-							"type":        "string",
-							"description": "The message to reply the user with",
-						}},
-				},
-			}
-
-			// Append the no action function
-			if !config.FunctionsConfig.DisableNoAction {
-				funcs = append(funcs, noActionGrammar)
-			}
-
-			// Force picking one of the functions by the request
-			if config.FunctionToCall() != "" {
-				funcs = funcs.Select(config.FunctionToCall())
-			}
-
+		case (!config.FunctionsConfig.GrammarConfig.NoGrammar || strictFunctions(input)) && shouldUseFn:
 			// Update input grammar
 			jsStruct := funcs.ToJSONStructure(config.FunctionsConfig.FunctionNameKey, config.FunctionsConfig.FunctionNameKey)
 			g, err := jsStruct.Grammar(config.FunctionsConfig.GrammarOptions()...)
@@ -266,11 +276,6 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 			if err == nil {
 				config.Grammar = g
 			}
-		default:
-			// Force picking one of the functions by the request
-			if config.FunctionToCall() != "" {
-				funcs = funcs.Select(config.FunctionToCall())
-			}
 		}
 
 		// process functions if we have any defined or if we have a function call string
@@ -278,163 +283,16 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 		// functions are not supported in stream mode (yet?)
 		toStream := input.Stream
 
-		log.Debug().Msgf("Parameters: %+v", config)
-
-		var predInput string
-
-		// If we are using the tokenizer template, we don't need to process the messages
-		// unless we are processing functions
-		if !config.TemplateConfig.UseTokenizerTemplate || shouldUseFn {
-			suppressConfigSystemPrompt := false
-			mess := []string{}
-			for messageIndex, i := range input.Messages {
-				var content string
-				role := i.Role
-
-				// if function call, we might want to customize the role so we can display better that the "assistant called a json action"
-				// if an "assistant_function_call" role is defined, we use it, otherwise we use the role that is passed by in the request
-				if (i.FunctionCall != nil || i.ToolCalls != nil) && i.Role == "assistant" {
-					roleFn := "assistant_function_call"
-					r := config.Roles[roleFn]
-					if r != "" {
-						role = roleFn
-					}
-				}
-				r := config.Roles[role]
-				contentExists := i.Content != nil && i.StringContent != ""
-
-				fcall := i.FunctionCall
-				if len(i.ToolCalls) > 0 {
-					fcall = i.ToolCalls
-				}
-
-				// First attempt to populate content via a chat message specific template
-				if config.TemplateConfig.ChatMessage != "" {
-					chatMessageData := model.ChatMessageTemplateData{
-						SystemPrompt: config.SystemPrompt,
-						Role:         r,
-						RoleName:     role,
-						Content:      i.StringContent,
-						FunctionCall: fcall,
-						FunctionName: i.Name,
-						LastMessage:  messageIndex == (len(input.Messages) - 1),
-						Function:     config.Grammar != "" && (messageIndex == (len(input.Messages) - 1)),
-						MessageIndex: messageIndex,
-					}
-					templatedChatMessage, err := ml.EvaluateTemplateForChatMessage(config.TemplateConfig.ChatMessage, chatMessageData)
-					if err != nil {
-						log.Error().Err(err).Interface("message", chatMessageData).Str("template", config.TemplateConfig.ChatMessage).Msg("error processing message with template, skipping")
-					} else {
-						if templatedChatMessage == "" {
-							log.Warn().Msgf("template \"%s\" produced blank output for %+v. Skipping!", config.TemplateConfig.ChatMessage, chatMessageData)
-							continue // TODO: This continue is here intentionally to skip over the line `mess = append(mess, content)` below, and to prevent the sprintf
-						}
-						log.Debug().Msgf("templated message for chat: %s", templatedChatMessage)
-						content = templatedChatMessage
-					}
-				}
-
-				marshalAnyRole := func(f any) {
-					j, err := json.Marshal(f)
-					if err == nil {
-						if contentExists {
-							content += "\n" + fmt.Sprint(r, " ", string(j))
-						} else {
-							content = fmt.Sprint(r, " ", string(j))
-						}
-					}
-				}
-				marshalAny := func(f any) {
-					j, err := json.Marshal(f)
-					if err == nil {
-						if contentExists {
-							content += "\n" + string(j)
-						} else {
-							content = string(j)
-						}
-					}
-				}
-				// If this model doesn't have such a template, or if that template fails to return a value, template at the message level.
-				if content == "" {
-					if r != "" {
-						if contentExists {
-							content = fmt.Sprint(r, i.StringContent)
-						}
-
-						if i.FunctionCall != nil {
-							marshalAnyRole(i.FunctionCall)
-						}
-						if i.ToolCalls != nil {
-							marshalAnyRole(i.ToolCalls)
-						}
-					} else {
-						if contentExists {
-							content = fmt.Sprint(i.StringContent)
-						}
-						if i.FunctionCall != nil {
-							marshalAny(i.FunctionCall)
-						}
-						if i.ToolCalls != nil {
-							marshalAny(i.ToolCalls)
-						}
-					}
-					// Special Handling: System. We care if it was printed at all, not the r branch, so check seperately
-					if contentExists && role == "system" {
-						suppressConfigSystemPrompt = true
-					}
-				}
-
-				mess = append(mess, content)
-			}
+		requestLog(input).Debug().Msgf("Parameters: %+v", config)
 
-			joinCharacter := "\n"
-			if config.TemplateConfig.JoinChatMessagesByCharacter != nil {
-				joinCharacter = *config.TemplateConfig.JoinChatMessagesByCharacter
-			}
+		predInput := chatPrompt(input, config, ml, startupOptions, funcs, shouldUseFn)
 
-			predInput = strings.Join(mess, joinCharacter)
-			log.Debug().Msgf("Prompt (before templating): %s", predInput)
-
-			templateFile := ""
-
-			// A model can have a "file.bin.tmpl" file associated with a prompt template prefix
-			if ml.ExistsInModelPath(fmt.Sprintf("%s.tmpl", config.Model)) {
-				templateFile = config.Model
-			}
-
-			if config.TemplateConfig.Chat != "" && !shouldUseFn {
-				templateFile = config.TemplateConfig.Chat
-			}
-
-			if config.TemplateConfig.Functions != "" && shouldUseFn {
-				templateFile = config.TemplateConfig.Functions
-			}
-
-			if templateFile != "" {
-				templatedInput, err := ml.EvaluateTemplateForPrompt(model.ChatPromptTemplate, templateFile, model.PromptTemplateData{
-					SystemPrompt:         config.SystemPrompt,
-					SuppressSystemPrompt: suppressConfigSystemPrompt,
-					Input:                predInput,
-					Functions:            funcs,
-				})
-				if err == nil {
-					predInput = templatedInput
-					log.Debug().Msgf("Template found, input modified to: %s", predInput)
-				} else {
-					log.Debug().Msgf("Template failed loading: %s", err.Error())
-				}
-			}
-
-			log.Debug().Msgf("Prompt (after templating): %s", predInput)
-			if shouldUseFn && config.Grammar != "" {
-				log.Debug().Msgf("Grammar: %+v", config.Grammar)
-			}
-		}
+		shadowDone := shadowChat(input, config, cl, ml, startupOptions)
 
 		switch {
 		case toStream:
 
-			log.Debug().Msgf("Stream request received")
+			requestLog(input).Debug().Msgf("Stream request received")
 			c.Context().SetContentType("text/event-stream")
 			//c.Response().Header.SetContentType(fiber.MIMETextHTMLCharsetUTF8)
 			//	c.Set("Content-Type", "text/event-stream")
@@ -443,35 +301,60 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 			c.Set("Transfer-Encoding", "chunked")
 
 			responses := make(chan schema.OpenAIResponse)
+			usages := make(chan backend.TokenUsage, 1)
+			sendTiming := timingRequested(c)
+			recordUsage := fiberContext.TokenUsageRecorder(c)
+			streamProgress(c, input, responses)
 
-			if !shouldUseFn {
-				go process(predInput, input, config, ml, responses)
-			} else {
-				go processTools(noActionName, predInput, input, config, ml, responses)
-			}
+			go func() {
+				switch {
+				case shouldUseFn:
+					usages <- processTools(noActionName, predInput, input, config, ml, responses)
+				case jsonMode:
+					usages <- processJSON(predInput, input, config, ml, responses)
+				default:
+					usages <- process(predInput, input, config, ml, responses)
+				}
+			}()
 
 			c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+				defer input.Cancel()
 				usage := &schema.OpenAIUsage{}
 				toolsCalled := false
+				failed := false
+				streamed := strings.Builder{}
 				for ev := range responses {
+					if ev.Progress != nil {
+						writeProgressEvent(w, *ev.Progress)
+						w.Flush()
+						continue
+					}
 					usage = &ev.Usage // Copy a pointer to the latest usage chunk so that the stop message can reference it
 					if len(ev.Choices[0].Delta.ToolCalls) > 0 {
 						toolsCalled = true
 					}
+					if ev.Choices[0].FinishReason == "error" {
+						failed = true
+					}
+					if content, ok := ev.Choices[0].Delta.Content.(*string); ok && content != nil {
+						streamed.WriteString(*content)
+					}
 					var buf bytes.Buffer
 					enc := json.NewEncoder(&buf)
 					enc.Encode(ev)
-					log.Debug().Msgf("Sending chunk: %s", buf.String())
+					requestLog(input).Debug().Msgf("Sending chunk: %s", buf.String())
 					_, err := fmt.Fprintf(w, "data: %v\n", buf.String())
 					if err != nil {
-						log.Debug().Msgf("Sending chunk failed: %v", err)
+						requestLog(input).Debug().Msgf("Sending chunk failed: %v", err)
 						input.Cancel()
 					}
 					w.Flush()
 				}
 
 				finishReason := "stop"
-				if toolsCalled {
+				if failed {
+					finishReason = "error"
+				} else if toolsCalled {
 					finishReason = "tool_calls"
 				} else if toolsCalled && len(input.Tools) == 0 {
 					finishReason = "function_call"
@@ -493,8 +376,14 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 				respData, _ := json.Marshal(resp)
 
 				w.WriteString(fmt.Sprintf("data: %s\n\n", respData))
+				tokenUsage := <-usages
+				if sendTiming {
+					writeTimingEvent(w, tokenUsage)
+				}
 				w.WriteString("data: [DONE]\n\n")
 				w.Flush()
+				recordUsage(tokenUsage.Prompt + tokenUsage.Completion)
+				shadowDone(streamed.String())
 			}))
 			return nil
 
@@ -510,14 +399,14 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 				textContentToReturn = functions.ParseTextContent(s, config.FunctionsConfig)
 				s = functions.CleanupLLMResult(s, config.FunctionsConfig)
 				results := functions.ParseFunctionCall(s, config.FunctionsConfig)
-				log.Debug().Msgf("Text content to return: %s", textContentToReturn)
+				requestLog(input).Debug().Msgf("Text content to return: %s", textContentToReturn)
 				noActionsToRun := len(results) > 0 && results[0].Name == noActionName || len(results) == 0
 
 				switch {
 				case noActionsToRun:
 					result, err := handleQuestion(config, input, ml, startupOptions, results, s, predInput)
 					if err != nil {
-						log.Error().Err(err).Msg("error handling question")
+						requestLog(input).Error().Err(err).Msg("error handling question")
 						return
 					}
 					*c = append(*c, schema.Choice{
@@ -572,9 +461,11 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 				}
 
 			}, nil)
+			shadowDone(choiceContent(result))
 			if err != nil {
 				return err
 			}
+			fiberContext.TokenUsageRecorder(c)(tokenUsage.Prompt + tokenUsage.Completion)
 
 			resp := &schema.OpenAIResponse{
 				ID:      id,
@@ -589,7 +480,11 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 				},
 			}
 			respData, _ := json.Marshal(resp)
-			log.Debug().Msgf("Response: %s", respData)
+			requestLog(input).Debug().Msgf("Response: %s", respData)
+
+			if timingRequested(c) {
+				setTimingHeader(c, tokenUsage)
+			}
 
 			// Return the prediction in the response body
 			return c.JSON(resp)
@@ -597,15 +492,84 @@ func ChatEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, startup
 	}
 }
 
+// strictFunctions tells if the request requires the model to follow the schema of its functions
+func strictFunctions(input *schema.OpenAIRequest) bool {
+	for _, f := range input.Functions {
+		if f.Strict {
+			return true
+		}
+	}
+	return false
+}
+
+// noActionFunction returns the function the model calls to answer without performing any action.
+// Its name and description can be customized in the model configuration
+func noActionFunction(config *config.BackendConfig) functions.Function {
+	noActionName := "answer"
+	noActionDescription := "use this action to answer without performing any action"
+
+	if config.FunctionsConfig.NoActionFunctionName != "" {
+		noActionName = config.FunctionsConfig.NoActionFunctionName
+	}
+	if config.FunctionsConfig.NoActionDescriptionName != "" {
+		noActionDescription = config.FunctionsConfig.NoActionDescriptionName
+	}
+
+	return functions.Function{
+		Name:        noActionName,
+		Description: noActionDescription,
+		Parameters: map[string]interface{}{
+			"properties": map[string]interface{}{
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "The message to reply the user with",
+				}},
+		},
+	}
+}
+
+// chatFunctions returns the functions of a chat request that are passed to the model, and if
+// the model has to call functions
+func chatFunctions(input *schema.OpenAIRequest, config *config.BackendConfig) (functions.Functions, bool) {
+	funcs := input.Functions
+	shouldUseFn := len(input.Functions) > 0 && config.ShouldUseFunctions()
+
+	switch {
+	case (!config.FunctionsConfig.GrammarConfig.NoGrammar || strictFunctions(input)) && shouldUseFn:
+		// Append the no action function
+		if !config.FunctionsConfig.DisableNoAction {
+			funcs = append(funcs, noActionFunction(config))
+		}
+
+		// Force picking one of the functions by the request
+		if config.FunctionToCall() != "" {
+			funcs = funcs.Select(config.FunctionToCall())
+		}
+	case input.JSONFunctionGrammarObject != nil:
+	default:
+		// Force picking one of the functions by the request
+		if config.FunctionToCall() != "" {
+			funcs = funcs.Select(config.FunctionToCall())
+		}
+	}
+
+	return funcs, shouldUseFn
+}
+
+// chatPrompt renders the messages of the request with the model templates, see backend.ChatPrompt
+func chatPrompt(input *schema.OpenAIRequest, config *config.BackendConfig, ml *model.ModelLoader, appConfig *config.ApplicationConfig, funcs functions.Functions, shouldUseFn bool) string {
+	return backend.ChatPrompt(input.Context, input.Messages, funcs, shouldUseFn, ml, *config, appConfig)
+}
+
 func handleQuestion(config *config.BackendConfig, input *schema.OpenAIRequest, ml *model.ModelLoader, o *config.ApplicationConfig, funcResults []functions.FuncCallResults, result, prompt string) (string, error) {
 
 	if len(funcResults) == 0 && result != "" {
-		log.Debug().Msgf("nothing function results but we had a message from the LLM")
+		requestLog(input).Debug().Msgf("nothing function results but we had a message from the LLM")
 
 		return result, nil
 	}
 
-	log.Debug().Msgf("nothing to do, computing a reply")
+	requestLog(input).Debug().Msgf("nothing to do, computing a reply")
 	arg := ""
 	if len(funcResults) > 0 {
 		arg = funcResults[0].Arguments
@@ -613,23 +577,23 @@ func handleQuestion(config *config.BackendConfig, input *schema.OpenAIRequest, m
 	// If there is a message that the LLM already sends as part of the JSON reply, use it
 	arguments := map[string]interface{}{}
 	if err := json.Unmarshal([]byte(arg), &arguments); err != nil {
-		log.Debug().Msg("handleQuestion: function result did not contain a valid JSON object")
+		requestLog(input).Debug().Msg("handleQuestion: function result did not contain a valid JSON object")
 	}
 	m, exists := arguments["message"]
 	if exists {
 		switch message := m.(type) {
 		case string:
 			if message != "" {
-				log.Debug().Msgf("Reply received from LLM: %s", message)
+				requestLog(input).Debug().Msgf("Reply received from LLM: %s", message)
 				message = backend.Finetune(*config, prompt, message)
-				log.Debug().Msgf("Reply received from LLM(finetuned): %s", message)
+				requestLog(input).Debug().Msgf("Reply received from LLM(finetuned): %s", message)
 
 				return message, nil
 			}
 		}
 	}
 
-	log.Debug().Msgf("No action received from LLM, without a message, computing a reply")
+	requestLog(input).Debug().Msgf("No action received from LLM, without a message, computing a reply")
 	// Otherwise ask the LLM to understand the JSON output and the context, and return a message
 	// Note: This costs (in term of CPU/GPU) another computation
 	config.Grammar = ""
@@ -640,13 +604,13 @@ func handleQuestion(config *config.BackendConfig, input *schema.OpenAIRequest, m
 
 	predFunc, err := backend.ModelInference(input.Context, prompt, input.Messages, images, ml, *config, o, nil)
 	if err != nil {
-		log.Error().Err(err).Msg("model inference failed")
+		requestLog(input).Error().Err(err).Msg("model inference failed")
 		return "", err
 	}
 
 	prediction, err := predFunc()
 	if err != nil {
-		log.Error().Err(err).Msg("prediction failed")
+		requestLog(input).Error().Err(err).Msg("prediction failed")
 		return "", err
 	}
 	return backend.Finetune(*config, prompt, prediction.Response), nil
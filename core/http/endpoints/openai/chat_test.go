@@ -0,0 +1,67 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/functions"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJinjaTemplate = `{%- if tools %}<|tools|>{% for tool in tools %}{{ tool.function.name }}{% endfor %}
+{% endif %}
+{%- for message in messages %}<|{{ message.role }}|>{{ message.content }}
+{%- if message.tool_calls %}{% for call in message.tool_calls %}{{ call.function.name }}({{ call.function.arguments.city }}){% endfor %}{% endif %}
+{% endfor %}
+{%- if add_generation_prompt %}<|assistant|>{% endif %}`
+
+func TestChatPromptJinja(t *testing.T) {
+	ml := model.NewModelLoader(t.TempDir())
+	appConfig := config.NewApplicationConfig()
+	cfg := &config.BackendConfig{
+		TemplateConfig: config.TemplateConfig{
+			Jinja: testJinjaTemplate,
+		},
+	}
+	input := &schema.OpenAIRequest{
+		Messages: []schema.Message{
+			{Role: "user", StringContent: "weather in Rome?"},
+			{Role: "assistant", ToolCalls: []schema.ToolCall{
+				{ID: "1", Type: "function", FunctionCall: schema.FunctionCall{Name: "weather", Arguments: `{"city": "Rome"}`}},
+			}},
+			{Role: "tool", StringContent: "sunny"},
+		},
+	}
+	funcs := functions.Functions{{Name: "weather"}}
+
+	assert.Equal(t, "<|user|>weather in Rome?\n<|assistant|>weather(Rome)\n<|tool|>sunny\n<|assistant|>",
+		chatPrompt(input, cfg, ml, appConfig, funcs, false))
+	assert.Equal(t, "<|tools|>weather\n<|user|>weather in Rome?\n<|assistant|>weather(Rome)\n<|tool|>sunny\n<|assistant|>",
+		chatPrompt(input, cfg, ml, appConfig, funcs, true))
+
+	// The template for the functions takes precedence when functions are used
+	cfg.TemplateConfig.Functions = "functions: {{.Input}}"
+	assert.Contains(t, chatPrompt(input, cfg, ml, appConfig, funcs, true), "functions: ")
+}
+
+func TestChatPromptVariables(t *testing.T) {
+	ml := model.NewModelLoader(t.TempDir())
+	appConfig := config.NewApplicationConfig()
+	cfg := &config.BackendConfig{
+		TemplateConfig: config.TemplateConfig{
+			Chat:        "{{.Variables.header}}\n{{.Input}}",
+			ChatMessage: "{{.RoleName}}: {{.Content}}",
+			Variables:   map[string]interface{}{"header": "# Chat", "bos_token": "<s>"},
+		},
+	}
+	input := &schema.OpenAIRequest{
+		Messages: []schema.Message{{Role: "user", StringContent: "hi"}},
+	}
+
+	assert.Equal(t, "# Chat\nuser: hi", chatPrompt(input, cfg, ml, appConfig, nil, false))
+
+	cfg.TemplateConfig.Jinja = "{{ bos_token }}{% for message in messages %}{{ message.content }}{% endfor %}"
+	assert.Equal(t, "<s>hi", chatPrompt(input, cfg, ml, appConfig, nil, false))
+}
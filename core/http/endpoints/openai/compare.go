@@ -0,0 +1,120 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// CompareEndpoint runs the same chat completion request with several models
+// @Summary Runs a chat completion request with several models concurrently, and returns their outputs side by side with their timings.
+// @Param request body schema.CompareRequest true "query params"
+// @Success 200 {object} schema.CompareResponse "Response"
+// @Router /v1/experimental/compare [post]
+func CompareEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.CompareRequest)
+		if err := c.BodyParser(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed parsing request body: %s", err))
+		}
+
+		if len(input.Models) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "no models to compare")
+		}
+		if len(input.Messages) == 0 {
+			prompt, ok := input.Prompt.(string)
+			if !ok || prompt == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "a prompt or messages are required")
+			}
+			input.Messages = []schema.Message{{Role: "user", Content: prompt}}
+		}
+
+		ctx, cancel := context.WithCancel(fiberContext.RequestContext(c, appConfig.Context))
+		defer cancel()
+
+		tenant := fiberContext.TenantFromContext(c)
+		results := make([]schema.CompareResult, len(input.Models))
+		wg := sync.WaitGroup{}
+		for i, m := range input.Models {
+			if tenant != nil && !tenant.CanUseModel(m) {
+				results[i] = schema.CompareResult{Model: m, Error: fmt.Sprintf("model %s not found", m)}
+				continue
+			}
+			wg.Add(1)
+			run := func(i int, m string) {
+				defer wg.Done()
+				results[i] = compareModel(ctx, m, input.OpenAIRequest, cl, ml, appConfig)
+			}
+			// With a single backend the models are loaded one at a time anyway
+			if appConfig.SingleBackend {
+				run(i, m)
+			} else {
+				go run(i, m)
+			}
+		}
+		wg.Wait()
+
+		recordUsage := fiberContext.TokenUsageRecorder(c)
+		for _, r := range results {
+			recordUsage(r.Usage.TotalTokens)
+		}
+
+		return c.JSON(schema.CompareResponse{
+			Created: int(time.Now().Unix()),
+			Results: results,
+		})
+	}
+}
+
+// compareModel runs the request with a model. The failures are reported in the result, so
+// that the other models of the comparison are still returned
+func compareModel(ctx context.Context, modelName string, input schema.OpenAIRequest, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) (result schema.CompareResult) {
+	result.Model = modelName
+	start := time.Now()
+	defer func() {
+		result.Duration = float64(time.Since(start).Microseconds()) / 1000
+		if result.Error != "" {
+			log.Debug().Msgf("Compare: model %s failed: %s", modelName, result.Error)
+		}
+	}()
+
+	// The messages are updated with the config of each model
+	request := input
+	request.Model = modelName
+	request.Messages = append([]schema.Message{}, input.Messages...)
+	request.Context = ctx
+
+	cfg, req, err := mergeRequestWithConfig(modelName, &request, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed reading parameters from request: %s", err)
+		return
+	}
+
+	predInput := chatPrompt(req, cfg, ml, appConfig, nil, false)
+	choices, usage, err := ComputeChoices(req, predInput, cfg, appConfig, ml, func(s string, c *[]schema.Choice) {
+		*c = append(*c, schema.Choice{Text: s})
+	}, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	if len(choices) > 0 {
+		result.Output = choices[0].Text
+	}
+	result.Usage = schema.OpenAIUsage{
+		PromptTokens:     usage.Prompt,
+		CompletionTokens: usage.Completion,
+		TotalTokens:      usage.Prompt + usage.Completion,
+	}
+	result.Timing = generationTiming(usage)
+	return
+}
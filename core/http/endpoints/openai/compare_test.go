@@ -0,0 +1,60 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareEndpoint(t *testing.T) {
+	modelPath := t.TempDir()
+	for _, name := range []string{"foo", "bar"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(modelPath, name+".yaml"), []byte(`name: `+name+`
+backend: missing-backend
+parameters:
+  model: `+name+`.gguf
+`), 0600))
+	}
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+	appConfig := config.NewApplicationConfig(config.WithModelPath(modelPath))
+
+	app := fiber.New()
+	app.Post("/v1/experimental/compare", CompareEndpoint(cl, ml, appConfig))
+
+	post := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/v1/experimental/compare", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, 400, post(`{"prompt": "hi"}`).StatusCode)
+	assert.Equal(t, 400, post(`{"models": ["foo"]}`).StatusCode)
+
+	// The failures are reported for each model, in the order of the request
+	resp := post(`{"models": ["foo", "bar"], "prompt": "hi"}`)
+	assert.Equal(t, 200, resp.StatusCode)
+	var r schema.CompareResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+	if assert.Len(t, r.Results, 2) {
+		assert.Equal(t, "foo", r.Results[0].Model)
+		assert.Equal(t, "bar", r.Results[1].Model)
+		assert.NotEmpty(t, r.Results[0].Error)
+		assert.NotEmpty(t, r.Results[1].Error)
+		assert.Empty(t, r.Results[0].Output)
+	}
+}
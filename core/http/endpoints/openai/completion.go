@@ -10,13 +10,13 @@ import (
 
 	"github.com/mudler/LocalAI/core/backend"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/pkg/functions"
 	model "github.com/mudler/LocalAI/pkg/model"
-	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
 )
 
@@ -25,12 +25,13 @@ import (
 // @Param request body schema.OpenAIRequest true "query params"
 // @Success 200 {object} schema.OpenAIResponse "Response"
 // @Router /v1/completions [post]
+// @Router /v1/infill [post]
 func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
 	id := uuid.New().String()
 	created := int(time.Now().Unix())
 
-	process := func(s string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) {
-		ComputeChoices(req, s, config, appConfig, loader, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
+	process := func(s string, req *schema.OpenAIRequest, config *config.BackendConfig, loader *model.ModelLoader, responses chan schema.OpenAIResponse) backend.TokenUsage {
+		_, tokenUsage, _ := ComputeChoices(req, s, config, appConfig, loader, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
 			resp := schema.OpenAIResponse{
 				ID:      id,
 				Created: created,
@@ -48,12 +49,13 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 					TotalTokens:      usage.Prompt + usage.Completion,
 				},
 			}
-			log.Debug().Msgf("Sending goroutine: %s", s)
+			requestLog(req).Debug().Msgf("Sending goroutine: %s", s)
 
 			responses <- resp
 			return true
 		})
 		close(responses)
+		return tokenUsage
 	}
 
 	return func(c *fiber.Ctx) error {
@@ -62,7 +64,7 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 			return fmt.Errorf("failed reading parameters from request:%w", err)
 		}
 
-		log.Debug().Msgf("`input`: %+v", input)
+		requestLog(input).Debug().Msgf("`input`: %+v", input)
 
 		config, input, err := mergeRequestWithConfig(modelFile, input, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
 		if err != nil {
@@ -80,10 +82,10 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 
 		config.Grammar = input.Grammar
 
-		log.Debug().Msgf("Parameter Config: %+v", config)
+		requestLog(input).Debug().Msgf("Parameter Config: %+v", config)
 
 		if input.Stream {
-			log.Debug().Msgf("Stream request received")
+			requestLog(input).Debug().Msgf("Stream request received")
 			c.Context().SetContentType("text/event-stream")
 			//c.Response().Header.SetContentType(fiber.MIMETextHTMLCharsetUTF8)
 			//c.Set("Content-Type", "text/event-stream")
@@ -92,47 +94,43 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 			c.Set("Transfer-Encoding", "chunked")
 		}
 
-		templateFile := ""
-
-		// A model can have a "file.bin.tmpl" file associated with a prompt template prefix
-		if ml.ExistsInModelPath(fmt.Sprintf("%s.tmpl", config.Model)) {
-			templateFile = config.Model
-		}
-
-		if config.TemplateConfig.Completion != "" {
-			templateFile = config.TemplateConfig.Completion
-		}
+		templateFile := completionTemplateFile(ml, config)
+		suffix := fimSuffix(config)
 
 		if input.Stream {
 			if len(config.PromptStrings) > 1 {
 				return errors.New("cannot handle more than 1 `PromptStrings` when Streaming")
 			}
 
-			predInput := config.PromptStrings[0]
-
-			if templateFile != "" {
-				templatedInput, err := ml.EvaluateTemplateForPrompt(model.CompletionPromptTemplate, templateFile, model.PromptTemplateData{
-					Input:        predInput,
-					SystemPrompt: config.SystemPrompt,
-				})
-				if err == nil {
-					predInput = templatedInput
-					log.Debug().Msgf("Template found, input modified to: %s", predInput)
-				}
+			predInput, err := templateCompletion(ml, config, appConfig, input, config.PromptStrings[0], suffix, templateFile)
+			if err != nil {
+				return err
 			}
 
 			responses := make(chan schema.OpenAIResponse)
+			usages := make(chan backend.TokenUsage, 1)
+			sendTiming := timingRequested(c)
+			recordUsage := fiberContext.TokenUsageRecorder(c)
+			streamProgress(c, input, responses)
 
-			go process(predInput, input, config, ml, responses)
+			go func() {
+				usages <- process(predInput, input, config, ml, responses)
+			}()
 
 			c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+				defer input.Cancel()
 
 				for ev := range responses {
+					if ev.Progress != nil {
+						writeProgressEvent(w, *ev.Progress)
+						w.Flush()
+						continue
+					}
 					var buf bytes.Buffer
 					enc := json.NewEncoder(&buf)
 					enc.Encode(ev)
 
-					log.Debug().Msgf("Sending chunk: %s", buf.String())
+					requestLog(input).Debug().Msgf("Sending chunk: %s", buf.String())
 					fmt.Fprintf(w, "data: %v\n", buf.String())
 					w.Flush()
 				}
@@ -152,8 +150,13 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 				respData, _ := json.Marshal(resp)
 
 				w.WriteString(fmt.Sprintf("data: %s\n\n", respData))
+				tokenUsage := <-usages
+				if sendTiming {
+					writeTimingEvent(w, tokenUsage)
+				}
 				w.WriteString("data: [DONE]\n\n")
 				w.Flush()
+				recordUsage(tokenUsage.Prompt + tokenUsage.Completion)
 			}))
 			return nil
 		}
@@ -163,16 +166,9 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 		totalTokenUsage := backend.TokenUsage{}
 
 		for k, i := range config.PromptStrings {
-			if templateFile != "" {
-				// A model can have a "file.bin.tmpl" file associated with a prompt template prefix
-				templatedInput, err := ml.EvaluateTemplateForPrompt(model.CompletionPromptTemplate, templateFile, model.PromptTemplateData{
-					SystemPrompt: config.SystemPrompt,
-					Input:        i,
-				})
-				if err == nil {
-					i = templatedInput
-					log.Debug().Msgf("Template found, input modified to: %s", i)
-				}
+			i, err := templateCompletion(ml, config, appConfig, input, i, suffix, templateFile)
+			if err != nil {
+				return err
 			}
 
 			r, tokenUsage, err := ComputeChoices(
@@ -182,9 +178,15 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 			if err != nil {
 				return err
 			}
+			fiberContext.TokenUsageRecorder(c)(tokenUsage.Prompt + tokenUsage.Completion)
 
 			totalTokenUsage.Prompt += tokenUsage.Prompt
 			totalTokenUsage.Completion += tokenUsage.Completion
+			totalTokenUsage.TimingPromptProcessing += tokenUsage.TimingPromptProcessing
+			totalTokenUsage.TimingTokenGeneration += tokenUsage.TimingTokenGeneration
+			if k == 0 {
+				totalTokenUsage.TimingTimeToFirstToken = tokenUsage.TimingTimeToFirstToken
+			}
 
 			result = append(result, r...)
 		}
@@ -203,9 +205,69 @@ func CompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 		}
 
 		jsonResult, _ := json.Marshal(resp)
-		log.Debug().Msgf("Response: %s", jsonResult)
+		requestLog(input).Debug().Msgf("Response: %s", jsonResult)
+
+		if timingRequested(c) {
+			setTimingHeader(c, totalTokenUsage)
+		}
 
 		// Return the prediction in the response body
 		return c.JSON(resp)
 	}
 }
+
+// completionTemplateFile returns the template of the completion prompts of the model, if any
+func completionTemplateFile(ml *model.ModelLoader, config *config.BackendConfig) string {
+	if config.TemplateConfig.Completion != "" {
+		return config.TemplateConfig.Completion
+	}
+
+	// A model can have a "file.bin.tmpl" file associated with a prompt template prefix
+	if ml.ExistsInModelPath(fmt.Sprintf("%s.tmpl", config.Model)) {
+		return config.Model
+	}
+
+	return ""
+}
+
+// fimSuffix returns the suffix of the fill-in-the-middle requests rendered with the fim template
+// of the model. Without it, the suffix is left to the backend, as llama.cpp which uses the infill
+// tokens of the model
+func fimSuffix(config *config.BackendConfig) string {
+	suffix := ""
+	if config.Suffix != "" && config.TemplateConfig.FIM != "" {
+		suffix, config.Suffix = config.Suffix, ""
+	}
+	return suffix
+}
+
+// templateCompletion renders the prompt of a completion with the fim template of the model when
+// there is a suffix, or with its completion template
+func templateCompletion(ml *model.ModelLoader, config *config.BackendConfig, appConfig *config.ApplicationConfig, input *schema.OpenAIRequest, prompt, suffix, templateFile string) (string, error) {
+	if suffix != "" {
+		templatedInput, err := ml.EvaluateTemplateForFIM(config.TemplateConfig.FIM, model.FIMTemplateData{
+			Prefix:    prompt,
+			Suffix:    suffix,
+			Variables: config.TemplateConfig.Variables,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed rendering the fim template: %w", err)
+		}
+		return templatedInput, nil
+	}
+
+	if templateFile != "" {
+		templatedInput, err := ml.EvaluateTemplateForPrompt(model.CompletionPromptTemplate, templateFile, model.PromptTemplateData{
+			SystemPrompt: config.SystemPrompt,
+			Input:        prompt,
+			Variables:    config.TemplateConfig.Variables,
+			Tokenizer:    backend.ModelTokenizer(ml, *config, appConfig),
+		})
+		if err == nil {
+			requestLog(input).Debug().Msgf("Template found, input modified to: %s", templatedInput)
+			return templatedInput, nil
+		}
+	}
+
+	return prompt, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mudler/LocalAI/core/backend"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -55,6 +56,8 @@ func EditEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConf
 					Input:        i,
 					Instruction:  input.Instruction,
 					SystemPrompt: config.SystemPrompt,
+					Variables:    config.TemplateConfig.Variables,
+					Tokenizer:    backend.ModelTokenizer(ml, *config, appConfig),
 				})
 				if err == nil {
 					i = templatedInput
@@ -68,6 +71,7 @@ func EditEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConf
 			if err != nil {
 				return err
 			}
+			fiberContext.TokenUsageRecorder(c)(tokenUsage.Prompt + tokenUsage.Completion)
 
 			totalTokenUsage.Prompt += tokenUsage.Prompt
 			totalTokenUsage.Completion += tokenUsage.Completion
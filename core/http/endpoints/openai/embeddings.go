@@ -1,13 +1,16 @@
 package openai
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mudler/LocalAI/core/backend"
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/utils"
 
 	"github.com/google/uuid"
 	"github.com/mudler/LocalAI/core/schema"
@@ -36,6 +39,11 @@ func EmbeddingsEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 		log.Debug().Msgf("Parameter Config: %+v", config)
 		items := []schema.Item{}
 
+		// The images are indexed as the texts, a request can't have both
+		if len(config.InputImages) > 0 && (len(config.InputStrings) > 0 || len(config.InputToken) > 0) {
+			return fiber.NewError(fiber.StatusBadRequest, "the input mixes texts and images, they must be embedded by separate requests")
+		}
+
 		for i, s := range config.InputToken {
 			// get the model function to call for the result
 			embedFn, err := backend.ModelEmbedding("", s, ml, *config, appConfig)
@@ -64,6 +72,24 @@ func EmbeddingsEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 			items = append(items, schema.Item{Embedding: embeddings, Index: i, Object: "embedding"})
 		}
 
+		for i, image := range config.InputImages {
+			b64, err := embeddingImage(image)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+
+			embedFn, err := backend.ModelImageEmbedding(b64, ml, *config, appConfig)
+			if err != nil {
+				return err
+			}
+
+			embeddings, err := embedFn()
+			if err != nil {
+				return err
+			}
+			items = append(items, schema.Item{Embedding: embeddings, Index: i, Object: "embedding"})
+		}
+
 		id := uuid.New().String()
 		created := int(time.Now().Unix())
 		resp := &schema.OpenAIResponse{
@@ -81,3 +107,18 @@ func EmbeddingsEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 		return c.JSON(resp)
 	}
 }
+
+// embeddingImage returns the image of the input as base64: the URLs are downloaded, and the prefix of the data URIs is dropped
+func embeddingImage(image string) (string, error) {
+	b64, err := utils.GetImageURLAsBase64(image)
+	if err == nil {
+		return b64, nil
+	}
+	if strings.HasPrefix(image, "http") {
+		return "", fmt.Errorf("failed downloading image: %w", err)
+	}
+	if _, err := base64.StdEncoding.DecodeString(image); err != nil {
+		return "", fmt.Errorf("invalid image: it is neither an URL nor base64")
+	}
+	return image, nil
+}
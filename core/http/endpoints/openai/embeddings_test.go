@@ -0,0 +1,34 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingInputImages(t *testing.T) {
+	cfg := &config.BackendConfig{}
+	updateRequestConfig(cfg, &schema.OpenAIRequest{Input: []interface{}{
+		map[string]interface{}{"type": "image", "image": "aW1hZ2U="},
+		map[string]interface{}{"image": "https://example.com/cat.png"},
+		map[string]interface{}{"type": "text", "text": "a cat"},
+	}})
+	assert.Equal(t, []string{"aW1hZ2U=", "https://example.com/cat.png"}, cfg.InputImages)
+	assert.Equal(t, []string{"a cat"}, cfg.InputStrings)
+}
+
+func TestEmbeddingImage(t *testing.T) {
+	image, err := embeddingImage("aW1hZ2U=")
+	require.NoError(t, err)
+	assert.Equal(t, "aW1hZ2U=", image)
+
+	image, err = embeddingImage("data:image/png;base64,aW1hZ2U=")
+	require.NoError(t, err)
+	assert.Equal(t, "aW1hZ2U=", image)
+
+	_, err = embeddingImage("not an image")
+	assert.Error(t, err)
+}
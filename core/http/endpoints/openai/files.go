@@ -1,15 +1,24 @@
 package openai
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/storage"
+	"github.com/rs/zerolog/log"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/pkg/utils"
@@ -17,7 +26,92 @@ import (
 
 var UploadedFiles []schema.File
 
+// UploadedFilesOwners maps the uploaded file IDs to a hash of the API key that uploaded them,
+// or to the tenant of the API key
+var UploadedFilesOwners = map[string]string{}
+
+var uploadedFilesMutex sync.Mutex
+
 const UploadedFilesFile = "uploadedFiles.json"
+const UploadedFilesOwnersFile = "uploadedFilesOwners.json"
+
+var FilePurposes = []string{"fine-tune", "assistants", "batch"}
+
+func uploadStorage(appConfig *config.ApplicationConfig) storage.Storage {
+	if appConfig.UploadStorage != nil {
+		return appConfig.UploadStorage
+	}
+	return storage.NewLocal(appConfig.UploadDir)
+}
+
+func saveUploadedFiles(appConfig *config.ApplicationConfig) {
+	utils.SaveConfig(appConfig.UploadDir, UploadedFilesFile, UploadedFiles)
+	utils.SaveConfig(appConfig.UploadDir, UploadedFilesOwnersFile, UploadedFilesOwners)
+}
+
+// fileOwner returns the owner of the files uploaded by the request: API keys are not stored as-is.
+// The files of a tenant are owned by the tenant, and shared by all its API keys.
+func fileOwner(c *fiber.Ctx) string {
+	return fiberContext.Owner(c)
+}
+
+// fileTenant returns the tenant owning the file, if any
+func fileTenant(id string) string {
+	if tenant, ok := strings.CutPrefix(UploadedFilesOwners[id], "tenant:"); ok {
+		return tenant
+	}
+	return ""
+}
+
+// filePath returns where the file is stored: the files of the tenants are kept in their own directory
+func filePath(tenant, filename string) string {
+	filename = utils.SanitizeFileName(filename)
+	if tenant == "" {
+		return filename
+	}
+	return path.Join(tenant, filename)
+}
+
+// fileVisible tells if the file can be seen by the request: the files of a tenant are only
+// visible to its API keys, which do not see the other files
+func fileVisible(c *fiber.Ctx, f schema.File) bool {
+	tenant := ""
+	if t := fiberContext.TenantFromContext(c); t != nil {
+		tenant = t.Name
+	}
+	return fileTenant(f.ID) == tenant
+}
+
+func ownerUsage(owner string) int64 {
+	var usage int64
+	for _, f := range UploadedFiles {
+		if UploadedFilesOwners[f.ID] == owner {
+			usage += int64(f.Bytes)
+		}
+	}
+	return usage
+}
+
+// fileExpiration returns when an uploaded file expires, either as requested with expires_after
+// or after the configured retention. It returns nil if the file never expires.
+func fileExpiration(c *fiber.Ctx, appConfig *config.ApplicationConfig, createdAt time.Time) (*time.Time, error) {
+	if seconds := c.FormValue("expires_after[seconds]"); seconds != "" {
+		s, err := strconv.Atoi(seconds)
+		if err != nil || s <= 0 {
+			return nil, fmt.Errorf("invalid expires_after[seconds]: %s", seconds)
+		}
+		expiresAt := createdAt.Add(time.Duration(s) * time.Second)
+		if appConfig.UploadRetention == 0 || time.Duration(s)*time.Second < appConfig.UploadRetention {
+			return &expiresAt, nil
+		}
+	}
+
+	if appConfig.UploadRetention > 0 {
+		expiresAt := createdAt.Add(appConfig.UploadRetention)
+		return &expiresAt, nil
+	}
+	return nil, nil
+}
 
 // UploadFilesEndpoint https://platform.openai.com/docs/api-reference/files/create
 func UploadFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
@@ -32,37 +126,71 @@ func UploadFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.Appli
 			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("File size %d exceeds upload limit %d", file.Size, appConfig.UploadLimitMB))
 		}
 
-		purpose := c.FormValue("purpose", "") //TODO put in purpose dirs
+		purpose := c.FormValue("purpose", "")
 		if purpose == "" {
 			return c.Status(fiber.StatusBadRequest).SendString("Purpose is not defined")
 		}
+		if !slices.Contains(FilePurposes, purpose) {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Invalid purpose %s, must be one of %v", purpose, FilePurposes))
+		}
+
+		createdAt := time.Now()
+		expiresAt, err := fileExpiration(c, appConfig, createdAt)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+
+		uploadedFilesMutex.Lock()
+		defer uploadedFilesMutex.Unlock()
+
+		owner := fileOwner(c)
+		if appConfig.UploadQuotaMB > 0 && ownerUsage(owner)+file.Size > int64(appConfig.UploadQuotaMB*1024*1024) {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("File size %d exceeds the storage quota of %d MB", file.Size, appConfig.UploadQuotaMB))
+		}
 
 		// Sanitize the filename to prevent directory traversal
-		filename := utils.SanitizeFileName(file.Filename)
+		tenant := ""
+		if t := fiberContext.TenantFromContext(c); t != nil {
+			tenant = t.Name
+		}
+		filename := filePath(tenant, file.Filename)
 
-		savePath := filepath.Join(appConfig.UploadDir, filename)
+		st := uploadStorage(appConfig)
 
 		// Check if file already exists
-		if _, err := os.Stat(savePath); !os.IsNotExist(err) {
+		exists, err := st.Exists(filename)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file: " + err.Error())
+		}
+		if exists {
 			return c.Status(fiber.StatusBadRequest).SendString("File already exists")
 		}
 
-		err = c.SaveFile(file, savePath)
+		content, err := file.Open()
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file: " + err.Error())
 		}
+		defer content.Close()
+
+		if err := st.Save(filename, content, file.Size); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file: " + err.Error())
+		}
 
 		f := schema.File{
-			ID:        fmt.Sprintf("file-%d", getNextFileId()),
+			ID:        nextFileID(),
 			Object:    "file",
 			Bytes:     int(file.Size),
-			CreatedAt: time.Now(),
+			CreatedAt: createdAt,
+			ExpiresAt: expiresAt,
 			Filename:  file.Filename,
 			Purpose:   purpose,
 		}
 
 		UploadedFiles = append(UploadedFiles, f)
-		utils.SaveConfig(appConfig.UploadDir, UploadedFilesFile, UploadedFiles)
+		if owner != "" {
+			UploadedFilesOwners[f.ID] = owner
+		}
+		saveUploadedFiles(appConfig)
 		return c.Status(fiber.StatusOK).JSON(f)
 	}
 }
@@ -70,8 +198,104 @@ func UploadFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.Appli
 var currentFileId int64 = 0
 
 func getNextFileId() int64 {
-	atomic.AddInt64(&currentId, 1)
-	return currentId
+	return atomic.AddInt64(&currentFileId, 1)
+}
+
+// nextFileID returns a file ID that is not used yet, also by the files loaded from a previous run
+func nextFileID() string {
+	for {
+		id := fmt.Sprintf("file-%d", getNextFileId())
+		if !slices.ContainsFunc(UploadedFiles, func(f schema.File) bool { return f.ID == id }) {
+			return id
+		}
+	}
+}
+
+// deleteUploadedFile removes a file from the storage and the list of uploaded files.
+// The caller must hold uploadedFilesMutex.
+func deleteUploadedFile(appConfig *config.ApplicationConfig, file schema.File) error {
+	err := uploadStorage(appConfig).Delete(filePath(fileTenant(file.ID), file.Filename))
+	if err != nil {
+		// If the file doesn't exist then we should just continue to remove it
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to delete file: %s, %w", file.Filename, err)
+		}
+	}
+
+	// Remove upload from list
+	for i, f := range UploadedFiles {
+		if f.ID == file.ID {
+			UploadedFiles = append(UploadedFiles[:i], UploadedFiles[i+1:]...)
+			break
+		}
+	}
+	delete(UploadedFilesOwners, file.ID)
+
+	saveUploadedFiles(appConfig)
+	return nil
+}
+
+// StartFilesRetention periodically deletes the uploaded files that expired, and the oldest ones while the uploads
+// are over their maximum size, until the context is done
+func StartFilesRetention(ctx context.Context, appConfig *config.ApplicationConfig, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleteExpiredFiles(appConfig)
+				deleteOldestFiles(appConfig)
+			}
+		}
+	}()
+}
+
+func deleteExpiredFiles(appConfig *config.ApplicationConfig) {
+	uploadedFilesMutex.Lock()
+	defer uploadedFilesMutex.Unlock()
+
+	now := time.Now()
+	for _, f := range slices.Clone(UploadedFiles) {
+		if f.ExpiresAt == nil || f.ExpiresAt.After(now) {
+			continue
+		}
+		if err := deleteUploadedFile(appConfig, f); err != nil {
+			log.Error().Err(err).Str("id", f.ID).Msg("failed deleting expired file")
+			continue
+		}
+		log.Debug().Str("id", f.ID).Msg("deleted expired file")
+	}
+}
+
+// deleteOldestFiles deletes the oldest uploaded files while all the uploads are over their maximum size
+func deleteOldestFiles(appConfig *config.ApplicationConfig) {
+	if appConfig.UploadDirMaxSizeMB <= 0 {
+		return
+	}
+	uploadedFilesMutex.Lock()
+	defer uploadedFilesMutex.Unlock()
+
+	maxSize := appConfig.UploadDirMaxSizeMB * 1024 * 1024
+	size := 0
+	for _, f := range UploadedFiles {
+		size += f.Bytes
+	}
+	oldest := slices.Clone(UploadedFiles)
+	slices.SortStableFunc(oldest, func(a, b schema.File) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for _, f := range oldest {
+		if size <= maxSize {
+			return
+		}
+		if err := deleteUploadedFile(appConfig, f); err != nil {
+			log.Error().Err(err).Str("id", f.ID).Msg("failed deleting file over the maximum size of the uploads")
+			continue
+		}
+		size -= f.Bytes
+		log.Debug().Str("id", f.ID).Msg("deleted file over the maximum size of the uploads")
+	}
 }
 
 // ListFilesEndpoint https://platform.openai.com/docs/api-reference/files/list
@@ -83,14 +307,13 @@ func ListFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.Applica
 	return func(c *fiber.Ctx) error {
 		var listFiles schema.ListFiles
 
+		uploadedFilesMutex.Lock()
+		defer uploadedFilesMutex.Unlock()
+
 		purpose := c.Query("purpose")
-		if purpose == "" {
-			listFiles.Data = UploadedFiles
-		} else {
-			for _, f := range UploadedFiles {
-				if purpose == f.Purpose {
-					listFiles.Data = append(listFiles.Data, f)
-				}
+		for _, f := range UploadedFiles {
+			if (purpose == "" || purpose == f.Purpose) && fileVisible(c, f) {
+				listFiles.Data = append(listFiles.Data, f)
 			}
 		}
 		listFiles.Object = "list"
@@ -105,7 +328,7 @@ func getFileFromRequest(c *fiber.Ctx) (*schema.File, error) {
 	}
 
 	for _, f := range UploadedFiles {
-		if id == f.ID {
+		if id == f.ID && fileVisible(c, f) {
 			return &f, nil
 		}
 	}
@@ -119,7 +342,9 @@ func getFileFromRequest(c *fiber.Ctx) (*schema.File, error) {
 // @Router /v1/files/{file_id} [get]
 func GetFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
+		uploadedFilesMutex.Lock()
 		file, err := getFileFromRequest(c)
+		uploadedFilesMutex.Unlock()
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
@@ -141,28 +366,18 @@ type DeleteStatus struct {
 func DeleteFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
 
 	return func(c *fiber.Ctx) error {
+		uploadedFilesMutex.Lock()
+		defer uploadedFilesMutex.Unlock()
+
 		file, err := getFileFromRequest(c)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
 
-		err = os.Remove(filepath.Join(appConfig.UploadDir, file.Filename))
-		if err != nil {
-			// If the file doesn't exist then we should just continue to remove it
-			if !errors.Is(err, os.ErrNotExist) {
-				return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Unable to delete file: %s, %v", file.Filename, err))
-			}
-		}
-
-		// Remove upload from list
-		for i, f := range UploadedFiles {
-			if f.ID == file.ID {
-				UploadedFiles = append(UploadedFiles[:i], UploadedFiles[i+1:]...)
-				break
-			}
+		if err := deleteUploadedFile(appConfig, *file); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
 
-		utils.SaveConfig(appConfig.UploadDir, UploadedFilesFile, UploadedFiles)
 		return c.JSON(DeleteStatus{
 			Id:      file.ID,
 			Object:  "file",
@@ -178,12 +393,20 @@ func DeleteFilesEndpoint(cm *config.BackendConfigLoader, appConfig *config.Appli
 // GetFilesContentsEndpoint
 func GetFilesContentsEndpoint(cm *config.BackendConfigLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
+		uploadedFilesMutex.Lock()
 		file, err := getFileFromRequest(c)
+		uploadedFilesMutex.Unlock()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+
+		r, err := uploadStorage(appConfig).Open(filePath(fileTenant(file.ID), file.Filename))
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
+		defer r.Close()
 
-		fileContents, err := os.ReadFile(filepath.Join(appConfig.UploadDir, file.Filename))
+		fileContents, err := io.ReadAll(r)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
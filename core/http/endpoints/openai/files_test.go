@@ -14,6 +14,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
 
 	"github.com/gofiber/fiber/v2"
@@ -85,6 +86,46 @@ func TestUploadFileExceedSizeLimit(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 		assert.Contains(t, bodyToString(resp, t), "Purpose is not defined")
 	})
+	t.Run("UploadFilesEndpoint invalid purpose", func(t *testing.T) {
+		t.Cleanup(tearDown())
+		resp, _ := CallFilesUploadEndpoint(t, app, "foo.txt", "file", "not-so-fine-tune", 5, option)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, bodyToString(resp, t), "Invalid purpose")
+	})
+	t.Run("UploadFilesEndpoint storage quota exceeded", func(t *testing.T) {
+		t.Cleanup(tearDown())
+		option.UploadQuotaMB = 8
+		t.Cleanup(func() { option.UploadQuotaMB = 0 })
+
+		_ = CallFilesUploadEndpointWithCleanup(t, app, "foo.txt", "file", "fine-tune", 5, option)
+		resp, err := CallFilesUploadEndpoint(t, app, "bar.txt", "file", "batch", 5, option)
+		assert.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, bodyToString(resp, t), "exceeds the storage quota")
+	})
+	t.Run("deleteOldestFiles keeps the uploads under their maximum size", func(t *testing.T) {
+		t.Cleanup(tearDown())
+		option.UploadDirMaxSizeMB = 8
+		t.Cleanup(func() { option.UploadDirMaxSizeMB = 0 })
+
+		oldest := CallFilesUploadEndpointWithCleanup(t, app, "foo.txt", "file", "fine-tune", 3, option)
+		newest := CallFilesUploadEndpointWithCleanup(t, app, "bar.txt", "file", "batch", 3, option)
+		deleteOldestFiles(option)
+		assert.Len(t, UploadedFiles, 2)
+
+		third := CallFilesUploadEndpointWithCleanup(t, app, "baz.txt", "file", "batch", 3, option)
+		deleteOldestFiles(option)
+
+		ids := []string{}
+		for _, f := range UploadedFiles {
+			ids = append(ids, f.ID)
+		}
+		assert.ElementsMatch(t, []string{newest.ID, third.ID}, ids)
+		_, err := os.Stat(filepath.Join(option.UploadDir, utils2.SanitizeFileName(oldest.Filename)))
+		assert.True(t, os.IsNotExist(err))
+	})
 	t.Run("UploadFilesEndpoint file already exists", func(t *testing.T) {
 		t.Cleanup(tearDown())
 		f1 := CallFilesUploadEndpointWithCleanup(t, app, "foo.txt", "file", "fine-tune", 5, option)
@@ -299,3 +340,62 @@ func responseToListFile(t *testing.T, resp *http.Response) schema.ListFiles {
 
 	return listFiles
 }
+
+func TestTenantFiles(t *testing.T) {
+	t.Cleanup(tearDown())
+	t.Cleanup(func() { UploadedFilesOwners = map[string]string{} })
+	loader := &config.BackendConfigLoader{}
+	option := &config.ApplicationConfig{
+		UploadLimitMB: 10,
+		UploadDir:     "test_dir",
+	}
+	_ = os.RemoveAll(option.UploadDir)
+
+	// The tenant is set by the auth middleware from the API key of the request
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if name := c.Get("X-Tenant"); name != "" {
+			fiberContext.SetTenant(c, &config.Tenant{Name: name})
+		}
+		return c.Next()
+	})
+	app.Post("/files", UploadFilesEndpoint(loader, option))
+	app.Get("/files", ListFilesEndpoint(loader, option))
+	app.Get("/files/:file_id/content", GetFilesContentsEndpoint(loader, option))
+
+	file := createTestFile(t, "tenant.txt", 1, option)
+	body, writer := newMultipartFile(file.Name(), "file", "assistants")
+	req := httptest.NewRequest(http.MethodPost, "/files", body)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	uploaded := responseToFile(t, resp)
+
+	_, err = os.Stat(filepath.Join(option.UploadDir, "acme", "tenant.txt"))
+	assert.NoError(t, err)
+
+	list := func(tenant string) schema.ListFiles {
+		req := httptest.NewRequest(http.MethodGet, "/files", nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return responseToListFile(t, resp)
+	}
+	assert.Len(t, list("acme").Data, 1)
+	assert.Len(t, list("other").Data, 0)
+	assert.Len(t, list("").Data, 0)
+
+	content := func(tenant string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/files/"+uploaded.ID+"/content", nil)
+		req.Header.Set("X-Tenant", tenant)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	assert.Equal(t, fiber.StatusOK, content("acme").StatusCode)
+	assert.Equal(t, fiber.StatusInternalServerError, content("other").StatusCode)
+}
@@ -0,0 +1,205 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// fineTuneOutputLines are the last lines of the output of the fine-tuning command returned with its failures
+const fineTuneOutputLines = 10
+
+// CreateFineTuningJobEndpoint fine-tunes a model with an uploaded file as a job https://platform.openai.com/docs/api-reference/fine-tuning/create
+// The fine-tuning command writes the fine-tuned model file, which is added to the models with the configuration
+// of the base model
+// @Summary Fine-tunes a model with a file uploaded for fine-tuning, in the background as a job.
+// @Param request body schema.FineTuningRequest true "query params"
+// @Success 202 {object} schema.Job "Response"
+// @Router /v1/fine_tuning/jobs [post]
+func CreateFineTuningJobEndpoint(cl *config.BackendConfigLoader, appConfig *config.ApplicationConfig, jobs *services.JobService) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if appConfig.FineTuneCommand == "" {
+			return fiber.NewError(fiber.StatusNotImplemented, "fine-tuning is disabled, it is enabled with --fine-tune-command")
+		}
+		input := new(schema.FineTuningRequest)
+		if err := c.BodyParser(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed parsing request body: %s", err))
+		}
+
+		cfg, exists := cl.GetBackendConfig(input.Model)
+		if !exists {
+			return fiber.NewError(fiber.StatusNotFound, "model "+input.Model+" not found")
+		}
+		if cfg.Model == "" || cfg.IsModelURL() {
+			return fiber.NewError(fiber.StatusBadRequest, "the model "+input.Model+" has no model file in the models path")
+		}
+
+		var trainingFile *schema.File
+		uploadedFilesMutex.Lock()
+		for _, f := range UploadedFiles {
+			if f.ID == input.TrainingFile && fileVisible(c, f) {
+				trainingFile = &f
+				break
+			}
+		}
+		uploadedFilesMutex.Unlock()
+		if trainingFile == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "unable to find file id "+input.TrainingFile)
+		}
+		if trainingFile.Purpose != "fine-tune" {
+			return fiber.NewError(fiber.StatusBadRequest, "the file "+trainingFile.ID+" was not uploaded for fine-tuning")
+		}
+
+		suffix := input.Suffix
+		if suffix == "" {
+			suffix = "ft-" + time.Now().UTC().Format("20060102150405")
+		}
+		name := input.Model + "-" + suffix
+		if _, exists := cl.GetBackendConfig(name); exists {
+			return fiber.NewError(fiber.StatusConflict, "model "+name+" already exists")
+		}
+		modelFile := name + filepath.Ext(cfg.Model)
+		if err := utils.VerifyPath(modelFile, appConfig.ModelPath); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		hyperparameters, err := json.Marshal(input.Hyperparameters)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		stored := filePath(fileTenant(trainingFile.ID), trainingFile.Filename)
+
+		job := jobs.Submit("fine_tune", fiberContext.Owner(c), func(ctx context.Context, progress func(float64, string)) (*services.JobResult, error) {
+			progress(0, "fine-tuning "+input.Model)
+
+			dir, err := os.MkdirTemp("", "fine-tune")
+			if err != nil {
+				return nil, err
+			}
+			defer os.RemoveAll(dir)
+			training := filepath.Join(dir, filepath.Base(stored))
+			if err := copyUploadedFile(appConfig, stored, training); err != nil {
+				return nil, fmt.Errorf("reading the training file: %w", err)
+			}
+
+			output := filepath.Join(appConfig.ModelPath, modelFile)
+			err = runFineTuneCommand(ctx, appConfig.FineTuneCommand, []string{
+				"LOCALAI_BASE_MODEL=" + filepath.Join(appConfig.ModelPath, cfg.Model),
+				"LOCALAI_BACKEND=" + cfg.Backend,
+				"LOCALAI_TRAINING_FILE=" + training,
+				"LOCALAI_OUTPUT_MODEL=" + output,
+				"LOCALAI_HYPERPARAMETERS=" + string(hyperparameters),
+			}, progress)
+			if err != nil {
+				os.Remove(output)
+				return nil, err
+			}
+			if _, err := os.Stat(output); err != nil {
+				return nil, fmt.Errorf("the fine-tuning command did not write the model file %s", modelFile)
+			}
+
+			if _, err := cl.CopyBackendConfig(input.Model, name, modelFile, appConfig.ToConfigLoaderOptions()...); err != nil {
+				return nil, fmt.Errorf("adding the fine-tuned model: %w", err)
+			}
+			log.Info().Str("model", name).Str("base", input.Model).Msg("Model fine-tuned")
+
+			body, err := json.Marshal(schema.FineTuningResult{
+				Object:         "fine_tuning.job",
+				Model:          input.Model,
+				FineTunedModel: name,
+				TrainingFile:   trainingFile.ID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &services.JobResult{StatusCode: fiber.StatusOK, ContentType: fiber.MIMEApplicationJSON, Body: body}, nil
+		})
+
+		c.Location("/v1/jobs/" + job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
+
+// copyUploadedFile copies an uploaded file, from the storage of the uploads, to dst
+func copyUploadedFile(appConfig *config.ApplicationConfig, stored, dst string) error {
+	r, err := uploadStorage(appConfig).Open(stored)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runFineTuneCommand runs the fine-tuning command with the variables of the job in its environment, until ctx
+// is cancelled. The lines "progress: <percent> <message>" of its output report the progress of the job, and
+// the last lines of its output are returned with its failures
+func runFineTuneCommand(ctx context.Context, command string, env []string, progress func(float64, string)) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	// the processes started by the command may keep its output open once it is killed
+	cmd.WaitDelay = 5 * time.Second
+	r, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		w.Close()
+		done <- err
+	}()
+
+	var last []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p, ok := strings.CutPrefix(line, "progress:"); ok {
+			percent, message, _ := strings.Cut(strings.TrimSpace(p), " ")
+			if v, err := strconv.ParseFloat(percent, 64); err == nil {
+				progress(min(v, 99), strings.TrimSpace(message))
+				continue
+			}
+		}
+		log.Debug().Str("output", line).Msg("[fine-tune]")
+		last = append(last, line)
+		if len(last) > fineTuneOutputLines {
+			last = last[1:]
+		}
+	}
+	// the output is drained until the command exits
+	io.Copy(io.Discard, r)
+
+	if err := <-done; err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+		return fmt.Errorf("the fine-tuning command failed: %w: %s", err, strings.Join(last, "\n"))
+	}
+	return nil
+}
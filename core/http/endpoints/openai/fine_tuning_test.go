@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunFineTuneCommand(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "tuned.gguf")
+	var reported []float64
+	var messages []string
+	err := runFineTuneCommand(context.Background(), `echo "progress: 25 epoch 1/4"; echo loading; echo "progress: 100"; cp "$LOCALAI_TRAINING_FILE" "$LOCALAI_OUTPUT_MODEL"`,
+		[]string{"LOCALAI_TRAINING_FILE=fine_tuning_test.go", "LOCALAI_OUTPUT_MODEL=" + output},
+		func(percent float64, message string) {
+			reported = append(reported, percent)
+			messages = append(messages, message)
+		})
+	assert.NoError(t, err)
+	// the job is only completed once the model is added
+	assert.Equal(t, []float64{25, 99}, reported)
+	assert.Equal(t, []string{"epoch 1/4", ""}, messages)
+	_, err = os.Stat(output)
+	assert.NoError(t, err)
+
+	err = runFineTuneCommand(context.Background(), `echo "out of memory" >&2; exit 3`, nil, func(float64, string) {})
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.ErrorContains(t, err, "out of memory")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = runFineTuneCommand(ctx, `sleep 30`, nil, func(float64, string) {})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -15,13 +15,20 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/internal"
 
 	"github.com/mudler/LocalAI/core/backend"
 
 	"github.com/gofiber/fiber/v2"
 	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/provenance"
+	"github.com/mudler/LocalAI/pkg/storage"
+	"github.com/mudler/LocalAI/pkg/utils"
 	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
 )
 
 func downloadFile(url string) (string, error) {
@@ -81,48 +88,54 @@ func ImageEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appCon
 			return fmt.Errorf("failed reading parameters from request:%w", err)
 		}
 
-		src := ""
-		if input.File != "" {
-
-			fileData := []byte{}
-			// check if input.File is an URL, if so download it and save it
-			// to a temporary file
-			if strings.HasPrefix(input.File, "http://") || strings.HasPrefix(input.File, "https://") {
-				out, err := downloadFile(input.File)
-				if err != nil {
-					return fmt.Errorf("failed downloading file:%w", err)
-				}
-				defer os.RemoveAll(out)
-
-				fileData, err = os.ReadFile(out)
-				if err != nil {
-					return fmt.Errorf("failed reading file:%w", err)
-				}
+		// The ControlNets and the IP-Adapter are set by the model
+		controlNet := ""
+		if input.ControlNet != "" || input.ConditioningImage != "" {
+			if input.ConditioningImage == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "conditioning_image is required by the controlnet")
+			}
+			controlNet, err = config.Diffusers.AllowedControlNet(input.ControlNet)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+		}
+		if input.IPAdapterImage != "" && config.Diffusers.IPAdapter == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "the model has no IP-Adapter")
+		}
 
-			} else {
-				// base 64 decode the file and write it somewhere
-				// that we will cleanup
-				fileData, err = base64.StdEncoding.DecodeString(input.File)
-				if err != nil {
-					return err
+		// The input images are removed once the images are generated, by the stream when streaming
+		var inputFiles []string
+		keepInputs := false
+		defer func() {
+			if !keepInputs {
+				for _, file := range inputFiles {
+					os.RemoveAll(file)
 				}
 			}
-
-			// Create a temporary file
-			outputFile, err := os.CreateTemp(appConfig.ImageDir, "b64")
-			if err != nil {
-				return err
+		}()
+		saveInput := func(image string) (string, error) {
+			if image == "" {
+				return "", nil
 			}
-			// write the base64 result
-			writer := bufio.NewWriter(outputFile)
-			_, err = writer.Write(fileData)
+			file, err := inputImage(image, appConfig.ImageDir)
 			if err != nil {
-				outputFile.Close()
-				return err
+				return "", err
 			}
-			outputFile.Close()
-			src = outputFile.Name()
-			defer os.RemoveAll(src)
+			inputFiles = append(inputFiles, file)
+			return file, nil
+		}
+
+		src, err := saveInput(input.File)
+		if err != nil {
+			return err
+		}
+		conditioningImage, err := saveInput(input.ConditioningImage)
+		if err != nil {
+			return err
+		}
+		ipAdapterImage, err := saveInput(input.IPAdapterImage)
+		if err != nil {
+			return err
 		}
 
 		log.Debug().Msgf("Parameter Config: %+v", config)
@@ -151,14 +164,29 @@ func ImageEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appCon
 
 		b64JSON := config.ResponseFormat == "b64_json"
 
+		// The n images of each prompt are generated batch_count at a time
+		batchCount := max(input.BatchCount, 1)
+		params := backend.ImageParameters{
+			CFGScale:  input.CFGScale,
+			Scheduler: input.Scheduler,
+			ClipSkip:  input.ClipSkip,
+			Upscaler:  input.Upscaler,
+
+			ControlNet:        controlNet,
+			ConditioningImage: conditioningImage,
+			ControlNetScale:   input.ControlNetScale,
+			IPAdapterImage:    ipAdapterImage,
+			IPAdapterScale:    input.IPAdapterScale,
+		}
+
 		// src and clip_skip
-		var result []schema.Item
+		var images []imageRequest
 		for _, i := range config.PromptStrings {
 			n := input.N
 			if input.N == 0 {
 				n = 1
 			}
-			for j := 0; j < n; j++ {
+			for j := 0; j < n; j += batchCount {
 				prompts := strings.Split(i, "|")
 				positive_prompt := prompts[0]
 				negative_prompt := ""
@@ -180,49 +208,134 @@ func ImageEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appCon
 					step = input.Step
 				}
 
-				tempDir := ""
-				if !b64JSON {
-					tempDir = appConfig.ImageDir
-				}
-				// Create a temporary file
-				outputFile, err := os.CreateTemp(tempDir, "b64")
-				if err != nil {
-					return err
-				}
-				outputFile.Close()
-				output := outputFile.Name() + ".png"
-				// Rename the temporary file
-				err = os.Rename(outputFile.Name(), output)
-				if err != nil {
-					return err
-				}
+				images = append(images, imageRequest{positivePrompt: positive_prompt, negativePrompt: negative_prompt, mode: mode, step: step, count: min(batchCount, n-j)})
+			}
+		}
 
-				baseURL := c.BaseURL()
+		baseURL := c.BaseURL()
+
+		// The parameters embedded in the images, when the instance records their provenance
+		provenanceParams := provenance.Parameters{
+			Scheduler: params.Scheduler,
+			CFGScale:  params.CFGScale,
+			Seed:      *config.Seed,
+			Width:     width,
+			Height:    height,
+			Model:     config.Name,
+			Backend:   config.Backend,
+		}
+		if provenanceParams.Scheduler == "" {
+			provenanceParams.Scheduler = config.Diffusers.SchedulerType
+		}
+		if provenanceParams.CFGScale == 0 {
+			provenanceParams.CFGScale = config.Diffusers.CFGScale
+		}
+		if appConfig.ImageMetadata || appConfig.C2PAManifest != "" {
+			provenanceParams.ModelHash, err = provenance.ModelHash(filepath.Join(appConfig.ModelPath, config.Model))
+			if err != nil {
+				log.Warn().Err(err).Str("model", config.Name).Msg("failed hashing the model, its hash is not embedded in the images")
+			}
+		}
+
+		ctx := fiberContext.RequestContext(c, appConfig.Context)
+		generate := func(img imageRequest, previewInterval int, progress backend.ImageProgress) ([]schema.Item, error) {
+			tempDir := ""
+			if !b64JSON {
+				tempDir = appConfig.ImageDir
+			}
+			// Create a temporary file
+			outputFile, err := os.CreateTemp(tempDir, "b64")
+			if err != nil {
+				return nil, err
+			}
+			outputFile.Close()
+			output := outputFile.Name() + ".png"
+			// Rename the temporary file
+			err = os.Rename(outputFile.Name(), output)
+			if err != nil {
+				return nil, err
+			}
+
+			imageParams := params
+			imageParams.BatchCount = img.count
+			fn, err := backend.ImageGenerationWithProgress(ctx, height, width, img.mode, img.step, *config.Seed, img.positivePrompt, img.negativePrompt, src, output, imageParams, previewInterval, progress, ml, *config, appConfig)
+			if err != nil {
+				return nil, err
+			}
+			if err := fn(); err != nil {
+				return nil, err
+			}
+
+			generationParams := provenanceParams
+			generationParams.Prompt = img.positivePrompt
+			generationParams.NegativePrompt = img.negativePrompt
+			generationParams.Steps = img.step
 
-				fn, err := backend.ImageGeneration(height, width, mode, step, *config.Seed, positive_prompt, negative_prompt, src, output, ml, *config, appConfig)
-				if err != nil {
-					return err
+			var items []schema.Item
+			for i := 0; i < img.count; i++ {
+				file := utils.BatchFileName(output, i)
+				if err := imageProvenance(appConfig, file, generationParams); err != nil {
+					return nil, err
 				}
-				if err := fn(); err != nil {
-					return err
+				// The images kept in the image directory are listed, with their parameters, in the gallery of the studio
+				if !b64JSON && appConfig.OutputStorage == nil {
+					if err := services.SaveImageMetadata(file, generationParams); err != nil {
+						return nil, err
+					}
 				}
-
-				item := &schema.Item{}
+				item := schema.Item{}
 
 				if b64JSON {
-					defer os.RemoveAll(output)
-					data, err := os.ReadFile(output)
+					data, err := os.ReadFile(file)
+					os.RemoveAll(file)
 					if err != nil {
-						return err
+						return nil, err
 					}
 					item.B64JSON = base64.StdEncoding.EncodeToString(data)
 				} else {
-					base := filepath.Base(output)
-					item.URL = baseURL + "/generated-images/" + base
+					item.URL, err = imageURL(appConfig, file, baseURL)
+					if err != nil {
+						return nil, err
+					}
 				}
+				items = append(items, item)
+			}
+			return items, nil
+		}
 
-				result = append(result, *item)
+		if input.Stream {
+			// The images are generated while the events are streamed, once the handler has returned
+			keepInputs = true
+			c.Context().SetContentType("text/event-stream")
+			c.Set("Cache-Control", "no-cache")
+			c.Set("Connection", "keep-alive")
+			c.Set("Transfer-Encoding", "chunked")
+			c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+				for _, file := range inputFiles {
+					defer os.RemoveAll(file)
+				}
+				streamImages(w, images, input.PartialImages, generate)
+			}))
+			return nil
+		}
+
+		// the requests run as jobs report the steps of the images to the job
+		jobProgress := fiberContext.JobProgress(c)
+		var result []schema.Item
+		for i, img := range images {
+			var progress backend.ImageProgress
+			if jobProgress != nil {
+				progress = func(step, steps int, _ []byte) {
+					if steps > 0 {
+						jobProgress(100*(float64(i)+float64(step)/float64(steps))/float64(len(images)), fmt.Sprintf("generating the image %d of %d, step %d of %d", i+1, len(images), step, steps))
+					}
+				}
+			}
+			items, err := generate(img, 0, progress)
+			if err != nil {
+				return err
 			}
+			result = append(result, items...)
 		}
 
 		id := uuid.New().String()
@@ -240,3 +353,157 @@ func ImageEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appCon
 		return c.JSON(resp)
 	}
 }
+
+// imageProvenance embeds the parameters of the generation in the metadata of the image, and signs it with a
+// C2PA manifest, as set by the instance
+func imageProvenance(appConfig *config.ApplicationConfig, file string, p provenance.Parameters) error {
+	software := "LocalAI " + internal.PrintableVersion()
+	if appConfig.ImageMetadata {
+		if err := provenance.Embed(file, p, software); err != nil {
+			return fmt.Errorf("failed embedding the metadata of the image: %w", err)
+		}
+	}
+	if appConfig.C2PAManifest != "" {
+		if err := provenance.SignC2PA(appConfig.C2PATool, appConfig.C2PAManifest, file, p, software); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputImage saves an image of a request, as an URL or base64, to a temporary file in dir
+func inputImage(image, dir string) (string, error) {
+	var data []byte
+	var err error
+	// check if the image is an URL, if so download it
+	if strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		out, err := downloadFile(image)
+		if err != nil {
+			return "", fmt.Errorf("failed downloading file:%w", err)
+		}
+		defer os.RemoveAll(out)
+
+		data, err = os.ReadFile(out)
+		if err != nil {
+			return "", fmt.Errorf("failed reading file:%w", err)
+		}
+	} else {
+		data, err = base64.StdEncoding.DecodeString(image)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Create a temporary file
+	outputFile, err := os.CreateTemp(dir, "b64")
+	if err != nil {
+		return "", err
+	}
+	defer outputFile.Close()
+	if _, err := outputFile.Write(data); err != nil {
+		os.RemoveAll(outputFile.Name())
+		return "", err
+	}
+	return outputFile.Name(), nil
+}
+
+// imageRequest is a batch of count images to generate for a request
+type imageRequest struct {
+	positivePrompt, negativePrompt string
+	mode, step, count              int
+}
+
+// streamImages generates the batches of images one after the other, streaming their steps, partialImages
+// previews of each batch when the backend sends them, and the images once generated. The steps and the
+// previews of a batch are reported for its first image
+func streamImages(w *bufio.Writer, images []imageRequest, partialImages int, generate func(imageRequest, int, backend.ImageProgress) ([]schema.Item, error)) {
+	disconnected := false
+	send := func(ev schema.ImageGenerationEvent) {
+		if disconnected {
+			return
+		}
+		if err := writeImageEvent(w, ev); err != nil {
+			log.Debug().Err(err).Msg("Sending image generation event failed")
+			disconnected = true
+		}
+	}
+
+	index := 0
+	for _, img := range images {
+		partials := 0
+		items, err := generate(img, previewInterval(img.step, partialImages), func(step, steps int, preview []byte) {
+			ev := schema.ImageGenerationEvent{Type: schema.ImageGenerationProgress, ImageIndex: index, Step: step, Steps: steps}
+			if len(preview) > 0 && partials < partialImages {
+				partialIndex := partials
+				partials++
+				ev.Type = schema.ImageGenerationPartialImage
+				ev.PartialImageIndex = &partialIndex
+				ev.B64JSON = base64.StdEncoding.EncodeToString(preview)
+			}
+			send(ev)
+		})
+		if err != nil {
+			send(schema.ImageGenerationEvent{
+				Type:       schema.ImageGenerationError,
+				ImageIndex: index,
+				Error:      &schema.APIError{Code: fiber.StatusInternalServerError, Message: err.Error(), Type: "server_error"},
+			})
+			break
+		}
+		for _, item := range items {
+			send(schema.ImageGenerationEvent{Type: schema.ImageGenerationCompleted, ImageIndex: index, URL: item.URL, B64JSON: item.B64JSON})
+			index++
+		}
+		if disconnected {
+			// Nobody is left to receive the next images
+			return
+		}
+	}
+
+	if !disconnected {
+		fmt.Fprintf(w, "data: [DONE]\n\n")
+		w.Flush()
+	}
+}
+
+// writeImageEvent sends an event of the image generation stream
+func writeImageEvent(w *bufio.Writer, ev schema.ImageGenerationEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// previewInterval returns the steps between the previews of an image generated in steps, so that
+// partialImages previews are sent before the image. No preview is requested when partialImages is 0
+func previewInterval(steps, partialImages int) int {
+	if partialImages <= 0 {
+		return 0
+	}
+	return max(steps/(partialImages+1), 1)
+}
+
+// imageURL returns the URL of a generated image. If an output storage is set, the image is
+// moved there with a content-addressed name, and linked with a signed URL when the storage supports it.
+func imageURL(appConfig *config.ApplicationConfig, output, baseURL string) (string, error) {
+	if appConfig.OutputStorage == nil {
+		return baseURL + "/generated-images/" + filepath.Base(output), nil
+	}
+
+	name, err := storage.SaveContentAddressed(appConfig.OutputStorage, output)
+	if err != nil {
+		return "", fmt.Errorf("failed storing image: %w", err)
+	}
+	if name != filepath.Base(output) {
+		os.RemoveAll(output)
+	}
+
+	if signer, ok := appConfig.OutputStorage.(storage.URLSigner); ok {
+		return signer.SignedURL(name, appConfig.OutputURLExpiry)
+	}
+	return baseURL + "/generated-images/" + name, nil
+}
@@ -0,0 +1,164 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewInterval(t *testing.T) {
+	assert.Equal(t, 0, previewInterval(15, 0))
+	assert.Equal(t, 5, previewInterval(15, 2))
+	assert.Equal(t, 1, previewInterval(2, 5))
+}
+
+// readImageEvents returns the events of an image generation stream, and whether it ended with [DONE]
+func readImageEvents(t *testing.T, stream string) ([]schema.ImageGenerationEvent, bool) {
+	var events []schema.ImageGenerationEvent
+	done := false
+	for _, line := range strings.Split(stream, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var ev schema.ImageGenerationEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &ev))
+		events = append(events, ev)
+	}
+	return events, done
+}
+
+func TestStreamImages(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	images := []imageRequest{{positivePrompt: "a cat", step: 4, count: 1}, {positivePrompt: "a dog", step: 4, count: 1}}
+
+	streamImages(w, images, 1, func(img imageRequest, interval int, progress backend.ImageProgress) ([]schema.Item, error) {
+		assert.Equal(t, 2, interval)
+		for step := 1; step <= img.step; step++ {
+			var preview []byte
+			if step%interval == 0 {
+				preview = []byte("png")
+			}
+			progress(step, img.step, preview)
+		}
+		return []schema.Item{{URL: "http://localhost/generated-images/" + img.positivePrompt}}, nil
+	})
+
+	events, done := readImageEvents(t, buf.String())
+	require.True(t, done)
+	require.Len(t, events, 10)
+
+	// Only one preview per image is sent, as requested
+	assert.Equal(t, schema.ImageGenerationProgress, events[0].Type)
+	assert.Equal(t, schema.ImageGenerationPartialImage, events[1].Type)
+	assert.Equal(t, 2, events[1].Step)
+	assert.Equal(t, 0, *events[1].PartialImageIndex)
+	assert.Equal(t, "cG5n", events[1].B64JSON)
+	assert.Equal(t, schema.ImageGenerationProgress, events[3].Type)
+	assert.Nil(t, events[3].PartialImageIndex)
+
+	assert.Equal(t, schema.ImageGenerationCompleted, events[4].Type)
+	assert.Equal(t, 0, events[4].ImageIndex)
+	assert.Equal(t, "http://localhost/generated-images/a cat", events[4].URL)
+	assert.Equal(t, schema.ImageGenerationCompleted, events[9].Type)
+	assert.Equal(t, 1, events[9].ImageIndex)
+}
+
+func TestStreamImagesError(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	images := []imageRequest{{step: 4, count: 1}, {step: 4, count: 1}}
+
+	generated := 0
+	streamImages(w, images, 0, func(img imageRequest, interval int, progress backend.ImageProgress) ([]schema.Item, error) {
+		generated++
+		assert.Zero(t, interval)
+		return nil, errors.New("out of memory")
+	})
+
+	// The generation stops at the first error
+	events, done := readImageEvents(t, buf.String())
+	assert.True(t, done)
+	assert.Equal(t, 1, generated)
+	require.Len(t, events, 1)
+	assert.Equal(t, schema.ImageGenerationError, events[0].Type)
+	assert.Equal(t, "out of memory", events[0].Error.Message)
+}
+
+func TestStreamImagesBatches(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	images := []imageRequest{{step: 1, count: 2}, {step: 1, count: 1}}
+
+	streamImages(w, images, 0, func(img imageRequest, interval int, progress backend.ImageProgress) ([]schema.Item, error) {
+		progress(1, 1, nil)
+		items := make([]schema.Item, img.count)
+		for i := range items {
+			items[i].B64JSON = "aW1n"
+		}
+		return items, nil
+	})
+
+	// The images of the batches are numbered one after the other
+	events, done := readImageEvents(t, buf.String())
+	require.True(t, done)
+	require.Len(t, events, 5)
+	assert.Equal(t, schema.ImageGenerationProgress, events[0].Type)
+	assert.Equal(t, 0, events[0].ImageIndex)
+	assert.Equal(t, 0, events[1].ImageIndex)
+	assert.Equal(t, 1, events[2].ImageIndex)
+	assert.Equal(t, schema.ImageGenerationProgress, events[3].Type)
+	assert.Equal(t, 2, events[3].ImageIndex)
+	assert.Equal(t, schema.ImageGenerationCompleted, events[4].Type)
+	assert.Equal(t, 2, events[4].ImageIndex)
+}
+
+func TestInputImage(t *testing.T) {
+	dir := t.TempDir()
+	file, err := inputImage("aW1hZ2U=", dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(file))
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "image", string(data))
+
+	_, err = inputImage("not base64", dir)
+	assert.Error(t, err)
+}
+
+func TestImageTransformRequest(t *testing.T) {
+	req, err := imageTransformRequest(grpc.ImageTransformUpscale, &schema.ImageTransformRequest{Scale: 2})
+	require.NoError(t, err)
+	assert.Equal(t, grpc.ImageTransformUpscale, req.Operation)
+	assert.Equal(t, int32(2), req.Scale)
+
+	_, err = imageTransformRequest(grpc.ImageTransformUpscale, &schema.ImageTransformRequest{Scale: 16})
+	assert.Error(t, err)
+	_, err = imageTransformRequest(grpc.ImageTransformUpscale, &schema.ImageTransformRequest{OnlyMask: true})
+	assert.Error(t, err)
+
+	req, err = imageTransformRequest(grpc.ImageTransformRemoveBackground, &schema.ImageTransformRequest{BackgroundColor: "#00FF00"})
+	require.NoError(t, err)
+	assert.Equal(t, "#00FF00", req.BackgroundColor)
+
+	_, err = imageTransformRequest(grpc.ImageTransformRemoveBackground, &schema.ImageTransformRequest{BackgroundColor: "green"})
+	assert.Error(t, err)
+	_, err = imageTransformRequest(grpc.ImageTransformRemoveBackground, &schema.ImageTransformRequest{Scale: 2})
+	assert.Error(t, err)
+}
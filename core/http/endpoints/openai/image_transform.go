@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// maxUpscale is the largest upscaling factor accepted
+const maxUpscale = 8
+
+var colorRegexp = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ImageUpscaleEndpoint upscales an image with an ESRGAN like model
+// @Summary Upscales an image.
+// @Param request body schema.ImageTransformRequest true "query params"
+// @Success 200 {object} schema.OpenAIResponse "Response"
+// @Router /v1/images/upscale [post]
+func ImageUpscaleEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return imageTransformEndpoint(grpc.ImageTransformUpscale, cl, ml, appConfig)
+}
+
+// ImageRemoveBackgroundEndpoint removes the background of an image with a segmentation model, as the rembg ones
+// @Summary Removes the background of an image.
+// @Param request body schema.ImageTransformRequest true "query params"
+// @Success 200 {object} schema.OpenAIResponse "Response"
+// @Router /v1/images/remove-background [post]
+func ImageRemoveBackgroundEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return imageTransformEndpoint(grpc.ImageTransformRemoveBackground, cl, ml, appConfig)
+}
+
+func imageTransformEndpoint(operation string, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.ImageTransformRequest)
+		if err := c.BodyParser(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed parsing request body: %s", err))
+		}
+		if input.Image == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "image is required")
+		}
+		request, err := imageTransformRequest(operation, input)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, false)
+		if err != nil {
+			return err
+		}
+		if modelFile == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "model is required")
+		}
+		cfg, err := cl.LoadBackendConfigFileByName(modelFile, appConfig.ModelPath,
+			config.LoadOptionDebug(appConfig.Debug),
+			config.LoadOptionThreads(appConfig.Threads),
+			config.LoadOptionContextSize(appConfig.ContextSize),
+			config.LoadOptionF16(appConfig.F16),
+		)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("model %s not found", modelFile))
+		}
+		log.Debug().Str("operation", operation).Msgf("Request for model: %s", cfg.Model)
+
+		request.Src, err = inputImage(input.Image, appConfig.ImageDir)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid image: %s", err))
+		}
+		defer os.RemoveAll(request.Src)
+
+		outputFile, err := os.CreateTemp(appConfig.ImageDir, "b64")
+		if err != nil {
+			return err
+		}
+		outputFile.Close()
+		request.Dst = outputFile.Name() + ".png"
+		if err := os.Rename(outputFile.Name(), request.Dst); err != nil {
+			return err
+		}
+
+		if err := backend.ImageTransform(request, ml, *cfg, appConfig); err != nil {
+			os.RemoveAll(request.Dst)
+			return err
+		}
+
+		item := schema.Item{}
+		if input.ResponseFormat == "b64_json" {
+			data, err := os.ReadFile(request.Dst)
+			os.RemoveAll(request.Dst)
+			if err != nil {
+				return err
+			}
+			item.B64JSON = base64.StdEncoding.EncodeToString(data)
+		} else {
+			item.URL, err = imageURL(appConfig, request.Dst, c.BaseURL())
+			if err != nil {
+				return err
+			}
+		}
+
+		return c.JSON(&schema.OpenAIResponse{
+			ID:      uuid.New().String(),
+			Created: int(time.Now().Unix()),
+			Data:    []schema.Item{item},
+		})
+	}
+}
+
+// imageTransformRequest returns the request of the operation to the backend, without its files, once its
+// parameters are checked
+func imageTransformRequest(operation string, input *schema.ImageTransformRequest) (*proto.ImageTransformRequest, error) {
+	switch operation {
+	case grpc.ImageTransformUpscale:
+		if input.Scale < 0 || input.Scale > maxUpscale {
+			return nil, fmt.Errorf("scale must be between 1 and %d", maxUpscale)
+		}
+		if input.OnlyMask || input.BackgroundColor != "" {
+			return nil, fmt.Errorf("only_mask and background_color are only supported by the background removal")
+		}
+	case grpc.ImageTransformRemoveBackground:
+		if input.Scale != 0 {
+			return nil, fmt.Errorf("scale is only supported by the upscaling")
+		}
+		if input.BackgroundColor != "" && !colorRegexp.MatchString(input.BackgroundColor) {
+			return nil, fmt.Errorf("invalid background_color %q, expected #rrggbb", input.BackgroundColor)
+		}
+	}
+	return &proto.ImageTransformRequest{
+		Operation:       operation,
+		Scale:           int32(input.Scale),
+		OnlyMask:        input.OnlyMask,
+		BackgroundColor: input.BackgroundColor,
+	}, nil
+}
@@ -11,7 +11,7 @@ import (
 func ComputeChoices(
 	req *schema.OpenAIRequest,
 	predInput string,
-	config *config.BackendConfig,
+	cfg *config.BackendConfig,
 	o *config.ApplicationConfig,
 	loader *model.ModelLoader,
 	cb func(string, *[]schema.Choice),
@@ -28,28 +28,46 @@ func ComputeChoices(
 		images = append(images, m.StringImages...)
 	}
 
-	// get the model function to call for the result
-	predFunc, err := backend.ModelInference(req.Context, predInput, req.Messages, images, loader, *config, o, tokenCallback)
-	if err != nil {
-		return result, backend.TokenUsage{}, err
+	// Once tokens are streamed to the client, the failed inferences are not run again
+	streamed := false
+	if tokenCallback != nil {
+		userTokenCallback := tokenCallback
+		tokenCallback = func(token string, usage backend.TokenUsage) bool {
+			streamed = true
+			return userTokenCallback(token, usage)
+		}
 	}
 
 	tokenUsage := backend.TokenUsage{}
 
 	for i := 0; i < n; i++ {
-		prediction, err := predFunc()
+		prediction, answered, err := inferWithPolicies(req.Context, cfg, loader.CircuitBreaker(), func(c *config.BackendConfig) error {
+			return backend.ModelHealthCheck(o.Context, loader, *c, o)
+		}, func() bool { return streamed }, func(c *config.BackendConfig) (backend.LLMResponse, error) {
+			// get the model function to call for the result
+			predFunc, err := backend.ModelInference(req.Context, predInput, req.Messages, images, loader, *c, o, tokenCallback)
+			if err != nil {
+				return backend.LLMResponse{}, err
+			}
+			return predFunc()
+		})
 		if err != nil {
 			return result, backend.TokenUsage{}, err
 		}
 
 		tokenUsage.Prompt += prediction.Usage.Prompt
 		tokenUsage.Completion += prediction.Usage.Completion
+		tokenUsage.TimingPromptProcessing += prediction.Usage.TimingPromptProcessing
+		tokenUsage.TimingTokenGeneration += prediction.Usage.TimingTokenGeneration
+		if i == 0 {
+			tokenUsage.TimingTimeToFirstToken = prediction.Usage.TimingTimeToFirstToken
+		}
 
-		finetunedResponse := backend.Finetune(*config, predInput, prediction.Response)
+		finetunedResponse := backend.Finetune(*answered, predInput, prediction.Response)
 		cb(finetunedResponse, &result)
 
 		//result = append(result, Choice{Text: prediction})
 
 	}
-	return result, tokenUsage, err
+	return result, tokenUsage, nil
 }
@@ -0,0 +1,79 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/functions"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultJSONAttempts is the number of generations of the streamed json mode requests of the models
+// without json_attempts
+const defaultJSONAttempts = 3
+
+// jsonGenerator runs a generation, passing its tokens to token. The generation stops when ctx is cancelled
+type jsonGenerator func(ctx context.Context, attempt int, token func(string)) (backend.TokenUsage, error)
+
+// generateJSON runs generate until its output is a valid JSON document, up to attempts times. The output
+// is validated while it is generated, and the generation is aborted at its first invalid character
+func generateJSON(ctx context.Context, attempts int, generate jsonGenerator) (string, backend.TokenUsage, error) {
+	tokenUsage := backend.TokenUsage{}
+	var invalid error
+	for attempt := 0; attempt < attempts; attempt++ {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		validator := functions.NewJSONStreamValidator()
+		output := strings.Builder{}
+		var attemptErr error
+		usage, err := generate(attemptCtx, attempt, func(token string) {
+			if attemptErr != nil {
+				// tokens generated before the cancellation is effective
+				return
+			}
+			output.WriteString(token)
+			if err := validator.Write(token); err != nil {
+				attemptErr = err
+				cancel()
+			}
+		})
+		cancel()
+
+		tokenUsage.Prompt += usage.Prompt
+		tokenUsage.Completion += usage.Completion
+		tokenUsage.TimingPromptProcessing += usage.TimingPromptProcessing
+		tokenUsage.TimingTokenGeneration += usage.TimingTokenGeneration
+		if attempt == 0 {
+			tokenUsage.TimingTimeToFirstToken = usage.TimingTimeToFirstToken
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			return "", tokenUsage, ctx.Err()
+		case attemptErr != nil:
+			invalid = attemptErr
+		case err != nil:
+			return "", tokenUsage, err
+		case !validator.Complete():
+			invalid = fmt.Errorf("incomplete JSON document")
+		default:
+			return strings.TrimSpace(output.String()), tokenUsage, nil
+		}
+		log.Debug().Msgf("JSON mode attempt %d/%d is not valid: %v", attempt+1, attempts, invalid)
+	}
+	return "", tokenUsage, fmt.Errorf("no valid JSON generated in %d attempts: %w", attempts, invalid)
+}
+
+// jsonAttemptConfig returns the configuration of an attempt of generateJSON: the retries of the models
+// with a fixed seed change it, not to generate the same invalid output again
+func jsonAttemptConfig(cfg *config.BackendConfig, attempt int) *config.BackendConfig {
+	if attempt == 0 || cfg.Seed == nil || *cfg.Seed == config.RAND_SEED {
+		return cfg
+	}
+	attemptConfig := *cfg
+	seed := *cfg.Seed + attempt
+	attemptConfig.Seed = &seed
+	return &attemptConfig
+}
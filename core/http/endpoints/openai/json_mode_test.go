@@ -0,0 +1,73 @@
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJSONGenerator generates the outputs in order, one character at a time, until its context is cancelled
+func fakeJSONGenerator(outputs []string, generated *[]string) jsonGenerator {
+	return func(ctx context.Context, attempt int, token func(string)) (backend.TokenUsage, error) {
+		usage := backend.TokenUsage{Prompt: 10}
+		sent := ""
+		for _, c := range outputs[attempt] {
+			if ctx.Err() != nil {
+				break
+			}
+			token(string(c))
+			sent += string(c)
+			usage.Completion++
+		}
+		*generated = append(*generated, sent)
+		return usage, ctx.Err()
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	generated := []string{}
+	result, usage, err := generateJSON(context.Background(), 3, fakeJSONGenerator([]string{
+		`Here is the JSON: {"a": 1}`,
+		`{"a": 1`,
+		` {"a": [1, 2]} `,
+	}, &generated))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": [1, 2]}`, result)
+	// the first attempt is aborted at its first invalid character, the second one is incomplete
+	assert.Equal(t, []string{"H", `{"a": 1`, ` {"a": [1, 2]} `}, generated)
+	assert.Equal(t, 30, usage.Prompt)
+	assert.Equal(t, 1+7+15, usage.Completion)
+}
+
+func TestGenerateJSONAttempts(t *testing.T) {
+	generated := []string{}
+	_, _, err := generateJSON(context.Background(), 2, fakeJSONGenerator([]string{`{"a": }`, `{"a": }`, `{}`}, &generated))
+	assert.ErrorContains(t, err, "no valid JSON generated in 2 attempts")
+	assert.Len(t, generated, 2)
+}
+
+func TestGenerateJSONCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	generated := []string{}
+	_, _, err := generateJSON(ctx, 3, fakeJSONGenerator([]string{`{}`, `{}`, `{}`}, &generated))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, generated, 1)
+}
+
+func TestJSONAttemptConfig(t *testing.T) {
+	seed := 42
+	cfg := &config.BackendConfig{}
+	cfg.Seed = &seed
+	assert.Same(t, cfg, jsonAttemptConfig(cfg, 0))
+	assert.Equal(t, 44, *jsonAttemptConfig(cfg, 2).Seed)
+	assert.Equal(t, 42, *cfg.Seed)
+
+	random := config.RAND_SEED
+	cfg.Seed = &random
+	assert.Same(t, cfg, jsonAttemptConfig(cfg, 1))
+}
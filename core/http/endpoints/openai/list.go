@@ -1,8 +1,11 @@
 package openai
 
 import (
+	"slices"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/core/services"
 	model "github.com/mudler/LocalAI/pkg/model"
@@ -10,6 +13,7 @@ import (
 
 // ListModelsEndpoint is the OpenAI Models API endpoint https://platform.openai.com/docs/api-reference/models
 // @Summary List and describe the various models available in the API.
+// @Param detailed query bool false "Add the backend, state, context size, quantization, size, capabilities and gallery of the models"
 // @Success 200 {object} schema.ModelsDataResponse "Response"
 // @Router /v1/models [get]
 func ListModelsEndpoint(bcl *config.BackendConfigLoader, ml *model.ModelLoader) func(ctx *fiber.Ctx) error {
@@ -20,10 +24,16 @@ func ListModelsEndpoint(bcl *config.BackendConfigLoader, ml *model.ModelLoader)
 		// By default, exclude any loose files that are already referenced by a configuration file.
 		excludeConfigured := c.QueryBool("excludeConfigured", true)
 
-		dataModels, err := modelList(bcl, ml, filter, excludeConfigured)
+		// The OpenAI SDKs expect only the id and object of the models
+		detailed := c.QueryBool("detailed", false)
+
+		dataModels, err := modelList(bcl, ml, filter, excludeConfigured, detailed)
 		if err != nil {
 			return err
 		}
+		if tenant := fiberContext.TenantFromContext(c); tenant != nil {
+			dataModels = slices.DeleteFunc(dataModels, func(m schema.OpenAIModel) bool { return !tenant.CanUseModel(m.ID) })
+		}
 		return c.JSON(schema.ModelsDataResponse{
 			Object: "list",
 			Data:   dataModels,
@@ -31,7 +41,7 @@ func ListModelsEndpoint(bcl *config.BackendConfigLoader, ml *model.ModelLoader)
 	}
 }
 
-func modelList(bcl *config.BackendConfigLoader, ml *model.ModelLoader, filter string, excludeConfigured bool) ([]schema.OpenAIModel, error) {
+func modelList(bcl *config.BackendConfigLoader, ml *model.ModelLoader, filter string, excludeConfigured, detailed bool) ([]schema.OpenAIModel, error) {
 
 	models, err := services.ListModels(bcl, ml, filter, excludeConfigured)
 	if err != nil {
@@ -42,7 +52,11 @@ func modelList(bcl *config.BackendConfigLoader, ml *model.ModelLoader, filter st
 
 	// Then iterate through the loose files:
 	for _, m := range models {
-		dataModels = append(dataModels, schema.OpenAIModel{ID: m, Object: "model"})
+		entry := schema.OpenAIModel{ID: m, Object: "model"}
+		if detailed {
+			entry.ModelDetails = services.GetModelDetails(bcl, ml, m)
+		}
+		dataModels = append(dataModels, entry)
 	}
 
 	return dataModels, nil
@@ -0,0 +1,81 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListModelsEndpointDetailed(t *testing.T) {
+	modelPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "mistral.yaml"), []byte(`name: mistral
+backend: llama-cpp
+context_size: 4096
+parameters:
+  model: mistral-7b-instruct.Q4_K_M.gguf
+template:
+  chat_message: "{{.Content}}"
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "mistral-7b-instruct.Q4_K_M.gguf"), []byte("weights"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "loose-f16.bin"), []byte("loose"), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	require.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+
+	app := fiber.New()
+	app.Get("/v1/models", ListModelsEndpoint(cl, ml))
+
+	list := func(target string) []map[string]any {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var models struct {
+			Data []map[string]any `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &models))
+		return models.Data
+	}
+
+	// The default shape is the OpenAI one
+	models := list("/v1/models")
+	require.Len(t, models, 2)
+	for _, m := range models {
+		assert.Len(t, m, 2)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/models?detailed=true", nil))
+	require.NoError(t, err)
+	var detailed schema.ModelsDataResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&detailed))
+	require.Len(t, detailed.Data, 2)
+
+	mistral := detailed.Data[0]
+	assert.Equal(t, "mistral", mistral.ID)
+	require.NotNil(t, mistral.ModelDetails)
+	assert.True(t, mistral.Configured)
+	assert.False(t, mistral.Loaded)
+	assert.Equal(t, "llama-cpp", mistral.Backend)
+	assert.Equal(t, 4096, mistral.ContextSize)
+	assert.Equal(t, "Q4_K_M", mistral.Quantization)
+	assert.Equal(t, int64(len("weights")), mistral.Size)
+	assert.Equal(t, []string{config.CapabilityChat, config.CapabilityCompletion}, mistral.Capabilities)
+
+	loose := detailed.Data[1]
+	assert.Equal(t, "loose-f16.bin", loose.ID)
+	require.NotNil(t, loose.ModelDetails)
+	assert.False(t, loose.Configured)
+	assert.Equal(t, "F16", loose.Quantization)
+	assert.Equal(t, int64(len("loose")), loose.Size)
+}
@@ -0,0 +1,216 @@
+package openai
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/valyala/fasthttp"
+)
+
+// LlamaCPPCompletionEndpoint mimics the /completion and /infill endpoints of the llama.cpp server
+// (https://github.com/ggerganov/llama.cpp/tree/master/examples/server), used by the editor plugins
+// @Summary Generate completions and fill-in-the-middle completions in the format of the llama.cpp server.
+// @Param request body schema.LlamaCPPCompletionRequest true "query params"
+// @Success 200 {object} schema.LlamaCPPCompletionResponse "Response"
+// @Router /completion [post]
+// @Router /infill [post]
+func LlamaCPPCompletionEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		req := new(schema.LlamaCPPCompletionRequest)
+		if err := c.BodyParser(req); err != nil {
+			return fmt.Errorf("failed parsing request body: %w", err)
+		}
+
+		// The request is translated to an OpenAI completion, read as the ones of /v1/completions
+		body, err := json.Marshal(llamaCPPToOpenAIRequest(req))
+		if err != nil {
+			return err
+		}
+		c.Request().Header.SetContentType(fiber.MIMEApplicationJSON)
+		c.Request().SetBody(body)
+
+		modelFile, input, err := readRequest(c, cl, ml, appConfig, true)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+
+		config, input, err := mergeRequestWithConfig(modelFile, input, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+
+		config.Grammar = input.Grammar
+
+		templateFile := completionTemplateFile(ml, config)
+		suffix := fimSuffix(config)
+
+		result := func(content string, usage backend.TokenUsage) schema.LlamaCPPCompletionResponse {
+			return schema.LlamaCPPCompletionResponse{
+				Content:         content,
+				Stop:            true,
+				Model:           config.Name,
+				TokensPredicted: usage.Completion,
+				TokensEvaluated: usage.Prompt,
+				StoppedLimit:    input.Maxtokens != nil && usage.Completion >= *input.Maxtokens,
+				Timings:         llamaCPPTimings(usage),
+			}
+		}
+
+		if input.Stream {
+			if len(config.PromptStrings) > 1 {
+				return errors.New("cannot handle more than 1 `PromptStrings` when Streaming")
+			}
+
+			predInput, err := templateCompletion(ml, config, appConfig, input, config.PromptStrings[0], suffix, templateFile)
+			if err != nil {
+				return err
+			}
+
+			c.Context().SetContentType("text/event-stream")
+			c.Set("Cache-Control", "no-cache")
+			c.Set("Connection", "keep-alive")
+			c.Set("Transfer-Encoding", "chunked")
+
+			tokens := make(chan string)
+			usages := make(chan backend.TokenUsage, 1)
+			recordUsage := fiberContext.TokenUsageRecorder(c)
+
+			go func() {
+				_, tokenUsage, _ := ComputeChoices(input, predInput, config, appConfig, ml, func(s string, c *[]schema.Choice) {}, func(s string, usage backend.TokenUsage) bool {
+					tokens <- s
+					return true
+				})
+				close(tokens)
+				usages <- tokenUsage
+			}()
+
+			c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+				defer input.Cancel()
+
+				for token := range tokens {
+					writeLlamaCPPEvent(w, schema.LlamaCPPCompletionResponse{Content: token})
+				}
+
+				tokenUsage := <-usages
+				writeLlamaCPPEvent(w, result("", tokenUsage))
+				recordUsage(tokenUsage.Prompt + tokenUsage.Completion)
+			}))
+			return nil
+		}
+
+		results := []schema.LlamaCPPCompletionResponse{}
+		for _, i := range config.PromptStrings {
+			predInput, err := templateCompletion(ml, config, appConfig, input, i, suffix, templateFile)
+			if err != nil {
+				return err
+			}
+
+			var content string
+			_, tokenUsage, err := ComputeChoices(input, predInput, config, appConfig, ml, func(s string, c *[]schema.Choice) {
+				content = s
+			}, nil)
+			if err != nil {
+				return err
+			}
+			fiberContext.TokenUsageRecorder(c)(tokenUsage.Prompt + tokenUsage.Completion)
+
+			results = append(results, result(content, tokenUsage))
+		}
+
+		// As the llama.cpp server, a list of prompts gets a list of results
+		if len(results) == 1 {
+			return c.JSON(results[0])
+		}
+		return c.JSON(results)
+	}
+}
+
+// llamaCPPToOpenAIRequest translates a llama.cpp server request. The extra context and the
+// prefix of the infill requests make the prefix of the completion, followed by the prompt
+// which is the beginning of the completion
+func llamaCPPToOpenAIRequest(req *schema.LlamaCPPCompletionRequest) *schema.OpenAIRequest {
+	input := &schema.OpenAIRequest{
+		PredictionOptions: schema.PredictionOptions{
+			Model:            req.Model,
+			Temperature:      req.Temperature,
+			TopK:             req.TopK,
+			TopP:             req.TopP,
+			TypicalP:         req.TypicalP,
+			TFZ:              req.TFZ,
+			RepeatPenalty:    req.RepeatPenalty,
+			RepeatLastN:      req.RepeatLastN,
+			FrequencyPenalty: req.FrequencyPenalty,
+			PresencePenalty:  req.PresencePenalty,
+			Keep:             req.Keep,
+			Seed:             req.Seed,
+			IgnoreEOS:        req.IgnoreEOS,
+		},
+		Prompt:  req.Prompt,
+		Grammar: req.Grammar,
+		Stream:  req.Stream,
+	}
+
+	// A negative n_predict generates until the end of the text
+	if req.NPredict != nil && *req.NPredict >= 0 {
+		input.Maxtokens = req.NPredict
+	}
+
+	if len(req.Stop) > 0 {
+		input.Stop = req.Stop
+	}
+
+	if req.InputPrefix == "" && req.InputSuffix == "" && len(req.InputExtra) == 0 {
+		return input
+	}
+
+	var prefix strings.Builder
+	for _, chunk := range req.InputExtra {
+		prefix.WriteString(chunk.Text)
+		if !strings.HasSuffix(chunk.Text, "\n") {
+			prefix.WriteString("\n")
+		}
+	}
+	prefix.WriteString(req.InputPrefix)
+	if prompt, ok := req.Prompt.(string); ok {
+		prefix.WriteString(prompt)
+	}
+
+	input.Prompt = prefix.String()
+	input.Suffix = req.InputSuffix
+	return input
+}
+
+func llamaCPPTimings(usage backend.TokenUsage) *schema.LlamaCPPTimings {
+	t := &schema.LlamaCPPTimings{
+		PromptN:     usage.Prompt,
+		PromptMS:    usage.TimingPromptProcessing,
+		PredictedN:  usage.Completion,
+		PredictedMS: usage.TimingTokenGeneration,
+	}
+	if usage.TimingPromptProcessing > 0 {
+		t.PromptPerSecond = float64(usage.Prompt) / usage.TimingPromptProcessing * 1000
+	}
+	if usage.TimingTokenGeneration > 0 {
+		t.PredictedPerSecond = float64(usage.Completion) / usage.TimingTokenGeneration * 1000
+	}
+	return t
+}
+
+// writeLlamaCPPEvent writes a streamed response of the llama.cpp server, which has no [DONE] event
+func writeLlamaCPPEvent(w *bufio.Writer, resp schema.LlamaCPPCompletionResponse) {
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	w.WriteString(fmt.Sprintf("data: %s\n\n", dat))
+	w.Flush()
+}
@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLlamaCPPToOpenAIRequest(t *testing.T) {
+	nPredict := 32
+	input := llamaCPPToOpenAIRequest(&schema.LlamaCPPCompletionRequest{
+		Prompt:   "Once upon a time",
+		NPredict: &nPredict,
+		Stop:     []string{"\n"},
+		Stream:   true,
+	})
+	assert.Equal(t, "Once upon a time", input.Prompt)
+	assert.Equal(t, &nPredict, input.Maxtokens)
+	assert.Equal(t, []string{"\n"}, input.Stop)
+	assert.True(t, input.Stream)
+	assert.Empty(t, input.Suffix)
+
+	unlimited := -1
+	input = llamaCPPToOpenAIRequest(&schema.LlamaCPPCompletionRequest{Prompt: "a", NPredict: &unlimited})
+	assert.Nil(t, input.Maxtokens)
+	assert.Nil(t, input.Stop)
+}
+
+func TestLlamaCPPToOpenAIRequestInfill(t *testing.T) {
+	input := llamaCPPToOpenAIRequest(&schema.LlamaCPPCompletionRequest{
+		InputExtra:  []schema.LlamaCPPInfillChunk{{Filename: "utils.py", Text: "import os"}},
+		InputPrefix: "def main():\n    ",
+		InputSuffix: "\n\nmain()",
+		Prompt:      "print(",
+	})
+	assert.Equal(t, "import os\ndef main():\n    print(", input.Prompt)
+	assert.Equal(t, "\n\nmain()", input.Suffix)
+
+	input = llamaCPPToOpenAIRequest(&schema.LlamaCPPCompletionRequest{InputSuffix: "}"})
+	assert.Equal(t, "", input.Prompt)
+	assert.Equal(t, "}", input.Suffix)
+}
+
+func TestWriteLlamaCPPEvent(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeLlamaCPPEvent(w, schema.LlamaCPPCompletionResponse{Content: "Hi"})
+	assert.Equal(t, "data: {\"content\":\"Hi\",\"stop\":false}\n\n", buf.String())
+}
@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/schema"
+)
+
+// progressHeader is set by the clients to opt in to the progress of the prompt evaluation. It is
+// streamed as events before the first token, so the long prompts do not look stalled
+const progressHeader = "X-LocalAI-Progress"
+
+func progressRequested(c *fiber.Ctx) bool {
+	b, _ := strconv.ParseBool(c.Get(progressHeader))
+	return b
+}
+
+// streamProgress sends the progress of the prompt evaluation of the request to the responses
+// streamed, if the client opted in
+func streamProgress(c *fiber.Ctx, input *schema.OpenAIRequest, responses chan schema.OpenAIResponse) {
+	if !progressRequested(c) {
+		return
+	}
+	input.Context = backend.WithPromptProgress(input.Context, func(p schema.PromptProgress) {
+		responses <- schema.OpenAIResponse{Progress: &p}
+	})
+}
+
+func writeProgressEvent(w *bufio.Writer, p schema.PromptProgress) {
+	dat, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	w.WriteString(fmt.Sprintf("event: x-localai-progress\ndata: %s\n\n", dat))
+}
@@ -0,0 +1,18 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteProgressEvent(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeProgressEvent(w, schema.PromptProgress{NPast: 512, NPrompt: 2048})
+	w.Flush()
+	assert.Equal(t, "event: x-localai-progress\ndata: {\"n_past\":512,\"n_prompt\":2048}\n\n", buf.String())
+}
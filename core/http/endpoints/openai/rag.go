@@ -0,0 +1,272 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/grpc/proto"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/store"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultRAGRetrieveK = 10
+	defaultRAGTopN      = 3
+)
+
+// defaultRAGTemplate is the prompt of the models without a rag template
+const defaultRAGTemplate = `Answer the question using only the documents below. Cite the documents the answer is based on with their number, as [1].
+{{range .Documents}}
+[{{.Number}}] {{.Text}}
+{{end}}
+Question: {{.Query}}
+Answer:`
+
+var ragCitationRegex = regexp.MustCompile(`\[(\d+)\]`)
+
+// RAGEndpoint answers a query with the documents retrieved from the vector store of the model
+// @Summary Retrieves the documents of a query from a vector store, reranks them and generates the answer, citing them.
+// @Param request body schema.RAGRequest true "query params"
+// @Success 200 {object} schema.RAGResponse "Response"
+// @Router /v1/rag/query [post]
+func RAGEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, sl *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		input := new(schema.RAGRequest)
+		if err := c.BodyParser(input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed parsing request body: %s", err))
+		}
+		if input.Query == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "query is required")
+		}
+		if input.RetrieveK < 0 || input.TopN < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "retrieve_k and top_n must be positive")
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, false)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(fiberContext.RequestContext(c, appConfig.Context))
+		defer cancel()
+		req := &schema.OpenAIRequest{PredictionOptions: input.PredictionOptions, Context: ctx, Cancel: cancel}
+		// Only one answer is generated
+		req.N = 1
+
+		cfg, req, err := mergeRequestWithConfig(modelFile, req, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+		if cfg.RAG.EmbeddingModel == "" {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("the model %s has no rag embedding_model", cfg.Name))
+		}
+
+		retrieveK := firstPositive(input.RetrieveK, cfg.RAG.RetrieveK, defaultRAGRetrieveK)
+		topN := firstPositive(input.TopN, cfg.RAG.TopN, defaultRAGTopN)
+
+		citations, err := ragRetrieve(ctx, input.Query, retrieveK, cfg.RAG, cl, ml, sl, appConfig)
+		if err != nil {
+			return err
+		}
+
+		if cfg.RAG.RerankModel != "" && (input.Rerank == nil || *input.Rerank) && len(citations) > 0 {
+			citations, err = ragRerank(input.Query, citations, topN, cfg.RAG.RerankModel, cl, ml, appConfig)
+			if err != nil {
+				return err
+			}
+		}
+		if len(citations) > topN {
+			citations = citations[:topN]
+		}
+		for i := range citations {
+			citations[i].Number = i + 1
+		}
+
+		prompt, err := ragPrompt(ml, cfg, input.Query, citations)
+		if err != nil {
+			return err
+		}
+
+		templateFile := ""
+		// A model can have a "file.bin.tmpl" file associated with a prompt template prefix
+		if ml.ExistsInModelPath(fmt.Sprintf("%s.tmpl", cfg.Model)) {
+			templateFile = cfg.Model
+		}
+		if cfg.TemplateConfig.Completion != "" {
+			templateFile = cfg.TemplateConfig.Completion
+		}
+		if templateFile != "" {
+			templatedInput, err := ml.EvaluateTemplateForPrompt(model.CompletionPromptTemplate, templateFile, model.PromptTemplateData{
+				SystemPrompt: cfg.SystemPrompt,
+				Input:        prompt,
+				Variables:    cfg.TemplateConfig.Variables,
+				Tokenizer:    backend.ModelTokenizer(ml, *cfg, appConfig),
+			})
+			if err == nil {
+				prompt = templatedInput
+			}
+		}
+		log.Debug().Msgf("RAG prompt: %s", prompt)
+
+		choices, tokenUsage, err := ComputeChoices(req, prompt, cfg, appConfig, ml, func(s string, c *[]schema.Choice) {
+			*c = append(*c, schema.Choice{Text: s, FinishReason: "stop"})
+		}, nil)
+		if err != nil {
+			return err
+		}
+		fiberContext.TokenUsageRecorder(c)(tokenUsage.Prompt + tokenUsage.Completion)
+
+		answer := ""
+		if len(choices) > 0 {
+			answer = choices[0].Text
+		}
+		markCitations(citations, answer)
+
+		return c.JSON(schema.RAGResponse{
+			ID:        uuid.New().String(),
+			Created:   int(time.Now().Unix()),
+			Model:     input.Model,
+			Answer:    answer,
+			Citations: citations,
+			Usage: schema.OpenAIUsage{
+				PromptTokens:     tokenUsage.Prompt,
+				CompletionTokens: tokenUsage.Completion,
+				TotalTokens:      tokenUsage.Prompt + tokenUsage.Completion,
+			},
+		})
+	}
+}
+
+// ragRetrieve returns the k documents of the store the most similar to the query
+func ragRetrieve(ctx context.Context, query string, k int, rag config.RAG, cl *config.BackendConfigLoader, ml *model.ModelLoader, sl *model.ModelLoader, appConfig *config.ApplicationConfig) ([]schema.RAGCitation, error) {
+	embeddingConfig, err := cl.LoadBackendConfigFileByName(rag.EmbeddingModel, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	embedFn, err := backend.ModelEmbedding(query, []int{}, ml, *embeddingConfig, appConfig)
+	if err != nil {
+		return nil, err
+	}
+	key, err := embedFn()
+	if err != nil {
+		return nil, err
+	}
+
+	sb, err := backend.StoreBackend(sl, appConfig, rag.Store)
+	if err != nil {
+		return nil, err
+	}
+	_, values, similarities, err := store.Find(ctx, sb, key, k)
+	if err != nil {
+		return nil, err
+	}
+
+	citations := make([]schema.RAGCitation, len(values))
+	for i, v := range values {
+		citations[i] = ragDocument(v)
+		citations[i].Similarity = similarities[i]
+	}
+	return citations, nil
+}
+
+// ragDocument reads a value of the store: either the text of the document, or a JSON object
+// with its text and its source
+func ragDocument(value []byte) schema.RAGCitation {
+	doc := struct {
+		Text   string `json:"text"`
+		Source string `json:"source"`
+	}{}
+	if err := json.Unmarshal(value, &doc); err == nil && doc.Text != "" {
+		return schema.RAGCitation{Text: doc.Text, Source: doc.Source}
+	}
+	return schema.RAGCitation{Text: string(value)}
+}
+
+// ragRerank returns the topN documents the most relevant to the query, by decreasing relevance
+func ragRerank(query string, citations []schema.RAGCitation, topN int, rerankModel string, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) ([]schema.RAGCitation, error) {
+	rerankConfig, err := cl.LoadBackendConfigFileByName(rerankModel, appConfig.ModelPath, appConfig.ToConfigLoaderOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]string, len(citations))
+	for i, c := range citations {
+		documents[i] = c.Text
+	}
+	res, err := backend.Rerank(rerankConfig.Backend, rerankConfig.Model, &proto.RerankRequest{
+		Query:     query,
+		Documents: documents,
+		TopN:      int32(topN),
+	}, ml, appConfig, *rerankConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	reranked := []schema.RAGCitation{}
+	for _, r := range res.Results {
+		if r.Index < 0 || int(r.Index) >= len(citations) {
+			continue
+		}
+		c := citations[r.Index]
+		score := r.RelevanceScore
+		c.RelevanceScore = &score
+		reranked = append(reranked, c)
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return *reranked[i].RelevanceScore > *reranked[j].RelevanceScore
+	})
+	return reranked, nil
+}
+
+// ragPrompt renders the rag template of the model with the query and the documents
+func ragPrompt(ml *model.ModelLoader, cfg *config.BackendConfig, query string, citations []schema.RAGCitation) (string, error) {
+	templateName := cfg.TemplateConfig.RAG
+	if templateName == "" {
+		templateName = defaultRAGTemplate
+	}
+
+	documents := make([]model.RAGDocument, len(citations))
+	for i, c := range citations {
+		documents[i] = model.RAGDocument{Number: c.Number, Text: c.Text, Source: c.Source}
+	}
+	return ml.EvaluateTemplateForRAG(templateName, model.RAGTemplateData{
+		SystemPrompt: cfg.SystemPrompt,
+		Query:        query,
+		Documents:    documents,
+		Variables:    cfg.TemplateConfig.Variables,
+	})
+}
+
+// markCitations flags the documents cited in the answer by their number, as [1]
+func markCitations(citations []schema.RAGCitation, answer string) {
+	cited := map[int]bool{}
+	for _, m := range ragCitationRegex.FindAllStringSubmatch(answer, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			cited[n] = true
+		}
+	}
+	for i := range citations {
+		citations[i].Cited = cited[citations[i].Number]
+	}
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
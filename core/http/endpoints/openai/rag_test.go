@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRAGDocument(t *testing.T) {
+	doc := ragDocument([]byte(`{"text": "LocalAI runs models locally", "source": "README.md"}`))
+	assert.Equal(t, "LocalAI runs models locally", doc.Text)
+	assert.Equal(t, "README.md", doc.Source)
+
+	doc = ragDocument([]byte("plain text document"))
+	assert.Equal(t, "plain text document", doc.Text)
+	assert.Empty(t, doc.Source)
+
+	doc = ragDocument([]byte(`{"title": "no text"}`))
+	assert.Equal(t, `{"title": "no text"}`, doc.Text)
+}
+
+func TestRAGPrompt(t *testing.T) {
+	ml := model.NewModelLoader(t.TempDir())
+	citations := []schema.RAGCitation{
+		{Number: 1, Text: "The sky is blue."},
+		{Number: 2, Text: "Grass is green."},
+	}
+
+	prompt, err := ragPrompt(ml, &config.BackendConfig{}, "What color is the sky?", citations)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "[1] The sky is blue.")
+	assert.Contains(t, prompt, "[2] Grass is green.")
+	assert.Contains(t, prompt, "Question: What color is the sky?")
+
+	cfg := &config.BackendConfig{}
+	cfg.TemplateConfig.RAG = `{{range .Documents}}{{.Number}}={{.Text}};{{end}}{{.Query}}`
+	prompt, err = ragPrompt(ml, cfg, "sky?", citations)
+	require.NoError(t, err)
+	assert.Equal(t, "1=The sky is blue.;2=Grass is green.;sky?", prompt)
+}
+
+func TestMarkCitations(t *testing.T) {
+	citations := []schema.RAGCitation{{Number: 1}, {Number: 2}, {Number: 3}}
+	markCitations(citations, "The sky is blue [1], as stated in [3][1].")
+	assert.True(t, citations[0].Cited)
+	assert.False(t, citations[1].Cited)
+	assert.True(t, citations[2].Cited)
+}
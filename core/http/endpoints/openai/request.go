@@ -2,16 +2,20 @@ package openai
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/core/config"
 	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/pkg/functions"
+	"github.com/mudler/LocalAI/pkg/logging"
 	"github.com/mudler/LocalAI/pkg/model"
 	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,20 +26,72 @@ func readRequest(c *fiber.Ctx, cl *config.BackendConfigLoader, ml *model.ModelLo
 	if err := c.BodyParser(input); err != nil {
 		return "", nil, fmt.Errorf("failed parsing request body: %w", err)
 	}
+	if err := checkRequestLimits(o, input.Messages, input.File, input.ConditioningImage, input.IPAdapterImage); err != nil {
+		return "", nil, err
+	}
 
 	received, _ := json.Marshal(input)
 
-	ctx, cancel := context.WithCancel(o.Context)
+	ctx, cancel := context.WithCancel(model.WithAffinityKey(fiberContext.RequestContext(c, o.Context), input.AffinityKey()))
 	input.Context = ctx
 	input.Cancel = cancel
 
-	log.Debug().Msgf("Request received: %s", string(received))
+	logging.FromContext(ctx).Debug().Msgf("Request received: %s", string(received))
 
 	modelFile, err := fiberContext.ModelFromContext(c, cl, ml, input.Model, firstModel)
+	if err := fiberContext.StartGeneration(c, ctx, cancel, modelFile); err != nil {
+		cancel()
+		return "", nil, err
+	}
 
 	return modelFile, input, err
 }
 
+// checkRequestLimits rejects the requests with more messages than allowed, or with inline images, in the
+// messages or as base64 strings, larger than allowed
+func checkRequestLimits(o *config.ApplicationConfig, messages []schema.Message, images ...string) error {
+	if o.MaxMessages > 0 && len(messages) > o.MaxMessages {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("the request has %d messages, more than the limit of %d", len(messages), o.MaxMessages))
+	}
+	if o.MaxImageSizeMB <= 0 {
+		return nil
+	}
+	for _, m := range messages {
+		parts, ok := m.Content.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, part := range parts {
+			p, ok := part.(map[string]interface{})
+			if !ok || p["type"] != "image_url" {
+				continue
+			}
+			if url, ok := p["image_url"].(map[string]interface{}); ok {
+				if s, ok := url["url"].(string); ok {
+					images = append(images, s)
+				}
+			}
+		}
+	}
+	for _, image := range images {
+		if strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+			continue
+		}
+		if _, data, found := strings.Cut(image, ";base64,"); found {
+			image = data
+		}
+		if size := base64.StdEncoding.DecodedLen(len(image)); size > o.MaxImageSizeMB*1024*1024 {
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("an image of the request exceeds the limit of %d MB", o.MaxImageSizeMB))
+		}
+	}
+	return nil
+}
+
+// requestLog returns the logger adding the ID of the API request to the log lines
+func requestLog(req *schema.OpenAIRequest) *zerolog.Logger {
+	return logging.FromContext(req.Context)
+}
+
 func updateRequestConfig(config *config.BackendConfig, input *schema.OpenAIRequest) {
 	if input.Echo {
 		config.Echo = input.Echo
@@ -90,6 +146,7 @@ func updateRequestConfig(config *config.BackendConfig, input *schema.OpenAIReque
 	if input.Maxtokens != nil {
 		config.Maxtokens = input.Maxtokens
 	}
+	config.LimitMaxTokens()
 
 	if input.ResponseFormat != nil {
 		switch responseFormat := input.ResponseFormat.(type) {
@@ -211,6 +268,15 @@ func updateRequestConfig(config *config.BackendConfig, input *schema.OpenAIReque
 					tokens = append(tokens, int(ii.(float64)))
 				}
 				config.InputToken = append(config.InputToken, tokens)
+			case map[string]interface{}:
+				dat, _ := json.Marshal(i)
+				item := schema.EmbeddingInput{}
+				json.Unmarshal(dat, &item)
+				if item.Image != "" {
+					config.InputImages = append(config.InputImages, item.Image)
+				} else {
+					config.InputStrings = append(config.InputStrings, item.Text)
+				}
 			}
 		}
 	}
@@ -233,6 +299,12 @@ func updateRequestConfig(config *config.BackendConfig, input *schema.OpenAIReque
 		config.SetFunctionCallNameString(name)
 	}
 
+	// the prefix of the fill-in-the-middle requests is their prompt
+	if input.Prompt == nil && input.Prefix != "" {
+		input.Prompt = input.Prefix
+	}
+	config.Suffix = input.Suffix
+
 	switch p := input.Prompt.(type) {
 	case string:
 		config.PromptStrings = append(config.PromptStrings, p)
@@ -253,6 +325,31 @@ func mergeRequestWithConfig(modelFile string, input *schema.OpenAIRequest, cm *c
 		config.LoadOptionF16(f16),
 		config.ModelPath(loader.ModelPath),
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The preset sets the defaults of the parameters of the request
+	preset := input.Preset
+	if preset == "" {
+		preset = cfg.Preset
+	}
+	if preset != "" {
+		p, ok := cm.Preset(cfg, preset)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown preset %q", preset)
+		}
+		cfg.ApplyPreset(p)
+	}
+
+	// The keep_alive of the request overrides the ttl of the model
+	if input.KeepAlive != nil {
+		keepAlive := fmt.Sprint(input.KeepAlive)
+		if _, err := config.ParseTTL(keepAlive); err != nil {
+			return nil, nil, fmt.Errorf("invalid keep_alive: %w", err)
+		}
+		cfg.TTL = keepAlive
+	}
 
 	// Set the parameters for the language model prediction
 	updateRequestConfig(cfg, input)
@@ -261,5 +358,31 @@ func mergeRequestWithConfig(modelFile string, input *schema.OpenAIRequest, cm *c
 		return nil, nil, fmt.Errorf("failed to validate config")
 	}
 
+	for _, name := range cfg.Fallback {
+		fallback, err := cm.LoadBackendConfigFileByName(name, loader.ModelPath,
+			config.LoadOptionDebug(debug),
+			config.LoadOptionThreads(threads),
+			config.LoadOptionContextSize(ctx),
+			config.LoadOptionF16(f16),
+			config.ModelPath(loader.ModelPath),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed loading fallback model %s: %w", name, err)
+		}
+		// The messages, the tools and the prompt of the request are already decoded
+		request := *input
+		request.Backend = ""
+		request.Messages = nil
+		request.Tools = nil
+		request.ToolsChoice = nil
+		request.Input = nil
+		request.Prompt = nil
+		updateRequestConfig(fallback, &request)
+		if !fallback.Validate() {
+			return nil, nil, fmt.Errorf("failed to validate the config of fallback model %s", name)
+		}
+		cfg.FallbackConfigs = append(cfg.FallbackConfigs, fallback)
+	}
+
 	return cfg, input, err
 }
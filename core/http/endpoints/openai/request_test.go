@@ -0,0 +1,167 @@
+package openai
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeRequestWithConfigPreset(t *testing.T) {
+	modelPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "foo.yaml"), []byte(`name: foo
+parameters:
+  model: foo.gguf
+  temperature: 0.7
+presets:
+  creative:
+    temperature: 1.2
+    top_p: 0.98
+    presence_penalty: 0.5
+`), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+
+	cfg, _, err := mergeRequestWithConfig("foo", &schema.OpenAIRequest{}, cl, ml, false, 0, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.7, *cfg.Temperature)
+
+	// The parameters of the request replace the ones of the preset
+	topP := 0.5
+	input := &schema.OpenAIRequest{Preset: "creative"}
+	input.TopP = &topP
+	cfg, _, err = mergeRequestWithConfig("foo", input, cl, ml, false, 0, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.2, *cfg.Temperature)
+	assert.Equal(t, 0.5, *cfg.TopP)
+	assert.Equal(t, 0.5, cfg.PresencePenalty)
+
+	_, _, err = mergeRequestWithConfig("foo", &schema.OpenAIRequest{Preset: "missing"}, cl, ml, false, 0, 0, false)
+	assert.EqualError(t, err, `unknown preset "missing"`)
+}
+
+func TestMergeRequestWithConfigMaxTokensLimit(t *testing.T) {
+	modelPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "foo.yaml"), []byte(`name: foo
+parameters:
+  model: foo.gguf
+max_tokens_limit: 256
+`), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+
+	// Without max_tokens the limit is used
+	cfg, _, err := mergeRequestWithConfig("foo", &schema.OpenAIRequest{}, cl, ml, false, 0, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 256, *cfg.Maxtokens)
+
+	for requested, expected := range map[int]int{100: 100, 1000: 256} {
+		input := &schema.OpenAIRequest{}
+		input.Maxtokens = &requested
+		cfg, _, err := mergeRequestWithConfig("foo", input, cl, ml, false, 0, 0, false)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, *cfg.Maxtokens)
+	}
+}
+
+func TestMergeRequestWithConfigKeepAlive(t *testing.T) {
+	modelPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "foo.yaml"), []byte(`name: foo
+parameters:
+  model: foo.gguf
+ttl: 5m
+`), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+
+	cfg, _, err := mergeRequestWithConfig("foo", &schema.OpenAIRequest{}, cl, ml, false, 0, 0, false)
+	assert.NoError(t, err)
+	ttl, ok := cfg.IdleTimeout()
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Minute, ttl)
+
+	// The keep_alive of the request is a duration or a number of seconds, as decoded from JSON
+	for keepAlive, expected := range map[interface{}]time.Duration{
+		"1h":        time.Hour,
+		float64(30): 30 * time.Second,
+		float64(-1): -time.Second,
+		"0":         0,
+	} {
+		cfg, _, err := mergeRequestWithConfig("foo", &schema.OpenAIRequest{KeepAlive: keepAlive}, cl, ml, false, 0, 0, false)
+		assert.NoError(t, err)
+		ttl, ok := cfg.IdleTimeout()
+		assert.True(t, ok)
+		assert.Equal(t, expected, ttl)
+	}
+
+	_, _, err = mergeRequestWithConfig("foo", &schema.OpenAIRequest{KeepAlive: "forever"}, cl, ml, false, 0, 0, false)
+	assert.ErrorContains(t, err, "invalid keep_alive")
+}
+
+func TestMergeRequestWithConfigInfill(t *testing.T) {
+	modelPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "coder.yaml"), []byte(`name: coder
+parameters:
+  model: coder.gguf
+template:
+  fim: "<fim_prefix>{{.Prefix}}<fim_suffix>{{.Suffix}}<fim_middle>"
+`), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+
+	// The prefix is the prompt of the fill-in-the-middle requests
+	cfg, _, err := mergeRequestWithConfig("coder", &schema.OpenAIRequest{Prefix: "def add(a, b):\n", Suffix: "\n\nprint(add(1, 2))"}, cl, ml, false, 0, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"def add(a, b):\n"}, cfg.PromptStrings)
+	assert.Equal(t, "\n\nprint(add(1, 2))", cfg.Suffix)
+
+	prompt, err := ml.EvaluateTemplateForFIM(cfg.TemplateConfig.FIM, model.FIMTemplateData{Prefix: cfg.PromptStrings[0], Suffix: cfg.Suffix})
+	assert.NoError(t, err)
+	assert.Equal(t, "<fim_prefix>def add(a, b):\n<fim_suffix>\n\nprint(add(1, 2))<fim_middle>", prompt)
+}
+
+func TestCheckRequestLimits(t *testing.T) {
+	o := &config.ApplicationConfig{MaxMessages: 2, MaxImageSizeMB: 1}
+	image := func(size int) string {
+		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(make([]byte, size))
+	}
+	message := func(url string) schema.Message {
+		return schema.Message{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "describe"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": url}},
+		}}
+	}
+
+	assert.NoError(t, checkRequestLimits(o, []schema.Message{{Content: "a"}, message(image(1024))}))
+	assert.NoError(t, checkRequestLimits(o, []schema.Message{message("https://example.com/large.png")}))
+
+	var fiberErr *fiber.Error
+	err := checkRequestLimits(o, []schema.Message{{Content: "a"}, {Content: "b"}, {Content: "c"}})
+	assert.ErrorAs(t, err, &fiberErr)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, fiberErr.Code)
+
+	err = checkRequestLimits(o, []schema.Message{message(image(2 * 1024 * 1024))})
+	assert.ErrorAs(t, err, &fiberErr)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, fiberErr.Code)
+
+	// The base64 images of the image and transcription requests are checked as well
+	err = checkRequestLimits(o, nil, base64.StdEncoding.EncodeToString(make([]byte, 2*1024*1024)))
+	assert.ErrorAs(t, err, &fiberErr)
+
+	assert.NoError(t, checkRequestLimits(&config.ApplicationConfig{}, []schema.Message{{Content: "a"}, {Content: "b"}, {Content: "c"}, message(image(2 * 1024 * 1024))}))
+}
@@ -0,0 +1,277 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+)
+
+// CreateResponseEndpoint is the OpenAI Responses API endpoint https://platform.openai.com/docs/api-reference/responses/create
+// @Summary Create a model response, optionally chained to a previous stored response.
+// @Param request body schema.ResponseRequest true "query params"
+// @Success 200 {object} schema.Response "Response"
+// @Router /v1/responses [post]
+func CreateResponseEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, store *services.ResponseStore) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		request := new(schema.ResponseRequest)
+		if err := c.BodyParser(request); err != nil {
+			return fmt.Errorf("failed parsing request body: %w", err)
+		}
+
+		modelFile, err := fiberContext.ModelFromContext(c, cl, ml, request.Model, true)
+		if err != nil {
+			return err
+		}
+
+		// The conversation so far: the one stored with the previous response, if any
+		history := []schema.Message{}
+		if request.PreviousResponseID != "" {
+			previous, err := store.Get(request.PreviousResponseID, fiberContext.Owner(c))
+			if err != nil {
+				if errors.Is(err, services.ErrResponseNotFound) {
+					return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("previous response %s not found", request.PreviousResponseID))
+				}
+				return err
+			}
+			history = previous.Messages
+		}
+
+		inputMessages, err := responseInputMessages(request.Input)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		history = append(history, inputMessages...)
+
+		// Instructions are not carried over chained responses
+		messages := []schema.Message{}
+		if request.Instructions != "" {
+			messages = append(messages, schema.Message{Role: "system", Content: request.Instructions})
+		}
+		messages = append(messages, history...)
+
+		input := &schema.OpenAIRequest{
+			PredictionOptions: schema.PredictionOptions{
+				Model:       request.Model,
+				Temperature: request.Temperature,
+				TopP:        request.TopP,
+				Maxtokens:   request.MaxOutputTokens,
+			},
+			Messages: messages,
+			Stream:   request.Stream,
+		}
+		input.Context, input.Cancel = context.WithCancel(fiberContext.RequestContext(c, appConfig.Context))
+
+		cfg, input, err := mergeRequestWithConfig(modelFile, input, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+
+		predInput := chatPrompt(input, cfg, ml, appConfig, nil, false)
+
+		var previousResponseID *string
+		if request.PreviousResponseID != "" {
+			previousResponseID = &request.PreviousResponseID
+		}
+
+		response := &schema.Response{
+			ID:                 "resp_" + uuid.New().String(),
+			Object:             "response",
+			CreatedAt:          time.Now().Unix(),
+			Status:             "in_progress",
+			Model:              request.Model,
+			Instructions:       request.Instructions,
+			PreviousResponseID: previousResponseID,
+			Output:             []schema.ResponseOutputItem{},
+			Metadata:           request.Metadata,
+		}
+		itemID := "msg_" + uuid.New().String()
+		recordUsage := fiberContext.TokenUsageRecorder(c)
+		owner := fiberContext.Owner(c)
+
+		finish := func(text string, usage backend.TokenUsage, err error) {
+			recordUsage(usage.Prompt + usage.Completion)
+			if err != nil {
+				response.Status = "failed"
+				response.Error = &schema.APIError{Message: err.Error(), Type: "server_error"}
+			} else {
+				response.Status = "completed"
+				response.OutputText = text
+				response.Output = []schema.ResponseOutputItem{{
+					Type:    "message",
+					ID:      itemID,
+					Status:  "completed",
+					Role:    "assistant",
+					Content: []schema.ResponseOutputContent{{Type: "output_text", Text: text, Annotations: []interface{}{}}},
+				}}
+			}
+			response.Usage = schema.ResponseUsage{
+				InputTokens:  usage.Prompt,
+				OutputTokens: usage.Completion,
+				TotalTokens:  usage.Prompt + usage.Completion,
+			}
+
+			if err == nil && (request.Store == nil || *request.Store) {
+				if err := store.Save(services.StoredResponse{
+					Response: *response,
+					Messages: append(history, schema.Message{Role: "assistant", Content: text}),
+					Owner:    owner,
+				}); err != nil {
+					log.Error().Err(err).Str("id", response.ID).Msg("failed storing response")
+				}
+			}
+		}
+
+		if !request.Stream {
+			text := ""
+			_, usage, err := ComputeChoices(input, predInput, cfg, appConfig, ml, func(s string, c *[]schema.Choice) {
+				text = s
+			}, nil)
+			finish(text, usage, err)
+			if err != nil {
+				return err
+			}
+			return c.JSON(response)
+		}
+
+		c.Context().SetContentType("text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("Transfer-Encoding", "chunked")
+
+		events := make(chan schema.ResponseStreamEvent)
+
+		go func() {
+			created := *response
+			events <- schema.ResponseStreamEvent{Type: "response.created", Response: &created}
+
+			text := ""
+			_, usage, err := ComputeChoices(input, predInput, cfg, appConfig, ml, func(s string, c *[]schema.Choice) {
+				text = s
+			}, func(s string, usage backend.TokenUsage) bool {
+				events <- schema.ResponseStreamEvent{Type: "response.output_text.delta", ItemID: itemID, Delta: s}
+				return true
+			})
+			finish(text, usage, err)
+
+			eventType := "response.completed"
+			if err != nil {
+				eventType = "response.failed"
+			}
+			events <- schema.ResponseStreamEvent{Type: eventType, Response: response}
+			close(events)
+		}()
+
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			for ev := range events {
+				dat, err := json.Marshal(ev)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, dat); err != nil {
+					log.Debug().Msgf("Sending event failed: %v", err)
+					input.Cancel()
+				}
+				w.Flush()
+			}
+		}))
+		return nil
+	}
+}
+
+// GetResponseEndpoint https://platform.openai.com/docs/api-reference/responses/get
+// @Summary Retrieve a stored model response.
+// @Success 200 {object} schema.Response "Response"
+// @Router /v1/responses/{response_id} [get]
+func GetResponseEndpoint(store *services.ResponseStore) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		r, err := store.Get(c.Params("response_id"), fiberContext.Owner(c))
+		if err != nil {
+			if errors.Is(err, services.ErrResponseNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, err.Error())
+			}
+			return err
+		}
+		return c.JSON(r.Response)
+	}
+}
+
+// DeleteResponseEndpoint https://platform.openai.com/docs/api-reference/responses/delete
+// @Summary Delete a stored model response.
+// @Success 200 {object} schema.DeleteResponseResponse "Response"
+// @Router /v1/responses/{response_id} [delete]
+func DeleteResponseEndpoint(store *services.ResponseStore) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("response_id")
+		if err := store.Delete(id, fiberContext.Owner(c)); err != nil {
+			if errors.Is(err, services.ErrResponseNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, err.Error())
+			}
+			return err
+		}
+		return c.JSON(schema.DeleteResponseResponse{
+			ID:      id,
+			Object:  "response",
+			Deleted: true,
+		})
+	}
+}
+
+// responseInputMessages converts the input of the Responses API to chat messages.
+// The input can be a plain string, or a list of messages whose content is either
+// a string or a list of input_text parts.
+func responseInputMessages(input interface{}) ([]schema.Message, error) {
+	switch in := input.(type) {
+	case string:
+		return []schema.Message{{Role: "user", Content: in}}, nil
+	case []interface{}:
+		messages := []schema.Message{}
+		for _, item := range in {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unsupported input item: %v", item)
+			}
+			role, _ := m["role"].(string)
+			if role == "" {
+				role = "user"
+			}
+			switch content := m["content"].(type) {
+			case string:
+				messages = append(messages, schema.Message{Role: role, Content: content})
+			case []interface{}:
+				text := ""
+				for _, part := range content {
+					p, ok := part.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					switch p["type"] {
+					case "input_text", "output_text", "text":
+						t, _ := p["text"].(string)
+						text += t
+					}
+				}
+				messages = append(messages, schema.Message{Role: role, Content: text})
+			default:
+				return nil, fmt.Errorf("unsupported content for input message: %v", content)
+			}
+		}
+		return messages, nil
+	case nil:
+		return nil, fmt.Errorf("input is required")
+	}
+	return nil, fmt.Errorf("unsupported input: %v", input)
+}
@@ -0,0 +1,114 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseInputMessages(t *testing.T) {
+	messages, err := responseInputMessages("hello")
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.Message{{Role: "user", Content: "hello"}}, messages)
+
+	var input interface{}
+	err = json.Unmarshal([]byte(`[
+		{"role": "system", "content": "be brief"},
+		{"role": "user", "content": [{"type": "input_text", "text": "hi "}, {"type": "input_text", "text": "there"}]}
+	]`), &input)
+	assert.NoError(t, err)
+
+	messages, err = responseInputMessages(input)
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hi there"},
+	}, messages)
+
+	_, err = responseInputMessages(nil)
+	assert.Error(t, err)
+}
+
+func TestGetAndDeleteStoredResponse(t *testing.T) {
+	store := services.NewResponseStore(t.TempDir())
+	err := store.Save(services.StoredResponse{
+		Response: schema.Response{ID: "resp_1", Object: "response", Status: "completed", OutputText: "hi"},
+		Messages: []schema.Message{{Role: "user", Content: "hello"}, {Role: "assistant", Content: "hi"}},
+	})
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	app.Get("/responses/:response_id", GetResponseEndpoint(store))
+	app.Delete("/responses/:response_id", DeleteResponseEndpoint(store))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/responses/resp_1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var r schema.Response
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+	assert.Equal(t, "hi", r.OutputText)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/responses/resp_1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/responses/resp_1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+func TestStoredResponsesOfOtherTenants(t *testing.T) {
+	store := services.NewResponseStore(t.TempDir())
+	err := store.Save(services.StoredResponse{
+		Response: schema.Response{ID: "resp_1", Object: "response", Status: "completed", OutputText: "hi"},
+		Messages: []schema.Message{{Role: "user", Content: "my secret"}, {Role: "assistant", Content: "hi"}},
+		Owner:    (&config.Tenant{Name: "acme"}).Owner(),
+	})
+	assert.NoError(t, err)
+
+	// The tenant is set by the auth middleware from the API key of the request
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if name := c.Get("X-Tenant"); name != "" {
+			fiberContext.SetTenant(c, &config.Tenant{Name: name})
+		}
+		return c.Next()
+	})
+	dir := t.TempDir()
+	app.Post("/responses", CreateResponseEndpoint(config.NewBackendConfigLoader(dir), model.NewModelLoader(dir), &config.ApplicationConfig{}, store))
+	app.Get("/responses/:response_id", GetResponseEndpoint(store))
+	app.Delete("/responses/:response_id", DeleteResponseEndpoint(store))
+
+	request := func(method, path, tenant string, body io.Reader) int {
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// The responses of the other tenants are reported as missing
+	for _, tenant := range []string{"other", ""} {
+		assert.Equal(t, 404, request("GET", "/responses/resp_1", tenant, nil))
+		assert.Equal(t, 404, request("DELETE", "/responses/resp_1", tenant, nil))
+		assert.Equal(t, 404, request("POST", "/responses", tenant,
+			strings.NewReader(`{"model": "gpt", "input": "what did I say?", "previous_response_id": "resp_1"}`)))
+	}
+
+	assert.Equal(t, 200, request("GET", "/responses/resp_1", "acme", nil))
+	assert.Equal(t, 200, request("DELETE", "/responses/resp_1", "acme", nil))
+}
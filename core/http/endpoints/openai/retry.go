@@ -0,0 +1,99 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// inferWithPolicies runs infer with the config of the model, again as its retry policy allows, then
+// with the configs of its fallback models, in order, until one of them answers. It returns the
+// response and the config of the model which answered. Once tokens are streamed, the failures are
+// returned as they are, as the client already received a part of the output.
+// The models with an open circuit are skipped, and the failures are counted by the circuit breaker,
+// which runs probe to close the circuits
+func inferWithPolicies(ctx context.Context, cfg *config.BackendConfig, circuits *model.CircuitBreaker, probe func(*config.BackendConfig) error, streamed func() bool, infer func(*config.BackendConfig) (backend.LLMResponse, error)) (backend.LLMResponse, *config.BackendConfig, error) {
+	candidates := []*config.BackendConfig{cfg}
+	for _, fallback := range cfg.FallbackConfigs {
+		// The endpoints set the grammar of the request after reading the configs
+		c := *fallback
+		c.Grammar = cfg.Grammar
+		candidates = append(candidates, &c)
+	}
+
+	var response backend.LLMResponse
+	var err error
+	for i, c := range candidates {
+		if i > 0 {
+			log.Warn().Err(err).Str("model", cfg.Name).Str("fallback", c.Name).Msg("Inference failed, falling back to the next model")
+		}
+		for attempt := 1; attempt <= c.Retry.Attempts(); attempt++ {
+			if attempt > 1 {
+				select {
+				case <-ctx.Done():
+					return response, c, ctx.Err()
+				case <-time.After(c.Retry.Delay(attempt - 1)):
+				}
+			}
+
+			if err = circuits.Allow(c.Name); err != nil {
+				break
+			}
+			response, err = infer(c)
+			failure := inferenceFailure(err, response.Response)
+			switch {
+			case failure == "":
+				circuits.Success(c.Name)
+			case ctx.Err() == nil && failure != config.RetryOnEmpty:
+				circuits.Failure(c.Name, err, func() error { return probe(c) })
+			}
+			// Empty outputs are only failures for the policies retrying them
+			if failure == "" || failure == config.RetryOnEmpty && !c.Retry.Retries(config.RetryOnEmpty) {
+				return response, c, nil
+			}
+			if ctx.Err() != nil || streamed() {
+				return response, c, err
+			}
+			if !c.Retry.Retries(failure) || attempt == c.Retry.Attempts() {
+				break
+			}
+			log.Warn().Err(err).Str("model", c.Name).Str("failure", failure).Msgf("Inference failed, retrying (attempt %d of %d)", attempt+1, c.Retry.Attempts())
+		}
+	}
+
+	// No model answered with a non-empty output
+	return response, candidates[len(candidates)-1], err
+}
+
+// inferenceFailure returns the class of the failure of an inference, as the retry_on of the retry
+// policies, or "" if the inference succeeded
+func inferenceFailure(err error, output string) string {
+	switch {
+	case err == nil && strings.TrimSpace(output) == "":
+		return config.RetryOnEmpty
+	case err == nil:
+		return ""
+	case isBackendCrash(err):
+		return config.RetryOnCrash
+	default:
+		return config.RetryOnError
+	}
+}
+
+// isBackendCrash returns true for the errors of the backends which stopped or can not be reached
+func isBackendCrash(err error) bool {
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unavailable {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "connection reset")
+}
@@ -0,0 +1,127 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type inferenceResult struct {
+	output string
+	err    error
+}
+
+// fakeInference returns the results in order, recording the models running the inferences
+func fakeInference(results []inferenceResult, models *[]string) func(*config.BackendConfig) (backend.LLMResponse, error) {
+	return func(c *config.BackendConfig) (backend.LLMResponse, error) {
+		r := results[len(*models)]
+		*models = append(*models, c.Name)
+		return backend.LLMResponse{Response: r.output}, r.err
+	}
+}
+
+func notStreamed() bool { return false }
+
+func TestInferWithPoliciesRetries(t *testing.T) {
+	cfg := &config.BackendConfig{Name: "model", Retry: config.RetryPolicy{MaxAttempts: 3, Backoff: "1ms", RetryOn: []string{config.RetryOnCrash, config.RetryOnEmpty}}}
+	models := []string{}
+	response, answered, err := inferWithPolicies(context.Background(), cfg, nil, nil, notStreamed, fakeInference([]inferenceResult{
+		{err: status.Error(codes.Unavailable, "connection refused")},
+		{output: "  "},
+		{output: "answer"},
+	}, &models))
+	require.NoError(t, err)
+	assert.Equal(t, "answer", response.Response)
+	assert.Equal(t, "model", answered.Name)
+	assert.Equal(t, []string{"model", "model", "model"}, models)
+}
+
+func TestInferWithPoliciesFallback(t *testing.T) {
+	cfg := &config.BackendConfig{Name: "model", Fallback: []string{"small", "tiny"}}
+	cfg.Grammar = "root ::= \"yes\""
+	cfg.FallbackConfigs = []*config.BackendConfig{{Name: "small"}, {Name: "tiny"}}
+	models := []string{}
+	response, answered, err := inferWithPolicies(context.Background(), cfg, nil, nil, notStreamed, fakeInference([]inferenceResult{
+		// errors are not retried by default, but they fall back to the next model
+		{err: errors.New("out of memory")},
+		{err: errors.New("out of memory")},
+		{output: "yes"},
+	}, &models))
+	require.NoError(t, err)
+	assert.Equal(t, "yes", response.Response)
+	assert.Equal(t, "tiny", answered.Name)
+	assert.Equal(t, cfg.Grammar, answered.Grammar)
+	assert.Equal(t, []string{"model", "small", "tiny"}, models)
+}
+
+func TestInferWithPoliciesFailures(t *testing.T) {
+	// empty outputs are returned unless the policy retries them
+	models := []string{}
+	response, _, err := inferWithPolicies(context.Background(), &config.BackendConfig{Name: "model"}, nil, nil, notStreamed, fakeInference([]inferenceResult{{output: ""}}, &models))
+	require.NoError(t, err)
+	assert.Empty(t, response.Response)
+	assert.Len(t, models, 1)
+
+	// the errors of the last model are returned
+	models = []string{}
+	cfg := &config.BackendConfig{Name: "model", Retry: config.RetryPolicy{MaxAttempts: 2, Backoff: "1ms", RetryOn: []string{config.RetryOnError}}}
+	_, _, err = inferWithPolicies(context.Background(), cfg, nil, nil, notStreamed, fakeInference([]inferenceResult{{err: errors.New("first")}, {err: errors.New("second")}}, &models))
+	assert.EqualError(t, err, "second")
+	assert.Len(t, models, 2)
+
+	// nothing is run again once tokens were streamed
+	models = []string{}
+	_, _, err = inferWithPolicies(context.Background(), cfg, nil, nil, func() bool { return true }, fakeInference([]inferenceResult{{err: errors.New("first")}, {output: "answer"}}, &models))
+	assert.EqualError(t, err, "first")
+	assert.Len(t, models, 1)
+}
+
+func TestInferWithPoliciesCircuitBreaker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	circuits := model.NewCircuitBreaker(ctx, 2, time.Hour)
+	probe := func(*config.BackendConfig) error { return errors.New("still down") }
+
+	cfg := &config.BackendConfig{Name: "model", Retry: config.RetryPolicy{MaxAttempts: 3, Backoff: "1ms", RetryOn: []string{config.RetryOnCrash}}}
+	cfg.FallbackConfigs = []*config.BackendConfig{{Name: "small"}}
+	crash := status.Error(codes.Unavailable, "connection refused")
+
+	// the circuit opens after the second crash, and the request falls back to the next model
+	models := []string{}
+	response, answered, err := inferWithPolicies(ctx, cfg, circuits, probe, notStreamed, fakeInference([]inferenceResult{{err: crash}, {err: crash}, {output: "answer"}}, &models))
+	require.NoError(t, err)
+	assert.Equal(t, "answer", response.Response)
+	assert.Equal(t, "small", answered.Name)
+	assert.Equal(t, []string{"model", "model", "small"}, models)
+
+	// the model is skipped while its circuit is open
+	models = []string{}
+	_, answered, err = inferWithPolicies(ctx, cfg, circuits, probe, notStreamed, fakeInference([]inferenceResult{{output: "answer"}}, &models))
+	require.NoError(t, err)
+	assert.Equal(t, "small", answered.Name)
+	assert.Equal(t, []string{"small"}, models)
+
+	// without fallback, the requests fail fast
+	models = []string{}
+	_, _, err = inferWithPolicies(ctx, &config.BackendConfig{Name: "model"}, circuits, probe, notStreamed, fakeInference(nil, &models))
+	var circuitErr *model.CircuitOpenError
+	assert.ErrorAs(t, err, &circuitErr)
+	assert.Empty(t, models)
+}
+
+func TestInferenceFailure(t *testing.T) {
+	assert.Equal(t, "", inferenceFailure(nil, "answer"))
+	assert.Equal(t, config.RetryOnEmpty, inferenceFailure(nil, "\n"))
+	assert.Equal(t, config.RetryOnCrash, inferenceFailure(status.Error(codes.Unavailable, "transport is closing"), ""))
+	assert.Equal(t, config.RetryOnCrash, inferenceFailure(errors.New("dial tcp 127.0.0.1:40000: connect: connection refused"), ""))
+	assert.Equal(t, config.RetryOnError, inferenceFailure(status.Error(codes.Internal, "failed"), ""))
+}
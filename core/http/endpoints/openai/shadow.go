@@ -0,0 +1,107 @@
+package openai
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	model "github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// shadowChat mirrors the chat request to the shadow model of the config, for its share of the
+// requests. The shadow request runs in the background on a copy of the request, and is not
+// canceled with the request of the client. The returned function is called with the response of
+// the model when it completes, to log it next to the response of the shadow model.
+// With a single active backend the requests are not mirrored, as the models would replace each other
+func shadowChat(input *schema.OpenAIRequest, cfg *config.BackendConfig, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(response string) {
+	shadow := cfg.Shadow
+	if shadow.Model == "" || shadow.Percentage <= 0 || appConfig.SingleBackend || rand.Float64()*100 >= shadow.Percentage {
+		return func(string) {}
+	}
+
+	request := *input
+	request.Model = shadow.Model
+	request.Stream = false
+	// The contents of the messages are decoded again with the config of the shadow model
+	request.Messages = make([]schema.Message, len(input.Messages))
+	for i, m := range input.Messages {
+		m.StringContent = ""
+		m.StringImages = nil
+		request.Messages[i] = m
+	}
+	ctx, cancel := context.WithCancel(appConfig.Context)
+	request.Context = ctx
+	request.Cancel = cancel
+
+	responses := make(chan string, 1)
+	start := time.Now()
+	go func() {
+		defer cancel()
+
+		output, usage, err := runShadowChat(&request, cl, ml, appConfig)
+		if err != nil {
+			log.Warn().Err(err).Str("model", cfg.Name).Str("shadow_model", shadow.Model).Msg("Shadow request failed")
+			return
+		}
+
+		l := log.Info().
+			Str("model", cfg.Name).
+			Str("shadow_model", shadow.Model).
+			Dur("shadow_duration", time.Since(start)).
+			Int("shadow_prompt_tokens", usage.PromptTokens).
+			Int("shadow_completion_tokens", usage.CompletionTokens)
+		if shadow.LogResponses {
+			l = l.Str("response", <-responses).Str("shadow_response", output)
+		}
+		l.Msg("Shadow request completed")
+	}()
+
+	return func(response string) {
+		responses <- response
+	}
+}
+
+func runShadowChat(input *schema.OpenAIRequest, cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) (string, schema.OpenAIUsage, error) {
+	cfg, input, err := mergeRequestWithConfig(input.Model, input, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+	if err != nil {
+		return "", schema.OpenAIUsage{}, err
+	}
+
+	funcs, shouldUseFn := chatFunctions(input, cfg)
+	predInput := chatPrompt(input, cfg, ml, appConfig, funcs, shouldUseFn)
+	choices, tokenUsage, err := ComputeChoices(input, predInput, cfg, appConfig, ml, func(s string, c *[]schema.Choice) {
+		*c = append(*c, schema.Choice{Text: s})
+	}, nil)
+	if err != nil {
+		return "", schema.OpenAIUsage{}, err
+	}
+
+	output := ""
+	if len(choices) > 0 {
+		output = choices[0].Text
+	}
+	return output, schema.OpenAIUsage{
+		PromptTokens:     tokenUsage.Prompt,
+		CompletionTokens: tokenUsage.Completion,
+		TotalTokens:      tokenUsage.Prompt + tokenUsage.Completion,
+	}, nil
+}
+
+// choiceContent returns the content of the message of the first choice
+func choiceContent(choices []schema.Choice) string {
+	if len(choices) == 0 || choices[0].Message == nil {
+		return ""
+	}
+	switch content := choices[0].Message.Content.(type) {
+	case *string:
+		if content != nil {
+			return *content
+		}
+	case string:
+		return content
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer collects the logs written by the shadow requests in the background
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestShadowChat(t *testing.T) {
+	modelPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "candidate.yaml"), []byte(`name: candidate
+backend: missing-backend
+parameters:
+  model: candidate.gguf
+`), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+	appConfig := config.NewApplicationConfig(config.WithModelPath(modelPath))
+
+	logs := &syncBuffer{}
+	logger := log.Logger
+	log.Logger = zerolog.New(logs)
+	defer func() { log.Logger = logger }()
+
+	input := &schema.OpenAIRequest{
+		Messages: []schema.Message{{Role: "user", Content: "hi", StringContent: "hi"}},
+	}
+	cfg := &config.BackendConfig{Name: "production"}
+
+	// Without shadow model, or with the requests not selected, nothing is mirrored
+	shadowChat(input, cfg, cl, ml, appConfig)("hello")
+	cfg.Shadow = config.Shadow{Model: "candidate"}
+	shadowChat(input, cfg, cl, ml, appConfig)("hello")
+
+	cfg.Shadow.Percentage = 100
+	shadowChat(input, cfg, cl, ml, appConfig)("hello")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), "Shadow request failed")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, strings.Count(logs.String(), `"shadow_model":"candidate"`))
+
+	// The request of the client is not changed
+	assert.Equal(t, "hi", input.Messages[0].StringContent)
+	assert.Empty(t, input.Model)
+}
@@ -0,0 +1,50 @@
+package openai
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	model "github.com/mudler/LocalAI/pkg/model"
+)
+
+// TemplateDebugEndpoint renders the prompt of a chat completion request, without running the model
+// @Summary	Renders a chat completion request with the templates of the model, and returns the final prompt with its number of tokens. Set tokenize=false to skip loading the model to count the tokens.
+// @Param request body schema.OpenAIRequest true "query params"
+// @Param tokenize query bool false "count the tokens of the prompt (default true)"
+// @Success 200 {object} schema.TemplateDebugResponse "Response"
+// @Router /debug/template [post]
+func TemplateDebugEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		modelFile, input, err := readRequest(c, cl, ml, appConfig, true)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+
+		config, input, err := mergeRequestWithConfig(modelFile, input, cl, ml, appConfig.Debug, appConfig.Threads, appConfig.ContextSize, appConfig.F16)
+		if err != nil {
+			return fmt.Errorf("failed reading parameters from request:%w", err)
+		}
+
+		funcs, shouldUseFn := chatFunctions(input, config)
+		res := schema.TemplateDebugResponse{
+			Model:                input.Model,
+			Prompt:               chatPrompt(input, config, ml, appConfig, funcs, shouldUseFn),
+			UseTokenizerTemplate: config.TemplateConfig.UseTokenizerTemplate && !shouldUseFn,
+			Functions:            shouldUseFn,
+		}
+
+		if res.Prompt != "" && c.QueryBool("tokenize", true) {
+			tokens, err := backend.ModelTokenize(res.Prompt, ml, *config, appConfig)
+			if err != nil {
+				res.TokenizeError = err.Error()
+			} else {
+				res.Tokens = &tokens
+			}
+		}
+
+		return c.JSON(res)
+	}
+}
@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateDebugEndpoint(t *testing.T) {
+	modelPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "foo.yaml"), []byte(`name: foo
+parameters:
+  model: foo.gguf
+template:
+  chat: chat
+  chat_message: "<|{{.RoleName}}|>{{.Content}}"
+`), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(modelPath, "chat.tmpl"), []byte("{{.Input}}\n<|assistant|>"), 0600))
+
+	cl := config.NewBackendConfigLoader(modelPath)
+	assert.NoError(t, cl.LoadBackendConfigsFromPath(modelPath))
+	ml := model.NewModelLoader(modelPath)
+	appConfig := config.NewApplicationConfig(config.WithModelPath(modelPath))
+
+	app := fiber.New()
+	app.Post("/debug/template", TemplateDebugEndpoint(cl, ml, appConfig))
+
+	req := httptest.NewRequest("POST", "/debug/template?tokenize=false", strings.NewReader(`{
+		"model": "foo",
+		"messages": [{"role": "system", "content": "be brief"}, {"role": "user", "content": "hi"}]
+	}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var r schema.TemplateDebugResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+	assert.Equal(t, "foo", r.Model)
+	assert.Equal(t, "<|system|>be brief\n<|user|>hi\n<|assistant|>", r.Prompt)
+	assert.False(t, r.Functions)
+	assert.Nil(t, r.Tokens)
+}
@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/mudler/LocalAI/core/schema"
+)
+
+// timingHeader is set by the clients to opt in to the timing of the generation. It is
+// sent back as a response header, or as the last event of streamed responses
+const timingHeader = "X-LocalAI-Timing"
+
+func timingRequested(c *fiber.Ctx) bool {
+	b, _ := strconv.ParseBool(c.Get(timingHeader))
+	return b
+}
+
+// generationTiming computes the timing of a generation from its token usage. The queue
+// wait is the time spent before the backend started processing the prompt, so it is only
+// known with the backends reporting their timings
+func generationTiming(usage backend.TokenUsage) schema.Timing {
+	t := schema.Timing{
+		TimeToFirstToken: usage.TimingTimeToFirstToken,
+		PromptProcessing: usage.TimingPromptProcessing,
+		TokenGeneration:  usage.TimingTokenGeneration,
+	}
+	if usage.TimingTokenGeneration > 0 {
+		t.TokensPerSecond = float64(usage.Completion) / usage.TimingTokenGeneration * 1000
+	}
+	if usage.TimingPromptProcessing > 0 && usage.TimingTimeToFirstToken > usage.TimingPromptProcessing {
+		t.QueueWait = usage.TimingTimeToFirstToken - usage.TimingPromptProcessing
+	}
+	return t
+}
+
+func setTimingHeader(c *fiber.Ctx, usage backend.TokenUsage) {
+	dat, err := json.Marshal(generationTiming(usage))
+	if err != nil {
+		return
+	}
+	c.Set(timingHeader, string(dat))
+}
+
+func writeTimingEvent(w *bufio.Writer, usage backend.TokenUsage) {
+	dat, err := json.Marshal(generationTiming(usage))
+	if err != nil {
+		return
+	}
+	w.WriteString(fmt.Sprintf("event: x-localai-timing\ndata: %s\n\n", dat))
+}
@@ -0,0 +1,27 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/mudler/LocalAI/core/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerationTiming(t *testing.T) {
+	timing := generationTiming(backend.TokenUsage{
+		Prompt:                 10,
+		Completion:             50,
+		TimingPromptProcessing: 100,
+		TimingTokenGeneration:  2000,
+		TimingTimeToFirstToken: 150,
+	})
+	assert.Equal(t, 150.0, timing.TimeToFirstToken)
+	assert.Equal(t, 25.0, timing.TokensPerSecond)
+	assert.Equal(t, 50.0, timing.QueueWait)
+
+	// Backends not reporting timings only have the time to first token
+	timing = generationTiming(backend.TokenUsage{Completion: 50, TimingTimeToFirstToken: 150})
+	assert.Equal(t, 150.0, timing.TimeToFirstToken)
+	assert.Zero(t, timing.TokensPerSecond)
+	assert.Zero(t, timing.QueueWait)
+}
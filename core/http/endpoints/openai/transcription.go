@@ -10,6 +10,7 @@ import (
 
 	"github.com/mudler/LocalAI/core/backend"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	model "github.com/mudler/LocalAI/pkg/model"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,9 +22,31 @@ import (
 // @accept multipart/form-data
 // @Param model formData string true "model"
 // @Param file formData file true "file"
+// @Param language formData string false "language"
+// @Param prompt formData string false "prompt"
+// @Param response_format formData string false "response_format"
+// @Param timestamp_granularities[] formData []string false "timestamp_granularities"
+// @Param diarize formData bool false "diarize"
 // @Success 200 {object} map[string]string	 "Response"
 // @Router /v1/audio/transcriptions [post]
 func TranscriptEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return audioEndpoint(cl, ml, appConfig, false)
+}
+
+// TranslationEndpoint is the OpenAI Whisper API endpoint https://platform.openai.com/docs/api-reference/audio/createTranslation
+// @Summary Translates audio into English.
+// @accept multipart/form-data
+// @Param model formData string true "model"
+// @Param file formData file true "file"
+// @Param prompt formData string false "prompt"
+// @Param response_format formData string false "response_format"
+// @Success 200 {object} map[string]string	 "Response"
+// @Router /v1/audio/translations [post]
+func TranslationEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig) func(c *fiber.Ctx) error {
+	return audioEndpoint(cl, ml, appConfig, true)
+}
+
+func audioEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, translate bool) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		m, input, err := readRequest(c, cl, ml, appConfig, false)
 		if err != nil {
@@ -65,13 +88,38 @@ func TranscriptEndpoint(cl *config.BackendConfigLoader, ml *model.ModelLoader, a
 
 		log.Debug().Msgf("Audio file copied to: %+v", dst)
 
-		tr, err := backend.ModelTranscription(dst, input.Language, input.Translate, ml, *config, appConfig)
+		granularities := map[string]bool{}
+		if form, err := c.MultipartForm(); err == nil {
+			for _, g := range append(form.Value["timestamp_granularities[]"], form.Value["timestamp_granularities"]...) {
+				granularities[g] = true
+			}
+		}
+		wordTimestamps := granularities["word"]
+
+		// Speaker labels are returned only in the segments of verbose responses
+		responseFormat := c.FormValue("response_format")
+		diarize := c.FormValue("diarize") == "true" && responseFormat == "verbose_json"
+
+		if progress := fiberContext.JobProgress(c); progress != nil {
+			progress(0, fmt.Sprintf("transcribing %s", file.Filename))
+		}
+		tr, err := backend.ModelTranscription(fiberContext.RequestContext(c, appConfig.Context), dst, input.Language, c.FormValue("prompt"), translate || input.Translate, wordTimestamps, diarize, ml, *config, appConfig)
 		if err != nil {
 			return err
 		}
 
 		log.Debug().Msgf("Trascribed: %+v", tr)
-		// TODO: handle different outputs here
+
+		switch responseFormat {
+		case "text":
+			return c.Status(http.StatusOK).SendString(tr.Text)
+		case "verbose_json":
+			if wordTimestamps {
+				for _, s := range tr.Segments {
+					tr.Words = append(tr.Words, s.Words...)
+				}
+			}
+		}
 		return c.Status(http.StatusOK).JSON(tr)
 	}
 }
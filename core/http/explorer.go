@@ -8,9 +8,10 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/mudler/LocalAI/core/explorer"
 	"github.com/mudler/LocalAI/core/http/routes"
+	"github.com/mudler/LocalAI/pkg/ipfilter"
 )
 
-func Explorer(db *explorer.Database, discoveryServer *explorer.DiscoveryServer) *fiber.App {
+func Explorer(db *explorer.Database, discoveryServer *explorer.DiscoveryServer, filter *ipfilter.Filter) *fiber.App {
 
 	fiberCfg := fiber.Config{
 		Views: renderEngine(),
@@ -21,6 +22,7 @@ func Explorer(db *explorer.Database, discoveryServer *explorer.DiscoveryServer)
 	}
 
 	app := fiber.New(fiberCfg)
+	app.Use(ipFilter(filter))
 
 	routes.RegisterExplorerRoutes(app, db, discoveryServer)
 
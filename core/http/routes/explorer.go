@@ -10,4 +10,5 @@ func RegisterExplorerRoutes(app *fiber.App, db *coreExplorer.Database, ds *coreE
 	app.Get("/", explorer.Dashboard())
 	app.Post("/network/add", explorer.AddNetwork(db))
 	app.Get("/networks", explorer.ShowNetworks(db, ds))
+	app.Get("/models", explorer.ShowModels(db, ds))
 }
@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/swagger"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/http/endpoints/localai"
+	"github.com/mudler/LocalAI/core/http/endpoints/openai"
 	"github.com/mudler/LocalAI/core/p2p"
 	"github.com/mudler/LocalAI/core/services"
 	"github.com/mudler/LocalAI/internal"
@@ -16,24 +20,78 @@ func RegisterLocalAIRoutes(app *fiber.App,
 	ml *model.ModelLoader,
 	appConfig *config.ApplicationConfig,
 	galleryService *services.GalleryService,
+	tokenQuotas *services.TokenQuotas,
+	jobs *services.JobService,
+	generations *services.GenerationService,
+	recentErrors *services.RecentErrors,
 	auth func(*fiber.Ctx) error) {
 
 	app.Get("/swagger/*", swagger.HandlerDefault) // default
 
 	// LocalAI API endpoints
 
-	modelGalleryEndpointService := localai.CreateModelGalleryEndpointService(appConfig.Galleries, appConfig.ModelPath, galleryService)
-	app.Post("/models/apply", auth, modelGalleryEndpointService.ApplyModelGalleryEndpoint())
-	app.Post("/models/delete/:name", auth, modelGalleryEndpointService.DeleteModelGalleryEndpoint())
+	// The galleries are not part of the minimal profile
+	if !appConfig.DisableGalleryEndpoint {
+		modelGalleryEndpointService := localai.CreateModelGalleryEndpointService(appConfig, galleryService)
+		// the models, the galleries and the backends are shared by all the tenants, and only managed by the
+		// API keys of the instance
+		app.Post("/models/apply", auth, fiberContext.AdminOnly, modelGalleryEndpointService.ApplyModelGalleryEndpoint())
+		app.Post("/models/delete/:name", auth, fiberContext.AdminOnly, modelGalleryEndpointService.DeleteModelGalleryEndpoint())
 
-	app.Get("/models/available", auth, modelGalleryEndpointService.ListModelFromGalleryEndpoint())
-	app.Get("/models/galleries", auth, modelGalleryEndpointService.ListModelGalleriesEndpoint())
-	app.Post("/models/galleries", auth, modelGalleryEndpointService.AddModelGalleryEndpoint())
-	app.Delete("/models/galleries", auth, modelGalleryEndpointService.RemoveModelGalleryEndpoint())
-	app.Get("/models/jobs/:uuid", auth, modelGalleryEndpointService.GetOpStatusEndpoint())
-	app.Get("/models/jobs", auth, modelGalleryEndpointService.GetAllStatusEndpoint())
+		app.Get("/models/available", auth, modelGalleryEndpointService.ListModelFromGalleryEndpoint())
+		app.Get("/models/available/:id", auth, modelGalleryEndpointService.GetModelFromGalleryEndpoint())
+		app.Get("/models/galleries", auth, modelGalleryEndpointService.ListModelGalleriesEndpoint())
+		app.Post("/models/galleries", auth, fiberContext.AdminOnly, modelGalleryEndpointService.AddModelGalleryEndpoint())
+		app.Delete("/models/galleries", auth, fiberContext.AdminOnly, modelGalleryEndpointService.RemoveModelGalleryEndpoint())
+		app.Get("/models/jobs/:uuid", auth, modelGalleryEndpointService.GetOpStatusEndpoint())
+		app.Get("/models/jobs", auth, modelGalleryEndpointService.GetAllStatusEndpoint())
+
+		backendGalleryEndpointService := localai.CreateBackendGalleryEndpointService(appConfig.BackendGalleries, appConfig.AssetsDestination, galleryService)
+		app.Get("/backends", auth, backendGalleryEndpointService.ListBackendsEndpoint())
+		app.Get("/backends/available", auth, backendGalleryEndpointService.ListAvailableBackendsEndpoint())
+		app.Post("/backends/apply", auth, fiberContext.AdminOnly, backendGalleryEndpointService.ApplyBackendEndpoint())
+		app.Post("/backends/delete/:name", auth, fiberContext.AdminOnly, backendGalleryEndpointService.DeleteBackendEndpoint())
+	}
+	app.Get("/models/:name/status", auth, localai.ModelStatusEndpoint(cl, ml))
+	app.Post("/models/:name/swap", auth, fiberContext.AdminOnly, localai.ModelSwapEndpoint(cl, ml, appConfig))
+
+	// The configurations of the models, edited remotely by the API keys of the instance
+	app.Get("/models/config/:name", auth, fiberContext.AdminOnly, localai.GetModelConfigEndpoint(cl))
+	app.Post("/models/config", auth, fiberContext.AdminOnly, localai.CreateModelConfigEndpoint(cl, ml, appConfig))
+	app.Put("/models/config/:name", auth, fiberContext.AdminOnly, localai.UpdateModelConfigEndpoint(cl, ml, appConfig))
+	app.Delete("/models/config/:name", auth, fiberContext.AdminOnly, localai.DeleteModelConfigEndpoint(cl, ml))
+
+	// Jobs run in the background: the async requests and the operations of the galleries
+	app.Get("/v1/jobs", auth, localai.ListJobsEndpoint(jobs, galleryService))
+	app.Get("/v1/jobs/:id", auth, localai.GetJobEndpoint(jobs, galleryService))
+	app.Get("/v1/jobs/:id/result", auth, localai.GetJobResultEndpoint(jobs))
+	app.Get("/v1/jobs/:id/events", auth, localai.JobEventsEndpoint(jobs, galleryService))
+	app.Delete("/v1/jobs/:id", auth, localai.CancelJobEndpoint(jobs, galleryService))
+
+	app.Get("/v1/generations", auth, localai.ListGenerationsEndpoint(generations))
+	app.Post("/v1/generations/:id/cancel", auth, localai.CancelGenerationEndpoint(generations))
+
+	// the images generated by the instance, shown in the gallery of the image studio
+	if appConfig.ImageDir != "" {
+		app.Get("/api/images", auth, localai.ListGeneratedImagesEndpoint(appConfig))
+	}
 
 	app.Post("/tts", auth, localai.TTSEndpoint(cl, ml, appConfig))
+	app.Get("/v1/audio/voices", auth, localai.ListVoicesEndpoint(cl, ml, appConfig))
+	app.Post("/v1/sound-generation", auth, localai.SoundGenerationEndpoint(cl, ml, appConfig))
+	app.Post("/v1/audio/voice-clone", auth, localai.VoiceCloneEndpoint(appConfig))
+	app.Get("/v1/audio/voice-clone", auth, localai.ListClonedVoicesEndpoint(appConfig))
+	app.Delete("/v1/audio/voice-clone/:name", auth, localai.DeleteClonedVoiceEndpoint(appConfig))
+	app.Get("/v1/audio/transcriptions/stream", auth, localai.TranscriptionStreamUpgrade(cl, ml, appConfig), websocket.New(localai.TranscriptionStreamEndpoint(ml, appConfig)))
+
+	// Vision
+	app.Post("/v1/detection", auth, localai.DetectionEndpoint(cl, ml, appConfig))
+	app.Post("/v1/classification", auth, localai.ClassificationEndpoint(cl, ml, appConfig))
+	app.Post("/v1/similarity", auth, localai.SimilarityEndpoint(cl, ml, appConfig))
+	app.Post("/v1/classify", auth, localai.TextClassificationEndpoint(cl, ml, appConfig))
+	app.Post("/v1/ner", auth, localai.EntitiesEndpoint(cl, ml, appConfig))
+	app.Post("/v1/translate", auth, localai.TranslationEndpoint(cl, ml, appConfig))
+	app.Post("/v1/detect-language", auth, localai.LanguageDetectionEndpoint(cl, ml, appConfig))
 
 	// Stores
 	sl := model.NewModelLoader("")
@@ -42,6 +100,25 @@ func RegisterLocalAIRoutes(app *fiber.App,
 	app.Post("/stores/get", auth, localai.StoresGetEndpoint(sl, appConfig))
 	app.Post("/stores/find", auth, localai.StoresFindEndpoint(sl, appConfig))
 
+	// Pipelines, whose retrieve steps query the stores
+	app.Get("/v1/pipelines", auth, localai.ListPipelinesEndpoint(appConfig))
+	app.Post("/v1/pipelines/:name", auth, localai.PipelineEndpoint(cl, ml, sl, appConfig))
+
+	// Retrieval augmented generation, from the stores
+	app.Post("/v1/rag/query", auth, openai.RAGEndpoint(cl, ml, sl, appConfig))
+
+	// Agents running the tool calls server-side, whose vector_search tool queries the stores
+	app.Post("/v1/agent", auth, openai.AgentEndpoint(cl, ml, sl, appConfig))
+
+	// Scheduled tasks, run as jobs. The scheduler shares the loader of the stores with the pipelines
+	tasks := services.NewTaskScheduler(appConfig, jobs, tokenQuotas, localai.TaskRunner(cl, ml, sl, appConfig))
+	tasks.Start()
+	app.Get("/v1/tasks", auth, localai.ListTasksEndpoint(tasks))
+	app.Post("/v1/tasks", auth, localai.CreateTaskEndpoint(tasks))
+	app.Get("/v1/tasks/:name", auth, localai.GetTaskEndpoint(tasks))
+	app.Delete("/v1/tasks/:name", auth, localai.DeleteTaskEndpoint(tasks))
+	app.Post("/v1/tasks/:name/run", auth, localai.RunTaskEndpoint(tasks))
+
 	// Kubernetes health checks
 	ok := func(c *fiber.Ctx) error {
 		return c.SendStatus(200)
@@ -50,17 +127,38 @@ func RegisterLocalAIRoutes(app *fiber.App,
 	app.Get("/healthz", ok)
 	app.Get("/readyz", ok)
 
-	app.Get("/metrics", auth, localai.LocalAIMetricsEndpoint())
+	if !appConfig.DisableMetrics {
+		app.Get("/metrics", auth, localai.LocalAIMetricsEndpoint())
+	}
+	app.Get("/system", auth, localai.SystemInformationsEndpoint(ml, appConfig))
+	app.Get("/api/dashboard", auth, localai.DashboardEndpoint(ml, generations, recentErrors))
+	app.Get("/api/dashboard/stream", auth, localai.DashboardStreamEndpoint(ml, generations, recentErrors))
+	app.Get("/v1/usage", auth, localai.UsageEndpoint(tokenQuotas, appConfig))
+
+	app.Get("/admin/loglevel", auth, localai.GetLogLevelEndpoint())
+	app.Post("/admin/loglevel", auth, fiberContext.AdminOnly, localai.SetLogLevelEndpoint())
+
+	// The profiles of the Go runtime, to diagnose the goroutine leaks and the memory usage
+	if appConfig.EnablePprof {
+		app.Get("/debug/goroutines", auth, fiberContext.AdminOnly, localai.GoroutinesEndpoint())
+		app.Use("/debug/pprof", auth, fiberContext.AdminOnly, pprof.New())
+	}
+
+	app.Get("/config/validate", auth, localai.ValidateConfigEndpoint(appConfig))
+	app.Get("/config/schema", auth, localai.ConfigSchemaEndpoint())
+	app.Get("/config/effective", auth, fiberContext.AdminOnly, localai.EffectiveConfigEndpoint(appConfig))
+	app.Get("/config/export", auth, fiberContext.AdminOnly, localai.ExportConfigEndpoint(cl, appConfig))
+	app.Post("/config/import", auth, fiberContext.AdminOnly, localai.ImportConfigEndpoint(galleryService))
 
 	// Experimental Backend Statistics Module
 	backendMonitorService := services.NewBackendMonitorService(ml, cl, appConfig) // Split out for now
 	app.Get("/backend/monitor", auth, localai.BackendMonitorEndpoint(backendMonitorService))
-	app.Post("/backend/shutdown", auth, localai.BackendShutdownEndpoint(backendMonitorService))
+	app.Post("/backend/shutdown", auth, fiberContext.AdminOnly, localai.BackendShutdownEndpoint(backendMonitorService))
 
 	// p2p
 	if p2p.IsP2PEnabled() {
 		app.Get("/api/p2p", auth, localai.ShowP2PNodes(appConfig))
-		app.Get("/api/p2p/token", auth, localai.ShowP2PToken(appConfig))
+		app.Get("/api/p2p/token", auth, fiberContext.AdminOnly, localai.ShowP2PToken(appConfig))
 	}
 
 	app.Get("/version", auth, func(c *fiber.Ctx) error {
@@ -1,23 +1,43 @@
 package routes
 
 import (
+	"path/filepath"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/mudler/LocalAI/core/config"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/http/endpoints/localai"
 	"github.com/mudler/LocalAI/core/http/endpoints/openai"
+	"github.com/mudler/LocalAI/core/services"
 	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/mudler/LocalAI/pkg/storage"
 )
 
 func RegisterOpenAIRoutes(app *fiber.App,
 	cl *config.BackendConfigLoader,
 	ml *model.ModelLoader,
 	appConfig *config.ApplicationConfig,
+	jobs *services.JobService,
+	generations *services.GenerationService,
 	auth func(*fiber.Ctx) error) {
 	// openAI compatible API endpoint
 
+	// the chat and completion requests can be cancelled by their ID
+	cancellable := localai.GenerationMiddleware(generations)
+
 	// chat
-	app.Post("/v1/chat/completions", auth, openai.ChatEndpoint(cl, ml, appConfig))
-	app.Post("/chat/completions", auth, openai.ChatEndpoint(cl, ml, appConfig))
+	app.Post("/v1/chat/completions", auth, cancellable, openai.ChatEndpoint(cl, ml, appConfig))
+	app.Post("/chat/completions", auth, cancellable, openai.ChatEndpoint(cl, ml, appConfig))
+	// renders the prompt of a chat request, to debug the templates of the models
+	app.Post("/debug/template", auth, openai.TemplateDebugEndpoint(cl, ml, appConfig))
+	// runs a chat request with several models, to compare their outputs
+	app.Post("/v1/experimental/compare", auth, openai.CompareEndpoint(cl, ml, appConfig))
+
+	// responses
+	responseStore := services.NewResponseStore(filepath.Join(appConfig.ConfigsDir, "responses"))
+	app.Post("/v1/responses", auth, openai.CreateResponseEndpoint(cl, ml, appConfig, responseStore))
+	app.Get("/v1/responses/:response_id", auth, openai.GetResponseEndpoint(responseStore))
+	app.Delete("/v1/responses/:response_id", auth, openai.DeleteResponseEndpoint(responseStore))
 
 	// edit
 	app.Post("/v1/edits", auth, openai.EditEndpoint(cl, ml, appConfig))
@@ -55,10 +75,19 @@ func RegisterOpenAIRoutes(app *fiber.App,
 	app.Get("/v1/files/:file_id/content", auth, openai.GetFilesContentsEndpoint(cl, appConfig))
 	app.Get("/files/:file_id/content", auth, openai.GetFilesContentsEndpoint(cl, appConfig))
 
+	// fine-tuning, as jobs adding the fine-tuned models to the models of the instance
+	app.Post("/v1/fine_tuning/jobs", auth, fiberContext.AdminOnly, openai.CreateFineTuningJobEndpoint(cl, appConfig, jobs))
+
 	// completion
-	app.Post("/v1/completions", auth, openai.CompletionEndpoint(cl, ml, appConfig))
-	app.Post("/completions", auth, openai.CompletionEndpoint(cl, ml, appConfig))
-	app.Post("/v1/engines/:model/completions", auth, openai.CompletionEndpoint(cl, ml, appConfig))
+	app.Post("/v1/completions", auth, cancellable, openai.CompletionEndpoint(cl, ml, appConfig))
+	app.Post("/completions", auth, cancellable, openai.CompletionEndpoint(cl, ml, appConfig))
+	app.Post("/v1/engines/:model/completions", auth, cancellable, openai.CompletionEndpoint(cl, ml, appConfig))
+	// fill-in-the-middle completions, with the text before and after the cursor, for the code editors
+	app.Post("/v1/infill", auth, cancellable, openai.CompletionEndpoint(cl, ml, appConfig))
+
+	// llama.cpp server compatible completions, for the editor plugins
+	app.Post("/completion", auth, cancellable, openai.LlamaCPPCompletionEndpoint(cl, ml, appConfig))
+	app.Post("/infill", auth, cancellable, openai.LlamaCPPCompletionEndpoint(cl, ml, appConfig))
 
 	// embeddings
 	app.Post("/v1/embeddings", auth, openai.EmbeddingsEndpoint(cl, ml, appConfig))
@@ -66,18 +95,31 @@ func RegisterOpenAIRoutes(app *fiber.App,
 	app.Post("/v1/engines/:model/embeddings", auth, openai.EmbeddingsEndpoint(cl, ml, appConfig))
 
 	// audio
-	app.Post("/v1/audio/transcriptions", auth, openai.TranscriptEndpoint(cl, ml, appConfig))
+	app.Post("/v1/audio/transcriptions", auth, localai.AsyncJobMiddleware(jobs, "transcription"), openai.TranscriptEndpoint(cl, ml, appConfig))
+	app.Post("/v1/audio/translations", auth, localai.AsyncJobMiddleware(jobs, "translation"), openai.TranslationEndpoint(cl, ml, appConfig))
 	app.Post("/v1/audio/speech", auth, localai.TTSEndpoint(cl, ml, appConfig))
 
 	// images
-	app.Post("/v1/images/generations", auth, openai.ImageEndpoint(cl, ml, appConfig))
+	app.Post("/v1/images/generations", auth, localai.AsyncJobMiddleware(jobs, "image_generation"), openai.ImageEndpoint(cl, ml, appConfig))
+	app.Post("/v1/images/upscale", auth, openai.ImageUpscaleEndpoint(cl, ml, appConfig))
+	app.Post("/v1/images/remove-background", auth, openai.ImageRemoveBackgroundEndpoint(cl, ml, appConfig))
 
-	if appConfig.ImageDir != "" {
+	// The encrypted files are decrypted when served
+	switch {
+	case appConfig.StorageEncryptionKey != nil && appConfig.OutputStorage != nil:
+		app.Get("/generated-images/*", localai.GeneratedFileEndpoint(appConfig.OutputStorage))
+	case appConfig.ImageDir != "":
 		app.Static("/generated-images", appConfig.ImageDir)
 	}
 
 	if appConfig.AudioDir != "" {
-		app.Static("/generated-audio", appConfig.AudioDir)
+		if appConfig.StorageEncryptionKey != nil {
+			// the key is validated on startup
+			audio, _ := storage.NewEncrypted(storage.NewLocal(appConfig.AudioDir), appConfig.StorageEncryptionKey)
+			app.Get("/generated-audio/*", localai.GeneratedFileEndpoint(audio))
+		} else {
+			app.Static("/generated-audio", appConfig.AudioDir)
+		}
 	}
 
 	// List models
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/core/gallery"
+	fiberContext "github.com/mudler/LocalAI/core/http/ctx"
 	"github.com/mudler/LocalAI/core/http/elements"
 	"github.com/mudler/LocalAI/core/http/endpoints/localai"
 	"github.com/mudler/LocalAI/core/p2p"
@@ -88,7 +89,8 @@ func RegisterUIRoutes(app *fiber.App,
 	app.Get("/", auth, localai.WelcomeEndpoint(appConfig, cl, ml, modelStatus))
 
 	if p2p.IsP2PEnabled() {
-		app.Get("/p2p", auth, func(c *fiber.Ctx) error {
+		// the dashboard shows the token of the network
+		app.Get("/p2p", auth, fiberContext.AdminOnly, func(c *fiber.Ctx) error {
 			summary := fiber.Map{
 				"Title":   "LocalAI - P2P dashboard",
 				"Version": internal.PrintableVersion(),
@@ -119,6 +121,32 @@ func RegisterUIRoutes(app *fiber.App,
 		})
 	}
 
+	// Show the dashboard, with the state of the instance streamed from /api/dashboard/stream
+	app.Get("/dashboard", auth, func(c *fiber.Ctx) error {
+		summary := fiber.Map{
+			"Title":        "LocalAI - Dashboard",
+			"Version":      internal.PrintableVersion(),
+			"IsP2PEnabled": p2p.IsP2PEnabled(),
+		}
+
+		// Render index
+		return c.Render("views/dashboard", summary)
+	})
+
+	// Show the gallery browser, which installs and deletes the models with the jobs of the API
+	if !appConfig.DisableGalleryEndpoint {
+		app.Get("/gallery/", auth, func(c *fiber.Ctx) error {
+			summary := fiber.Map{
+				"Title":        "LocalAI - Model gallery",
+				"Version":      internal.PrintableVersion(),
+				"IsP2PEnabled": p2p.IsP2PEnabled(),
+			}
+
+			// Render index
+			return c.Render("views/gallery", summary)
+		})
+	}
+
 	// Show the Models page (all models)
 	app.Get("/browse", auth, func(c *fiber.Ctx) error {
 		term := c.Query("term")
@@ -186,7 +214,7 @@ func RegisterUIRoutes(app *fiber.App,
 
 	// This route is used when the "Install" button is pressed, we submit here a new job to the gallery service
 	// https://htmx.org/examples/progress-bar/
-	app.Post("/browse/install/model/:id", auth, func(c *fiber.Ctx) error {
+	app.Post("/browse/install/model/:id", auth, fiberContext.AdminOnly, func(c *fiber.Ctx) error {
 		galleryID := strings.Clone(c.Params("id")) // note: strings.Clone is required for multiple requests!
 		log.Debug().Msgf("UI job submitted to install  : %+v\n", galleryID)
 
@@ -203,6 +231,7 @@ func RegisterUIRoutes(app *fiber.App,
 			Id:               uid,
 			GalleryModelName: galleryID,
 			Galleries:        appConfig.Galleries,
+			Owner:            fiberContext.Owner(c),
 		}
 		go func() {
 			galleryService.C <- op
@@ -213,7 +242,7 @@ func RegisterUIRoutes(app *fiber.App,
 
 	// This route is used when the "Install" button is pressed, we submit here a new job to the gallery service
 	// https://htmx.org/examples/progress-bar/
-	app.Post("/browse/delete/model/:id", auth, func(c *fiber.Ctx) error {
+	app.Post("/browse/delete/model/:id", auth, fiberContext.AdminOnly, func(c *fiber.Ctx) error {
 		galleryID := strings.Clone(c.Params("id")) // note: strings.Clone is required for multiple requests!
 		log.Debug().Msgf("UI job submitted to delete  : %+v\n", galleryID)
 		var galleryName = galleryID
@@ -241,6 +270,7 @@ func RegisterUIRoutes(app *fiber.App,
 			Id:               uid,
 			Delete:           true,
 			GalleryModelName: galleryName,
+			Owner:            fiberContext.Owner(c),
 		}
 		go func() {
 			galleryService.C <- op
@@ -315,6 +345,42 @@ func RegisterUIRoutes(app *fiber.App,
 		return c.Render("views/chat", summary)
 	})
 
+	// Show the Playground page, to try the chat and completion requests with their parameters
+	app.Get("/playground/:model", auth, func(c *fiber.Ctx) error {
+		backendConfigs, _ := services.ListModels(cl, ml, "", true)
+
+		summary := fiber.Map{
+			"Title":        "LocalAI - Playground with " + c.Params("model"),
+			"ModelsConfig": backendConfigs,
+			"Model":        c.Params("model"),
+			"Version":      internal.PrintableVersion(),
+			"IsP2PEnabled": p2p.IsP2PEnabled(),
+		}
+
+		// Render index
+		return c.Render("views/playground", summary)
+	})
+
+	app.Get("/playground/", auth, func(c *fiber.Ctx) error {
+		backendConfigs, _ := services.ListModels(cl, ml, "", true)
+
+		if len(backendConfigs) == 0 {
+			// If no model is available redirect to the index which suggests how to install models
+			return c.Redirect("/")
+		}
+
+		summary := fiber.Map{
+			"Title":        "LocalAI - Playground with " + backendConfigs[0],
+			"ModelsConfig": backendConfigs,
+			"Model":        backendConfigs[0],
+			"Version":      internal.PrintableVersion(),
+			"IsP2PEnabled": p2p.IsP2PEnabled(),
+		}
+
+		// Render index
+		return c.Render("views/playground", summary)
+	})
+
 	app.Get("/talk/", auth, func(c *fiber.Ctx) error {
 		backendConfigs, _ := services.ListModels(cl, ml, "", true)
 
@@ -392,6 +458,63 @@ func RegisterUIRoutes(app *fiber.App,
 		return c.Render("views/text2image", summary)
 	})
 
+	// Show the Image studio page, to generate the images with their parameters and browse the images generated
+	app.Get("/image-studio/:model", auth, func(c *fiber.Ctx) error {
+		backendConfigs := cl.GetAllBackendConfigs()
+
+		summary := fiber.Map{
+			"Title":        "LocalAI - Image studio with " + c.Params("model"),
+			"ModelsConfig": backendConfigs,
+			"Model":        c.Params("model"),
+			"Version":      internal.PrintableVersion(),
+			"IsP2PEnabled": p2p.IsP2PEnabled(),
+		}
+
+		// Render index
+		return c.Render("views/image_studio", summary)
+	})
+
+	app.Get("/image-studio/", auth, func(c *fiber.Ctx) error {
+		backendConfigs := cl.GetAllBackendConfigs()
+
+		if len(backendConfigs) == 0 {
+			// If no model is available redirect to the index which suggests how to install models
+			return c.Redirect("/")
+		}
+
+		summary := fiber.Map{
+			"Title":        "LocalAI - Image studio with " + backendConfigs[0].Name,
+			"ModelsConfig": backendConfigs,
+			"Model":        backendConfigs[0].Name,
+			"Version":      internal.PrintableVersion(),
+			"IsP2PEnabled": p2p.IsP2PEnabled(),
+		}
+
+		// Render index
+		return c.Render("views/image_studio", summary)
+	})
+
+	// Show the Audio tools page, to synthesize speech and transcribe audio
+	app.Get("/audio/", auth, func(c *fiber.Ctx) error {
+		backendConfigs := cl.GetAllBackendConfigs()
+
+		if len(backendConfigs) == 0 {
+			// If no model is available redirect to the index which suggests how to install models
+			return c.Redirect("/")
+		}
+
+		summary := fiber.Map{
+			"Title":        "LocalAI - Audio tools",
+			"ModelsConfig": backendConfigs,
+			"Model":        backendConfigs[0].Name,
+			"Version":      internal.PrintableVersion(),
+			"IsP2PEnabled": p2p.IsP2PEnabled(),
+		}
+
+		// Render index
+		return c.Render("views/audio", summary)
+	})
+
 	app.Get("/tts/:model", auth, func(c *fiber.Ctx) error {
 		backendConfigs := cl.GetAllBackendConfigs()
 
@@ -0,0 +1,142 @@
+/*
+
+The audio tools synthesize the speech of a text with the voices of a TTS model, and transcribe
+an audio file, uploaded or recorded in the browser
+
+*/
+
+function audioTools(model) {
+  return {
+    // Text to speech
+    ttsModel: model,
+    voices: [],
+    voice: "",
+    text: "",
+    speaking: false,
+    audioURL: "",
+
+    // Transcription
+    transcriptionModel: model,
+    language: "",
+    file: null,
+    recorder: null,
+    recording: false,
+    uploadProgress: 0,
+    transcribing: false,
+    transcript: null,
+
+    error: "",
+
+    headers() {
+      const headers = {};
+      const key = localStorage.getItem("key");
+      if (key) {
+        headers.Authorization = `Bearer ${key}`;
+      }
+      return headers;
+    },
+
+    async loadVoices() {
+      this.voices = [];
+      this.voice = "";
+      if (!this.ttsModel) return;
+      const response = await fetch(`/v1/audio/voices?model=${encodeURIComponent(this.ttsModel)}`, {
+        headers: this.headers(),
+      });
+      if (response.ok) {
+        this.voices = (await response.json()).data || [];
+      }
+    },
+
+    async speak() {
+      this.error = "";
+      this.speaking = true;
+      if (this.audioURL) {
+        URL.revokeObjectURL(this.audioURL);
+        this.audioURL = "";
+      }
+      try {
+        const response = await fetch("/v1/audio/speech", {
+          method: "POST",
+          headers: { ...this.headers(), "Content-Type": "application/json" },
+          body: JSON.stringify({ model: this.ttsModel, input: this.text, voice: this.voice }),
+        });
+        if (!response.ok) {
+          this.error = `POST /v1/audio/speech ${response.status}: ${await response.text()}`;
+          return;
+        }
+        this.audioURL = URL.createObjectURL(await response.blob());
+      } catch (error) {
+        this.error = `Failed to synthesize the speech: ${error}`;
+      } finally {
+        this.speaking = false;
+      }
+    },
+
+    async record() {
+      this.error = "";
+      try {
+        const stream = await navigator.mediaDevices.getUserMedia({ audio: true });
+        const chunks = [];
+        this.recorder = new MediaRecorder(stream);
+        this.recorder.ondataavailable = (event) => chunks.push(event.data);
+        this.recorder.onstop = () => {
+          stream.getTracks().forEach((track) => track.stop());
+          this.file = new File([new Blob(chunks, { type: "audio/webm" })], "recording.webm", { type: "audio/webm" });
+          this.recording = false;
+        };
+        this.recorder.start();
+        this.recording = true;
+      } catch (error) {
+        this.error = `Failed to record the audio: ${error}`;
+      }
+    },
+
+    stopRecording() {
+      if (this.recorder) {
+        this.recorder.stop();
+        this.recorder = null;
+      }
+    },
+
+    // transcribe uploads the audio with an XMLHttpRequest, which reports the progress of the upload
+    transcribe() {
+      this.error = "";
+      this.transcript = null;
+      this.uploadProgress = 0;
+      this.transcribing = true;
+
+      const form = new FormData();
+      form.append("file", this.file);
+      form.append("model", this.transcriptionModel);
+      form.append("response_format", "verbose_json");
+      if (this.language) {
+        form.append("language", this.language);
+      }
+
+      const xhr = new XMLHttpRequest();
+      xhr.open("POST", "/v1/audio/transcriptions");
+      for (const [name, value] of Object.entries(this.headers())) {
+        xhr.setRequestHeader(name, value);
+      }
+      xhr.upload.onprogress = (event) => {
+        if (event.lengthComputable) {
+          this.uploadProgress = Math.round((event.loaded / event.total) * 100);
+        }
+      };
+      xhr.onload = () => {
+        this.transcribing = false;
+        if (xhr.status !== 200) {
+          this.error = `POST /v1/audio/transcriptions ${xhr.status}: ${xhr.responseText}`;
+          return;
+        }
+        this.transcript = JSON.parse(xhr.responseText);
+      };
+      xhr.onerror = () => {
+        this.transcribing = false;
+        this.error = "Failed to send the audio";
+      };
+      xhr.send(form);
+    },
+  };
+}
@@ -0,0 +1,164 @@
+/*
+
+The gallery browser lists the models of the galleries, installs and deletes them as jobs of the API,
+and follows the progress of their downloads with the events of the jobs
+
+*/
+
+function galleryBrowser() {
+  return {
+    models: [],
+    loading: true,
+    search: "",
+    tag: "",
+    installedOnly: false,
+    selected: null,
+    details: null,
+    loadingDetails: false,
+    // jobs are the running installs and deletions, by model ID
+    jobs: {},
+    error: "",
+
+    headers() {
+      const headers = {};
+      const key = localStorage.getItem("key");
+      if (key) {
+        headers.Authorization = `Bearer ${key}`;
+      }
+      return headers;
+    },
+
+    async load() {
+      this.loading = true;
+      try {
+        const response = await fetch("/models/available", { headers: this.headers() });
+        if (!response.ok) {
+          this.error = `GET /models/available ${response.status}: ${await response.text()}`;
+          return;
+        }
+        this.models = await response.json();
+      } catch (error) {
+        this.error = `Failed to load the galleries: ${error}`;
+      } finally {
+        this.loading = false;
+      }
+    },
+
+    id(model) {
+      return `${model.gallery.name}@${model.name}`;
+    },
+
+    get tags() {
+      return [...new Set(this.models.flatMap((m) => m.tags || []))].sort();
+    },
+
+    get filtered() {
+      const term = this.search.toLowerCase();
+      return this.models.filter(
+        (m) =>
+          (!this.installedOnly || m.installed) &&
+          (!this.tag || (m.tags || []).includes(this.tag)) &&
+          (!term ||
+            m.name.toLowerCase().includes(term) ||
+            (m.description || "").toLowerCase().includes(term) ||
+            (m.tags || []).some((t) => t.toLowerCase().includes(term))),
+      );
+    },
+
+    async select(model) {
+      this.selected = model;
+      this.details = null;
+      this.loadingDetails = true;
+      try {
+        const response = await fetch(`/models/available/${encodeURIComponent(this.id(model))}`, {
+          headers: this.headers(),
+        });
+        if (response.ok) {
+          this.details = await response.json();
+        }
+      } finally {
+        this.loadingDetails = false;
+      }
+    },
+
+    async install(model) {
+      await this.run(model, "/models/apply", { id: this.id(model) });
+    },
+
+    async uninstall(model) {
+      if (!confirm(`Delete the model ${model.name}?`)) return;
+      await this.run(model, `/models/delete/${encodeURIComponent(model.name)}`);
+    },
+
+    // run starts an install or a deletion, and follows it until it is finished
+    async run(model, url, body) {
+      this.error = "";
+      const id = this.id(model);
+      const response = await fetch(url, {
+        method: "POST",
+        headers: { ...this.headers(), "Content-Type": "application/json" },
+        body: body ? JSON.stringify(body) : undefined,
+      });
+      if (!response.ok) {
+        this.error = `POST ${url} ${response.status}: ${await response.text()}`;
+        return;
+      }
+      const { uuid } = await response.json();
+      this.jobs[id] = { status: "running", progress: 0, message: "queued" };
+
+      try {
+        const job = await this.follow(uuid, (job) => (this.jobs[id] = job));
+        if (job && job.status === "failed") {
+          this.error = `${model.name}: ${job.error}`;
+        } else if (job) {
+          model.installed = !!body;
+          if (this.details && this.details.id === id) {
+            this.details.installed = model.installed;
+          }
+        }
+      } catch (error) {
+        this.error = `Failed to follow the job of ${model.name}: ${error}`;
+      } finally {
+        delete this.jobs[id];
+      }
+    },
+
+    // follow reads the events of a job until it is finished, and returns its last status.
+    // The events are read with fetch, as EventSource can not send the API key
+    async follow(uuid, onUpdate) {
+      const response = await fetch(`/v1/jobs/${uuid}/events`, { headers: this.headers() });
+      if (!response.ok) {
+        throw new Error(`GET /v1/jobs/${uuid}/events ${response.status}: ${await response.text()}`);
+      }
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      let job = null;
+      while (true) {
+        const { value, done } = await reader.read();
+        if (done) break;
+
+        buffer += value;
+        const lines = buffer.split("\n");
+        buffer = lines.pop(); // Retain any incomplete line in the buffer
+
+        for (const line of lines) {
+          if (!line.startsWith("data: ")) continue;
+          job = JSON.parse(line.substring(6));
+          onUpdate(job);
+        }
+      }
+      return job;
+    },
+
+    formatSize(bytes) {
+      if (!bytes) return "unknown";
+      const units = ["B", "KB", "MB", "GB", "TB"];
+      let i = 0;
+      while (bytes >= 1024 && i < units.length - 1) {
+        bytes /= 1024;
+        i++;
+      }
+      return `${bytes.toFixed(i ? 1 : 0)} ${units[i]}`;
+    },
+  };
+}
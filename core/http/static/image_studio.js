@@ -0,0 +1,139 @@
+/*
+
+The image studio generates the images with the parameters set in the page, streaming the steps
+and the previews of the generation, and shows the gallery of the images generated by the instance
+
+*/
+
+function imageStudio(model) {
+  return {
+    model: model,
+    prompt: "",
+    negativePrompt: "",
+    size: "512x512",
+    step: 20,
+    seed: "",
+    n: 1,
+    running: false,
+    progress: 0,
+    preview: "",
+    results: [],
+    error: "",
+    gallery: [],
+    controller: null,
+
+    headers() {
+      const headers = { "Content-Type": "application/json" };
+      const key = localStorage.getItem("key");
+      if (key) {
+        headers.Authorization = `Bearer ${key}`;
+      }
+      return headers;
+    },
+
+    request() {
+      const req = {
+        model: this.model,
+        // the negative prompt follows the prompt, after a |
+        prompt: this.negativePrompt ? `${this.prompt}|${this.negativePrompt}` : this.prompt,
+        size: this.size,
+        step: Number(this.step),
+        n: Number(this.n),
+        stream: true,
+        partial_images: 2,
+      };
+      if (this.seed !== "") {
+        req.seed = Number(this.seed);
+      }
+      return req;
+    },
+
+    async generate() {
+      this.running = true;
+      this.progress = 0;
+      this.preview = "";
+      this.results = [];
+      this.error = "";
+      this.controller = new AbortController();
+      try {
+        const response = await fetch("/v1/images/generations", {
+          method: "POST",
+          headers: this.headers(),
+          body: JSON.stringify(this.request()),
+          signal: this.controller.signal,
+        });
+        if (!response.ok) {
+          this.error = `POST /v1/images/generations ${response.status}: ${await response.text()}`;
+          return;
+        }
+        await this.read(response);
+        this.loadGallery();
+      } catch (error) {
+        if (error.name !== "AbortError") {
+          this.error = `Failed to generate the images: ${error}`;
+        }
+      } finally {
+        this.running = false;
+        this.controller = null;
+      }
+    },
+
+    async read(response) {
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      while (true) {
+        const { value, done } = await reader.read();
+        if (done) break;
+
+        buffer += value;
+        const lines = buffer.split("\n");
+        buffer = lines.pop(); // Retain any incomplete line in the buffer
+
+        for (const line of lines) {
+          if (!line.startsWith("data: ") || line === "data: [DONE]") continue;
+          const ev = JSON.parse(line.substring(6));
+          switch (ev.type) {
+            case "image_generation.progress":
+              this.progress = Math.round((ev.step / ev.steps) * 100);
+              break;
+            case "image_generation.partial_image":
+              this.progress = Math.round((ev.step / ev.steps) * 100);
+              this.preview = `data:image/png;base64,${ev.b64_json}`;
+              break;
+            case "image_generation.completed":
+              this.results.push(ev.url || `data:image/png;base64,${ev.b64_json}`);
+              this.preview = "";
+              break;
+            case "error":
+              this.error = ev.error.message;
+              break;
+          }
+        }
+      }
+    },
+
+    stop() {
+      if (this.controller) {
+        this.controller.abort();
+      }
+    },
+
+    async loadGallery() {
+      const response = await fetch("/api/images?limit=24", { headers: this.headers() });
+      if (response.ok) {
+        this.gallery = (await response.json()).data;
+      }
+    },
+
+    // reuse sets the parameters of an image of the gallery, to generate it again or a variation of it
+    reuse(image) {
+      const p = image.parameters;
+      this.prompt = p.prompt;
+      this.negativePrompt = p.negative_prompt || "";
+      this.size = `${p.width}x${p.height}`;
+      this.step = p.steps || this.step;
+      this.seed = p.seed;
+      window.scrollTo({ top: 0, behavior: "smooth" });
+    },
+  };
+}
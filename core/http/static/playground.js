@@ -0,0 +1,168 @@
+/*
+
+The playground runs a chat or a completion request with the parameters set in the page,
+and shows the prompt rendered by the templates of the model and the request as cURL
+
+*/
+
+function playground(model) {
+  return {
+    model: model,
+    mode: "chat",
+    systemPrompt: "",
+    input: "",
+    temperature: 0.7,
+    topP: 0.9,
+    maxTokens: 256,
+    stream: true,
+    output: "",
+    error: "",
+    usage: null,
+    prompt: "",
+    curl: "",
+    running: false,
+    controller: null,
+
+    endpoint() {
+      return this.mode === "chat" ? "/v1/chat/completions" : "/v1/completions";
+    },
+
+    request() {
+      const req = {
+        model: this.model,
+        temperature: Number(this.temperature),
+        top_p: Number(this.topP),
+        stream: this.stream,
+      };
+      if (Number(this.maxTokens) > 0) {
+        req.max_tokens = Number(this.maxTokens);
+      }
+      if (this.mode === "chat") {
+        req.messages = [];
+        if (this.systemPrompt) {
+          req.messages.push({ role: "system", content: this.systemPrompt });
+        }
+        req.messages.push({ role: "user", content: this.input });
+      } else {
+        req.prompt = this.input;
+      }
+      return req;
+    },
+
+    headers() {
+      const headers = { "Content-Type": "application/json" };
+      const key = localStorage.getItem("key");
+      if (key) {
+        headers.Authorization = `Bearer ${key}`;
+      }
+      return headers;
+    },
+
+    async run() {
+      this.output = "";
+      this.error = "";
+      this.usage = null;
+      this.running = true;
+      this.controller = new AbortController();
+      try {
+        const response = await fetch(this.endpoint(), {
+          method: "POST",
+          headers: this.headers(),
+          body: JSON.stringify(this.request()),
+          signal: this.controller.signal,
+        });
+        if (!response.ok) {
+          this.error = `POST ${this.endpoint()} ${response.status}: ${await response.text()}`;
+          return;
+        }
+        if (!this.stream) {
+          const res = await response.json();
+          const choice = res.choices[0];
+          this.output = this.mode === "chat" ? choice.message.content : choice.text;
+          this.usage = res.usage;
+          return;
+        }
+        await this.read(response);
+      } catch (error) {
+        // aborting the request cancels the generation on the server
+        if (error.name !== "AbortError") {
+          this.error = `Failed to run the request: ${error}`;
+        }
+      } finally {
+        this.running = false;
+        this.controller = null;
+      }
+    },
+
+    async read(response) {
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      while (true) {
+        const { value, done } = await reader.read();
+        if (done) break;
+
+        buffer += value;
+        const lines = buffer.split("\n");
+        buffer = lines.pop(); // Retain any incomplete line in the buffer
+
+        for (const line of lines) {
+          if (!line.startsWith("data: ") || line === "data: [DONE]") continue;
+          try {
+            const res = JSON.parse(line.substring(6));
+            const choice = res.choices && res.choices[0];
+            if (choice) {
+              const token = this.mode === "chat" ? choice.delta && choice.delta.content : choice.text;
+              if (token) {
+                this.output += token;
+              }
+            }
+            if (res.usage && res.usage.total_tokens) {
+              this.usage = res.usage;
+            }
+          } catch (error) {
+            console.error("Failed to parse line:", line, error);
+          }
+        }
+      }
+    },
+
+    stop() {
+      if (this.controller) {
+        this.controller.abort();
+      }
+    },
+
+    // preview renders the prompt of the chat request with the templates of the model, without running it
+    async preview() {
+      this.prompt = "";
+      this.error = "";
+      if (this.mode !== "chat") {
+        this.prompt = "The preview of the prompt is only available for the chat requests.";
+        return;
+      }
+      const response = await fetch("/debug/template?tokenize=false", {
+        method: "POST",
+        headers: this.headers(),
+        body: JSON.stringify(this.request()),
+      });
+      if (!response.ok) {
+        this.error = `POST /debug/template ${response.status}: ${await response.text()}`;
+        return;
+      }
+      const res = await response.json();
+      this.prompt = res.use_tokenizer_template
+        ? "The prompt is rendered by the backend, with the chat template of the tokenizer of the model."
+        : res.prompt;
+    },
+
+    exportCurl() {
+      const body = JSON.stringify(this.request(), null, 2).replaceAll("'", "'\\''");
+      let curl = `curl ${window.location.origin}${this.endpoint()} \\\n  -H "Content-Type: application/json" \\\n`;
+      if (localStorage.getItem("key")) {
+        curl += `  -H "Authorization: Bearer $API_KEY" \\\n`;
+      }
+      this.curl = curl + `  -d '${body}'`;
+      navigator.clipboard?.writeText(this.curl);
+    },
+  };
+}
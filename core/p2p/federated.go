@@ -1,6 +1,10 @@
 package p2p
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/mudler/LocalAI/pkg/ipfilter"
+)
 
 const FederatedID = "federated"
 
@@ -15,16 +19,32 @@ type FederatedServer struct {
 	listenAddr, service, p2ptoken string
 	requestTable                  map[string]int
 	loadBalanced                  bool
+	// scheduler queues the requests by priority when the workers have a number of slots
+	scheduler *FederatedScheduler
+	// filter denies the connections of the clients not allowed
+	filter *ipfilter.Filter
+	// priorityClients are the clients whose high priority requests are scheduled as such
+	priorityClients *ipfilter.Filter
 }
 
-func NewFederatedServer(listenAddr, service, p2pToken string, loadBalanced bool) *FederatedServer {
-	return &FederatedServer{
-		listenAddr:   listenAddr,
-		service:      service,
-		p2ptoken:     p2pToken,
-		requestTable: map[string]int{},
-		loadBalanced: loadBalanced,
+// NewFederatedServer proxies the requests to the workers of the service. With workerSlots, each worker
+// serves up to workerSlots requests at a time, and the other requests are scheduled by priority: the high
+// priority is only given to the clients allowed by priorityClients. The connections of the clients not
+// allowed by filter are closed
+func NewFederatedServer(listenAddr, service, p2pToken string, loadBalanced bool, workerSlots int, filter, priorityClients *ipfilter.Filter) *FederatedServer {
+	fs := &FederatedServer{
+		listenAddr:      listenAddr,
+		service:         service,
+		p2ptoken:        p2pToken,
+		requestTable:    map[string]int{},
+		loadBalanced:    loadBalanced,
+		filter:          filter,
+		priorityClients: priorityClients,
+	}
+	if workerSlots > 0 {
+		fs.scheduler = NewFederatedScheduler(workerSlots)
 	}
+	return fs
 }
 
 func (fs *FederatedServer) SelectLeastUsedServer() string {
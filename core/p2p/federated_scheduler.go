@@ -0,0 +1,325 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The priority classes of the federated requests
+const (
+	PriorityLow = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// PriorityHeader sets the priority class of a federated request: low, normal (default) or high
+const PriorityHeader = "X-LocalAI-Priority"
+
+// originRetention is how long the accounting of an origin is kept once it has no request anymore
+const originRetention = time.Hour
+
+var ErrNoWorkers = errors.New("no available nodes yet")
+
+// ParsePriority parses the priority class of a request, normal when it is empty
+func ParsePriority(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q, expected low, normal or high", s)
+}
+
+// OriginUsage is the accounting of the requests of an origin node
+type OriginUsage struct {
+	Requests int
+	// Busy is the time the requests of the origin held the slots of the workers
+	Busy time.Duration
+	// Preempted is the number of requests of the origin preempted by high priority requests
+	Preempted int
+
+	// last is when a request of the origin arrived or finished
+	last time.Time
+}
+
+// FederatedScheduler shares the slots of the workers between the federated requests: each worker serves
+// up to slots requests at a time, and the other requests wait in a queue, by priority, then from the
+// origins which used the workers the least, then by arrival. A high priority request arriving while all
+// the slots are busy preempts a low priority request the worker did not start answering: it is cancelled
+// and requeued, to run elsewhere. The accounting of the origins without requests for an hour is dropped
+type FederatedScheduler struct {
+	slots int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	workers []string
+	active  map[string]int
+	running []*FederatedSlot
+	queue   []*FederatedSlot
+	usage   map[string]*OriginUsage
+}
+
+func NewFederatedScheduler(slots int) *FederatedScheduler {
+	return &FederatedScheduler{
+		slots:  slots,
+		now:    time.Now,
+		active: map[string]int{},
+		usage:  map[string]*OriginUsage{},
+	}
+}
+
+// FederatedSlot is a request holding, or waiting for, a slot of a worker
+type FederatedSlot struct {
+	s        *FederatedScheduler
+	Origin   string
+	Priority int
+
+	worker     string
+	avoid      string
+	enqueued   time.Time
+	assignedAt time.Time
+	started    bool
+	preempted  bool
+	ready      chan struct{}
+	preempt    chan struct{}
+}
+
+// Acquire waits for a slot of one of the workers for a request, until the context is done
+func (s *FederatedScheduler) Acquire(ctx context.Context, workers []string, origin string, priority int) (*FederatedSlot, error) {
+	s.mu.Lock()
+	s.evict()
+	u := s.origin(origin)
+	u.Requests++
+	u.last = s.now()
+	slot := &FederatedSlot{s: s, Origin: origin, Priority: priority, enqueued: s.now()}
+	s.mu.Unlock()
+	return slot, s.wait(ctx, slot, workers)
+}
+
+// Requeue waits for a new slot for a preempted request, on another worker when possible. The request keeps
+// its place in the queue
+func (slot *FederatedSlot) Requeue(ctx context.Context, workers []string) error {
+	slot.s.mu.Lock()
+	slot.avoid = slot.worker
+	slot.worker = ""
+	slot.started, slot.preempted = false, false
+	slot.s.mu.Unlock()
+	return slot.s.wait(ctx, slot, workers)
+}
+
+func (s *FederatedScheduler) wait(ctx context.Context, slot *FederatedSlot, workers []string) error {
+	s.mu.Lock()
+	if len(workers) == 0 {
+		s.mu.Unlock()
+		return ErrNoWorkers
+	}
+	s.workers = workers
+	slot.ready = make(chan struct{})
+	slot.preempt = make(chan struct{})
+	// the waiting requests go first, as the slots of new workers may be free
+	s.dispatch()
+	switch {
+	case s.assignFree(slot):
+	case slot.Priority == PriorityHigh && s.preemptFor(slot):
+	default:
+		s.queue = append(s.queue, slot)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-slot.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if i := slices.Index(s.queue, slot); i >= 0 {
+			s.queue = slices.Delete(s.queue, i, i+1)
+			return ctx.Err()
+		}
+		// the slot was assigned meanwhile
+		s.release(slot)
+		return ctx.Err()
+	}
+}
+
+// Worker returns the address of the worker of the slot
+func (slot *FederatedSlot) Worker() string {
+	slot.s.mu.Lock()
+	defer slot.s.mu.Unlock()
+	return slot.worker
+}
+
+// Preempted is closed when the request is preempted by a high priority request
+func (slot *FederatedSlot) Preempted() <-chan struct{} {
+	slot.s.mu.Lock()
+	defer slot.s.mu.Unlock()
+	return slot.preempt
+}
+
+// Start marks the request as answered by the worker, so that it cannot be preempted anymore. It returns
+// false if the request was preempted before
+func (slot *FederatedSlot) Start() bool {
+	slot.s.mu.Lock()
+	defer slot.s.mu.Unlock()
+	if slot.preempted {
+		return false
+	}
+	slot.started = true
+	return true
+}
+
+// Release frees the slot of a request done, for the requests waiting in the queue
+func (slot *FederatedSlot) Release() {
+	slot.s.mu.Lock()
+	defer slot.s.mu.Unlock()
+	slot.s.release(slot)
+}
+
+// Usage returns the accounting of the origin nodes
+func (s *FederatedScheduler) Usage() map[string]OriginUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := map[string]OriginUsage{}
+	for origin, u := range s.usage {
+		usage[origin] = *u
+	}
+	return usage
+}
+
+// release frees the slot, unless it was given to the request preempting it. Called with the lock held
+func (s *FederatedScheduler) release(slot *FederatedSlot) {
+	i := slices.Index(s.running, slot)
+	if i < 0 {
+		return
+	}
+	s.running = slices.Delete(s.running, i, i+1)
+	s.active[slot.worker]--
+	u := s.origin(slot.Origin)
+	u.Busy += s.now().Sub(slot.assignedAt)
+	u.last = s.now()
+	s.dispatch()
+}
+
+// origin returns the accounting of the origin, created if needed. Called with the lock held
+func (s *FederatedScheduler) origin(origin string) *OriginUsage {
+	u := s.usage[origin]
+	if u == nil {
+		u = &OriginUsage{last: s.now()}
+		s.usage[origin] = u
+	}
+	return u
+}
+
+// evict drops the accounting of the origins without running or waiting request for longer than the
+// retention of the origins. Called with the lock held
+func (s *FederatedScheduler) evict() {
+	pending := map[string]bool{}
+	for _, slot := range append(slices.Clone(s.running), s.queue...) {
+		pending[slot.Origin] = true
+	}
+	for origin, u := range s.usage {
+		if !pending[origin] && s.now().Sub(u.last) > originRetention {
+			delete(s.usage, origin)
+		}
+	}
+}
+
+// dispatch gives the free slots to the requests of the queue, the next one first. Called with the lock held
+func (s *FederatedScheduler) dispatch() {
+	for len(s.queue) > 0 {
+		next := 0
+		for i := range s.queue {
+			if s.before(s.queue[i], s.queue[next]) {
+				next = i
+			}
+		}
+		if !s.assignFree(s.queue[next]) {
+			return
+		}
+		s.queue = slices.Delete(s.queue, next, next+1)
+	}
+}
+
+// before tells if the request a is served before b: by priority, then from the origin which used the
+// workers the least, then by arrival
+func (s *FederatedScheduler) before(a, b *FederatedSlot) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if busyA, busyB := s.origin(a.Origin).Busy, s.origin(b.Origin).Busy; busyA != busyB {
+		return busyA < busyB
+	}
+	return a.enqueued.Before(b.enqueued)
+}
+
+// assignFree gives the request a slot of the worker with the fewest requests, avoiding the worker which
+// preempted it when possible. Called with the lock held
+func (s *FederatedScheduler) assignFree(slot *FederatedSlot) bool {
+	free := []string{}
+	for _, w := range s.workers {
+		if s.active[w] < s.slots {
+			free = append(free, w)
+		}
+	}
+	if len(free) == 0 {
+		return false
+	}
+	if others := slices.DeleteFunc(slices.Clone(free), func(w string) bool { return w == slot.avoid }); len(others) > 0 {
+		free = others
+	}
+	rand.Shuffle(len(free), func(i, j int) { free[i], free[j] = free[j], free[i] })
+	worker := slices.MinFunc(free, func(a, b string) int { return s.active[a] - s.active[b] })
+	s.active[worker]++
+	s.assign(slot, worker)
+	return true
+}
+
+// preemptFor gives the request the slot of a low priority request not answered yet: the one of the origin
+// which used the workers the most, and the most recent one, to lose the least work. Called with the lock held
+func (s *FederatedScheduler) preemptFor(slot *FederatedSlot) bool {
+	var victim *FederatedSlot
+	for _, r := range s.running {
+		if r.Priority != PriorityLow || r.started || !slices.Contains(s.workers, r.worker) {
+			continue
+		}
+		if victim == nil {
+			victim = r
+			continue
+		}
+		busyR, busyV := s.origin(r.Origin).Busy, s.origin(victim.Origin).Busy
+		if busyR > busyV || (busyR == busyV && r.assignedAt.After(victim.assignedAt)) {
+			victim = r
+		}
+	}
+	if victim == nil {
+		return false
+	}
+
+	i := slices.Index(s.running, victim)
+	s.running = slices.Delete(s.running, i, i+1)
+	victim.preempted = true
+	close(victim.preempt)
+	u := s.origin(victim.Origin)
+	u.Busy += s.now().Sub(victim.assignedAt)
+	u.Preempted++
+
+	// the slot goes to the request right away, the worker keeps the same number of requests
+	s.assign(slot, victim.worker)
+	return true
+}
+
+func (s *FederatedScheduler) assign(slot *FederatedSlot, worker string) {
+	slot.worker = worker
+	slot.assignedAt = s.now()
+	s.running = append(s.running, slot)
+	close(slot.ready)
+}
@@ -0,0 +1,146 @@
+package p2p
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FederatedScheduler", func() {
+	var (
+		s       *FederatedScheduler
+		ctx     context.Context
+		clockMu sync.Mutex
+		now     time.Time
+	)
+	workers := []string{"worker"}
+
+	advance := func(d time.Duration) {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		now = now.Add(d)
+	}
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		s = NewFederatedScheduler(1)
+		s.now = func() time.Time {
+			clockMu.Lock()
+			defer clockMu.Unlock()
+			return now
+		}
+	})
+
+	// acquire waits for a slot in the background, once the request is queued
+	acquire := func(origin string, priority int) <-chan *FederatedSlot {
+		s.mu.Lock()
+		queued := len(s.queue)
+		s.mu.Unlock()
+		acquired := make(chan *FederatedSlot, 1)
+		go func() {
+			defer GinkgoRecover()
+			slot, err := s.Acquire(ctx, workers, origin, priority)
+			Expect(err).ToNot(HaveOccurred())
+			acquired <- slot
+		}()
+		Eventually(func() int {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.queue)
+		}).Should(Equal(queued + 1))
+		return acquired
+	}
+
+	It("serves the higher priorities first, then the origins which used the workers the least", func() {
+		running, err := s.Acquire(ctx, workers, "a", PriorityNormal)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(running.Worker()).To(Equal("worker"))
+
+		low := acquire("b", PriorityLow)
+		fromA := acquire("a", PriorityNormal)
+		fromC := acquire("c", PriorityNormal)
+		high := acquire("b", PriorityHigh)
+
+		advance(time.Minute)
+		running.Release()
+		var next *FederatedSlot
+		Eventually(high).Should(Receive(&next))
+		Expect(s.Usage()["a"].Busy).To(Equal(time.Minute))
+
+		// a used the worker for a minute, c did not
+		next.Release()
+		Eventually(fromC).Should(Receive(&next))
+		next.Release()
+		Eventually(fromA).Should(Receive(&next))
+		next.Release()
+		Eventually(low).Should(Receive(&next))
+		next.Release()
+
+		Expect(s.Usage()["a"].Requests).To(Equal(2))
+		Expect(s.Usage()["b"].Requests).To(Equal(2))
+	})
+
+	It("preempts the low priority requests not answered yet for the high priority ones", func() {
+		low, err := s.Acquire(ctx, workers, "batch", PriorityLow)
+		Expect(err).ToNot(HaveOccurred())
+		advance(10 * time.Second)
+
+		high, err := s.Acquire(ctx, workers, "frontend", PriorityHigh)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(low.Preempted()).To(BeClosed())
+		Expect(low.Start()).To(BeFalse())
+		Expect(high.Worker()).To(Equal("worker"))
+		Expect(s.Usage()["batch"].Preempted).To(Equal(1))
+		Expect(s.Usage()["batch"].Busy).To(Equal(10 * time.Second))
+
+		// the preempted request waits for the slot again
+		requeued := make(chan error, 1)
+		go func() { requeued <- low.Requeue(ctx, workers) }()
+		Consistently(requeued, 100*time.Millisecond).ShouldNot(Receive())
+		high.Release()
+		Eventually(requeued).Should(Receive(BeNil()))
+
+		// the started requests are not preempted
+		Expect(low.Start()).To(BeTrue())
+		waiting := acquire("frontend", PriorityHigh)
+		Consistently(low.Preempted(), 100*time.Millisecond).ShouldNot(BeClosed())
+		low.Release()
+		Eventually(waiting).Should(Receive())
+	})
+
+	It("drops the accounting of the idle origins", func() {
+		idle, err := s.Acquire(ctx, workers, "idle", PriorityNormal)
+		Expect(err).ToNot(HaveOccurred())
+		idle.Release()
+		long, err := s.Acquire(ctx, workers, "long", PriorityNormal)
+		Expect(err).ToNot(HaveOccurred())
+
+		advance(originRetention + time.Minute)
+		waiting := acquire("new", PriorityNormal)
+		Expect(s.Usage()).To(HaveKey("long"))
+		Expect(s.Usage()).To(HaveKey("new"))
+		Expect(s.Usage()).ToNot(HaveKey("idle"))
+
+		long.Release()
+		Eventually(waiting).Should(Receive())
+	})
+
+	It("fails without workers", func() {
+		_, err := s.Acquire(ctx, nil, "a", PriorityNormal)
+		Expect(err).To(MatchError(ErrNoWorkers))
+	})
+
+	It("parses the priorities", func() {
+		Expect(ParsePriority("")).To(Equal(PriorityNormal))
+		Expect(ParsePriority("HIGH")).To(Equal(PriorityHigh))
+		Expect(ParsePriority(" low ")).To(Equal(PriorityLow))
+		_, err := ParsePriority("urgent")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -4,10 +4,16 @@
 package p2p
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/netip"
 	"time"
 
 	"math/rand/v2"
@@ -15,9 +21,11 @@ import (
 	"github.com/mudler/edgevpn/pkg/node"
 	"github.com/mudler/edgevpn/pkg/protocol"
 	"github.com/mudler/edgevpn/pkg/types"
-	"github.com/rs/zerolog/log"
 )
 
+// maxReplayedBody is the size of the largest body of the requests which can be preempted
+const maxReplayedBody = 32 << 20
+
 func (f *FederatedServer) Start(ctx context.Context) error {
 
 	n, err := NewNode(f.p2ptoken)
@@ -30,7 +38,7 @@ func (f *FederatedServer) Start(ctx context.Context) error {
 	}
 
 	if err := ServiceDiscoverer(ctx, n, f.p2ptoken, f.service, func(servicesID string, tunnel NodeData) {
-		log.Debug().Msgf("Discovered node: %s", tunnel.ID)
+		zlog.Debug().Msgf("Discovered node: %s", tunnel.ID)
 	}); err != nil {
 		return err
 	}
@@ -40,11 +48,11 @@ func (f *FederatedServer) Start(ctx context.Context) error {
 
 func (fs *FederatedServer) proxy(ctx context.Context, node *node.Node) error {
 
-	log.Info().Msgf("Allocating service '%s' on: %s", fs.service, fs.listenAddr)
+	zlog.Info().Msgf("Allocating service '%s' on: %s", fs.service, fs.listenAddr)
 	// Open local port for listening
 	l, err := net.Listen("tcp", fs.listenAddr)
 	if err != nil {
-		log.Error().Err(err).Msg("Error listening")
+		zlog.Error().Err(err).Msg("Error listening")
 		return err
 	}
 	//	ll.Info("Binding local port on", srcaddr)
@@ -76,7 +84,7 @@ func (fs *FederatedServer) proxy(ctx context.Context, node *node.Node) error {
 		case <-ctx.Done():
 			return errors.New("context canceled")
 		default:
-			log.Debug().Msg("New for connection")
+			zlog.Debug().Msg("New for connection")
 			// Listen for an incoming connection.
 			conn, err := l.Accept()
 			if err != nil {
@@ -84,19 +92,21 @@ func (fs *FederatedServer) proxy(ctx context.Context, node *node.Node) error {
 				continue
 			}
 
+			if !fs.allowed(conn) {
+				conn.Close()
+				continue
+			}
+
 			// Handle connections in a new goroutine, forwarding to the p2p service
 			go func() {
-				var tunnelAddresses []string
-				for _, v := range GetAvailableNodes(fs.service) {
-					if v.IsOnline() {
-						tunnelAddresses = append(tunnelAddresses, v.TunnelAddress)
-					} else {
-						log.Info().Msgf("Node %s is offline", v.ID)
-					}
+				if fs.scheduler != nil {
+					fs.schedule(ctx, conn)
+					return
 				}
 
+				tunnelAddresses := fs.onlineWorkers()
 				if len(tunnelAddresses) == 0 {
-					log.Error().Msg("No available nodes yet")
+					zlog.Error().Msg("No available nodes yet")
 					return
 				}
 
@@ -108,7 +118,7 @@ func (fs *FederatedServer) proxy(ctx context.Context, node *node.Node) error {
 					}
 
 					tunnelAddr = fs.SelectLeastUsedServer()
-					log.Debug().Msgf("Selected tunnel %s", tunnelAddr)
+					zlog.Debug().Msgf("Selected tunnel %s", tunnelAddr)
 					if tunnelAddr == "" {
 						tunnelAddr = tunnelAddresses[rand.IntN(len(tunnelAddresses))]
 					}
@@ -120,11 +130,11 @@ func (fs *FederatedServer) proxy(ctx context.Context, node *node.Node) error {
 
 				tunnelConn, err := net.Dial("tcp", tunnelAddr)
 				if err != nil {
-					log.Error().Err(err).Msg("Error connecting to tunnel")
+					zlog.Error().Err(err).Msg("Error connecting to tunnel")
 					return
 				}
 
-				log.Info().Msgf("Redirecting %s to %s", conn.LocalAddr().String(), tunnelConn.RemoteAddr().String())
+				zlog.Info().Msgf("Redirecting %s to %s", conn.LocalAddr().String(), tunnelConn.RemoteAddr().String())
 				closer := make(chan struct{}, 2)
 				go copyStream(closer, tunnelConn, conn)
 				go copyStream(closer, conn, tunnelConn)
@@ -138,3 +148,142 @@ func (fs *FederatedServer) proxy(ctx context.Context, node *node.Node) error {
 	}
 
 }
+
+// onlineWorkers returns the tunnel addresses of the workers online
+func (fs *FederatedServer) onlineWorkers() []string {
+	var tunnelAddresses []string
+	for _, v := range GetAvailableNodes(fs.service) {
+		if v.IsOnline() {
+			tunnelAddresses = append(tunnelAddresses, v.TunnelAddress)
+		} else {
+			zlog.Info().Msgf("Node %s is offline", v.ID)
+		}
+	}
+	return tunnelAddresses
+}
+
+// schedule forwards the request of the connection once the scheduler gives it a slot of a worker, and
+// requeues it when it is preempted
+func (fs *FederatedServer) schedule(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	client := bufio.NewReader(conn)
+	req, err := http.ReadRequest(client)
+	if err != nil {
+		zlog.Debug().Err(err).Msg("Error reading the federated request")
+		return
+	}
+	priority, err := ParsePriority(req.Header.Get(PriorityHeader))
+	if err != nil {
+		writeFederatedError(conn, http.StatusBadRequest, err.Error())
+		return
+	}
+	// The origin is the client of the connection: the headers are set by the clients, and can not be trusted
+	remote, _ := netip.ParseAddrPort(conn.RemoteAddr().String())
+	origin := remote.Addr().Unmap().String()
+	if priority == PriorityHigh && !fs.trusted(remote.Addr()) {
+		zlog.Debug().Msgf("High priority request of %s scheduled as a normal one", origin)
+		priority = PriorityNormal
+	}
+	// One request is sent by connection, so that each request is scheduled
+	req.Close = true
+
+	// The body is kept to send the request again when it is preempted. The requests with a larger body,
+	// as the uploads, are not preempted
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxReplayedBody+1))
+	if err != nil {
+		writeFederatedError(conn, http.StatusBadRequest, err.Error())
+		return
+	}
+	replayable := len(body) <= maxReplayedBody
+	if !replayable {
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	}
+
+	slot, err := fs.scheduler.Acquire(ctx, fs.onlineWorkers(), origin, priority)
+	for {
+		if err != nil {
+			writeFederatedError(conn, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if replayable {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		} else {
+			slot.Start()
+		}
+		if !fs.forward(conn, client, req, slot) {
+			slot.Release()
+			return
+		}
+		zlog.Info().Msgf("Request of %s preempted on %s, requeued", origin, slot.Worker())
+		err = slot.Requeue(ctx, fs.onlineWorkers())
+	}
+}
+
+// forward sends the request to the worker of the slot, and copies the response to the client. It returns
+// true if the request was preempted before the worker started answering
+func (fs *FederatedServer) forward(conn net.Conn, client *bufio.Reader, req *http.Request, slot *FederatedSlot) bool {
+	tunnelConn, err := net.Dial("tcp", slot.Worker())
+	if err != nil {
+		zlog.Error().Err(err).Msg("Error connecting to tunnel")
+		writeFederatedError(conn, http.StatusBadGateway, err.Error())
+		return false
+	}
+	// closing the connection cancels the request on the worker
+	defer tunnelConn.Close()
+
+	if err := req.Write(tunnelConn); err != nil {
+		writeFederatedError(conn, http.StatusBadGateway, err.Error())
+		return false
+	}
+
+	// The first byte of the response tells that the worker started answering
+	worker := bufio.NewReader(tunnelConn)
+	answered := make(chan error, 1)
+	go func() {
+		_, err := worker.Peek(1)
+		answered <- err
+	}()
+	select {
+	case <-slot.Preempted():
+		return true
+	case err := <-answered:
+		if err != nil {
+			writeFederatedError(conn, http.StatusBadGateway, err.Error())
+			return false
+		}
+	}
+	if !slot.Start() {
+		return true
+	}
+
+	zlog.Info().Msgf("Redirecting %s to %s", conn.LocalAddr().String(), tunnelConn.RemoteAddr().String())
+	closer := make(chan struct{}, 2)
+	go copyStream(closer, tunnelConn, client)
+	go copyStream(closer, conn, worker)
+	<-closer
+	return false
+}
+
+func writeFederatedError(conn net.Conn, status int, message string) {
+	body, _ := json.Marshal(map[string]any{"error": map[string]any{"code": status, "message": message}})
+	fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nContent-Type: application/json\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", status, http.StatusText(status), len(body), body)
+}
+
+// trusted returns whether the client can send high priority requests
+func (fs *FederatedServer) trusted(addr netip.Addr) bool {
+	return fs.priorityClients.Enabled() && fs.priorityClients.Allowed(addr)
+}
+
+// allowed returns whether the client of conn is allowed by the filter of the server
+func (fs *FederatedServer) allowed(conn net.Conn) bool {
+	if !fs.filter.Enabled() {
+		return true
+	}
+	addr, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err == nil && fs.filter.Allowed(addr.Addr()) {
+		return true
+	}
+	zlog.Warn().Msgf("Connection of %s denied", conn.RemoteAddr().String())
+	return false
+}
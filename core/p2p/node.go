@@ -15,6 +15,8 @@ type NodeData struct {
 	ID            string
 	TunnelAddress string
 	LastSeen      time.Time
+	// Models are the models the node serves, advertised by the federated instances
+	Models []string
 }
 
 func (d NodeData) IsOnline() bool {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ipfs/go-log"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/mudler/LocalAI/pkg/logging"
 	"github.com/mudler/LocalAI/pkg/utils"
 	"github.com/mudler/edgevpn/pkg/config"
 	"github.com/mudler/edgevpn/pkg/node"
@@ -22,11 +23,12 @@ import (
 	"github.com/mudler/edgevpn/pkg/services"
 	"github.com/mudler/edgevpn/pkg/types"
 	"github.com/phayes/freeport"
-	zlog "github.com/rs/zerolog/log"
 
 	"github.com/mudler/edgevpn/pkg/logger"
 )
 
+var zlog = logging.Logger(logging.P2P)
+
 func GenerateToken() string {
 	// Generates a new config and exit
 	newData := node.GenerateNewConnectionData(900)
@@ -271,8 +273,9 @@ func ensureService(ctx context.Context, n *node.Node, nd *NodeData, sserv string
 	}
 }
 
-// This is the P2P worker main
-func ExposeService(ctx context.Context, host, port, token, servicesID string) error {
+// This is the P2P worker main. models returns the models served by the node, advertised to the network, and
+// is nil for the nodes which do not serve models, as the llama.cpp workers
+func ExposeService(ctx context.Context, host, port, token, servicesID string, models func() []string) error {
 	if servicesID == "" {
 		servicesID = defaultServicesID
 	}
@@ -312,11 +315,15 @@ func ExposeService(ctx context.Context, host, port, token, servicesID string) er
 			// If mismatch, update the blockchain
 			//if !found {
 			updatedMap := map[string]interface{}{}
-			updatedMap[name] = &NodeData{
+			nd := &NodeData{
 				Name:     name,
 				LastSeen: time.Now(),
 				ID:       nodeID(name),
 			}
+			if models != nil {
+				nd.Models = models()
+			}
+			updatedMap[name] = nd
 			ledger.Add(servicesID, updatedMap)
 			//	}
 		},
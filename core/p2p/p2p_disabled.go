@@ -22,7 +22,7 @@ func ServiceDiscoverer(ctx context.Context, node *node.Node, token, servicesID s
 	return fmt.Errorf("not implemented")
 }
 
-func ExposeService(ctx context.Context, host, port, token, servicesID string) error {
+func ExposeService(ctx context.Context, host, port, token, servicesID string, models func() []string) error {
 	return fmt.Errorf("not implemented")
 }
 
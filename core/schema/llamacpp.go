@@ -0,0 +1,58 @@
+package schema
+
+// LlamaCPPCompletionRequest is the payload of the /completion and /infill endpoints of the llama.cpp server
+type LlamaCPPCompletionRequest struct {
+	Model string `json:"model"`
+	// Prompt is a string or a list of strings. In infill requests it is the beginning of the completion
+	Prompt interface{} `json:"prompt"`
+
+	// Infill only
+	InputPrefix string                `json:"input_prefix"`
+	InputSuffix string                `json:"input_suffix"`
+	InputExtra  []LlamaCPPInfillChunk `json:"input_extra"`
+
+	NPredict         *int     `json:"n_predict"`
+	Temperature      *float64 `json:"temperature"`
+	TopK             *int     `json:"top_k"`
+	TopP             *float64 `json:"top_p"`
+	TypicalP         *float64 `json:"typical_p"`
+	TFZ              *float64 `json:"tfs_z"`
+	RepeatPenalty    float64  `json:"repeat_penalty"`
+	RepeatLastN      int      `json:"repeat_last_n"`
+	FrequencyPenalty float64  `json:"frequency_penalty"`
+	PresencePenalty  float64  `json:"presence_penalty"`
+	Keep             int      `json:"n_keep"`
+	Seed             *int     `json:"seed"`
+	IgnoreEOS        bool     `json:"ignore_eos"`
+	Stop             []string `json:"stop"`
+	Grammar          string   `json:"grammar"`
+	Stream           bool     `json:"stream"`
+}
+
+// LlamaCPPInfillChunk is an extra context of the infill requests, as the other files of the project
+type LlamaCPPInfillChunk struct {
+	Filename string `json:"filename"`
+	Text     string `json:"text"`
+}
+
+// LlamaCPPCompletionResponse is the result of the /completion and /infill endpoints. Streamed
+// responses send one for each token, the last one with Stop set and the statistics
+type LlamaCPPCompletionResponse struct {
+	Content         string           `json:"content"`
+	Stop            bool             `json:"stop"`
+	Model           string           `json:"model,omitempty"`
+	TokensPredicted int              `json:"tokens_predicted,omitempty"`
+	TokensEvaluated int              `json:"tokens_evaluated,omitempty"`
+	StoppedLimit    bool             `json:"stopped_limit,omitempty"`
+	Timings         *LlamaCPPTimings `json:"timings,omitempty"`
+}
+
+// LlamaCPPTimings holds the timings of a generation, in milliseconds
+type LlamaCPPTimings struct {
+	PromptN            int     `json:"prompt_n"`
+	PromptMS           float64 `json:"prompt_ms"`
+	PromptPerSecond    float64 `json:"prompt_per_second"`
+	PredictedN         int     `json:"predicted_n"`
+	PredictedMS        float64 `json:"predicted_ms"`
+	PredictedPerSecond float64 `json:"predicted_per_second"`
+}
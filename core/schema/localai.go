@@ -1,7 +1,10 @@
 package schema
 
 import (
+	"time"
+
 	"github.com/mudler/LocalAI/core/p2p"
+	"github.com/mudler/LocalAI/pkg/provenance"
 	gopsutil "github.com/shirou/gopsutil/v3/process"
 )
 
@@ -20,6 +23,32 @@ type GalleryResponse struct {
 	StatusURL string `json:"status"`
 }
 
+// @Description Model of the galleries, with the files downloaded by its install
+type GalleryModelDetails struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	License     string   `json:"license,omitempty"`
+	URLs        []string `json:"urls,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Installed   bool     `json:"installed"`
+	// LicenseGated requires the license to be acknowledged with accept_license to install the model
+	LicenseGated bool `json:"license_gated,omitempty"`
+	// Size is the sum of the sizes of the files which are known, in bytes
+	Size  int64              `json:"size,omitempty"`
+	Files []GalleryModelFile `json:"files"`
+}
+
+type GalleryModelFile struct {
+	Filename string `json:"filename"`
+	URI      string `json:"uri"`
+	// Size is in bytes, unknown when the server of the file does not report it
+	Size int64 `json:"size,omitempty"`
+	// Quantization is read from the name of the file, e.g. Q4_K_M
+	Quantization string `json:"quantization,omitempty"`
+}
+
 // @Description TTS request body
 type TTSRequest struct {
 	Model    string `json:"model" yaml:"model"` // model name or full path
@@ -27,6 +56,275 @@ type TTSRequest struct {
 	Voice    string `json:"voice" yaml:"voice"` // voice audio file or speaker id
 	Backend  string `json:"backend" yaml:"backend"`
 	Language string `json:"language,omitempty" yaml:"language,omitempty"` // (optional) language to use with TTS model
+	// (optional) format of the generated audio: wav (default), mp3, opus, flac or pcm16
+	ResponseFormat string `json:"response_format,omitempty" yaml:"response_format,omitempty"`
+	SampleRate     int    `json:"sample_rate,omitempty" yaml:"sample_rate,omitempty"` // (optional) sample rate to resample the generated audio to
+}
+
+// @Description Sound generation request body
+type SoundGenerationRequest struct {
+	Model       string   `json:"model" form:"model" yaml:"model"`
+	Backend     string   `json:"backend" form:"backend" yaml:"backend"`
+	Text        string   `json:"text" form:"text" yaml:"text"`                                          // prompt describing the sound
+	Duration    *float32 `json:"duration,omitempty" form:"duration" yaml:"duration,omitempty"`          // (optional) length of the sound, in seconds
+	Temperature *float32 `json:"temperature,omitempty" form:"temperature" yaml:"temperature,omitempty"` // (optional) sampling temperature
+	DoSample    *bool    `json:"do_sample,omitempty" form:"do_sample" yaml:"do_sample,omitempty"`       // (optional) sample instead of greedy decoding
+	Audio       string   `json:"audio,omitempty" form:"-" yaml:"audio,omitempty"`                       // (optional) base64 encoded audio to condition the generation on
+}
+
+// @Description Image upscaling and background removal request body
+type ImageTransformRequest struct {
+	Model           string `json:"model" yaml:"model"`
+	Image           string `json:"image" yaml:"image"`                                           // URL or base64 encoded image
+	Scale           int    `json:"scale,omitempty" yaml:"scale,omitempty"`                       // (optional) upscaling factor, the one of the model by default
+	OnlyMask        bool   `json:"only_mask,omitempty" yaml:"only_mask,omitempty"`               // (optional) return the mask of the foreground instead of the image
+	BackgroundColor string `json:"background_color,omitempty" yaml:"background_color,omitempty"` // (optional) color replacing the background, as #rrggbb. Transparent by default
+	ResponseFormat  string `json:"response_format,omitempty" yaml:"response_format,omitempty"`   // (optional) url (default) or b64_json
+}
+
+// @Description Object detection request body
+type DetectionRequest struct {
+	Model     string   `json:"model" form:"model" yaml:"model"`
+	Image     string   `json:"image,omitempty" form:"image" yaml:"image,omitempty"`             // URL or base64 encoded image, unless uploaded as file
+	Threshold float32  `json:"threshold,omitempty" form:"threshold" yaml:"threshold,omitempty"` // (optional) minimum score of the detections
+	Labels    []string `json:"labels,omitempty" form:"labels" yaml:"labels,omitempty"`          // (optional) objects to detect, required by the open vocabulary models
+}
+
+// @Description Object found in the image, in the box with its top left corner at (x, y), in pixels
+type Detection struct {
+	Label string  `json:"label"`
+	Score float32 `json:"score"`
+	Box   Box     `json:"box"`
+}
+
+type Box struct {
+	X      float32 `json:"x"`
+	Y      float32 `json:"y"`
+	Width  float32 `json:"width"`
+	Height float32 `json:"height"`
+}
+
+type DetectionResponse struct {
+	Model      string      `json:"model"`
+	Detections []Detection `json:"detections"`
+}
+
+// @Description Image classification request body
+type ClassificationRequest struct {
+	Model  string   `json:"model" form:"model" yaml:"model"`
+	Image  string   `json:"image,omitempty" form:"image" yaml:"image,omitempty"`    // URL or base64 encoded image, unless uploaded as file
+	Labels []string `json:"labels,omitempty" form:"labels" yaml:"labels,omitempty"` // (optional) candidate labels, required by the zero-shot classifiers as CLIP
+	TopK   int      `json:"top_k,omitempty" form:"top_k" yaml:"top_k,omitempty"`    // (optional) number of labels returned
+}
+
+type Classification struct {
+	Label string  `json:"label"`
+	Score float32 `json:"score"`
+}
+
+type ClassificationResponse struct {
+	Model           string           `json:"model"`
+	Classifications []Classification `json:"classifications"`
+}
+
+// @Description Text classification request body
+type TextClassificationRequest struct {
+	Model  string   `json:"model" yaml:"model"`
+	Text   string   `json:"text" yaml:"text"`
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"` // (optional) candidate labels, required by the zero-shot classifiers
+	TopK   int      `json:"top_k,omitempty" yaml:"top_k,omitempty"`   // (optional) number of labels returned, all by default
+}
+
+// @Description Named entity recognition request body
+type EntitiesRequest struct {
+	Model string `json:"model" yaml:"model"`
+	Text  string `json:"text" yaml:"text"`
+}
+
+// @Description Named entity of the text, from the character start to end
+type Entity struct {
+	Label string  `json:"label"`
+	Text  string  `json:"text"`
+	Score float32 `json:"score"`
+	Start int     `json:"start"`
+	End   int     `json:"end"`
+}
+
+type EntitiesResponse struct {
+	Model    string   `json:"model"`
+	Entities []Entity `json:"entities"`
+}
+
+// @Description Translation request body
+type TranslationRequest struct {
+	Model  string `json:"model,omitempty" yaml:"model,omitempty"` // (optional) by default, the model translating the source to the target language
+	Text   string `json:"text" yaml:"text"`
+	Source string `json:"source,omitempty" yaml:"source,omitempty"` // (optional) detected when missing, with a language detection model
+	Target string `json:"target" yaml:"target"`
+}
+
+type TranslationResponse struct {
+	Model  string `json:"model"`
+	Text   string `json:"text"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// @Description Pipeline request body. The audio of the transcription steps is uploaded as file
+type PipelineRequest struct {
+	Input  string `json:"input" form:"input"`
+	Stream bool   `json:"stream,omitempty" form:"stream"` // (optional) streams the tokens of the output step, when it is a chat step
+	// (optional) format and sample rate of the audio of the pipelines whose output is a tts step
+	ResponseFormat string `json:"response_format,omitempty" form:"response_format"`
+	SampleRate     int    `json:"sample_rate,omitempty" form:"sample_rate"`
+}
+
+// PipelineResponse is the output of a pipeline, with the outputs of its steps by name. The streamed events
+// have the tokens of the output step as delta, and the last one its output and the outputs of the steps
+type PipelineResponse struct {
+	Pipeline string            `json:"pipeline"`
+	Output   string            `json:"output,omitempty"`
+	Steps    map[string]string `json:"steps,omitempty"`
+	Delta    string            `json:"delta,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type PipelineStep struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Model string   `json:"model"`
+	Needs []string `json:"needs,omitempty"`
+}
+
+type Pipeline struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Output      string         `json:"output"`
+	Steps       []PipelineStep `json:"steps"`
+}
+
+type PipelinesResponse struct {
+	Object string     `json:"object"`
+	Data   []Pipeline `json:"data"`
+}
+
+// @Description Language detection request body
+type LanguageDetectionRequest struct {
+	Model string `json:"model,omitempty" yaml:"model,omitempty"` // (optional) by default, the language detection model
+	Text  string `json:"text" yaml:"text"`
+	TopK  int    `json:"top_k,omitempty" yaml:"top_k,omitempty"` // (optional) number of languages returned, all by default
+}
+
+type DetectedLanguage struct {
+	Language string  `json:"language"`
+	Score    float32 `json:"score"`
+}
+
+type LanguageDetectionResponse struct {
+	Model     string             `json:"model"`
+	Language  string             `json:"language"`
+	Languages []DetectedLanguage `json:"languages"`
+}
+
+// @Description Similarity request body
+type SimilarityRequest struct {
+	Model  string   `json:"model" yaml:"model"`
+	Query  string   `json:"query" yaml:"query"`
+	Texts  []string `json:"texts" yaml:"texts"`
+	Method string   `json:"method,omitempty" yaml:"method,omitempty"` // (optional) cosine or cross-encoder, by default cosine for the embedding models
+}
+
+// @Description Similarity of a text to the query, higher is more similar
+type Similarity struct {
+	Index int     `json:"index"`
+	Text  string  `json:"text"`
+	Score float32 `json:"score"`
+}
+
+type SimilarityResponse struct {
+	Model        string       `json:"model"`
+	Method       string       `json:"method"`
+	Similarities []Similarity `json:"similarities"`
+}
+
+// @Description Retrieval augmented generation request body
+type RAGRequest struct {
+	PredictionOptions
+
+	Query     string `json:"query" yaml:"query"`
+	RetrieveK int    `json:"retrieve_k,omitempty" yaml:"retrieve_k,omitempty"` // (optional) documents retrieved from the store
+	TopN      int    `json:"top_n,omitempty" yaml:"top_n,omitempty"`           // (optional) documents kept in the prompt
+	Rerank    *bool  `json:"rerank,omitempty" yaml:"rerank,omitempty"`         // (optional) false skips the reranking of the documents
+}
+
+// @Description Document of the prompt of the answer, cited as [number] by the answer when cited is true
+type RAGCitation struct {
+	Number         int      `json:"number"`
+	Text           string   `json:"text"`
+	Source         string   `json:"source,omitempty"`
+	Similarity     float32  `json:"similarity"`
+	RelevanceScore *float32 `json:"relevance_score,omitempty"` // set when the documents are reranked
+	Cited          bool     `json:"cited"`
+}
+
+type RAGResponse struct {
+	ID        string        `json:"id"`
+	Created   int           `json:"created"`
+	Model     string        `json:"model"`
+	Answer    string        `json:"answer"`
+	Citations []RAGCitation `json:"citations"`
+	Usage     OpenAIUsage   `json:"usage"`
+}
+
+// @Description Agent request body: the model calls the built-in tools until it answers
+type AgentRequest struct {
+	PredictionOptions
+
+	Messages []Message `json:"messages" yaml:"messages"`
+	Tools    []string  `json:"tools,omitempty" yaml:"tools,omitempty"`         // (optional) built-in tools the model can call, all the available ones by default
+	MaxSteps int       `json:"max_steps,omitempty" yaml:"max_steps,omitempty"` // (optional) model calls before the answer is forced
+}
+
+// @Description Tool call of a step of an agent, with its result or its error
+type AgentToolCall struct {
+	Tool      string `json:"tool"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// @Description Model call of an agent, with the text and the tool calls it returned
+type AgentStep struct {
+	Step      int             `json:"step"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []AgentToolCall `json:"tool_calls"`
+}
+
+type AgentResponse struct {
+	ID      string `json:"id"`
+	Created int    `json:"created"`
+	Model   string `json:"model"`
+	Answer  string `json:"answer"`
+	// FinishReason is "stop", or "max_steps" when the answer was forced after the last step
+	FinishReason string      `json:"finish_reason"`
+	Steps        []AgentStep `json:"steps"`
+	Usage        OpenAIUsage `json:"usage"`
+}
+
+// @Description Voice available with a TTS model
+type Voice struct {
+	ID         string   `json:"id"` // to pass as voice in TTS requests
+	Name       string   `json:"name"`
+	Languages  []string `json:"languages,omitempty"`
+	Gender     string   `json:"gender,omitempty"`
+	SampleRate int      `json:"sample_rate,omitempty"`
+}
+
+type VoicesResponse struct {
+	Object  string  `json:"object"`
+	Model   string  `json:"model,omitempty"`
+	Backend string  `json:"backend,omitempty"`
+	Data    []Voice `json:"data"`
 }
 
 type StoresSet struct {
@@ -70,3 +368,281 @@ type P2PNodesResponse struct {
 	Nodes          []p2p.NodeData `json:"nodes" yaml:"nodes"`
 	FederatedNodes []p2p.NodeData `json:"federated_nodes" yaml:"federated_nodes"`
 }
+
+// @Description Errors found in the configurations of the models
+type ConfigValidationResponse struct {
+	Valid  bool                    `json:"valid"`
+	Errors []ConfigValidationError `json:"errors"`
+}
+
+type ConfigValidationError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// @Description Runtime settings of the instance, by their environment variables, and where each of them comes from
+type EffectiveConfigResponse struct {
+	Settings []EffectiveSetting `json:"settings"`
+}
+
+type EffectiveSetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Source is default, settings_file, env, flag or dynamic, from the lowest to the highest precedence
+	Source string `json:"source"`
+}
+
+// @Description Prompt of a chat completion request, rendered with the templates of the model
+type TemplateDebugResponse struct {
+	Model string `json:"model"`
+	// The final prompt sent to the model. It is empty when the backend renders the
+	// messages itself, with the template of the tokenizer
+	Prompt               string `json:"prompt"`
+	UseTokenizerTemplate bool   `json:"use_tokenizer_template"`
+	// True when the prompt is rendered with the template for functions
+	Functions bool `json:"functions"`
+	// Number of tokens of the prompt, if the backend of the model can tokenize it
+	Tokens        *int   `json:"tokens,omitempty"`
+	TokenizeError string `json:"tokenize_error,omitempty"`
+}
+
+// @Description Chat completion request run with several models, to compare their outputs
+type CompareRequest struct {
+	OpenAIRequest
+	// Models running the request. A prompt can be sent in place of the messages
+	Models []string `json:"models"`
+}
+
+// @Description Outputs of the models of a compare request, in the order of the request
+type CompareResponse struct {
+	Created int             `json:"created"`
+	Results []CompareResult `json:"results"`
+}
+
+type CompareResult struct {
+	Model  string      `json:"model"`
+	Output string      `json:"output"`
+	Error  string      `json:"error,omitempty"`
+	Usage  OpenAIUsage `json:"usage"`
+	Timing Timing      `json:"timing"`
+	// Time to answer, including the loading of the model, in milliseconds
+	Duration float64 `json:"duration_ms"`
+}
+
+// @Description Tokens used today (UTC) by the API key of the request and by its tenant
+type UsageResponse struct {
+	Tenant string `json:"tenant,omitempty"`
+	// Tokens used by the API key, and its daily limit (0 when unlimited)
+	Tokens      int `json:"tokens"`
+	DailyTokens int `json:"daily_tokens"`
+	// Tokens used by all the API keys of the tenant, and the daily limit of the tenant
+	TenantTokens      int `json:"tenant_tokens,omitempty"`
+	TenantDailyTokens int `json:"tenant_daily_tokens,omitempty"`
+}
+
+// @Description Progress of the download and of the loading of a model
+type ModelStatusResponse struct {
+	Model string `json:"model"`
+	// State is "not_loaded", "downloading", "downloaded", "loading", "loaded" or "failed"
+	State string `json:"state"`
+	Stage string `json:"stage,omitempty"`
+	// Progress is the percentage of each stage reported: download, mmap and warmup, or load
+	// for the backends which do not report their stages
+	Progress map[string]float64 `json:"progress,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	// WarmupDuration is the seconds taken by the warmup request run after loading the model
+	WarmupDuration float64    `json:"warmup_duration,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// @Description Request to swap the model file of a model, loading the new version before unloading the previous one
+type ModelSwapRequest struct {
+	// Model is the file of the new version of the model, in the models path
+	Model string `json:"model"`
+}
+
+// @Description Configuration of a model created, updated or deleted by the API
+type ModelConfigResponse struct {
+	Model string `json:"model"`
+	// File is the YAML file of the configuration, in the models path
+	File string `json:"file"`
+	// Unloaded tells if the model was running, and was unloaded to use the new configuration
+	Unloaded bool `json:"unloaded"`
+}
+
+type ModelSwapResponse struct {
+	Model    string `json:"model"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+	// Unloaded tells if the previous version was unloaded. It stays loaded while other models use its file
+	Unloaded bool `json:"unloaded"`
+}
+
+// @Description Backends and models of the instance, with the circuits of the models which failed
+type SystemInformationResponse struct {
+	Backends     []string       `json:"backends"`
+	LoadedModels []string       `json:"loaded_models"`
+	Circuits     []CircuitState `json:"circuits"`
+	// ModelStats are the statistics of the models loaded since the start
+	ModelStats []ModelStats `json:"model_stats"`
+}
+
+// @Description Statistics of the loader for a model, kept after the model is unloaded
+type ModelStats struct {
+	Model  string `json:"model"`
+	Loaded bool   `json:"loaded"`
+	// LoadedAt is the time of the last load of the model, and LoadDuration the seconds it took
+	LoadedAt     *time.Time `json:"loaded_at,omitempty"`
+	LoadDuration float64    `json:"load_duration"`
+	LastUsed     *time.Time `json:"last_used,omitempty"`
+	Loads        int64      `json:"loads"`
+	Evictions    int64      `json:"evictions"`
+	// WatchDogKills counts the backends of the model stopped by the watchdog, by reason: busy, idle or ttl
+	WatchDogKills map[string]int64 `json:"watchdog_kills,omitempty"`
+}
+
+// @Description Circuit of a model: its requests fail fast with a 503 error while it is not closed
+type CircuitState struct {
+	Model string `json:"model"`
+	// State is "closed", "open" or "half_open" while the model is probed
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	// OpenedAt is the time the circuit opened, and RetryAt the time of the next probe of the model
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+// Statuses of the jobs
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// @Description Long-running operation run in the background, as an image generation or a gallery install
+type Job struct {
+	ID     string `json:"id"`
+	Object string `json:"object"`
+	// Type is "image_generation", "transcription", "translation", "task" or "gallery"
+	Type string `json:"type"`
+	// Status is "running", "completed", "failed" or "cancelled"
+	Status   string  `json:"status"`
+	Progress float64 `json:"progress"`
+	Message  string  `json:"message,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	// CreatedAt and FinishedAt are Unix timestamps, unknown for the gallery jobs
+	CreatedAt  int64 `json:"created_at,omitempty"`
+	FinishedAt int64 `json:"finished_at,omitempty"`
+	// ResultURL returns the response of the request of the completed jobs
+	ResultURL string `json:"result_url,omitempty"`
+}
+
+// @Description Prompt or pipeline run at the times of a cron expression, whose output is posted to a webhook
+// @Description or written to a file
+type ScheduledTask struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Model    string `json:"model,omitempty"`
+	System   string `json:"system,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	Pipeline string `json:"pipeline,omitempty"`
+	Input    string `json:"input,omitempty"`
+	Webhook  string `json:"webhook,omitempty"`
+	File     string `json:"file,omitempty"`
+	// Source is "file" for the tasks of the tasks file, which cannot be deleted, or "api"
+	Source string `json:"source,omitempty"`
+	// NextRun is a Unix timestamp
+	NextRun int64 `json:"next_run,omitempty"`
+	// LastJob is the job of the last run of the task, while it is kept
+	LastJob *Job `json:"last_job,omitempty"`
+}
+
+type ScheduledTaskList struct {
+	Object string          `json:"object"`
+	Data   []ScheduledTask `json:"data"`
+}
+
+// @Description Output of a run of a scheduled task, the result of its job
+type TaskResult struct {
+	Task   string `json:"task"`
+	Output string `json:"output"`
+	// File is the path of the file the output was written to
+	File string `json:"file,omitempty"`
+	// StartedAt and FinishedAt are Unix timestamps
+	StartedAt  int64 `json:"started_at"`
+	FinishedAt int64 `json:"finished_at"`
+}
+
+// @Description Chat or completion request being generated, cancellable by its ID
+type Generation struct {
+	// ID is the ID of the request, returned in the X-Request-ID header
+	ID     string `json:"id"`
+	Object string `json:"object"`
+	Model  string `json:"model,omitempty"`
+	// CreatedAt is a Unix timestamp
+	CreatedAt int64 `json:"created_at"`
+}
+
+type GenerationList struct {
+	Object string       `json:"object"`
+	Data   []Generation `json:"data"`
+}
+
+// @Description State of the instance shown in the dashboard: the models loaded, the requests running and
+// @Description waiting for a slot, the memory of the GPUs and the last errors
+type DashboardResponse struct {
+	Timestamp   time.Time        `json:"timestamp"`
+	Models      []DashboardModel `json:"models"`
+	Generations []Generation     `json:"generations"`
+	// InFlight counts the requests running on the backends, and Queued the ones waiting for a slot
+	InFlight int            `json:"in_flight"`
+	Queued   int            `json:"queued"`
+	GPUs     []DashboardGPU `json:"gpus"`
+	Errors   []RecentError  `json:"errors"`
+}
+
+// @Description Loaded model of the dashboard, with the use of the slots of its backend
+type DashboardModel struct {
+	Name     string     `json:"name"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+	Slots    int        `json:"slots"`
+	Busy     int        `json:"busy"`
+	Waiting  int        `json:"waiting"`
+}
+
+// @Description Memory of a GPU, in bytes
+type DashboardGPU struct {
+	Index int    `json:"index"`
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+}
+
+// @Description Error returned by the API
+type RecentError struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Message   string    `json:"message"`
+}
+
+// @Description Image generated by the instance, with the parameters of its generation
+type GeneratedImage struct {
+	Name       string                `json:"name"`
+	URL        string                `json:"url"`
+	CreatedAt  time.Time             `json:"created_at"`
+	Parameters provenance.Parameters `json:"parameters"`
+}
+
+type GeneratedImageList struct {
+	Object string           `json:"object"`
+	Data   []GeneratedImage `json:"data"`
+}
+
+type JobList struct {
+	Object string `json:"object"`
+	Data   []Job  `json:"data"`
+}
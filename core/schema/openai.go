@@ -25,6 +25,24 @@ type OpenAIUsage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// Timing of a generation, in milliseconds. Sent to the clients opting in
+// with the X-LocalAI-Timing header
+type Timing struct {
+	TimeToFirstToken float64 `json:"time_to_first_token_ms"`
+	TokensPerSecond  float64 `json:"tokens_per_second"`
+	PromptProcessing float64 `json:"prompt_eval_ms"`
+	TokenGeneration  float64 `json:"token_generation_ms"`
+	QueueWait        float64 `json:"queue_wait_ms"`
+}
+
+// Progress of the evaluation of the prompt, before the first token: the prompt tokens evaluated,
+// the cached ones included, out of the prompt tokens. Sent to the clients opting in with the
+// X-LocalAI-Progress header
+type PromptProgress struct {
+	NPast   int `json:"n_past"`
+	NPrompt int `json:"n_prompt"`
+}
+
 type Item struct {
 	Embedding []float32 `json:"embedding"`
 	Index     int       `json:"index"`
@@ -35,6 +53,41 @@ type Item struct {
 	B64JSON string `json:"b64_json,omitempty"`
 }
 
+// EmbeddingInput is an item of the input of the embeddings, as {"type": "image", "image": "https://..."}.
+// The image is an URL or base64 encoded
+type EmbeddingInput struct {
+	Type  string `json:"type,omitempty"`
+	Text  string `json:"text,omitempty"`
+	Image string `json:"image,omitempty"`
+}
+
+// Types of the events streamed by the image generation endpoint
+const (
+	ImageGenerationProgress     = "image_generation.progress"
+	ImageGenerationPartialImage = "image_generation.partial_image"
+	ImageGenerationCompleted    = "image_generation.completed"
+	ImageGenerationError        = "error"
+)
+
+// ImageGenerationEvent is streamed by the image generation endpoint when stream is set: the
+// steps of the generation of each image, their previews, and the images once generated
+type ImageGenerationEvent struct {
+	Type       string `json:"type"`
+	ImageIndex int    `json:"image_index"`
+
+	// Progress
+	Step  int `json:"step,omitempty"`
+	Steps int `json:"steps,omitempty"`
+
+	// Partial images are low resolution previews, as PNG
+	PartialImageIndex *int `json:"partial_image_index,omitempty"`
+
+	URL     string `json:"url,omitempty"`
+	B64JSON string `json:"b64_json,omitempty"`
+
+	Error *APIError `json:"error,omitempty"`
+}
+
 type OpenAIResponse struct {
 	Created int      `json:"created,omitempty"`
 	Object  string   `json:"object,omitempty"`
@@ -44,6 +97,9 @@ type OpenAIResponse struct {
 	Data    []Item   `json:"data,omitempty"`
 
 	Usage OpenAIUsage `json:"usage"`
+
+	// Progress of the prompt evaluation, streamed as a separate event instead of a chunk
+	Progress *PromptProgress `json:"-"`
 }
 
 type Choice struct {
@@ -98,6 +154,23 @@ type FunctionCall struct {
 type OpenAIModel struct {
 	ID     string `json:"id"`
 	Object string `json:"object"`
+	// ModelDetails are only set with ?detailed=true, to keep the OpenAI shape by default
+	*ModelDetails
+}
+
+// @Description Metadata of a model, listed by /v1/models?detailed=true
+type ModelDetails struct {
+	Backend string `json:"backend,omitempty"`
+	// Configured is false for the files of the models path without a configuration
+	Configured   bool   `json:"configured"`
+	Loaded       bool   `json:"loaded"`
+	ContextSize  int    `json:"context_size,omitempty"`
+	Quantization string `json:"quantization,omitempty"`
+	// Size of the model file in bytes
+	Size         int64    `json:"size,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Gallery the model was installed from
+	Gallery string `json:"gallery,omitempty"`
 }
 
 type DeleteAssistantResponse struct {
@@ -108,12 +181,13 @@ type DeleteAssistantResponse struct {
 
 // File represents the structure of a file object from the OpenAI API.
 type File struct {
-	ID        string    `json:"id"`         // Unique identifier for the file
-	Object    string    `json:"object"`     // Type of the object (e.g., "file")
-	Bytes     int       `json:"bytes"`      // Size of the file in bytes
-	CreatedAt time.Time `json:"created_at"` // The time at which the file was created
-	Filename  string    `json:"filename"`   // The name of the file
-	Purpose   string    `json:"purpose"`    // The purpose of the file (e.g., "fine-tune", "classifications", etc.)
+	ID        string     `json:"id"`                   // Unique identifier for the file
+	Object    string     `json:"object"`               // Type of the object (e.g., "file")
+	Bytes     int        `json:"bytes"`                // Size of the file in bytes
+	CreatedAt time.Time  `json:"created_at"`           // The time at which the file was created
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // The time at which the file is deleted, if it expires
+	Filename  string     `json:"filename"`             // The name of the file
+	Purpose   string     `json:"purpose"`              // The purpose of the file (e.g., "fine-tune", "classifications", etc.)
 }
 
 type ListFiles struct {
@@ -121,6 +195,24 @@ type ListFiles struct {
 	Object string
 }
 
+// FineTuningRequest fine-tunes a model with an uploaded file, as https://platform.openai.com/docs/api-reference/fine-tuning/create
+type FineTuningRequest struct {
+	Model        string `json:"model"`
+	TrainingFile string `json:"training_file"`
+	// Suffix is added to the name of the model to name the fine-tuned model
+	Suffix string `json:"suffix,omitempty"`
+	// Hyperparameters are passed as-is, as JSON, to the fine-tuning command
+	Hyperparameters map[string]interface{} `json:"hyperparameters,omitempty"`
+}
+
+// FineTuningResult is the result of a completed fine-tuning job
+type FineTuningResult struct {
+	Object         string `json:"object"`
+	Model          string `json:"model"`
+	FineTunedModel string `json:"fine_tuned_model"`
+	TrainingFile   string `json:"training_file"`
+}
+
 type AssistantFileRequest struct {
 	FileID string `json:"file_id"`
 }
@@ -164,6 +256,10 @@ type OpenAIRequest struct {
 	Size string `json:"size"`
 	// Prompt is read only by completion/image API calls
 	Prompt interface{} `json:"prompt" yaml:"prompt"`
+	// Suffix is the text after the completion of the fill-in-the-middle requests, as the code after the cursor
+	Suffix string `json:"suffix,omitempty" yaml:"suffix,omitempty"`
+	// Prefix is the text before the completion of the fill-in-the-middle requests, in place of the prompt
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
 
 	// Edit endpoint
 	Instruction string      `json:"instruction" yaml:"instruction"`
@@ -183,9 +279,37 @@ type OpenAIRequest struct {
 
 	Stream bool `json:"stream"`
 
+	// Named generation preset of the model or of the presets file (not supported by OpenAI)
+	Preset string `json:"preset" yaml:"preset"`
+
+	// End user of the request, as in the OpenAI API
+	User string `json:"user,omitempty"`
+	// Conversation of the request, routed to the same slot of the backend as its previous requests to reuse
+	// the KV cache. Defaults to the user (not supported by OpenAI)
+	SessionID string `json:"session_id,omitempty"`
+
+	// Time the model stays loaded once idle, as "5m" or a number of seconds (Ollama style, not supported by OpenAI)
+	KeepAlive interface{} `json:"keep_alive,omitempty" yaml:"keep_alive"`
+
 	// Image (not supported by OpenAI)
 	Mode int `json:"mode"`
 	Step int `json:"step"`
+	// Previews of the images streamed while they are generated, when stream is set
+	PartialImages int `json:"partial_images"`
+	// Advanced parameters of the generation, overriding the ones of the model
+	CFGScale  float32 `json:"cfg_scale"`
+	Scheduler string  `json:"scheduler"`
+	ClipSkip  int     `json:"clip_skip"`
+	// Images generated by each call to the backend, out of the n images of each prompt
+	BatchCount int    `json:"batch_count"`
+	Upscaler   string `json:"upscaler"`
+	// Conditioning of the generation, with images as URLs or base64: a pose, edges or a depth map guiding a
+	// ControlNet allowed by the model, and an image prompt for the IP-Adapter of the model
+	ControlNet        string  `json:"controlnet"`
+	ConditioningImage string  `json:"conditioning_image"`
+	ControlNetScale   float32 `json:"controlnet_conditioning_scale"`
+	IPAdapterImage    string  `json:"ip_adapter_image"`
+	IPAdapterScale    float32 `json:"ip_adapter_scale"`
 
 	// A grammar to constrain the LLM output
 	Grammar string `json:"grammar" yaml:"grammar"`
@@ -198,6 +322,15 @@ type OpenAIRequest struct {
 	ModelBaseName string `json:"model_base_name" yaml:"model_base_name"`
 }
 
+// AffinityKey returns the key routing the request to the slot of the backend of its conversation, or of
+// its user
+func (r *OpenAIRequest) AffinityKey() string {
+	if r.SessionID != "" {
+		return r.SessionID
+	}
+	return r.User
+}
+
 type ModelsDataResponse struct {
 	Object string        `json:"object"`
 	Data   []OpenAIModel `json:"data"`
@@ -0,0 +1,75 @@
+package schema
+
+// ResponseRequest is the request body of the OpenAI Responses API
+// https://platform.openai.com/docs/api-reference/responses/create
+type ResponseRequest struct {
+	Model string `json:"model"`
+
+	// Input can be either a string or a list of input messages
+	Input        interface{} `json:"input"`
+	Instructions string      `json:"instructions,omitempty"`
+
+	// PreviousResponseID chains the request to a stored response, the conversation
+	// of the previous response is prepended to the input
+	PreviousResponseID string `json:"previous_response_id,omitempty"`
+
+	// Store defaults to true
+	Store  *bool `json:"store,omitempty"`
+	Stream bool  `json:"stream"`
+
+	Temperature     *float64          `json:"temperature,omitempty"`
+	TopP            *float64          `json:"top_p,omitempty"`
+	MaxOutputTokens *int              `json:"max_output_tokens,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+type Response struct {
+	ID                 string               `json:"id"`
+	Object             string               `json:"object"`
+	CreatedAt          int64                `json:"created_at"`
+	Status             string               `json:"status"`
+	Model              string               `json:"model"`
+	Instructions       string               `json:"instructions,omitempty"`
+	PreviousResponseID *string              `json:"previous_response_id"`
+	Output             []ResponseOutputItem `json:"output"`
+	OutputText         string               `json:"output_text"`
+	Usage              ResponseUsage        `json:"usage"`
+	Metadata           map[string]string    `json:"metadata,omitempty"`
+	Error              *APIError            `json:"error"`
+}
+
+type ResponseOutputItem struct {
+	Type    string                  `json:"type"`
+	ID      string                  `json:"id"`
+	Status  string                  `json:"status"`
+	Role    string                  `json:"role"`
+	Content []ResponseOutputContent `json:"content"`
+}
+
+type ResponseOutputContent struct {
+	Type        string        `json:"type"`
+	Text        string        `json:"text"`
+	Annotations []interface{} `json:"annotations"`
+}
+
+type ResponseUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// ResponseStreamEvent is sent as server-sent event when streaming a response
+type ResponseStreamEvent struct {
+	Type         string    `json:"type"`
+	Response     *Response `json:"response,omitempty"`
+	ItemID       string    `json:"item_id,omitempty"`
+	OutputIndex  int       `json:"output_index"`
+	ContentIndex int       `json:"content_index"`
+	Delta        string    `json:"delta,omitempty"`
+}
+
+type DeleteResponseResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
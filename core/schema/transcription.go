@@ -2,15 +2,40 @@ package schema
 
 import "time"
 
+type Word struct {
+	Word        string        `json:"word"`
+	Start       time.Duration `json:"start"`
+	End         time.Duration `json:"end"`
+	Probability float32       `json:"probability,omitempty"`
+}
+
 type Segment struct {
 	Id     int           `json:"id"`
 	Start  time.Duration `json:"start"`
 	End    time.Duration `json:"end"`
 	Text   string        `json:"text"`
 	Tokens []int         `json:"tokens"`
+	Words  []Word        `json:"words,omitempty"`
+	// Speaker label, only set when diarization is requested
+	Speaker string `json:"speaker,omitempty"`
 }
 
 type TranscriptionResult struct {
 	Segments []Segment `json:"segments"`
 	Text     string    `json:"text"`
+
+	// Language is the spoken language, as detected when it is not given in the request
+	Language            string  `json:"language,omitempty"`
+	LanguageProbability float32 `json:"language_probability,omitempty"`
+
+	// Words of all the segments, only set when word timestamps are requested
+	Words []Word `json:"words,omitempty"`
+}
+
+// TranscriptionStreamEvent is sent over the websocket of a live transcription
+type TranscriptionStreamEvent struct {
+	// Type is one of segment, error or done
+	Type    string   `json:"type"`
+	Segment *Segment `json:"segment,omitempty"`
+	Error   string   `json:"error,omitempty"`
 }
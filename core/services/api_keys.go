@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/config"
+)
+
+var (
+	ErrAuthorizationMissing = errors.New("authorization missing")
+	ErrInvalidAuthFormat    = errors.New("invalid authorization format")
+	ErrInvalidAPIKey        = errors.New("invalid API key")
+	ErrAPIKeyQuota          = errors.New("daily token quota of the API key exhausted")
+	ErrTenantRateLimit      = errors.New("rate limit of the tenant exceeded")
+	ErrTenantQuota          = errors.New("daily token quota of the tenant exhausted")
+)
+
+// APIKeys checks the API keys of the requests, for the HTTP and the gRPC APIs alike, and enforces the daily
+// token quotas and the rate limits of the keys and of their tenants. If no API key is set, no auth is required
+type APIKeys struct {
+	appConfig  *config.ApplicationConfig
+	quotas     *TokenQuotas
+	rateLimits *RateLimits
+}
+
+func NewAPIKeys(appConfig *config.ApplicationConfig) *APIKeys {
+	return &APIKeys{
+		appConfig:  appConfig,
+		quotas:     NewTokenQuotas(),
+		rateLimits: NewRateLimits(),
+	}
+}
+
+// Quotas returns the tokens used by the API keys and the tenants during the day
+func (k *APIKeys) Quotas() *TokenQuotas {
+	return k.quotas
+}
+
+// Required tells if the requests must be authenticated with an API key
+func (k *APIKeys) Required() bool {
+	return len(k.appConfig.ApiKeys) > 0 || len(k.appConfig.Tenants) > 0
+}
+
+// BearerToken returns the API key of an authorization header, as "Bearer <key>"
+func BearerToken(authorization string) (string, error) {
+	if authorization == "" {
+		return "", ErrAuthorizationMissing
+	}
+	scheme, apiKey, found := strings.Cut(authorization, " ")
+	if !found || scheme != "Bearer" || apiKey == "" || strings.Contains(apiKey, " ") {
+		return "", ErrInvalidAuthFormat
+	}
+	return apiKey, nil
+}
+
+// Authenticate checks the API key of a request, counting it in the rate limit of its tenant, and returns
+// the tenant of the key, or nil for the keys of the instance
+func (k *APIKeys) Authenticate(apiKey string) (*config.Tenant, error) {
+	tenant := k.appConfig.TenantByAPIKey(apiKey)
+	if tenant == nil && !slices.Contains(k.appConfig.ApiKeys, apiKey) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if k.quotas.Exhausted(config.APIKeyOwner(apiKey), k.appConfig.APIKeyDailyTokens) {
+		return nil, ErrAPIKeyQuota
+	}
+	if tenant != nil {
+		if !k.rateLimits.Allow(tenant.Name, tenant.RequestsPerMinute) {
+			return nil, ErrTenantRateLimit
+		}
+		if k.quotas.Exhausted(tenant.Owner(), tenant.DailyTokens) {
+			return nil, ErrTenantQuota
+		}
+	}
+	return tenant, nil
+}
+
+// RecordTokens counts the tokens used by a request in the quotas of its API key and of its tenant
+func (k *APIKeys) RecordTokens(apiKey string, tenant *config.Tenant, tokens int) {
+	k.quotas.Add(config.APIKeyOwner(apiKey), tokens)
+	if tenant != nil {
+		k.quotas.Add(tenant.Owner(), tokens)
+	}
+}
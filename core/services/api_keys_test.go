@@ -0,0 +1,72 @@
+package services_test
+
+import (
+	"github.com/mudler/LocalAI/core/config"
+	. "github.com/mudler/LocalAI/core/services"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APIKeys", func() {
+	var (
+		appConfig *config.ApplicationConfig
+		apiKeys   *APIKeys
+	)
+
+	BeforeEach(func() {
+		appConfig = config.NewApplicationConfig(
+			config.WithApiKeys([]string{"admin"}),
+			config.WithAPIKeyDailyTokens(100),
+		)
+		appConfig.Tenants = map[string]config.Tenant{
+			"team": {Name: "team", APIKeys: []string{"team-key"}, DailyTokens: 10, RequestsPerMinute: 2},
+		}
+		apiKeys = NewAPIKeys(appConfig)
+	})
+
+	It("parses the bearer tokens", func() {
+		Expect(BearerToken("Bearer admin")).To(Equal("admin"))
+		_, err := BearerToken("")
+		Expect(err).To(MatchError(ErrAuthorizationMissing))
+		_, err = BearerToken("Basic admin")
+		Expect(err).To(MatchError(ErrInvalidAuthFormat))
+		_, err = BearerToken("Bearer a b")
+		Expect(err).To(MatchError(ErrInvalidAuthFormat))
+	})
+
+	It("accepts the keys of the instance and of the tenants", func() {
+		Expect(apiKeys.Required()).To(BeTrue())
+		Expect(apiKeys.Authenticate("admin")).To(BeNil())
+		tenant, err := apiKeys.Authenticate("team-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tenant.Name).To(Equal("team"))
+		_, err = apiKeys.Authenticate("other")
+		Expect(err).To(MatchError(ErrInvalidAPIKey))
+	})
+
+	It("enforces the rate limits of the tenants", func() {
+		for range 2 {
+			_, err := apiKeys.Authenticate("team-key")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err := apiKeys.Authenticate("team-key")
+		Expect(err).To(MatchError(ErrTenantRateLimit))
+	})
+
+	It("counts the tokens in the quotas of the keys and of their tenants", func() {
+		tenant := appConfig.TenantByAPIKey("team-key")
+		apiKeys.RecordTokens("team-key", tenant, 10)
+		Expect(apiKeys.Quotas().Used(config.APIKeyOwner("team-key"))).To(Equal(10))
+		Expect(apiKeys.Quotas().Used(tenant.Owner())).To(Equal(10))
+		_, err := apiKeys.Authenticate("team-key")
+		Expect(err).To(MatchError(ErrTenantQuota))
+
+		apiKeys.RecordTokens("admin", nil, 100)
+		_, err = apiKeys.Authenticate("admin")
+		Expect(err).To(MatchError(ErrAPIKeyQuota))
+	})
+
+	It("requires no key without API keys nor tenants", func() {
+		Expect(NewAPIKeys(config.NewApplicationConfig()).Required()).To(BeFalse())
+	})
+})
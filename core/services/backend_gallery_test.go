@@ -0,0 +1,77 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/mudler/LocalAI/core/services"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backend gallery operations", func() {
+	var (
+		appConfig *config.ApplicationConfig
+		galleries []config.Gallery
+		run       string
+		g         *services.GalleryService
+	)
+
+	apply := func(op gallery.GalleryOp) {
+		op.Id = fmt.Sprintf("op-%s-%t", op.GalleryBackendName, op.Delete)
+		op.Galleries = galleries
+		g.C <- op
+		Eventually(func() bool {
+			status := g.GetStatus(op.Id)
+			return status != nil && status.Processed
+		}).Should(BeTrue())
+		Expect(g.GetStatus(op.Id).Error).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/index.yaml":
+				fmt.Fprintf(w, "- name: fake-backend\n  version: v1\n  files:\n    - filename: fake-backend\n      uri: http://%s/fake-backend\n", r.Host)
+			case "/fake-backend":
+				fmt.Fprint(w, "#!/bin/sh\n")
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		galleries = []config.Gallery{{Name: "test", URL: server.URL + "/index.yaml"}}
+		appConfig = config.NewApplicationConfig(config.WithBackendAssetsOutput(GinkgoT().TempDir()))
+		run = filepath.Join(gallery.BackendsPath(appConfig.AssetsDestination), "fake-backend", "fake-backend")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		g = services.NewGalleryService(appConfig)
+		g.Start(ctx, config.NewBackendConfigLoader(GinkgoT().TempDir()))
+	})
+
+	It("registers the installed backends, and unregisters them once deleted", func() {
+		apply(gallery.GalleryOp{GalleryBackendName: "fake-backend"})
+		Expect(appConfig.ExternalGRPCBackends).To(HaveKeyWithValue("fake-backend", run))
+
+		apply(gallery.GalleryOp{GalleryBackendName: "fake-backend", Delete: true})
+		Expect(appConfig.ExternalGRPCBackends).ToNot(HaveKey("fake-backend"))
+	})
+
+	It("leaves the external backends of the user with the same name", func() {
+		services.RegisterBackend(appConfig, "fake-backend", "127.0.0.1:9000")
+
+		apply(gallery.GalleryOp{GalleryBackendName: "fake-backend"})
+		Expect(appConfig.ExternalGRPCBackends).To(HaveKeyWithValue("fake-backend", "127.0.0.1:9000"))
+
+		apply(gallery.GalleryOp{GalleryBackendName: "fake-backend", Delete: true})
+		Expect(appConfig.ExternalGRPCBackends).To(HaveKeyWithValue("fake-backend", "127.0.0.1:9000"))
+	})
+})
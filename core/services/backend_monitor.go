@@ -126,11 +126,25 @@ func (bms BackendMonitorService) CheckAndSample(modelName string) (*proto.Status
 					"gopsutil-RSS": val.MemoryInfo.RSS,
 				},
 			},
+			WarmupDuration: bms.warmupDuration(backendId),
 		}, nil
 	}
+	// The warmup is run by LocalAI, unless the backend warms up the model itself
+	if status.WarmupDuration == 0 {
+		status.WarmupDuration = bms.warmupDuration(backendId)
+	}
 	return status, nil
 }
 
+// warmupDuration returns the seconds taken by the warmup of the model after loading it, or 0
+func (bms BackendMonitorService) warmupDuration(backendId string) float32 {
+	loadStatus, exists := bms.modelLoader.GetLoadStatus(backendId)
+	if !exists {
+		return 0
+	}
+	return float32(loadStatus.WarmupDuration.Seconds())
+}
+
 func (bms BackendMonitorService) ShutdownModel(modelName string) error {
 	backendId, err := bms.getModelLoaderIDFromModelName(modelName)
 	if err != nil {
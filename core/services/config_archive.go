@@ -0,0 +1,225 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/mudler/LocalAI/pkg/secrets"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// configArchiveSettings is the env file with the runtime settings, read by `local-ai run`
+	configArchiveSettings = "localai.env"
+	// configArchiveModels contains the files written in the models path
+	configArchiveModels = "models/"
+
+	galleryFilePrefix = "._gallery_"
+)
+
+// ExportConfigArchive writes a tar.gz archive with the runtime settings of the instance, the
+// configurations of all the models, the prompt templates (Go and Jinja) and the state of the models
+// installed from the galleries. The files of the models are not included, the gallery state is used
+// to download them again on import. The configurations of the models path are exported as they are
+// written, with their references to the environment, and the others as they are loaded. The values
+// of the secrets are redacted, and the archives with redacted values are refused on import.
+func ExportConfigArchive(w io.Writer, cl *config.BackendConfigLoader, appConfig *config.ApplicationConfig) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	settings, err := godotenv.Marshal(appConfig.ToEnvironment())
+	if err != nil {
+		return err
+	}
+	if err := add(configArchiveSettings, []byte(secrets.Redact(settings)+"\n")); err != nil {
+		return err
+	}
+
+	for _, c := range cl.GetAllBackendConfigs() {
+		data, err := backendConfigData(cl, c)
+		if err != nil {
+			return err
+		}
+		// the values of the secrets in the configuration are not exported
+		if err := add(configArchiveModels+c.Name+".yaml", []byte(secrets.Redact(string(data)))); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(appConfig.ModelPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".tmpl") && !strings.HasSuffix(e.Name(), ".jinja") && !strings.HasPrefix(e.Name(), galleryFilePrefix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(appConfig.ModelPath, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := add(configArchiveModels+e.Name(), data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// backendConfigData returns the configuration of the model as written in the models path, before
+// the interpolation of the environment, or as loaded when it is not configured by a file of the models path
+func backendConfigData(cl *config.BackendConfigLoader, c config.BackendConfig) ([]byte, error) {
+	if file, err := cl.BackendConfigFile(c.Name); err == nil {
+		return os.ReadFile(file)
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the configuration of %s: %w", c.Name, err)
+	}
+	return data, nil
+}
+
+// checkRedacted refuses the files of an archive with the values of secrets redacted on export, which
+// would replace the secrets
+func checkRedacted(name string, data []byte) error {
+	if bytes.Contains(data, []byte(secrets.Redacted)) {
+		return fmt.Errorf("%s of the archive contains redacted secrets: replace them with references, as ${NAME} or env://NAME, before importing it", name)
+	}
+	return nil
+}
+
+// ImportConfigArchive installs the models of an archive written by ExportConfigArchive in the
+// models path. The models installed from the galleries are installed again, downloading their
+// files, and then the configurations of the archive are written over the ones of the galleries.
+// The runtime settings are not applied, as they are read on startup: see ReadConfigArchiveSettings.
+func ImportConfigArchive(r io.Reader, modelPath string, downloadStatus func(string, string, string, float64), enforceScan bool) error {
+	files, err := readConfigArchive(r)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range files {
+		if strings.HasPrefix(name, configArchiveModels) {
+			names = append(names, strings.TrimPrefix(name, configArchiveModels))
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := utils.VerifyPath(name, modelPath); err != nil {
+			return err
+		}
+		if err := checkRedacted(configArchiveModels+name, files[configArchiveModels+name]); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(modelPath, 0750); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, galleryFilePrefix) {
+			continue
+		}
+		modelName := strings.TrimSuffix(strings.TrimPrefix(name, galleryFilePrefix), ".yaml")
+		galleryConfig := gallery.Config{}
+		if err := yaml.Unmarshal(files[configArchiveModels+name], &galleryConfig); err != nil {
+			return fmt.Errorf("failed to read the gallery state of %s: %w", modelName, err)
+		}
+		log.Debug().Msgf("Installing %s from the gallery state", modelName)
+		if err := gallery.InstallModel(modelPath, modelName, &galleryConfig, nil, downloadStatus, enforceScan); err != nil {
+			return fmt.Errorf("failed to install %s: %w", modelName, err)
+		}
+	}
+
+	for _, name := range names {
+		if strings.HasPrefix(name, galleryFilePrefix) {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(modelPath, name), files[configArchiveModels+name], 0600); err != nil {
+			return err
+		}
+		log.Debug().Msgf("Written %s", name)
+	}
+
+	return nil
+}
+
+// ReadConfigArchiveSettings returns the env file with the runtime settings of an archive
+// written by ExportConfigArchive
+func ReadConfigArchiveSettings(r io.Reader) ([]byte, error) {
+	files, err := readConfigArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	settings, exists := files[configArchiveSettings]
+	if !exists {
+		return nil, fmt.Errorf("%s not found in the archive", configArchiveSettings)
+	}
+	if err := checkRedacted(configArchiveSettings, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func readConfigArchive(r io.Reader) (map[string][]byte, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	defer gr.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/secrets"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configuration archives", func() {
+	const token = "hf_archive_secret_token"
+	var modelPath string
+
+	BeforeEach(func() {
+		GinkgoT().Setenv("LOCALAI_TEST_ARCHIVE_TOKEN", token)
+		secrets.Track(token)
+		modelPath = GinkgoT().TempDir()
+	})
+
+	export := func(cl *config.BackendConfigLoader) []byte {
+		var buf bytes.Buffer
+		Expect(services.ExportConfigArchive(&buf, cl, &config.ApplicationConfig{ModelPath: modelPath})).To(Succeed())
+		return buf.Bytes()
+	}
+
+	It("keeps the references to the secrets of the models over an export and an import", func() {
+		llama := "name: llama\ndescription: ${LOCALAI_TEST_ARCHIVE_TOKEN}\nparameters:\n  model: llama.gguf\n"
+		Expect(os.WriteFile(filepath.Join(modelPath, "llama.yaml"), []byte(llama), 0600)).To(Succeed())
+		cl := config.NewBackendConfigLoader(modelPath)
+		Expect(cl.LoadBackendConfigsFromPath(modelPath)).To(Succeed())
+		loaded, _ := cl.GetBackendConfig("llama")
+		Expect(loaded.Description).To(Equal(token))
+
+		importPath := GinkgoT().TempDir()
+		Expect(services.ImportConfigArchive(bytes.NewReader(export(cl)), importPath, nil, false)).To(Succeed())
+		dat, err := os.ReadFile(filepath.Join(importPath, "llama.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(dat)).To(Equal(llama))
+
+		imported := config.NewBackendConfigLoader(importPath)
+		Expect(imported.LoadBackendConfigsFromPath(importPath)).To(Succeed())
+		loaded, _ = imported.GetBackendConfig("llama")
+		Expect(loaded.Description).To(Equal(token))
+	})
+
+	It("refuses the archives with the values of secrets redacted", func() {
+		// The configurations which are not in the models path are exported as loaded, with the secrets redacted
+		configPath := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(configPath, "phi.yaml"), []byte("name: phi\ndescription: "+token+"\n"), 0600)).To(Succeed())
+		cl := config.NewBackendConfigLoader(modelPath)
+		Expect(cl.LoadBackendConfigsFromPath(configPath)).To(Succeed())
+
+		importPath := GinkgoT().TempDir()
+		err := services.ImportConfigArchive(bytes.NewReader(export(cl)), importPath, nil, false)
+		Expect(err).To(MatchError(ContainSubstring("models/phi.yaml of the archive contains redacted secrets")))
+		Expect(os.ReadDir(importPath)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EvalMessage is a message of the conversation of an evaluation item
+type EvalMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// EvalItem is a line of an evaluation dataset. The model answers the prompt (or the messages),
+// and the answer is scored against the expected answer, or by a judge model with the criteria
+type EvalItem struct {
+	ID       string        `json:"id"`
+	Prompt   string        `json:"prompt"`
+	Messages []EvalMessage `json:"messages"`
+	// Expected answer. With criteria it is given to the judge as the reference answer
+	Expected string `json:"expected"`
+	// Match compares the answer with the expected one: "contains" (default), "exact" or "regex"
+	Match string `json:"match"`
+	// Criteria the judge model scores the answer with
+	Criteria string `json:"criteria"`
+}
+
+// EvalResult is the answer of a model to an evaluation item. Score is nil when the item is not scored
+type EvalResult struct {
+	ID       string   `json:"id"`
+	Output   string   `json:"output"`
+	Score    *float64 `json:"score,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Duration float64  `json:"duration_ms"`
+}
+
+// EvalModelReport is the score of a model, the mean of the scores of its answers from 0 to 1
+type EvalModelReport struct {
+	Model   string       `json:"model"`
+	Score   float64      `json:"score"`
+	Scored  int          `json:"scored"`
+	Errors  int          `json:"errors"`
+	Results []EvalResult `json:"results"`
+}
+
+type EvalReport struct {
+	Dataset string            `json:"dataset"`
+	Judge   string            `json:"judge,omitempty"`
+	Created time.Time         `json:"created"`
+	Models  []EvalModelReport `json:"models"`
+}
+
+// EvalChat returns the answer of a model to a conversation
+type EvalChat func(ctx context.Context, model string, messages []EvalMessage) (string, error)
+
+type EvalOptions struct {
+	Models []string
+	// Judge is the model scoring the answers of the items with criteria
+	Judge       string
+	Concurrency int
+}
+
+// ReadEvalDataset reads a JSONL dataset, one item for each line. Items without id are numbered by their line
+func ReadEvalDataset(path string) ([]EvalItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	items := []EvalItem{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		item := EvalItem{}
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if item.Prompt == "" && len(item.Messages) == 0 {
+			return nil, fmt.Errorf("line %d: a prompt or messages are required", line)
+		}
+		switch item.Match {
+		case "", "contains", "exact":
+		case "regex":
+			if _, err := regexp.Compile(item.Expected); err != nil {
+				return nil, fmt.Errorf("line %d: invalid expected regex: %w", line, err)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown match %q", line, item.Match)
+		}
+		if item.ID == "" {
+			item.ID = strconv.Itoa(line)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// RunEval runs the dataset with each model in turn, sending the given number of concurrent
+// requests, and scores the answers
+func RunEval(ctx context.Context, items []EvalItem, opts EvalOptions, chat EvalChat) (*EvalReport, error) {
+	for _, item := range items {
+		if item.Criteria != "" && opts.Judge == "" {
+			return nil, fmt.Errorf("item %s has criteria, a judge model is required", item.ID)
+		}
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	report := &EvalReport{
+		Judge:   opts.Judge,
+		Created: time.Now(),
+	}
+	for _, model := range opts.Models {
+		results := make([]EvalResult, len(items))
+		indexes := make(chan int, len(items))
+		for i := range items {
+			indexes <- i
+		}
+		close(indexes)
+
+		var wg sync.WaitGroup
+		for w := 0; w < concurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					results[i] = evalItem(ctx, model, items[i], opts.Judge, chat)
+				}
+			}()
+		}
+		wg.Wait()
+
+		modelReport := EvalModelReport{Model: model, Results: results}
+		total := 0.0
+		for _, r := range results {
+			if r.Error != "" {
+				modelReport.Errors++
+			}
+			if r.Score != nil {
+				modelReport.Scored++
+				total += *r.Score
+			}
+		}
+		if modelReport.Scored > 0 {
+			modelReport.Score = total / float64(modelReport.Scored)
+		}
+		report.Models = append(report.Models, modelReport)
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func evalItem(ctx context.Context, model string, item EvalItem, judge string, chat EvalChat) EvalResult {
+	result := EvalResult{ID: item.ID}
+
+	messages := item.Messages
+	if len(messages) == 0 {
+		messages = []EvalMessage{{Role: "user", Content: item.Prompt}}
+	}
+
+	start := time.Now()
+	output, err := chat(ctx, model, messages)
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Error = err.Error()
+		log.Debug().Msgf("Eval: model %s failed item %s: %s", model, item.ID, err)
+		return result
+	}
+	result.Output = output
+
+	switch {
+	case item.Criteria != "":
+		score, reason, err := judgeAnswer(ctx, judge, item, messages, output, chat)
+		if err != nil {
+			result.Error = fmt.Sprintf("judge: %s", err)
+			return result
+		}
+		result.Score = &score
+		result.Reason = reason
+	case item.Expected != "":
+		score := matchAnswer(item, output)
+		result.Score = &score
+	}
+	return result
+}
+
+// matchAnswer scores 1 when the answer matches the expected one, ignoring the case and the
+// surrounding spaces
+func matchAnswer(item EvalItem, output string) float64 {
+	output = strings.TrimSpace(output)
+	expected := strings.TrimSpace(item.Expected)
+
+	matched := false
+	switch item.Match {
+	case "exact":
+		matched = strings.EqualFold(output, expected)
+	case "regex":
+		matched = regexp.MustCompile(expected).MatchString(output)
+	default:
+		matched = strings.Contains(strings.ToLower(output), strings.ToLower(expected))
+	}
+	if matched {
+		return 1
+	}
+	return 0
+}
+
+const judgePrompt = `You are evaluating the answer of an AI assistant to a conversation.
+
+Conversation:
+%s
+
+Answer:
+%s
+%s
+Criteria:
+%s
+
+Rate how well the answer meets the criteria on a scale from 0 to 10, and explain briefly.
+Reply with the rating on the first line, as "Score: <number>", and the explanation after it.`
+
+var judgeScore = regexp.MustCompile(`(?i)score\s*[:=]?\s*(\d+(?:\.\d+)?)`)
+
+// judgeAnswer asks the judge model to score the answer with the criteria of the item, and
+// returns the score from 0 to 1 and the explanation of the judge
+func judgeAnswer(ctx context.Context, judge string, item EvalItem, messages []EvalMessage, output string, chat EvalChat) (float64, string, error) {
+	conversation := []string{}
+	for _, m := range messages {
+		conversation = append(conversation, fmt.Sprintf("%s: %s", m.Role, m.Content))
+	}
+	reference := ""
+	if item.Expected != "" {
+		reference = fmt.Sprintf("\nReference answer:\n%s\n", item.Expected)
+	}
+
+	answer, err := chat(ctx, judge, []EvalMessage{{
+		Role:    "user",
+		Content: fmt.Sprintf(judgePrompt, strings.Join(conversation, "\n"), output, reference, item.Criteria),
+	}})
+	if err != nil {
+		return 0, "", err
+	}
+
+	match := judgeScore.FindStringSubmatch(answer)
+	if match == nil {
+		return 0, "", fmt.Errorf("no score in the answer of the judge: %q", answer)
+	}
+	score, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, "", err
+	}
+	score = min(max(score, 0), 10) / 10
+
+	reason := strings.TrimSpace(strings.Replace(answer, match[0], "", 1))
+	return score, reason, nil
+}
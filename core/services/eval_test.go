@@ -0,0 +1,145 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/mudler/LocalAI/core/services"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Eval", func() {
+	writeDataset := func(lines ...string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "dataset.jsonl")
+		Expect(os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)).To(Succeed())
+		return path
+	}
+
+	Context("datasets", func() {
+		It("reads the items and numbers the ones without id by their line", func() {
+			items, err := ReadEvalDataset(writeDataset(
+				`{"id":"capital","prompt":"Capital of France?","expected":"Paris"}`,
+				``,
+				`{"messages":[{"role":"user","content":"2+2?"}],"expected":"^4$","match":"regex"}`,
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(items).To(HaveLen(2))
+			Expect(items[0].ID).To(Equal("capital"))
+			Expect(items[1].ID).To(Equal("3"))
+			Expect(items[1].Messages).To(Equal([]EvalMessage{{Role: "user", Content: "2+2?"}}))
+		})
+
+		It("rejects the invalid items with their line", func() {
+			_, err := ReadEvalDataset(writeDataset(`{"prompt":"a"}`, `{"expected":"b"}`))
+			Expect(err).To(MatchError("line 2: a prompt or messages are required"))
+
+			_, err = ReadEvalDataset(writeDataset(`{"prompt":"a","expected":"(","match":"regex"}`))
+			Expect(err).To(MatchError(ContainSubstring("line 1: invalid expected regex")))
+
+			_, err = ReadEvalDataset(writeDataset(`{"prompt":"a","match":"fuzzy"}`))
+			Expect(err).To(MatchError(`line 1: unknown match "fuzzy"`))
+		})
+	})
+
+	Context("runs", func() {
+		answers := map[string]string{
+			"Capital of France?": " The capital is PARIS. ",
+			"2+2?":               "4",
+			"Write a haiku":      "An old silent pond",
+		}
+		var (
+			mu       sync.Mutex
+			requests map[string][]string
+		)
+		chat := func(ctx context.Context, model string, messages []EvalMessage) (string, error) {
+			prompt := messages[len(messages)-1].Content
+			mu.Lock()
+			requests[model] = append(requests[model], prompt)
+			mu.Unlock()
+
+			switch {
+			case model == "judge":
+				return "Score: 7\nThe haiku lacks the seasonal word.", nil
+			case model == "broken":
+				return "", errors.New("model not loaded")
+			}
+			return answers[prompt], nil
+		}
+
+		BeforeEach(func() {
+			requests = map[string][]string{}
+		})
+
+		It("scores the answers of each model against the expected ones", func() {
+			items := []EvalItem{
+				{ID: "contains", Prompt: "Capital of France?", Expected: "paris"},
+				{ID: "exact", Prompt: "Capital of France?", Expected: "paris", Match: "exact"},
+				{ID: "regex", Prompt: "2+2?", Expected: `^\d$`, Match: "regex"},
+				{ID: "unscored", Prompt: "2+2?"},
+			}
+			report, err := RunEval(context.Background(), items, EvalOptions{Models: []string{"phi", "broken"}, Concurrency: 2}, chat)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Models).To(HaveLen(2))
+
+			phi := report.Models[0]
+			Expect(phi.Model).To(Equal("phi"))
+			Expect(phi.Scored).To(Equal(3))
+			Expect(phi.Errors).To(BeZero())
+			Expect(phi.Score).To(BeNumerically("~", 2.0/3))
+			Expect(*phi.Results[0].Score).To(Equal(1.0))
+			Expect(*phi.Results[1].Score).To(Equal(0.0))
+			Expect(*phi.Results[2].Score).To(Equal(1.0))
+			Expect(phi.Results[3].Score).To(BeNil())
+			Expect(phi.Results[3].Output).To(Equal("4"))
+
+			broken := report.Models[1]
+			Expect(broken.Errors).To(Equal(4))
+			Expect(broken.Scored).To(BeZero())
+			Expect(broken.Results[0].Error).To(Equal("model not loaded"))
+		})
+
+		It("has the answers with criteria scored by the judge", func() {
+			items := []EvalItem{{ID: "haiku", Prompt: "Write a haiku", Expected: "A haiku about autumn", Criteria: "Is it a haiku about autumn?"}}
+			report, err := RunEval(context.Background(), items, EvalOptions{Models: []string{"phi"}, Judge: "judge"}, chat)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Judge).To(Equal("judge"))
+
+			result := report.Models[0].Results[0]
+			Expect(*result.Score).To(Equal(0.7))
+			Expect(result.Reason).To(Equal("The haiku lacks the seasonal word."))
+
+			Expect(requests["judge"]).To(HaveLen(1))
+			Expect(requests["judge"][0]).To(ContainSubstring("user: Write a haiku"))
+			Expect(requests["judge"][0]).To(ContainSubstring("An old silent pond"))
+			Expect(requests["judge"][0]).To(ContainSubstring("Reference answer:\nA haiku about autumn"))
+			Expect(requests["judge"][0]).To(ContainSubstring("Is it a haiku about autumn?"))
+		})
+
+		It("requires a judge for the items with criteria", func() {
+			items := []EvalItem{{ID: "haiku", Prompt: "Write a haiku", Criteria: "Is it a haiku?"}}
+			_, err := RunEval(context.Background(), items, EvalOptions{Models: []string{"phi"}}, chat)
+			Expect(err).To(MatchError("item haiku has criteria, a judge model is required"))
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("reports the answers of the judge without score", func() {
+			judge := func(ctx context.Context, model string, messages []EvalMessage) (string, error) {
+				if model == "judge" {
+					return "It is a fine haiku", nil
+				}
+				return "An old silent pond", nil
+			}
+			items := []EvalItem{{ID: "haiku", Prompt: "Write a haiku", Criteria: "Is it a haiku?"}}
+			report, err := RunEval(context.Background(), items, EvalOptions{Models: []string{"phi"}, Judge: "judge"}, judge)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Models[0].Errors).To(Equal(1))
+			Expect(report.Models[0].Results[0].Error).To(Equal(`judge: no score in the answer of the judge: "It is a fine haiku"`))
+			Expect(report.Models[0].Results[0].Output).To(Equal("An old silent pond"))
+		})
+	})
+})
@@ -1,17 +1,22 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/mudler/LocalAI/core/config"
 	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/mudler/LocalAI/pkg/events"
 	"github.com/mudler/LocalAI/pkg/startup"
 	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
 )
 
@@ -20,6 +25,7 @@ type GalleryService struct {
 	sync.Mutex
 	C        chan gallery.GalleryOp
 	statuses map[string]*gallery.GalleryOpStatus
+	owners   map[string]string
 }
 
 func NewGalleryService(appConfig *config.ApplicationConfig) *GalleryService {
@@ -27,6 +33,7 @@ func NewGalleryService(appConfig *config.ApplicationConfig) *GalleryService {
 		appConfig: appConfig,
 		C:         make(chan gallery.GalleryOp),
 		statuses:  make(map[string]*gallery.GalleryOpStatus),
+		owners:    make(map[string]string),
 	}
 }
 
@@ -59,7 +66,32 @@ func (g *GalleryService) GetAllStatus() map[string]*gallery.GalleryOpStatus {
 	g.Lock()
 	defer g.Unlock()
 
-	return g.statuses
+	return maps.Clone(g.statuses)
+}
+
+// GetOwnerStatus returns the status of the operation requested by the owner, or nil
+func (g *GalleryService) GetOwnerStatus(s, owner string) *gallery.GalleryOpStatus {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.owners[s] != owner {
+		return nil
+	}
+	return g.statuses[s]
+}
+
+// GetAllOwnerStatus returns the status of the operations requested by the owner
+func (g *GalleryService) GetAllOwnerStatus(owner string) map[string]*gallery.GalleryOpStatus {
+	g.Lock()
+	defer g.Unlock()
+
+	statuses := map[string]*gallery.GalleryOpStatus{}
+	for id, status := range g.statuses {
+		if g.owners[id] == owner {
+			statuses[id] = status
+		}
+	}
+	return statuses
 }
 
 func (g *GalleryService) Start(c context.Context, cl *config.BackendConfigLoader) {
@@ -71,6 +103,9 @@ func (g *GalleryService) Start(c context.Context, cl *config.BackendConfigLoader
 			case op := <-g.C:
 				utils.ResetDownloadTimers()
 
+				g.Lock()
+				g.owners[op.Id] = op.Owner
+				g.Unlock()
 				g.UpdateStatus(op.Id, &gallery.GalleryOpStatus{Message: "processing", Progress: 0})
 
 				// updates the status with an error
@@ -78,10 +113,12 @@ func (g *GalleryService) Start(c context.Context, cl *config.BackendConfigLoader
 				if !g.appConfig.OpaqueErrors {
 					updateError = func(e error) {
 						g.UpdateStatus(op.Id, &gallery.GalleryOpStatus{Error: e, Processed: true, Message: "error: " + e.Error()})
+						g.jobCompleted(op, e)
 					}
 				} else {
-					updateError = func(_ error) {
+					updateError = func(e error) {
 						g.UpdateStatus(op.Id, &gallery.GalleryOpStatus{Error: fmt.Errorf("an error occurred"), Processed: true})
+						g.jobCompleted(op, e)
 					}
 				}
 
@@ -91,6 +128,22 @@ func (g *GalleryService) Start(c context.Context, cl *config.BackendConfigLoader
 					utils.DisplayDownloadFunction(fileName, current, total, percentage)
 				}
 
+				if op.GalleryBackendName != "" {
+					if err := g.applyBackendOp(op, progressCallback); err != nil {
+						updateError(err)
+						continue
+					}
+					g.UpdateStatus(op.Id,
+						&gallery.GalleryOpStatus{
+							Deletion:           op.Delete,
+							Processed:          true,
+							GalleryBackendName: op.GalleryBackendName,
+							Message:            "completed",
+							Progress:           100})
+					g.jobCompleted(op, nil)
+					continue
+				}
+
 				var err error
 
 				// delete a model
@@ -126,7 +179,9 @@ func (g *GalleryService) Start(c context.Context, cl *config.BackendConfigLoader
 					}
 				} else {
 					// if the request contains a gallery name, we apply the gallery from the gallery list
-					if op.GalleryModelName != "" {
+					if op.ConfigArchive != nil {
+						err = ImportConfigArchive(bytes.NewReader(op.ConfigArchive), g.appConfig.ModelPath, progressCallback, g.appConfig.EnforcePredownloadScans)
+					} else if op.GalleryModelName != "" {
 						err = gallery.InstallModelFromGallery(op.Galleries, op.GalleryModelName, g.appConfig.ModelPath, op.Req, progressCallback, g.appConfig.EnforcePredownloadScans)
 					} else if op.ConfigURL != "" {
 						err = startup.InstallModels(op.Galleries, op.ConfigURL, g.appConfig.ModelPath, g.appConfig.EnforcePredownloadScans, progressCallback, op.ConfigURL)
@@ -165,11 +220,92 @@ func (g *GalleryService) Start(c context.Context, cl *config.BackendConfigLoader
 						GalleryModelName: op.GalleryModelName,
 						Message:          "completed",
 						Progress:         100})
+				g.jobCompleted(op, nil)
 			}
 		}
 	}()
 }
 
+// jobCompleted publishes the end of the job, with its error if any
+func (g *GalleryService) jobCompleted(op gallery.GalleryOp, err error) {
+	operation := "install"
+	switch {
+	case op.Delete:
+		operation = "delete"
+	case op.ConfigArchive != nil:
+		operation = "import"
+	}
+	data := map[string]any{
+		"job":       op.Id,
+		"operation": operation,
+	}
+	if op.GalleryModelName != "" {
+		data["model"] = op.GalleryModelName
+	}
+	if op.GalleryBackendName != "" {
+		data["backend"] = op.GalleryBackendName
+	}
+	if err != nil {
+		data["error"] = err.Error()
+		if g.appConfig.OpaqueErrors {
+			data["error"] = "an error occurred"
+		}
+	}
+	g.appConfig.Events.Publish(events.GalleryJobCompleted, data)
+}
+
+// applyBackendOp installs or deletes a backend, and updates the external backends accordingly:
+// the models loaded from then on use the new version of the backend. As at startup, the external
+// backends set by the user with the same name take precedence, and are left as they are
+func (g *GalleryService) applyBackendOp(op gallery.GalleryOp, progressCallback func(string, string, string, float64)) error {
+	if op.Delete {
+		if err := gallery.DeleteBackendFromSystem(g.appConfig.AssetsDestination, op.GalleryBackendName); err != nil {
+			return err
+		}
+		if g.isGalleryBackend(op.GalleryBackendName) {
+			UnregisterBackend(g.appConfig, op.GalleryBackendName)
+		}
+		return nil
+	}
+
+	installed, err := gallery.InstallBackendFromGallery(op.Galleries, op.GalleryBackendName, g.appConfig.AssetsDestination, progressCallback)
+	if err != nil {
+		return err
+	}
+	if _, exists := g.appConfig.ExternalGRPCBackends[installed.Name]; exists && !g.isGalleryBackend(installed.Name) {
+		log.Warn().Str("backend", installed.Name).Msg("installed backend shadowed by an external backend with the same name")
+		return nil
+	}
+	RegisterBackend(g.appConfig, installed.Name, installed.Run)
+	return nil
+}
+
+// isGalleryBackend tells if the external backend is the one installed from the backend galleries, in
+// the directory of the backend, rather than one set by the user
+func (g *GalleryService) isGalleryBackend(name string) bool {
+	uri, exists := g.appConfig.ExternalGRPCBackends[name]
+	dir := filepath.Join(gallery.BackendsPath(g.appConfig.AssetsDestination), name)
+	return exists && strings.HasPrefix(uri, dir+string(filepath.Separator))
+}
+
+// RegisterBackend makes the backend available to the models as an external backend.
+// The map is replaced rather than updated, as it is read by the requests being served
+func RegisterBackend(appConfig *config.ApplicationConfig, name, run string) {
+	backends := maps.Clone(appConfig.ExternalGRPCBackends)
+	if backends == nil {
+		backends = map[string]string{}
+	}
+	backends[name] = run
+	appConfig.ExternalGRPCBackends = backends
+}
+
+// UnregisterBackend removes a backend registered with RegisterBackend
+func UnregisterBackend(appConfig *config.ApplicationConfig, name string) {
+	backends := maps.Clone(appConfig.ExternalGRPCBackends)
+	delete(backends, name)
+	appConfig.ExternalGRPCBackends = backends
+}
+
 type galleryModel struct {
 	gallery.GalleryModel `yaml:",inline"` // https://github.com/go-yaml/yaml/issues/63
 	ID                   string           `json:"id"`
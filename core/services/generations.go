@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mudler/LocalAI/core/schema"
+)
+
+var (
+	ErrGenerationNotFound = errors.New("generation not found")
+	ErrGenerationExists   = errors.New("a generation with this request ID is already running")
+)
+
+// generationKey identifies the generations by their owner, so the owners can not replace the generations
+// of the others by setting the same request ID
+type generationKey struct {
+	owner, id string
+}
+
+type generation struct {
+	schema.Generation
+	owner  string
+	cancel context.CancelFunc
+}
+
+// GenerationService keeps the chat and completion requests being generated, so they can be cancelled
+// by their ID from another request, as the stop button of a UI, without dropping their connection
+type GenerationService struct {
+	sync.Mutex
+	generations map[generationKey]*generation
+}
+
+func NewGenerationService() *GenerationService {
+	return &GenerationService{
+		generations: make(map[generationKey]*generation),
+	}
+}
+
+// Start registers the generation of the request, cancelled with cancel. The owner is the API key, or the
+// tenant, of the request: the generations can only be cancelled by their owner. The request IDs set by the
+// clients already used by a running generation of the owner are rejected. The returned function
+// unregisters the generation once it is finished
+func (gs *GenerationService) Start(id, owner, model string, cancel context.CancelFunc) (func(), error) {
+	g := &generation{
+		Generation: schema.Generation{
+			ID:        id,
+			Object:    "generation",
+			Model:     model,
+			CreatedAt: time.Now().Unix(),
+		},
+		owner:  owner,
+		cancel: cancel,
+	}
+
+	key := generationKey{owner: owner, id: id}
+	gs.Lock()
+	defer gs.Unlock()
+	if _, exists := gs.generations[key]; exists {
+		return nil, ErrGenerationExists
+	}
+	gs.generations[key] = g
+
+	return func() {
+		gs.Lock()
+		defer gs.Unlock()
+		// the clients may reuse the request IDs once the generation is cancelled
+		if gs.generations[key] == g {
+			delete(gs.generations, key)
+		}
+	}, nil
+}
+
+// List returns the generations of the owner, the oldest first
+func (gs *GenerationService) List(owner string) []schema.Generation {
+	return gs.list(func(g *generation) bool { return g.owner == owner })
+}
+
+// All returns the generations of all the owners, the oldest first
+func (gs *GenerationService) All() []schema.Generation {
+	return gs.list(func(g *generation) bool { return true })
+}
+
+func (gs *GenerationService) list(filter func(*generation) bool) []schema.Generation {
+	gs.Lock()
+	defer gs.Unlock()
+	res := []schema.Generation{}
+	for _, g := range gs.generations {
+		if filter(g) {
+			res = append(res, g.Generation)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CreatedAt != res[j].CreatedAt {
+			return res[i].CreatedAt < res[j].CreatedAt
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Cancel cancels the generation of the owner. It ends as the requests whose connection is dropped: the
+// backend stops generating, and the streamed responses are finished
+func (gs *GenerationService) Cancel(id, owner string) (schema.Generation, error) {
+	key := generationKey{owner: owner, id: id}
+	gs.Lock()
+	g, exists := gs.generations[key]
+	delete(gs.generations, key)
+	gs.Unlock()
+	if !exists {
+		return schema.Generation{}, ErrGenerationNotFound
+	}
+	g.cancel()
+	return g.Generation, nil
+}
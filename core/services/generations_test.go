@@ -0,0 +1,80 @@
+package services_test
+
+import (
+	"context"
+
+	. "github.com/mudler/LocalAI/core/services"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GenerationService", func() {
+	var generations *GenerationService
+
+	BeforeEach(func() {
+		generations = NewGenerationService()
+	})
+
+	It("lists and cancels the generations of their owner", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		finish, err := generations.Start("checkout-42", "alice", "phi", cancel)
+		Expect(err).ToNot(HaveOccurred())
+		context.AfterFunc(ctx, finish)
+
+		Expect(generations.List("alice")).To(HaveLen(1))
+		Expect(generations.List("bob")).To(BeEmpty())
+		Expect(generations.All()).To(HaveLen(1))
+
+		_, err = generations.Cancel("checkout-42", "bob")
+		Expect(err).To(MatchError(ErrGenerationNotFound))
+		Expect(ctx.Err()).ToNot(HaveOccurred())
+
+		g, err := generations.Cancel("checkout-42", "alice")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.ID).To(Equal("checkout-42"))
+		Expect(g.Model).To(Equal("phi"))
+		Expect(ctx.Err()).To(MatchError(context.Canceled))
+		Expect(generations.All()).To(BeEmpty())
+
+		_, err = generations.Cancel("checkout-42", "alice")
+		Expect(err).To(MatchError(ErrGenerationNotFound))
+	})
+
+	It("rejects the request IDs of the running generations of the owner", func() {
+		_, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		finish, err := generations.Start("id", "alice", "phi", cancel)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = generations.Start("id", "alice", "phi", cancel)
+		Expect(err).To(MatchError(ErrGenerationExists))
+
+		// the other owners can not replace the generation with the same ID
+		_, otherCancel := context.WithCancel(context.Background())
+		defer otherCancel()
+		_, err = generations.Start("id", "bob", "llama", otherCancel)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(generations.List("alice")).To(ConsistOf(HaveField("Model", "phi")))
+		Expect(generations.List("bob")).To(ConsistOf(HaveField("Model", "llama")))
+
+		// the ID can be used again once the generation is finished
+		finish()
+		Expect(generations.List("alice")).To(BeEmpty())
+		_, err = generations.Start("id", "alice", "phi", cancel)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("does not unregister the next generation with the same ID", func() {
+		_, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		finish, err := generations.Start("id", "alice", "phi", cancel)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = generations.Cancel("id", "alice")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = generations.Start("id", "alice", "llama", cancel)
+		Expect(err).ToNot(HaveOccurred())
+		finish()
+		Expect(generations.List("alice")).To(ConsistOf(HaveField("Model", "llama")))
+	})
+})
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/provenance"
+)
+
+// imageMetadataExt is the extension of the files with the parameters of the generated images, written next to them
+const imageMetadataExt = ".json"
+
+// SaveImageMetadata writes the parameters of the generation of the image next to it, for the gallery of the images
+func SaveImageMetadata(file string, p provenance.Parameters) error {
+	dat, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file+imageMetadataExt, dat, 0600)
+}
+
+// ListGeneratedImages returns the images of dir with the parameters of their generation, the most recent
+// first, up to limit. The images without parameters, as the ones generated before, are not listed
+func ListGeneratedImages(dir string, limit int) ([]schema.GeneratedImage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []schema.GeneratedImage{}, nil
+		}
+		return nil, err
+	}
+
+	images := []schema.GeneratedImage{}
+	for _, e := range entries {
+		name, isMetadata := strings.CutSuffix(e.Name(), imageMetadataExt)
+		if !isMetadata || e.IsDir() {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			// the image was removed
+			continue
+		}
+		dat, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		image := schema.GeneratedImage{Name: name, CreatedAt: info.ModTime()}
+		if err := json.Unmarshal(dat, &image.Parameters); err != nil {
+			continue
+		}
+		images = append(images, image)
+	}
+
+	sort.Slice(images, func(i, j int) bool { return images[i].CreatedAt.After(images[j].CreatedAt) })
+	if limit > 0 && len(images) > limit {
+		images = images[:limit]
+	}
+	return images, nil
+}
@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/mudler/LocalAI/core/services"
+	"github.com/mudler/LocalAI/pkg/provenance"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image gallery", func() {
+	var dir string
+
+	// generate writes an image with the parameters of its generation, created at the given time
+	generate := func(name, prompt string, created time.Time) {
+		file := filepath.Join(dir, name)
+		Expect(os.WriteFile(file, []byte("\x89PNG"), 0600)).To(Succeed())
+		Expect(SaveImageMetadata(file, provenance.Parameters{Prompt: prompt, Model: "sd", Seed: 42})).To(Succeed())
+		Expect(os.Chtimes(file, created, created)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("lists the generated images with their parameters, the most recent first", func() {
+		now := time.Now()
+		generate("cat.png", "a cat", now.Add(-time.Hour))
+		generate("dog.png", "a dog", now)
+		// images generated without the parameters are not listed
+		Expect(os.WriteFile(filepath.Join(dir, "old.png"), []byte("\x89PNG"), 0600)).To(Succeed())
+
+		images, err := ListGeneratedImages(dir, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(images).To(HaveLen(2))
+		Expect(images[0].Name).To(Equal("dog.png"))
+		Expect(images[0].Parameters).To(Equal(provenance.Parameters{Prompt: "a dog", Model: "sd", Seed: 42}))
+		Expect(images[0].CreatedAt).To(BeTemporally("~", now, time.Second))
+		Expect(images[1].Name).To(Equal("cat.png"))
+
+		images, err = ListGeneratedImages(dir, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(images).To(HaveLen(1))
+		Expect(images[0].Name).To(Equal("dog.png"))
+	})
+
+	It("skips the images removed and the invalid parameters", func() {
+		generate("cat.png", "a cat", time.Now())
+		generate("dog.png", "a dog", time.Now())
+		Expect(os.Remove(filepath.Join(dir, "cat.png"))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "dog.png.json"), []byte("{"), 0600)).To(Succeed())
+
+		Expect(ListGeneratedImages(dir, 0)).To(BeEmpty())
+	})
+
+	It("lists no image before the image directory is created", func() {
+		images, err := ListGeneratedImages(filepath.Join(dir, "missing"), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(images).To(BeEmpty())
+	})
+})
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobNotFinished = errors.New("job not completed")
+	ErrJobFinished    = errors.New("job already finished")
+)
+
+// JobResult is the response of the request run by a job
+type JobResult struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// JobFunc runs a job. It reports its progress, and should stop when the context is cancelled
+type JobFunc func(ctx context.Context, progress func(percent float64, message string)) (*JobResult, error)
+
+type job struct {
+	schema.Job
+	owner  string
+	result *JobResult
+	cancel context.CancelFunc
+}
+
+// JobService runs the long-running requests in the background, and keeps their status and
+// their result in memory until they are retrieved, for the retention of the jobs
+type JobService struct {
+	ctx       context.Context
+	retention time.Duration
+	sync.Mutex
+	jobs map[string]*job
+}
+
+// NewJobService keeps the finished jobs for retention
+func NewJobService(ctx context.Context, retention time.Duration) *JobService {
+	return &JobService{
+		ctx:       ctx,
+		retention: retention,
+		jobs:      make(map[string]*job),
+	}
+}
+
+// Submit runs the job in the background. The owner is the API key, or the tenant, submitting
+// it: the jobs are only visible to their owner
+func (js *JobService) Submit(jobType, owner string, run JobFunc) schema.Job {
+	ctx, cancel := context.WithCancel(js.ctx)
+	j := &job{
+		Job: schema.Job{
+			ID:        "job_" + uuid.New().String(),
+			Object:    "job",
+			Type:      jobType,
+			Status:    schema.JobStatusRunning,
+			CreatedAt: time.Now().Unix(),
+		},
+		owner:  owner,
+		cancel: cancel,
+	}
+
+	js.Lock()
+	js.prune()
+	js.jobs[j.ID] = j
+	js.Unlock()
+
+	go func() {
+		defer cancel()
+		result, err := run(ctx, func(percent float64, message string) {
+			js.Lock()
+			defer js.Unlock()
+			if j.Status == schema.JobStatusRunning {
+				j.Progress = percent
+				j.Message = message
+			}
+		})
+
+		js.Lock()
+		defer js.Unlock()
+		// The result of the cancelled jobs is dropped
+		if j.Status != schema.JobStatusRunning {
+			return
+		}
+		j.FinishedAt = time.Now().Unix()
+		if err != nil {
+			log.Debug().Err(err).Str("job", j.ID).Msg("job failed")
+			j.Status = schema.JobStatusFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = schema.JobStatusCompleted
+		j.Progress = 100
+		j.result = result
+	}()
+
+	return j.Job
+}
+
+// Prune drops the jobs finished for longer than the retention, with their results
+func (js *JobService) Prune() {
+	js.Lock()
+	defer js.Unlock()
+	js.prune()
+}
+
+// prune drops the jobs finished for longer than the retention. Called with the lock held
+func (js *JobService) prune() {
+	for id, j := range js.jobs {
+		if j.FinishedAt != 0 && time.Since(time.Unix(j.FinishedAt, 0)) > js.retention {
+			delete(js.jobs, id)
+		}
+	}
+}
+
+// Get returns the job of the owner
+func (js *JobService) Get(id, owner string) (schema.Job, error) {
+	js.Lock()
+	defer js.Unlock()
+	j, exists := js.jobs[id]
+	if !exists || j.owner != owner {
+		return schema.Job{}, ErrJobNotFound
+	}
+	return j.Job, nil
+}
+
+// List returns the jobs of the owner, from the oldest
+func (js *JobService) List(owner string) []schema.Job {
+	js.Lock()
+	defer js.Unlock()
+	jobs := []schema.Job{}
+	for _, j := range js.jobs {
+		if j.owner == owner {
+			jobs = append(jobs, j.Job)
+		}
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		if jobs[i].CreatedAt != jobs[k].CreatedAt {
+			return jobs[i].CreatedAt < jobs[k].CreatedAt
+		}
+		return jobs[i].ID < jobs[k].ID
+	})
+	return jobs
+}
+
+// Result returns the result of the completed job of the owner
+func (js *JobService) Result(id, owner string) (*JobResult, error) {
+	js.Lock()
+	defer js.Unlock()
+	j, exists := js.jobs[id]
+	if !exists || j.owner != owner {
+		return nil, ErrJobNotFound
+	}
+	if j.Status != schema.JobStatusCompleted {
+		return nil, ErrJobNotFinished
+	}
+	return j.result, nil
+}
+
+// Cancel cancels the running job of the owner
+func (js *JobService) Cancel(id, owner string) (schema.Job, error) {
+	js.Lock()
+	defer js.Unlock()
+	j, exists := js.jobs[id]
+	if !exists || j.owner != owner {
+		return schema.Job{}, ErrJobNotFound
+	}
+	if j.Status != schema.JobStatusRunning {
+		return j.Job, ErrJobFinished
+	}
+	j.Status = schema.JobStatusCancelled
+	j.FinishedAt = time.Now().Unix()
+	j.cancel()
+	return j.Job, nil
+}
+
+// GalleryJob returns the operation of the galleries as a job
+func GalleryJob(id string, op *gallery.GalleryOpStatus) schema.Job {
+	j := schema.Job{
+		ID:       id,
+		Object:   "job",
+		Type:     "gallery",
+		Status:   schema.JobStatusRunning,
+		Progress: op.Progress,
+		Message:  op.Message,
+	}
+	if op.Processed {
+		j.Status = schema.JobStatusCompleted
+		if op.Error != nil {
+			j.Status = schema.JobStatusFailed
+			j.Error = op.Error.Error()
+		}
+	}
+	return j
+}
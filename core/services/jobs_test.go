@@ -0,0 +1,129 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mudler/LocalAI/core/schema"
+	. "github.com/mudler/LocalAI/core/services"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobService", func() {
+	var jobs *JobService
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		jobs = NewJobService(ctx, time.Hour)
+	})
+
+	status := func(id, owner string) func() string {
+		return func() string {
+			job, err := jobs.Get(id, owner)
+			Expect(err).ToNot(HaveOccurred())
+			return job.Status
+		}
+	}
+
+	It("runs the jobs and keeps their results", func() {
+		release := make(chan struct{})
+		job := jobs.Submit("transcription", "owner", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			progress(50, "halfway")
+			<-release
+			return &JobResult{StatusCode: 200, ContentType: "text/plain", Body: []byte("done")}, nil
+		})
+		Expect(job.Status).To(Equal(schema.JobStatusRunning))
+		Expect(job.Type).To(Equal("transcription"))
+
+		Eventually(func() string {
+			j, _ := jobs.Get(job.ID, "owner")
+			return j.Message
+		}).Should(Equal("halfway"))
+		_, err := jobs.Result(job.ID, "owner")
+		Expect(err).To(MatchError(ErrJobNotFinished))
+
+		close(release)
+		Eventually(status(job.ID, "owner")).Should(Equal(schema.JobStatusCompleted))
+		j, _ := jobs.Get(job.ID, "owner")
+		Expect(j.Progress).To(Equal(100.0))
+		Expect(j.FinishedAt).ToNot(BeZero())
+		result, err := jobs.Result(job.ID, "owner")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Body).To(Equal([]byte("done")))
+	})
+
+	It("records the errors of the failed jobs", func() {
+		job := jobs.Submit("image_generation", "owner", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			return nil, errors.New("out of memory")
+		})
+		Eventually(status(job.ID, "owner")).Should(Equal(schema.JobStatusFailed))
+		j, _ := jobs.Get(job.ID, "owner")
+		Expect(j.Error).To(Equal("out of memory"))
+		_, err := jobs.Result(job.ID, "owner")
+		Expect(err).To(MatchError(ErrJobNotFinished))
+	})
+
+	It("cancels the context of the cancelled jobs and drops their result", func() {
+		cancelled := make(chan struct{})
+		job := jobs.Submit("transcription", "owner", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			<-ctx.Done()
+			close(cancelled)
+			progress(80, "too late")
+			return &JobResult{StatusCode: 200}, nil
+		})
+
+		j, err := jobs.Cancel(job.ID, "owner")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(j.Status).To(Equal(schema.JobStatusCancelled))
+		Eventually(cancelled).Should(BeClosed())
+
+		Consistently(status(job.ID, "owner"), 100*time.Millisecond).Should(Equal(schema.JobStatusCancelled))
+		j, _ = jobs.Get(job.ID, "owner")
+		Expect(j.Message).To(BeEmpty())
+		_, err = jobs.Cancel(job.ID, "owner")
+		Expect(err).To(MatchError(ErrJobFinished))
+	})
+
+	It("only shows the jobs to their owner", func() {
+		first := jobs.Submit("transcription", "owner", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			return &JobResult{StatusCode: 200}, nil
+		})
+		second := jobs.Submit("translation", "owner", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			return &JobResult{StatusCode: 200}, nil
+		})
+		jobs.Submit("translation", "other", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			return &JobResult{StatusCode: 200}, nil
+		})
+		Eventually(status(first.ID, "owner")).Should(Equal(schema.JobStatusCompleted))
+
+		Expect(jobs.List("owner")).To(HaveLen(2))
+		Expect([]string{jobs.List("owner")[0].ID, jobs.List("owner")[1].ID}).To(ConsistOf(first.ID, second.ID))
+		Expect(jobs.List("other")).To(HaveLen(1))
+		Expect(jobs.List("nobody")).To(BeEmpty())
+
+		_, err := jobs.Get(first.ID, "other")
+		Expect(err).To(MatchError(ErrJobNotFound))
+		_, err = jobs.Result(first.ID, "other")
+		Expect(err).To(MatchError(ErrJobNotFound))
+		_, err = jobs.Cancel(second.ID, "other")
+		Expect(err).To(MatchError(ErrJobNotFound))
+	})
+
+	It("drops the jobs finished for longer than the retention", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		jobs = NewJobService(ctx, 0)
+		job := jobs.Submit("transcription", "owner", func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+			return &JobResult{StatusCode: 200}, nil
+		})
+		Eventually(status(job.ID, "owner")).Should(Equal(schema.JobStatusCompleted))
+
+		Eventually(func() []schema.Job {
+			jobs.Prune()
+			return jobs.List("owner")
+		}, 3*time.Second, 100*time.Millisecond).Should(BeEmpty())
+	})
+})
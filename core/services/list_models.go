@@ -1,9 +1,13 @@
 package services
 
 import (
+	"os"
+	"path/filepath"
 	"regexp"
 
 	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/mudler/LocalAI/core/schema"
 	"github.com/mudler/LocalAI/pkg/model"
 )
 
@@ -55,3 +59,61 @@ func ListModels(bcl *config.BackendConfigLoader, ml *model.ModelLoader, filter s
 
 	return dataModels, nil
 }
+
+// GetModelDetails returns the metadata of a model returned by ListModels
+func GetModelDetails(bcl *config.BackendConfigLoader, ml *model.ModelLoader, name string) *schema.ModelDetails {
+	details := &schema.ModelDetails{}
+	file, loadedAs := name, name
+	if cfg, exists := bcl.GetBackendConfig(name); exists {
+		details.Configured = true
+		details.Backend = cfg.Backend
+		if cfg.ContextSize != nil {
+			details.ContextSize = *cfg.ContextSize
+		}
+		details.Quantization = cfg.Quantization
+		details.Capabilities = cfg.Capabilities()
+		file, loadedAs = cfg.ModelFileName(), cfg.Model
+	}
+	if details.Quantization == "" {
+		details.Quantization = config.GuessQuantization(file)
+	}
+	details.Loaded = loadedAs != "" && ml.IsLoaded(loadedAs)
+	if file != "" {
+		// The models in a directory, as the diffusers ones, have no size
+		if info, err := os.Stat(filepath.Join(ml.ModelPath, file)); err == nil && info.Mode().IsRegular() {
+			details.Size = info.Size()
+		}
+	}
+	if galleryConfig, err := gallery.GetLocalModelConfiguration(ml.ModelPath, name); err == nil {
+		details.Gallery = galleryConfig.Gallery
+	}
+	return details
+}
+
+// GetModelStatus returns the progress of the download and of the loading of a model returned by ListModels.
+// It returns false if the model does not exist
+func GetModelStatus(bcl *config.BackendConfigLoader, ml *model.ModelLoader, name string) (*schema.ModelStatusResponse, bool) {
+	// The models are loaded, and downloaded, by the name of their file
+	keys := []string{name}
+	cfg, configured := bcl.GetBackendConfig(name)
+	if configured {
+		keys = []string{cfg.Model, cfg.ModelFileName()}
+	}
+
+	resp := &schema.ModelStatusResponse{Model: name, State: "not_loaded"}
+	for _, k := range keys {
+		if status, exists := ml.GetLoadStatus(k); exists {
+			resp.State = status.State
+			resp.Stage = status.Stage
+			resp.Progress = status.Progress
+			resp.Error = status.Error
+			resp.WarmupDuration = status.WarmupDuration.Seconds()
+			resp.UpdatedAt = &status.UpdatedAt
+			return resp, true
+		}
+	}
+	if !configured && !ml.ExistsInModelPath(name) {
+		return nil, false
+	}
+	return resp, true
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 
+	"github.com/mudler/LocalAI/pkg/model"
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/prometheus"
@@ -23,6 +24,82 @@ func (m *LocalAIMetricsService) ObserveAPICall(method string, path string, durat
 	m.ApiTimeMetric.Record(context.Background(), duration, opts)
 }
 
+// ObserveCircuits exports the states of the circuits of the circuit breaker: circuit_breaker_open is 1
+// for the models with an open circuit, and circuit_breaker_failures counts their consecutive failures
+func (m *LocalAIMetricsService) ObserveCircuits(circuits *model.CircuitBreaker) error {
+	open, err := m.Meter.Int64ObservableGauge("circuit_breaker_open", metric.WithDescription("models failing fast after repeated failures"))
+	if err != nil {
+		return err
+	}
+	failures, err := m.Meter.Int64ObservableGauge("circuit_breaker_failures", metric.WithDescription("consecutive failures of the models"))
+	if err != nil {
+		return err
+	}
+	_, err = m.Meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, s := range circuits.States() {
+			opts := metric.WithAttributes(attribute.String("model", s.Model), attribute.String("state", s.State))
+			isOpen := int64(0)
+			if s.State != model.CircuitClosed {
+				isOpen = 1
+			}
+			o.ObserveInt64(open, isOpen, opts)
+			o.ObserveInt64(failures, int64(s.ConsecutiveFailures), opts)
+		}
+		return nil
+	}, open, failures)
+	return err
+}
+
+// ObserveLoader exports the statistics of the model loader: model_loaded is 1 for the models loaded,
+// model_last_used_timestamp_seconds and model_load_duration_seconds are the last request and the last load
+// of the models, model_loads and model_evictions count their loads and unloads, and watchdog_kills counts
+// the backends stopped by the watchdog, by model and by reason
+func (m *LocalAIMetricsService) ObserveLoader(ml *model.ModelLoader) error {
+	loaded, err := m.Meter.Int64ObservableGauge("model_loaded", metric.WithDescription("models loaded in memory"))
+	if err != nil {
+		return err
+	}
+	lastUsed, err := m.Meter.Float64ObservableGauge("model_last_used_timestamp_seconds", metric.WithDescription("time of the last request to the models"))
+	if err != nil {
+		return err
+	}
+	loadDuration, err := m.Meter.Float64ObservableGauge("model_load_duration_seconds", metric.WithDescription("time taken by the last load of the models"))
+	if err != nil {
+		return err
+	}
+	loads, err := m.Meter.Int64ObservableCounter("model_loads", metric.WithDescription("loads of the models"))
+	if err != nil {
+		return err
+	}
+	evictions, err := m.Meter.Int64ObservableCounter("model_evictions", metric.WithDescription("unloads of the models"))
+	if err != nil {
+		return err
+	}
+	kills, err := m.Meter.Int64ObservableCounter("watchdog_kills", metric.WithDescription("backends stopped by the watchdog"))
+	if err != nil {
+		return err
+	}
+	_, err = m.Meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, s := range ml.Stats() {
+			opts := metric.WithAttributes(attribute.String("model", s.Model))
+			isLoaded := int64(0)
+			if s.Loaded {
+				isLoaded = 1
+			}
+			o.ObserveInt64(loaded, isLoaded, opts)
+			o.ObserveFloat64(lastUsed, float64(s.LastUsed.UnixMilli())/1000, opts)
+			o.ObserveFloat64(loadDuration, s.LoadDuration.Seconds(), opts)
+			o.ObserveInt64(loads, s.Loads, opts)
+			o.ObserveInt64(evictions, s.Evictions, opts)
+			for reason, count := range s.WatchDogKills {
+				o.ObserveInt64(kills, count, metric.WithAttributes(attribute.String("model", s.Model), attribute.String("reason", reason)))
+			}
+		}
+		return nil
+	}, loaded, lastUsed, loadDuration, loads, evictions, kills)
+	return err
+}
+
 // setupOTelSDK bootstraps the OpenTelemetry pipeline.
 // If it does not return an error, make sure to call shutdown for proper cleanup.
 func NewLocalAIMetricsService() (*LocalAIMetricsService, error) {
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/cron"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// scheduleLookback is how far back the scheduler looks at startup for the last load and unload of the models
+const scheduleLookback = 366 * 24 * time.Hour
+
+type modelSchedule struct {
+	model        string
+	load, unload *cron.Schedule
+}
+
+// ModelScheduler loads the models in memory, and unloads them, at the times of their schedules
+type ModelScheduler struct {
+	bcl       *config.BackendConfigLoader
+	ml        *model.ModelLoader
+	appConfig *config.ApplicationConfig
+	schedules []modelSchedule
+	// load loads a model in memory, as the first request to the model would
+	load func(cfg config.BackendConfig) error
+	now  func() time.Time
+}
+
+func NewModelScheduler(bcl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, load func(cfg config.BackendConfig) error) *ModelScheduler {
+	s := &ModelScheduler{
+		bcl:       bcl,
+		ml:        ml,
+		appConfig: appConfig,
+		load:      load,
+		now:       time.Now,
+	}
+	for _, ms := range appConfig.ModelSchedules {
+		schedule := modelSchedule{model: ms.Model}
+		// The expressions are validated when reading the schedules
+		if ms.Load != "" {
+			schedule.load, _ = cron.Parse(ms.Load)
+		}
+		if ms.Unload != "" {
+			schedule.unload, _ = cron.Parse(ms.Unload)
+		}
+		s.schedules = append(s.schedules, schedule)
+	}
+	return s
+}
+
+// Start loads the models which are within their loading window, then checks the schedules every
+// minute until the application stops
+func (s *ModelScheduler) Start() {
+	if len(s.schedules) == 0 {
+		return
+	}
+	go func() {
+		last := s.now().Truncate(time.Minute)
+		s.loadWithinWindow(last)
+
+		for {
+			next := last.Add(time.Minute)
+			select {
+			case <-s.appConfig.Context.Done():
+				return
+			case <-time.After(time.Until(next)):
+			}
+			// The minutes spent loading a model are checked too
+			now := s.now().Truncate(time.Minute)
+			for m := next; !m.After(now); m = m.Add(time.Minute) {
+				s.check(m)
+			}
+			if now.After(last) {
+				last = now
+			}
+		}
+	}()
+}
+
+// loadWithinWindow loads the models whose last load is more recent than their last unload, as
+// when LocalAI starts during the business hours
+func (s *ModelScheduler) loadWithinWindow(t time.Time) {
+	for _, ms := range s.schedules {
+		if ms.load == nil {
+			continue
+		}
+		lastLoad, loaded := ms.load.Prev(t, scheduleLookback)
+		if !loaded {
+			continue
+		}
+		if ms.unload != nil {
+			if lastUnload, unloaded := ms.unload.Prev(t, scheduleLookback); unloaded && !lastUnload.Before(lastLoad) {
+				continue
+			}
+		}
+		s.loadModel(ms.model)
+	}
+}
+
+// check unloads, then loads, the models scheduled at the minute
+func (s *ModelScheduler) check(t time.Time) {
+	for _, ms := range s.schedules {
+		if ms.unload != nil && ms.unload.Matches(t) {
+			s.unloadModel(ms.model)
+		}
+		if ms.load != nil && ms.load.Matches(t) {
+			s.loadModel(ms.model)
+		}
+	}
+}
+
+func (s *ModelScheduler) loadModel(name string) {
+	cfg, exists := s.bcl.GetBackendConfig(name)
+	if !exists {
+		log.Error().Str("model", name).Msg("[scheduler] cannot load unknown model")
+		return
+	}
+	log.Info().Str("model", name).Msg("[scheduler] loading model")
+	if err := s.load(cfg); err != nil {
+		log.Error().Err(err).Str("model", name).Msg("[scheduler] failed loading model")
+	}
+}
+
+func (s *ModelScheduler) unloadModel(name string) {
+	id := name
+	if cfg, exists := s.bcl.GetBackendConfig(name); exists {
+		id = cfg.Model
+	}
+	if !s.ml.IsLoaded(id) {
+		return
+	}
+	log.Info().Str("model", name).Msg("[scheduler] unloading model")
+	if err := s.ml.ShutdownModel(id); err != nil {
+		log.Error().Err(err).Str("model", name).Msg("[scheduler] failed unloading model")
+	}
+}
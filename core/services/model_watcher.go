@@ -0,0 +1,119 @@
+package services
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ModelFileWatcher reloads the loaded models when their files change, as when a fine-tuning pipeline
+// writes new weights. A model is reloaded once its file stays unchanged for the reload delay, so that
+// the files being written are not loaded
+type ModelFileWatcher struct {
+	bcl       *config.BackendConfigLoader
+	ml        *model.ModelLoader
+	appConfig *config.ApplicationConfig
+	// reload loads the model again, swapping its backend once the new one is ready
+	reload func(cfg config.BackendConfig) error
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+func NewModelFileWatcher(bcl *config.BackendConfigLoader, ml *model.ModelLoader, appConfig *config.ApplicationConfig, reload func(cfg config.BackendConfig) error) *ModelFileWatcher {
+	return &ModelFileWatcher{
+		bcl:       bcl,
+		ml:        ml,
+		appConfig: appConfig,
+		reload:    reload,
+		pending:   map[string]*time.Timer{},
+	}
+}
+
+// Start watches the models directory, and the directories of the files of the models, until the
+// application stops
+func (w *ModelFileWatcher) Start() error {
+	if w.appConfig.ModelReloadDelay <= 0 {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{filepath.Clean(w.appConfig.ModelPath): true}
+	for _, cfg := range w.bcl.GetAllBackendConfigs() {
+		if cfg.Model != "" {
+			dirs[filepath.Dir(w.modelFile(cfg))] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			log.Warn().Err(err).Str("dir", dir).Msg("[reload] cannot watch the directory of the models")
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-w.appConfig.Context.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// The files replaced by a rename are created
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+					w.changed(filepath.Clean(event.Name))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error().Err(err).Msg("[reload] model files watcher error received")
+			}
+		}
+	}()
+	return nil
+}
+
+func (w *ModelFileWatcher) modelFile(cfg config.BackendConfig) string {
+	return filepath.Join(w.appConfig.ModelPath, cfg.Model)
+}
+
+// changed delays the reload of the models of the file until it stays unchanged for the reload delay
+func (w *ModelFileWatcher) changed(file string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if timer, exists := w.pending[file]; exists {
+		timer.Reset(w.appConfig.ModelReloadDelay)
+		return
+	}
+	w.pending[file] = time.AfterFunc(w.appConfig.ModelReloadDelay, func() {
+		w.mu.Lock()
+		delete(w.pending, file)
+		w.mu.Unlock()
+		w.reloadFile(file)
+	})
+}
+
+// reloadFile reloads the loaded models of the file, once even when several configurations share it
+func (w *ModelFileWatcher) reloadFile(file string) {
+	reloaded := map[string]bool{}
+	for _, cfg := range w.bcl.GetAllBackendConfigs() {
+		if cfg.Model == "" || reloaded[cfg.Model] || w.modelFile(cfg) != file || !w.ml.IsLoaded(cfg.Model) {
+			continue
+		}
+		reloaded[cfg.Model] = true
+		log.Info().Str("model", cfg.Name).Str("file", file).Msg("[reload] model file changed, reloading the model")
+		if err := w.reload(cfg); err != nil {
+			log.Error().Err(err).Str("model", cfg.Name).Msg("[reload] failed reloading the model, the previous backend keeps serving it")
+		}
+	}
+}
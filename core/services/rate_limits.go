@@ -0,0 +1,42 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimits counts the requests of each key during the current minute, to enforce their
+// limit of requests per minute
+type RateLimits struct {
+	minute int64
+	counts map[string]int
+	now    func() time.Time
+	sync.Mutex
+}
+
+func NewRateLimits() *RateLimits {
+	return &RateLimits{
+		counts: map[string]int{},
+		now:    time.Now,
+	}
+}
+
+// Allow counts a request of the key, and tells if it is within the limit. A limit of 0 allows all the requests
+func (r *RateLimits) Allow(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return true
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	minute := r.now().Unix() / 60
+	if minute != r.minute {
+		r.minute = minute
+		r.counts = map[string]int{}
+	}
+	if r.counts[key] >= perMinute {
+		return false
+	}
+	r.counts[key]++
+	return true
+}
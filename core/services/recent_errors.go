@@ -0,0 +1,39 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/mudler/LocalAI/core/schema"
+)
+
+// RecentErrors keeps the last errors returned by the API, shown in the dashboard
+type RecentErrors struct {
+	size   int
+	errors []schema.RecentError
+	sync.Mutex
+}
+
+func NewRecentErrors(size int) *RecentErrors {
+	return &RecentErrors{size: size}
+}
+
+// Record keeps the error, dropping the oldest one once there are more than the size
+func (r *RecentErrors) Record(e schema.RecentError) {
+	r.Lock()
+	defer r.Unlock()
+	r.errors = append(r.errors, e)
+	if len(r.errors) > r.size {
+		r.errors = r.errors[len(r.errors)-r.size:]
+	}
+}
+
+// List returns the errors kept, the most recent first
+func (r *RecentErrors) List() []schema.RecentError {
+	r.Lock()
+	defer r.Unlock()
+	res := make([]schema.RecentError, 0, len(r.errors))
+	for i := len(r.errors) - 1; i >= 0; i-- {
+		res = append(res, r.errors[i])
+	}
+	return res
+}
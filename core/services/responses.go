@@ -0,0 +1,118 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/utils"
+)
+
+var ErrResponseNotFound = errors.New("response not found")
+
+// StoredResponse is a response of the Responses API as persisted on disk,
+// alongside the conversation that led to it so it can be chained by later requests.
+// Only its owner can retrieve, delete or chain it
+type StoredResponse struct {
+	Response schema.Response  `json:"response"`
+	Messages []schema.Message `json:"messages"`
+	Owner    string           `json:"owner"`
+}
+
+// ResponseStore persists the responses of the Responses API on disk, one JSON file per response.
+type ResponseStore struct {
+	path string
+	sync.Mutex
+}
+
+func NewResponseStore(path string) *ResponseStore {
+	return &ResponseStore{path: path}
+}
+
+func (rs *ResponseStore) file(id string) (string, error) {
+	name := utils.SanitizeFileName(id) + ".json"
+	if err := utils.VerifyPath(name, rs.path); err != nil {
+		return "", err
+	}
+	return filepath.Join(rs.path, name), nil
+}
+
+func (rs *ResponseStore) Save(r StoredResponse) error {
+	rs.Lock()
+	defer rs.Unlock()
+
+	if err := os.MkdirAll(rs.path, 0750); err != nil {
+		return fmt.Errorf("failed creating responses directory: %w", err)
+	}
+
+	f, err := rs.file(r.Response.ID)
+	if err != nil {
+		return err
+	}
+
+	dat, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, dat, 0600)
+}
+
+// Get returns the response of the owner
+func (rs *ResponseStore) Get(id, owner string) (*StoredResponse, error) {
+	rs.Lock()
+	defer rs.Unlock()
+	return rs.get(id, owner)
+}
+
+// get returns the response of the owner. Called with the lock held
+func (rs *ResponseStore) get(id, owner string) (*StoredResponse, error) {
+	f, err := rs.file(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dat, err := os.ReadFile(f)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrResponseNotFound
+		}
+		return nil, err
+	}
+
+	r := &StoredResponse{}
+	if err := json.Unmarshal(dat, r); err != nil {
+		return nil, err
+	}
+	if r.Owner != owner {
+		return nil, ErrResponseNotFound
+	}
+	return r, nil
+}
+
+// Delete deletes the response of the owner
+func (rs *ResponseStore) Delete(id, owner string) error {
+	rs.Lock()
+	defer rs.Unlock()
+
+	if _, err := rs.get(id, owner); err != nil {
+		return err
+	}
+
+	f, err := rs.file(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(f); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrResponseNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/pkg/retention"
+	"github.com/rs/zerolog/log"
+)
+
+// StartRetention periodically deletes the generated images and audio out of their retention, and the
+// finished jobs expired, until the application stops. The uploads are deleted with their metadata by
+// the files API
+func StartRetention(appConfig *config.ApplicationConfig, jobs *JobService, interval time.Duration) {
+	// the cloned voices are kept in the audio directory
+	voices := filepath.Join(appConfig.AudioDir, "voices")
+	dirs := []struct {
+		dir    string
+		policy retention.Policy
+		keep   func(string) bool
+	}{
+		{appConfig.ImageDir, retention.Policy{MaxAge: appConfig.GeneratedRetention, MaxSize: megabytes(appConfig.ImageDirMaxSizeMB)}, nil},
+		{appConfig.AudioDir, retention.Policy{MaxAge: appConfig.GeneratedRetention, MaxSize: megabytes(appConfig.AudioDirMaxSizeMB)}, func(path string) bool { return path == voices }},
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-appConfig.Context.Done():
+				return
+			case <-ticker.C:
+			}
+			jobs.Prune()
+			for _, d := range dirs {
+				if d.dir == "" || !d.policy.Enabled() {
+					continue
+				}
+				result, err := retention.Clean(d.dir, d.policy, time.Now(), d.keep)
+				if err != nil {
+					log.Error().Err(err).Str("dir", d.dir).Msg("[retention] failed deleting the generated files")
+				}
+				if result.Files > 0 {
+					log.Debug().Str("dir", d.dir).Int("files", result.Files).Int64("bytes", result.Bytes).Msg("[retention] deleted the generated files")
+				}
+			}
+		}
+	}()
+}
+
+func megabytes(mb int) int64 {
+	return int64(mb) * 1024 * 1024
+}
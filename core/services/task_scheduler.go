@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/schema"
+	"github.com/mudler/LocalAI/pkg/agent"
+	"github.com/mudler/LocalAI/pkg/cron"
+	"github.com/mudler/LocalAI/pkg/events"
+	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrTaskExists    = errors.New("a task with this name already exists")
+	ErrTaskReadOnly  = errors.New("the tasks of the tasks file cannot be deleted")
+	ErrTaskForbidden = errors.New("the task is not allowed")
+	ErrTaskQuota     = errors.New("daily token quota exhausted")
+)
+
+// TasksConfigFile holds the tasks created with the API, in the configuration directory
+const TasksConfigFile = "tasks.json"
+
+// taskLookahead is how far the scheduler looks for the next run of the tasks
+const taskLookahead = 366 * 24 * time.Hour
+
+// TaskFunc runs the prompt or the pipeline of a task, and returns its output and the tokens it used
+type TaskFunc func(ctx context.Context, task config.ScheduledTask) (string, int, error)
+
+// taskKey identifies a task: the names are unique per owner. The tasks of the tasks file have no owner
+type taskKey struct {
+	owner, name string
+}
+
+// storedTask is a task created with the API, with the owner who created it
+type storedTask struct {
+	Task  config.ScheduledTask `json:"task"`
+	Owner string               `json:"owner"`
+}
+
+type scheduledTask struct {
+	config.ScheduledTask
+	schedule *cron.Schedule
+	// owner is the API key, or the tenant, which created the task, and owns the jobs of its runs. The
+	// tasks of the tasks file have no owner, and are visible to everyone
+	owner    string
+	fromFile bool
+	// lastJob is the job of the last run, owned by lastJobOwner
+	lastJob, lastJobOwner string
+}
+
+// TaskScheduler runs the scheduled tasks at the times of their schedules, as jobs of the job service, and
+// delivers their output to their webhook or their file
+type TaskScheduler struct {
+	appConfig *config.ApplicationConfig
+	jobs      *JobService
+	quotas    *TokenQuotas
+	run       TaskFunc
+	now       func() time.Time
+
+	sync.Mutex
+	tasks map[taskKey]*scheduledTask
+}
+
+// NewTaskScheduler schedules the tasks of the tasks file, and the ones created with the API, saved in the
+// configuration directory. The tokens used by the runs count in the quotas of their owner
+func NewTaskScheduler(appConfig *config.ApplicationConfig, jobs *JobService, quotas *TokenQuotas, run TaskFunc) *TaskScheduler {
+	s := &TaskScheduler{
+		appConfig: appConfig,
+		jobs:      jobs,
+		quotas:    quotas,
+		run:       run,
+		now:       time.Now,
+		tasks:     map[taskKey]*scheduledTask{},
+	}
+	for name, t := range appConfig.Tasks {
+		// The schedules are validated when reading the tasks
+		schedule, _ := cron.Parse(t.Schedule)
+		s.tasks[taskKey{name: name}] = &scheduledTask{ScheduledTask: t, schedule: schedule, fromFile: true}
+	}
+
+	stored := []storedTask{}
+	utils.LoadConfig(appConfig.ConfigsDir, TasksConfigFile, &stored)
+	for _, st := range stored {
+		if _, exists := s.tasks[taskKey{name: st.Task.Name}]; exists {
+			log.Warn().Str("task", st.Task.Name).Msg("[tasks] the task of the tasks file replaces the one created with the API")
+			continue
+		}
+		if err := st.Task.Validate(appConfig.Pipelines); err != nil {
+			log.Error().Err(err).Str("task", st.Task.Name).Msg("[tasks] skipping invalid task")
+			continue
+		}
+		schedule, _ := cron.Parse(st.Task.Schedule)
+		s.tasks[taskKey{st.Owner, st.Task.Name}] = &scheduledTask{ScheduledTask: st.Task, schedule: schedule, owner: st.Owner}
+	}
+	return s
+}
+
+// Start runs the tasks scheduled at each minute, until the application stops
+func (s *TaskScheduler) Start() {
+	go func() {
+		last := s.now().Truncate(time.Minute)
+		for {
+			next := last.Add(time.Minute)
+			select {
+			case <-s.appConfig.Context.Done():
+				return
+			case <-time.After(time.Until(next)):
+			}
+			// The minutes missed, as when the machine was suspended, are checked too
+			now := s.now().Truncate(time.Minute)
+			for m := next; !m.After(now); m = m.Add(time.Minute) {
+				s.check(m)
+			}
+			if now.After(last) {
+				last = now
+			}
+		}
+	}()
+}
+
+// check runs the tasks scheduled at the minute
+func (s *TaskScheduler) check(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	for _, task := range s.tasks {
+		if task.schedule.Matches(t) {
+			log.Info().Str("task", task.Name).Msg("[tasks] running task")
+			s.submit(task, task.owner)
+		}
+	}
+}
+
+// List returns the tasks visible to the owner: the ones it created, and the ones of the tasks file
+func (s *TaskScheduler) List(owner string) []schema.ScheduledTask {
+	s.Lock()
+	defer s.Unlock()
+	tasks := []schema.ScheduledTask{}
+	for _, t := range s.tasks {
+		if t.fromFile || t.owner == owner {
+			tasks = append(tasks, s.status(t))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks
+}
+
+// Get returns a task visible to the owner
+func (s *TaskScheduler) Get(name, owner string) (schema.ScheduledTask, error) {
+	s.Lock()
+	defer s.Unlock()
+	t, err := s.task(name, owner)
+	if err != nil {
+		return schema.ScheduledTask{}, err
+	}
+	return s.status(t), nil
+}
+
+// Add schedules a task created by the owner, and saves it in the configuration directory. The tasks of the
+// tenants can only use their models, and post their output to the hosts of the webhook allowlist
+func (s *TaskScheduler) Add(task config.ScheduledTask, owner string) (schema.ScheduledTask, error) {
+	if err := task.Validate(s.appConfig.Pipelines); err != nil {
+		return schema.ScheduledTask{}, err
+	}
+	if err := s.authorize(task, owner); err != nil {
+		return schema.ScheduledTask{}, err
+	}
+	schedule, _ := cron.Parse(task.Schedule)
+
+	s.Lock()
+	defer s.Unlock()
+	// the names of the tasks of the tasks file are visible to everyone, the ones of the other owners are not
+	if _, exists := s.tasks[taskKey{name: task.Name}]; exists {
+		return schema.ScheduledTask{}, ErrTaskExists
+	}
+	if _, exists := s.tasks[taskKey{owner, task.Name}]; exists {
+		return schema.ScheduledTask{}, ErrTaskExists
+	}
+	t := &scheduledTask{ScheduledTask: task, schedule: schedule, owner: owner}
+	s.tasks[taskKey{owner, task.Name}] = t
+	s.save()
+	return s.status(t), nil
+}
+
+// Delete unschedules a task created by the owner. Its running job is not cancelled
+func (s *TaskScheduler) Delete(name, owner string) error {
+	s.Lock()
+	defer s.Unlock()
+	t, err := s.task(name, owner)
+	if err != nil {
+		return err
+	}
+	if t.fromFile {
+		return ErrTaskReadOnly
+	}
+	delete(s.tasks, taskKey{owner, name})
+	s.save()
+	return nil
+}
+
+// Run runs a task visible to the owner right away, as a job of the owner
+func (s *TaskScheduler) Run(name, owner string) (schema.Job, error) {
+	s.Lock()
+	defer s.Unlock()
+	t, err := s.task(name, owner)
+	if err != nil {
+		return schema.Job{}, err
+	}
+	return s.submit(t, owner), nil
+}
+
+// task returns a task visible to the owner. Called with the lock held
+func (s *TaskScheduler) task(name, owner string) (*scheduledTask, error) {
+	if t, exists := s.tasks[taskKey{name: name}]; exists && t.fromFile {
+		return t, nil
+	}
+	if t, exists := s.tasks[taskKey{owner, name}]; exists {
+		return t, nil
+	}
+	return nil, ErrTaskNotFound
+}
+
+// authorize checks that the owner can run the task: the tenants only use their models, and only post
+// to the hosts of the webhook allowlist. The tasks of the tasks file and of the API keys of the instance
+// are not restricted
+func (s *TaskScheduler) authorize(task config.ScheduledTask, owner string) error {
+	tenant := s.appConfig.TenantByOwner(owner)
+	if tenant == nil {
+		return nil
+	}
+	models := []string{task.Model}
+	if task.Pipeline != "" {
+		models = nil
+		for _, step := range s.appConfig.Pipelines[task.Pipeline].Steps {
+			models = append(models, step.Model)
+		}
+	}
+	for _, m := range models {
+		if !tenant.CanUseModel(m) {
+			return fmt.Errorf("%w: model %s not found", ErrTaskForbidden, m)
+		}
+	}
+	if !s.webhookAllowed(task, owner) {
+		return fmt.Errorf("%w: the host of the webhook %s is not in the webhook allowlist", ErrTaskForbidden, task.Webhook)
+	}
+	return nil
+}
+
+// webhookAllowed reports whether the task of the owner can post to its webhook
+func (s *TaskScheduler) webhookAllowed(task config.ScheduledTask, owner string) bool {
+	if task.Webhook == "" || s.appConfig.TenantByOwner(owner) == nil {
+		return true
+	}
+	u, err := url.Parse(task.Webhook)
+	return err == nil && (agent.Fetcher{Allowlist: s.appConfig.TaskWebhookAllowlist}).Allowed(u)
+}
+
+// checkQuota fails when the owner used the tokens of its daily quota
+func (s *TaskScheduler) checkQuota(owner string) error {
+	limit := s.appConfig.APIKeyDailyTokens
+	if tenant := s.appConfig.TenantByOwner(owner); tenant != nil {
+		limit = tenant.DailyTokens
+	}
+	if owner != "" && s.quotas.Exhausted(owner, limit) {
+		return ErrTaskQuota
+	}
+	return nil
+}
+
+// status returns the task with its next run and its last job. Called with the lock held
+func (s *TaskScheduler) status(t *scheduledTask) schema.ScheduledTask {
+	st := schema.ScheduledTask{
+		Name:     t.Name,
+		Schedule: t.Schedule,
+		Model:    t.Model,
+		System:   t.System,
+		Prompt:   t.Prompt,
+		Pipeline: t.Pipeline,
+		Input:    t.Input,
+		Webhook:  t.Webhook,
+		File:     t.File,
+		Source:   "api",
+	}
+	if t.fromFile {
+		st.Source = "file"
+	}
+	if next, ok := t.schedule.Next(s.now(), taskLookahead); ok {
+		st.NextRun = next.Unix()
+	}
+	if t.lastJob != "" {
+		if job, err := s.jobs.Get(t.lastJob, t.lastJobOwner); err == nil {
+			st.LastJob = &job
+		}
+	}
+	return st
+}
+
+// save writes the tasks created with the API to the configuration directory. Called with the lock held
+func (s *TaskScheduler) save() {
+	stored := []storedTask{}
+	for _, t := range s.tasks {
+		if !t.fromFile {
+			stored = append(stored, storedTask{Task: t.ScheduledTask, Owner: t.owner})
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].Owner+"/"+stored[i].Task.Name < stored[j].Owner+"/"+stored[j].Task.Name
+	})
+	utils.SaveConfig(s.appConfig.ConfigsDir, TasksConfigFile, stored)
+}
+
+// submit runs the task as a job of the owner. Called with the lock held
+func (s *TaskScheduler) submit(t *scheduledTask, owner string) schema.Job {
+	task := t.ScheduledTask
+	// the ID of the job is delivered with the output, and is known once the job is submitted
+	jobID := make(chan string, 1)
+	job := s.jobs.Submit("task", owner, func(ctx context.Context, progress func(float64, string)) (*JobResult, error) {
+		id := <-jobID
+		progress(0, fmt.Sprintf("running task %s", task.Name))
+		started := s.now()
+		// the models and the quota are checked again, as the tenants may have changed since the task was added
+		err := s.authorize(task, owner)
+		if err == nil {
+			err = s.checkQuota(owner)
+		}
+		var output string
+		if err == nil {
+			var tokens int
+			output, tokens, err = s.run(ctx, task)
+			if owner != "" {
+				s.quotas.Add(owner, tokens)
+			}
+		}
+		if err != nil {
+			s.publish(task, owner, events.TaskFailed, map[string]any{"task": task.Name, "job": id, "error": err.Error()})
+			return nil, err
+		}
+
+		result := schema.TaskResult{Task: task.Name, Output: output, StartedAt: started.Unix()}
+		if task.File != "" {
+			file, err := s.write(task, owner, started, output)
+			if err != nil {
+				err = fmt.Errorf("writing the output to the file: %w", err)
+				s.publish(task, owner, events.TaskFailed, map[string]any{"task": task.Name, "job": id, "error": err.Error()})
+				return nil, err
+			}
+			result.File = file
+		}
+		result.FinishedAt = s.now().Unix()
+
+		data := map[string]any{"task": task.Name, "job": id, "output": output}
+		if result.File != "" {
+			data["file"] = result.File
+		}
+		if err := s.publish(task, owner, events.TaskCompleted, data); err != nil {
+			return nil, fmt.Errorf("posting the output to the webhook: %w", err)
+		}
+
+		body, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		return &JobResult{StatusCode: 200, ContentType: "application/json", Body: body}, nil
+	})
+	jobID <- job.ID
+	t.lastJob, t.lastJobOwner = job.ID, owner
+	return job
+}
+
+// publish sends the event of a run to the webhook of the task, and to the sinks of the events
+func (s *TaskScheduler) publish(task config.ScheduledTask, owner, eventType string, data map[string]any) error {
+	s.appConfig.Events.Publish(eventType, data)
+	if task.Webhook == "" || !s.webhookAllowed(task, owner) {
+		return nil
+	}
+	err := events.NewWebhookSink(task.Webhook).Send(events.Event{Type: eventType, Time: s.now(), Data: data})
+	if err != nil {
+		log.Error().Err(err).Str("task", task.Name).Msg("[tasks] failed posting to the webhook")
+	}
+	return err
+}
+
+// write writes the output of a run to the file of the task, in the tasks directory, and returns its path.
+// The files of the tenants are written in their own directory
+func (s *TaskScheduler) write(task config.ScheduledTask, owner string, t time.Time, output string) (string, error) {
+	tmpl, err := template.New(task.Name).Parse(task.File)
+	if err != nil {
+		return "", err
+	}
+	var name strings.Builder
+	if err := tmpl.Execute(&name, struct {
+		Name string
+		Time time.Time
+	}{task.Name, t}); err != nil {
+		return "", err
+	}
+	dir := s.appConfig.TasksDir
+	if tenant := s.appConfig.TenantByOwner(owner); tenant != nil {
+		dir = filepath.Join(dir, tenant.Name)
+	}
+	if err := utils.VerifyPath(name.String(), dir); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name.String())
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenQuotas counts the tokens used during the day by each API key and each tenant, to
+// enforce their daily quota and to report their usage. The days are in UTC, and the counts are
+// kept in memory: they start again from zero when LocalAI restarts
+type TokenQuotas struct {
+	day  string
+	used map[string]int
+	now  func() time.Time
+	sync.Mutex
+}
+
+func NewTokenQuotas() *TokenQuotas {
+	return &TokenQuotas{
+		used: map[string]int{},
+		now:  time.Now,
+	}
+}
+
+// reset starts counting again when the day changes. Called with the lock held
+func (q *TokenQuotas) reset() {
+	day := q.now().UTC().Format(time.DateOnly)
+	if day != q.day {
+		q.day = day
+		q.used = map[string]int{}
+	}
+}
+
+// Exhausted tells if the key used all the tokens of its daily limit. A limit of 0 disables the quota
+func (q *TokenQuotas) Exhausted(key string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	return q.Used(key) >= limit
+}
+
+// Add counts the tokens used by a request of the key
+func (q *TokenQuotas) Add(key string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.reset()
+	q.used[key] += tokens
+}
+
+// Used returns the tokens used by the key during the day
+func (q *TokenQuotas) Used(key string) int {
+	q.Lock()
+	defer q.Unlock()
+	q.reset()
+	return q.used[key]
+}
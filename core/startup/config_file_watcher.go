@@ -1,16 +1,21 @@
 package startup
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"dario.cat/mergo"
 	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
+	"github.com/mudler/LocalAI/pkg/logging"
+	"github.com/mudler/LocalAI/pkg/secrets"
 	"github.com/rs/zerolog/log"
 )
 
@@ -39,6 +44,14 @@ func newConfigFileHandler(appConfig *config.ApplicationConfig) configFileHandler
 	if err != nil {
 		log.Error().Err(err).Str("file", "external_backends.json").Msg("unable to register config file handler")
 	}
+	err = c.Register("galleries.json", readGalleriesJson(*appConfig), true)
+	if err != nil {
+		log.Error().Err(err).Str("file", "galleries.json").Msg("unable to register config file handler")
+	}
+	err = c.Register("log_level.json", readLogLevelJson(), true)
+	if err != nil {
+		log.Error().Err(err).Str("file", "log_level.json").Msg("unable to register config file handler")
+	}
 	return c
 }
 
@@ -142,6 +155,11 @@ func readApiKeysJson(startupAppConfig config.ApplicationConfig) fileHandler {
 
 			log.Trace().Int("numKeys", len(fileKeys)).Msg("discovered API keys from api keys dynamic config dile")
 
+			fileKeys, err = secrets.ResolveAll(context.Background(), fileKeys)
+			if err != nil {
+				return err
+			}
+
 			appConfig.ApiKeys = append(startupAppConfig.ApiKeys, fileKeys...)
 		} else {
 			log.Trace().Msg("no API keys discovered from dynamic config file")
@@ -154,6 +172,37 @@ func readApiKeysJson(startupAppConfig config.ApplicationConfig) fileHandler {
 	return handler
 }
 
+// readLogLevelJson applies the settings of the logs of the file over the ones of the startup, which
+// are restored when the file is emptied
+func readLogLevelJson() fileHandler {
+	startup := logging.Current()
+	handler := func(fileContent []byte, appConfig *config.ApplicationConfig) error {
+		log.Debug().Msg("processing log_level.json")
+
+		settings := logging.Settings{Level: startup.Level, Format: startup.Format, Subsystems: map[string]string{}}
+		for _, s := range logging.Subsystems {
+			settings.Subsystems[s] = startup.Subsystems[s]
+		}
+		if len(fileContent) > 0 {
+			var fileSettings logging.Settings
+			if err := json.Unmarshal(fileContent, &fileSettings); err != nil {
+				return err
+			}
+			if fileSettings.Level != "" {
+				settings.Level = fileSettings.Level
+			}
+			if fileSettings.Format != "" {
+				settings.Format = fileSettings.Format
+			}
+			for s, level := range fileSettings.Subsystems {
+				settings.Subsystems[s] = level
+			}
+		}
+		return logging.Apply(settings)
+	}
+	return handler
+}
+
 func readExternalBackendsJson(startupAppConfig config.ApplicationConfig) fileHandler {
 	handler := func(fileContent []byte, appConfig *config.ApplicationConfig) error {
 		log.Debug().Msg("processing external_backends.json")
@@ -173,8 +222,69 @@ func readExternalBackendsJson(startupAppConfig config.ApplicationConfig) fileHan
 		} else {
 			appConfig.ExternalGRPCBackends = startupAppConfig.ExternalGRPCBackends
 		}
+		if appConfig.DisablePythonBackends {
+			for name, uri := range appConfig.ExternalGRPCBackends {
+				if config.IsPythonBackend(uri) {
+					log.Debug().Str("backend", name).Msgf("Python backend not registered in the %s profile", appConfig.Profile)
+					delete(appConfig.ExternalGRPCBackends, name)
+				}
+			}
+		}
+		if appConfig.AssetsDestination != "" {
+			// keep the backends installed at runtime
+			registerInstalledBackends(appConfig)
+		}
 		log.Debug().Msg("external backends loaded from external_backends.json")
 		return nil
 	}
 	return handler
 }
+
+// readGalleriesJson adds the galleries of the file to the ones of the startup, replacing the galleries of
+// the same name. The indexes of the galleries added are fetched in the background, to report the broken
+// URLs right away
+func readGalleriesJson(startupAppConfig config.ApplicationConfig) fileHandler {
+	handler := func(fileContent []byte, appConfig *config.ApplicationConfig) error {
+		log.Debug().Msg("processing galleries.json")
+
+		galleries := slices.Clone(startupAppConfig.Galleries)
+		if len(fileContent) > 0 {
+			var fileGalleries []config.Gallery
+			if err := json.Unmarshal(fileContent, &fileGalleries); err != nil {
+				return err
+			}
+			for _, g := range fileGalleries {
+				if g.Name == "" || g.URL == "" {
+					return fmt.Errorf("the galleries of galleries.json need a name and a URL")
+				}
+				i := slices.IndexFunc(galleries, func(existing config.Gallery) bool { return existing.Name == g.Name })
+				if i >= 0 {
+					galleries[i] = g
+				} else {
+					galleries = append(galleries, g)
+				}
+			}
+		}
+		if slices.Equal(galleries, appConfig.Galleries) {
+			return nil
+		}
+
+		for _, g := range galleries {
+			if slices.Contains(appConfig.Galleries, g) {
+				continue
+			}
+			go func() {
+				models, err := gallery.AvailableGalleryModels([]config.Gallery{g}, appConfig.ModelPath)
+				if err != nil {
+					log.Error().Err(err).Str("gallery", g.Name).Msg("unable to fetch the index of the gallery")
+					return
+				}
+				log.Info().Str("gallery", g.Name).Int("models", len(models)).Msg("gallery added")
+			}()
+		}
+		appConfig.Galleries = galleries
+		log.Debug().Int("galleries", len(galleries)).Msg("galleries loaded from galleries.json")
+		return nil
+	}
+	return handler
+}
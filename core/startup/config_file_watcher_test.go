@@ -0,0 +1,78 @@
+package startup
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/core/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("galleries.json", func() {
+	var (
+		dir       string
+		localai   config.Gallery
+		startup   config.ApplicationConfig
+		appConfig *config.ApplicationConfig
+	)
+
+	gallery := func(name string) config.Gallery {
+		index := filepath.Join(dir, name+".yaml")
+		Expect(os.WriteFile(index, []byte("[]"), 0600)).To(Succeed())
+		return config.Gallery{Name: name, URL: "file://" + index}
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		localai = gallery("localai")
+		startup = config.ApplicationConfig{
+			ModelPath:         dir,
+			DynamicConfigsDir: dir,
+			Galleries:         []config.Gallery{localai},
+		}
+		appConfig = &config.ApplicationConfig{
+			ModelPath:         dir,
+			DynamicConfigsDir: dir,
+			Galleries:         []config.Gallery{localai},
+		}
+	})
+
+	It("adds the galleries of the file to the startup ones and replaces them by name", func() {
+		mirror := config.Gallery{Name: "localai", URL: "file://" + filepath.Join(dir, "mirror.yaml")}
+		team := gallery("team")
+		handler := readGalleriesJson(startup)
+
+		Expect(handler([]byte(`[{"name":"localai","url":"`+mirror.URL+`"},{"name":"team","url":"`+team.URL+`"}]`), appConfig)).To(Succeed())
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{mirror, team}))
+		Expect(startup.Galleries).To(Equal([]config.Gallery{localai}))
+	})
+
+	It("restores the startup galleries when the file is emptied or removed", func() {
+		team := gallery("team")
+		handler := readGalleriesJson(startup)
+
+		Expect(handler([]byte(`[{"name":"team","url":"`+team.URL+`"}]`), appConfig)).To(Succeed())
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{localai, team}))
+
+		Expect(handler(nil, appConfig)).To(Succeed())
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{localai}))
+	})
+
+	It("keeps the galleries when the file is invalid", func() {
+		handler := readGalleriesJson(startup)
+
+		Expect(handler([]byte(`[{"name":"team"}]`), appConfig)).To(MatchError("the galleries of galleries.json need a name and a URL"))
+		Expect(handler([]byte(`{"name":`), appConfig)).ToNot(Succeed())
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{localai}))
+	})
+
+	It("loads the file of the dynamic configs directory on registration", func() {
+		team := gallery("team")
+		Expect(os.WriteFile(filepath.Join(dir, "galleries.json"), []byte(`[{"name":"team","url":"`+team.URL+`"}]`), 0600)).To(Succeed())
+
+		newConfigFileHandler(appConfig)
+		Expect(appConfig.Galleries).To(Equal([]config.Gallery{localai, team}))
+	})
+})
@@ -5,10 +5,15 @@ import (
 	"os"
 
 	"github.com/mudler/LocalAI/core"
+	"github.com/mudler/LocalAI/core/backend"
 	"github.com/mudler/LocalAI/core/config"
+	"github.com/mudler/LocalAI/core/gallery"
 	"github.com/mudler/LocalAI/core/services"
 	"github.com/mudler/LocalAI/internal"
 	"github.com/mudler/LocalAI/pkg/assets"
+	"github.com/mudler/LocalAI/pkg/downloader"
+	"github.com/mudler/LocalAI/pkg/events"
+	"github.com/mudler/LocalAI/pkg/grpc"
 	"github.com/mudler/LocalAI/pkg/library"
 	"github.com/mudler/LocalAI/pkg/model"
 	pkgStartup "github.com/mudler/LocalAI/pkg/startup"
@@ -60,6 +65,9 @@ func Startup(opts ...config.AppOption) (*config.BackendConfigLoader, *model.Mode
 		}
 	}
 
+	downloader.SetIndexCache(options.GalleryCacheDir, options.GalleryOffline)
+	gallery.SetLicenseGate(options.GatedLicenses, options.AcceptedLicenses, options.ConfigsDir)
+
 	if err := pkgStartup.InstallModels(options.Galleries, options.ModelLibraryURL, options.ModelPath, options.EnforcePredownloadScans, nil, options.ModelsURL...); err != nil {
 		log.Error().Err(err).Msg("error installing models")
 	}
@@ -67,18 +75,50 @@ func Startup(opts ...config.AppOption) (*config.BackendConfigLoader, *model.Mode
 	cl := config.NewBackendConfigLoader(options.ModelPath)
 	ml := model.NewModelLoader(options.ModelPath)
 
+	// Publish the events of the models and of the galleries to the webhooks and the brokers
+	if options.EventSinks.Enabled() {
+		options.Events = newEventBus(options.EventSinks)
+		ml.SetEvents(options.Events)
+		go func() {
+			<-options.Context.Done()
+			if err := options.Events.Close(); err != nil {
+				log.Error().Err(err).Msg("error while closing the event sinks")
+			}
+		}()
+	}
+
 	configLoaderOpts := options.ToConfigLoaderOptions()
 
 	if err := cl.LoadBackendConfigsFromPath(options.ModelPath, configLoaderOpts...); err != nil {
+		if options.StrictConfig {
+			return nil, nil, nil, fmt.Errorf("invalid configurations of the models: %w", err)
+		}
 		log.Error().Err(err).Msg("error loading config files")
 	}
 
 	if options.ConfigFile != "" {
 		if err := cl.LoadMultipleBackendConfigsSingleFile(options.ConfigFile, configLoaderOpts...); err != nil {
+			if options.StrictConfig {
+				return nil, nil, nil, fmt.Errorf("invalid configuration file %s: %w", options.ConfigFile, err)
+			}
 			log.Error().Err(err).Msg("error loading config file")
 		}
 	}
 
+	if options.PresetsFile != "" {
+		presets, err := config.ReadPresetsFile(options.PresetsFile)
+		if err != nil {
+			log.Error().Err(err).Msg("error loading presets file")
+		} else {
+			cl.SetPresets(presets)
+		}
+	}
+
+	// The downloads of the models are reported in their loading status
+	cl.SetPreloadProgress(func(model string, percent float64) {
+		ml.ReportLoadProgress(model, grpc.LoadStageDownload, percent)
+	})
+
 	if err := cl.Preload(options.ModelPath); err != nil {
 		log.Error().Err(err).Msg("error downloading models")
 	}
@@ -108,6 +148,8 @@ func Startup(opts ...config.AppOption) (*config.BackendConfigLoader, *model.Mode
 		if err != nil {
 			log.Warn().Msgf("Failed extracting backend assets files: %s (might be required for some backends to work properly)", err)
 		}
+
+		registerInstalledBackends(options)
 	}
 
 	if options.LibPath != "" {
@@ -128,20 +170,50 @@ func Startup(opts ...config.AppOption) (*config.BackendConfigLoader, *model.Mode
 		}
 	}()
 
-	if options.WatchDog {
-		wd := model.NewWatchDog(
-			ml,
-			options.WatchDogBusyTimeout,
-			options.WatchDogIdleTimeout,
-			options.WatchDogBusy,
-			options.WatchDogIdle)
-		ml.SetWatchDog(wd)
-		go wd.Run()
-		go func() {
-			<-options.Context.Done()
-			log.Debug().Msgf("Context canceled, shutting down")
-			wd.Shutdown()
-		}()
+	// The watchdog always runs, as it also stops the models with a ttl, or a keep_alive, once they are idle
+	wd := model.NewWatchDog(
+		ml,
+		options.WatchDogBusyTimeout,
+		options.WatchDogIdleTimeout,
+		options.WatchDogBusy,
+		options.WatchDogIdle)
+	wd.SetDefaultActions(options.WatchDogBusyActions, options.WatchDogIdleActions)
+	wd.SetWebhook(options.WatchDogWebhook)
+	wd.SetRestart(func(modelFile string) {
+		for _, cfg := range cl.GetAllBackendConfigs() {
+			if cfg.Model != modelFile {
+				continue
+			}
+			log.Info().Str("model", cfg.Name).Msg("Restarting the model stopped by the watchdog")
+			if err := backend.LoadModel(ml, cfg, options); err != nil {
+				log.Error().Err(err).Str("model", cfg.Name).Msg("error while restarting the model")
+			}
+			return
+		}
+		log.Warn().Str("model", modelFile).Msg("No configuration found to restart the model, it is loaded again on its next request")
+	})
+	ml.SetWatchDog(wd)
+	go wd.Run()
+	if options.CircuitBreakerFailures > 0 {
+		ml.SetCircuitBreaker(model.NewCircuitBreaker(options.Context, options.CircuitBreakerFailures, options.CircuitBreakerCooldown))
+	}
+	go func() {
+		<-options.Context.Done()
+		log.Debug().Msgf("Context canceled, shutting down")
+		wd.Shutdown()
+	}()
+
+	// Load and unload the models at the times of their schedules
+	services.NewModelScheduler(cl, ml, options, func(cfg config.BackendConfig) error {
+		return backend.LoadModel(ml, cfg, options)
+	}).Start()
+
+	// Reload the models when their files change
+	err = services.NewModelFileWatcher(cl, ml, options, func(cfg config.BackendConfig) error {
+		return backend.ReloadModel(ml, cfg, options)
+	}).Start()
+	if err != nil {
+		log.Error().Err(err).Msg("failed watching the model files")
 	}
 
 	// Watch the configuration directory
@@ -197,10 +269,56 @@ func createApplication(appConfig *config.ApplicationConfig) *core.Application {
 	app.GalleryService = services.NewGalleryService(app.ApplicationConfig)
 	// app.OpenAIService = services.NewOpenAIService(app.ModelLoader, app.BackendConfigLoader, app.ApplicationConfig, app.LLMBackendService)
 
-	app.LocalAIMetricsService, err = services.NewLocalAIMetricsService()
-	if err != nil {
-		log.Error().Err(err).Msg("encountered an error initializing metrics service, startup will continue but metrics will not be tracked.")
+	if !appConfig.DisableMetrics {
+		app.LocalAIMetricsService, err = services.NewLocalAIMetricsService()
+		if err != nil {
+			log.Error().Err(err).Msg("encountered an error initializing metrics service, startup will continue but metrics will not be tracked.")
+		}
 	}
 
 	return app
 }
+
+// newEventBus returns a bus publishing the events to the sinks. The sinks failing to connect are skipped
+func newEventBus(sinks config.EventSinks) *events.Bus {
+	bus := events.NewBus(sinks.Types)
+	for _, url := range sinks.Webhooks {
+		bus.AddSink(events.NewWebhookSink(url))
+	}
+	if sinks.NATSURL != "" {
+		sink, err := events.NewNATSSink(sinks.NATSURL, sinks.NATSSubject)
+		if err != nil {
+			log.Error().Err(err).Msg("error connecting to NATS, the events are not published there")
+		} else {
+			bus.AddSink(sink)
+		}
+	}
+	if sinks.MQTTURL != "" {
+		sink, err := events.NewMQTTSink(sinks.MQTTURL, sinks.MQTTTopic)
+		if err != nil {
+			log.Error().Err(err).Msg("error connecting to MQTT, the events are not published there")
+		} else {
+			bus.AddSink(sink)
+		}
+	}
+	return bus
+}
+
+// registerInstalledBackends registers the backends installed from the backend galleries. They take
+// precedence over the embedded backends, but not over the external backends set by the user
+func registerInstalledBackends(options *config.ApplicationConfig) {
+	installedBackends, err := gallery.InstalledBackends(options.AssetsDestination)
+	if err != nil {
+		log.Error().Err(err).Msg("error listing the installed backends")
+	}
+	for _, b := range installedBackends {
+		if uri, exists := options.ExternalGRPCBackends[b.Name]; exists {
+			if uri != b.Run {
+				log.Warn().Str("backend", b.Name).Msg("installed backend shadowed by an external backend with the same name")
+			}
+			continue
+		}
+		services.RegisterBackend(options, b.Name, b.Run)
+		log.Debug().Str("backend", b.Name).Str("version", b.Version).Msg("installed backend registered")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/mudler/LocalAI/core/cli"
 	"github.com/mudler/LocalAI/internal"
+	"github.com/mudler/LocalAI/pkg/logging"
+	"github.com/mudler/LocalAI/pkg/secrets"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -21,8 +23,8 @@ func main() {
 	var err error
 
 	// Initialize zerolog at a level of INFO, we will set the desired level after we parse the CLI options
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	logging.Init(secrets.NewRedactWriter(os.Stderr), logging.FormatConsole)
+	logging.SetLevel(zerolog.InfoLevel)
 
 	// Catch signals from the OS requesting us to exit
 	go func() {
@@ -51,6 +53,11 @@ func main() {
 		}
 	}
 
+	settingsFiles := []string{}
+	if settingsFile := os.Getenv(cli.SettingsFileEnv); settingsFile != "" {
+		settingsFiles = append(settingsFiles, settingsFile)
+	}
+
 	// Actually parse the CLI options
 	ctx := kong.Parse(&cli.CLI,
 		kong.Description(
@@ -73,11 +80,15 @@ Version: ${version}
 `,
 		),
 		kong.UsageOnError(),
+		// the settings file set with --settings-file is loaded by the flag, the one set in the environment here
+		kong.Configuration(cli.SettingsLoader, settingsFiles...),
 		kong.Vars{
-			"basepath":         kong.ExpandPath("."),
-			"remoteLibraryURL": "https://raw.githubusercontent.com/mudler/LocalAI/master/embedded/model_library.yaml",
-			"galleries":        `[{"name":"localai", "url":"github:mudler/LocalAI/gallery/index.yaml@master"}]`,
-			"version":          internal.PrintableVersion(),
+			"basepath":          kong.ExpandPath("."),
+			"remoteLibraryURL":  "https://raw.githubusercontent.com/mudler/LocalAI/master/embedded/model_library.yaml",
+			"galleries":         `[{"name":"localai", "url":"github:mudler/LocalAI/gallery/index.yaml@master"}]`,
+			"backend_galleries": "",
+			"gated_licenses":    "llama2,llama3,llama3.1,llama3.2,gemma,mnpl",
+			"version":           internal.PrintableVersion(),
 		},
 	)
 
@@ -86,7 +97,7 @@ Version: ${version}
 	logLevel := "info"
 	if cli.CLI.Debug && cli.CLI.LogLevel == nil {
 		logLevel = "debug"
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		logging.SetLevel(zerolog.DebugLevel)
 		cli.CLI.LogLevel = &logLevel
 	}
 
@@ -94,21 +105,25 @@ Version: ${version}
 		cli.CLI.LogLevel = &logLevel
 	}
 
+	if err := logging.SetFormat(cli.CLI.LogFormat); err != nil {
+		log.Fatal().Err(err).Msg("Error configuring the logs")
+	}
+
 	switch *cli.CLI.LogLevel {
 	case "error":
-		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+		logging.SetLevel(zerolog.ErrorLevel)
 		log.Info().Msg("Setting logging to error")
 	case "warn":
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+		logging.SetLevel(zerolog.WarnLevel)
 		log.Info().Msg("Setting logging to warn")
 	case "info":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		logging.SetLevel(zerolog.InfoLevel)
 		log.Info().Msg("Setting logging to info")
 	case "debug":
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		logging.SetLevel(zerolog.DebugLevel)
 		log.Debug().Msg("Setting logging to debug")
 	case "trace":
-		zerolog.SetGlobalLevel(zerolog.TraceLevel)
+		logging.SetLevel(zerolog.TraceLevel)
 		log.Trace().Msg("Setting logging to trace")
 	}
 
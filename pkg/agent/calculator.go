@@ -0,0 +1,287 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxExpressionLength bounds the expressions, which come from the models
+const maxExpressionLength = 1024
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+var functions = map[string]func(args []float64) (float64, error){
+	"sqrt":  unary(math.Sqrt),
+	"abs":   unary(math.Abs),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"ln":    unary(math.Log),
+	"log":   unary(math.Log10),
+	"log2":  unary(math.Log2),
+	"exp":   unary(math.Exp),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"pow": func(args []float64) (float64, error) {
+		if len(args) != 2 {
+			return 0, fmt.Errorf("expected 2 arguments, got %d", len(args))
+		}
+		return math.Pow(args[0], args[1]), nil
+	},
+	"min": variadic(math.Min),
+	"max": variadic(math.Max),
+}
+
+func unary(f func(float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("expected 1 argument, got %d", len(args))
+		}
+		return f(args[0]), nil
+	}
+}
+
+func variadic(f func(float64, float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("expected at least 1 argument")
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			v = f(v, a)
+		}
+		return v, nil
+	}
+}
+
+// Calculate evaluates an arithmetic expression: numbers, + - * / % and ^ (power), parentheses, the
+// constants pi and e, and the functions sqrt, abs, floor, ceil, round, ln, log, log2, exp, sin, cos, tan,
+// pow, min and max
+func Calculate(expression string) (float64, error) {
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("the expression is longer than %d characters", maxExpressionLength)
+	}
+	p := &calculator{input: expression}
+	v, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("the result is not a finite number")
+	}
+	return v, nil
+}
+
+// FormatNumber prints the results of Calculate without trailing zeros
+func FormatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// calculator is a recursive descent parser of the expressions
+type calculator struct {
+	input string
+	pos   int
+}
+
+func (p *calculator) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes the next character when it is one of chars
+func (p *calculator) accept(chars string) (byte, bool) {
+	p.skipSpaces()
+	if p.pos < len(p.input) && strings.IndexByte(chars, p.input[p.pos]) >= 0 {
+		p.pos++
+		return p.input[p.pos-1], true
+	}
+	return 0, false
+}
+
+// expression := term (('+' | '-') term)*
+func (p *calculator) expression() (float64, error) {
+	v, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.accept("+-")
+		if !ok {
+			return v, nil
+		}
+		r, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			v += r
+		} else {
+			v -= r
+		}
+	}
+}
+
+// term := unary (('*' | '/' | '%') unary)*
+func (p *calculator) term() (float64, error) {
+	v, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.accept("*/%")
+		if !ok {
+			return v, nil
+		}
+		r, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			v *= r
+		case '/':
+			if r == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			v /= r
+		case '%':
+			if r == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			v = math.Mod(v, r)
+		}
+	}
+}
+
+// unary := ('+' | '-') unary | power
+func (p *calculator) unary() (float64, error) {
+	if op, ok := p.accept("+-"); ok {
+		v, err := p.unary()
+		if op == '-' {
+			v = -v
+		}
+		return v, err
+	}
+	return p.power()
+}
+
+// power := primary ('^' unary)?, right associative so that 2^3^2 is 2^9
+func (p *calculator) power() (float64, error) {
+	v, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.accept("^"); ok {
+		e, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(v, e), nil
+	}
+	return v, nil
+}
+
+// primary := number | constant | function '(' arguments ')' | '(' expression ')'
+func (p *calculator) primary() (float64, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0, fmt.Errorf("unexpected end of the expression")
+	}
+
+	if _, ok := p.accept("("); ok {
+		v, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return 0, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		return v, nil
+	}
+
+	start := p.pos
+	c := p.input[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		// exponent, as 1e-3
+		if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.input) && (p.input[end] == '+' || p.input[end] == '-') {
+				end++
+			}
+			if end < len(p.input) && p.input[end] >= '0' && p.input[end] <= '9' {
+				for end < len(p.input) && p.input[end] >= '0' && p.input[end] <= '9' {
+					end++
+				}
+				p.pos = end
+			}
+		}
+		v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return v, nil
+
+	case unicode.IsLetter(rune(c)):
+		for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || p.input[p.pos] >= '0' && p.input[p.pos] <= '9') {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		if f, exists := functions[name]; exists {
+			args, err := p.arguments()
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", name, err)
+			}
+			v, err := f(args)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", name, err)
+			}
+			return v, nil
+		}
+		if v, exists := constants[name]; exists {
+			return v, nil
+		}
+		return 0, fmt.Errorf("unknown name %q", name)
+	}
+	return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+}
+
+// arguments := '(' (expression (',' expression)*)? ')'
+func (p *calculator) arguments() ([]float64, error) {
+	if _, ok := p.accept("("); !ok {
+		return nil, fmt.Errorf("missing '(' at position %d", p.pos)
+	}
+	args := []float64{}
+	if _, ok := p.accept(")"); ok {
+		return args, nil
+	}
+	for {
+		v, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+		sep, ok := p.accept(",)")
+		if !ok {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		if sep == ')' {
+			return args, nil
+		}
+	}
+}
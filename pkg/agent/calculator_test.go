@@ -0,0 +1,48 @@
+package agent_test
+
+import (
+	"math"
+
+	. "github.com/mudler/LocalAI/pkg/agent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Calculate", func() {
+	DescribeTable("evaluates the expressions",
+		func(expression string, expected float64) {
+			v, err := Calculate(expression)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v).To(BeNumerically("~", expected, 1e-9))
+		},
+		Entry("precedence", "1 + 2 * 3", 7.0),
+		Entry("parentheses", "(1 + 2) * 3", 9.0),
+		Entry("unary minus", "-2 - -3", 1.0),
+		Entry("right associative power", "2^3^2", 512.0),
+		Entry("negative power", "-2^2", -4.0),
+		Entry("modulo", "17 % 5", 2.0),
+		Entry("exponents", "1.5e3 / 3", 500.0),
+		Entry("constants", "2 * pi", 2*math.Pi),
+		Entry("functions", "sqrt(16) + max(1, 7, 3) + pow(2, 10)", 1035.0),
+		Entry("nested functions", "round(ln(e^2))", 2.0),
+	)
+
+	DescribeTable("reports the invalid expressions",
+		func(expression, message string) {
+			_, err := Calculate(expression)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("trailing characters", "1 + 2)", "unexpected ')'"),
+		Entry("missing parenthesis", "(1 + 2", "missing ')'"),
+		Entry("unknown names", "foo(2)", `unknown name "foo"`),
+		Entry("arity", "sqrt(1, 2)", "sqrt: expected 1 argument"),
+		Entry("division by zero", "1 / (2 - 2)", "division by zero"),
+		Entry("infinite results", "10^1000", "not a finite number"),
+		Entry("empty expressions", "", "unexpected end"),
+	)
+
+	It("formats the results without trailing zeros", func() {
+		Expect(FormatNumber(7)).To(Equal("7"))
+		Expect(FormatNumber(0.25)).To(Equal("0.25"))
+	})
+})
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultFetchLength is the number of characters of the pages returned by default
+	DefaultFetchLength = 8000
+	// maxFetchBytes bounds the bytes read from the pages
+	maxFetchBytes = 2 << 20
+	fetchTimeout  = 30 * time.Second
+)
+
+// Fetcher fetches the pages of the hosts of its allowlist, as text
+type Fetcher struct {
+	// Allowlist holds the hosts which can be fetched: "example.com" allows this host only,
+	// "*.example.com" its subdomains too, and "*" any host
+	Allowlist []string
+	// MaxLength is the number of characters of the text returned, DefaultFetchLength if 0
+	MaxLength int
+	// Client defaults to a client with a 30s timeout
+	Client *http.Client
+}
+
+// Allowed tells if the URL is an http(s) URL of a host of the allowlist
+func (f Fetcher) Allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return false
+	}
+	for _, entry := range f.Allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "*":
+			return true
+		case strings.HasPrefix(entry, "*."):
+			if host == entry[2:] || strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case host == entry:
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch gets the page of the URL, and returns its text: the markup of the HTML pages is removed. The
+// redirects to the hosts outside of the allowlist are refused
+func (f Fetcher) Fetch(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if !f.Allowed(u) {
+		return "", fmt.Errorf("the host of %s is not allowed", u.Redacted())
+	}
+
+	client := http.Client{Timeout: fetchTimeout}
+	if f.Client != nil {
+		client = *f.Client
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if !f.Allowed(req.URL) {
+			return fmt.Errorf("redirected to %s, whose host is not allowed", req.URL.Redacted())
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html,text/plain,application/json;q=0.9,*/*;q=0.5")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("%s returned %s", u.Redacted(), resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && !strings.HasPrefix(mediaType, "text/") && !strings.HasSuffix(mediaType, "json") && !strings.HasSuffix(mediaType, "xml") {
+		return "", fmt.Errorf("unsupported content type %s", mediaType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+	if err != nil {
+		return "", err
+	}
+
+	text := string(body)
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		text = HTMLText(text)
+	}
+	return truncate(strings.TrimSpace(text), f.maxLength()), nil
+}
+
+func (f Fetcher) maxLength() int {
+	if f.MaxLength > 0 {
+		return f.MaxLength
+	}
+	return DefaultFetchLength
+}
+
+var (
+	htmlHidden = regexp.MustCompile(`(?is)<(script|style|noscript|template|svg|head)\b.*?</(script|style|noscript|template|svg|head)\s*>|<!--.*?-->`)
+	htmlBlock  = regexp.MustCompile(`(?i)</?(p|div|br|li|tr|h[1-6]|section|article|header|footer|pre|blockquote|table|ul|ol)\b[^>]*>`)
+	htmlTag    = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLines = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// HTMLText returns the text of an HTML page: the scripts, the styles and the tags are removed, and the
+// blocks are separated by new lines
+func HTMLText(page string) string {
+	text := htmlHidden.ReplaceAllString(page, "")
+	text = htmlBlock.ReplaceAllString(text, "\n")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = spaces.ReplaceAllString(text, " ")
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// truncate cuts the text to n characters
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
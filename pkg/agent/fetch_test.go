@@ -0,0 +1,76 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/mudler/LocalAI/pkg/agent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fetcher", func() {
+	It("allows the hosts of the allowlist only", func() {
+		f := Fetcher{Allowlist: []string{"example.com", "*.wikipedia.org"}}
+		for rawURL, allowed := range map[string]bool{
+			"https://example.com/page":        true,
+			"http://EXAMPLE.com:8080/":        true,
+			"https://www.example.com/":        false,
+			"https://en.wikipedia.org/wiki/a": true,
+			"https://wikipedia.org/":          true,
+			"https://evilwikipedia.org/":      false,
+			"ftp://example.com/file":          false,
+			"file:///etc/passwd":              false,
+		} {
+			u, err := url.Parse(rawURL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Allowed(u)).To(Equal(allowed), rawURL)
+		}
+		u, _ := url.Parse("https://anything.org/")
+		Expect(Fetcher{Allowlist: []string{"*"}}.Allowed(u)).To(BeTrue())
+		Expect(Fetcher{}.Allowed(u)).To(BeFalse())
+	})
+
+	It("returns the text of the pages", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><head><title>T</title><style>p{}</style></head><body><h1>Title</h1><p>Fish &amp; chips<script>alert(1)</script></p></body></html>`)
+		}))
+		defer server.Close()
+
+		text, err := Fetcher{Allowlist: []string{"127.0.0.1"}}.Fetch(context.Background(), server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(text).To(Equal("Title\n\nFish & chips"))
+
+		text, err = Fetcher{Allowlist: []string{"127.0.0.1"}, MaxLength: 5}.Fetch(context.Background(), server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(text).To(Equal("Title…"))
+	})
+
+	It("refuses the redirects outside of the allowlist", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+		}))
+		defer server.Close()
+
+		_, err := Fetcher{Allowlist: []string{"127.0.0.1"}}.Fetch(context.Background(), server.URL)
+		Expect(err).To(MatchError(ContainSubstring("whose host is not allowed")))
+
+		_, err = Fetcher{Allowlist: []string{"example.com"}}.Fetch(context.Background(), server.URL)
+		Expect(err).To(MatchError(ContainSubstring("is not allowed")))
+	})
+
+	It("refuses the binary content", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0, 1, 2})
+		}))
+		defer server.Close()
+
+		_, err := Fetcher{Allowlist: []string{"127.0.0.1"}}.Fetch(context.Background(), server.URL)
+		Expect(err).To(MatchError(ContainSubstring("unsupported content type")))
+	})
+})
@@ -0,0 +1,79 @@
+package cron_test
+
+import (
+	"time"
+
+	. "github.com/mudler/LocalAI/pkg/cron"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Monday, 2024-07-01
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.July, day, hour, minute, 0, 0, time.UTC)
+}
+
+var _ = Describe("Cron schedules", func() {
+	It("matches the business days", func() {
+		s, err := Parse("30 8 * * 1-5")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Matches(at(1, 8, 30))).To(BeTrue())
+		Expect(s.Matches(at(5, 8, 30))).To(BeTrue())
+		Expect(s.Matches(at(1, 8, 31))).To(BeFalse())
+		Expect(s.Matches(at(6, 8, 30))).To(BeFalse()) // Saturday
+	})
+
+	It("supports lists, steps and the Sunday as 7", func() {
+		s, err := Parse("*/15 9,18 * * 7")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Matches(at(7, 9, 45))).To(BeTrue())
+		Expect(s.Matches(at(7, 18, 0))).To(BeTrue())
+		Expect(s.Matches(at(7, 18, 10))).To(BeFalse())
+		Expect(s.Matches(at(8, 9, 45))).To(BeFalse())
+	})
+
+	It("matches either day when both days are restricted", func() {
+		s, err := Parse("0 0 15 * 1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Matches(at(15, 0, 0))).To(BeTrue()) // Monday the 15th
+		Expect(s.Matches(at(8, 0, 0))).To(BeTrue())  // Monday
+		Expect(s.Matches(at(16, 0, 0))).To(BeFalse())
+	})
+
+	It("supports the macros", func() {
+		s, err := Parse("@daily")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Matches(at(3, 0, 0))).To(BeTrue())
+		Expect(s.Matches(at(3, 1, 0))).To(BeFalse())
+	})
+
+	It("returns the last firing", func() {
+		s, err := Parse("0 20 * * *")
+		Expect(err).ToNot(HaveOccurred())
+		prev, ok := s.Prev(at(3, 8, 15), 48*time.Hour)
+		Expect(ok).To(BeTrue())
+		Expect(prev).To(Equal(at(2, 20, 0)))
+
+		_, ok = s.Prev(at(3, 8, 15), time.Hour)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the next firing", func() {
+		s, err := Parse("0 20 * * *")
+		Expect(err).ToNot(HaveOccurred())
+		next, ok := s.Next(at(3, 20, 0), 48*time.Hour)
+		Expect(ok).To(BeTrue())
+		Expect(next).To(Equal(at(4, 20, 0)))
+
+		_, ok = s.Next(at(3, 8, 15), time.Hour)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects the invalid expressions", func() {
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+			_, err := Parse(expr)
+			Expect(err).To(HaveOccurred(), expr)
+		}
+	})
+})
@@ -0,0 +1,154 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNotCached is returned in offline mode for the files which were never fetched
+var ErrNotCached = errors.New("not in the cache of the offline mode")
+
+// indexCache caches the files fetched with DownloadAndUnmarshal, as the indexes of the galleries and
+// the configurations of their models
+var indexCache struct {
+	sync.RWMutex
+	dir     string
+	offline bool
+}
+
+// SetIndexCache caches the files fetched with DownloadAndUnmarshal in dir, revalidated at each fetch
+// with their ETag and their modification time. In offline mode, only the cached files are returned,
+// without any request. An empty dir disables the cache
+func SetIndexCache(dir string, offline bool) {
+	indexCache.Lock()
+	defer indexCache.Unlock()
+	indexCache.dir, indexCache.offline = dir, offline
+}
+
+type cachedIndex struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// cacheEnabled returns whether the files of DownloadAndUnmarshal go through the cache
+func cacheEnabled() bool {
+	indexCache.RLock()
+	defer indexCache.RUnlock()
+	return indexCache.dir != "" || indexCache.offline
+}
+
+// fetchIndex returns the content of url, from the cache when it did not change. When the server cannot
+// be reached, the cached content is returned as it is
+func fetchIndex(url string) ([]byte, error) {
+	indexCache.RLock()
+	dir, offline := indexCache.dir, indexCache.offline
+	indexCache.RUnlock()
+
+	var name string
+	var body []byte
+	var meta *cachedIndex
+	if dir != "" {
+		sum := sha256.Sum256([]byte(url))
+		name = filepath.Join(dir, hex.EncodeToString(sum[:]))
+		meta, body = readIndex(name)
+	}
+	if offline {
+		if meta == nil {
+			return nil, fmt.Errorf("%s: %w", url, ErrNotCached)
+		}
+		return body, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("failed to fetch %s, invalid status code %d", url, resp.StatusCode)
+		}
+	}
+	if err != nil {
+		if meta != nil {
+			log.Warn().Err(err).Str("url", url).Msgf("using the copy cached on %s", meta.Fetched.Format(time.RFC3339))
+			return body, nil
+		}
+		return nil, err
+	}
+	if meta != nil && resp.StatusCode == http.StatusNotModified {
+		return body, nil
+	}
+
+	fresh, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		meta = &cachedIndex{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), Fetched: time.Now()}
+		if err := saveIndex(name, fresh, meta); err != nil {
+			log.Warn().Err(err).Str("url", url).Msg("unable to cache the file")
+		}
+	}
+	return fresh, nil
+}
+
+// readIndex returns the cached copy of name, or nil if it is not cached
+func readIndex(name string) (*cachedIndex, []byte) {
+	dat, err := os.ReadFile(name + ".json")
+	if err != nil {
+		return nil, nil
+	}
+	meta := &cachedIndex{}
+	if err := json.Unmarshal(dat, meta); err != nil {
+		return nil, nil
+	}
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return nil, nil
+	}
+	return meta, body
+}
+
+func saveIndex(name string, body []byte, meta *cachedIndex) error {
+	if name == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0750); err != nil {
+		return err
+	}
+	dat, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	// the content is written before its metadata, which marks it as complete
+	if err := os.WriteFile(name+".tmp", body, 0640); err != nil {
+		return err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return err
+	}
+	return os.WriteFile(name+".json", dat, 0640)
+}
@@ -0,0 +1,86 @@
+package downloader_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/mudler/LocalAI/pkg/downloader"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Index cache", func() {
+	var server *httptest.Server
+	var mu sync.Mutex
+	var index string
+	var requests, notModified int
+
+	fetch := func() (string, error) {
+		var content string
+		err := URI(server.URL+"/index.yaml").DownloadAndUnmarshal("", func(_ string, d []byte) error {
+			content = string(d)
+			return nil
+		})
+		return content, err
+	}
+
+	BeforeEach(func() {
+		index, requests, notModified = "- name: phi-2\n", 0, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests++
+			etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(index)))
+			if r.Header.Get("If-None-Match") == etag {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write([]byte(index))
+		}))
+		SetIndexCache(GinkgoT().TempDir(), false)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		SetIndexCache("", false)
+	})
+
+	It("revalidates the cached files with their ETag", func() {
+		Expect(fetch()).To(Equal("- name: phi-2\n"))
+		Expect(fetch()).To(Equal("- name: phi-2\n"))
+		Expect(requests).To(Equal(2))
+		Expect(notModified).To(Equal(1))
+
+		mu.Lock()
+		index = "- name: phi-3\n"
+		mu.Unlock()
+		Expect(fetch()).To(Equal("- name: phi-3\n"))
+		Expect(notModified).To(Equal(1))
+	})
+
+	It("returns the cached files when the server cannot be reached", func() {
+		Expect(fetch()).To(Equal("- name: phi-2\n"))
+		server.Close()
+		Expect(fetch()).To(Equal("- name: phi-2\n"))
+	})
+
+	It("returns only the cached files in offline mode", func() {
+		dir := GinkgoT().TempDir()
+		SetIndexCache(dir, false)
+		Expect(fetch()).To(Equal("- name: phi-2\n"))
+
+		SetIndexCache(dir, true)
+		Expect(fetch()).To(Equal("- name: phi-2\n"))
+		Expect(requests).To(Equal(1))
+
+		err := URI(server.URL+"/other.yaml").DownloadAndUnmarshal("", func(string, []byte) error { return nil })
+		Expect(errors.Is(err, ErrNotCached)).To(BeTrue())
+		Expect(requests).To(Equal(1))
+	})
+})
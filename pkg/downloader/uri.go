@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -62,6 +63,14 @@ func (uri URI) DownloadAndUnmarshal(basePath string, f func(url string, i []byte
 		return f(url, body)
 	}
 
+	if cacheEnabled() {
+		body, err := fetchIndex(url)
+		if err != nil {
+			return err
+		}
+		return f(url, body)
+	}
+
 	// Send a GET request to the URL
 	response, err := http.Get(url)
 	if err != nil {
@@ -178,6 +187,29 @@ func (s URI) ResolveURL() string {
 	return string(s)
 }
 
+// ContentLength returns the size of the file of the URI, as reported by the server, without downloading it
+func (u URI) ContentLength(ctx context.Context) (int64, error) {
+	if u.LooksLikeOCI() || !u.LooksLikeURL() {
+		return 0, fmt.Errorf("the size of %q is not known", string(u))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.ResolveURL(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("failed to get the size of %q, invalid status code %d", string(u), resp.StatusCode)
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("the size of %q is not known", string(u))
+	}
+	return resp.ContentLength, nil
+}
+
 func removePartialFile(tmpFilePath string) error {
 	_, err := os.Stat(tmpFilePath)
 	if err == nil {
@@ -1,6 +1,10 @@
 package downloader_test
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
 	. "github.com/mudler/LocalAI/pkg/downloader"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -37,4 +41,31 @@ var _ = Describe("Gallery API tests", func() {
 			).ToNot(HaveOccurred())
 		})
 	})
+
+	Context("ContentLength", func() {
+		It("returns the size of the file without downloading it", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodHead))
+				w.Header().Set("Content-Length", "1024")
+			}))
+			defer server.Close()
+
+			size, err := URI(server.URL + "/model.gguf").ContentLength(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(int64(1024)))
+		})
+
+		It("fails when the server does not have the file", func() {
+			server := httptest.NewServer(http.NotFoundHandler())
+			defer server.Close()
+
+			_, err := URI(server.URL + "/model.gguf").ContentLength(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails for the URIs which are not files", func() {
+			_, err := URI("ollama://gemma:2b").ContentLength(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Types of the events published by LocalAI
+const (
+	// ModelLoaded is published when a model is loaded in memory
+	ModelLoaded = "model.loaded"
+	// ModelUnloaded is published when a model is stopped
+	ModelUnloaded = "model.unloaded"
+	// BackendCrashed is published when the process of a loaded model is found dead
+	BackendCrashed = "backend.crashed"
+	// GalleryJobCompleted is published when a job of the galleries, as an install or a deletion, is processed
+	GalleryJobCompleted = "gallery.job.completed"
+	// TaskCompleted is published when a run of a scheduled task is done, and TaskFailed when it failed
+	TaskCompleted = "task.completed"
+	TaskFailed    = "task.failed"
+)
+
+// Types are all the types of the events
+var Types = []string{ModelLoaded, ModelUnloaded, BackendCrashed, GalleryJobCompleted, TaskCompleted, TaskFailed}
+
+// Event is a change of the state of LocalAI
+type Event struct {
+	Type string         `json:"type"`
+	Time time.Time      `json:"time"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Sink is a destination of the events, as a webhook or a message broker
+type Sink interface {
+	Send(Event) error
+	Close() error
+}
+
+// ValidateTypes returns an error if one of the types is unknown
+func ValidateTypes(types []string) error {
+	for _, t := range types {
+		if !slices.Contains(Types, t) {
+			return fmt.Errorf("invalid event type %q: expected one of %v", t, Types)
+		}
+	}
+	return nil
+}
+
+// Bus publishes the events to the sinks in the background, so that publishing never blocks.
+// The events are dropped when the sinks do not keep up. A nil Bus discards the events
+type Bus struct {
+	mu     sync.RWMutex
+	sinks  []Sink
+	types  []string
+	ch     chan Event
+	done   chan struct{}
+	closed bool
+}
+
+// NewBus returns a bus publishing the events of the types, or all the events if types is empty
+func NewBus(types []string) *Bus {
+	b := &Bus{
+		types: types,
+		ch:    make(chan Event, 100),
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// AddSink adds a destination of the events
+func (b *Bus) AddSink(s Sink) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sinks = append(b.sinks, s)
+}
+
+// Publish publishes an event of the type
+func (b *Bus) Publish(eventType string, data map[string]any) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed || len(b.sinks) == 0 || (len(b.types) > 0 && !slices.Contains(b.types, eventType)) {
+		return
+	}
+
+	select {
+	case b.ch <- Event{Type: eventType, Time: time.Now(), Data: data}:
+	default:
+		log.Warn().Str("event", eventType).Msg("[events] too many events pending, dropping the event")
+	}
+}
+
+func (b *Bus) run() {
+	defer close(b.done)
+	for e := range b.ch {
+		b.mu.RLock()
+		sinks := b.sinks
+		b.mu.RUnlock()
+		for _, s := range sinks {
+			if err := s.Send(e); err != nil {
+				log.Error().Err(err).Str("event", e.Type).Msg("[events] failed to send the event")
+			}
+		}
+	}
+}
+
+// Close sends the pending events and closes the sinks
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.ch)
+	b.mu.Unlock()
+
+	<-b.done
+	var err error
+	for _, s := range b.sinks {
+		err = errors.Join(err, s.Close())
+	}
+	return err
+}
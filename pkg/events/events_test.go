@@ -0,0 +1,117 @@
+package events_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/mudler/LocalAI/pkg/events"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// recordingSink records the events sent
+type recordingSink struct {
+	sync.Mutex
+	events []Event
+	closed bool
+}
+
+func (r *recordingSink) Send(e Event) error {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recordingSink) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (r *recordingSink) types() []string {
+	r.Lock()
+	defer r.Unlock()
+	types := []string{}
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+var _ = Describe("Bus", func() {
+	It("publishes the events to all the sinks", func() {
+		a, b := &recordingSink{}, &recordingSink{}
+		bus := NewBus(nil)
+		bus.AddSink(a)
+		bus.AddSink(b)
+
+		bus.Publish(ModelLoaded, map[string]any{"model": "model.gguf"})
+		bus.Publish(ModelUnloaded, map[string]any{"model": "model.gguf"})
+		Expect(bus.Close()).To(Succeed())
+
+		Expect(a.types()).To(Equal([]string{ModelLoaded, ModelUnloaded}))
+		Expect(b.types()).To(Equal([]string{ModelLoaded, ModelUnloaded}))
+		Expect(a.events[0].Data).To(HaveKeyWithValue("model", "model.gguf"))
+		Expect(a.events[0].Time).ToNot(BeZero())
+		Expect(a.closed).To(BeTrue())
+	})
+
+	It("only publishes the events of the types", func() {
+		sink := &recordingSink{}
+		bus := NewBus([]string{BackendCrashed})
+		bus.AddSink(sink)
+
+		bus.Publish(ModelLoaded, nil)
+		bus.Publish(BackendCrashed, nil)
+		Expect(bus.Close()).To(Succeed())
+		Expect(sink.types()).To(Equal([]string{BackendCrashed}))
+	})
+
+	It("discards the events of a nil or closed bus", func() {
+		var bus *Bus
+		Expect(func() { bus.Publish(ModelLoaded, nil) }).ToNot(Panic())
+
+		sink := &recordingSink{}
+		bus = NewBus(nil)
+		bus.AddSink(sink)
+		Expect(bus.Close()).To(Succeed())
+		Expect(func() { bus.Publish(ModelLoaded, nil) }).ToNot(Panic())
+		Expect(sink.types()).To(BeEmpty())
+	})
+
+	It("validates the types", func() {
+		Expect(ValidateTypes(Types)).To(Succeed())
+		Expect(ValidateTypes([]string{"model.exploded"})).To(MatchError(ContainSubstring(`invalid event type "model.exploded"`)))
+	})
+})
+
+var _ = Describe("WebhookSink", func() {
+	It("posts the events as JSON", func() {
+		received := make(chan Event, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			var e Event
+			Expect(json.NewDecoder(r.Body).Decode(&e)).To(Succeed())
+			received <- e
+		}))
+		defer server.Close()
+
+		Expect(NewWebhookSink(server.URL).Send(Event{Type: GalleryJobCompleted, Data: map[string]any{"job": "1"}})).To(Succeed())
+		e := <-received
+		Expect(e.Type).To(Equal(GalleryJobCompleted))
+		Expect(e.Data).To(HaveKeyWithValue("job", "1"))
+	})
+
+	It("fails when the webhook fails", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		Expect(NewWebhookSink(server.URL).Send(Event{Type: ModelLoaded})).To(MatchError(ContainSubstring("status 500")))
+	})
+})
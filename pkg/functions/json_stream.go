@@ -0,0 +1,246 @@
+package functions
+
+import "fmt"
+
+type jsonStreamState int
+
+const (
+	jsonStart jsonStreamState = iota
+	jsonValue
+	jsonArrayValueOrEnd
+	jsonObjectKeyOrEnd
+	jsonObjectKey
+	jsonColon
+	jsonAfterValue
+	jsonString
+	jsonStringEscape
+	jsonStringUnicode
+	jsonNumberMinus
+	jsonNumberZero
+	jsonNumberInt
+	jsonNumberDot
+	jsonNumberFraction
+	jsonNumberExponent
+	jsonNumberExponentSign
+	jsonNumberExponentDigits
+	jsonLiteral
+)
+
+// JSONStreamValidator checks a JSON document while it is generated, chunk by chunk, to detect the
+// invalid outputs at their first invalid character. The document is a JSON object or array
+type JSONStreamValidator struct {
+	state jsonStreamState
+	// containers open, '{' or '['
+	stack []byte
+	// the string read is an object key
+	key bool
+	// hexadecimal digits left in an \u escape, or characters left in a literal (true, false, null)
+	unicodeLeft int
+	literal     string
+	offset      int
+	err         error
+}
+
+func NewJSONStreamValidator() *JSONStreamValidator {
+	return &JSONStreamValidator{}
+}
+
+// Write reads the next chunk of the document. It returns an error if the document read is not the
+// beginning of a valid JSON document, and keeps returning it for the next chunks
+func (v *JSONStreamValidator) Write(s string) error {
+	if v.err != nil {
+		return v.err
+	}
+	for i := 0; i < len(s); i++ {
+		if err := v.read(s[i]); err != nil {
+			v.err = err
+			return err
+		}
+		v.offset++
+	}
+	return nil
+}
+
+// Complete returns true when the document read is a whole valid JSON document
+func (v *JSONStreamValidator) Complete() bool {
+	return v.err == nil && v.state == jsonAfterValue && len(v.stack) == 0
+}
+
+func (v *JSONStreamValidator) read(c byte) error {
+	switch v.state {
+	case jsonStart:
+		switch {
+		case isJSONSpace(c):
+		case c == '{' || c == '[':
+			return v.value(c)
+		default:
+			return v.unexpected(c, "the document must be a JSON object or array")
+		}
+	case jsonValue:
+		if isJSONSpace(c) {
+			return nil
+		}
+		return v.value(c)
+	case jsonArrayValueOrEnd:
+		switch {
+		case isJSONSpace(c):
+		case c == ']':
+			v.close()
+		default:
+			return v.value(c)
+		}
+	case jsonObjectKeyOrEnd, jsonObjectKey:
+		switch {
+		case isJSONSpace(c):
+		case c == '"':
+			v.state, v.key = jsonString, true
+		case c == '}' && v.state == jsonObjectKeyOrEnd:
+			v.close()
+		default:
+			return v.unexpected(c, "expecting an object key")
+		}
+	case jsonColon:
+		switch {
+		case isJSONSpace(c):
+		case c == ':':
+			v.state = jsonValue
+		default:
+			return v.unexpected(c, "expecting ':'")
+		}
+	case jsonAfterValue:
+		if isJSONSpace(c) {
+			return nil
+		}
+		if len(v.stack) == 0 {
+			return v.unexpected(c, "the document is already complete")
+		}
+		switch top := v.stack[len(v.stack)-1]; {
+		case c == ',' && top == '{':
+			v.state = jsonObjectKey
+		case c == ',' && top == '[':
+			v.state = jsonValue
+		case c == '}' && top == '{', c == ']' && top == '[':
+			v.close()
+		default:
+			return v.unexpected(c, "expecting ',' or the end of the container")
+		}
+	case jsonString:
+		switch {
+		case c == '"':
+			if v.key {
+				v.state, v.key = jsonColon, false
+			} else {
+				v.state = jsonAfterValue
+			}
+		case c == '\\':
+			v.state = jsonStringEscape
+		case c < 0x20:
+			return v.unexpected(c, "control characters must be escaped in strings")
+		}
+	case jsonStringEscape:
+		switch c {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			v.state = jsonString
+		case 'u':
+			v.state, v.unicodeLeft = jsonStringUnicode, 4
+		default:
+			return v.unexpected(c, "invalid escape")
+		}
+	case jsonStringUnicode:
+		if !isJSONHex(c) {
+			return v.unexpected(c, "invalid unicode escape")
+		}
+		v.unicodeLeft--
+		if v.unicodeLeft == 0 {
+			v.state = jsonString
+		}
+	case jsonLiteral:
+		if c != v.literal[0] {
+			return v.unexpected(c, "invalid literal")
+		}
+		v.literal = v.literal[1:]
+		if v.literal == "" {
+			v.state = jsonAfterValue
+		}
+	default:
+		return v.number(c)
+	}
+	return nil
+}
+
+// value starts the value beginning with c
+func (v *JSONStreamValidator) value(c byte) error {
+	switch {
+	case c == '{':
+		v.stack = append(v.stack, c)
+		v.state = jsonObjectKeyOrEnd
+	case c == '[':
+		v.stack = append(v.stack, c)
+		v.state = jsonArrayValueOrEnd
+	case c == '"':
+		v.state = jsonString
+	case c == '-':
+		v.state = jsonNumberMinus
+	case c == '0':
+		v.state = jsonNumberZero
+	case c >= '1' && c <= '9':
+		v.state = jsonNumberInt
+	case c == 't':
+		v.state, v.literal = jsonLiteral, "rue"
+	case c == 'f':
+		v.state, v.literal = jsonLiteral, "alse"
+	case c == 'n':
+		v.state, v.literal = jsonLiteral, "ull"
+	default:
+		return v.unexpected(c, "expecting a value")
+	}
+	return nil
+}
+
+// number reads c in a number: '-'? ('0' | [1-9][0-9]*) ('.' [0-9]+)? ([eE] [-+]? [0-9]+)?
+func (v *JSONStreamValidator) number(c byte) error {
+	digit := c >= '0' && c <= '9'
+	switch {
+	case v.state == jsonNumberMinus && c == '0':
+		v.state = jsonNumberZero
+	case v.state == jsonNumberMinus && digit:
+		v.state = jsonNumberInt
+	case v.state == jsonNumberInt && digit,
+		v.state == jsonNumberFraction && digit,
+		v.state == jsonNumberExponentDigits && digit:
+	case (v.state == jsonNumberZero || v.state == jsonNumberInt) && c == '.':
+		v.state = jsonNumberDot
+	case (v.state == jsonNumberDot) && digit:
+		v.state = jsonNumberFraction
+	case (v.state == jsonNumberZero || v.state == jsonNumberInt || v.state == jsonNumberFraction) && (c == 'e' || c == 'E'):
+		v.state = jsonNumberExponent
+	case v.state == jsonNumberExponent && (c == '+' || c == '-'):
+		v.state = jsonNumberExponentSign
+	case (v.state == jsonNumberExponent || v.state == jsonNumberExponentSign) && digit:
+		v.state = jsonNumberExponentDigits
+	case v.state == jsonNumberZero || v.state == jsonNumberInt || v.state == jsonNumberFraction || v.state == jsonNumberExponentDigits:
+		// c ends the number
+		v.state = jsonAfterValue
+		return v.read(c)
+	default:
+		return v.unexpected(c, "invalid number")
+	}
+	return nil
+}
+
+func (v *JSONStreamValidator) close() {
+	v.stack = v.stack[:len(v.stack)-1]
+	v.state = jsonAfterValue
+}
+
+func (v *JSONStreamValidator) unexpected(c byte, reason string) error {
+	return fmt.Errorf("invalid JSON at offset %d: unexpected %q, %s", v.offset, c, reason)
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isJSONHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
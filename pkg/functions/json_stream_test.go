@@ -0,0 +1,63 @@
+package functions_test
+
+import (
+	"strings"
+
+	. "github.com/mudler/LocalAI/pkg/functions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// validateJSON writes the document to a validator one character at a time, as the tokens are streamed
+func validateJSON(document string) (*JSONStreamValidator, error) {
+	v := NewJSONStreamValidator()
+	for _, c := range strings.Split(document, "") {
+		if err := v.Write(c); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+var _ = Describe("JSONStreamValidator", func() {
+	DescribeTable("accepts the valid documents",
+		func(document string) {
+			v, err := validateJSON(document)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v.Complete()).To(BeTrue())
+		},
+		Entry("empty object", `{}`),
+		Entry("empty array", ` [ ] `),
+		Entry("nested values", `{"a": [1, -2.5, 3e10, 0.1E-2, true, false, null], "b": {"c": "d"}}`),
+		Entry("escapes", `{"text": "line\nquote \" unicode é slash \/"}`),
+		Entry("number before the end of a container", `[0]`),
+		Entry("unicode characters", `{"name": "café ☕"}`),
+	)
+
+	DescribeTable("rejects the invalid documents at their first invalid character",
+		func(document string, validPrefix string) {
+			v := NewJSONStreamValidator()
+			Expect(v.Write(validPrefix)).To(Succeed())
+			Expect(v.Write(document[len(validPrefix):])).ToNot(Succeed())
+			// the error is kept for the next chunks
+			Expect(v.Write("}")).ToNot(Succeed())
+			Expect(v.Complete()).To(BeFalse())
+		},
+		Entry("text before the document", `Sure! {"a": 1}`, ``),
+		Entry("top level string", `"a"`, ``),
+		Entry("unquoted key", `{a: 1}`, `{`),
+		Entry("missing colon", `{"a" 1}`, `{"a" `),
+		Entry("trailing comma", `{"a": 1,}`, `{"a": 1,`),
+		Entry("mismatched container", `{"a": [1}`, `{"a": [1`),
+		Entry("leading zero", `[01]`, `[0`),
+		Entry("invalid literal", `[tru]`, `[tru`),
+		Entry("invalid escape", `["\x"]`, `["\`),
+		Entry("text after the document", `{} and more`, `{} `),
+	)
+
+	It("is not complete while the document is open", func() {
+		v, err := validateJSON(`{"a": [1, 2`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(v.Complete()).To(BeFalse())
+	})
+})
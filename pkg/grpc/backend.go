@@ -28,6 +28,7 @@ func NewGrpcClient(address string, parallel bool, wd WatchDog, enableWatchDog bo
 	return &Client{
 		address:  address,
 		parallel: parallel,
+		slots:    NewSlots(1),
 		wd:       wd,
 	}
 }
@@ -38,9 +39,19 @@ type Backend interface {
 	Embeddings(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.EmbeddingResult, error)
 	Predict(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.Reply, error)
 	LoadModel(ctx context.Context, in *pb.ModelOptions, opts ...grpc.CallOption) (*pb.Result, error)
-	PredictStream(ctx context.Context, in *pb.PredictOptions, f func(s []byte), opts ...grpc.CallOption) error
+	LoadModelStatus(ctx context.Context, in *pb.ModelOptions, f func(progress *pb.ModelLoadProgress), opts ...grpc.CallOption) (*pb.Result, error)
+	PredictStream(ctx context.Context, in *pb.PredictOptions, f func(reply *pb.Reply), opts ...grpc.CallOption) error
 	GenerateImage(ctx context.Context, in *pb.GenerateImageRequest, opts ...grpc.CallOption) (*pb.Result, error)
+	GenerateImageStream(ctx context.Context, in *pb.GenerateImageRequest, f func(progress *pb.GenerateImageProgress), opts ...grpc.CallOption) (*pb.Result, error)
+	ImageTransform(ctx context.Context, in *pb.ImageTransformRequest, opts ...grpc.CallOption) (*pb.Result, error)
+	Detect(ctx context.Context, in *pb.DetectRequest, opts ...grpc.CallOption) (*pb.DetectResponse, error)
+	Classify(ctx context.Context, in *pb.ClassifyRequest, opts ...grpc.CallOption) (*pb.ClassifyResponse, error)
+	ClassifyText(ctx context.Context, in *pb.TextClassifyRequest, opts ...grpc.CallOption) (*pb.ClassifyResponse, error)
+	ExtractEntities(ctx context.Context, in *pb.EntitiesRequest, opts ...grpc.CallOption) (*pb.EntitiesResponse, error)
+	Translate(ctx context.Context, in *pb.TranslateRequest, opts ...grpc.CallOption) (*pb.TranslateResponse, error)
 	TTS(ctx context.Context, in *pb.TTSRequest, opts ...grpc.CallOption) (*pb.Result, error)
+	SoundGeneration(ctx context.Context, in *pb.SoundGenerationRequest, opts ...grpc.CallOption) (*pb.Result, error)
+	ListVoices(ctx context.Context, in *pb.ListVoicesRequest, opts ...grpc.CallOption) (*pb.ListVoicesResponse, error)
 	AudioTranscription(ctx context.Context, in *pb.TranscriptRequest, opts ...grpc.CallOption) (*schema.TranscriptionResult, error)
 	TokenizeString(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.TokenizationResponse, error)
 	Status(ctx context.Context) (*pb.StatusResponse, error)
@@ -51,4 +62,7 @@ type Backend interface {
 	StoresFind(ctx context.Context, in *pb.StoresFindOptions, opts ...grpc.CallOption) (*pb.StoresFindResult, error)
 
 	Rerank(ctx context.Context, in *pb.RerankRequest, opts ...grpc.CallOption) (*pb.RerankResult, error)
+
+	SaveCache(ctx context.Context, in *pb.CacheRequest, opts ...grpc.CallOption) (*pb.Result, error)
+	LoadCache(ctx context.Context, in *pb.CacheRequest, opts ...grpc.CallOption) (*pb.Result, error)
 }
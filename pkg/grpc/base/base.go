@@ -53,14 +53,50 @@ func (llm *Base) GenerateImage(*pb.GenerateImageRequest) error {
 	return fmt.Errorf("unimplemented")
 }
 
+func (llm *Base) ImageTransform(*pb.ImageTransformRequest) error {
+	return fmt.Errorf("unimplemented")
+}
+
 func (llm *Base) AudioTranscription(*pb.TranscriptRequest) (schema.TranscriptionResult, error) {
 	return schema.TranscriptionResult{}, fmt.Errorf("unimplemented")
 }
 
+func (llm *Base) Detect(*pb.DetectRequest) (pb.DetectResponse, error) {
+	return pb.DetectResponse{}, fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) Classify(*pb.ClassifyRequest) (pb.ClassifyResponse, error) {
+	return pb.ClassifyResponse{}, fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) ClassifyText(*pb.TextClassifyRequest) (pb.ClassifyResponse, error) {
+	return pb.ClassifyResponse{}, fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) Rerank(*pb.RerankRequest) (pb.RerankResult, error) {
+	return pb.RerankResult{}, fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) ExtractEntities(*pb.EntitiesRequest) (pb.EntitiesResponse, error) {
+	return pb.EntitiesResponse{}, fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) Translate(*pb.TranslateRequest) (pb.TranslateResponse, error) {
+	return pb.TranslateResponse{}, fmt.Errorf("unimplemented")
+}
+
 func (llm *Base) TTS(*pb.TTSRequest) error {
 	return fmt.Errorf("unimplemented")
 }
 
+func (llm *Base) SoundGeneration(*pb.SoundGenerationRequest) error {
+	return fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) ListVoices(*pb.ListVoicesRequest) (pb.ListVoicesResponse, error) {
+	return pb.ListVoicesResponse{}, fmt.Errorf("unimplemented")
+}
+
 func (llm *Base) TokenizeString(opts *pb.PredictOptions) (pb.TokenizationResponse, error) {
 	return pb.TokenizationResponse{}, fmt.Errorf("unimplemented")
 }
@@ -88,6 +124,14 @@ func (llm *Base) StoresFind(*pb.StoresFindOptions) (pb.StoresFindResult, error)
 	return pb.StoresFindResult{}, fmt.Errorf("unimplemented")
 }
 
+func (llm *Base) SaveCache(*pb.CacheRequest) error {
+	return fmt.Errorf("unimplemented")
+}
+
+func (llm *Base) LoadCache(*pb.CacheRequest) error {
+	return fmt.Errorf("unimplemented")
+}
+
 func memoryUsage() *pb.MemoryUsageData {
 	mud := pb.MemoryUsageData{
 		Breakdown: make(map[string]uint64),
@@ -15,11 +15,12 @@ import (
 
 type Client struct {
 	address  string
-	busy     bool
+	busy     int
 	parallel bool
 	sync.Mutex
-	opMutex sync.Mutex
-	wd      WatchDog
+	// slots schedules the requests on the slots of the backend, one at a time unless it reports more slots
+	slots *Slots
+	wd    WatchDog
 }
 
 type WatchDog interface {
@@ -27,26 +28,51 @@ type WatchDog interface {
 	UnMark(address string)
 }
 
+// dial connects to the backend, passing the ID of the API request of the calls in their metadata
+func (c *Client) dial() (*grpc.ClientConn, error) {
+	return grpc.Dial(c.address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(requestIDUnaryClientInterceptor),
+		grpc.WithStreamInterceptor(requestIDStreamClientInterceptor),
+	)
+}
+
+// SetSlots sets the number of requests sent at once to the backend, as the slots reported by its status
+func (c *Client) SetSlots(n int) {
+	c.slots.Resize(n)
+}
+
+// SetPrioritySlots reserves slots of the backend for the priority requests
+func (c *Client) SetPrioritySlots(n int) {
+	c.slots.Reserve(n)
+}
+
+// Slots returns the scheduler of the requests on the slots of the backend
+func (c *Client) Slots() *Slots {
+	return c.slots
+}
+
 func (c *Client) IsBusy() bool {
 	c.Lock()
 	defer c.Unlock()
-	return c.busy
+	return c.busy > 0
 }
 
+// setBusy counts the requests running on the backend, which run at once on its slots
 func (c *Client) setBusy(v bool) {
 	c.Lock()
-	c.busy = v
+	if v {
+		c.busy++
+	} else {
+		c.busy--
+	}
 	c.Unlock()
 }
 
+// HealthCheck checks that the backend answers. It does not wait for a slot, nor mark the backend as busy:
+// the backends answer the health checks while generating
 func (c *Client) HealthCheck(ctx context.Context) (bool, error) {
-	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
-	}
-	c.setBusy(true)
-	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return false, err
 	}
@@ -62,17 +88,20 @@ func (c *Client) HealthCheck(ctx context.Context) (bool, error) {
 		return false, err
 	}
 
-	if string(res.Message) == "OK" {
+	if string(res.GetMessage()) == "OK" {
 		return true, nil
 	}
 
-	return false, fmt.Errorf("health check failed: %s", res.Message)
+	return false, fmt.Errorf("health check failed: %s", res.GetMessage())
 }
 
 func (c *Client) Embeddings(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.EmbeddingResult, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -80,7 +109,7 @@ func (c *Client) Embeddings(ctx context.Context, in *pb.PredictOptions, opts ...
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -92,8 +121,11 @@ func (c *Client) Embeddings(ctx context.Context, in *pb.PredictOptions, opts ...
 
 func (c *Client) Predict(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.Reply, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -101,7 +133,7 @@ func (c *Client) Predict(ctx context.Context, in *pb.PredictOptions, opts ...grp
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -113,8 +145,11 @@ func (c *Client) Predict(ctx context.Context, in *pb.PredictOptions, opts ...grp
 
 func (c *Client) LoadModel(ctx context.Context, in *pb.ModelOptions, opts ...grpc.CallOption) (*pb.Result, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -122,7 +157,7 @@ func (c *Client) LoadModel(ctx context.Context, in *pb.ModelOptions, opts ...grp
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -131,10 +166,13 @@ func (c *Client) LoadModel(ctx context.Context, in *pb.ModelOptions, opts ...grp
 	return client.LoadModel(ctx, in, opts...)
 }
 
-func (c *Client) PredictStream(ctx context.Context, in *pb.PredictOptions, f func(s []byte), opts ...grpc.CallOption) error {
+func (c *Client) LoadModelStatus(ctx context.Context, in *pb.ModelOptions, f func(progress *pb.ModelLoadProgress), opts ...grpc.CallOption) (*pb.Result, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -142,7 +180,48 @@ func (c *Client) PredictStream(ctx context.Context, in *pb.PredictOptions, f fun
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+
+	stream, err := client.LoadModelStatus(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			return nil, fmt.Errorf("the backend did not send the result of the loading")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if progress.Result != nil {
+			return progress.Result, nil
+		}
+		f(progress)
+	}
+}
+
+func (c *Client) PredictStream(ctx context.Context, in *pb.PredictOptions, f func(reply *pb.Reply), opts ...grpc.CallOption) error {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
 	if err != nil {
 		return err
 	}
@@ -164,7 +243,7 @@ func (c *Client) PredictStream(ctx context.Context, in *pb.PredictOptions, f fun
 
 			return err
 		}
-		f(feature.GetMessage())
+		f(feature)
 	}
 
 	return nil
@@ -172,8 +251,11 @@ func (c *Client) PredictStream(ctx context.Context, in *pb.PredictOptions, f fun
 
 func (c *Client) GenerateImage(ctx context.Context, in *pb.GenerateImageRequest, opts ...grpc.CallOption) (*pb.Result, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -181,7 +263,7 @@ func (c *Client) GenerateImage(ctx context.Context, in *pb.GenerateImageRequest,
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -190,10 +272,192 @@ func (c *Client) GenerateImage(ctx context.Context, in *pb.GenerateImageRequest,
 	return client.GenerateImage(ctx, in, opts...)
 }
 
+func (c *Client) GenerateImageStream(ctx context.Context, in *pb.GenerateImageRequest, f func(progress *pb.GenerateImageProgress), opts ...grpc.CallOption) (*pb.Result, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+
+	stream, err := client.GenerateImageStream(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			return nil, fmt.Errorf("the backend did not send the result of the generation")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if progress.Result != nil {
+			return progress.Result, nil
+		}
+		f(progress)
+	}
+}
+
+func (c *Client) ImageTransform(ctx context.Context, in *pb.ImageTransformRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.ImageTransform(ctx, in, opts...)
+}
+
+func (c *Client) Detect(ctx context.Context, in *pb.DetectRequest, opts ...grpc.CallOption) (*pb.DetectResponse, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.Detect(ctx, in, opts...)
+}
+
+func (c *Client) Classify(ctx context.Context, in *pb.ClassifyRequest, opts ...grpc.CallOption) (*pb.ClassifyResponse, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.Classify(ctx, in, opts...)
+}
+
+func (c *Client) ClassifyText(ctx context.Context, in *pb.TextClassifyRequest, opts ...grpc.CallOption) (*pb.ClassifyResponse, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.ClassifyText(ctx, in, opts...)
+}
+
+func (c *Client) ExtractEntities(ctx context.Context, in *pb.EntitiesRequest, opts ...grpc.CallOption) (*pb.EntitiesResponse, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.ExtractEntities(ctx, in, opts...)
+}
+
+func (c *Client) Translate(ctx context.Context, in *pb.TranslateRequest, opts ...grpc.CallOption) (*pb.TranslateResponse, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.Translate(ctx, in, opts...)
+}
+
 func (c *Client) TTS(ctx context.Context, in *pb.TTSRequest, opts ...grpc.CallOption) (*pb.Result, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -201,7 +465,7 @@ func (c *Client) TTS(ctx context.Context, in *pb.TTSRequest, opts ...grpc.CallOp
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -210,10 +474,36 @@ func (c *Client) TTS(ctx context.Context, in *pb.TTSRequest, opts ...grpc.CallOp
 	return client.TTS(ctx, in, opts...)
 }
 
+func (c *Client) SoundGeneration(ctx context.Context, in *pb.SoundGenerationRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	if c.wd != nil {
+		c.wd.Mark(c.address)
+		defer c.wd.UnMark(c.address)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.SoundGeneration(ctx, in, opts...)
+}
+
 func (c *Client) AudioTranscription(ctx context.Context, in *pb.TranscriptRequest, opts ...grpc.CallOption) (*schema.TranscriptionResult, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -221,7 +511,7 @@ func (c *Client) AudioTranscription(ctx context.Context, in *pb.TranscriptReques
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -231,29 +521,16 @@ func (c *Client) AudioTranscription(ctx context.Context, in *pb.TranscriptReques
 	if err != nil {
 		return nil, err
 	}
-	tresult := &schema.TranscriptionResult{}
-	for _, s := range res.Segments {
-		tks := []int{}
-		for _, t := range s.Tokens {
-			tks = append(tks, int(t))
-		}
-		tresult.Segments = append(tresult.Segments,
-			schema.Segment{
-				Text:   s.Text,
-				Id:     int(s.Id),
-				Start:  time.Duration(s.Start),
-				End:    time.Duration(s.End),
-				Tokens: tks,
-			})
-	}
-	tresult.Text = res.Text
-	return tresult, err
+	return transcriptResultFromProto(res), nil
 }
 
 func (c *Client) TokenizeString(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.TokenizationResponse, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
@@ -261,7 +538,7 @@ func (c *Client) TokenizeString(ctx context.Context, in *pb.PredictOptions, opts
 		c.wd.Mark(c.address)
 		defer c.wd.UnMark(c.address)
 	}
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -276,14 +553,9 @@ func (c *Client) TokenizeString(ctx context.Context, in *pb.PredictOptions, opts
 	return res, nil
 }
 
+// Status returns the state of the backend, as its slots. As the health checks, it does not wait for a slot
 func (c *Client) Status(ctx context.Context) (*pb.StatusResponse, error) {
-	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
-	}
-	c.setBusy(true)
-	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -294,12 +566,15 @@ func (c *Client) Status(ctx context.Context) (*pb.StatusResponse, error) {
 
 func (c *Client) StoresSet(ctx context.Context, in *pb.StoresSetOptions, opts ...grpc.CallOption) (*pb.Result, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -310,12 +585,15 @@ func (c *Client) StoresSet(ctx context.Context, in *pb.StoresSetOptions, opts ..
 
 func (c *Client) StoresDelete(ctx context.Context, in *pb.StoresDeleteOptions, opts ...grpc.CallOption) (*pb.Result, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -326,12 +604,15 @@ func (c *Client) StoresDelete(ctx context.Context, in *pb.StoresDeleteOptions, o
 
 func (c *Client) StoresGet(ctx context.Context, in *pb.StoresGetOptions, opts ...grpc.CallOption) (*pb.StoresGetResult, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -342,12 +623,15 @@ func (c *Client) StoresGet(ctx context.Context, in *pb.StoresGetOptions, opts ..
 
 func (c *Client) StoresFind(ctx context.Context, in *pb.StoresFindOptions, opts ...grpc.CallOption) (*pb.StoresFindResult, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -358,12 +642,15 @@ func (c *Client) StoresFind(ctx context.Context, in *pb.StoresFindOptions, opts
 
 func (c *Client) Rerank(ctx context.Context, in *pb.RerankRequest, opts ...grpc.CallOption) (*pb.RerankResult, error) {
 	if !c.parallel {
-		c.opMutex.Lock()
-		defer c.opMutex.Unlock()
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 	}
 	c.setBusy(true)
 	defer c.setBusy(false)
-	conn, err := grpc.Dial(c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := c.dial()
 	if err != nil {
 		return nil, err
 	}
@@ -371,3 +658,44 @@ func (c *Client) Rerank(ctx context.Context, in *pb.RerankRequest, opts ...grpc.
 	client := pb.NewBackendClient(conn)
 	return client.Rerank(ctx, in, opts...)
 }
+
+// SaveCache and LoadCache do not wait for the slots: the cache is saved before unloading the model and
+// restored once it is loaded, when no request is processed
+func (c *Client) SaveCache(ctx context.Context, in *pb.CacheRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.SaveCache(ctx, in, opts...)
+}
+
+func (c *Client) LoadCache(ctx context.Context, in *pb.CacheRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.LoadCache(ctx, in, opts...)
+}
+
+func (c *Client) ListVoices(ctx context.Context, in *pb.ListVoicesRequest, opts ...grpc.CallOption) (*pb.ListVoicesResponse, error) {
+	if !c.parallel {
+		release, err := c.slots.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	c.setBusy(true)
+	defer c.setBusy(false)
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := pb.NewBackendClient(conn)
+	return client.ListVoices(ctx, in, opts...)
+}
@@ -0,0 +1,28 @@
+package grpc_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/mudler/LocalAI/pkg/grpc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	It("checks the health and the status of the backends without waiting for a slot", func() {
+		client := NewGrpcClient("127.0.0.1:1", false, nil, false).(*Client)
+		release, err := client.Slots().Acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// the backend is not running: the calls fail right away, instead of waiting for the slot
+		client.HealthCheck(ctx)
+		client.Status(ctx)
+		Expect(ctx.Err()).ToNot(HaveOccurred())
+		Expect(client.IsBusy()).To(BeFalse())
+	})
+})
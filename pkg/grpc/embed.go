@@ -2,7 +2,7 @@ package grpc
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"github.com/mudler/LocalAI/core/schema"
 	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
@@ -12,6 +12,8 @@ import (
 
 var _ Backend = new(embedBackend)
 var _ pb.Backend_PredictStreamServer = new(embedBackendServerStream)
+var _ pb.Backend_LoadModelStatusServer = new(embedBackendLoadStream)
+var _ pb.Backend_GenerateImageStreamServer = new(embedBackendImageStream)
 
 type embedBackend struct {
 	s *server
@@ -37,7 +39,26 @@ func (e *embedBackend) LoadModel(ctx context.Context, in *pb.ModelOptions, opts
 	return e.s.LoadModel(ctx, in)
 }
 
-func (e *embedBackend) PredictStream(ctx context.Context, in *pb.PredictOptions, f func(s []byte), opts ...grpc.CallOption) error {
+func (e *embedBackend) LoadModelStatus(ctx context.Context, in *pb.ModelOptions, f func(progress *pb.ModelLoadProgress), opts ...grpc.CallOption) (*pb.Result, error) {
+	var result *pb.Result
+	ls := &embedBackendLoadStream{
+		ctx: ctx,
+		fn: func(progress *pb.ModelLoadProgress) {
+			if progress.Result != nil {
+				result = progress.Result
+				return
+			}
+			f(progress)
+		},
+	}
+	err := e.s.LoadModelStatus(in, ls)
+	if result == nil && err == nil {
+		err = fmt.Errorf("the backend did not send the result of the loading")
+	}
+	return result, err
+}
+
+func (e *embedBackend) PredictStream(ctx context.Context, in *pb.PredictOptions, f func(reply *pb.Reply), opts ...grpc.CallOption) error {
 	bs := &embedBackendServerStream{
 		ctx: ctx,
 		fn:  f,
@@ -49,32 +70,63 @@ func (e *embedBackend) GenerateImage(ctx context.Context, in *pb.GenerateImageRe
 	return e.s.GenerateImage(ctx, in)
 }
 
+func (e *embedBackend) GenerateImageStream(ctx context.Context, in *pb.GenerateImageRequest, f func(progress *pb.GenerateImageProgress), opts ...grpc.CallOption) (*pb.Result, error) {
+	var result *pb.Result
+	is := &embedBackendImageStream{
+		ctx: ctx,
+		fn: func(progress *pb.GenerateImageProgress) {
+			if progress.Result != nil {
+				result = progress.Result
+				return
+			}
+			f(progress)
+		},
+	}
+	err := e.s.GenerateImageStream(in, is)
+	if result == nil && err == nil {
+		err = fmt.Errorf("the backend did not send the result of the generation")
+	}
+	return result, err
+}
+
+func (e *embedBackend) ImageTransform(ctx context.Context, in *pb.ImageTransformRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	return e.s.ImageTransform(ctx, in)
+}
+
+func (e *embedBackend) Detect(ctx context.Context, in *pb.DetectRequest, opts ...grpc.CallOption) (*pb.DetectResponse, error) {
+	return e.s.Detect(ctx, in)
+}
+
+func (e *embedBackend) Classify(ctx context.Context, in *pb.ClassifyRequest, opts ...grpc.CallOption) (*pb.ClassifyResponse, error) {
+	return e.s.Classify(ctx, in)
+}
+
+func (e *embedBackend) ClassifyText(ctx context.Context, in *pb.TextClassifyRequest, opts ...grpc.CallOption) (*pb.ClassifyResponse, error) {
+	return e.s.ClassifyText(ctx, in)
+}
+
+func (e *embedBackend) ExtractEntities(ctx context.Context, in *pb.EntitiesRequest, opts ...grpc.CallOption) (*pb.EntitiesResponse, error) {
+	return e.s.ExtractEntities(ctx, in)
+}
+
+func (e *embedBackend) Translate(ctx context.Context, in *pb.TranslateRequest, opts ...grpc.CallOption) (*pb.TranslateResponse, error) {
+	return e.s.Translate(ctx, in)
+}
+
 func (e *embedBackend) TTS(ctx context.Context, in *pb.TTSRequest, opts ...grpc.CallOption) (*pb.Result, error) {
 	return e.s.TTS(ctx, in)
 }
 
+func (e *embedBackend) SoundGeneration(ctx context.Context, in *pb.SoundGenerationRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	return e.s.SoundGeneration(ctx, in)
+}
+
 func (e *embedBackend) AudioTranscription(ctx context.Context, in *pb.TranscriptRequest, opts ...grpc.CallOption) (*schema.TranscriptionResult, error) {
 	r, err := e.s.AudioTranscription(ctx, in)
 	if err != nil {
 		return nil, err
 	}
-	tr := &schema.TranscriptionResult{}
-	for _, s := range r.Segments {
-		var tks []int
-		for _, t := range s.Tokens {
-			tks = append(tks, int(t))
-		}
-		tr.Segments = append(tr.Segments,
-			schema.Segment{
-				Text:   s.Text,
-				Id:     int(s.Id),
-				Start:  time.Duration(s.Start),
-				End:    time.Duration(s.End),
-				Tokens: tks,
-			})
-	}
-	tr.Text = r.Text
-	return tr, err
+	return transcriptResultFromProto(r), nil
 }
 
 func (e *embedBackend) TokenizeString(ctx context.Context, in *pb.PredictOptions, opts ...grpc.CallOption) (*pb.TokenizationResponse, error) {
@@ -101,17 +153,29 @@ func (e *embedBackend) StoresFind(ctx context.Context, in *pb.StoresFindOptions,
 	return e.s.StoresFind(ctx, in)
 }
 
+func (e *embedBackend) ListVoices(ctx context.Context, in *pb.ListVoicesRequest, opts ...grpc.CallOption) (*pb.ListVoicesResponse, error) {
+	return e.s.ListVoices(ctx, in)
+}
+
 func (e *embedBackend) Rerank(ctx context.Context, in *pb.RerankRequest, opts ...grpc.CallOption) (*pb.RerankResult, error) {
 	return e.s.Rerank(ctx, in)
 }
 
+func (e *embedBackend) SaveCache(ctx context.Context, in *pb.CacheRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	return e.s.SaveCache(ctx, in)
+}
+
+func (e *embedBackend) LoadCache(ctx context.Context, in *pb.CacheRequest, opts ...grpc.CallOption) (*pb.Result, error) {
+	return e.s.LoadCache(ctx, in)
+}
+
 type embedBackendServerStream struct {
 	ctx context.Context
-	fn  func(s []byte)
+	fn  func(reply *pb.Reply)
 }
 
 func (e *embedBackendServerStream) Send(reply *pb.Reply) error {
-	e.fn(reply.GetMessage())
+	e.fn(reply)
 	return nil
 }
 
@@ -140,3 +204,75 @@ func (e *embedBackendServerStream) SendMsg(m any) error {
 func (e *embedBackendServerStream) RecvMsg(m any) error {
 	return nil
 }
+
+type embedBackendLoadStream struct {
+	ctx context.Context
+	fn  func(progress *pb.ModelLoadProgress)
+}
+
+func (e *embedBackendLoadStream) Send(progress *pb.ModelLoadProgress) error {
+	e.fn(progress)
+	return nil
+}
+
+func (e *embedBackendLoadStream) SetHeader(md metadata.MD) error {
+	return nil
+}
+
+func (e *embedBackendLoadStream) SendHeader(md metadata.MD) error {
+	return nil
+}
+
+func (e *embedBackendLoadStream) SetTrailer(md metadata.MD) {
+}
+
+func (e *embedBackendLoadStream) Context() context.Context {
+	return e.ctx
+}
+
+func (e *embedBackendLoadStream) SendMsg(m any) error {
+	if x, ok := m.(*pb.ModelLoadProgress); ok {
+		return e.Send(x)
+	}
+	return nil
+}
+
+func (e *embedBackendLoadStream) RecvMsg(m any) error {
+	return nil
+}
+
+type embedBackendImageStream struct {
+	ctx context.Context
+	fn  func(progress *pb.GenerateImageProgress)
+}
+
+func (e *embedBackendImageStream) Send(progress *pb.GenerateImageProgress) error {
+	e.fn(progress)
+	return nil
+}
+
+func (e *embedBackendImageStream) SetHeader(md metadata.MD) error {
+	return nil
+}
+
+func (e *embedBackendImageStream) SendHeader(md metadata.MD) error {
+	return nil
+}
+
+func (e *embedBackendImageStream) SetTrailer(md metadata.MD) {
+}
+
+func (e *embedBackendImageStream) Context() context.Context {
+	return e.ctx
+}
+
+func (e *embedBackendImageStream) SendMsg(m any) error {
+	if x, ok := m.(*pb.GenerateImageProgress); ok {
+		return e.Send(x)
+	}
+	return nil
+}
+
+func (e *embedBackendImageStream) RecvMsg(m any) error {
+	return nil
+}
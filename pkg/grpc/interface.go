@@ -15,8 +15,17 @@ type LLM interface {
 	Load(*pb.ModelOptions) error
 	Embeddings(*pb.PredictOptions) ([]float32, error)
 	GenerateImage(*pb.GenerateImageRequest) error
+	ImageTransform(*pb.ImageTransformRequest) error
+	Detect(*pb.DetectRequest) (pb.DetectResponse, error)
+	Classify(*pb.ClassifyRequest) (pb.ClassifyResponse, error)
+	ClassifyText(*pb.TextClassifyRequest) (pb.ClassifyResponse, error)
+	Rerank(*pb.RerankRequest) (pb.RerankResult, error)
+	ExtractEntities(*pb.EntitiesRequest) (pb.EntitiesResponse, error)
+	Translate(*pb.TranslateRequest) (pb.TranslateResponse, error)
 	AudioTranscription(*pb.TranscriptRequest) (schema.TranscriptionResult, error)
 	TTS(*pb.TTSRequest) error
+	SoundGeneration(*pb.SoundGenerationRequest) error
+	ListVoices(*pb.ListVoicesRequest) (pb.ListVoicesResponse, error)
 	TokenizeString(*pb.PredictOptions) (pb.TokenizationResponse, error)
 	Status() (pb.StatusResponse, error)
 
@@ -24,6 +33,35 @@ type LLM interface {
 	StoresDelete(*pb.StoresDeleteOptions) error
 	StoresGet(*pb.StoresGetOptions) (pb.StoresGetResult, error)
 	StoresFind(*pb.StoresFindOptions) (pb.StoresFindResult, error)
+
+	SaveCache(*pb.CacheRequest) error
+	LoadCache(*pb.CacheRequest) error
+}
+
+// Stages of the loading of a model reported by LoadModelStatus
+const (
+	LoadStageDownload = "download"
+	LoadStageMmap     = "mmap"
+	LoadStageWarmup   = "warmup"
+	// LoadStageLoad is reported by the backends which do not report the progress of their stages
+	LoadStageLoad = "load"
+)
+
+// Operations of ImageTransform
+const (
+	ImageTransformUpscale          = "upscale"
+	ImageTransformRemoveBackground = "remove-background"
+)
+
+// ProgressLoader is implemented by the backends which report the progress of the loading of the models
+type ProgressLoader interface {
+	LoadWithProgress(opts *pb.ModelOptions, progress func(stage string, percent float32)) error
+}
+
+// ProgressImageGenerator is implemented by the backends which report the steps of the generation of the images,
+// with a preview of the image every PreviewInterval steps when it is set
+type ProgressImageGenerator interface {
+	GenerateImageWithProgress(req *pb.GenerateImageRequest, progress func(step, steps int32, preview []byte)) error
 }
 
 func newReply(s string) *pb.Reply {
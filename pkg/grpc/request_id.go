@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/mudler/LocalAI/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDMetadata is the key of the gRPC metadata with the ID of the API request of the calls
+var requestIDMetadata = strings.ToLower(logging.RequestIDHeader)
+
+// withRequestIDMetadata passes the ID of the API request of the context to the backend
+func withRequestIDMetadata(ctx context.Context, method string, cc *grpc.ClientConn) context.Context {
+	id := logging.RequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+	logging.Logger(logging.GRPC).Debug().Str("request_id", id).Str("backend", cc.Target()).Str("method", method).Msg("calling backend")
+	return metadata.AppendToOutgoingContext(ctx, requestIDMetadata, id)
+}
+
+func requestIDUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(withRequestIDMetadata(ctx, method, cc), method, req, reply, cc, opts...)
+}
+
+func requestIDStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(withRequestIDMetadata(ctx, method, cc), desc, cc, method, opts...)
+}
+
+// logRequestID writes the ID of the API request of the call in the output of the backend, which is
+// logged by LocalAI along with the rest of the output
+func logRequestID(ctx context.Context, method string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return
+	}
+	if ids := md.Get(requestIDMetadata); len(ids) > 0 {
+		log.Printf("request_id=%s method=%s", ids[0], method)
+	}
+}
+
+func requestIDUnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	logRequestID(ctx, info.FullMethod)
+	return handler(ctx, req)
+}
+
+func requestIDStreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	logRequestID(ss.Context(), info.FullMethod)
+	return handler(srv, ss)
+}
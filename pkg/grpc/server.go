@@ -54,6 +54,40 @@ func (s *server) LoadModel(ctx context.Context, in *pb.ModelOptions) (*pb.Result
 	return &pb.Result{Message: "Loading succeeded", Success: true}, nil
 }
 
+// LoadModelStatus loads the model as LoadModel, streaming the progress of its stages. The backends which
+// do not implement ProgressLoader only report the start and the end of the loading
+func (s *server) LoadModelStatus(in *pb.ModelOptions, stream pb.Backend_LoadModelStatusServer) error {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+
+	var err error
+	if pl, ok := s.llm.(ProgressLoader); ok {
+		err = pl.LoadWithProgress(in, func(stage string, percent float32) {
+			if sendErr := stream.Send(&pb.ModelLoadProgress{Stage: stage, Progress: percent}); sendErr != nil {
+				log.Printf("failed sending the loading progress: %v", sendErr)
+			}
+		})
+	} else {
+		if err := stream.Send(&pb.ModelLoadProgress{Stage: LoadStageLoad, Progress: 0}); err != nil {
+			return err
+		}
+		err = s.llm.Load(in)
+		if err == nil {
+			if err := stream.Send(&pb.ModelLoadProgress{Stage: LoadStageLoad, Progress: 100}); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Error loading model: %s", err.Error())
+		stream.Send(&pb.ModelLoadProgress{Message: msg, Result: &pb.Result{Message: msg, Success: false}})
+		return err
+	}
+	return stream.Send(&pb.ModelLoadProgress{Message: "Loading succeeded", Result: &pb.Result{Message: "Loading succeeded", Success: true}})
+}
+
 func (s *server) Predict(ctx context.Context, in *pb.PredictOptions) (*pb.Reply, error) {
 	if s.llm.Locking() {
 		s.llm.Lock()
@@ -75,6 +109,124 @@ func (s *server) GenerateImage(ctx context.Context, in *pb.GenerateImageRequest)
 	return &pb.Result{Message: "Image generated", Success: true}, nil
 }
 
+// GenerateImageStream generates the image as GenerateImage, streaming its steps. The backends which
+// do not implement ProgressImageGenerator only report the start and the end of the generation
+func (s *server) GenerateImageStream(in *pb.GenerateImageRequest, stream pb.Backend_GenerateImageStreamServer) error {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+
+	var err error
+	if pg, ok := s.llm.(ProgressImageGenerator); ok {
+		err = pg.GenerateImageWithProgress(in, func(step, steps int32, preview []byte) {
+			if sendErr := stream.Send(&pb.GenerateImageProgress{Step: step, Steps: steps, Preview: preview}); sendErr != nil {
+				log.Printf("failed sending the generation progress: %v", sendErr)
+			}
+		})
+	} else {
+		if err := stream.Send(&pb.GenerateImageProgress{Step: 0, Steps: in.Step}); err != nil {
+			return err
+		}
+		err = s.llm.GenerateImage(in)
+		if err == nil {
+			if err := stream.Send(&pb.GenerateImageProgress{Step: in.Step, Steps: in.Step}); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Error generating image: %s", err.Error())
+		stream.Send(&pb.GenerateImageProgress{Result: &pb.Result{Message: msg, Success: false}})
+		return err
+	}
+	return stream.Send(&pb.GenerateImageProgress{Result: &pb.Result{Message: "Image generated", Success: true}})
+}
+
+func (s *server) ImageTransform(ctx context.Context, in *pb.ImageTransformRequest) (*pb.Result, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	err := s.llm.ImageTransform(in)
+	if err != nil {
+		return &pb.Result{Message: fmt.Sprintf("Error transforming image: %s", err.Error()), Success: false}, err
+	}
+	return &pb.Result{Message: "Image transformed", Success: true}, nil
+}
+
+func (s *server) Detect(ctx context.Context, in *pb.DetectRequest) (*pb.DetectResponse, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.Detect(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *server) Classify(ctx context.Context, in *pb.ClassifyRequest) (*pb.ClassifyResponse, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.Classify(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *server) ClassifyText(ctx context.Context, in *pb.TextClassifyRequest) (*pb.ClassifyResponse, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.ClassifyText(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *server) Rerank(ctx context.Context, in *pb.RerankRequest) (*pb.RerankResult, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.Rerank(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *server) ExtractEntities(ctx context.Context, in *pb.EntitiesRequest) (*pb.EntitiesResponse, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.ExtractEntities(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *server) Translate(ctx context.Context, in *pb.TranslateRequest) (*pb.TranslateResponse, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.Translate(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 func (s *server) TTS(ctx context.Context, in *pb.TTSRequest) (*pb.Result, error) {
 	if s.llm.Locking() {
 		s.llm.Lock()
@@ -87,6 +239,30 @@ func (s *server) TTS(ctx context.Context, in *pb.TTSRequest) (*pb.Result, error)
 	return &pb.Result{Message: "Audio generated", Success: true}, nil
 }
 
+func (s *server) SoundGeneration(ctx context.Context, in *pb.SoundGenerationRequest) (*pb.Result, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	err := s.llm.SoundGeneration(in)
+	if err != nil {
+		return &pb.Result{Message: fmt.Sprintf("Error generating audio: %s", err.Error()), Success: false}, err
+	}
+	return &pb.Result{Message: "Sound Generation audio generated", Success: true}, nil
+}
+
+func (s *server) ListVoices(ctx context.Context, in *pb.ListVoicesRequest) (*pb.ListVoicesResponse, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	res, err := s.llm.ListVoices(in)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 func (s *server) AudioTranscription(ctx context.Context, in *pb.TranscriptRequest) (*pb.TranscriptResult, error) {
 	if s.llm.Locking() {
 		s.llm.Lock()
@@ -96,24 +272,7 @@ func (s *server) AudioTranscription(ctx context.Context, in *pb.TranscriptReques
 	if err != nil {
 		return nil, err
 	}
-	tresult := &pb.TranscriptResult{}
-	for _, s := range result.Segments {
-		tks := []int32{}
-		for _, t := range s.Tokens {
-			tks = append(tks, int32(t))
-		}
-		tresult.Segments = append(tresult.Segments,
-			&pb.TranscriptSegment{
-				Text:   s.Text,
-				Id:     int32(s.Id),
-				Start:  int64(s.Start),
-				End:    int64(s.End),
-				Tokens: tks,
-			})
-	}
-
-	tresult.Text = result.Text
-	return tresult, nil
+	return transcriptResultToProto(result), nil
 }
 
 func (s *server) PredictStream(in *pb.PredictOptions, stream pb.Backend_PredictStreamServer) error {
@@ -215,12 +374,39 @@ func (s *server) StoresFind(ctx context.Context, in *pb.StoresFindOptions) (*pb.
 	return &res, nil
 }
 
+func (s *server) SaveCache(ctx context.Context, in *pb.CacheRequest) (*pb.Result, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	err := s.llm.SaveCache(in)
+	if err != nil {
+		return &pb.Result{Message: fmt.Sprintf("Error saving the cache: %s", err.Error()), Success: false}, err
+	}
+	return &pb.Result{Message: "Saved the cache", Success: true}, nil
+}
+
+func (s *server) LoadCache(ctx context.Context, in *pb.CacheRequest) (*pb.Result, error) {
+	if s.llm.Locking() {
+		s.llm.Lock()
+		defer s.llm.Unlock()
+	}
+	err := s.llm.LoadCache(in)
+	if err != nil {
+		return &pb.Result{Message: fmt.Sprintf("Error loading the cache: %s", err.Error()), Success: false}, err
+	}
+	return &pb.Result{Message: "Loaded the cache", Success: true}, nil
+}
+
 func StartServer(address string, model LLM) error {
 	lis, err := net.Listen("tcp", address)
 	if err != nil {
 		return err
 	}
-	s := grpc.NewServer()
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(requestIDUnaryServerInterceptor),
+		grpc.StreamInterceptor(requestIDStreamServerInterceptor),
+	)
 	pb.RegisterBackendServer(s, &server{llm: model})
 	log.Printf("gRPC Server listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
@@ -235,7 +421,10 @@ func RunServer(address string, model LLM) (func() error, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := grpc.NewServer()
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(requestIDUnaryServerInterceptor),
+		grpc.StreamInterceptor(requestIDStreamServerInterceptor),
+	)
 	pb.RegisterBackendServer(s, &server{llm: model})
 	log.Printf("gRPC Server listening at %v", lis.Addr())
 	if err = s.Serve(lis); err != nil {
@@ -0,0 +1,143 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+)
+
+// Slots schedules the requests to a backend on its slots: as many requests run at once as the backend has
+// slots, the others wait for a free slot in their order of arrival, or until they are cancelled. The
+// priority requests, as the code completions, are given the free slots before the other requests, and can
+// have slots reserved for them
+type Slots struct {
+	mu   sync.Mutex
+	size int
+	// reserved slots are only used by the priority requests
+	reserved int
+	busy     int
+	waiting  []chan struct{}
+	priority []chan struct{}
+}
+
+// NewSlots returns the scheduler of a backend with the number of slots, at least one
+func NewSlots(size int) *Slots {
+	return &Slots{size: max(size, 1)}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests to the backends are scheduled before the other requests
+func WithPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, priorityKey{}, true)
+}
+
+// IsPriority tells if the requests of the context are scheduled before the other requests
+func IsPriority(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	priority, _ := ctx.Value(priorityKey{}).(bool)
+	return priority
+}
+
+// Acquire waits for a free slot, and returns the function freeing it
+func (s *Slots) Acquire(ctx context.Context) (func(), error) {
+	priority := IsPriority(ctx)
+
+	s.mu.Lock()
+	if priority && s.busy < s.size && len(s.priority) == 0 ||
+		!priority && s.busy < s.available() && len(s.waiting) == 0 && len(s.priority) == 0 {
+		s.busy++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+	ready := make(chan struct{})
+	if priority {
+		s.priority = append(s.priority, ready)
+	} else {
+		s.waiting = append(s.waiting, ready)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.dequeue(ready) {
+			return nil, ctx.Err()
+		}
+		// The slot was given to the request while it was cancelled
+		s.busy--
+		s.next()
+		return nil, ctx.Err()
+	}
+}
+
+// Resize sets the number of slots, at least one, when the backend reports them
+func (s *Slots) Resize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.size = max(size, 1)
+	s.next()
+}
+
+// Reserve keeps slots for the priority requests, so they do not wait for the other requests to finish.
+// One slot at least is left to the other requests
+func (s *Slots) Reserve(reserved int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reserved = max(reserved, 0)
+	s.next()
+}
+
+// Usage returns the number of slots and the number of requests running and waiting for a slot
+func (s *Slots) Usage() (size, busy, waiting int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size, s.busy, len(s.waiting) + len(s.priority)
+}
+
+func (s *Slots) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busy--
+	s.next()
+}
+
+// available returns the number of slots the requests without priority can use. Called with the lock held
+func (s *Slots) available() int {
+	return s.size - min(s.reserved, s.size-1)
+}
+
+// dequeue removes the request from the requests waiting, if it was not given a slot. Called with the lock held
+func (s *Slots) dequeue(ready chan struct{}) bool {
+	for i, w := range s.priority {
+		if w == ready {
+			s.priority = append(s.priority[:i], s.priority[i+1:]...)
+			return true
+		}
+	}
+	for i, w := range s.waiting {
+		if w == ready {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// next gives the free slots to the requests waiting, the priority requests first. Called with the lock held
+func (s *Slots) next() {
+	for s.busy < s.size && len(s.priority) > 0 {
+		s.busy++
+		close(s.priority[0])
+		s.priority = s.priority[1:]
+	}
+	for s.busy < s.available() && len(s.waiting) > 0 {
+		s.busy++
+		close(s.waiting[0])
+		s.waiting = s.waiting[1:]
+	}
+}
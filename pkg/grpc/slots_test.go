@@ -0,0 +1,121 @@
+package grpc_test
+
+import (
+	"context"
+
+	. "github.com/mudler/LocalAI/pkg/grpc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Slots", func() {
+	It("runs as many requests at once as there are slots", func() {
+		slots := NewSlots(2)
+		first, err := slots.Acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		second, err := slots.Acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		acquired := make(chan func())
+		go func() {
+			release, _ := slots.Acquire(context.Background())
+			acquired <- release
+		}()
+		Eventually(func() int { _, _, waiting := slots.Usage(); return waiting }).Should(Equal(1))
+		Consistently(acquired).ShouldNot(Receive())
+
+		first()
+		var third func()
+		Eventually(acquired).Should(Receive(&third))
+		size, busy, waiting := slots.Usage()
+		Expect([]int{size, busy, waiting}).To(Equal([]int{2, 2, 0}))
+		second()
+		third()
+		_, busy, _ = slots.Usage()
+		Expect(busy).To(BeZero())
+	})
+
+	It("gives the slots to the waiting requests when the backend reports more slots", func() {
+		slots := NewSlots(1)
+		release, _ := slots.Acquire(context.Background())
+		defer release()
+
+		acquired := make(chan struct{})
+		go func() {
+			r, _ := slots.Acquire(context.Background())
+			defer r()
+			close(acquired)
+		}()
+		Eventually(func() int { _, _, waiting := slots.Usage(); return waiting }).Should(Equal(1))
+		slots.Resize(2)
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	It("stops waiting for a slot when the request is cancelled", func() {
+		slots := NewSlots(1)
+		release, _ := slots.Acquire(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := slots.Acquire(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+		_, busy, waiting := slots.Usage()
+		Expect([]int{busy, waiting}).To(Equal([]int{1, 0}))
+
+		release()
+		_, busy, _ = slots.Usage()
+		Expect(busy).To(BeZero())
+	})
+
+	It("gives the free slots to the priority requests first", func() {
+		slots := NewSlots(1)
+		release, _ := slots.Acquire(context.Background())
+
+		order := make(chan string, 2)
+		acquire := func(ctx context.Context, name string) {
+			r, err := slots.Acquire(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			order <- name
+			r()
+		}
+		go acquire(context.Background(), "chat")
+		Eventually(func() int { _, _, waiting := slots.Usage(); return waiting }).Should(Equal(1))
+		go acquire(WithPriority(context.Background()), "completion")
+		Eventually(func() int { _, _, waiting := slots.Usage(); return waiting }).Should(Equal(2))
+
+		release()
+		Eventually(order).Should(Receive(Equal("completion")))
+		Eventually(order).Should(Receive(Equal("chat")))
+	})
+
+	It("keeps the reserved slots for the priority requests", func() {
+		slots := NewSlots(2)
+		slots.Reserve(1)
+		release, err := slots.Acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		acquired := make(chan struct{})
+		go func() {
+			if r, err := slots.Acquire(ctx); err == nil {
+				r()
+				close(acquired)
+			}
+		}()
+		Eventually(func() int { _, _, waiting := slots.Usage(); return waiting }).Should(Equal(1))
+		Consistently(acquired).ShouldNot(BeClosed())
+
+		priority, err := slots.Acquire(WithPriority(context.Background()))
+		Expect(err).ToNot(HaveOccurred())
+		priority()
+
+		// One slot at least is left to the other requests
+		slots.Resize(1)
+		release()
+		Eventually(acquired).Should(BeClosed())
+		_, busy, _ := slots.Usage()
+		Expect(busy).To(BeZero())
+	})
+})
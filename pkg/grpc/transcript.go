@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"time"
+
+	"github.com/mudler/LocalAI/core/schema"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+)
+
+func transcriptResultFromProto(r *pb.TranscriptResult) *schema.TranscriptionResult {
+	tr := &schema.TranscriptionResult{
+		Text:                r.Text,
+		Language:            r.Language,
+		LanguageProbability: r.LanguageProbability,
+	}
+	for _, s := range r.Segments {
+		tks := []int{}
+		for _, t := range s.Tokens {
+			tks = append(tks, int(t))
+		}
+		var words []schema.Word
+		for _, w := range s.Words {
+			words = append(words, schema.Word{
+				Word:        w.Word,
+				Start:       time.Duration(w.Start),
+				End:         time.Duration(w.End),
+				Probability: w.Probability,
+			})
+		}
+		tr.Segments = append(tr.Segments,
+			schema.Segment{
+				Text:    s.Text,
+				Id:      int(s.Id),
+				Start:   time.Duration(s.Start),
+				End:     time.Duration(s.End),
+				Tokens:  tks,
+				Words:   words,
+				Speaker: s.Speaker,
+			})
+	}
+	return tr
+}
+
+func transcriptResultToProto(r schema.TranscriptionResult) *pb.TranscriptResult {
+	tr := &pb.TranscriptResult{
+		Text:                r.Text,
+		Language:            r.Language,
+		LanguageProbability: r.LanguageProbability,
+	}
+	for _, s := range r.Segments {
+		tks := []int32{}
+		for _, t := range s.Tokens {
+			tks = append(tks, int32(t))
+		}
+		words := []*pb.TranscriptWord{}
+		for _, w := range s.Words {
+			words = append(words, &pb.TranscriptWord{
+				Word:        w.Word,
+				Start:       int64(w.Start),
+				End:         int64(w.End),
+				Probability: w.Probability,
+			})
+		}
+		tr.Segments = append(tr.Segments,
+			&pb.TranscriptSegment{
+				Text:    s.Text,
+				Id:      int32(s.Id),
+				Start:   int64(s.Start),
+				End:     int64(s.End),
+				Tokens:  tks,
+				Words:   words,
+				Speaker: s.Speaker,
+			})
+	}
+	return tr
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"time"
+
+	"github.com/mudler/LocalAI/core/schema"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transcript results", func() {
+	It("keeps the words, the language and the speakers over the wire", func() {
+		result := schema.TranscriptionResult{
+			Text:                " hello world",
+			Language:            "en",
+			LanguageProbability: 0.9,
+			Segments: []schema.Segment{
+				{
+					Id:     0,
+					Start:  0,
+					End:    2 * time.Second,
+					Text:   " hello world",
+					Tokens: []int{1, 2},
+					Words: []schema.Word{
+						{Word: "hello", Start: 0, End: time.Second, Probability: 0.8},
+						{Word: "world", Start: time.Second, End: 2 * time.Second, Probability: 0.7},
+					},
+					Speaker: "SPEAKER_00",
+				},
+			},
+		}
+
+		Expect(*transcriptResultFromProto(transcriptResultToProto(result))).To(Equal(result))
+	})
+
+	It("leaves the words unset when they are not requested", func() {
+		result := transcriptResultFromProto(transcriptResultToProto(schema.TranscriptionResult{
+			Text:     " hello",
+			Segments: []schema.Segment{{Text: " hello", Tokens: []int{1}}},
+		}))
+		Expect(result.Segments).To(HaveLen(1))
+		Expect(result.Segments[0].Words).To(BeNil())
+		Expect(result.Language).To(BeEmpty())
+	})
+})
@@ -0,0 +1,110 @@
+// Package ipfilter allows or denies the clients by their IP address, resolving the address of the clients
+// behind trusted reverse proxies from the X-Forwarded-For header.
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Filter allows the clients in the allow list, all of them when it is empty, unless they are in the deny
+// list. A nil Filter allows all the clients and trusts no proxy
+type Filter struct {
+	allow, deny, trustedProxies []netip.Prefix
+}
+
+// New parses the lists of IP addresses and CIDR ranges, as 10.0.0.0/8 or 2001:db8::1
+func New(allow, deny, trustedProxies []string) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.allow, err = parsePrefixes(allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = parsePrefixes(deny); err != nil {
+		return nil, err
+	}
+	if f.trustedProxies, err = parsePrefixes(trustedProxies); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.Contains(s, "/") {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q: %w", s, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", s, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled returns whether the filter allows or denies any client
+func (f *Filter) Enabled() bool {
+	return f != nil && (len(f.allow) > 0 || len(f.deny) > 0)
+}
+
+// Allowed returns whether the client of address addr is allowed. The invalid addresses are only allowed
+// without allow list
+func (f *Filter) Allowed(addr netip.Addr) bool {
+	if f == nil {
+		return true
+	}
+	if !addr.IsValid() {
+		return len(f.allow) == 0
+	}
+	addr = addr.Unmap()
+	if contains(f.deny, addr) {
+		return false
+	}
+	return len(f.allow) == 0 || contains(f.allow, addr)
+}
+
+// ClientIP returns the address of the client connected from remote. When remote is a trusted proxy, it is
+// the last address of the X-Forwarded-For header which is not a trusted proxy: the addresses on its left
+// are set by the client, and can not be trusted
+func (f *Filter) ClientIP(remote netip.Addr, forwardedFor string) netip.Addr {
+	remote = remote.Unmap()
+	if f == nil || !contains(f.trustedProxies, remote) || forwardedFor == "" {
+		return remote
+	}
+	client := remote
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// the proxies chain is broken, the last valid address is the one of the client
+			break
+		}
+		client = addr.Unmap()
+		if !contains(f.trustedProxies, client) {
+			break
+		}
+	}
+	return client
+}
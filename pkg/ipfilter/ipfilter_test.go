@@ -0,0 +1,66 @@
+package ipfilter_test
+
+import (
+	"net/netip"
+
+	. "github.com/mudler/LocalAI/pkg/ipfilter"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filter", func() {
+	addr := netip.MustParseAddr
+
+	It("allows all the clients without lists", func() {
+		var f *Filter
+		Expect(f.Allowed(addr("203.0.113.7"))).To(BeTrue())
+		Expect(f.ClientIP(addr("203.0.113.7"), "198.51.100.1")).To(Equal(addr("203.0.113.7")))
+
+		f, err := New(nil, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Enabled()).To(BeFalse())
+		Expect(f.Allowed(addr("203.0.113.7"))).To(BeTrue())
+	})
+
+	It("allows the clients of the allow list, except the denied ones", func() {
+		f, err := New([]string{"10.0.0.0/8", "2001:db8::/32", "192.168.1.10"}, []string{"10.0.0.13"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Enabled()).To(BeTrue())
+		Expect(f.Allowed(addr("10.1.2.3"))).To(BeTrue())
+		Expect(f.Allowed(addr("::ffff:10.1.2.3"))).To(BeTrue())
+		Expect(f.Allowed(addr("2001:db8::1"))).To(BeTrue())
+		Expect(f.Allowed(addr("192.168.1.10"))).To(BeTrue())
+		Expect(f.Allowed(addr("192.168.1.11"))).To(BeFalse())
+		Expect(f.Allowed(addr("10.0.0.13"))).To(BeFalse())
+		Expect(f.Allowed(netip.Addr{})).To(BeFalse())
+	})
+
+	It("denies the clients of the deny list only", func() {
+		f, err := New(nil, []string{"203.0.113.0/24"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Allowed(addr("203.0.113.7"))).To(BeFalse())
+		Expect(f.Allowed(addr("198.51.100.1"))).To(BeTrue())
+	})
+
+	It("rejects the invalid addresses and ranges", func() {
+		_, err := New([]string{"10.0.0.0/33"}, nil, nil)
+		Expect(err).To(HaveOccurred())
+		_, err = New(nil, []string{"not-an-ip"}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("resolves the client behind the trusted proxies", func() {
+		f, err := New(nil, nil, []string{"10.0.0.1", "172.16.0.0/12"})
+		Expect(err).ToNot(HaveOccurred())
+
+		// untrusted remotes can not set their address
+		Expect(f.ClientIP(addr("203.0.113.7"), "198.51.100.1")).To(Equal(addr("203.0.113.7")))
+		// the address added by the proxy
+		Expect(f.ClientIP(addr("10.0.0.1"), "198.51.100.1")).To(Equal(addr("198.51.100.1")))
+		// the addresses set by the client, on the left, are ignored
+		Expect(f.ClientIP(addr("10.0.0.1"), "1.2.3.4, 198.51.100.1, 172.16.0.5")).To(Equal(addr("198.51.100.1")))
+		// without header, the proxy is the client
+		Expect(f.ClientIP(addr("10.0.0.1"), "")).To(Equal(addr("10.0.0.1")))
+		Expect(f.ClientIP(addr("10.0.0.1"), "garbage")).To(Equal(addr("10.0.0.1")))
+	})
+})
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader is the header with the ID of the API requests, returned in the responses and passed to
+// the backends in the gRPC metadata, in lower case
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the API request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the API request of the context, empty if there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the logger of LocalAI adding the ID of the API request of the context, if any,
+// to the log lines
+func FromContext(ctx context.Context) *zerolog.Logger {
+	id := RequestID(ctx)
+	if id == "" {
+		return &log.Logger
+	}
+	l := log.Logger.With().Str("request_id", id).Logger()
+	return &l
+}
@@ -0,0 +1,228 @@
+// Package logging controls the level and the format of the logs while LocalAI runs, with a level for each
+// of the subsystems (http, grpc, p2p, gallery) logging with their own logger.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// The subsystems with their own log level
+const (
+	HTTP    = "http"
+	GRPC    = "grpc"
+	P2P     = "p2p"
+	Gallery = "gallery"
+)
+
+// The formats of the logs
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+var Subsystems = []string{HTTP, GRPC, P2P, Gallery}
+
+// Settings are the level and the format of the logs. The subsystems without a level log at the level of
+// the rest of LocalAI
+type Settings struct {
+	Level      string            `json:"level"`
+	Format     string            `json:"format"`
+	Subsystems map[string]string `json:"subsystems,omitempty"`
+}
+
+var (
+	mu              sync.RWMutex
+	level           = zerolog.InfoLevel
+	subsystemLevels = map[string]zerolog.Level{}
+	output          = &formatWriter{}
+	loggers         = map[string]*zerolog.Logger{}
+)
+
+func init() {
+	output.set(os.Stderr, FormatConsole)
+	for _, s := range Subsystems {
+		l := zerolog.New(output).With().Timestamp().Str("subsystem", s).Logger().Hook(levelHook{subsystem: s})
+		loggers[s] = &l
+	}
+}
+
+// Init makes the loggers, including the global one of zerolog, write to out with the format
+func Init(out io.Writer, format string) error {
+	if err := output.set(out, format); err != nil {
+		return err
+	}
+	log.Logger = zerolog.New(output).With().Timestamp().Logger().Hook(levelHook{})
+	return nil
+}
+
+// Logger returns the logger of the subsystem, logging at the level of the subsystem
+func Logger(subsystem string) *zerolog.Logger {
+	if l, exists := loggers[subsystem]; exists {
+		return l
+	}
+	return &log.Logger
+}
+
+// SetLevel sets the level of the logs of LocalAI, but of the subsystems with their own level
+func SetLevel(l zerolog.Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+	updateGlobalLevel()
+}
+
+// SetSubsystemLevel sets the level of the logs of a subsystem, or resets it to the level of LocalAI when
+// the level is empty
+func SetSubsystemLevel(subsystem, l string) error {
+	if _, exists := loggers[subsystem]; !exists {
+		return fmt.Errorf("unknown subsystem %q, available subsystems: %v", subsystem, Subsystems)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if l == "" {
+		delete(subsystemLevels, subsystem)
+	} else {
+		parsed, err := zerolog.ParseLevel(l)
+		if err != nil {
+			return err
+		}
+		subsystemLevels[subsystem] = parsed
+	}
+	updateGlobalLevel()
+	return nil
+}
+
+// SetFormat switches the logs between the console and the JSON formats
+func SetFormat(format string) error {
+	return output.set(nil, format)
+}
+
+// Current returns the settings of the logs
+func Current() Settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	s := Settings{Level: level.String(), Format: output.currentFormat(), Subsystems: map[string]string{}}
+	for subsystem, l := range subsystemLevels {
+		s.Subsystems[subsystem] = l.String()
+	}
+	return s
+}
+
+// Apply changes the settings of the logs. The empty fields are left unchanged, while the subsystems set
+// to an empty level are reset to the level of LocalAI. Nothing is changed if the settings are invalid.
+func Apply(s Settings) error {
+	var l zerolog.Level
+	var err error
+	if s.Level != "" {
+		if l, err = zerolog.ParseLevel(s.Level); err != nil {
+			return err
+		}
+	}
+	if s.Format != "" && s.Format != FormatConsole && s.Format != FormatJSON {
+		return fmt.Errorf("unknown log format %q, available formats: %s, %s", s.Format, FormatConsole, FormatJSON)
+	}
+	subsystems := make([]string, 0, len(s.Subsystems))
+	for subsystem, sl := range s.Subsystems {
+		if _, exists := loggers[subsystem]; !exists {
+			return fmt.Errorf("unknown subsystem %q, available subsystems: %v", subsystem, Subsystems)
+		}
+		if sl != "" {
+			if _, err := zerolog.ParseLevel(sl); err != nil {
+				return err
+			}
+		}
+		subsystems = append(subsystems, subsystem)
+	}
+	sort.Strings(subsystems)
+
+	if s.Level != "" {
+		SetLevel(l)
+	}
+	if s.Format != "" {
+		if err := SetFormat(s.Format); err != nil {
+			return err
+		}
+	}
+	for _, subsystem := range subsystems {
+		if err := SetSubsystemLevel(subsystem, s.Subsystems[subsystem]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateGlobalLevel lets zerolog create the events of the lowest of the levels, the hooks discard the
+// ones below the level of their logger
+func updateGlobalLevel() {
+	global := level
+	for _, l := range subsystemLevels {
+		if l < global {
+			global = l
+		}
+	}
+	zerolog.SetGlobalLevel(global)
+}
+
+func levelOf(subsystem string) zerolog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	if l, exists := subsystemLevels[subsystem]; exists {
+		return l
+	}
+	return level
+}
+
+// levelHook discards the events below the level of the subsystem, or of LocalAI for the global logger
+type levelHook struct {
+	subsystem string
+}
+
+func (h levelHook) Run(e *zerolog.Event, l zerolog.Level, msg string) {
+	if l < levelOf(h.subsystem) {
+		e.Discard()
+	}
+}
+
+// formatWriter writes the JSON events of zerolog as they are, or formatted for the console
+type formatWriter struct {
+	mu      sync.RWMutex
+	out     io.Writer
+	format  string
+	console io.Writer
+}
+
+func (w *formatWriter) set(out io.Writer, format string) error {
+	if format != FormatConsole && format != FormatJSON {
+		return fmt.Errorf("unknown log format %q, available formats: %s, %s", format, FormatConsole, FormatJSON)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if out != nil {
+		w.out = out
+	}
+	w.format = format
+	w.console = zerolog.ConsoleWriter{Out: w.out}
+	return nil
+}
+
+func (w *formatWriter) currentFormat() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.format
+}
+
+func (w *formatWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.format == FormatJSON {
+		return w.out.Write(p)
+	}
+	return w.console.Write(p)
+}
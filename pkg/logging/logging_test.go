@@ -0,0 +1,93 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/mudler/LocalAI/pkg/logging"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var _ = Describe("Logging", func() {
+	var out *bytes.Buffer
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		Expect(Init(out, FormatJSON)).To(Succeed())
+		Expect(Apply(Settings{Level: "info", Subsystems: map[string]string{P2P: "", GRPC: ""}})).To(Succeed())
+	})
+
+	It("logs at the level of LocalAI", func() {
+		log.Debug().Msg("hidden")
+		log.Info().Msg("shown")
+		Logger(HTTP).Debug().Msg("hidden")
+		Expect(out.String()).ToNot(ContainSubstring("hidden"))
+		Expect(out.String()).To(ContainSubstring(`"message":"shown"`))
+
+		SetLevel(zerolog.DebugLevel)
+		log.Debug().Msg("debug")
+		Expect(out.String()).To(ContainSubstring(`"message":"debug"`))
+	})
+
+	It("logs the subsystems at their own level", func() {
+		Expect(SetSubsystemLevel(P2P, "debug")).To(Succeed())
+		log.Debug().Msg("hidden")
+		Logger(GRPC).Debug().Msg("hidden")
+		Logger(P2P).Debug().Msg("peer discovered")
+		Expect(out.String()).ToNot(ContainSubstring("hidden"))
+		Expect(out.String()).To(ContainSubstring(`"subsystem":"p2p"`))
+		Expect(out.String()).To(ContainSubstring("peer discovered"))
+
+		Expect(SetSubsystemLevel(P2P, "")).To(Succeed())
+		out.Reset()
+		Logger(P2P).Debug().Msg("hidden")
+		Expect(out.String()).To(BeEmpty())
+	})
+
+	It("switches between the JSON and the console formats", func() {
+		Expect(SetFormat(FormatConsole)).To(Succeed())
+		log.Info().Msg("console")
+		Expect(out.String()).To(ContainSubstring("console"))
+		Expect(out.String()).ToNot(ContainSubstring(`"message"`))
+
+		out.Reset()
+		Expect(SetFormat(FormatJSON)).To(Succeed())
+		log.Info().Msg("json")
+		Expect(out.String()).To(ContainSubstring(`"level":"info"`))
+	})
+
+	It("applies and returns the settings", func() {
+		Expect(Apply(Settings{Level: "warn", Format: FormatConsole, Subsystems: map[string]string{Gallery: "trace"}})).To(Succeed())
+		Expect(Current()).To(Equal(Settings{Level: "warn", Format: FormatConsole, Subsystems: map[string]string{Gallery: "trace"}}))
+		Expect(Apply(Settings{Subsystems: map[string]string{Gallery: ""}})).To(Succeed())
+		Expect(Current().Subsystems).To(BeEmpty())
+	})
+
+	It("rejects the invalid settings without changing anything", func() {
+		Expect(Apply(Settings{Level: "debug", Subsystems: map[string]string{"storage": "debug"}})).To(MatchError(ContainSubstring("unknown subsystem")))
+		Expect(Apply(Settings{Level: "loud"})).ToNot(Succeed())
+		Expect(Apply(Settings{Format: "xml"})).To(MatchError(ContainSubstring("unknown log format")))
+		Expect(Current().Level).To(Equal("info"))
+		Expect(Current().Format).To(Equal(FormatJSON))
+	})
+})
+
+var _ = Describe("Request IDs", func() {
+	It("adds the ID of the request to the log lines", func() {
+		out := &bytes.Buffer{}
+		Expect(Init(out, FormatJSON)).To(Succeed())
+		SetLevel(zerolog.InfoLevel)
+
+		ctx := WithRequestID(context.Background(), "req-1")
+		Expect(RequestID(ctx)).To(Equal("req-1"))
+		FromContext(ctx).Info().Msg("with id")
+		FromContext(ctx).Debug().Msg("hidden")
+		FromContext(context.Background()).Info().Msg("without id")
+		Expect(out.String()).To(MatchRegexp(`"request_id":"req-1".*"message":"with id"`))
+		Expect(out.String()).ToNot(ContainSubstring("hidden"))
+		Expect(RequestID(WithRequestID(context.Background(), ""))).To(BeEmpty())
+	})
+})
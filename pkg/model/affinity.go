@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// affinityPoints is the number of points of each slot on the hash ring, spreading the keys evenly
+const affinityPoints = 64
+
+// AffinityRouter routes the requests sharing an affinity key, as a conversation or a user, to the same slot
+// of a backend, so they reuse its KV cache. The keys are placed on a consistent hash ring: when the number of
+// slots changes only the keys of the added or removed slots move
+type AffinityRouter struct {
+	points []uint32
+	slots  map[uint32]int
+}
+
+// NewAffinityRouter returns the router of the affinity keys to the slots of a backend
+func NewAffinityRouter(slots int) *AffinityRouter {
+	r := &AffinityRouter{slots: map[uint32]int{}}
+	for slot := 0; slot < slots; slot++ {
+		for i := 0; i < affinityPoints; i++ {
+			point := affinityHash(strconv.Itoa(slot) + "-" + strconv.Itoa(i))
+			if _, exists := r.slots[point]; exists {
+				continue
+			}
+			r.slots[point] = slot
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Route returns the slot of the affinity key, -1 if the key is empty or there are no slots
+func (r *AffinityRouter) Route(key string) int {
+	if key == "" || len(r.points) == 0 {
+		return -1
+	}
+	h := affinityHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.slots[r.points[i]]
+}
+
+func affinityHash(key string) uint32 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+type affinityKey struct{}
+
+// WithAffinityKey returns a context carrying the affinity key of the request, routing it to the slot of the
+// backend used by the previous requests with the same key
+func WithAffinityKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+// AffinityKey returns the affinity key of the request of the context, empty if there is none
+func AffinityKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	key, _ := ctx.Value(affinityKey{}).(string)
+	return key
+}
+
+// Slot returns the slot of the backend of the model for the affinity key, -1 if the backend has a single
+// slot or the key is empty
+func (ml *ModelLoader) Slot(modelName, key string) int {
+	ml.statusMu.Lock()
+	router, exists := ml.affinity[modelName]
+	ml.statusMu.Unlock()
+	if !exists {
+		return -1
+	}
+	return router.Route(key)
+}
@@ -0,0 +1,60 @@
+package model_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AffinityRouter", func() {
+	It("routes the same key to the same slot", func() {
+		router := NewAffinityRouter(4)
+		slot := router.Route("conversation-1")
+		Expect(slot).To(BeNumerically(">=", 0))
+		Expect(slot).To(BeNumerically("<", 4))
+		Expect(NewAffinityRouter(4).Route("conversation-1")).To(Equal(slot))
+	})
+
+	It("spreads the keys over all the slots", func() {
+		router := NewAffinityRouter(4)
+		used := map[int]int{}
+		for i := 0; i < 1000; i++ {
+			used[router.Route(fmt.Sprintf("user-%d", i))]++
+		}
+		Expect(used).To(HaveLen(4))
+		for _, keys := range used {
+			Expect(keys).To(BeNumerically(">", 100))
+		}
+	})
+
+	It("moves only the keys of the new slots when the slots grow", func() {
+		before, after := NewAffinityRouter(4), NewAffinityRouter(5)
+		moved := 0
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("user-%d", i)
+			if slot := after.Route(key); slot != before.Route(key) {
+				Expect(slot).To(Equal(4))
+				moved++
+			}
+		}
+		Expect(moved).To(BeNumerically("<", 400))
+	})
+
+	It("does not route the requests without a key", func() {
+		Expect(NewAffinityRouter(4).Route("")).To(Equal(-1))
+		Expect(NewAffinityRouter(0).Route("user")).To(Equal(-1))
+	})
+
+	It("carries the key in the context", func() {
+		Expect(AffinityKey(context.Background())).To(BeEmpty())
+		Expect(AffinityKey(WithAffinityKey(context.Background(), "conversation-1"))).To(Equal("conversation-1"))
+	})
+
+	It("has no slot for the models with a single slot", func() {
+		Expect(NewModelLoader(GinkgoT().TempDir()).Slot("model", "user")).To(Equal(-1))
+	})
+})
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// States of the circuits of the CircuitBreaker
+const (
+	// CircuitClosed lets the requests through
+	CircuitClosed = "closed"
+	// CircuitOpen fails the requests, until a probe succeeds
+	CircuitOpen = "open"
+	// CircuitHalfOpen fails the requests while a probe runs
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitOpenError is returned for the requests to the models with an open circuit
+type CircuitOpenError struct {
+	Model string
+	// RetryAfter is the time until the next probe of the model
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("model %s is unhealthy after repeated failures, retry in %s", e.Model, e.RetryAfter.Round(time.Second))
+}
+
+// CircuitState is the state of the circuit of a model
+type CircuitState struct {
+	Model               string
+	State               string
+	ConsecutiveFailures int
+	LastError           string
+	// OpenedAt is the time the circuit opened, and RetryAt the time of the next probe, when it is not closed
+	OpenedAt time.Time
+	RetryAt  time.Time
+}
+
+type circuit struct {
+	CircuitState
+	probe func() error
+}
+
+// CircuitBreaker fails fast the requests to the models failing repeatedly: after threshold consecutive
+// failures, the circuit of the model opens and its requests fail until it closes again. While the
+// circuit is open, a probe of the model runs in the background every cooldown, and closes the circuit
+// once it succeeds. A nil CircuitBreaker lets all the requests through
+type CircuitBreaker struct {
+	ctx       context.Context
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	circuits  map[string]*circuit
+	now       func() time.Time
+}
+
+// NewCircuitBreaker returns a circuit breaker opening the circuits after threshold consecutive failures.
+// The probes stop with the context
+func NewCircuitBreaker(ctx context.Context, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		ctx:       ctx,
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  map[string]*circuit{},
+		now:       time.Now,
+	}
+}
+
+// Allow returns a *CircuitOpenError if the circuit of the model is not closed
+func (cb *CircuitBreaker) Allow(model string) error {
+	if cb == nil {
+		return nil
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c, exists := cb.circuits[model]
+	if !exists || c.State == CircuitClosed {
+		return nil
+	}
+	return &CircuitOpenError{Model: model, RetryAfter: max(c.RetryAt.Sub(cb.now()), 0)}
+}
+
+// Success resets the consecutive failures of the model
+func (cb *CircuitBreaker) Success(model string) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c, exists := cb.circuits[model]; exists && c.State == CircuitClosed {
+		c.ConsecutiveFailures = 0
+	}
+}
+
+// Failure counts a failure of the model, and opens its circuit after threshold consecutive failures.
+// probe checks the model is healthy again, to close the circuit
+func (cb *CircuitBreaker) Failure(model string, err error, probe func() error) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c, exists := cb.circuits[model]
+	if !exists {
+		c = &circuit{CircuitState: CircuitState{Model: model, State: CircuitClosed}}
+		cb.circuits[model] = c
+	}
+	if c.State != CircuitClosed {
+		return
+	}
+	c.ConsecutiveFailures++
+	c.LastError = err.Error()
+	if c.ConsecutiveFailures < cb.threshold {
+		return
+	}
+
+	log.Warn().Err(err).Str("model", model).Int("failures", c.ConsecutiveFailures).Msgf("Circuit opened, the requests to the model fail until it recovers")
+	c.State = CircuitOpen
+	c.OpenedAt = cb.now()
+	c.RetryAt = c.OpenedAt.Add(cb.cooldown)
+	c.probe = probe
+	go cb.probeUntilClosed(c)
+}
+
+// probeUntilClosed probes the model of the circuit every cooldown, until a probe succeeds
+func (cb *CircuitBreaker) probeUntilClosed(c *circuit) {
+	for {
+		select {
+		case <-cb.ctx.Done():
+			return
+		case <-time.After(cb.cooldown):
+		}
+
+		cb.mu.Lock()
+		c.State = CircuitHalfOpen
+		cb.mu.Unlock()
+
+		err := c.probe()
+
+		cb.mu.Lock()
+		if err == nil {
+			log.Info().Str("model", c.Model).Msg("Circuit closed, the model recovered")
+			c.State = CircuitClosed
+			c.ConsecutiveFailures = 0
+			c.LastError = ""
+			c.OpenedAt, c.RetryAt = time.Time{}, time.Time{}
+			c.probe = nil
+			cb.mu.Unlock()
+			return
+		}
+		log.Debug().Err(err).Str("model", c.Model).Msg("Circuit probe failed")
+		c.State = CircuitOpen
+		c.LastError = err.Error()
+		c.RetryAt = cb.now().Add(cb.cooldown)
+		cb.mu.Unlock()
+	}
+}
+
+// States returns the states of the circuits of the models which failed, by model name
+func (cb *CircuitBreaker) States() []CircuitState {
+	if cb == nil {
+		return nil
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	states := make([]CircuitState, 0, len(cb.circuits))
+	for _, c := range cb.circuits {
+		states = append(states, c.CircuitState)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Model < states[j].Model
+	})
+	return states
+}
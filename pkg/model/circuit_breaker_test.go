@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CircuitBreaker", func() {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	failing := errors.New("backend crashed")
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("opens the circuit after consecutive failures", func() {
+		cb := NewCircuitBreaker(ctx, 3, time.Hour)
+		probe := func() error { return failing }
+
+		cb.Failure("model", failing, probe)
+		cb.Failure("model", failing, probe)
+		// a success resets the failures
+		cb.Success("model")
+		cb.Failure("model", failing, probe)
+		cb.Failure("model", failing, probe)
+		Expect(cb.Allow("model")).To(Succeed())
+
+		cb.Failure("model", failing, probe)
+		err := cb.Allow("model")
+		var circuitErr *CircuitOpenError
+		Expect(errors.As(err, &circuitErr)).To(BeTrue())
+		Expect(circuitErr.Model).To(Equal("model"))
+		Expect(circuitErr.RetryAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+		// the other models are not affected
+		Expect(cb.Allow("other")).To(Succeed())
+
+		states := cb.States()
+		Expect(states).To(HaveLen(1))
+		Expect(states[0].State).To(Equal(CircuitOpen))
+		Expect(states[0].ConsecutiveFailures).To(Equal(3))
+		Expect(states[0].LastError).To(Equal("backend crashed"))
+	})
+
+	It("closes the circuit once a probe succeeds", func() {
+		cb := NewCircuitBreaker(ctx, 1, 10*time.Millisecond)
+		var probes atomic.Int32
+		cb.Failure("model", failing, func() error {
+			if probes.Add(1) < 3 {
+				return failing
+			}
+			return nil
+		})
+		Expect(cb.Allow("model")).ToNot(Succeed())
+
+		Eventually(func() error { return cb.Allow("model") }).Should(Succeed())
+		Expect(probes.Load()).To(Equal(int32(3)))
+		Expect(cb.States()[0].State).To(Equal(CircuitClosed))
+		Expect(cb.States()[0].ConsecutiveFailures).To(BeZero())
+	})
+
+	It("lets all the requests through when it is nil", func() {
+		var cb *CircuitBreaker
+		cb.Failure("model", failing, func() error { return failing })
+		Expect(cb.Allow("model")).To(Succeed())
+		Expect(cb.States()).To(BeEmpty())
+	})
+})
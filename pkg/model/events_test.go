@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"github.com/mudler/LocalAI/pkg/events"
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// eventSink records the types of the events
+type eventSink struct {
+	types []string
+}
+
+func (s *eventSink) Send(e events.Event) error {
+	s.types = append(s.types, e.Type)
+	return nil
+}
+
+func (s *eventSink) Close() error {
+	return nil
+}
+
+var _ = Describe("Events", func() {
+	It("publishes the models loaded and unloaded", func() {
+		sink := &eventSink{}
+		bus := events.NewBus(nil)
+		bus.AddSink(sink)
+		ml := NewModelLoader(GinkgoT().TempDir())
+		ml.SetEvents(bus)
+
+		_, err := ml.LoadModel("model.gguf", func(name, file string) (ModelAddress, error) {
+			return ModelAddress("127.0.0.1:1"), nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ml.ShutdownModel("model.gguf")).To(Succeed())
+		Expect(ml.ShutdownModel("model.gguf")).ToNot(Succeed())
+
+		Expect(bus.Close()).To(Succeed())
+		Expect(sink.types).To(Equal([]string{events.ModelLoaded, events.ModelUnloaded}))
+	})
+})
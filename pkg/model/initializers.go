@@ -12,6 +12,7 @@ import (
 
 	"github.com/klauspost/cpuid/v2"
 	grpc "github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
 	"github.com/mudler/LocalAI/pkg/library"
 	"github.com/mudler/LocalAI/pkg/utils"
 	"github.com/mudler/LocalAI/pkg/xsysinfo"
@@ -19,6 +20,8 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/elliotchance/orderedmap/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var Aliases map[string]string = map[string]string{
@@ -178,6 +181,50 @@ ENTRY:
 	return orderedBackends.Keys(), nil
 }
 
+// AvailableBackends returns the backends found in the asset directory, in the order
+// they are tried when a model does not specify its backend
+func AvailableBackends(assetDir string) ([]string, error) {
+	return backendsInAssetDir(assetDir)
+}
+
+// LlamaCPPVariant returns the llama.cpp variant selected for the system, or an empty
+// string if no variant is available
+func LlamaCPPVariant(assetDir string, f16 bool) string {
+	p := selectGRPCProcess(LLamaCPP, assetDir, f16)
+	if p == "" {
+		return ""
+	}
+	return filepath.Base(p)
+}
+
+// KnownBackends returns the names that a model can use as backend: the backends in the asset
+// directory (including the llama.cpp variants), their aliases and the external backends.
+// It returns nil if there are no backends at all, e.g. when the assets are not extracted
+func KnownBackends(assetDir string, externalBackends map[string]string) []string {
+	known := []string{}
+	if entries, err := os.ReadDir(backendPath(assetDir, "")); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() {
+				known = append(known, e.Name())
+			}
+		}
+		if backends, err := backendsInAssetDir(assetDir); err == nil {
+			known = append(known, backends...)
+		}
+	}
+	for name := range externalBackends {
+		known = append(known, name)
+	}
+	if len(known) == 0 {
+		return nil
+	}
+	for alias := range Aliases {
+		known = append(known, alias)
+	}
+	slices.Sort(known)
+	return slices.Compact(known)
+}
+
 // selectGRPCProcess selects the GRPC process to start based on system capabilities
 func selectGRPCProcess(backend, assetDir string, f16 bool) string {
 	foundCUDA := false
@@ -300,7 +347,7 @@ func (ml *ModelLoader) grpcModel(backend string, o *Options) func(string, string
 					return "", fmt.Errorf("failed allocating free ports: %s", err.Error())
 				}
 				// Make sure the process is executable
-				if err := ml.startProcess(uri, o.model, serverAddress); err != nil {
+				if err := ml.startProcess(uri, o.model, serverAddress, ml.backendResources(o), o.environment, o.backendArgs...); err != nil {
 					return "", err
 				}
 
@@ -338,9 +385,10 @@ func (ml *ModelLoader) grpcModel(backend string, o *Options) func(string, string
 
 			// Load the ld.so if it exists
 			args, grpcProcess = library.LoadLDSO(o.assetDir, args, grpcProcess)
+			args = append(args, o.backendArgs...)
 
 			// Make sure the process is executable in any circumstance
-			if err := ml.startProcess(grpcProcess, o.model, serverAddress, args...); err != nil {
+			if err := ml.startProcess(grpcProcess, o.model, serverAddress, ml.backendResources(o), o.environment, args...); err != nil {
 				return "", err
 			}
 
@@ -375,7 +423,18 @@ func (ml *ModelLoader) grpcModel(backend string, o *Options) func(string, string
 
 		log.Debug().Msgf("GRPC: Loading model with options: %+v", options)
 
-		res, err := client.GRPC(o.parallelRequests, ml.wd).LoadModel(o.context, &options)
+		grpcBackend := client.GRPC(o.parallelRequests, ml.wd)
+		res, err := grpcBackend.LoadModelStatus(o.context, &options, func(progress *pb.ModelLoadProgress) {
+			ml.ReportLoadProgress(modelName, progress.Stage, float64(progress.Progress))
+		})
+		if status.Code(err) == codes.Unimplemented {
+			// The backend does not report the progress of the loading
+			ml.ReportLoadProgress(modelName, grpc.LoadStageLoad, 0)
+			res, err = grpcBackend.LoadModel(o.context, &options)
+			if err == nil && res.Success {
+				ml.ReportLoadProgress(modelName, grpc.LoadStageLoad, 100)
+			}
+		}
 		if err != nil {
 			return "", fmt.Errorf("could not load model: %w", err)
 		}
@@ -383,6 +442,28 @@ func (ml *ModelLoader) grpcModel(backend string, o *Options) func(string, string
 			return "", fmt.Errorf("could not load model (no success): %s", res.Message)
 		}
 
+		// The backends processing several requests at once report their slots
+		if status, err := grpcBackend.Status(o.context); err == nil && status.Slots > 1 {
+			log.Debug().Str("model", modelName).Int32("slots", status.Slots).Msg("the backend processes several requests at once")
+			ml.statusMu.Lock()
+			ml.slots[string(client)] = int(status.Slots)
+			if o.prioritySlots > 0 {
+				ml.prioritySlots[string(client)] = o.prioritySlots
+			}
+			ml.affinity[modelName] = NewAffinityRouter(int(status.Slots))
+			ml.statusMu.Unlock()
+		}
+
+		if o.kvCache != "" {
+			ml.restoreKVCache(modelName, grpcBackend, o)
+		}
+		if o.warmup != nil {
+			ml.warmup(modelName, grpcBackend, o)
+		}
+		if o.threadsTuning != nil {
+			ml.tuneThreads(modelName, grpcBackend, o)
+		}
+
 		return client, nil
 	}
 }
@@ -393,7 +474,18 @@ func (ml *ModelLoader) resolveAddress(addr ModelAddress, parallel bool) (grpc.Ba
 	}
 
 	if _, ok := ml.grpcClients[string(addr)]; !ok {
-		ml.grpcClients[string(addr)] = addr.GRPC(parallel, ml.wd)
+		client := addr.GRPC(parallel, ml.wd)
+		ml.statusMu.Lock()
+		slots := ml.slots[string(addr)]
+		prioritySlots := ml.prioritySlots[string(addr)]
+		ml.statusMu.Unlock()
+		if c, ok := client.(interface{ SetSlots(int) }); ok && slots > 1 {
+			c.SetSlots(slots)
+		}
+		if c, ok := client.(interface{ SetPrioritySlots(int) }); ok && prioritySlots > 0 {
+			c.SetPrioritySlots(prioritySlots)
+		}
+		ml.grpcClients[string(addr)] = client
 	}
 	return ml.grpcClients[string(addr)], nil
 }
@@ -440,6 +532,17 @@ func (ml *ModelLoader) BackendLoader(opts ...Option) (client grpc.Backend, err e
 		return nil, err
 	}
 
+	// Each request sets how long the model stays loaded once idle, as the ttl of the model or its keep_alive,
+	// and the actions of the watchdog for the model
+	if ml.wd != nil {
+		if o.idleTimeout != nil {
+			ml.wd.SetIdleTimeout(o.model, *o.idleTimeout)
+		} else {
+			ml.wd.ResetIdleTimeout(o.model)
+		}
+		ml.wd.SetActions(o.model, o.busyActions, o.idleActions)
+	}
+
 	return ml.resolveAddress(addr, o.parallelRequests)
 }
 
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/rs/zerolog/log"
+)
+
+// kvCacheSaveTimeout bounds the saving of the KV cache of a model being unloaded
+const kvCacheSaveTimeout = 2 * time.Minute
+
+// restoreKVCache restores the KV cache of the model saved when it was last unloaded, before the warmup.
+// The backends which cannot restore it start with an empty cache
+func (ml *ModelLoader) restoreKVCache(modelName string, backend grpc.Backend, o *Options) {
+	ml.statusMu.Lock()
+	ml.kvCaches[modelName] = o.kvCache
+	ml.statusMu.Unlock()
+
+	if _, err := os.Stat(o.kvCache); err != nil {
+		return
+	}
+	res, err := backend.LoadCache(o.context, &pb.CacheRequest{Path: o.kvCache})
+	if err == nil && !res.Success {
+		log.Warn().Str("model", modelName).Str("path", o.kvCache).Msgf("failed restoring the KV cache: %s", res.Message)
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("model", modelName).Str("path", o.kvCache).Msg("failed restoring the KV cache")
+		return
+	}
+	log.Info().Str("model", modelName).Str("path", o.kvCache).Msg(res.Message)
+}
+
+// saveKVCache saves the KV cache of the model before its backend is stopped, to be restored when it is
+// loaded again
+func (ml *ModelLoader) saveKVCache(modelName string, addr ModelAddress) {
+	ml.statusMu.Lock()
+	path, exists := ml.kvCaches[modelName]
+	delete(ml.kvCaches, modelName)
+	ml.statusMu.Unlock()
+	if !exists {
+		return
+	}
+
+	if err := os.MkdirAll(path, 0750); err != nil {
+		log.Warn().Err(err).Str("model", modelName).Str("path", path).Msg("failed saving the KV cache")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kvCacheSaveTimeout)
+	defer cancel()
+	res, err := addr.GRPC(false, nil).SaveCache(ctx, &pb.CacheRequest{Path: path})
+	if err == nil && !res.Success {
+		log.Warn().Str("model", modelName).Str("path", path).Msgf("failed saving the KV cache: %s", res.Message)
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("model", modelName).Str("path", path).Msg("failed saving the KV cache")
+		return
+	}
+	log.Info().Str("model", modelName).Str("path", path).Msg(res.Message)
+}
@@ -0,0 +1,83 @@
+package model_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// cacheLLM is an embedded backend keeping its KV cache in a file of the cache directory
+type cacheLLM struct {
+	fakeLLM
+	saved, loaded []string
+}
+
+func (c *cacheLLM) SaveCache(in *pb.CacheRequest) error {
+	c.saved = append(c.saved, in.Path)
+	return os.WriteFile(filepath.Join(in.Path, "slot-0.bin"), []byte("cache"), 0600)
+}
+
+func (c *cacheLLM) LoadCache(in *pb.CacheRequest) error {
+	c.loaded = append(c.loaded, in.Path)
+	return nil
+}
+
+var _ = Describe("KV cache", func() {
+	var ml *ModelLoader
+	var cache string
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		ml = NewModelLoader(dir)
+		cache = filepath.Join(dir, "cache", "model")
+	})
+
+	load := func(backend string, opts ...Option) error {
+		_, err := ml.BackendLoader(append([]Option{
+			WithBackendString(backend),
+			WithModel("model.gguf"),
+			WithExternalBackend(backend, backend),
+			WithGRPCAttempts(1),
+		}, opts...)...)
+		return err
+	}
+
+	It("saves the cache when the model is unloaded and restores it when it is loaded again", func() {
+		llm := &cacheLLM{}
+		grpc.Provide("kv-cache", llm)
+
+		Expect(load("kv-cache", WithKVCache(cache))).To(Succeed())
+		Expect(llm.loaded).To(BeEmpty())
+
+		Expect(ml.ShutdownModel("model.gguf")).To(Succeed())
+		Expect(llm.saved).To(Equal([]string{cache}))
+		Expect(filepath.Join(cache, "slot-0.bin")).To(BeAnExistingFile())
+
+		Expect(load("kv-cache", WithKVCache(cache))).To(Succeed())
+		Expect(llm.loaded).To(Equal([]string{cache}))
+	})
+
+	It("does not save the cache of the models without a cache directory", func() {
+		llm := &cacheLLM{}
+		grpc.Provide("kv-cache-none", llm)
+
+		Expect(load("kv-cache-none")).To(Succeed())
+		Expect(ml.ShutdownModel("model.gguf")).To(Succeed())
+		Expect(llm.saved).To(BeEmpty())
+		Expect(cache).ToNot(BeADirectory())
+	})
+
+	It("loads the models whose backend can not restore the cache", func() {
+		grpc.Provide("kv-cache-unsupported", &fakeLLM{})
+		Expect(os.MkdirAll(cache, 0750)).To(Succeed())
+
+		Expect(load("kv-cache-unsupported", WithKVCache(cache))).To(Succeed())
+		Expect(ml.ShutdownModel("model.gguf")).To(Succeed())
+	})
+})
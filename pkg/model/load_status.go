@@ -0,0 +1,113 @@
+package model
+
+import (
+	"maps"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/rs/zerolog/log"
+)
+
+// States of the models in LoadStatus
+const (
+	StateDownloading = "downloading"
+	StateDownloaded  = "downloaded"
+	StateLoading     = "loading"
+	StateLoaded      = "loaded"
+	StateFailed      = "failed"
+)
+
+// LoadStatus is the progress of the download and of the loading of a model
+type LoadStatus struct {
+	State string
+	// Stage is the stage in progress, or the last one reported
+	Stage string
+	// Progress is the percentage of each stage reported, as the download, mmap or warmup
+	Progress map[string]float64
+	Error    string
+	// WarmupDuration is the duration of the warmup request run after loading the model, if any
+	WarmupDuration time.Duration
+	UpdatedAt      time.Time
+}
+
+// GetLoadStatus returns the progress of the model, by the name used to load it. It does not
+// wait for the model being loaded, if any
+func (ml *ModelLoader) GetLoadStatus(modelName string) (LoadStatus, bool) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	s, exists := ml.loadStatus[modelName]
+	if !exists {
+		return LoadStatus{}, false
+	}
+	status := *s
+	status.Progress = maps.Clone(s.Progress)
+	return status, true
+}
+
+// ReportLoadProgress records the percentage of a stage of the loading of the model. The changes of stage
+// and every 10% of progress of the loading are logged, to follow the loading of the big models
+func (ml *ModelLoader) ReportLoadProgress(modelName, stage string, percent float64) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	s, exists := ml.loadStatus[modelName]
+	if !exists {
+		s = &LoadStatus{Progress: map[string]float64{}}
+		ml.loadStatus[modelName] = s
+	}
+
+	previous, reported := s.Progress[stage]
+	switch {
+	case stage == grpc.LoadStageDownload && percent >= 100:
+		s.State = StateDownloaded
+	case stage == grpc.LoadStageDownload:
+		s.State = StateDownloading
+	default:
+		s.State = StateLoading
+	}
+	s.Stage = stage
+	s.Progress[stage] = percent
+	s.UpdatedAt = time.Now()
+
+	// The downloads are already logged by the downloader
+	if stage != grpc.LoadStageDownload && (!reported || int(percent/10) != int(previous/10)) {
+		log.Info().Str("model", modelName).Str("stage", stage).Msgf("%.0f%%", percent)
+	}
+}
+
+// startLoading resets the progress of the model before loading it. The progress of its download is kept
+func (ml *ModelLoader) startLoading(modelName string) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	s := &LoadStatus{State: StateLoading, Progress: map[string]float64{}, UpdatedAt: time.Now()}
+	if previous, exists := ml.loadStatus[modelName]; exists {
+		if download, downloaded := previous.Progress[grpc.LoadStageDownload]; downloaded {
+			s.Progress[grpc.LoadStageDownload] = download
+		}
+	}
+	ml.loadStatus[modelName] = s
+}
+
+// finishLoading records the result of the loading of the model
+func (ml *ModelLoader) finishLoading(modelName string, err error) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	s, exists := ml.loadStatus[modelName]
+	if !exists {
+		return
+	}
+	s.State = StateLoaded
+	if err != nil {
+		s.State = StateFailed
+		s.Error = err.Error()
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// forgetLoadStatus drops the progress and the threads tuning of a model which is not loaded anymore
+func (ml *ModelLoader) forgetLoadStatus(modelName string) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	delete(ml.loadStatus, modelName)
+	delete(ml.tuning, modelName)
+	delete(ml.affinity, modelName)
+}
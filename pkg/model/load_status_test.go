@@ -0,0 +1,81 @@
+package model_test
+
+import (
+	"errors"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load status", func() {
+	var ml *ModelLoader
+
+	BeforeEach(func() {
+		ml = NewModelLoader(GinkgoT().TempDir())
+	})
+
+	It("has no status for the models never downloaded or loaded", func() {
+		_, exists := ml.GetLoadStatus("model.gguf")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("reports the progress of the download", func() {
+		ml.ReportLoadProgress("model.gguf", grpc.LoadStageDownload, 42)
+		status, exists := ml.GetLoadStatus("model.gguf")
+		Expect(exists).To(BeTrue())
+		Expect(status.State).To(Equal(StateDownloading))
+		Expect(status.Stage).To(Equal(grpc.LoadStageDownload))
+		Expect(status.Progress).To(Equal(map[string]float64{grpc.LoadStageDownload: 42}))
+
+		ml.ReportLoadProgress("model.gguf", grpc.LoadStageDownload, 100)
+		status, _ = ml.GetLoadStatus("model.gguf")
+		Expect(status.State).To(Equal(StateDownloaded))
+	})
+
+	It("reports the stages of the loading and keeps the download", func() {
+		ml.ReportLoadProgress("model.gguf", grpc.LoadStageDownload, 100)
+		_, err := ml.LoadModel("model.gguf", func(name, file string) (ModelAddress, error) {
+			status, _ := ml.GetLoadStatus(name)
+			Expect(status.State).To(Equal(StateLoading))
+			ml.ReportLoadProgress(name, grpc.LoadStageMmap, 100)
+			ml.ReportLoadProgress(name, grpc.LoadStageWarmup, 50)
+			return ModelAddress("127.0.0.1:1"), nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		status, exists := ml.GetLoadStatus("model.gguf")
+		Expect(exists).To(BeTrue())
+		Expect(status.State).To(Equal(StateLoaded))
+		Expect(status.Stage).To(Equal(grpc.LoadStageWarmup))
+		Expect(status.Progress).To(Equal(map[string]float64{
+			grpc.LoadStageDownload: 100,
+			grpc.LoadStageMmap:     100,
+			grpc.LoadStageWarmup:   50,
+		}))
+	})
+
+	It("reports the failures of the loading", func() {
+		_, err := ml.LoadModel("model.gguf", func(name, file string) (ModelAddress, error) {
+			ml.ReportLoadProgress(name, grpc.LoadStageMmap, 10)
+			return "", errors.New("out of memory")
+		})
+		Expect(err).To(HaveOccurred())
+
+		status, _ := ml.GetLoadStatus("model.gguf")
+		Expect(status.State).To(Equal(StateFailed))
+		Expect(status.Error).To(Equal("out of memory"))
+		Expect(status.Progress).To(HaveKeyWithValue(grpc.LoadStageMmap, 10.0))
+	})
+
+	It("returns a copy of the progress", func() {
+		ml.ReportLoadProgress("model.gguf", grpc.LoadStageDownload, 10)
+		status, _ := ml.GetLoadStatus("model.gguf")
+		status.Progress[grpc.LoadStageDownload] = 90
+
+		status, _ = ml.GetLoadStatus("model.gguf")
+		Expect(status.Progress[grpc.LoadStageDownload]).To(Equal(10.0))
+	})
+})
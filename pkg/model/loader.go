@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mudler/LocalAI/pkg/templates"
 
+	"github.com/mudler/LocalAI/pkg/events"
 	"github.com/mudler/LocalAI/pkg/functions"
 	"github.com/mudler/LocalAI/pkg/grpc"
 	"github.com/mudler/LocalAI/pkg/utils"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
 
 	process "github.com/mudler/go-processmanager"
 	"github.com/rs/zerolog/log"
@@ -28,6 +32,31 @@ type PromptTemplateData struct {
 	Instruction          string
 	Functions            []functions.Function
 	MessageIndex         int
+	Variables            map[string]interface{} // custom variables of the model configuration
+	Tokenizer            templates.Tokenizer    `json:"-"` // counts the tokens for truncateTokens, they are estimated if nil
+}
+
+// RAGTemplateData is the data of the prompt of the retrieval augmented generation: the query, and the
+// documents retrieved for it, numbered from 1 to be cited
+type RAGTemplateData struct {
+	SystemPrompt string
+	Query        string
+	Documents    []RAGDocument
+	Variables    map[string]interface{}
+}
+
+// FIMTemplateData is the data of the prompt of the fill-in-the-middle requests: the text before and after
+// the completion, as the code around the cursor
+type FIMTemplateData struct {
+	Prefix    string
+	Suffix    string
+	Variables map[string]interface{}
+}
+
+type RAGDocument struct {
+	Number int
+	Text   string
+	Source string
 }
 
 type ChatMessageTemplateData struct {
@@ -40,6 +69,8 @@ type ChatMessageTemplateData struct {
 	Function     bool
 	FunctionCall interface{}
 	LastMessage  bool
+	Variables    map[string]interface{}
+	Tokenizer    templates.Tokenizer `json:"-"`
 }
 
 // new idea: what if we declare a struct of these here, and use a loop to check?
@@ -48,12 +79,34 @@ type ChatMessageTemplateData struct {
 type ModelLoader struct {
 	ModelPath string
 	mu        sync.Mutex
+	// reloadMu serializes the reloads of the models, which hold mu only to swap the backends
+	reloadMu sync.Mutex
 	// TODO: this needs generics
-	grpcClients   map[string]grpc.Backend
-	models        map[string]ModelAddress
+	grpcClients map[string]grpc.Backend
+	models      map[string]ModelAddress
+	// grpcProcesses have their own lock, to start the backend reloading a model while the models are served
+	processMu     sync.Mutex
 	grpcProcesses map[string]*process.Process
 	templates     *templates.TemplateCache
 	wd            *WatchDog
+	// loadStatus, stats, tuning, running, slots, affinity and kvCaches have their own lock, to be read while the models are loaded
+	statusMu   sync.Mutex
+	loadStatus map[string]*LoadStatus
+	stats      map[string]*ModelStats
+	tuning     map[string]*ThreadsTuning
+	running    map[string]int
+	events     *events.Bus
+	circuits   *CircuitBreaker
+	// gpus returns the free memory of the GPUs, to place the models
+	gpus func() ([]xsysinfo.GPUMemory, error)
+	// slots are the requests processed at once by the backends, and prioritySlots the ones kept for the
+	// priority requests, by address
+	slots         map[string]int
+	prioritySlots map[string]int
+	// affinity routes the requests of the same conversation to the same slot, by model
+	affinity map[string]*AffinityRouter
+	// kvCaches are the directories where the KV caches of the models are saved when they are unloaded
+	kvCaches map[string]string
 }
 
 type ModelAddress string
@@ -73,6 +126,15 @@ func NewModelLoader(modelPath string) *ModelLoader {
 		models:        make(map[string]ModelAddress),
 		templates:     templates.NewTemplateCache(modelPath),
 		grpcProcesses: make(map[string]*process.Process),
+		loadStatus:    make(map[string]*LoadStatus),
+		stats:         make(map[string]*ModelStats),
+		tuning:        make(map[string]*ThreadsTuning),
+		running:       make(map[string]int),
+		slots:         make(map[string]int),
+		prioritySlots: make(map[string]int),
+		affinity:      make(map[string]*AffinityRouter),
+		kvCaches:      make(map[string]string),
+		gpus:          xsysinfo.GPUsMemory,
 	}
 
 	return nml
@@ -82,6 +144,21 @@ func (ml *ModelLoader) SetWatchDog(wd *WatchDog) {
 	ml.wd = wd
 }
 
+// SetEvents sets the bus where the models loaded, unloaded and crashed are published
+func (ml *ModelLoader) SetEvents(bus *events.Bus) {
+	ml.events = bus
+}
+
+// SetCircuitBreaker sets the circuit breaker failing fast the requests to the models failing repeatedly
+func (ml *ModelLoader) SetCircuitBreaker(cb *CircuitBreaker) {
+	ml.circuits = cb
+}
+
+// CircuitBreaker returns the circuit breaker of the models, nil when it is disabled
+func (ml *ModelLoader) CircuitBreaker() *CircuitBreaker {
+	return ml.circuits
+}
+
 func (ml *ModelLoader) ExistsInModelPath(s string) bool {
 	return utils.ExistsInPath(ml.ModelPath, s)
 }
@@ -147,6 +224,7 @@ func (ml *ModelLoader) LoadModel(modelName string, loader func(string, string) (
 
 	// Check if we already have a loaded model
 	if model := ml.CheckIsLoaded(modelName); model != "" {
+		ml.recordUse(modelName)
 		return model, nil
 	}
 
@@ -154,7 +232,10 @@ func (ml *ModelLoader) LoadModel(modelName string, loader func(string, string) (
 	modelFile := filepath.Join(ml.ModelPath, modelName)
 	log.Debug().Msgf("Loading model in memory from file: %s", modelFile)
 
+	ml.startLoading(modelName)
+	start := time.Now()
 	model, err := loader(modelName, modelFile)
+	ml.finishLoading(modelName, err)
 	if err != nil {
 		return "", err
 	}
@@ -168,6 +249,8 @@ func (ml *ModelLoader) LoadModel(modelName string, loader func(string, string) (
 	// }
 
 	ml.models[modelName] = model
+	ml.recordLoad(modelName, time.Since(start))
+	ml.events.Publish(events.ModelLoaded, map[string]any{"model": modelName, "address": string(model)})
 	return model, nil
 }
 
@@ -187,6 +270,27 @@ func (ml *ModelLoader) stopModel(modelName string) error {
 	//return ml.deleteProcess(modelName)
 }
 
+// IsLoaded tells if the model is loaded in memory, without checking that its backend is healthy.
+// It waits for the model being loaded, if any
+func (ml *ModelLoader) IsLoaded(s string) bool {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	_, ok := ml.models[s]
+	return ok
+}
+
+// LoadedModels returns the names of the models loaded in memory
+func (ml *ModelLoader) LoadedModels() []string {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	models := make([]string, 0, len(ml.models))
+	for m := range ml.models {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models
+}
+
 func (ml *ModelLoader) CheckIsLoaded(s string) ModelAddress {
 	var client grpc.Backend
 	if m, ok := ml.models[s]; ok {
@@ -200,8 +304,9 @@ func (ml *ModelLoader) CheckIsLoaded(s string) ModelAddress {
 		if !alive {
 			log.Warn().Msgf("GRPC Model not responding: %s", err.Error())
 			log.Warn().Msgf("Deleting the process in order to recreate it")
-			if !ml.grpcProcesses[s].IsAlive() {
+			if p, _ := ml.grpcProcess(s); !p.IsAlive() {
 				log.Debug().Msgf("GRPC Process is not responding: %s", s)
+				ml.events.Publish(events.BackendCrashed, map[string]any{"model": s, "error": err.Error()})
 				// stop and delete the process, this forces to re-load the model and re-create again the service
 				err := ml.deleteProcess(s)
 				if err != nil {
@@ -223,6 +328,8 @@ const (
 	CompletionPromptTemplate
 	EditPromptTemplate
 	FunctionsPromptTemplate
+	RAGPromptTemplate
+	FIMPromptTemplate
 )
 
 func (ml *ModelLoader) EvaluateTemplateForPrompt(templateType templates.TemplateType, templateName string, in PromptTemplateData) (string, error) {
@@ -230,9 +337,24 @@ func (ml *ModelLoader) EvaluateTemplateForPrompt(templateType templates.Template
 	if templateType == ChatMessageTemplate {
 		return "", fmt.Errorf("invalid templateType: ChatMessage")
 	}
-	return ml.templates.EvaluateTemplate(templateType, templateName, in)
+	return ml.templates.EvaluateTemplateWithTokenizer(templateType, templateName, in, in.Tokenizer)
 }
 
 func (ml *ModelLoader) EvaluateTemplateForChatMessage(templateName string, messageData ChatMessageTemplateData) (string, error) {
-	return ml.templates.EvaluateTemplate(ChatMessageTemplate, templateName, messageData)
+	return ml.templates.EvaluateTemplateWithTokenizer(ChatMessageTemplate, templateName, messageData, messageData.Tokenizer)
+}
+
+// EvaluateTemplateForRAG renders the prompt of the retrieval augmented generation
+func (ml *ModelLoader) EvaluateTemplateForRAG(templateName string, in RAGTemplateData) (string, error) {
+	return ml.templates.EvaluateTemplate(RAGPromptTemplate, templateName, in)
+}
+
+// EvaluateTemplateForFIM renders the prompt of the fill-in-the-middle requests
+func (ml *ModelLoader) EvaluateTemplateForFIM(templateName string, in FIMTemplateData) (string, error) {
+	return ml.templates.EvaluateTemplate(FIMPromptTemplate, templateName, in)
+}
+
+// EvaluateJinjaTemplate renders a Jinja chat template with the variables of the HuggingFace tokenizers (messages, tools, ...)
+func (ml *ModelLoader) EvaluateJinjaTemplate(templateName string, in map[string]interface{}, tokenizer templates.Tokenizer) (string, error) {
+	return ml.templates.EvaluateJinjaTemplate(templateName, in, tokenizer)
 }
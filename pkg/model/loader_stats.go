@@ -0,0 +1,110 @@
+package model
+
+import (
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+)
+
+// ModelStats are the statistics of the loader for a model. They are kept after the model is unloaded
+type ModelStats struct {
+	Model  string
+	Loaded bool
+	// LoadedAt is the time the model was last loaded, and LoadDuration the time taken to load it
+	LoadedAt     time.Time
+	LoadDuration time.Duration
+	// LastUsed is the time the model was last requested
+	LastUsed time.Time
+	Loads    int64
+	// Evictions counts the times the model was unloaded, by the watchdog, the single active backend or the API
+	Evictions int64
+	// WatchDogKills counts the backends of the model stopped by the watchdog, by reason: busy, idle or ttl
+	WatchDogKills map[string]int64
+}
+
+// Stats returns the statistics of the models loaded since the start, sorted by name
+func (ml *ModelLoader) Stats() []ModelStats {
+	ml.statusMu.Lock()
+	stats := make([]ModelStats, 0, len(ml.stats))
+	for _, s := range ml.stats {
+		stats = append(stats, *s)
+	}
+	ml.statusMu.Unlock()
+
+	// The watchdog is read without the lock of the stats, as it unloads the models with its own lock held
+	var kills map[string]map[string]int64
+	if ml.wd != nil {
+		kills = ml.wd.Kills()
+	}
+	for i := range stats {
+		stats[i].WatchDogKills = maps.Clone(kills[stats[i].Model])
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Model < stats[j].Model })
+	return stats
+}
+
+// ModelSlots is the use of the slots of a loaded model: the requests running, and the ones waiting for a slot
+type ModelSlots struct {
+	Model   string
+	Size    int
+	Busy    int
+	Waiting int
+}
+
+// SlotsUsage returns the use of the slots of the loaded models, sorted by name. The backends running the
+// requests in parallel, without slots, are not reported
+func (ml *ModelLoader) SlotsUsage() []ModelSlots {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	usage := []ModelSlots{}
+	for name, addr := range ml.models {
+		client, ok := ml.grpcClients[string(addr)].(interface{ Slots() *grpc.Slots })
+		if !ok || client.Slots() == nil {
+			continue
+		}
+		size, busy, waiting := client.Slots().Usage()
+		usage = append(usage, ModelSlots{Model: name, Size: size, Busy: busy, Waiting: waiting})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Model < usage[j].Model })
+	return usage
+}
+
+// recordLoad records the model loaded in the duration
+func (ml *ModelLoader) recordLoad(modelName string, duration time.Duration) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	s := ml.modelStats(modelName)
+	s.Loaded = true
+	s.LoadedAt = time.Now()
+	s.LoadDuration = duration
+	s.LastUsed = s.LoadedAt
+	s.Loads++
+}
+
+// recordUse records a request for the model already loaded
+func (ml *ModelLoader) recordUse(modelName string) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	ml.modelStats(modelName).LastUsed = time.Now()
+}
+
+// recordEviction records the model unloaded
+func (ml *ModelLoader) recordEviction(modelName string) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	s := ml.modelStats(modelName)
+	s.Loaded = false
+	s.Evictions++
+}
+
+// modelStats returns the statistics of the model, created on its first load. Called with the lock held
+func (ml *ModelLoader) modelStats(modelName string) *ModelStats {
+	s, exists := ml.stats[modelName]
+	if !exists {
+		s = &ModelStats{Model: modelName}
+		ml.stats[modelName] = s
+	}
+	return s
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Slots usage", func() {
+	It("reports the slots of the loaded models with a client", func() {
+		ml := NewModelLoader(GinkgoT().TempDir())
+		Expect(ml.SlotsUsage()).To(BeEmpty())
+
+		ml.models["b"] = "127.0.0.1:1"
+		ml.models["a"] = "127.0.0.1:2"
+		ml.models["parallel"] = "127.0.0.1:3"
+		for _, addr := range []ModelAddress{"127.0.0.1:1", "127.0.0.1:2"} {
+			_, err := ml.resolveAddress(addr, false)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		ml.grpcClients["127.0.0.1:1"].(interface{ SetSlots(int) }).SetSlots(4)
+
+		Expect(ml.SlotsUsage()).To(Equal([]ModelSlots{
+			{Model: "a", Size: 1},
+			{Model: "b", Size: 4},
+		}))
+	})
+})
@@ -0,0 +1,57 @@
+package model_test
+
+import (
+	"errors"
+
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loader stats", func() {
+	var ml *ModelLoader
+
+	load := func(name string) error {
+		_, err := ml.LoadModel(name, func(name, file string) (ModelAddress, error) {
+			return ModelAddress("127.0.0.1:1"), nil
+		})
+		return err
+	}
+
+	BeforeEach(func() {
+		ml = NewModelLoader(GinkgoT().TempDir())
+	})
+
+	It("has no stats for the models never loaded", func() {
+		Expect(ml.Stats()).To(BeEmpty())
+		_, err := ml.LoadModel("broken.gguf", func(name, file string) (ModelAddress, error) {
+			return "", errors.New("no such file")
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(ml.Stats()).To(BeEmpty())
+	})
+
+	It("records the loads and the unloads of the models", func() {
+		Expect(load("b.gguf")).To(Succeed())
+		Expect(load("a.gguf")).To(Succeed())
+
+		stats := ml.Stats()
+		Expect(stats).To(HaveLen(2))
+		Expect(stats[0].Model).To(Equal("a.gguf"))
+		Expect(stats[0].Loaded).To(BeTrue())
+		Expect(stats[0].Loads).To(Equal(int64(1)))
+		Expect(stats[0].LoadedAt).ToNot(BeZero())
+		Expect(stats[0].LastUsed).To(Equal(stats[0].LoadedAt))
+
+		Expect(ml.ShutdownModel("a.gguf")).To(Succeed())
+		stats = ml.Stats()
+		Expect(stats[0].Loaded).To(BeFalse())
+		Expect(stats[0].Evictions).To(Equal(int64(1)))
+
+		Expect(load("a.gguf")).To(Succeed())
+		stats = ml.Stats()
+		Expect(stats[0].Loaded).To(BeTrue())
+		Expect(stats[0].Loads).To(Equal(int64(2)))
+	})
+})
@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"time"
 
 	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
 )
@@ -17,6 +18,33 @@ type Options struct {
 
 	externalBackends map[string]string
 
+	// warmup is run after loading the model, as a prediction or as an embedding
+	warmup           *pb.PredictOptions
+	warmupEmbeddings bool
+
+	// threadsTuning measures the speed of the model with several numbers of threads after loading it
+	threadsTuning *threadsTuning
+
+	// idleTimeout overrides the idle timeout of the watchdog for the model
+	idleTimeout *time.Duration
+
+	// busyActions and idleActions override the actions of the watchdog for the model
+	busyActions, idleActions []string
+
+	// resources limits the resources of the backend process of the model
+	resources Resources
+	// gpu is auto, or the GPUs of the backend when they are not set by the resources
+	gpu string
+
+	// environment and backendArgs are added to the environment and to the arguments of the backend process
+	environment, backendArgs []string
+
+	// kvCache is the directory where the KV cache of the model is saved when it is unloaded, and restored from
+	kvCache string
+
+	// prioritySlots are the slots of the backend kept for the priority requests
+	prioritySlots int
+
 	grpcAttempts        int
 	grpcAttemptsDelay   int
 	singleActiveBackend bool
@@ -38,6 +66,78 @@ func WithExternalBackend(name string, uri string) Option {
 	}
 }
 
+// WithWarmup runs the request after loading the model, as an embedding when embeddings is true, or else as a prediction
+func WithWarmup(opts *pb.PredictOptions, embeddings bool) Option {
+	return func(o *Options) {
+		o.warmup = opts
+		o.warmupEmbeddings = embeddings
+	}
+}
+
+// WithThreadsTuning predicts the request with each number of threads of the candidates after loading the
+// model, to run its requests with the fastest for the load
+func WithThreadsTuning(request *pb.PredictOptions, candidates []int) Option {
+	return func(o *Options) {
+		o.threadsTuning = &threadsTuning{request: request, candidates: candidates}
+	}
+}
+
+// WithIdleTimeout sets the time the model stays loaded once idle. A negative timeout keeps the model loaded
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.idleTimeout = &timeout
+	}
+}
+
+// WithWatchDogActions overrides the actions of the watchdog when the model is busy or idle for too long.
+// Nil keeps the actions of the watchdog
+func WithWatchDogActions(busy, idle []string) Option {
+	return func(o *Options) {
+		o.busyActions = busy
+		o.idleActions = idle
+	}
+}
+
+// WithResources limits the CPUs, the priority, the memory and the GPUs of the backend process of the model
+func WithResources(r Resources) Option {
+	return func(o *Options) {
+		o.resources = r
+	}
+}
+
+// WithGPU places the model on the GPUs with the most free memory with auto, or on the GPUs of the list of
+// indexes, when its backend is started
+func WithGPU(preference string) Option {
+	return func(o *Options) {
+		o.gpu = preference
+	}
+}
+
+// WithBackendProcess adds the environment variables, as KEY=value, and the arguments to the backend
+// process of the model when it is started
+func WithBackendProcess(env, args []string) Option {
+	return func(o *Options) {
+		o.environment = env
+		o.backendArgs = args
+	}
+}
+
+// WithKVCache saves the KV cache of the model to the directory when it is unloaded, and restores it when the
+// model is loaded again
+func WithKVCache(path string) Option {
+	return func(o *Options) {
+		o.kvCache = path
+	}
+}
+
+// WithPrioritySlots keeps slots of the backend for the priority requests, when it processes several
+// requests at once
+func WithPrioritySlots(n int) Option {
+	return func(o *Options) {
+		o.prioritySlots = n
+	}
+}
+
 func WithGRPCAttempts(attempts int) Option {
 	return func(o *Options) {
 		o.grpcAttempts = attempts
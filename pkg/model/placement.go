@@ -0,0 +1,100 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
+)
+
+// GPUAuto places the model on the GPUs with the most free memory when its backend is started
+const GPUAuto = "auto"
+
+// ValidateGPU returns an error if the GPU preference of a model is neither auto nor a list of GPU indexes
+func ValidateGPU(preference string) error {
+	if preference == "" || preference == GPUAuto {
+		return nil
+	}
+	for _, index := range strings.Split(preference, ",") {
+		if i, err := strconv.Atoi(strings.TrimSpace(index)); err != nil || i < 0 {
+			return fmt.Errorf("invalid gpu %q, expected %s or GPU indexes as \"0,1\"", preference, GPUAuto)
+		}
+	}
+	return nil
+}
+
+// PlaceGPUs returns the indexes of the count GPUs with the most free memory, in the order of their indexes
+func PlaceGPUs(gpus []xsysinfo.GPUMemory, count int) ([]int, error) {
+	if len(gpus) < count {
+		return nil, fmt.Errorf("%d GPUs requested, %d available", count, len(gpus))
+	}
+	gpus = append([]xsysinfo.GPUMemory{}, gpus...)
+	sort.SliceStable(gpus, func(i, j int) bool { return gpus[i].Free > gpus[j].Free })
+	placed := make([]int, 0, count)
+	for _, gpu := range gpus[:count] {
+		placed = append(placed, gpu.Index)
+	}
+	sort.Ints(placed)
+	return placed, nil
+}
+
+// gpuCount is the number of GPUs of a model placed automatically: one by ratio of its tensor split, or its
+// tensor parallel size
+func gpuCount(opts *pb.ModelOptions) int {
+	if opts.TensorSplit != "" {
+		return len(strings.Split(opts.TensorSplit, ","))
+	}
+	if opts.TensorParallelSize > 1 {
+		return int(opts.TensorParallelSize)
+	}
+	return 1
+}
+
+// placeGPUs returns the GPUs visible to the backend of the model, as CUDA_VISIBLE_DEVICES, by its preference.
+// The tensor split of llama.cpp applies to the GPUs visible to the backend
+func (ml *ModelLoader) placeGPUs(preference string, opts *pb.ModelOptions) (string, error) {
+	if preference != GPUAuto {
+		return strings.ReplaceAll(preference, " ", ""), nil
+	}
+	gpus, err := ml.gpus()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the free memory of the GPUs: %w", err)
+	}
+	if len(gpus) == 0 {
+		return "", errors.New("no GPU available")
+	}
+	placed, err := PlaceGPUs(gpus, gpuCount(opts))
+	if err != nil {
+		return "", err
+	}
+	indexes := make([]string, len(placed))
+	for i, index := range placed {
+		indexes[i] = strconv.Itoa(index)
+	}
+	return strings.Join(indexes, ","), nil
+}
+
+// backendResources returns the resources of the backend of the model, with the GPUs placed by its
+// preference when they are not set
+func (ml *ModelLoader) backendResources(o *Options) Resources {
+	resources := o.resources
+	if o.gpu == "" || resources.GPUs != "" {
+		return resources
+	}
+	gpus, err := ml.placeGPUs(o.gpu, o.gRPCOptions)
+	if err != nil {
+		zlog.Warn().Err(err).Str("model", o.model).Msg("the model is not placed on the GPUs")
+		return resources
+	}
+	zlog.Info().Str("model", o.model).Str("gpus", gpus).Msg("placing the model on the GPUs")
+	resources.GPUs = gpus
+	// vLLM runs on the GPUs visible to the backend by its tensor parallelism
+	if count := len(strings.Split(gpus, ",")); count > 1 && o.gRPCOptions.TensorParallelSize == 0 {
+		o.gRPCOptions.TensorParallelSize = int32(count)
+	}
+	return resources
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	"github.com/mudler/LocalAI/pkg/xsysinfo"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GPU placement", func() {
+	gpus := []xsysinfo.GPUMemory{
+		{Index: 0, Total: 24 << 30, Free: 2 << 30},
+		{Index: 1, Total: 24 << 30, Free: 20 << 30},
+		{Index: 2, Total: 24 << 30, Free: 12 << 30},
+	}
+
+	var ml *ModelLoader
+	BeforeEach(func() {
+		ml = NewModelLoader(GinkgoT().TempDir())
+		ml.gpus = func() ([]xsysinfo.GPUMemory, error) { return gpus, nil }
+	})
+
+	It("places the models on the GPUs with the most free memory", func() {
+		Expect(PlaceGPUs(gpus, 1)).To(Equal([]int{1}))
+		Expect(PlaceGPUs(gpus, 2)).To(Equal([]int{1, 2}))
+		_, err := PlaceGPUs(gpus, 4)
+		Expect(err).To(MatchError("4 GPUs requested, 3 available"))
+	})
+
+	It("places a model on a GPU by ratio of its tensor split", func() {
+		o := NewOptions(WithModel("llama"), WithGPU(GPUAuto), WithLoadGRPCLoadModelOpts(&pb.ModelOptions{TensorSplit: "0.6,0.4"}))
+		Expect(ml.backendResources(o).GPUs).To(Equal("1,2"))
+		Expect(o.gRPCOptions.TensorParallelSize).To(Equal(int32(2)))
+
+		o = NewOptions(WithModel("llama"), WithGPU(GPUAuto))
+		Expect(ml.backendResources(o).GPUs).To(Equal("1"))
+		Expect(o.gRPCOptions.TensorParallelSize).To(BeZero())
+	})
+
+	It("selects the GPUs of the list", func() {
+		o := NewOptions(WithModel("vllm"), WithGPU("0, 2"))
+		Expect(ml.backendResources(o).GPUs).To(Equal("0,2"))
+		Expect(o.gRPCOptions.TensorParallelSize).To(Equal(int32(2)))
+	})
+
+	It("keeps the GPUs of the resources", func() {
+		o := NewOptions(WithGPU(GPUAuto), WithResources(Resources{GPUs: "0"}))
+		Expect(ml.backendResources(o).GPUs).To(Equal("0"))
+	})
+
+	It("does not place the models without GPU", func() {
+		ml.gpus = func() ([]xsysinfo.GPUMemory, error) { return nil, errors.New("nvidia-smi not found") }
+		Expect(ml.backendResources(NewOptions(WithGPU(GPUAuto))).GPUs).To(BeEmpty())
+	})
+
+	It("validates the preferences", func() {
+		Expect(ValidateGPU("")).To(Succeed())
+		Expect(ValidateGPU(GPUAuto)).To(Succeed())
+		Expect(ValidateGPU("0,1")).To(Succeed())
+		Expect(ValidateGPU("first")).To(MatchError(ContainSubstring(`invalid gpu "first"`)))
+	})
+})
@@ -3,6 +3,7 @@ package model
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
 	"strconv"
@@ -11,32 +12,61 @@ import (
 	"time"
 
 	"github.com/hpcloud/tail"
+	"github.com/mudler/LocalAI/pkg/events"
+	"github.com/mudler/LocalAI/pkg/logging"
 	process "github.com/mudler/go-processmanager"
-	"github.com/rs/zerolog/log"
 )
 
+// zlog logs the processes of the backends and their output
+var zlog = logging.Logger(logging.GRPC)
+
 func (ml *ModelLoader) StopAllExcept(s string) error {
 	return ml.StopGRPC(func(id string, p *process.Process) bool {
 		if id != s {
 			for ml.models[id].GRPC(false, ml.wd).IsBusy() {
-				log.Debug().Msgf("%s busy. Waiting.", id)
+				zlog.Debug().Msgf("%s busy. Waiting.", id)
 				time.Sleep(2 * time.Second)
 			}
-			log.Debug().Msgf("[single-backend] Stopping %s", id)
+			zlog.Debug().Msgf("[single-backend] Stopping %s", id)
 			return true
 		}
 		return false
 	})
 }
 
+// grpcProcess returns the process of the backend started with the id, if any
+func (ml *ModelLoader) grpcProcess(id string) (*process.Process, bool) {
+	ml.processMu.Lock()
+	defer ml.processMu.Unlock()
+	p, exists := ml.grpcProcesses[id]
+	return p, exists
+}
+
 func (ml *ModelLoader) deleteProcess(s string) error {
-	if _, exists := ml.grpcProcesses[s]; exists {
-		if err := ml.grpcProcesses[s].Stop(); err != nil {
+	if addr, loaded := ml.models[s]; loaded {
+		ml.saveKVCache(s, addr)
+	}
+	if p, exists := ml.grpcProcess(s); exists {
+		if err := p.Stop(); err != nil {
 			return err
 		}
 	}
+	ml.processMu.Lock()
 	delete(ml.grpcProcesses, s)
+	ml.processMu.Unlock()
+	releaseResources(s)
+	if addr, loaded := ml.models[s]; loaded {
+		// The clients of the backend, with its slots, are not reused if another backend gets its address
+		delete(ml.grpcClients, string(addr))
+		ml.statusMu.Lock()
+		delete(ml.slots, string(addr))
+		delete(ml.prioritySlots, string(addr))
+		ml.statusMu.Unlock()
+		ml.recordEviction(s)
+		ml.events.Publish(events.ModelUnloaded, map[string]any{"model": s})
+	}
 	delete(ml.models, s)
+	ml.forgetLoadStatus(s)
 	return nil
 }
 
@@ -48,7 +78,10 @@ func includeAllProcesses(_ string, _ *process.Process) bool {
 
 func (ml *ModelLoader) StopGRPC(filter GRPCProcessFilter) error {
 	var err error = nil
-	for k, p := range ml.grpcProcesses {
+	ml.processMu.Lock()
+	processes := maps.Clone(ml.grpcProcesses)
+	ml.processMu.Unlock()
+	for k, p := range processes {
 		if filter(k, p) {
 			e := ml.deleteProcess(k)
 			err = errors.Join(err, e)
@@ -62,28 +95,28 @@ func (ml *ModelLoader) StopAllGRPC() error {
 }
 
 func (ml *ModelLoader) GetGRPCPID(id string) (int, error) {
-	p, exists := ml.grpcProcesses[id]
+	p, exists := ml.grpcProcess(id)
 	if !exists {
 		return -1, fmt.Errorf("no grpc backend found for %s", id)
 	}
 	return strconv.Atoi(p.PID)
 }
 
-func (ml *ModelLoader) startProcess(grpcProcess, id string, serverAddress string, args ...string) error {
+func (ml *ModelLoader) startProcess(grpcProcess, id string, serverAddress string, resources Resources, env []string, args ...string) error {
 	// Make sure the process is executable
 	if err := os.Chmod(grpcProcess, 0700); err != nil {
 		return err
 	}
 
-	log.Debug().Msgf("Loading GRPC Process: %s", grpcProcess)
+	zlog.Debug().Msgf("Loading GRPC Process: %s", grpcProcess)
 
-	log.Debug().Msgf("GRPC Service for %s will be running at: '%s'", id, serverAddress)
+	zlog.Debug().Msgf("GRPC Service for %s will be running at: '%s'", id, serverAddress)
 
 	grpcControlProcess := process.New(
 		process.WithTemporaryStateDir(),
 		process.WithName(grpcProcess),
 		process.WithArgs(append(args, []string{"--addr", serverAddress}...)...),
-		process.WithEnvironment(os.Environ()...),
+		process.WithEnvironment(append(append(os.Environ(), resources.environment()...), env...)...),
 	)
 
 	if ml.wd != nil {
@@ -91,13 +124,25 @@ func (ml *ModelLoader) startProcess(grpcProcess, id string, serverAddress string
 		ml.wd.AddAddressModelMap(serverAddress, id)
 	}
 
+	ml.processMu.Lock()
 	ml.grpcProcesses[id] = grpcControlProcess
+	ml.processMu.Unlock()
 
 	if err := grpcControlProcess.Run(); err != nil {
 		return err
 	}
 
-	log.Debug().Msgf("GRPC Service state dir: %s", grpcControlProcess.StateDir())
+	if !resources.IsZero() {
+		pid, err := strconv.Atoi(grpcControlProcess.PID)
+		if err == nil {
+			err = applyResources(pid, id, resources)
+		}
+		if err != nil {
+			zlog.Warn().Err(err).Str("model", id).Msg("the resources of the backend are not limited")
+		}
+	}
+
+	zlog.Debug().Msgf("GRPC Service state dir: %s", grpcControlProcess.StateDir())
 	// clean up process
 	go func() {
 		c := make(chan os.Signal, 1)
@@ -105,26 +150,26 @@ func (ml *ModelLoader) startProcess(grpcProcess, id string, serverAddress string
 		<-c
 		err := grpcControlProcess.Stop()
 		if err != nil {
-			log.Error().Err(err).Msg("error while shutting down grpc process")
+			zlog.Error().Err(err).Msg("error while shutting down grpc process")
 		}
 	}()
 
 	go func() {
 		t, err := tail.TailFile(grpcControlProcess.StderrPath(), tail.Config{Follow: true})
 		if err != nil {
-			log.Debug().Msgf("Could not tail stderr")
+			zlog.Debug().Msgf("Could not tail stderr")
 		}
 		for line := range t.Lines {
-			log.Debug().Msgf("GRPC(%s): stderr %s", strings.Join([]string{id, serverAddress}, "-"), line.Text)
+			zlog.Debug().Msgf("GRPC(%s): stderr %s", strings.Join([]string{id, serverAddress}, "-"), line.Text)
 		}
 	}()
 	go func() {
 		t, err := tail.TailFile(grpcControlProcess.StdoutPath(), tail.Config{Follow: true})
 		if err != nil {
-			log.Debug().Msgf("Could not tail stdout")
+			zlog.Debug().Msgf("Could not tail stdout")
 		}
 		for line := range t.Lines {
-			log.Debug().Msgf("GRPC(%s): stdout %s", strings.Join([]string{id, serverAddress}, "-"), line.Text)
+			zlog.Debug().Msgf("GRPC(%s): stdout %s", strings.Join([]string{id, serverAddress}, "-"), line.Text)
 		}
 	}()
 
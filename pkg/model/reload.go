@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/events"
+	"github.com/mudler/LocalAI/pkg/grpc"
+	process "github.com/mudler/go-processmanager"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// reloadDrainInterval is how often a backend is checked while its requests are drained
+	reloadDrainInterval = 100 * time.Millisecond
+	// reloadDrainTimeout is how long the requests of a backend can take before it is stopped anyway
+	reloadDrainTimeout = 5 * time.Minute
+)
+
+// reloadID is the id of the backend loading the model again, until it replaces the running backend
+func reloadID(modelName string) string {
+	return modelName + ".reload"
+}
+
+// BackendReloader loads the model again with the backend of the options, see ReloadModel
+func (ml *ModelLoader) BackendReloader(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	backend := strings.ToLower(o.backendString)
+	if realBackend, exists := Aliases[backend]; exists {
+		backend = realBackend
+	}
+	if backend == PiperBackend {
+		o.gRPCOptions.LibrarySearchPath = filepath.Join(o.assetDir, "backend-assets", "espeak-ng-data")
+	}
+
+	// The process of the new backend runs next to the one of the running backend
+	staged := *o
+	staged.model = reloadID(o.model)
+	return ml.ReloadModel(o.model, ml.grpcModel(backend, &staged))
+}
+
+// ReloadModel loads the model again with loader, to pick up its updated files, and swaps the backends
+// once the new one is ready. The running backend keeps serving the model while the new one loads, and
+// finishes its requests before being stopped, while the new requests go to the new backend. The other
+// models are not blocked. If the loading fails, the running backend keeps serving the model.
+// The models which are not loaded are left alone, they are loaded with their new files on their next request
+func (ml *ModelLoader) ReloadModel(modelName string, loader func(string, string) (ModelAddress, error)) error {
+	ml.reloadMu.Lock()
+	defer ml.reloadMu.Unlock()
+
+	ml.mu.Lock()
+	previous, loaded := ml.models[modelName]
+	ml.mu.Unlock()
+	if !loaded {
+		return nil
+	}
+
+	log.Info().Str("model", modelName).Msg("Reloading model")
+	ml.startLoading(modelName)
+	addr, err := loader(modelName, filepath.Join(ml.ModelPath, modelName))
+	// The running backend is still loaded whether the new one loaded or not
+	ml.finishLoading(modelName, nil)
+
+	ml.mu.Lock()
+	if current, loaded := ml.models[modelName]; err == nil && current != previous {
+		// The model was unloaded, or loaded again, while the new backend was loading
+		err = fmt.Errorf("model %s was unloaded during the reload", modelName)
+		if !loaded {
+			ml.forgetLoadStatus(modelName)
+		}
+	}
+	if err != nil {
+		if err := ml.deleteProcess(reloadID(modelName)); err != nil {
+			log.Error().Err(err).Str("model", modelName).Msg("error stopping the backend which failed to reload the model")
+		}
+		ml.mu.Unlock()
+		return fmt.Errorf("failed reloading model %s: %w", modelName, err)
+	}
+
+	// The external backends served at an address reload the model in place
+	swapped := addr != previous
+	var client grpc.Backend
+	var previousProcess *process.Process
+	if swapped {
+		client = ml.grpcClients[string(previous)]
+		delete(ml.grpcClients, string(previous))
+		if ml.wd != nil {
+			ml.wd.AddAddressModelMap(string(addr), modelName)
+		}
+		ml.processMu.Lock()
+		previousProcess = ml.grpcProcesses[modelName]
+		delete(ml.grpcProcesses, modelName)
+		if p, staged := ml.grpcProcesses[reloadID(modelName)]; staged {
+			ml.grpcProcesses[modelName] = p
+			delete(ml.grpcProcesses, reloadID(modelName))
+		}
+		ml.processMu.Unlock()
+		ml.models[modelName] = addr
+	}
+	ml.mu.Unlock()
+
+	log.Info().Str("model", modelName).Msg("Model reloaded")
+	ml.events.Publish(events.ModelLoaded, map[string]any{"model": modelName, "address": string(addr), "reloaded": true})
+
+	if swapped {
+		ml.waitIdle(previous, client)
+		if previousProcess != nil {
+			if err := previousProcess.Stop(); err != nil {
+				log.Error().Err(err).Str("model", modelName).Msg("error stopping the previous backend of the model")
+			}
+		}
+		if ml.wd != nil {
+			ml.wd.Remove(string(previous))
+		}
+	}
+	return nil
+}
+
+// DrainModel waits for the requests running on the model to finish. The requests to the other models,
+// and the new requests to the model, are not blocked
+func (ml *ModelLoader) DrainModel(modelName string) {
+	ml.mu.Lock()
+	addr, loaded := ml.models[modelName]
+	client := ml.grpcClients[string(addr)]
+	ml.mu.Unlock()
+	if loaded {
+		ml.waitIdle(addr, client)
+	}
+}
+
+// waitIdle waits for the requests running on the backend at the address to finish. client is the
+// client of the backend shared by the requests, nil for the backends running parallel requests
+func (ml *ModelLoader) waitIdle(addr ModelAddress, client grpc.Backend) {
+	deadline := time.Now().Add(reloadDrainTimeout)
+	for (ml.wd != nil && ml.wd.IsBusy(string(addr))) || (client != nil && client.IsBusy()) {
+		if time.Now().After(deadline) {
+			log.Warn().Str("address", string(addr)).Msg("Requests still running after the drain timeout, stopping the backend anyway")
+			return
+		}
+		time.Sleep(reloadDrainInterval)
+	}
+}
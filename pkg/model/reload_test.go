@@ -0,0 +1,187 @@
+package model_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/events"
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// addressSink records the addresses of the models loaded
+type addressSink struct {
+	sync.Mutex
+	addresses []any
+}
+
+func (s *addressSink) Send(e events.Event) error {
+	s.Lock()
+	defer s.Unlock()
+	s.addresses = append(s.addresses, e.Data["address"])
+	return nil
+}
+
+func (s *addressSink) Close() error { return nil }
+
+func (s *addressSink) Addresses() []any {
+	s.Lock()
+	defer s.Unlock()
+	return append([]any{}, s.addresses...)
+}
+
+func loadAt(address string) func(string, string) (ModelAddress, error) {
+	return func(string, string) (ModelAddress, error) {
+		return ModelAddress(address), nil
+	}
+}
+
+var _ = Describe("ReloadModel", func() {
+	var ml *ModelLoader
+	var bus *events.Bus
+	var sink *addressSink
+
+	BeforeEach(func() {
+		ml = NewModelLoader(GinkgoT().TempDir())
+		bus = events.NewBus([]string{events.ModelLoaded})
+		sink = &addressSink{}
+		bus.AddSink(sink)
+		ml.SetEvents(bus)
+	})
+
+	It("leaves the models which are not loaded alone", func() {
+		Expect(ml.ReloadModel("model.gguf", func(string, string) (ModelAddress, error) {
+			Fail("the model should not be loaded")
+			return "", nil
+		})).To(Succeed())
+		Expect(ml.LoadedModels()).To(BeEmpty())
+	})
+
+	It("swaps the backend once the model is loaded again", func() {
+		_, err := ml.LoadModel("model.gguf", loadAt("127.0.0.1:1"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var file string
+		Expect(ml.ReloadModel("model.gguf", func(name, modelFile string) (ModelAddress, error) {
+			file = modelFile
+			return "127.0.0.1:2", nil
+		})).To(Succeed())
+		Expect(file).To(Equal(filepath.Join(ml.ModelPath, "model.gguf")))
+		Expect(ml.LoadedModels()).To(Equal([]string{"model.gguf"}))
+
+		Expect(bus.Close()).To(Succeed())
+		Expect(sink.addresses).To(Equal([]any{"127.0.0.1:1", "127.0.0.1:2"}))
+	})
+
+	It("keeps the running backend when the loading fails", func() {
+		_, err := ml.LoadModel("model.gguf", loadAt("127.0.0.1:1"))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ml.ReloadModel("model.gguf", func(string, string) (ModelAddress, error) {
+			return "", errors.New("truncated file")
+		})
+		Expect(err).To(MatchError(ContainSubstring("truncated file")))
+		Expect(ml.LoadedModels()).To(Equal([]string{"model.gguf"}))
+		status, _ := ml.GetLoadStatus("model.gguf")
+		Expect(status.State).To(Equal(StateLoaded))
+	})
+
+	It("drains the requests of the running backend before stopping it", func() {
+		wd := NewWatchDog(ml, time.Hour, time.Hour, false, false)
+		ml.SetWatchDog(wd)
+		_, err := ml.LoadModel("model.gguf", loadAt("127.0.0.1:1"))
+		Expect(err).ToNot(HaveOccurred())
+		wd.Mark("127.0.0.1:1")
+
+		reloaded := make(chan error)
+		go func() {
+			reloaded <- ml.ReloadModel("model.gguf", loadAt("127.0.0.1:2"))
+		}()
+		Consistently(reloaded, 300*time.Millisecond).ShouldNot(Receive())
+
+		wd.UnMark("127.0.0.1:1")
+		Eventually(reloaded).Should(Receive(BeNil()))
+		Expect(wd.IsBusy("127.0.0.1:1")).To(BeFalse())
+	})
+
+	It("serves the models while the model is reloaded", func() {
+		wd := NewWatchDog(ml, time.Hour, time.Hour, false, false)
+		ml.SetWatchDog(wd)
+		_, err := ml.LoadModel("model.gguf", loadAt("127.0.0.1:1"))
+		Expect(err).ToNot(HaveOccurred())
+		wd.Mark("127.0.0.1:1")
+
+		// load loads another model, without waiting for the reload
+		load := func(name, address string) chan ModelAddress {
+			loaded := make(chan ModelAddress, 1)
+			go func() {
+				addr, _ := ml.LoadModel(name, loadAt(address))
+				loaded <- addr
+			}()
+			return loaded
+		}
+
+		loading := make(chan bool)
+		reloaded := make(chan error)
+		go func() {
+			reloaded <- ml.ReloadModel("model.gguf", func(string, string) (ModelAddress, error) {
+				<-loading
+				return "127.0.0.1:2", nil
+			})
+		}()
+
+		// While the new backend loads, the running one serves the model and the other models are loaded
+		Eventually(load("other.gguf", "127.0.0.1:3")).Should(Receive(Equal(ModelAddress("127.0.0.1:3"))))
+		Expect(ml.IsLoaded("model.gguf")).To(BeTrue())
+
+		// While the running backend drains, the requests of the model go to the new one, which is not busy
+		close(loading)
+		Eventually(sink.Addresses).Should(ContainElement("127.0.0.1:2"))
+		drained := make(chan bool)
+		go func() {
+			ml.DrainModel("model.gguf")
+			close(drained)
+		}()
+		Eventually(drained).Should(BeClosed())
+		Consistently(reloaded, 300*time.Millisecond).ShouldNot(Receive())
+		Eventually(load("third.gguf", "127.0.0.1:4")).Should(Receive(Equal(ModelAddress("127.0.0.1:4"))))
+
+		wd.UnMark("127.0.0.1:1")
+		Eventually(reloaded).Should(Receive(BeNil()))
+	})
+
+	It("drops the new backend when the model is unloaded during the reload", func() {
+		_, err := ml.LoadModel("model.gguf", loadAt("127.0.0.1:1"))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ml.ReloadModel("model.gguf", func(string, string) (ModelAddress, error) {
+			Expect(ml.ShutdownModel("model.gguf")).To(Succeed())
+			return "127.0.0.1:2", nil
+		})
+		Expect(err).To(MatchError(ContainSubstring("unloaded during the reload")))
+		Expect(ml.LoadedModels()).To(BeEmpty())
+	})
+
+	It("drains the requests of a model without blocking the other models", func() {
+		wd := NewWatchDog(ml, time.Hour, time.Hour, false, false)
+		ml.SetWatchDog(wd)
+		_, err := ml.LoadModel("model-v1.gguf", loadAt("127.0.0.1:1"))
+		Expect(err).ToNot(HaveOccurred())
+		wd.Mark("127.0.0.1:1")
+
+		drained := make(chan bool)
+		go func() {
+			ml.DrainModel("model-v1.gguf")
+			close(drained)
+		}()
+		Consistently(drained, 300*time.Millisecond).ShouldNot(BeClosed())
+		Expect(ml.LoadedModels()).To(Equal([]string{"model-v1.gguf"}))
+
+		wd.UnMark("127.0.0.1:1")
+		Eventually(drained).Should(BeClosed())
+	})
+})
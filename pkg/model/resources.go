@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resources limits the resources of the backend process of a model, so that a model can not starve the host
+type Resources struct {
+	// CPUs the backend runs on, as "0-3,8". Empty runs it on all the CPUs
+	CPUSet string
+	// Nice is the niceness of the backend, from -20 (highest priority) to 19. Nil keeps the one of LocalAI
+	Nice *int
+	// Memory limit of the backend in bytes, enforced with cgroups v2 where available. 0 is unlimited
+	Memory uint64
+	// GPUs visible to the backend, as the CUDA_VISIBLE_DEVICES indexes or UUIDs. Empty keeps them all
+	GPUs string
+}
+
+// IsZero tells if the resources of the backend are not limited
+func (r Resources) IsZero() bool {
+	return r.CPUSet == "" && r.Nice == nil && r.Memory == 0 && r.GPUs == ""
+}
+
+// Validate returns an error if the cpuset or the nice level are invalid
+func (r Resources) Validate() error {
+	if _, err := ParseCPUSet(r.CPUSet); err != nil {
+		return err
+	}
+	if r.Nice != nil && (*r.Nice < -20 || *r.Nice > 19) {
+		return fmt.Errorf("invalid nice level %d, expected a level between -20 and 19", *r.Nice)
+	}
+	return nil
+}
+
+// environment returns the variables selecting the GPUs of the backend
+func (r Resources) environment() []string {
+	if r.GPUs == "" {
+		return nil
+	}
+	return []string{"CUDA_VISIBLE_DEVICES=" + r.GPUs, "HIP_VISIBLE_DEVICES=" + r.GPUs}
+}
+
+// ParseCPUSet parses a list of CPUs and of ranges of CPUs, as "0-3,8"
+func ParseCPUSet(cpuset string) ([]int, error) {
+	cpus := []int{}
+	if cpuset == "" {
+		return cpus, nil
+	}
+	for _, part := range strings.Split(cpuset, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid cpuset %q, expected CPUs and ranges of CPUs as \"0-3,8\"", cpuset)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return nil, fmt.Errorf("invalid cpuset %q, expected CPUs and ranges of CPUs as \"0-3,8\"", cpuset)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroupRoot is the cgroup v2 hierarchy where the cgroups of the backends are created, one by model
+var cgroupRoot = "/sys/fs/cgroup"
+
+const cgroupParent = "localai"
+
+// applyResources limits the resources of the backend process started with the pid. The cpuset and the nice
+// level are applied to the threads started afterwards, which is the case of the threads of the backend
+// started right away
+func applyResources(pid int, id string, r Resources) error {
+	cpus, err := ParseCPUSet(r.CPUSet)
+	if err != nil {
+		return err
+	}
+	if len(cpus) > 0 {
+		set := unix.CPUSet{}
+		for _, cpu := range cpus {
+			set.Set(cpu)
+		}
+		if err := unix.SchedSetaffinity(pid, &set); err != nil {
+			return fmt.Errorf("failed to set the cpuset %s: %w", r.CPUSet, err)
+		}
+	}
+	if r.Nice != nil {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, pid, *r.Nice); err != nil {
+			return fmt.Errorf("failed to set the nice level %d: %w", *r.Nice, err)
+		}
+	}
+	if r.Memory > 0 {
+		if err := limitMemory(pid, id, r.Memory); err != nil {
+			return fmt.Errorf("failed to limit the memory to %d bytes, cgroups v2 must be writable by LocalAI: %w", r.Memory, err)
+		}
+	}
+	return nil
+}
+
+// limitMemory moves the process to the cgroup of the model, limited to the memory
+func limitMemory(pid int, id string, memory uint64) error {
+	parent := filepath.Join(cgroupRoot, cgroupParent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	// The memory controller is enabled for the children of the cgroups, LocalAI staying out of them
+	for _, dir := range []string{cgroupRoot, parent} {
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+memory"), 0644); err != nil {
+			return err
+		}
+	}
+	dir := cgroupPath(id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatUint(memory, 10)), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+}
+
+// releaseResources removes the cgroup of the model, once its backend is stopped
+func releaseResources(id string) {
+	os.Remove(cgroupPath(id))
+}
+
+func cgroupPath(id string) string {
+	return filepath.Join(cgroupRoot, cgroupParent, strings.NewReplacer("/", "_", "..", "_").Replace(id))
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package model
+
+import "errors"
+
+// applyResources only selects the GPUs of the backends, with their environment, outside of Linux
+func applyResources(pid int, id string, r Resources) error {
+	if r.CPUSet != "" || r.Nice != nil || r.Memory > 0 {
+		return errors.New("the cpuset, the nice level and the memory limit of the backends are only supported on Linux")
+	}
+	return nil
+}
+
+func releaseResources(id string) {}
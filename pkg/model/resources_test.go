@@ -0,0 +1,33 @@
+package model
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resources", func() {
+	It("parses the cpusets", func() {
+		Expect(ParseCPUSet("")).To(BeEmpty())
+		Expect(ParseCPUSet("0-3,8")).To(Equal([]int{0, 1, 2, 3, 8}))
+		Expect(ParseCPUSet("2, 4")).To(Equal([]int{2, 4}))
+		for _, invalid := range []string{"a", "3-1", "-1", "0-"} {
+			_, err := ParseCPUSet(invalid)
+			Expect(err).To(MatchError(ContainSubstring("invalid cpuset")), invalid)
+		}
+	})
+
+	It("validates the nice levels", func() {
+		nice := 10
+		Expect(Resources{Nice: &nice}.Validate()).To(Succeed())
+		nice = 20
+		Expect(Resources{Nice: &nice}.Validate()).To(MatchError(ContainSubstring("invalid nice level 20")))
+	})
+
+	It("selects the GPUs of the backends with their environment", func() {
+		Expect(Resources{}.IsZero()).To(BeTrue())
+		Expect(Resources{}.environment()).To(BeEmpty())
+		r := Resources{GPUs: "1"}
+		Expect(r.IsZero()).To(BeFalse())
+		Expect(r.environment()).To(ContainElement("CUDA_VISIBLE_DEVICES=1"))
+	})
+})
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"maps"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+)
+
+// threadsTuning is the request predicted with each number of threads to tune the threads of a model
+type threadsTuning struct {
+	request    *pb.PredictOptions
+	candidates []int
+}
+
+// ThreadsTuning is the speed of a model measured with several numbers of threads after loading it
+type ThreadsTuning struct {
+	// TokensPerSecond generated with each number of threads
+	TokensPerSecond map[int]float64
+	TunedAt         time.Time
+	// Threads is the number of threads of the last request, for the load at the time
+	Threads int
+}
+
+// DefaultThreadsCandidates are the powers of two up to the number of CPUs, and the number of CPUs
+func DefaultThreadsCandidates() []int {
+	candidates := []int{}
+	for t := 1; t < runtime.NumCPU(); t *= 2 {
+		candidates = append(candidates, t)
+	}
+	return append(candidates, runtime.NumCPU())
+}
+
+// tuneThreads measures the tokens per second of the model with each number of threads of the options
+func (ml *ModelLoader) tuneThreads(modelName string, backend grpc.Backend, o *Options) {
+	tuning := &ThreadsTuning{TokensPerSecond: map[int]float64{}, TunedAt: time.Now()}
+	for _, threads := range o.threadsTuning.candidates {
+		request := *o.threadsTuning.request
+		request.Threads = int32(threads)
+		start := time.Now()
+		res, err := backend.Predict(o.context, &request)
+		if err != nil {
+			zlog.Warn().Err(err).Str("model", modelName).Int("threads", threads).Msg("failed measuring the speed of the model")
+			continue
+		}
+		tokens, seconds := float64(res.Tokens), time.Since(start).Seconds()
+		if tokens == 0 {
+			tokens = float64(request.Tokens)
+		}
+		if res.TimingTokenGeneration > 0 {
+			seconds = res.TimingTokenGeneration / 1000
+		}
+		tuning.TokensPerSecond[threads] = tokens / seconds
+		zlog.Debug().Str("model", modelName).Int("threads", threads).Float64("tokens_per_second", tokens/seconds).Msg("measured the speed of the model")
+	}
+	if len(tuning.TokensPerSecond) == 0 {
+		return
+	}
+
+	ml.statusMu.Lock()
+	ml.tuning[modelName] = tuning
+	threads := ml.tunedThreads(modelName, tuning)
+	ml.statusMu.Unlock()
+	zlog.Info().Str("model", modelName).Int("threads", threads).Msg("tuned the threads of the model")
+}
+
+// GetThreadsTuning returns the speeds of the model measured with each number of threads, if they were tuned
+func (ml *ModelLoader) GetThreadsTuning(modelName string) (ThreadsTuning, bool) {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	tuning, exists := ml.tuning[modelName]
+	if !exists {
+		return ThreadsTuning{}, false
+	}
+	t := *tuning
+	t.TokensPerSecond = maps.Clone(tuning.TokensPerSecond)
+	return t, true
+}
+
+// Threads returns the number of threads of a request to the model, the fastest measured which fits in the
+// CPUs left by the other models running requests, or 0 when the threads of the model were not tuned.
+// The number is evaluated again for each request, so it follows the concurrent load
+func (ml *ModelLoader) Threads(modelName string) int {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	tuning, exists := ml.tuning[modelName]
+	if !exists {
+		return 0
+	}
+	return ml.tunedThreads(modelName, tuning)
+}
+
+// tunedThreads picks the threads of the model for the current load. Called with the lock held
+func (ml *ModelLoader) tunedThreads(modelName string, tuning *ThreadsTuning) int {
+	busy := 1
+	for model, running := range ml.running {
+		if model != modelName && running > 0 {
+			busy++
+		}
+	}
+	threads := pickThreads(tuning.TokensPerSecond, runtime.NumCPU()/busy)
+	if threads != tuning.Threads && tuning.Threads != 0 {
+		zlog.Info().Str("model", modelName).Int("threads", threads).Int("models_running", busy).Msg("changing the threads of the model for the load")
+	}
+	tuning.Threads = threads
+	return threads
+}
+
+// pickThreads returns the number of threads with the most tokens per second up to the budget of CPUs, or the
+// smallest number of threads measured when none fits
+func pickThreads(tokensPerSecond map[int]float64, budget int) int {
+	candidates := make([]int, 0, len(tokensPerSecond))
+	for threads := range tokensPerSecond {
+		candidates = append(candidates, threads)
+	}
+	sort.Ints(candidates)
+	best := candidates[0]
+	for _, threads := range candidates {
+		if threads <= budget && tokensPerSecond[threads] > tokensPerSecond[best] {
+			best = threads
+		}
+	}
+	return best
+}
+
+// Running records a request running on the model, until the returned function is called, to share the CPUs
+// among the models running requests
+func (ml *ModelLoader) Running(modelName string) func() {
+	ml.statusMu.Lock()
+	defer ml.statusMu.Unlock()
+	ml.running[modelName]++
+	return func() {
+		ml.statusMu.Lock()
+		defer ml.statusMu.Unlock()
+		ml.running[modelName]--
+		if ml.running[modelName] <= 0 {
+			delete(ml.running, modelName)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Threads picking", func() {
+	speeds := map[int]float64{4: 10, 8: 18, 16: 24, 32: 21}
+
+	It("picks the fastest number of threads in the budget of CPUs", func() {
+		Expect(pickThreads(speeds, 64)).To(Equal(16))
+		Expect(pickThreads(speeds, 12)).To(Equal(8))
+		Expect(pickThreads(speeds, 2)).To(Equal(4))
+	})
+
+	It("shares the CPUs among the models running requests", func() {
+		if runtime.NumCPU() < 2 {
+			Skip("a single CPU can not be shared")
+		}
+		ml := NewModelLoader(GinkgoT().TempDir())
+		ml.tuning["a"] = &ThreadsTuning{TokensPerSecond: map[int]float64{1: 1, runtime.NumCPU(): 2}}
+		Expect(ml.Threads("a")).To(Equal(runtime.NumCPU()))
+
+		done := ml.Running("b")
+		Expect(ml.Threads("a")).To(Equal(1))
+		done()
+		Expect(ml.Threads("a")).To(Equal(runtime.NumCPU()))
+		Expect(ml.running).To(BeEmpty())
+	})
+})
@@ -0,0 +1,67 @@
+package model_test
+
+import (
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// threadsLLM predicts faster with more threads
+type threadsLLM struct {
+	fakeLLM
+	threads []int32
+}
+
+func (t *threadsLLM) Predict(opts *pb.PredictOptions) (string, error) {
+	t.threads = append(t.threads, opts.Threads)
+	time.Sleep(time.Duration(40/opts.Threads) * time.Millisecond)
+	return "ok", nil
+}
+
+var _ = Describe("Threads tuning", func() {
+	var ml *ModelLoader
+
+	BeforeEach(func() {
+		ml = NewModelLoader(GinkgoT().TempDir())
+	})
+
+	It("measures the speed of the model with each number of threads after loading it", func() {
+		llm := &threadsLLM{}
+		grpc.Provide("threads-tuning", llm)
+
+		_, err := ml.BackendLoader(
+			WithBackendString("threads-tuning"),
+			WithModel("model.gguf"),
+			WithExternalBackend("threads-tuning", "threads-tuning"),
+			WithGRPCAttempts(1),
+			WithThreadsTuning(&pb.PredictOptions{Prompt: "warmup", Tokens: 8}, []int{1, 2, 4}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.threads).To(Equal([]int32{1, 2, 4}))
+
+		tuning, exists := ml.GetThreadsTuning("model.gguf")
+		Expect(exists).To(BeTrue())
+		Expect(tuning.TokensPerSecond).To(HaveLen(3))
+		Expect(tuning.TokensPerSecond[4]).To(BeNumerically(">", tuning.TokensPerSecond[1]))
+		Expect(ml.Threads("model.gguf")).To(BeNumerically(">", 0))
+	})
+
+	It("does not tune the threads of the other models", func() {
+		Expect(ml.Threads("model.gguf")).To(BeZero())
+		_, exists := ml.GetThreadsTuning("model.gguf")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("proposes the powers of two up to the number of CPUs", func() {
+		candidates := DefaultThreadsCandidates()
+		Expect(candidates[0]).To(Equal(1))
+		for i := 1; i < len(candidates); i++ {
+			Expect(candidates[i]).To(BeNumerically(">", candidates[i-1]))
+		}
+	})
+})
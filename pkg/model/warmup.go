@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/rs/zerolog/log"
+)
+
+// warmup runs the warmup request of the options after loading the model, so that the first request of the
+// users does not wait for the backend to compile its graphs or to fill its caches. A failure of the warmup
+// is only logged: the model is loaded anyway
+func (ml *ModelLoader) warmup(modelName string, backend grpc.Backend, o *Options) {
+	ml.ReportLoadProgress(modelName, grpc.LoadStageWarmup, 0)
+
+	start := time.Now()
+	var err error
+	if o.warmupEmbeddings {
+		_, err = backend.Embeddings(o.context, o.warmup)
+	} else {
+		_, err = backend.Predict(o.context, o.warmup)
+	}
+	duration := time.Since(start)
+	if err != nil {
+		log.Warn().Err(err).Str("model", modelName).Msg("failed warming up the model")
+		return
+	}
+
+	ml.ReportLoadProgress(modelName, grpc.LoadStageWarmup, 100)
+	ml.statusMu.Lock()
+	if s, exists := ml.loadStatus[modelName]; exists {
+		s.WarmupDuration = duration
+	}
+	ml.statusMu.Unlock()
+	log.Info().Str("model", modelName).Dur("duration", duration).Msg("model warmed up")
+}
@@ -0,0 +1,120 @@
+package model_test
+
+import (
+	"errors"
+
+	"github.com/mudler/LocalAI/pkg/grpc"
+	"github.com/mudler/LocalAI/pkg/grpc/base"
+	pb "github.com/mudler/LocalAI/pkg/grpc/proto"
+	. "github.com/mudler/LocalAI/pkg/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeLLM is an embedded backend recording the warmup requests
+type fakeLLM struct {
+	base.Base
+	predictions []string
+	embeddings  []string
+	failWarmup  bool
+}
+
+func (f *fakeLLM) Load(*pb.ModelOptions) error {
+	return nil
+}
+
+func (f *fakeLLM) Predict(opts *pb.PredictOptions) (string, error) {
+	if f.failWarmup {
+		return "", errors.New("warmup failed")
+	}
+	f.predictions = append(f.predictions, opts.Prompt)
+	return "ok", nil
+}
+
+func (f *fakeLLM) Embeddings(opts *pb.PredictOptions) ([]float32, error) {
+	f.embeddings = append(f.embeddings, opts.Embeddings)
+	return []float32{0.1}, nil
+}
+
+// progressLLM also reports the stages of the loading
+type progressLLM struct {
+	fakeLLM
+}
+
+func (p *progressLLM) LoadWithProgress(opts *pb.ModelOptions, progress func(stage string, percent float32)) error {
+	progress(grpc.LoadStageMmap, 50)
+	progress(grpc.LoadStageMmap, 100)
+	return nil
+}
+
+var _ = Describe("Warmup", func() {
+	var ml *ModelLoader
+
+	BeforeEach(func() {
+		ml = NewModelLoader(GinkgoT().TempDir())
+	})
+
+	load := func(backend string, opts ...Option) error {
+		_, err := ml.BackendLoader(append([]Option{
+			WithBackendString(backend),
+			WithModel("model.gguf"),
+			WithExternalBackend(backend, backend),
+			WithGRPCAttempts(1),
+		}, opts...)...)
+		return err
+	}
+
+	It("runs the prediction of the warmup after loading the model", func() {
+		llm := &fakeLLM{}
+		grpc.Provide("warmup-predict", llm)
+
+		Expect(load("warmup-predict", WithWarmup(&pb.PredictOptions{Prompt: "Hello", Tokens: 1}, false))).To(Succeed())
+		Expect(llm.predictions).To(Equal([]string{"Hello"}))
+		Expect(llm.embeddings).To(BeEmpty())
+
+		status, exists := ml.GetLoadStatus("model.gguf")
+		Expect(exists).To(BeTrue())
+		Expect(status.State).To(Equal(StateLoaded))
+		Expect(status.Progress).To(Equal(map[string]float64{grpc.LoadStageLoad: 100, grpc.LoadStageWarmup: 100}))
+		Expect(status.WarmupDuration).To(BeNumerically(">", 0))
+	})
+
+	It("computes an embedding as warmup", func() {
+		llm := &fakeLLM{}
+		grpc.Provide("warmup-embeddings", llm)
+
+		Expect(load("warmup-embeddings", WithWarmup(&pb.PredictOptions{Embeddings: "warmup"}, true))).To(Succeed())
+		Expect(llm.embeddings).To(Equal([]string{"warmup"}))
+		Expect(llm.predictions).To(BeEmpty())
+	})
+
+	It("loads the model when the warmup fails", func() {
+		grpc.Provide("warmup-failing", &fakeLLM{failWarmup: true})
+
+		Expect(load("warmup-failing", WithWarmup(&pb.PredictOptions{Prompt: "Hello"}, false))).To(Succeed())
+		status, _ := ml.GetLoadStatus("model.gguf")
+		Expect(status.State).To(Equal(StateLoaded))
+		Expect(status.Progress).To(HaveKeyWithValue(grpc.LoadStageWarmup, 0.0))
+		Expect(status.WarmupDuration).To(BeZero())
+	})
+
+	It("does not warm up the models without warmup", func() {
+		llm := &fakeLLM{}
+		grpc.Provide("warmup-none", llm)
+
+		Expect(load("warmup-none")).To(Succeed())
+		Expect(llm.predictions).To(BeEmpty())
+		status, _ := ml.GetLoadStatus("model.gguf")
+		Expect(status.Progress).ToNot(HaveKey(grpc.LoadStageWarmup))
+	})
+
+	It("records the stages reported by the backend", func() {
+		grpc.Provide("warmup-progress", &progressLLM{})
+
+		Expect(load("warmup-progress")).To(Succeed())
+		status, _ := ml.GetLoadStatus("model.gguf")
+		Expect(status.State).To(Equal(StateLoaded))
+		Expect(status.Progress).To(Equal(map[string]float64{grpc.LoadStageMmap: 100}))
+	})
+})
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"maps"
 	"sync"
 	"time"
 
@@ -11,8 +12,8 @@ import (
 // All GRPC Clients created by ModelLoader should have an associated injected
 // watchdog that will keep track of the state of each backend (busy or not)
 // and for how much time it has been busy.
-// If a backend is busy for too long, the watchdog will kill the process and
-// force a reload of the model
+// If a backend is busy or idle for too long, the watchdog takes the actions
+// configured, by default it kills the process and forces a reload of the model
 // The watchdog runs as a separate go routine,
 // and the GRPC client talks to it via a channel to send status updates
 
@@ -23,8 +24,20 @@ type WatchDog struct {
 	timeout, idletimeout time.Duration
 	addressMap           map[string]*process.Process
 	addressModelMap      map[string]string
-	pm                   ProcessManager
-	stop                 chan bool
+	// idleTimeouts overrides the idle timeout for some models, by their name
+	idleTimeouts map[string]time.Duration
+	pm           ProcessManager
+	stop         chan bool
+
+	// Actions taken when the backends are busy or idle for too long, and their overrides by model
+	busyActions, idleActions           []string
+	modelBusyActions, modelIdleActions map[string][]string
+	webhookURL                         string
+	restart                            func(model string)
+	// notified has the breaches already reported, to report them once when the backends are not stopped
+	notified map[string]time.Time
+	// kills counts the backends stopped by the watchdog, by model and by reason
+	kills map[string]map[string]int64
 
 	busyCheck, idleCheck bool
 }
@@ -44,6 +57,15 @@ func NewWatchDog(pm ProcessManager, timeoutBusy, timeoutIdle time.Duration, busy
 		busyCheck:       busy,
 		idleCheck:       idle,
 		addressModelMap: make(map[string]string),
+		idleTimeouts:    make(map[string]time.Duration),
+		stop:            make(chan bool, 1),
+
+		busyActions:      []string{WatchDogActionKill},
+		idleActions:      []string{WatchDogActionKill},
+		modelBusyActions: make(map[string][]string),
+		modelIdleActions: make(map[string][]string),
+		notified:         make(map[string]time.Time),
+		kills:            make(map[string]map[string]int64),
 	}
 }
 
@@ -59,6 +81,33 @@ func (wd *WatchDog) AddAddressModelMap(address string, model string) {
 	wd.addressModelMap[address] = model
 
 }
+
+// SetIdleTimeout overrides the idle timeout for the model, even when the idle check is disabled.
+// A negative timeout keeps the model loaded, 0 stops it as soon as it is idle
+func (wd *WatchDog) SetIdleTimeout(model string, timeout time.Duration) {
+	wd.Lock()
+	defer wd.Unlock()
+	wd.idleTimeouts[model] = timeout
+}
+
+// ResetIdleTimeout restores the idle timeout of the watchdog for the model
+func (wd *WatchDog) ResetIdleTimeout(model string) {
+	wd.Lock()
+	defer wd.Unlock()
+	delete(wd.idleTimeouts, model)
+}
+
+// idleTimeout returns the idle timeout of the backend at the address, false if it is not checked, and true
+// if the timeout is the one of the model. Called with the lock held
+func (wd *WatchDog) idleTimeout(address string) (time.Duration, bool, bool) {
+	if model, ok := wd.addressModelMap[address]; ok {
+		if timeout, ok := wd.idleTimeouts[model]; ok {
+			return timeout, timeout >= 0, true
+		}
+	}
+	return wd.idletimeout, wd.idleCheck, false
+}
+
 func (wd *WatchDog) Add(address string, p *process.Process) {
 	wd.Lock()
 	defer wd.Unlock()
@@ -79,6 +128,26 @@ func (wd *WatchDog) UnMark(ModelAddress string) {
 	wd.idleTime[ModelAddress] = time.Now()
 }
 
+// IsBusy tells if a request to the backend at the address is running
+func (wd *WatchDog) IsBusy(address string) bool {
+	wd.Lock()
+	defer wd.Unlock()
+	_, busy := wd.timetable[address]
+	return busy
+}
+
+// Remove forgets the backend at the address, once another backend replaced it
+func (wd *WatchDog) Remove(address string) {
+	wd.Lock()
+	defer wd.Unlock()
+	delete(wd.timetable, address)
+	delete(wd.idleTime, address)
+	delete(wd.addressMap, address)
+	delete(wd.addressModelMap, address)
+	delete(wd.notified, WatchDogEventBusy+":"+address)
+	delete(wd.notified, WatchDogEventIdle+":"+address)
+}
+
 func (wd *WatchDog) Run() {
 	log.Info().Msg("[WatchDog] starting watchdog")
 
@@ -88,16 +157,11 @@ func (wd *WatchDog) Run() {
 			log.Info().Msg("[WatchDog] Stopping watchdog")
 			return
 		case <-time.After(30 * time.Second):
-			if !wd.busyCheck && !wd.idleCheck {
-				log.Info().Msg("[WatchDog] No checks enabled, stopping watchdog")
-				return
-			}
 			if wd.busyCheck {
 				wd.checkBusy()
 			}
-			if wd.idleCheck {
-				wd.checkIdle()
-			}
+			// The models with their own idle timeout are checked even when the idle check is disabled
+			wd.checkIdle()
 		}
 	}
 }
@@ -108,20 +172,27 @@ func (wd *WatchDog) checkIdle() {
 	log.Debug().Msg("[WatchDog] Watchdog checks for idle connections")
 	for address, t := range wd.idleTime {
 		log.Debug().Msgf("[WatchDog] %s: idle connection", address)
-		if time.Since(t) > wd.idletimeout {
-			log.Warn().Msgf("[WatchDog] Address %s is idle for too long, killing it", address)
-			model, ok := wd.addressModelMap[address]
-			if ok {
-				if err := wd.pm.ShutdownModel(model); err != nil {
-					log.Error().Err(err).Str("model", model).Msg("[watchdog] error shutting down model")
-				}
-				log.Debug().Msgf("[WatchDog] model shut down: %s", address)
-				delete(wd.idleTime, address)
-				delete(wd.addressModelMap, address)
-				delete(wd.addressMap, address)
+		timeout, check, ttl := wd.idleTimeout(address)
+		if !check || time.Since(t) <= timeout {
+			continue
+		}
+		model, ok := wd.addressModelMap[address]
+		if !ok {
+			log.Warn().Msgf("[WatchDog] Address %s unresolvable", address)
+			delete(wd.idleTime, address)
+			continue
+		}
+		actions := wd.actions(model, wd.idleActions, wd.modelIdleActions)
+		if ttl {
+			// The ttl of the model, or the keep_alive of its requests, always stops it
+			actions = []string{WatchDogActionKill}
+		}
+		if wd.breach(WatchDogEventIdle, address, model, t, actions) {
+			delete(wd.idleTime, address)
+			if ttl {
+				wd.countKill(model, WatchDogReasonTTL)
 			} else {
-				log.Warn().Msgf("[WatchDog] Address %s unresolvable", address)
-				delete(wd.idleTime, address)
+				wd.countKill(model, WatchDogEventIdle)
 			}
 		}
 	}
@@ -134,23 +205,41 @@ func (wd *WatchDog) checkBusy() {
 
 	for address, t := range wd.timetable {
 		log.Debug().Msgf("[WatchDog] %s: active connection", address)
-
-		if time.Since(t) > wd.timeout {
-
-			model, ok := wd.addressModelMap[address]
-			if ok {
-				log.Warn().Msgf("[WatchDog] Model %s is busy for too long, killing it", model)
-				if err := wd.pm.ShutdownModel(model); err != nil {
-					log.Error().Err(err).Str("model", model).Msg("[watchdog] error shutting down model")
-				}
-				log.Debug().Msgf("[WatchDog] model shut down: %s", address)
-				delete(wd.timetable, address)
-				delete(wd.addressModelMap, address)
-				delete(wd.addressMap, address)
-			} else {
-				log.Warn().Msgf("[WatchDog] Address %s unresolvable", address)
-				delete(wd.timetable, address)
-			}
+		if time.Since(t) <= wd.timeout {
+			continue
+		}
+		model, ok := wd.addressModelMap[address]
+		if !ok {
+			log.Warn().Msgf("[WatchDog] Address %s unresolvable", address)
+			delete(wd.timetable, address)
+			continue
 		}
+		if wd.breach(WatchDogEventBusy, address, model, t, wd.actions(model, wd.busyActions, wd.modelBusyActions)) {
+			delete(wd.timetable, address)
+			wd.countKill(model, WatchDogEventBusy)
+		}
+	}
+}
+
+// WatchDogReasonTTL is the reason of the kills of the models idle for longer than their own idle timeout,
+// the busy and idle kills having the reason of their event
+const WatchDogReasonTTL = "ttl"
+
+// countKill counts a backend stopped by the watchdog. Called with the lock held
+func (wd *WatchDog) countKill(model, reason string) {
+	if wd.kills[model] == nil {
+		wd.kills[model] = map[string]int64{}
+	}
+	wd.kills[model][reason]++
+}
+
+// Kills returns the number of backends stopped by the watchdog, by model and by reason
+func (wd *WatchDog) Kills() map[string]map[string]int64 {
+	wd.Lock()
+	defer wd.Unlock()
+	kills := make(map[string]map[string]int64, len(wd.kills))
+	for model, reasons := range wd.kills {
+		kills[model] = maps.Clone(reasons)
 	}
+	return kills
 }
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Actions taken by the watchdog when a backend is busy or idle for too long
+const (
+	// WatchDogActionLog only logs the breach
+	WatchDogActionLog = "log"
+	// WatchDogActionWebhook posts a WatchDogEvent to the webhook of the watchdog
+	WatchDogActionWebhook = "webhook"
+	// WatchDogActionRestart stops the backend and loads the model again
+	WatchDogActionRestart = "restart"
+	// WatchDogActionKill stops the backend, the model is loaded again on its next request
+	WatchDogActionKill = "kill"
+)
+
+// Events of the watchdog
+const (
+	WatchDogEventBusy = "busy"
+	WatchDogEventIdle = "idle"
+)
+
+// WatchDogEvent is the body posted to the webhook of the watchdog
+type WatchDogEvent struct {
+	Event   string `json:"event"`
+	Model   string `json:"model"`
+	Address string `json:"address"`
+	// Seconds is how long the backend has been busy or idle
+	Seconds float64   `json:"seconds"`
+	Actions []string  `json:"actions"`
+	Time    time.Time `json:"time"`
+}
+
+// ValidateWatchDogActions returns an error if one of the actions is unknown
+func ValidateWatchDogActions(actions []string) error {
+	for _, a := range actions {
+		switch a {
+		case WatchDogActionLog, WatchDogActionWebhook, WatchDogActionRestart, WatchDogActionKill:
+		default:
+			return fmt.Errorf("invalid watchdog action %q: expected one of %s, %s, %s or %s",
+				a, WatchDogActionLog, WatchDogActionWebhook, WatchDogActionRestart, WatchDogActionKill)
+		}
+	}
+	return nil
+}
+
+// SetDefaultActions sets the actions taken when the backends are busy or idle for too long. Nil keeps the current ones
+func (wd *WatchDog) SetDefaultActions(busy, idle []string) {
+	wd.Lock()
+	defer wd.Unlock()
+	if busy != nil {
+		wd.busyActions = busy
+	}
+	if idle != nil {
+		wd.idleActions = idle
+	}
+}
+
+// SetActions overrides the actions of the watchdog for the model. Nil restores the default actions
+func (wd *WatchDog) SetActions(model string, busy, idle []string) {
+	wd.Lock()
+	defer wd.Unlock()
+	if busy == nil {
+		delete(wd.modelBusyActions, model)
+	} else {
+		wd.modelBusyActions[model] = busy
+	}
+	if idle == nil {
+		delete(wd.modelIdleActions, model)
+	} else {
+		wd.modelIdleActions[model] = idle
+	}
+}
+
+// SetWebhook sets the URL where the events of the webhook action are posted
+func (wd *WatchDog) SetWebhook(url string) {
+	wd.Lock()
+	defer wd.Unlock()
+	wd.webhookURL = url
+}
+
+// SetRestart sets the function loading the models again after the restart action stopped them
+func (wd *WatchDog) SetRestart(restart func(model string)) {
+	wd.Lock()
+	defer wd.Unlock()
+	wd.restart = restart
+}
+
+// actions returns the actions for the model, or the default ones. Called with the lock held
+func (wd *WatchDog) actions(model string, defaults []string, overrides map[string][]string) []string {
+	if actions, ok := overrides[model]; ok {
+		return actions
+	}
+	return defaults
+}
+
+// breach takes the actions for a backend busy or idle since the time, and returns true if it was stopped.
+// The breaches of the backends which are not stopped are reported once. Called with the lock held
+func (wd *WatchDog) breach(event, address, model string, since time.Time, actions []string) bool {
+	key := event + ":" + address
+	stop := slices.Contains(actions, WatchDogActionKill) || slices.Contains(actions, WatchDogActionRestart)
+	if !stop && wd.notified[key].Equal(since) {
+		return false
+	}
+	wd.notified[key] = since
+
+	log.Warn().Str("model", model).Strs("actions", actions).Msgf("[WatchDog] Model %s is %s for too long (%s)", model, event, time.Since(since).Round(time.Second))
+	if slices.Contains(actions, WatchDogActionWebhook) {
+		wd.notify(WatchDogEvent{
+			Event:   event,
+			Model:   model,
+			Address: address,
+			Seconds: time.Since(since).Seconds(),
+			Actions: actions,
+			Time:    time.Now(),
+		})
+	}
+	if !stop {
+		return false
+	}
+
+	if err := wd.pm.ShutdownModel(model); err != nil {
+		log.Error().Err(err).Str("model", model).Msg("[watchdog] error shutting down model")
+	}
+	log.Debug().Msgf("[WatchDog] model shut down: %s", address)
+	delete(wd.addressModelMap, address)
+	delete(wd.addressMap, address)
+	delete(wd.idleTimeouts, model)
+	delete(wd.notified, WatchDogEventBusy+":"+address)
+	delete(wd.notified, WatchDogEventIdle+":"+address)
+
+	if slices.Contains(actions, WatchDogActionRestart) {
+		if wd.restart == nil {
+			log.Warn().Str("model", model).Msg("[WatchDog] No restart configured, the model is loaded again on its next request")
+		} else {
+			go wd.restart(model)
+		}
+	}
+	return true
+}
+
+// notify posts the event to the webhook, without blocking the watchdog
+func (wd *WatchDog) notify(event WatchDogEvent) {
+	if wd.webhookURL == "" {
+		log.Warn().Str("model", event.Model).Msg("[WatchDog] No webhook configured")
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Msg("[WatchDog] failed to encode the event")
+		return
+	}
+	go func(url string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			log.Error().Err(err).Msg("[WatchDog] failed to create the webhook request")
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Error().Err(err).Str("url", url).Msg("[WatchDog] failed to call the webhook")
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Error().Str("url", url).Int("status", resp.StatusCode).Msg("[WatchDog] the webhook failed")
+		}
+	}(wd.webhookURL)
+}
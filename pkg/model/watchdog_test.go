@@ -0,0 +1,221 @@
+package model
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeProcessManager struct {
+	stopped []string
+}
+
+func (pm *fakeProcessManager) ShutdownModel(modelName string) error {
+	pm.stopped = append(pm.stopped, modelName)
+	return nil
+}
+
+var _ = Describe("WatchDog idle timeouts", func() {
+	var pm *fakeProcessManager
+
+	// idle registers a backend idle for the duration
+	idle := func(wd *WatchDog, address, model string, since time.Duration) {
+		wd.AddAddressModelMap(address, model)
+		wd.idleTime[address] = time.Now().Add(-since)
+	}
+
+	BeforeEach(func() {
+		pm = &fakeProcessManager{}
+	})
+
+	It("stops the models idle for longer than the idle timeout", func() {
+		wd := NewWatchDog(pm, time.Minute, 10*time.Minute, false, true)
+		idle(wd, "a", "model-a", 11*time.Minute)
+		idle(wd, "b", "model-b", time.Minute)
+
+		wd.checkIdle()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+	})
+
+	It("does not stop the models without the idle check", func() {
+		wd := NewWatchDog(pm, time.Minute, 10*time.Minute, false, false)
+		idle(wd, "a", "model-a", time.Hour)
+
+		wd.checkIdle()
+		Expect(pm.stopped).To(BeEmpty())
+	})
+
+	It("stops the models with an idle timeout when the idle check is disabled", func() {
+		wd := NewWatchDog(pm, time.Minute, 10*time.Minute, false, false)
+		idle(wd, "a", "model-a", 2*time.Minute)
+		wd.SetIdleTimeout("model-a", time.Minute)
+
+		wd.checkIdle()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+	})
+
+	It("overrides the idle timeout of the watchdog", func() {
+		wd := NewWatchDog(pm, time.Minute, time.Minute, false, true)
+		idle(wd, "a", "model-a", 2*time.Minute)
+		idle(wd, "b", "model-b", 2*time.Minute)
+		wd.SetIdleTimeout("model-a", time.Hour)
+		wd.SetIdleTimeout("model-b", -1)
+
+		wd.checkIdle()
+		Expect(pm.stopped).To(BeEmpty())
+
+		wd.ResetIdleTimeout("model-a")
+		wd.checkIdle()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+	})
+
+	It("stops the models with a zero idle timeout as soon as they are idle", func() {
+		wd := NewWatchDog(pm, time.Minute, time.Hour, false, true)
+		idle(wd, "a", "model-a", time.Second)
+		wd.SetIdleTimeout("model-a", 0)
+
+		wd.checkIdle()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+	})
+})
+
+var _ = Describe("WatchDog actions", func() {
+	var pm *fakeProcessManager
+
+	// busy registers a backend busy for the duration
+	busy := func(wd *WatchDog, address, model string, since time.Duration) {
+		wd.AddAddressModelMap(address, model)
+		wd.timetable[address] = time.Now().Add(-since)
+	}
+
+	BeforeEach(func() {
+		pm = &fakeProcessManager{}
+	})
+
+	It("kills the busy models by default", func() {
+		wd := NewWatchDog(pm, time.Minute, time.Minute, true, false)
+		busy(wd, "a", "model-a", 2*time.Minute)
+
+		wd.checkBusy()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+		Expect(wd.timetable).To(BeEmpty())
+	})
+
+	It("only logs the breaches with the log action", func() {
+		wd := NewWatchDog(pm, time.Minute, time.Minute, true, false)
+		wd.SetDefaultActions([]string{WatchDogActionLog}, nil)
+		busy(wd, "a", "model-a", 2*time.Minute)
+
+		wd.checkBusy()
+		wd.checkBusy()
+		Expect(pm.stopped).To(BeEmpty())
+		Expect(wd.timetable).To(HaveKey("a"))
+		Expect(wd.notified).To(HaveLen(1))
+	})
+
+	It("posts the events to the webhook once per breach", func() {
+		events := make(chan WatchDogEvent, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event WatchDogEvent
+			Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+			events <- event
+		}))
+		defer server.Close()
+
+		wd := NewWatchDog(pm, time.Minute, time.Minute, false, true)
+		wd.SetDefaultActions(nil, []string{WatchDogActionLog, WatchDogActionWebhook})
+		wd.SetWebhook(server.URL)
+		idle := func() {
+			wd.AddAddressModelMap("a", "model-a")
+			wd.idleTime["a"] = time.Now().Add(-2 * time.Minute)
+		}
+		idle()
+
+		wd.checkIdle()
+		wd.checkIdle()
+		var event WatchDogEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Event).To(Equal(WatchDogEventIdle))
+		Expect(event.Model).To(Equal("model-a"))
+		Expect(event.Address).To(Equal("a"))
+		Expect(event.Seconds).To(BeNumerically(">=", 120))
+		Expect(event.Actions).To(Equal([]string{WatchDogActionLog, WatchDogActionWebhook}))
+		Consistently(events, 200*time.Millisecond).ShouldNot(Receive())
+		Expect(pm.stopped).To(BeEmpty())
+
+		// A new idle period is a new breach
+		idle()
+		wd.checkIdle()
+		Eventually(events).Should(Receive())
+	})
+
+	It("loads the models again with the restart action", func() {
+		restarted := make(chan string, 1)
+		wd := NewWatchDog(pm, time.Minute, time.Minute, true, false)
+		wd.SetDefaultActions([]string{WatchDogActionRestart}, nil)
+		wd.SetRestart(func(model string) {
+			restarted <- model
+		})
+		busy(wd, "a", "model-a", 2*time.Minute)
+
+		wd.checkBusy()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+		Eventually(restarted).Should(Receive(Equal("model-a")))
+	})
+
+	It("overrides the actions for a model", func() {
+		wd := NewWatchDog(pm, time.Minute, time.Minute, true, false)
+		busy(wd, "a", "model-a", 2*time.Minute)
+		busy(wd, "b", "model-b", 2*time.Minute)
+		wd.SetActions("model-a", []string{WatchDogActionLog}, nil)
+
+		wd.checkBusy()
+		Expect(pm.stopped).To(Equal([]string{"model-b"}))
+
+		wd.SetActions("model-a", nil, nil)
+		wd.checkBusy()
+		Expect(pm.stopped).To(Equal([]string{"model-b", "model-a"}))
+	})
+
+	It("always stops the idle models with a ttl", func() {
+		wd := NewWatchDog(pm, time.Minute, time.Minute, false, true)
+		wd.SetDefaultActions(nil, []string{WatchDogActionLog})
+		wd.AddAddressModelMap("a", "model-a")
+		wd.idleTime["a"] = time.Now().Add(-2 * time.Minute)
+		wd.SetIdleTimeout("model-a", time.Minute)
+
+		wd.checkIdle()
+		Expect(pm.stopped).To(Equal([]string{"model-a"}))
+	})
+
+	It("validates the actions", func() {
+		Expect(ValidateWatchDogActions([]string{WatchDogActionLog, WatchDogActionWebhook, WatchDogActionRestart, WatchDogActionKill})).To(Succeed())
+		Expect(ValidateWatchDogActions([]string{"reboot"})).To(MatchError(ContainSubstring(`invalid watchdog action "reboot"`)))
+	})
+})
+
+var _ = Describe("WatchDog kills", func() {
+	It("counts the backends stopped by model and by reason", func() {
+		pm := &fakeProcessManager{}
+		wd := NewWatchDog(pm, time.Minute, time.Minute, true, true)
+		wd.AddAddressModelMap("a", "model-a")
+		wd.timetable["a"] = time.Now().Add(-2 * time.Minute)
+		wd.checkBusy()
+
+		wd.AddAddressModelMap("b", "model-a")
+		wd.idleTime["b"] = time.Now().Add(-2 * time.Minute)
+		wd.AddAddressModelMap("c", "model-c")
+		wd.idleTime["c"] = time.Now().Add(-2 * time.Minute)
+		wd.SetIdleTimeout("model-c", time.Minute)
+		wd.checkIdle()
+
+		Expect(wd.Kills()).To(Equal(map[string]map[string]int64{
+			"model-a": {WatchDogEventBusy: 1, WatchDogEventIdle: 1},
+			"model-c": {WatchDogReasonTTL: 1},
+		}))
+	})
+})
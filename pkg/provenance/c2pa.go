@@ -0,0 +1,73 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// trainedAlgorithmicMedia is the IPTC digital source type of the media generated by AI models
+const trainedAlgorithmicMedia = "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia"
+
+// SignC2PA embeds a signed C2PA manifest in the image with c2patool (https://github.com/contentauth/c2patool).
+// The manifest is a c2patool manifest definition, with the signing key and certificate: the creation of
+// the image by an AI model, and the parameters of the generation, are added to its assertions
+func SignC2PA(tool, manifest, file string, p Parameters, software string) error {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return err
+	}
+	definition := map[string]any{}
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return fmt.Errorf("invalid C2PA manifest %s: %w", manifest, err)
+	}
+
+	// c2patool reads the key and the certificate relatively to the manifest, which is written elsewhere
+	for _, k := range []string{"private_key", "sign_cert"} {
+		if path, ok := definition[k].(string); ok && !filepath.IsAbs(path) {
+			definition[k] = filepath.Join(filepath.Dir(manifest), path)
+		}
+	}
+	if _, ok := definition["claim_generator"]; !ok && software != "" {
+		definition["claim_generator"] = software
+	}
+	assertions, _ := definition["assertions"].([]any)
+	definition["assertions"] = append(assertions,
+		map[string]any{
+			"label": "c2pa.actions",
+			"data": map[string]any{
+				"actions": []any{
+					map[string]any{
+						"action":            "c2pa.created",
+						"digitalSourceType": trainedAlgorithmicMedia,
+						"softwareAgent":     software,
+					},
+				},
+			},
+		},
+		map[string]any{
+			"label": "org.localai.generation",
+			"data":  p,
+		},
+	)
+
+	tmp, err := os.CreateTemp("", "c2pa-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(definition); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	signed := file + ".c2pa" + filepath.Ext(file)
+	defer os.Remove(signed)
+	if out, err := exec.Command(tool, file, "--manifest", tmp.Name(), "--output", signed, "--force").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed signing the image with %s: %w: %s", tool, err, out)
+	}
+	return os.Rename(signed, file)
+}
@@ -0,0 +1,65 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF tags of IFD0
+const (
+	exifImageDescription = 0x010e
+	exifSoftware         = 0x0131
+	exifTypeASCII        = 2
+)
+
+// EXIF returns a little-endian EXIF block with the description and the software of the image,
+// as embedded by the eXIf chunks of the PNG images
+func EXIF(description, software string) []byte {
+	type entry struct {
+		tag   uint16
+		value []byte
+	}
+	var entries []entry
+	// The tags of an IFD are sorted
+	if description != "" {
+		entries = append(entries, entry{exifImageDescription, append([]byte(description), 0)})
+	}
+	if software != "" {
+		entries = append(entries, entry{exifSoftware, append([]byte(software), 0)})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	le := binary.LittleEndian
+	// TIFF header, with IFD0 at offset 8
+	buf.WriteString("II")
+	binary.Write(buf, le, uint16(42))
+	binary.Write(buf, le, uint32(8))
+
+	// The values longer than 4 bytes follow the IFD: its entry count, its entries and the offset of the next IFD
+	offset := uint32(8 + 2 + 12*len(entries) + 4)
+	binary.Write(buf, le, uint16(len(entries)))
+	var values []byte
+	for _, e := range entries {
+		binary.Write(buf, le, e.tag)
+		binary.Write(buf, le, uint16(exifTypeASCII))
+		binary.Write(buf, le, uint32(len(e.value)))
+		if len(e.value) <= 4 {
+			v := make([]byte, 4)
+			copy(v, e.value)
+			buf.Write(v)
+			continue
+		}
+		binary.Write(buf, le, offset+uint32(len(values)))
+		values = append(values, e.value...)
+		// The values start on word boundaries
+		if len(values)%2 == 1 {
+			values = append(values, 0)
+		}
+	}
+	binary.Write(buf, le, uint32(0))
+	buf.Write(values)
+	return buf.Bytes()
+}
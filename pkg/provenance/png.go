@@ -0,0 +1,146 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// ErrNotPNG is returned for the files which are not PNG images
+var ErrNotPNG = errors.New("not a PNG image")
+
+type chunk struct {
+	typ  string
+	data []byte
+}
+
+func readChunks(data []byte) ([]chunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrNotPNG
+	}
+	var chunks []chunk
+	for rest := data[len(pngSignature):]; len(rest) > 0; {
+		if len(rest) < 12 {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		length := binary.BigEndian.Uint32(rest[:4])
+		if uint64(len(rest)) < 12+uint64(length) {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		chunks = append(chunks, chunk{typ: string(rest[4:8]), data: rest[8 : 8+length]})
+		rest = rest[12+length:]
+	}
+	return chunks, nil
+}
+
+func writeChunk(buf *bytes.Buffer, c chunk) {
+	binary.Write(buf, binary.BigEndian, uint32(len(c.data)))
+	buf.WriteString(c.typ)
+	buf.Write(c.data)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(c.typ))
+	crc.Write(c.data)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// textChunk returns a tEXt chunk when the text is Latin-1, as required by tEXt, and an uncompressed iTXt chunk otherwise
+func textChunk(keyword, text string) chunk {
+	latin1 := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r > 0xff {
+			data := append([]byte(keyword), 0, 0, 0, 0, 0)
+			return chunk{typ: "iTXt", data: append(data, text...)}
+		}
+		latin1 = append(latin1, byte(r))
+	}
+	return chunk{typ: "tEXt", data: append(append([]byte(keyword), 0), latin1...)}
+}
+
+// AddPNGMetadata adds the text, as tEXt or iTXt chunks, and the EXIF data, as an eXIf chunk, to the PNG image.
+// The chunks are written before the image data, so that they are read without decoding the image
+func AddPNGMetadata(file string, text map[string]string, exif []byte) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	chunks, err := readChunks(data)
+	if err != nil {
+		return err
+	}
+
+	keywords := make([]string, 0, len(text))
+	for k := range text {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	var metadata []chunk
+	for _, k := range keywords {
+		metadata = append(metadata, textChunk(k, text[k]))
+	}
+	if len(exif) > 0 {
+		metadata = append(metadata, chunk{typ: "eXIf", data: exif})
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+1024))
+	buf.Write(pngSignature)
+	written := false
+	for _, c := range chunks {
+		if !written && (c.typ == "IDAT" || c.typ == "IEND") {
+			for _, m := range metadata {
+				writeChunk(buf, m)
+			}
+			written = true
+		}
+		writeChunk(buf, c)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), info.Mode())
+}
+
+// ReadPNGText returns the text of the tEXt and uncompressed iTXt chunks of the PNG image
+func ReadPNGText(file string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := readChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	text := map[string]string{}
+	for _, c := range chunks {
+		keyword, value, found := bytes.Cut(c.data, []byte{0})
+		if !found {
+			continue
+		}
+		switch c.typ {
+		case "tEXt":
+			runes := make([]rune, len(value))
+			for i, b := range value {
+				runes[i] = rune(b)
+			}
+			text[string(keyword)] = string(runes)
+		case "iTXt":
+			// Compression flag and method, then the language tag and the translated keyword
+			if len(value) < 2 || value[0] != 0 {
+				continue
+			}
+			parts := bytes.SplitN(value[2:], []byte{0}, 3)
+			if len(parts) == 3 {
+				text[string(keyword)] = string(parts[2])
+			}
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,116 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parameters are the parameters an image was generated with, to audit and reproduce it
+type Parameters struct {
+	Prompt         string  `json:"prompt"`
+	NegativePrompt string  `json:"negative_prompt,omitempty"`
+	Steps          int     `json:"steps,omitempty"`
+	Scheduler      string  `json:"scheduler,omitempty"`
+	CFGScale       float32 `json:"cfg_scale,omitempty"`
+	Seed           int     `json:"seed"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	Model          string  `json:"model"`
+	ModelHash      string  `json:"model_hash,omitempty"`
+	Backend        string  `json:"backend,omitempty"`
+}
+
+// String returns the parameters as written by the Stable Diffusion web UIs, so that the tools reading them
+// recognize them: the prompt, the negative prompt and a line of comma separated settings
+func (p Parameters) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(p.Prompt)
+	if p.NegativePrompt != "" {
+		fmt.Fprintf(sb, "\nNegative prompt: %s", p.NegativePrompt)
+	}
+
+	settings := []string{}
+	if p.Steps != 0 {
+		settings = append(settings, fmt.Sprintf("Steps: %d", p.Steps))
+	}
+	if p.Scheduler != "" {
+		settings = append(settings, "Sampler: "+p.Scheduler)
+	}
+	if p.CFGScale != 0 {
+		settings = append(settings, fmt.Sprintf("CFG scale: %g", p.CFGScale))
+	}
+	settings = append(settings, fmt.Sprintf("Seed: %d", p.Seed), fmt.Sprintf("Size: %dx%d", p.Width, p.Height))
+	if p.ModelHash != "" {
+		settings = append(settings, "Model hash: "+p.ModelHash)
+	}
+	settings = append(settings, "Model: "+p.Model)
+	if p.Backend != "" {
+		settings = append(settings, "Backend: "+p.Backend)
+	}
+	fmt.Fprintf(sb, "\n%s", strings.Join(settings, ", "))
+	return sb.String()
+}
+
+// Embed writes the parameters, and the software which generated the image, in the metadata of the PNG image:
+// as the "parameters" and "Software" text, and as the description and the software of its EXIF data.
+// The other files, as the videos, are left untouched
+func Embed(file string, p Parameters, software string) error {
+	text := map[string]string{"parameters": p.String()}
+	if software != "" {
+		text["Software"] = software
+	}
+	err := AddPNGMetadata(file, text, EXIF(p.String(), software))
+	if errors.Is(err, ErrNotPNG) {
+		return nil
+	}
+	return err
+}
+
+type hashKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+var hashes sync.Map
+
+// ModelHash returns the short SHA256 of the model file, as shown by the Stable Diffusion web UIs. The hashes
+// are cached until the file changes. It is empty for the models which are not a single file, as directories
+// or the models downloaded by the backends
+func ModelHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if info.IsDir() {
+		return "", nil
+	}
+
+	key := hashKey{path: path, size: info.Size(), modTime: info.ModTime()}
+	if hash, ok := hashes.Load(key); ok {
+		return hash.(string), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))[:10]
+	hashes.Store(key, hash)
+	return hash, nil
+}
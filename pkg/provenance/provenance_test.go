@@ -0,0 +1,127 @@
+package provenance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	. "github.com/mudler/LocalAI/pkg/provenance"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provenance", func() {
+	var dir, file string
+	params := Parameters{
+		Prompt:         "a cute baby sea otter",
+		NegativePrompt: "blurry",
+		Steps:          15,
+		Scheduler:      "k_dpmpp_2m",
+		CFGScale:       7.5,
+		Seed:           42,
+		Width:          64,
+		Height:         32,
+		Model:          "dreamshaper",
+		ModelHash:      "0123456789",
+		Backend:        "diffusers",
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		file = filepath.Join(dir, "image.png")
+		buf := &bytes.Buffer{}
+		Expect(png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 64, 32)))).To(Succeed())
+		Expect(os.WriteFile(file, buf.Bytes(), 0644)).To(Succeed())
+	})
+
+	It("writes the parameters as the Stable Diffusion web UIs", func() {
+		Expect(params.String()).To(Equal("a cute baby sea otter\nNegative prompt: blurry\n" +
+			"Steps: 15, Sampler: k_dpmpp_2m, CFG scale: 7.5, Seed: 42, Size: 64x32, Model hash: 0123456789, Model: dreamshaper, Backend: diffusers"))
+	})
+
+	It("embeds the parameters in the PNG images", func() {
+		Expect(Embed(file, params, "LocalAI v2.20.0")).To(Succeed())
+
+		text, err := ReadPNGText(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(text).To(HaveKeyWithValue("parameters", params.String()))
+		Expect(text).To(HaveKeyWithValue("Software", "LocalAI v2.20.0"))
+
+		// The image is still valid
+		f, err := os.Open(file)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		img, err := png.Decode(f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(img.Bounds().Dx()).To(Equal(64))
+	})
+
+	It("embeds the text which is not Latin-1", func() {
+		p := params
+		p.Prompt = "un chat, 猫"
+		Expect(Embed(file, p, "")).To(Succeed())
+		text, err := ReadPNGText(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(text).To(HaveKeyWithValue("parameters", p.String()))
+	})
+
+	It("leaves the other files untouched", func() {
+		video := filepath.Join(dir, "video.mp4")
+		Expect(os.WriteFile(video, []byte("not an image"), 0644)).To(Succeed())
+		Expect(Embed(video, params, "LocalAI")).To(Succeed())
+		data, err := os.ReadFile(video)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("not an image"))
+	})
+
+	It("writes the EXIF data", func() {
+		exif := EXIF("a prompt", "LocalAI")
+		Expect(exif[:8]).To(Equal([]byte{'I', 'I', 42, 0, 8, 0, 0, 0}))
+		Expect(exif).To(ContainSubstring("a prompt\x00"))
+		Expect(exif).To(ContainSubstring("LocalAI\x00"))
+		Expect(EXIF("", "")).To(BeNil())
+	})
+
+	It("hashes the model files", func() {
+		model := filepath.Join(dir, "model.safetensors")
+		Expect(os.WriteFile(model, []byte("weights"), 0644)).To(Succeed())
+		hash, err := ModelHash(model)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).To(Equal("9a129038d9"))
+
+		// The models which are not a file have no hash
+		hash, err = ModelHash(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).To(BeEmpty())
+		hash, err = ModelHash(filepath.Join(dir, "org/repo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).To(BeEmpty())
+	})
+
+	It("signs the images with c2patool", func() {
+		// The tool copies the image and keeps the manifest it was given
+		tool := filepath.Join(dir, "c2patool")
+		Expect(os.WriteFile(tool, []byte("#!/bin/sh\ncp \"$3\" \""+dir+"/definition.json\"\ncp \"$1\" \"$5\"\necho signed >> \"$5\"\n"), 0755)).To(Succeed())
+		manifest := filepath.Join(dir, "manifest.json")
+		Expect(os.WriteFile(manifest, []byte(`{"alg": "es256", "private_key": "es256_private.key", "sign_cert": "/certs/es256_certs.pem"}`), 0644)).To(Succeed())
+
+		Expect(SignC2PA(tool, manifest, file, params, "LocalAI")).To(Succeed())
+		data, err := os.ReadFile(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(HaveSuffix("signed\n"))
+
+		data, err = os.ReadFile(filepath.Join(dir, "definition.json"))
+		Expect(err).ToNot(HaveOccurred())
+		definition := map[string]any{}
+		Expect(json.Unmarshal(data, &definition)).To(Succeed())
+		Expect(definition).To(HaveKeyWithValue("private_key", filepath.Join(dir, "es256_private.key")))
+		Expect(definition).To(HaveKeyWithValue("sign_cert", "/certs/es256_certs.pem"))
+		Expect(definition).To(HaveKeyWithValue("claim_generator", "LocalAI"))
+		Expect(definition["assertions"]).To(HaveLen(2))
+		Expect(string(data)).To(ContainSubstring("trainedAlgorithmicMedia"))
+		Expect(string(data)).To(ContainSubstring(`"seed":42`))
+	})
+})
@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Consul registers the services in the catalog of a Consul agent, with an HTTP check of their health
+// endpoint. The services failing their check for the TTL are deregistered by Consul
+type Consul struct {
+	url, token string
+	ttl        time.Duration
+	client     *http.Client
+}
+
+type consulCheck struct {
+	HTTP                           string
+	Interval                       string
+	Timeout                        string
+	DeregisterCriticalServiceAfter string
+}
+
+type consulService struct {
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	Tags    []string
+	Check   *consulCheck `json:",omitempty"`
+}
+
+type consulEntry struct {
+	Service struct {
+		ID      string
+		Service string
+		Address string
+		Port    int
+		Tags    []string
+	}
+	Node struct {
+		Address string
+	}
+}
+
+func (c *Consul) Register(ctx context.Context, s Service) error {
+	service := consulService{
+		ID:      s.ID,
+		Name:    s.Name,
+		Address: s.Address,
+		Port:    s.Port,
+		Tags:    []string{},
+	}
+	// the gateways select the instances of a model by its tag
+	for _, m := range s.Models {
+		service.Tags = append(service.Tags, "model:"+m)
+	}
+	if s.HealthURL != "" {
+		service.Check = &consulCheck{
+			HTTP:                           s.HealthURL,
+			Interval:                       "10s",
+			Timeout:                        "5s",
+			DeregisterCriticalServiceAfter: c.ttl.String(),
+		}
+	}
+	body, err := json.Marshal(service)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(c.url, "/")+"/v1/agent/service/register", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, nil)
+}
+
+// Services returns the instances of the service passing their checks
+func (c *Consul) Services(ctx context.Context, name string) ([]Service, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.url, "/")+"/v1/health/service/"+url.PathEscape(name)+"?passing=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := []consulEntry{}
+	if err := c.do(req, &entries); err != nil {
+		return nil, err
+	}
+	services := []Service{}
+	for _, e := range entries {
+		s := Service{ID: e.Service.ID, Name: e.Service.Service, Address: e.Service.Address, Port: e.Service.Port, Models: []string{}}
+		// the services registered without address are reached at the address of their node
+		if s.Address == "" {
+			s.Address = e.Node.Address
+		}
+		for _, tag := range e.Service.Tags {
+			if model, found := strings.CutPrefix(tag, "model:"); found {
+				s.Models = append(s.Models, model)
+			}
+		}
+		services = append(services, s)
+	}
+	return services, nil
+}
+
+// do sends the request to the agent, decoding the response in out
+func (c *Consul) do(req *http.Request, out any) error {
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("consul %s returned status %d", c.url, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
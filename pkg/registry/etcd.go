@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Etcd writes the services as JSON to the keys <prefix>/<id> of etcd, through its HTTP gateway. The keys
+// are attached to a lease, renewed at each registration, so that they are deleted with it once the
+// instance is gone
+type Etcd struct {
+	url, prefix        string
+	username, password string
+	ttl                int64
+	client             *http.Client
+
+	mu    sync.Mutex
+	lease string
+}
+
+func newEtcd(o Options, client *http.Client) (*Etcd, error) {
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid etcd URL: %w", err)
+	}
+	e := &Etcd{prefix: o.Prefix, ttl: max(int64(o.TTL.Seconds()), 1), client: client}
+	if u.User != nil {
+		e.username = u.User.Username()
+		e.password, _ = u.User.Password()
+		u.User = nil
+	}
+	e.url = strings.TrimSuffix(u.String(), "/")
+	return e, nil
+}
+
+func (e *Etcd) Register(ctx context.Context, s Service) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	token, err := e.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the lease is granted again once it expired, as after the instance lost the connection for the TTL
+	if e.lease != "" {
+		var keepalive struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		if err := e.call(ctx, token, "/v3/lease/keepalive", map[string]string{"ID": e.lease}, &keepalive); err != nil || keepalive.Result.TTL == "" || keepalive.Result.TTL == "0" {
+			e.lease = ""
+		}
+	}
+	if e.lease == "" {
+		var grant struct {
+			ID string `json:"ID"`
+		}
+		if err := e.call(ctx, token, "/v3/lease/grant", map[string]int64{"TTL": e.ttl}, &grant); err != nil {
+			return err
+		}
+		e.lease = grant.ID
+	}
+
+	value, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return e.call(ctx, token, "/v3/kv/put", map[string]string{
+		"key":   base64.StdEncoding.EncodeToString([]byte(path.Join(e.prefix, s.ID))),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": e.lease,
+	}, nil)
+}
+
+// Services returns the instances of the service name written under the prefix. The keys of the instances
+// gone are deleted with their lease
+func (e *Etcd) Services(ctx context.Context, name string) ([]Service, error) {
+	token, err := e.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the range of the prefix ends at the prefix with its last byte incremented, as with etcdctl --prefix
+	prefix := strings.TrimSuffix(e.prefix, "/") + "/"
+	end := []byte(prefix)
+	end[len(end)-1]++
+	var resp struct {
+		KVs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := e.call(ctx, token, "/v3/kv/range", map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(end),
+	}, &resp); err != nil {
+		return nil, err
+	}
+	services := []Service{}
+	for _, kv := range resp.KVs {
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		s := Service{}
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, err
+		}
+		if s.Name == name {
+			services = append(services, s)
+		}
+	}
+	return services, nil
+}
+
+// authenticate returns the token of the credentials of the URL, if any
+func (e *Etcd) authenticate(ctx context.Context) (string, error) {
+	if e.username == "" {
+		return "", nil
+	}
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := e.call(ctx, "", "/v3/auth/authenticate", map[string]string{"name": e.username, "password": e.password}, &auth); err != nil {
+		return "", err
+	}
+	return auth.Token, nil
+}
+
+// call posts the request to the endpoint of the gateway, decoding the response in out
+func (e *Etcd) call(ctx context.Context, token, endpoint string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("etcd %s%s returned status %d", e.url, endpoint, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
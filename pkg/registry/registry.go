@@ -0,0 +1,77 @@
+// Package registry registers the instance in a service registry, as Consul or etcd, so that the gateways
+// can discover the instances of a fleet and the models they serve.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Service is the instance registered
+type Service struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	// Models are the models served by the instance
+	Models []string `json:"models"`
+	// HealthURL is checked by the registries checking the health of the services
+	HealthURL string `json:"health_url"`
+}
+
+// Registry registers the services, and lists the ones registered. The registrations expire when they are
+// not renewed, as the instance may stop without deregistering
+type Registry interface {
+	Register(ctx context.Context, s Service) error
+	// Services returns the healthy instances of the service name
+	Services(ctx context.Context, name string) ([]Service, error)
+}
+
+// Options configure the registries
+type Options struct {
+	// URL of the registry, as http://consul:8500 or http://etcd:2379. The credentials of etcd are set in the URL
+	URL string
+	// Token is the ACL token of Consul
+	Token string
+	// Prefix of the keys of etcd, followed by the ID of the service
+	Prefix string
+	// TTL is the time a registration is kept once it is not renewed
+	TTL time.Duration
+}
+
+// New returns the registry of the kind, consul or etcd
+func New(kind string, o Options) (Registry, error) {
+	if o.URL == "" {
+		return nil, fmt.Errorf("the URL of the %s registry is required", kind)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch kind {
+	case "consul":
+		return &Consul{url: o.URL, token: o.Token, ttl: o.TTL, client: client}, nil
+	case "etcd":
+		return newEtcd(o, client)
+	default:
+		return nil, fmt.Errorf("unknown registry %q: expected consul or etcd", kind)
+	}
+}
+
+// Run registers the service returned by service every interval, until ctx is done, so that the
+// registration follows the models served
+func Run(ctx context.Context, r Registry, interval time.Duration, service func() Service) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Register(ctx, service()); err != nil {
+			log.Error().Err(err).Msg("error registering the instance")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
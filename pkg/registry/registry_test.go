@@ -0,0 +1,166 @@
+package registry_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/mudler/LocalAI/pkg/registry"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var service = Service{
+	ID:        "localai-node1-8080",
+	Name:      "localai",
+	Address:   "node1",
+	Port:      8080,
+	Models:    []string{"llama-3.2-1b-instruct", "whisper-1"},
+	HealthURL: "http://node1:8080/readyz",
+}
+
+// recorder records the requests received by a fake registry, and answers them with the responses of the paths
+type recorder struct {
+	sync.Mutex
+	requests  []string
+	bodies    map[string]map[string]any
+	headers   http.Header
+	responses map[string]string
+}
+
+func newRecorder(responses map[string]string) (*recorder, *httptest.Server) {
+	r := &recorder{bodies: map[string]map[string]any{}, responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Lock()
+		defer r.Unlock()
+		body := map[string]any{}
+		json.NewDecoder(req.Body).Decode(&body)
+		r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+		r.bodies[req.URL.Path] = body
+		r.headers = req.Header.Clone()
+		w.Write([]byte(r.responses[req.URL.Path]))
+	}))
+	return r, server
+}
+
+var _ = Describe("Registry", func() {
+	It("registers the service in the Consul agent with a health check", func() {
+		r, server := newRecorder(nil)
+		defer server.Close()
+
+		consul, err := New("consul", Options{URL: server.URL, Token: "acl", TTL: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(consul.Register(context.Background(), service)).To(Succeed())
+
+		Expect(r.requests).To(Equal([]string{"PUT /v1/agent/service/register"}))
+		Expect(r.headers.Get("X-Consul-Token")).To(Equal("acl"))
+		body := r.bodies["/v1/agent/service/register"]
+		Expect(body).To(HaveKeyWithValue("ID", "localai-node1-8080"))
+		Expect(body).To(HaveKeyWithValue("Port", BeNumerically("==", 8080)))
+		Expect(body["Tags"]).To(ConsistOf("model:llama-3.2-1b-instruct", "model:whisper-1"))
+		Expect(body["Check"]).To(HaveKeyWithValue("HTTP", "http://node1:8080/readyz"))
+		Expect(body["Check"]).To(HaveKeyWithValue("DeregisterCriticalServiceAfter", "1m0s"))
+	})
+
+	It("writes the service to etcd with a lease renewed at each registration", func() {
+		r, server := newRecorder(map[string]string{
+			"/v3/lease/grant":       `{"ID":"42","TTL":"60"}`,
+			"/v3/lease/keepalive":   `{"result":{"ID":"42","TTL":"60"}}`,
+			"/v3/auth/authenticate": `{"token":"etcd-token"}`,
+		})
+		defer server.Close()
+
+		etcd, err := New("etcd", Options{URL: "http://root:secret@" + server.Listener.Addr().String(), Prefix: "/localai/services", TTL: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(etcd.Register(context.Background(), service)).To(Succeed())
+		Expect(etcd.Register(context.Background(), service)).To(Succeed())
+
+		Expect(r.requests).To(Equal([]string{
+			"POST /v3/auth/authenticate", "POST /v3/lease/grant", "POST /v3/kv/put",
+			"POST /v3/auth/authenticate", "POST /v3/lease/keepalive", "POST /v3/kv/put",
+		}))
+		Expect(r.headers.Get("Authorization")).To(Equal("etcd-token"))
+		Expect(r.bodies["/v3/auth/authenticate"]).To(HaveKeyWithValue("password", "secret"))
+		Expect(r.bodies["/v3/lease/grant"]).To(HaveKeyWithValue("TTL", BeNumerically("==", 60)))
+
+		put := r.bodies["/v3/kv/put"]
+		Expect(put).To(HaveKeyWithValue("lease", "42"))
+		key, _ := base64.StdEncoding.DecodeString(put["key"].(string))
+		Expect(string(key)).To(Equal("/localai/services/localai-node1-8080"))
+		value, _ := base64.StdEncoding.DecodeString(put["value"].(string))
+		registered := Service{}
+		Expect(json.Unmarshal(value, &registered)).To(Succeed())
+		Expect(registered).To(Equal(service))
+	})
+
+	It("grants a new lease once it expired", func() {
+		r, server := newRecorder(map[string]string{
+			"/v3/lease/grant":     `{"ID":"42","TTL":"60"}`,
+			"/v3/lease/keepalive": `{"result":{"ID":"42"}}`,
+		})
+		defer server.Close()
+
+		etcd, err := New("etcd", Options{URL: server.URL, Prefix: "/localai/services", TTL: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(etcd.Register(context.Background(), service)).To(Succeed())
+		Expect(etcd.Register(context.Background(), service)).To(Succeed())
+		Expect(r.requests).To(Equal([]string{
+			"POST /v3/lease/grant", "POST /v3/kv/put",
+			"POST /v3/lease/keepalive", "POST /v3/lease/grant", "POST /v3/kv/put",
+		}))
+	})
+
+	It("lists the healthy instances of Consul", func() {
+		r, server := newRecorder(map[string]string{
+			"/v1/health/service/localai": `[
+				{"Node": {"Address": "10.0.0.1"}, "Service": {"ID": "localai-node1-8080", "Service": "localai", "Address": "node1", "Port": 8080, "Tags": ["model:whisper-1", "gpu"]}},
+				{"Node": {"Address": "10.0.0.2"}, "Service": {"ID": "localai-node2-8080", "Service": "localai", "Port": 8080}}
+			]`,
+		})
+		defer server.Close()
+
+		consul, err := New("consul", Options{URL: server.URL, TTL: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		services, err := consul.Services(context.Background(), "localai")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.requests).To(Equal([]string{"GET /v1/health/service/localai"}))
+		Expect(services).To(Equal([]Service{
+			{ID: "localai-node1-8080", Name: "localai", Address: "node1", Port: 8080, Models: []string{"whisper-1"}},
+			{ID: "localai-node2-8080", Name: "localai", Address: "10.0.0.2", Port: 8080, Models: []string{}},
+		}))
+	})
+
+	It("lists the instances written to etcd", func() {
+		value, err := json.Marshal(service)
+		Expect(err).ToNot(HaveOccurred())
+		other, err := json.Marshal(Service{ID: "other", Name: "other"})
+		Expect(err).ToNot(HaveOccurred())
+		r, server := newRecorder(map[string]string{
+			"/v3/kv/range": `{"kvs": [{"value": "` + base64.StdEncoding.EncodeToString(value) + `"}, {"value": "` + base64.StdEncoding.EncodeToString(other) + `"}]}`,
+		})
+		defer server.Close()
+
+		etcd, err := New("etcd", Options{URL: server.URL, Prefix: "/localai/services", TTL: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		services, err := etcd.Services(context.Background(), "localai")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(services).To(Equal([]Service{service}))
+
+		key, _ := base64.StdEncoding.DecodeString(r.bodies["/v3/kv/range"]["key"].(string))
+		end, _ := base64.StdEncoding.DecodeString(r.bodies["/v3/kv/range"]["range_end"].(string))
+		Expect(string(key)).To(Equal("/localai/services/"))
+		Expect(string(end)).To(Equal("/localai/services0"))
+	})
+
+	It("rejects the unknown registries", func() {
+		_, err := New("zookeeper", Options{URL: "http://localhost:2181"})
+		Expect(err).To(HaveOccurred())
+		_, err = New("consul", Options{})
+		Expect(err).To(HaveOccurred())
+	})
+})
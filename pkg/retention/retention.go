@@ -0,0 +1,105 @@
+// Package retention bounds the growth of the directories where files are generated, deleting the files
+// older than a maximum age, and the oldest ones over a maximum size.
+package retention
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Policy is the retention of the files of a directory. The zero values disable the limits
+type Policy struct {
+	// MaxAge deletes the files modified for longer
+	MaxAge time.Duration
+	// MaxSize deletes the oldest files while the directory is larger, in bytes
+	MaxSize int64
+}
+
+func (p Policy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxSize > 0
+}
+
+// Result reports the files deleted by Clean
+type Result struct {
+	Files int
+	Bytes int64
+}
+
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Clean deletes the files of dir, and of its subdirectories, out of the policy at the time now. The files
+// for which keep returns true are neither deleted nor counted in the size of the directory
+func Clean(dir string, p Policy, now time.Time, keep func(path string) bool) (Result, error) {
+	result := Result{}
+	if !p.Enabled() {
+		return result, nil
+	}
+
+	files := []file{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the files can be deleted meanwhile
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if keep != nil && keep(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files = append(files, file{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	// the oldest files first
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+
+	var errs []error
+	for _, f := range files {
+		expired := p.MaxAge > 0 && now.Sub(f.modTime) > p.MaxAge
+		oversized := p.MaxSize > 0 && size > p.MaxSize
+		if !expired && !oversized {
+			// the next files are more recent, and the directory is under its size
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		size -= f.size
+		result.Files++
+		result.Bytes += f.size
+	}
+	return result, errors.Join(errs...)
+}
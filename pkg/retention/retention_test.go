@@ -0,0 +1,74 @@
+package retention_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/mudler/LocalAI/pkg/retention"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clean", func() {
+	var dir string
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0750)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(strings.Repeat("a", size)), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, now.Add(-age), now.Add(-age))).To(Succeed())
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		write("old.png", 10, 48*time.Hour)
+		write("sub/old.wav", 20, 30*time.Hour)
+		write("recent.png", 30, time.Hour)
+		write("new.wav", 40, time.Minute)
+		write("voices/alice.wav", 100, 100*time.Hour)
+	})
+
+	keepVoices := func(path string) bool { return path == filepath.Join(dir, "voices") }
+
+	It("deletes the files older than the maximum age", func() {
+		result, err := Clean(dir, Policy{MaxAge: 24 * time.Hour}, now, keepVoices)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(Result{Files: 2, Bytes: 30}))
+		Expect(exists("old.png")).To(BeFalse())
+		Expect(exists("sub/old.wav")).To(BeFalse())
+		Expect(exists("recent.png")).To(BeTrue())
+		Expect(exists("voices/alice.wav")).To(BeTrue())
+	})
+
+	It("deletes the oldest files over the maximum size", func() {
+		result, err := Clean(dir, Policy{MaxSize: 75}, now, keepVoices)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(Result{Files: 2, Bytes: 30}))
+		Expect(exists("recent.png")).To(BeTrue())
+		Expect(exists("new.wav")).To(BeTrue())
+		Expect(exists("voices/alice.wav")).To(BeTrue())
+
+		result, err = Clean(dir, Policy{MaxSize: 50}, now, keepVoices)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(Result{Files: 1, Bytes: 30}))
+		Expect(exists("new.wav")).To(BeTrue())
+	})
+
+	It("does nothing without limits, or without the directory", func() {
+		result, err := Clean(dir, Policy{}, now, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(Result{}))
+		Expect(exists("old.png")).To(BeTrue())
+
+		result, err = Clean(filepath.Join(dir, "missing"), Policy{MaxAge: time.Hour}, now, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(Result{}))
+	})
+})
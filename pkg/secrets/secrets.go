@@ -0,0 +1,129 @@
+// Package secrets resolves the sensitive settings, as the API keys and the tokens, from references
+// to files, environment variables or secret stores, and redacts their values from the logs.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values of the secrets in the output of Redact
+const Redacted = "[REDACTED]"
+
+// minRedactedLength is the length under which the values are not redacted, as they would redact
+// most of the logs
+const minRedactedLength = 4
+
+// Store is a secret store, as Vault or a KMS, resolving the references of its scheme
+type Store interface {
+	// Get returns the secret at the path of a reference, without the scheme
+	Get(ctx context.Context, path string) (string, error)
+}
+
+var (
+	mu     sync.RWMutex
+	stores = map[string]Store{
+		"vault": NewVaultStore(),
+	}
+	values   = map[string]struct{}{}
+	replacer = strings.NewReplacer()
+)
+
+// RegisterStore resolves the references with the scheme (e.g. kms://key) with the store
+func RegisterStore(scheme string, store Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	stores[scheme] = store
+}
+
+// Resolve returns the value of a secret: file:///run/secrets/key reads the file, env://NAME the
+// environment variable and <scheme>://path asks the store registered for the scheme, as
+// vault://secret/data/localai#api_key. The other values are the secret themselves.
+// The values are tracked, to be redacted by Redact.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	value := ref
+	if scheme, path, found := strings.Cut(ref, "://"); found {
+		switch scheme {
+		case "file":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("cannot read secret file: %w", err)
+			}
+			value = strings.TrimSpace(string(data))
+		case "env":
+			v, set := os.LookupEnv(path)
+			if !set {
+				return "", fmt.Errorf("secret environment variable %s is not set", path)
+			}
+			value = v
+		default:
+			mu.RLock()
+			store, exists := stores[scheme]
+			mu.RUnlock()
+			if exists {
+				v, err := store.Get(ctx, path)
+				if err != nil {
+					return "", fmt.Errorf("cannot read secret from %s: %w", scheme, err)
+				}
+				value = v
+			}
+		}
+	}
+	Track(value)
+	return value, nil
+}
+
+// ResolveAll resolves the references of a list of secrets, as the API keys
+func ResolveAll(ctx context.Context, refs []string) ([]string, error) {
+	resolved := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		value, err := Resolve(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, value)
+	}
+	return resolved, nil
+}
+
+// Track marks the values as secrets, to be redacted by Redact
+func Track(secrets ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, s := range secrets {
+		if len(s) < minRedactedLength {
+			continue
+		}
+		if _, exists := values[s]; !exists {
+			values[s] = struct{}{}
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// the longest values first, so that a secret containing another one is redacted entirely
+	sorted := make([]string, 0, len(values))
+	for v := range values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, v := range sorted {
+		pairs = append(pairs, v, Redacted)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces the values of the secrets in s
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return replacer.Replace(s)
+}
@@ -0,0 +1,91 @@
+package secrets_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/mudler/LocalAI/pkg/secrets"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type mapStore map[string]string
+
+func (m mapStore) Get(ctx context.Context, path string) (string, error) {
+	v, ok := m[path]
+	if !ok {
+		return "", fmt.Errorf("%s not found", path)
+	}
+	return v, nil
+}
+
+var _ = Describe("Secrets", func() {
+	ctx := context.Background()
+
+	It("returns the plain values as they are", func() {
+		Expect(Resolve(ctx, "sk-plain-key")).To(Equal("sk-plain-key"))
+		Expect(Resolve(ctx, "https://s3.example.com")).To(Equal("https://s3.example.com"))
+	})
+
+	It("reads the secrets from files", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "api_key")
+		Expect(os.WriteFile(file, []byte("sk-from-file\n"), 0600)).To(Succeed())
+		Expect(Resolve(ctx, "file://"+file)).To(Equal("sk-from-file"))
+
+		_, err := Resolve(ctx, "file:///missing/secret")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads the secrets from the environment", func() {
+		GinkgoT().Setenv("LOCALAI_TEST_SECRET", "hf_from_env")
+		Expect(Resolve(ctx, "env://LOCALAI_TEST_SECRET")).To(Equal("hf_from_env"))
+
+		_, err := Resolve(ctx, "env://LOCALAI_TEST_MISSING_SECRET")
+		Expect(err).To(MatchError(ContainSubstring("not set")))
+	})
+
+	It("reads the secrets from the registered stores", func() {
+		RegisterStore("test", mapStore{"localai/key": "sk-from-store"})
+		Expect(ResolveAll(ctx, []string{"test://localai/key", "sk-other"})).To(Equal([]string{"sk-from-store", "sk-other"}))
+
+		_, err := Resolve(ctx, "test://localai/missing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads the secrets from Vault", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/secret/data/localai" || r.Header.Get("X-Vault-Token") != "root" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"data":{"data":{"api_key":"sk-from-vault"},"metadata":{"version":1}}}`)
+		}))
+		defer server.Close()
+		GinkgoT().Setenv("VAULT_ADDR", server.URL)
+		GinkgoT().Setenv("VAULT_TOKEN", "root")
+
+		Expect(Resolve(ctx, "vault://secret/data/localai#api_key")).To(Equal("sk-from-vault"))
+		_, err := Resolve(ctx, "vault://secret/data/localai#token")
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+		_, err = Resolve(ctx, "vault://secret/data/other#api_key")
+		Expect(err).To(MatchError(ContainSubstring("403")))
+	})
+
+	It("redacts the values of the secrets", func() {
+		Expect(Resolve(ctx, "sk-redacted-key")).To(Equal("sk-redacted-key"))
+		Track("abc")
+		Expect(Redact("authorized sk-redacted-key for abc")).To(Equal("authorized " + Redacted + " for abc"))
+
+		var out bytes.Buffer
+		w := NewRedactWriter(&out)
+		n, err := w.Write([]byte("key=sk-redacted-key\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(20))
+		Expect(out.String()).To(Equal("key=" + Redacted + "\n"))
+	})
+})
@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VaultStore reads the secrets of the KV engines of HashiCorp Vault, at VAULT_ADDR with VAULT_TOKEN.
+// The references are the path of the secret and the key of the value: vault://secret/data/localai#api_key
+type VaultStore struct {
+	Client *http.Client
+}
+
+func NewVaultStore() *VaultStore {
+	return &VaultStore{Client: http.DefaultClient}
+}
+
+func (v *VaultStore) Get(ctx context.Context, path string) (string, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	secretPath, key, found := strings.Cut(path, "#")
+	if !found || key == "" {
+		return "", fmt.Errorf("missing key in %q, expected path#key", path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(secretPath, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d reading %s", resp.StatusCode, secretPath)
+	}
+
+	// the KV version 2 engine nests the values in data.data, the version 1 in data
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %s not found in %s", key, secretPath)
+	}
+	return value, nil
+}
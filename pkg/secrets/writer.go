@@ -0,0 +1,20 @@
+package secrets
+
+import "io"
+
+type redactWriter struct {
+	w io.Writer
+}
+
+// NewRedactWriter returns a writer redacting the values of the secrets before writing to w, for the
+// logs. Each write is redacted on its own, as the log lines written at once.
+func NewRedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
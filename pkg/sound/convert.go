@@ -0,0 +1,192 @@
+package sound
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// Formats supported as output of the TTS backends, mapped to their file extension.
+// wav and pcm16 are converted in Go, the compressed formats require ffmpeg.
+var Formats = map[string]string{
+	"wav":   ".wav",
+	"pcm16": ".pcm",
+	"mp3":   ".mp3",
+	"opus":  ".opus",
+	"flac":  ".flac",
+}
+
+var contentTypes = map[string]string{
+	"wav":   "audio/wav",
+	"pcm16": "audio/pcm",
+	"mp3":   "audio/mpeg",
+	"opus":  "audio/ogg",
+	"flac":  "audio/flac",
+}
+
+// ffmpegArgs are the ffmpeg output options of the compressed formats
+var ffmpegArgs = map[string][]string{
+	"mp3":  {"-f", "mp3"},
+	"opus": {"-c:a", "libopus", "-f", "ogg"},
+	"flac": {"-f", "flac"},
+}
+
+// Convert transcodes the wav file at src to the given format, resampling it if sampleRate
+// is not zero. The converted file is written next to src, and its path returned.
+func Convert(src, format string, sampleRate int) (string, error) {
+	format = normalizeFormat(format)
+	ext, ok := Formats[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported audio format %q", format)
+	}
+	if sampleRate < 0 {
+		return "", fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if format == "wav" && sampleRate == 0 {
+		return src, nil
+	}
+
+	dst := strings.TrimSuffix(src, filepath.Ext(src))
+	if sampleRate != 0 {
+		dst += "_" + strconv.Itoa(sampleRate)
+	}
+	dst += ext
+	if args, ok := ffmpegArgs[format]; ok {
+		return dst, ffmpegConvert(src, dst, sampleRate, args)
+	}
+
+	buf, err := readWav(src)
+	if err != nil {
+		return "", err
+	}
+	if sampleRate != 0 {
+		buf = Resample(buf, sampleRate)
+	}
+
+	if format == "pcm16" {
+		return dst, writePCM16(dst, buf)
+	}
+	return dst, writeWav(dst, buf)
+}
+
+// ContentType returns the MIME type of the audio format
+func ContentType(format string) string {
+	if ct, ok := contentTypes[normalizeFormat(format)]; ok {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+func normalizeFormat(format string) string {
+	switch format {
+	case "":
+		return "wav"
+	case "pcm":
+		// pcm is the name used by the OpenAI API
+		return "pcm16"
+	}
+	return format
+}
+
+func ffmpegConvert(src, dst string, sampleRate int, args []string) error {
+	commandArgs := []string{"-y", "-i", src}
+	if sampleRate != 0 {
+		commandArgs = append(commandArgs, "-ar", strconv.Itoa(sampleRate))
+	}
+	commandArgs = append(commandArgs, args...)
+	commandArgs = append(commandArgs, dst)
+
+	cmd := exec.Command("ffmpeg", commandArgs...) // Constrain this to ffmpeg to permit security scanner to see that the command is safe.
+	cmd.Env = os.Environ()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error: %w out: %s", err, out)
+	}
+	return nil
+}
+
+func readWav(src string) (*audio.IntBuffer, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := wav.NewDecoder(f)
+	if !d.IsValidFile() {
+		return nil, fmt.Errorf("%s is not a valid wav file", src)
+	}
+	return d.FullPCMBuffer()
+}
+
+func writeWav(dst string, buf *audio.IntBuffer) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := wav.NewEncoder(f, buf.Format.SampleRate, buf.SourceBitDepth, buf.Format.NumChannels, 1)
+	if err := enc.Write(buf); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writePCM16 writes the samples as raw signed 16-bit little-endian PCM
+func writePCM16(dst string, buf *audio.IntBuffer) error {
+	shift := buf.SourceBitDepth - 16
+	data := make([]byte, 2*len(buf.Data))
+	for i, s := range buf.Data {
+		switch {
+		case buf.SourceBitDepth == 8:
+			// 8-bit wav samples are unsigned
+			s = (s - 128) << 8
+		case shift > 0:
+			s >>= shift
+		case shift < 0:
+			s <<= -shift
+		}
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(s)))
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// Resample changes the sample rate of the buffer with a linear interpolation
+func Resample(buf *audio.IntBuffer, sampleRate int) *audio.IntBuffer {
+	channels := buf.Format.NumChannels
+	if channels == 0 || buf.Format.SampleRate == sampleRate {
+		return buf
+	}
+
+	frames := len(buf.Data) / channels
+	outFrames := int(int64(frames) * int64(sampleRate) / int64(buf.Format.SampleRate))
+	ratio := float64(buf.Format.SampleRate) / float64(sampleRate)
+
+	data := make([]int, outFrames*channels)
+	for i := 0; i < outFrames; i++ {
+		pos := float64(i) * ratio
+		j := int(pos)
+		frac := pos - float64(j)
+		for c := 0; c < channels; c++ {
+			a := buf.Data[j*channels+c]
+			b := a
+			if j+1 < frames {
+				b = buf.Data[(j+1)*channels+c]
+			}
+			data[i*channels+c] = a + int(frac*float64(b-a))
+		}
+	}
+
+	return &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: channels, SampleRate: sampleRate},
+		Data:           data,
+		SourceBitDepth: buf.SourceBitDepth,
+	}
+}
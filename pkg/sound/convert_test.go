@@ -0,0 +1,72 @@
+package sound_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+	. "github.com/mudler/LocalAI/pkg/sound"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeWav(path string, sampleRate int, data []int) {
+	f, err := os.Create(path)
+	Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+
+	enc := wav.NewEncoder(f, sampleRate, 16, 1, 1)
+	Expect(enc.Write(&audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 1, SampleRate: sampleRate},
+		Data:           data,
+		SourceBitDepth: 16,
+	})).To(Succeed())
+	Expect(enc.Close()).To(Succeed())
+}
+
+var _ = Describe("Convert", func() {
+	var src string
+
+	BeforeEach(func() {
+		src = filepath.Join(GinkgoT().TempDir(), "tts.wav")
+		writeWav(src, 8000, []int{0, 100, 200, 300, -100, -200, -300, 0})
+	})
+
+	It("keeps wav files as they are", func() {
+		dst, err := Convert(src, "wav", 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dst).To(Equal(src))
+	})
+
+	It("resamples wav files", func() {
+		dst, err := Convert(src, "wav", 16000)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dst).ToNot(Equal(src))
+
+		f, err := os.Open(dst)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		buf, err := wav.NewDecoder(f).FullPCMBuffer()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.Format.SampleRate).To(Equal(16000))
+		Expect(buf.Data).To(HaveLen(16))
+		Expect(buf.Data[:4]).To(Equal([]int{0, 50, 100, 150}))
+	})
+
+	It("converts to raw pcm16", func() {
+		dst, err := Convert(src, "pcm", 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filepath.Ext(dst)).To(Equal(".pcm"))
+
+		dat, err := os.ReadFile(dst)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dat).To(HaveLen(16))
+		Expect(dat[2:4]).To(Equal([]byte{100, 0}))
+	})
+
+	It("rejects unknown formats", func() {
+		_, err := Convert(src, "aac", 0)
+		Expect(err).To(HaveOccurred())
+	})
+})
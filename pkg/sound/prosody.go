@@ -0,0 +1,160 @@
+package sound
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-audio/audio"
+)
+
+// Segment is a part of an audio assembled by Assemble: the wav file, spoken at a rate and a pitch,
+// followed by a silence
+type Segment struct {
+	// File is the wav file of the segment, none for a silence only
+	File string
+	// Rate and Pitch are the ratios applied to the speed and to the pitch of the audio, 1 keeps them
+	Rate, Pitch float64
+	Break       time.Duration
+}
+
+// Assemble writes to dst the wav of the segments, one after the other. The silences of the segments
+// without a file have the format of the other segments, or sampleRate mono when there is none
+func Assemble(dst string, segments []Segment, sampleRate int) error {
+	format := &audio.Format{NumChannels: 1, SampleRate: sampleRate}
+	bitDepth := 16
+	buffers := make([]*audio.IntBuffer, len(segments))
+	var first *audio.IntBuffer
+	for i, s := range segments {
+		if s.File == "" {
+			continue
+		}
+		buf, err := readWav(s.File)
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = buf
+			format, bitDepth = buf.Format, buf.SourceBitDepth
+		} else if buf.Format.NumChannels != first.Format.NumChannels {
+			return fmt.Errorf("%s has %d channels, and the other segments %d", s.File, buf.Format.NumChannels, first.Format.NumChannels)
+		}
+		buffers[i] = buf
+	}
+
+	out := &audio.IntBuffer{Format: format, SourceBitDepth: bitDepth}
+	for i, s := range segments {
+		if buf := buffers[i]; buf != nil {
+			buf = Prosody(Resample(buf, format.SampleRate), s.Rate, s.Pitch)
+			out.Data = append(out.Data, buf.Data...)
+		}
+		out.Data = append(out.Data, Silence(format, s.Break).Data...)
+	}
+	return writeWav(dst, out)
+}
+
+// Silence returns a silence of the duration in the format
+func Silence(format *audio.Format, d time.Duration) *audio.IntBuffer {
+	frames := int(d.Seconds() * float64(format.SampleRate))
+	return &audio.IntBuffer{Format: format, Data: make([]int, frames*format.NumChannels)}
+}
+
+// Prosody changes the speed of the buffer by rate and its pitch by pitch: the audio is stretched keeping
+// its pitch, and resampled to change the pitch without changing its duration again
+func Prosody(buf *audio.IntBuffer, rate, pitch float64) *audio.IntBuffer {
+	if rate <= 0 {
+		rate = 1
+	}
+	if pitch <= 0 {
+		pitch = 1
+	}
+	if rate == 1 && pitch == 1 {
+		return buf
+	}
+
+	sampleRate := buf.Format.SampleRate
+	// the resampling speeds the audio up by pitch, which the stretch compensates
+	out := stretch(buf, rate/pitch)
+	if pitch != 1 {
+		out = Resample(out, int(math.Round(float64(sampleRate)/pitch)))
+		out.Format = &audio.Format{NumChannels: out.Format.NumChannels, SampleRate: sampleRate}
+	}
+	return out
+}
+
+// stretch plays the buffer speed times faster without changing its pitch, with a waveform similarity
+// overlap-add (WSOLA): the windows of the audio are overlapped at the positions where they are the most
+// similar to the audio they continue, so that the stretch does not add echoes
+func stretch(buf *audio.IntBuffer, speed float64) *audio.IntBuffer {
+	channels := buf.Format.NumChannels
+	if speed == 1 || channels == 0 {
+		return buf
+	}
+	frames := len(buf.Data) / channels
+
+	// windows of 30ms, overlapped by half, searched for the best overlap in a quarter of window
+	window := max(buf.Format.SampleRate*30/1000, 16)
+	hop := window / 2
+	tolerance := window / 4
+	hann := make([]float64, window)
+	for i := range hann {
+		hann[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(window))
+	}
+
+	// sample returns the frame i of the channel c, and silence out of the buffer
+	sample := func(i, c int) float64 {
+		if i < 0 || i >= frames {
+			return 0
+		}
+		return float64(buf.Data[i*channels+c])
+	}
+
+	outFrames := int(float64(frames) / speed)
+	acc := make([]float64, (outFrames+window)*channels)
+	weights := make([]float64, outFrames+window)
+
+	// previous is the position in the input of the previous window
+	previous := 0
+	for k := 0; k*hop < outFrames; k++ {
+		position := int(float64(k*hop) * speed)
+		if k > 0 {
+			// the window continuing the previous one the most naturally, on the first channel
+			natural := previous + hop
+			best, bestScore := position, math.Inf(-1)
+			for delta := -tolerance; delta <= tolerance; delta++ {
+				candidate := position + delta
+				score := 0.0
+				for i := 0; i < hop; i++ {
+					score += sample(natural+i, 0) * sample(candidate+i, 0)
+				}
+				if score > bestScore {
+					best, bestScore = candidate, score
+				}
+			}
+			position = best
+		}
+		previous = position
+
+		for i := 0; i < window; i++ {
+			o := k*hop + i
+			weights[o] += hann[i]
+			for c := 0; c < channels; c++ {
+				acc[o*channels+c] += hann[i] * sample(position+i, c)
+			}
+		}
+	}
+
+	data := make([]int, outFrames*channels)
+	for i := 0; i < outFrames; i++ {
+		for c := 0; c < channels; c++ {
+			if weights[i] > 1e-6 {
+				data[i*channels+c] = int(math.Round(acc[i*channels+c] / weights[i]))
+			}
+		}
+	}
+	return &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: channels, SampleRate: buf.Format.SampleRate},
+		Data:           data,
+		SourceBitDepth: buf.SourceBitDepth,
+	}
+}
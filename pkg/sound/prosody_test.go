@@ -0,0 +1,107 @@
+package sound_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+	. "github.com/mudler/LocalAI/pkg/sound"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sine returns a second of a sine of the frequency, sampled at 8kHz
+func sine(frequency float64) []int {
+	data := make([]int, 8000)
+	for i := range data {
+		data[i] = int(10000 * math.Sin(2*math.Pi*frequency*float64(i)/8000))
+	}
+	return data
+}
+
+// crossings counts the times the signal goes from negative to positive, its frequency for a second of sine
+func crossings(data []int) int {
+	n := 0
+	for i := 1; i < len(data); i++ {
+		if data[i-1] < 0 && data[i] >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+var _ = Describe("Prosody", func() {
+	var buf *audio.IntBuffer
+
+	BeforeEach(func() {
+		buf = &audio.IntBuffer{
+			Format:         &audio.Format{NumChannels: 1, SampleRate: 8000},
+			Data:           sine(200),
+			SourceBitDepth: 16,
+		}
+	})
+
+	It("keeps the audio when the rate and the pitch are the ones of the voice", func() {
+		Expect(Prosody(buf, 1, 1)).To(BeIdenticalTo(buf))
+	})
+
+	It("changes the speed without changing the pitch", func() {
+		out := Prosody(buf, 2, 1)
+		Expect(out.Data).To(HaveLen(4000))
+		Expect(crossings(out.Data)).To(BeNumerically("~", 100, 5))
+
+		out = Prosody(buf, 0.5, 1)
+		Expect(out.Data).To(HaveLen(16000))
+		Expect(crossings(out.Data)).To(BeNumerically("~", 400, 10))
+	})
+
+	It("changes the pitch without changing the speed", func() {
+		out := Prosody(buf, 1, 1.5)
+		Expect(out.Format.SampleRate).To(Equal(8000))
+		Expect(len(out.Data)).To(BeNumerically("~", 8000, 10))
+		Expect(crossings(out.Data)).To(BeNumerically("~", 300, 10))
+	})
+})
+
+var _ = Describe("Assemble", func() {
+	It("joins the segments with their silences", func() {
+		dir := GinkgoT().TempDir()
+		first := filepath.Join(dir, "first.wav")
+		second := filepath.Join(dir, "second.wav")
+		writeWav(first, 8000, sine(200))
+		writeWav(second, 8000, sine(200)[:4000])
+
+		dst := filepath.Join(dir, "out.wav")
+		Expect(Assemble(dst, []Segment{
+			{Break: 250 * time.Millisecond},
+			{File: first, Rate: 2, Pitch: 1, Break: 500 * time.Millisecond},
+			{File: second, Rate: 1, Pitch: 1},
+		}, 22050)).To(Succeed())
+
+		f, err := os.Open(dst)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		out, err := wav.NewDecoder(f).FullPCMBuffer()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out.Format.SampleRate).To(Equal(8000))
+		Expect(out.Data).To(HaveLen(2000 + 4000 + 4000 + 4000))
+		Expect(out.Data[:2000]).To(HaveEach(0))
+		Expect(out.Data[6000:10000]).To(HaveEach(0))
+	})
+
+	It("writes the silences alone at the sample rate of the voice", func() {
+		dst := filepath.Join(GinkgoT().TempDir(), "out.wav")
+		Expect(Assemble(dst, []Segment{{Break: time.Second}}, 22050)).To(Succeed())
+
+		f, err := os.Open(dst)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		out, err := wav.NewDecoder(f).FullPCMBuffer()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out.Format.SampleRate).To(Equal(22050))
+		Expect(out.Data).To(HaveLen(22050))
+	})
+})
@@ -0,0 +1,320 @@
+package ssml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxBreak bounds the breaks, so that a request can't ask for hours of silence
+const MaxBreak = 10 * time.Second
+
+// Segment is a part of a SSML document spoken with the same prosody, followed by a silence
+type Segment struct {
+	Text string
+	// Rate and Pitch are ratios of the ones of the voice, 1 keeps them
+	Rate, Pitch float64
+	// Break is the silence after the text
+	Break time.Duration
+}
+
+// Document is a parsed SSML document
+type Document struct {
+	Segments []Segment
+}
+
+// IsSSML tells if the text is a SSML document, beginning with its speak element
+func IsSSML(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "<?xml") {
+		if i := strings.Index(text, "?>"); i >= 0 {
+			text = strings.TrimSpace(text[i+2:])
+		}
+	}
+	return strings.HasPrefix(text, "<speak")
+}
+
+// Text returns the text of the document without its markup, for the backends which do not support the
+// breaks and the prosody
+func (d Document) Text() string {
+	texts := []string{}
+	for _, s := range d.Segments {
+		if s.Text != "" {
+			texts = append(texts, s.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// breakStrengths are the silences of the strengths of the break element
+var breakStrengths = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   100 * time.Millisecond,
+	"weak":     250 * time.Millisecond,
+	"medium":   500 * time.Millisecond,
+	"strong":   750 * time.Millisecond,
+	"x-strong": time.Second,
+}
+
+var rates = map[string]float64{"x-slow": 0.5, "slow": 0.75, "medium": 1, "default": 1, "fast": 1.5, "x-fast": 2}
+
+var pitches = map[string]float64{"x-low": 0.8, "low": 0.9, "medium": 1, "default": 1, "high": 1.1, "x-high": 1.2}
+
+// Parse parses the subset of SSML supported by LocalAI: the breaks, the rate and the pitch of the
+// prosody, say-as and sub. The text of the other elements is kept, without their effect
+func Parse(text string) (Document, error) {
+	d := xml.NewDecoder(strings.NewReader(text))
+	p := &parser{prosody: []Segment{{Rate: 1, Pitch: 1}}}
+
+	// ignored counts the open elements whose content is not spoken, as the alias of sub replaces it
+	ignored := 0
+	root := true
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Document{}, fmt.Errorf("invalid SSML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root {
+				if t.Name.Local != "speak" {
+					return Document{}, fmt.Errorf("invalid SSML: the root element is %s, not speak", t.Name.Local)
+				}
+				root = false
+				continue
+			}
+			if ignored > 0 {
+				ignored++
+				continue
+			}
+			if err := p.start(t, d); err != nil {
+				return Document{}, fmt.Errorf("invalid SSML: %w", err)
+			}
+			if t.Name.Local == "sub" {
+				ignored = 1
+			}
+		case xml.EndElement:
+			if ignored > 0 {
+				ignored--
+				continue
+			}
+			p.end(t)
+		case xml.CharData:
+			if ignored == 0 {
+				p.text.WriteString(string(t))
+			}
+		}
+	}
+	if root {
+		return Document{}, errors.New("invalid SSML: no speak element")
+	}
+	p.flush()
+	return Document{Segments: p.segments}, nil
+}
+
+type parser struct {
+	segments []Segment
+	text     strings.Builder
+	// prosody is the stack of the prosody of the open prosody elements, with the one of the voice first
+	prosody []Segment
+}
+
+// flush ends the current segment, with the prosody of the text
+func (p *parser) flush() {
+	text := strings.Join(strings.Fields(p.text.String()), " ")
+	p.text.Reset()
+	if text == "" {
+		return
+	}
+	current := p.prosody[len(p.prosody)-1]
+	p.segments = append(p.segments, Segment{Text: text, Rate: current.Rate, Pitch: current.Pitch})
+}
+
+// pause adds a silence after the text
+func (p *parser) pause(d time.Duration) {
+	p.flush()
+	if len(p.segments) == 0 {
+		current := p.prosody[len(p.prosody)-1]
+		p.segments = append(p.segments, Segment{Rate: current.Rate, Pitch: current.Pitch})
+	}
+	last := &p.segments[len(p.segments)-1]
+	last.Break = min(last.Break+d, MaxBreak)
+}
+
+func (p *parser) start(t xml.StartElement, d *xml.Decoder) error {
+	switch t.Name.Local {
+	case "break":
+		duration, err := breakDuration(attr(t, "time"), attr(t, "strength"))
+		if err != nil {
+			return err
+		}
+		p.pause(duration)
+	case "prosody":
+		p.flush()
+		current := p.prosody[len(p.prosody)-1]
+		rate, err := ratio(attr(t, "rate"), rates, false)
+		if err != nil {
+			return fmt.Errorf("invalid prosody rate: %w", err)
+		}
+		pitch, err := ratio(attr(t, "pitch"), pitches, true)
+		if err != nil {
+			return fmt.Errorf("invalid prosody pitch: %w", err)
+		}
+		p.prosody = append(p.prosody, Segment{Rate: current.Rate * rate, Pitch: current.Pitch * pitch})
+	case "say-as":
+		// the content is read at once, to be spoken as interpret-as tells
+		var content string
+		if err := d.DecodeElement(&content, &t); err != nil {
+			return err
+		}
+		p.text.WriteString(sayAs(content, attr(t, "interpret-as")))
+	case "sub":
+		p.text.WriteString(attr(t, "alias"))
+	case "p", "s":
+		p.text.WriteString(" ")
+	}
+	return nil
+}
+
+func (p *parser) end(t xml.EndElement) {
+	switch t.Name.Local {
+	case "prosody":
+		p.flush()
+		if len(p.prosody) > 1 {
+			p.prosody = p.prosody[:len(p.prosody)-1]
+		}
+	case "p", "s":
+		p.text.WriteString(" ")
+	}
+}
+
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// breakDuration returns the silence of a break, from its time, as "500ms" or "2s", or its strength.
+// A break without them is a medium one
+func breakDuration(value, strength string) (time.Duration, error) {
+	if value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid break time %q: expected a duration as 500ms or 2s", value)
+		}
+		return min(d, MaxBreak), nil
+	}
+	if strength == "" {
+		strength = "medium"
+	}
+	d, known := breakStrengths[strength]
+	if !known {
+		return 0, fmt.Errorf("invalid break strength %q", strength)
+	}
+	return d, nil
+}
+
+// ratio returns the ratio of a rate or a pitch of the prosody: a keyword, a percentage, absolute as
+// "80%" or relative as "+20%", a ratio as "1.2", or, for the pitches, semitones as "-2st". The other
+// values, as the pitches in Hz, keep the ones of the voice
+func ratio(value string, keywords map[string]float64, semitones bool) (float64, error) {
+	if value == "" {
+		return 1, nil
+	}
+	if r, known := keywords[value]; known {
+		return r, nil
+	}
+
+	relative := value[0] == '+' || value[0] == '-'
+	switch {
+	case semitones && strings.HasSuffix(value, "st"):
+		n, err := strconv.ParseFloat(strings.TrimSuffix(value, "st"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of semitones", value)
+		}
+		return math.Pow(2, n/12), nil
+	case strings.HasSuffix(value, "%"):
+		n, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a percentage", value)
+		}
+		r := n / 100
+		if relative {
+			r += 1
+		}
+		if r <= 0 {
+			return 0, fmt.Errorf("%q must be positive", value)
+		}
+		return r, nil
+	case strings.HasSuffix(value, "Hz"):
+		return 1, nil
+	}
+	r, err := strconv.ParseFloat(value, 64)
+	if err != nil || r <= 0 {
+		return 0, fmt.Errorf("%q is not a keyword, a percentage or a positive ratio", value)
+	}
+	return r, nil
+}
+
+// sayAs returns the text as it is spoken: the characters and the digits are spelled one by one, and the
+// telephone numbers by groups of digits. The other types, as the dates or the numbers, are left to the
+// voice
+func sayAs(text, interpretAs string) string {
+	text = strings.TrimSpace(text)
+	switch interpretAs {
+	case "characters", "spell-out", "verbatim":
+		return spell(text, func(r rune) bool { return !unicode.IsSpace(r) })
+	case "digits":
+		return spell(text, unicode.IsDigit)
+	case "telephone":
+		groups := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsDigit(r) && r != '+' })
+		for i, g := range groups {
+			groups[i] = spell(g, unicode.IsDigit)
+		}
+		return strings.Join(groups, ", ")
+	case "ordinal":
+		if n, err := strconv.Atoi(text); err == nil {
+			return ordinal(n)
+		}
+	}
+	return text
+}
+
+// spell returns the runes of the text kept by keep, separated by spaces
+func spell(text string, keep func(rune) bool) string {
+	runes := []string{}
+	for _, r := range text {
+		if keep(r) {
+			runes = append(runes, string(r))
+		}
+	}
+	return strings.Join(runes, " ")
+}
+
+// ordinal returns the english ordinal of n, as 21st
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,13 @@
+package ssml_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSSML(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SSML test suite")
+}
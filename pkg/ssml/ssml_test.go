@@ -0,0 +1,78 @@
+package ssml_test
+
+import (
+	"math"
+	"time"
+
+	. "github.com/mudler/LocalAI/pkg/ssml"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SSML", func() {
+	It("detects the SSML documents", func() {
+		Expect(IsSSML(`<speak>Hello</speak>`)).To(BeTrue())
+		Expect(IsSSML(` <?xml version="1.0"?><speak version="1.1">Hello</speak>`)).To(BeTrue())
+		Expect(IsSSML(`Hello <speak>`)).To(BeFalse())
+	})
+
+	It("splits the text at the breaks", func() {
+		d, err := Parse(`<speak>Press one. <break time="2s"/>Press two.<break strength="weak"/><break/></speak>`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Segments).To(Equal([]Segment{
+			{Text: "Press one.", Rate: 1, Pitch: 1, Break: 2 * time.Second},
+			{Text: "Press two.", Rate: 1, Pitch: 1, Break: 750 * time.Millisecond},
+		}))
+		Expect(d.Text()).To(Equal("Press one. Press two."))
+	})
+
+	It("bounds the breaks", func() {
+		d, err := Parse(`<speak><break time="1h"/>Hello</speak>`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Segments[0].Break).To(Equal(MaxBreak))
+		Expect(d.Segments[1].Text).To(Equal("Hello"))
+	})
+
+	It("applies the nested prosody", func() {
+		d, err := Parse(`<speak>Welcome <prosody rate="slow" pitch="+2st">to the <prosody rate="200%">hotline</prosody></prosody> now</speak>`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Segments).To(HaveLen(4))
+		Expect(d.Segments[0]).To(Equal(Segment{Text: "Welcome", Rate: 1, Pitch: 1}))
+		Expect(d.Segments[1].Text).To(Equal("to the"))
+		Expect(d.Segments[1].Rate).To(Equal(0.75))
+		Expect(d.Segments[1].Pitch).To(BeNumerically("~", math.Pow(2, 2.0/12), 1e-9))
+		Expect(d.Segments[2].Text).To(Equal("hotline"))
+		Expect(d.Segments[2].Rate).To(Equal(1.5))
+		Expect(d.Segments[3]).To(Equal(Segment{Text: "now", Rate: 1, Pitch: 1}))
+	})
+
+	It("parses the relative prosody", func() {
+		d, err := Parse(`<speak><prosody rate="-20%" pitch="low">slow</prosody></speak>`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Segments[0].Rate).To(BeNumerically("~", 0.8, 1e-9))
+		Expect(d.Segments[0].Pitch).To(Equal(0.9))
+	})
+
+	It("speaks say-as and sub", func() {
+		d, err := Parse(`<speak>Call <say-as interpret-as="telephone">555-0123</say-as>, code <say-as interpret-as="characters">AB1</say-as>, <sub alias="World Wide Web">WWW</sub>, the <say-as interpret-as="ordinal">2</say-as> option</speak>`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Text()).To(Equal("Call 5 5 5, 0 1 2 3, code A B 1, World Wide Web, the 2nd option"))
+	})
+
+	It("keeps the text of the unsupported elements", func() {
+		d, err := Parse(`<speak><p><s>Hello</s><s><emphasis>world</emphasis></s></p></speak>`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Text()).To(Equal("Hello world"))
+	})
+
+	It("refuses the invalid documents", func() {
+		_, err := Parse(`<speak>Hello`)
+		Expect(err).To(MatchError(ContainSubstring("invalid SSML")))
+		_, err = Parse(`<voice>Hello</voice>`)
+		Expect(err).To(MatchError(ContainSubstring("not speak")))
+		_, err = Parse(`<speak><break time="soon"/></speak>`)
+		Expect(err).To(MatchError(ContainSubstring("invalid break time")))
+		_, err = Parse(`<speak><prosody rate="-100%">x</prosody></speak>`)
+		Expect(err).To(MatchError(ContainSubstring("invalid prosody rate")))
+	})
+})
@@ -7,8 +7,8 @@ import (
 	stableDiffusion "github.com/mudler/go-stable-diffusion"
 )
 
-func GenerateImage(height, width, mode, step, seed int, positive_prompt, negative_prompt, dst, asset_dir string) error {
-	if height > 512 || width > 512 {
+func GenerateImage(height, width, mode, step, seed int, positive_prompt, negative_prompt, dst, asset_dir string, upscale bool) error {
+	if upscale {
 		return stableDiffusion.GenerateImageUpscaled(
 			height,
 			width,
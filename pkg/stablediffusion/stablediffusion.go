@@ -1,6 +1,17 @@
 package stablediffusion
 
-import "os"
+import (
+	"fmt"
+	"os"
+)
+
+// Upscalers of the generated images
+const (
+	// UpscalerESRGAN upscales the images 4x with Real-ESRGAN. It is used by default for the images larger than 512x512
+	UpscalerESRGAN = "esrgan"
+	// UpscalerNone generates the images at their size
+	UpscalerNone = "none"
+)
 
 type StableDiffusion struct {
 	assetDir string
@@ -15,6 +26,16 @@ func New(assetDir string) (*StableDiffusion, error) {
 	}, nil
 }
 
-func (s *StableDiffusion) GenerateImage(height, width, mode, step, seed int, positive_prompt, negative_prompt, dst string) error {
-	return GenerateImage(height, width, mode, step, seed, positive_prompt, negative_prompt, dst, s.assetDir)
+func (s *StableDiffusion) GenerateImage(height, width, mode, step, seed int, positive_prompt, negative_prompt, dst, upscaler string) error {
+	upscale := height > 512 || width > 512
+	switch upscaler {
+	case "":
+	case UpscalerESRGAN:
+		upscale = true
+	case UpscalerNone:
+		upscale = false
+	default:
+		return fmt.Errorf("unsupported upscaler %q: expected %s or %s", upscaler, UpscalerESRGAN, UpscalerNone)
+	}
+	return GenerateImage(height, width, mode, step, seed, positive_prompt, negative_prompt, dst, s.assetDir, upscale)
 }
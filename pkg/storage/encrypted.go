@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The encrypted files start with encryptedMagic and a random nonce prefix, followed by the content
+// sealed with AES-GCM in chunks of encryptedChunkSize bytes. The nonce of a chunk is the prefix and
+// the index of the chunk, and the last chunk is authenticated as such, so that the chunks cannot be
+// reordered, and the file cannot be truncated, without failing the decryption.
+const (
+	encryptedMagic       = "LAIENC01"
+	encryptedPrefixSize  = 8
+	encryptedHeaderSize  = len(encryptedMagic) + encryptedPrefixSize
+	encryptedChunkSize   = 64 * 1024
+	encryptedChunkSealed = encryptedChunkSize + 16
+)
+
+var ErrInvalidEncryptedFile = errors.New("invalid encrypted file")
+
+// ParseKey decodes a base64 encoded AES-256 key, as generated with openssl rand -base64 32
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("the encryption key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the encryption key must be 32 bytes long, got %d", len(key))
+	}
+	return key, nil
+}
+
+// Encrypted encrypts the files of a storage with AES-GCM, and decrypts them when they are opened.
+// The files stored before the encryption was enabled are opened as they are.
+// It does not sign URLs, as the files would be downloaded encrypted.
+type Encrypted struct {
+	s    Storage
+	aead cipher.AEAD
+}
+
+func NewEncrypted(s Storage, key []byte) (*Encrypted, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypted{s: s, aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (e *Encrypted) Save(name string, r io.Reader, size int64) error {
+	if size >= 0 {
+		size = EncryptedSize(size)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := newEncryptWriter(pw, e.aead)
+		if err == nil {
+			_, err = io.Copy(w, r)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	err := e.s.Save(name, pr, size)
+	pr.CloseWithError(err)
+	return err
+}
+
+func (e *Encrypted) Open(name string) (io.ReadCloser, error) {
+	r, err := e.s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return newDecryptReader(r, e.aead)
+}
+
+func (e *Encrypted) Delete(name string) error {
+	return e.s.Delete(name)
+}
+
+func (e *Encrypted) Exists(name string) (bool, error) {
+	return e.s.Exists(name)
+}
+
+// EncryptedSize returns the size of a file of size bytes once encrypted
+func EncryptedSize(size int64) int64 {
+	chunks := max((size+encryptedChunkSize-1)/encryptedChunkSize, 1)
+	return int64(encryptedHeaderSize) + size + chunks*int64(encryptedChunkSealed-encryptedChunkSize)
+}
+
+// EncryptFile encrypts a file in place, as the files written by the backends. Encrypted files are left
+// as they are
+func EncryptFile(path string, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(src, magic); err == nil && string(magic) == encryptedMagic {
+		return nil
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".enc")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+	w, err := newEncryptWriter(dst, aead)
+	if err == nil {
+		_, err = io.Copy(w, src)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(dst.Name(), path)
+}
+
+// OpenFile opens a file encrypted with EncryptFile, decrypting it. Without key, or if the file is not
+// encrypted, it is opened as it is
+func OpenFile(path string, key []byte) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil || key == nil {
+		return f, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return newDecryptReader(f, aead)
+}
+
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	index  uint32
+	buf    []byte
+}
+
+func newEncryptWriter(w io.Writer, aead cipher.AEAD) (*encryptWriter, error) {
+	prefix := make([]byte, encryptedPrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append([]byte(encryptedMagic), prefix...)); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// a full chunk is sealed once more data comes, as the last one is sealed differently
+		if len(e.buf) == encryptedChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := min(len(p), encryptedChunkSize-len(e.buf))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the last chunk
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.index), e.buf, chunkAD(last))
+	e.index++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+type decryptReader struct {
+	r      *bufio.Reader
+	c      io.Closer
+	aead   cipher.AEAD
+	prefix []byte
+	index  uint32
+	chunk  []byte
+	done   bool
+}
+
+// newDecryptReader decrypts the content of r, or returns it as it is if it is not encrypted
+func newDecryptReader(r io.ReadCloser, aead cipher.AEAD) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(r, encryptedChunkSealed+1)
+	magic, err := br.Peek(len(encryptedMagic))
+	if err != nil || string(magic) != encryptedMagic {
+		return struct {
+			io.Reader
+			io.Closer
+		}{br, r}, nil
+	}
+	header := make([]byte, encryptedHeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		r.Close()
+		return nil, ErrInvalidEncryptedFile
+	}
+	return &decryptReader{r: br, c: r, aead: aead, prefix: header[len(encryptedMagic):]}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.chunk) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.chunk)
+	d.chunk = d.chunk[n:]
+	return n, nil
+}
+
+// open decrypts the next chunk. The chunk is the last one if nothing follows it
+func (d *decryptReader) open() error {
+	sealed := make([]byte, encryptedChunkSealed)
+	n, err := io.ReadFull(d.r, sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ErrInvalidEncryptedFile
+	}
+	_, err = d.r.Peek(1)
+	last := err == io.EOF
+	chunk, err := d.aead.Open(nil, chunkNonce(d.prefix, d.index), sealed[:n], chunkAD(last))
+	if err != nil {
+		return ErrInvalidEncryptedFile
+	}
+	d.index++
+	d.chunk, d.done = chunk, last
+	return nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.c.Close()
+}
+
+func chunkNonce(prefix []byte, index uint32) []byte {
+	return binary.BigEndian.AppendUint32(bytes.Clone(prefix), index)
+}
+
+func chunkAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,122 @@
+package storage_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/mudler/LocalAI/pkg/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encrypted storage", func() {
+	var dir string
+	var key []byte
+	var s *Encrypted
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		key = make([]byte, 32)
+		rand.Read(key)
+		var err error
+		s, err = NewEncrypted(NewLocal(dir), key)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	read := func(name string) ([]byte, error) {
+		r, err := s.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+
+	It("encrypts the files at rest and decrypts them when opened", func() {
+		for _, size := range []int{0, 10, 64 * 1024, 64*1024 + 1, 200 * 1024} {
+			content := make([]byte, size)
+			rand.Read(content)
+			Expect(s.Save("foo.bin", bytes.NewReader(content), int64(size))).To(Succeed())
+
+			stored, err := os.ReadFile(filepath.Join(dir, "foo.bin"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(int64(len(stored))).To(Equal(EncryptedSize(int64(size))))
+			if size > 0 {
+				Expect(bytes.Contains(stored, content)).To(BeFalse())
+			}
+
+			decrypted, err := read("foo.bin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal(content))
+		}
+	})
+
+	It("opens the files stored before the encryption as they are", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "plain.txt"), []byte("bar"), 0644)).To(Succeed())
+		content, err := read("plain.txt")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("bar"))
+	})
+
+	It("fails with another key, or a tampered or truncated file", func() {
+		Expect(s.Save("foo.txt", strings.NewReader(strings.Repeat("bar", 50000)), -1)).To(Succeed())
+		path := filepath.Join(dir, "foo.txt")
+		stored, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		other := make([]byte, 32)
+		rand.Read(other)
+		o, err := NewEncrypted(NewLocal(dir), other)
+		Expect(err).ToNot(HaveOccurred())
+		r, err := o.Open("foo.txt")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(r)
+		Expect(err).To(MatchError(ErrInvalidEncryptedFile))
+
+		tampered := bytes.Clone(stored)
+		tampered[len(tampered)-1] ^= 1
+		Expect(os.WriteFile(path, tampered, 0644)).To(Succeed())
+		_, err = read("foo.txt")
+		Expect(err).To(MatchError(ErrInvalidEncryptedFile))
+
+		// the first chunk alone is not the last one
+		Expect(os.WriteFile(path, stored[:16+64*1024+16], 0644)).To(Succeed())
+		_, err = read("foo.txt")
+		Expect(err).To(MatchError(ErrInvalidEncryptedFile))
+	})
+
+	It("encrypts files in place", func() {
+		path := filepath.Join(dir, "tts.wav")
+		Expect(os.WriteFile(path, []byte("RIFF audio"), 0644)).To(Succeed())
+		Expect(EncryptFile(path, key)).To(Succeed())
+		// encrypting twice keeps the file readable
+		Expect(EncryptFile(path, key)).To(Succeed())
+
+		stored, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(stored)).ToNot(ContainSubstring("RIFF"))
+
+		r, err := OpenFile(path, key)
+		Expect(err).ToNot(HaveOccurred())
+		content, err := io.ReadAll(r)
+		r.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("RIFF audio"))
+	})
+
+	It("parses base64 keys of 32 bytes", func() {
+		parsed, err := ParseKey(base64.StdEncoding.EncodeToString(key))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(key))
+
+		_, err = ParseKey(base64.StdEncoding.EncodeToString(key[:16]))
+		Expect(err).To(HaveOccurred())
+		_, err = ParseKey("not base64!")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to all the object names
+	Prefix string
+	// Insecure disables TLS when connecting to the endpoint
+	Insecure bool
+}
+
+// S3 stores files in a bucket of an S3 compatible object storage
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func NewS3(c S3Config) (*S3, error) {
+	if c.Endpoint == "" || c.Bucket == "" {
+		return nil, fmt.Errorf("S3 endpoint and bucket are required")
+	}
+
+	client, err := minio.New(c.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(c.AccessKey, c.SecretKey, ""),
+		Secure: !c.Insecure,
+		Region: c.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed creating S3 client: %w", err)
+	}
+
+	return &S3{client: client, bucket: c.Bucket, prefix: c.Prefix}, nil
+}
+
+func (s *S3) key(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func (s *S3) Save(name string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(context.Background(), s.bucket, s.key(name), r, size, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *S3) Open(name string) (io.ReadCloser, error) {
+	// GetObject is lazy, stat first to report missing objects right away
+	if _, err := s.stat(name); err != nil {
+		return nil, err
+	}
+	return s.client.GetObject(context.Background(), s.bucket, s.key(name), minio.GetObjectOptions{})
+}
+
+func (s *S3) Delete(name string) error {
+	if _, err := s.stat(name); err != nil {
+		return err
+	}
+	return s.client.RemoveObject(context.Background(), s.bucket, s.key(name), minio.RemoveObjectOptions{})
+}
+
+func (s *S3) Exists(name string) (bool, error) {
+	_, err := s.stat(name)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *S3) SignedURL(name string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(context.Background(), s.bucket, s.key(name), expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *S3) stat(name string) (minio.ObjectInfo, error) {
+	info, err := s.client.StatObject(context.Background(), s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return info, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+		}
+		return info, err
+	}
+	return info, nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Storage is a blob storage where LocalAI persists files, such as uploads.
+// Names are flat, relative identifiers: implementations are responsible of
+// mapping them to their own namespace (a directory, a bucket prefix, ...).
+// Missing files are reported with errors matching os.ErrNotExist.
+type Storage interface {
+	Save(name string, r io.Reader, size int64) error
+	Open(name string) (io.ReadCloser, error)
+	Delete(name string) error
+	Exists(name string) (bool, error)
+}
+
+// URLSigner is implemented by the storages that can hand out temporary URLs
+// to download files directly, without going through LocalAI
+type URLSigner interface {
+	SignedURL(name string, expiry time.Duration) (string, error)
+}
+
+// SaveContentAddressed stores the file at path with a name derived from the hash
+// of its content, keeping its extension, and returns the name.
+// Storing the same content twice results in the same name.
+func SaveContentAddressed(s Storage, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(h.Sum(nil)) + filepath.Ext(path)
+
+	exists, err := s.Exists(name)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return name, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return name, s.Save(name, f, size)
+}
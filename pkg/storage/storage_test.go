@@ -0,0 +1,63 @@
+package storage_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/mudler/LocalAI/pkg/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Local storage", func() {
+	var dir string
+	var s *Local
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		s = NewLocal(dir)
+	})
+
+	It("saves, opens and deletes files", func() {
+		Expect(s.Save("foo.txt", strings.NewReader("bar"), 3)).To(Succeed())
+
+		exists, err := s.Exists("foo.txt")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeTrue())
+
+		r, err := s.Open("foo.txt")
+		Expect(err).ToNot(HaveOccurred())
+		content, err := io.ReadAll(r)
+		r.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("bar"))
+
+		Expect(s.Delete("foo.txt")).To(Succeed())
+		exists, err = s.Exists("foo.txt")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeFalse())
+
+		_, err = s.Open("foo.txt")
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+
+	It("refuses names outside of its directory", func() {
+		Expect(s.Save("../foo.txt", strings.NewReader("bar"), 3)).ToNot(Succeed())
+	})
+
+	It("names files by their content", func() {
+		src := filepath.Join(GinkgoT().TempDir(), "image.png")
+		Expect(os.WriteFile(src, []byte("content"), 0600)).To(Succeed())
+
+		name, err := SaveContentAddressed(s, src)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73.png"))
+		Expect(filepath.Join(dir, name)).To(BeAnExistingFile())
+
+		again, err := SaveContentAddressed(s, src)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(Equal(name))
+	})
+})
@@ -11,6 +11,7 @@ import (
 	"github.com/mudler/LocalAI/pkg/utils"
 
 	"github.com/Masterminds/sprig/v3"
+	"github.com/nikolalohinski/gonja/v2/exec"
 )
 
 // Keep this in sync with config.TemplateConfig. Is there a more idiomatic way to accomplish this in go?
@@ -21,12 +22,16 @@ type TemplateCache struct {
 	mu            sync.Mutex
 	templatesPath string
 	templates     map[TemplateType]map[string]*template.Template
+
+	jinjaTemplates map[string]*exec.Template
 }
 
 func NewTemplateCache(templatesPath string) *TemplateCache {
 	tc := &TemplateCache{
 		templatesPath: templatesPath,
 		templates:     make(map[TemplateType]map[string]*template.Template),
+
+		jinjaTemplates: make(map[string]*exec.Template),
 	}
 	return tc
 }
@@ -38,6 +43,11 @@ func (tc *TemplateCache) initializeTemplateMapKey(tt TemplateType) {
 }
 
 func (tc *TemplateCache) EvaluateTemplate(templateType TemplateType, templateName string, in interface{}) (string, error) {
+	return tc.EvaluateTemplateWithTokenizer(templateType, templateName, in, nil)
+}
+
+// EvaluateTemplateWithTokenizer evaluates a template, counting the tokens of truncateTokens with the given tokenizer
+func (tc *TemplateCache) EvaluateTemplateWithTokenizer(templateType TemplateType, templateName string, in interface{}, tokenizer Tokenizer) (string, error) {
 	tc.mu.Lock()
 	defer tc.mu.Unlock()
 
@@ -57,12 +67,22 @@ func (tc *TemplateCache) EvaluateTemplate(templateType TemplateType, templateNam
 
 	var buf bytes.Buffer
 
-	if err := m.Execute(&buf, in); err != nil {
+	if err := m.Funcs(funcMap(tokenizer)).Execute(&buf, in); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
+// LoadTemplate parses a template without evaluating it, to report syntax errors before it
+// is used. Like EvaluateTemplate, the name is either a file in the templates path or the template itself
+func (tc *TemplateCache) LoadTemplate(templateType TemplateType, templateName string) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.initializeTemplateMapKey(templateType)
+	return tc.loadTemplateIfExists(templateType, templateName)
+}
+
 func (tc *TemplateCache) loadTemplateIfExists(templateType TemplateType, templateName string) error {
 
 	// Check if the template was already loaded
@@ -94,7 +114,7 @@ func (tc *TemplateCache) loadTemplateIfExists(templateType TemplateType, templat
 	}
 
 	// Parse the template
-	tmpl, err := template.New("prompt").Funcs(sprig.FuncMap()).Parse(dat)
+	tmpl, err := template.New("prompt").Funcs(sprig.FuncMap()).Funcs(funcMap(nil)).Parse(dat)
 	if err != nil {
 		return err
 	}
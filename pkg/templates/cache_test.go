@@ -59,6 +59,17 @@ var _ = Describe("TemplateCache", func() {
 		})
 	})
 
+	Describe("LoadTemplate", func() {
+		It("should parse valid templates", func() {
+			Expect(templateCache.LoadTemplate(1, "example")).To(Succeed())
+			Expect(templateCache.LoadTemplate(1, "{{.Name}}")).To(Succeed())
+		})
+
+		It("should report syntax errors", func() {
+			Expect(templateCache.LoadTemplate(1, "{{.Name")).ToNot(Succeed())
+		})
+	})
+
 	Describe("concurrency", func() {
 		It("should handle multiple concurrent accesses", func(done Done) {
 			go func() {
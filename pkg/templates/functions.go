@@ -0,0 +1,136 @@
+package templates
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of a text with the tokenizer of the model the prompt is rendered for
+type Tokenizer func(text string) (int, error)
+
+// EstimateTokens counts 4 characters per token, when the tokenizer of the model is not available
+func EstimateTokens(text string) (int, error) {
+	return (utf8.RuneCountInString(text) + 3) / 4, nil
+}
+
+// TruncateTokens keeps the first n tokens of a text, or the last -n tokens if n is negative,
+// as the trunc function of sprig does with the characters. Without tokenizer, the tokens are estimated
+func TruncateTokens(tokenizer Tokenizer, n int, text string) (string, error) {
+	if tokenizer == nil {
+		tokenizer = EstimateTokens
+	}
+	limit := n
+	if limit < 0 {
+		limit = -limit
+	}
+
+	count, err := tokenizer(text)
+	if err != nil {
+		return "", err
+	}
+	if count <= limit {
+		return text, nil
+	}
+
+	runes := []rune(text)
+	part := func(length int) string {
+		if n < 0 {
+			return string(runes[len(runes)-length:])
+		}
+		return string(runes[:length])
+	}
+
+	// Search the longest part of the text within the limit, the tokenizer can merge the characters in any way
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		count, err := tokenizer(part(mid))
+		if err != nil {
+			return "", err
+		}
+		if count <= limit {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return part(low), nil
+}
+
+// funcMap returns the functions added to sprig in the Go templates
+func funcMap(tokenizer Tokenizer) template.FuncMap {
+	return template.FuncMap{
+		"truncateTokens": func(n int, text string) (string, error) {
+			return TruncateTokens(tokenizer, n, text)
+		},
+	}
+}
+
+// jinjaFunctions returns the functions added to the context of the Jinja templates, the ones of the
+// HuggingFace tokenizers and the ones of the Go templates
+func jinjaFunctions(tokenizer Tokenizer) map[string]interface{} {
+	return map[string]interface{}{
+		"strftime_now": func(format string) string {
+			return Strftime(time.Now(), format)
+		},
+		"regex_replace": func(text, pattern, replacement string) (string, error) {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return "", err
+			}
+			return re.ReplaceAllString(text, replacement), nil
+		},
+		"truncate_tokens": func(text string, n int) (string, error) {
+			return TruncateTokens(tokenizer, n, text)
+		},
+	}
+}
+
+var strftimeLayouts = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'Z': "MST",
+	'z': "-0700",
+}
+
+// Strftime formats a time with the directives of the Python strftime, as used by the chat templates
+func Strftime(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i == len(format)-1 {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch c := format[i]; c {
+		case '%':
+			b.WriteByte('%')
+		case 'e':
+			b.WriteString(t.Format("_2"))
+		case 'j':
+			b.WriteString(t.Format("002"))
+		default:
+			if layout, ok := strftimeLayouts[c]; ok {
+				b.WriteString(t.Format(layout))
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package templates_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mudler/LocalAI/pkg/templates"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// wordTokenizer counts a token for each word
+func wordTokenizer(text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+var _ = Describe("Template functions", func() {
+	var templateCache *templates.TemplateCache
+
+	BeforeEach(func() {
+		templateCache = templates.NewTemplateCache(GinkgoT().TempDir())
+	})
+
+	Describe("TruncateTokens", func() {
+		It("keeps the first tokens", func() {
+			Expect(templates.TruncateTokens(wordTokenizer, 2, "one two three four")).To(Equal("one two "))
+		})
+		It("keeps the last tokens with a negative number", func() {
+			Expect(templates.TruncateTokens(wordTokenizer, -2, "one two three four")).To(Equal(" three four"))
+		})
+		It("keeps the texts within the tokens", func() {
+			Expect(templates.TruncateTokens(wordTokenizer, 5, "one two three four")).To(Equal("one two three four"))
+		})
+		It("estimates the tokens without tokenizer", func() {
+			Expect(templates.TruncateTokens(nil, 2, "abcdefghijkl")).To(Equal("abcdefgh"))
+		})
+	})
+
+	It("formats the time as the Python strftime", func() {
+		t := time.Date(2024, time.July, 5, 14, 3, 9, 0, time.UTC)
+		Expect(templates.Strftime(t, "%d %b %Y, %H:%M:%S (%A) 100%%")).To(Equal("05 Jul 2024, 14:03:09 (Friday) 100%"))
+	})
+
+	It("adds the functions to the Go templates", func() {
+		result, err := templateCache.EvaluateTemplateWithTokenizer(1, `{{truncateTokens 2 .Input}}|{{truncateTokens -1 .Input}}|{{.Variables.name}}`, map[string]interface{}{
+			"Input":     "one two three",
+			"Variables": map[string]interface{}{"name": "LocalAI"},
+		}, wordTokenizer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("one two | three|LocalAI"))
+	})
+
+	It("adds the functions to the Jinja templates", func() {
+		result, err := templateCache.EvaluateJinjaTemplate(`{{ truncate_tokens(text, 2) }}|{{ regex_replace(text, "t(\\w+)", "T$1") }}|{{ strftime_now("%Y") }}`, map[string]interface{}{
+			"text": "one two three",
+		}, wordTokenizer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("one two |one Two Three|" + time.Now().Format("2006")))
+	})
+})
@@ -0,0 +1,198 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mudler/LocalAI/pkg/utils"
+
+	"github.com/nikolalohinski/gonja/v2"
+	"github.com/nikolalohinski/gonja/v2/builtins"
+	"github.com/nikolalohinski/gonja/v2/exec"
+	"github.com/nikolalohinski/gonja/v2/loaders"
+)
+
+// jinjaEnvironment adds to the gonja builtins the functions and the methods of the Python strings
+// that are commonly found in the chat templates
+var jinjaEnvironment = &exec.Environment{
+	Context: gonja.DefaultContext.Inherit().Update(exec.NewContext(map[string]interface{}{
+		"raise_exception": func(message string) (string, error) {
+			return "", errors.New(message)
+		},
+	})),
+	Filters:           builtins.Filters,
+	Tests:             builtins.Tests,
+	ControlStructures: builtins.ControlStructures,
+	Methods: exec.Methods{
+		Bool:  builtins.Methods.Bool,
+		Int:   builtins.Methods.Int,
+		Float: builtins.Methods.Float,
+		Str:   exec.NewMethodSet(jinjaStringMethods),
+		Dict:  builtins.Methods.Dict,
+		List:  builtins.Methods.List,
+	},
+}
+
+var jinjaStringMethods = map[string]exec.Method[string]{
+	"upper": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		if err := arguments.Take(); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return strings.ToUpper(self), nil
+	},
+	"lower": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		if err := arguments.Take(); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return strings.ToLower(self), nil
+	},
+	"title": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		if err := arguments.Take(); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		words := strings.Fields(self)
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+		}
+		return strings.Join(words, " "), nil
+	},
+	"strip":  jinjaStripMethod(strings.Trim, strings.TrimSpace),
+	"lstrip": jinjaStripMethod(strings.TrimLeft, func(s string) string { return strings.TrimLeft(s, " \t\n\r\v\f") }),
+	"rstrip": jinjaStripMethod(strings.TrimRight, func(s string) string { return strings.TrimRight(s, " \t\n\r\v\f") }),
+	"startswith": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		var prefix string
+		if err := arguments.Take(exec.PositionalArgument("prefix", nil, exec.StringArgument(&prefix))); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return strings.HasPrefix(self, prefix), nil
+	},
+	"endswith": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		var suffix string
+		if err := arguments.Take(exec.PositionalArgument("suffix", nil, exec.StringArgument(&suffix))); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return strings.HasSuffix(self, suffix), nil
+	},
+	"replace": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		var old, new string
+		if err := arguments.Take(
+			exec.PositionalArgument("old", nil, exec.StringArgument(&old)),
+			exec.PositionalArgument("new", nil, exec.StringArgument(&new)),
+		); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return strings.ReplaceAll(self, old, new), nil
+	},
+	"split": func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		var sep string
+		if err := arguments.Take(exec.PositionalArgument("sep", exec.AsValue(""), exec.StringArgument(&sep))); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		parts := strings.Fields(self)
+		if sep != "" {
+			parts = strings.Split(self, sep)
+		}
+		out := make([]interface{}, 0, len(parts))
+		for _, p := range parts {
+			out = append(out, p)
+		}
+		return out, nil
+	},
+}
+
+// jinjaStripMethod strips the characters given as argument, or the whitespaces without it
+func jinjaStripMethod(strip func(string, string) string, stripSpaces func(string) string) exec.Method[string] {
+	return func(self string, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		var chars string
+		if err := arguments.Take(exec.PositionalArgument("chars", exec.AsValue(""), exec.StringArgument(&chars))); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		if chars == "" {
+			return stripSpaces(self), nil
+		}
+		return strip(self, chars), nil
+	}
+}
+
+// EvaluateJinjaTemplate renders a Jinja template, as the chat templates of the HuggingFace tokenizers.
+// The name is either a .jinja file in the templates path or the template itself. truncate_tokens
+// counts the tokens with the given tokenizer, or estimates them if it is nil
+func (tc *TemplateCache) EvaluateJinjaTemplate(templateName string, in map[string]interface{}, tokenizer Tokenizer) (out string, err error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if err := tc.loadJinjaTemplateIfExists(templateName); err != nil {
+		return "", err
+	}
+
+	// gonja can panic on the constructs it does not support
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to render the template: %v", r)
+		}
+	}()
+
+	var buf bytes.Buffer
+	ctx := exec.NewContext(jinjaFunctions(tokenizer)).Update(exec.NewContext(in))
+	if err := tc.jinjaTemplates[templateName].Execute(&buf, ctx); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// LoadJinjaTemplate parses a Jinja template without evaluating it, to report syntax errors before it is used
+func (tc *TemplateCache) LoadJinjaTemplate(templateName string) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	return tc.loadJinjaTemplateIfExists(templateName)
+}
+
+func (tc *TemplateCache) loadJinjaTemplateIfExists(templateName string) error {
+	if _, ok := tc.jinjaTemplates[templateName]; ok {
+		return nil
+	}
+
+	modelTemplateFile := fmt.Sprintf("%s.jinja", templateName)
+	dat := templateName
+
+	// Only names can be files, the templates span multiple lines or contain tags
+	if !strings.ContainsAny(templateName, "\n{") {
+		if err := utils.VerifyPath(modelTemplateFile, tc.templatesPath); err != nil {
+			return fmt.Errorf("template file outside path: %s", modelTemplateFile)
+		}
+		if utils.ExistsInPath(tc.templatesPath, modelTemplateFile) {
+			d, err := os.ReadFile(filepath.Join(tc.templatesPath, modelTemplateFile))
+			if err != nil {
+				return err
+			}
+			dat = string(d)
+		}
+	}
+
+	// As Jinja does by default (keep_trailing_newline), the newline ending the files and the YAML blocks is not rendered
+	dat = strings.TrimSuffix(dat, "\n")
+
+	loader, err := loaders.NewFileSystemLoader("")
+	if err != nil {
+		return err
+	}
+	// Same as gonja.FromString, with our configuration and environment
+	rootID := fmt.Sprintf("root-%x", sha256.Sum256([]byte(dat)))
+	shiftedLoader, err := loaders.NewShiftedLoader(rootID, strings.NewReader(dat), loader)
+	if err != nil {
+		return err
+	}
+	tmpl, err := exec.NewTemplate(rootID, gonja.DefaultConfig, shiftedLoader, jinjaEnvironment)
+	if err != nil {
+		return err
+	}
+	tc.jinjaTemplates[templateName] = tmpl
+
+	return nil
+}
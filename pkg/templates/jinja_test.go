@@ -0,0 +1,84 @@
+package templates_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mudler/LocalAI/pkg/templates"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const chatMLTemplate = `{% for message in messages %}{{'<|im_start|>' + message['role'] + '\n' + message['content'] + '<|im_end|>' + '\n'}}{% endfor %}{% if add_generation_prompt %}{{ '<|im_start|>assistant\n' }}{% endif %}`
+
+const mistralTemplate = `{{ bos_token }}{% for message in messages %}{% if (message['role'] == 'user') != (loop.index0 % 2 == 0) %}{{ raise_exception('Conversation roles must alternate user/assistant/user/assistant/...') }}{% endif %}{% if message['role'] == 'user' %}{{ '[INST] ' + message['content'].strip() + ' [/INST]' }}{% elif message['role'] == 'assistant' %}{{ message['content'] + eos_token}}{% endif %}{% endfor %}`
+
+var _ = Describe("Jinja templates", func() {
+	var (
+		templateCache *templates.TemplateCache
+		tempDir       string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "templates")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.WriteFile(filepath.Join(tempDir, "chatml.jinja"), []byte(chatMLTemplate+"\n"), 0600)
+		Expect(err).NotTo(HaveOccurred())
+
+		templateCache = templates.NewTemplateCache(tempDir)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir) // Clean up
+	})
+
+	messages := []interface{}{
+		map[string]interface{}{"role": "user", "content": " Hello "},
+		map[string]interface{}{"role": "assistant", "content": "Hi!"},
+		map[string]interface{}{"role": "user", "content": "How are you?"},
+	}
+
+	It("renders the template of a file", func() {
+		result, err := templateCache.EvaluateJinjaTemplate("chatml", map[string]interface{}{
+			"messages":              messages[:1],
+			"add_generation_prompt": true,
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("<|im_start|>user\n Hello <|im_end|>\n<|im_start|>assistant\n"))
+	})
+
+	It("renders the template of a string with the methods of the Python strings", func() {
+		result, err := templateCache.EvaluateJinjaTemplate(mistralTemplate, map[string]interface{}{
+			"messages":  messages,
+			"bos_token": "<s>",
+			"eos_token": "</s>",
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("<s>[INST] Hello [/INST]Hi!</s>[INST] How are you? [/INST]"))
+	})
+
+	It("renders the tools", func() {
+		result, err := templateCache.EvaluateJinjaTemplate("{%- if tools %}\n    {%- for tool in tools %}\n{{ tool.function.name }}: {{ tool.function | tojson }}\n    {%- endfor %}\n{%- endif %}", map[string]interface{}{
+			"tools": []interface{}{
+				map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "search"}},
+			},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("\nsearch: {\"name\":\"search\"}"))
+	})
+
+	It("returns the exceptions raised by the template", func() {
+		_, err := templateCache.EvaluateJinjaTemplate(mistralTemplate, map[string]interface{}{
+			"messages": messages[1:],
+		}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Conversation roles must alternate"))
+	})
+
+	It("reports syntax errors on load", func() {
+		Expect(templateCache.LoadJinjaTemplate("{% for message in messages %}")).To(HaveOccurred())
+		Expect(templateCache.LoadJinjaTemplate(chatMLTemplate)).To(Succeed())
+	})
+})
@@ -38,3 +38,13 @@ func SanitizeFileName(fileName string) string {
 	safeName := strings.ReplaceAll(baseName, "..", "")
 	return safeName
 }
+
+// BatchFileName returns the file of the image at index of a batch of images generated at dst: the
+// first image is written to dst, the next ones to dst suffixed by their index, as image_1.png
+func BatchFileName(dst string, index int) string {
+	if index == 0 {
+		return dst
+	}
+	ext := filepath.Ext(dst)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(dst, ext), index, ext)
+}
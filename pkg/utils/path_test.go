@@ -0,0 +1,15 @@
+package utils_test
+
+import (
+	. "github.com/mudler/LocalAI/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/path tests", func() {
+	It("BatchFileName suffixes the images after the first one with their index", func() {
+		Expect(BatchFileName("/tmp/images/b64123.png", 0)).To(Equal("/tmp/images/b64123.png"))
+		Expect(BatchFileName("/tmp/images/b64123.png", 2)).To(Equal("/tmp/images/b64123_2.png"))
+		Expect(BatchFileName("/tmp/images/b64123", 1)).To(Equal("/tmp/images/b64123_1"))
+	})
+})
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenStream buffers the bytes streamed by a backend before they are sent to the clients. The tokens may
+// end in the middle of a multi-byte UTF-8 character, as the CJK characters and the emojis, which is held
+// until its following bytes arrive. The text which may be the start of a stop sequence is held too, until
+// the sequence either completes, ending the stream, or diverges and is sent
+type TokenStream struct {
+	stops []string
+	// bytes of an incomplete UTF-8 character
+	partial []byte
+	// text matching the start of a stop sequence
+	held    string
+	stopped bool
+}
+
+// NewTokenStream returns the stream ending at the first of the stop sequences
+func NewTokenStream(stops []string) *TokenStream {
+	t := &TokenStream{}
+	for _, stop := range stops {
+		if stop != "" {
+			t.stops = append(t.stops, stop)
+		}
+	}
+	return t
+}
+
+// Write adds the bytes of a token, and returns the text which can be sent to the clients. It is empty
+// when all the text is held, or once a stop sequence was found
+func (t *TokenStream) Write(b []byte) string {
+	if t.stopped {
+		return ""
+	}
+	t.partial = append(t.partial, b...)
+
+	var sb strings.Builder
+	sb.WriteString(t.held)
+	for len(t.partial) > 0 {
+		r, size := utf8.DecodeRune(t.partial)
+		if r == utf8.RuneError && size == 1 {
+			if !utf8.FullRune(t.partial) {
+				// incomplete character, wait for its following bytes
+				break
+			}
+			// invalid byte, it would never complete
+			sb.WriteRune(utf8.RuneError)
+		} else {
+			sb.Write(t.partial[:size])
+		}
+		t.partial = t.partial[size:]
+	}
+	text := sb.String()
+	t.held = ""
+
+	if i := t.stopIndex(text); i >= 0 {
+		t.stopped = true
+		t.partial = nil
+		return text[:i]
+	}
+	held := t.stopPrefix(text)
+	t.held = text[len(text)-held:]
+	return text[:len(text)-held]
+}
+
+// Flush returns the text still held at the end of the stream, as it did not complete a stop sequence
+func (t *TokenStream) Flush() string {
+	if t.stopped {
+		return ""
+	}
+	text := t.held + strings.ToValidUTF8(string(t.partial), string(utf8.RuneError))
+	t.held = ""
+	t.partial = nil
+	return text
+}
+
+// Stopped tells if a stop sequence was found
+func (t *TokenStream) Stopped() bool {
+	return t.stopped
+}
+
+// stopIndex returns the position of the first stop sequence in the text, -1 if there is none
+func (t *TokenStream) stopIndex(text string) int {
+	first := -1
+	for _, stop := range t.stops {
+		if i := strings.Index(text, stop); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// stopPrefix returns the length of the longest end of the text which starts a stop sequence
+func (t *TokenStream) stopPrefix(text string) int {
+	longest := 0
+	for _, stop := range t.stops {
+		for n := min(len(stop)-1, len(text)); n > longest; n-- {
+			if strings.HasPrefix(stop, text[len(text)-n:]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
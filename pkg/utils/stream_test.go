@@ -0,0 +1,58 @@
+package utils_test
+
+import (
+	"strings"
+
+	. "github.com/mudler/LocalAI/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/stream tests", func() {
+	It("holds the multi-byte characters split across the tokens", func() {
+		t := NewTokenStream(nil)
+		emoji := []byte("😀")
+		Expect(t.Write([]byte("你"))).To(Equal("你"))
+		Expect(t.Write(emoji[:1])).To(BeEmpty())
+		Expect(t.Write(emoji[1:3])).To(BeEmpty())
+		Expect(t.Write(append(emoji[3:], []byte("好")...))).To(Equal("😀好"))
+		Expect(t.Flush()).To(BeEmpty())
+	})
+
+	It("replaces the invalid bytes", func() {
+		t := NewTokenStream(nil)
+		Expect(t.Write([]byte{'a', 0xff, 'b'})).To(Equal("a�b"))
+		Expect(t.Write([]byte{0xe4, 0xbd})).To(BeEmpty())
+		Expect(t.Flush()).To(Equal("�"))
+	})
+
+	It("stops at the stop sequences split across the tokens", func() {
+		t := NewTokenStream([]string{"<|im_end|>"})
+		Expect(t.Write([]byte("Hello <|im"))).To(Equal("Hello "))
+		Expect(t.Stopped()).To(BeFalse())
+		Expect(t.Write([]byte("_end|> ignored"))).To(BeEmpty())
+		Expect(t.Stopped()).To(BeTrue())
+		Expect(t.Write([]byte("more"))).To(BeEmpty())
+		Expect(t.Flush()).To(BeEmpty())
+	})
+
+	It("sends the held text when the stop sequence diverges", func() {
+		t := NewTokenStream([]string{"</s>"})
+		Expect(t.Write([]byte("a <"))).To(Equal("a "))
+		Expect(t.Write([]byte("b>"))).To(Equal("<b>"))
+		Expect(t.Write([]byte("</"))).To(BeEmpty())
+		Expect(t.Flush()).To(Equal("</"))
+	})
+
+	It("stops at the multi-byte stop sequences", func() {
+		t := NewTokenStream([]string{"<｜end▁of▁sentence｜>"})
+		stop := []byte("<｜end▁of▁sentence｜>")
+		var out strings.Builder
+		out.WriteString(t.Write([]byte("答案")))
+		for _, b := range stop {
+			out.WriteString(t.Write([]byte{b}))
+		}
+		Expect(out.String()).To(Equal("答案"))
+		Expect(t.Stopped()).To(BeTrue())
+	})
+})
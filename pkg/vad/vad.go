@@ -0,0 +1,120 @@
+package vad
+
+import (
+	"math"
+	"time"
+)
+
+// Segmenter splits a stream of mono PCM samples in utterances, separated by
+// silences. Voice activity is detected by comparing the energy of short frames
+// with a threshold, which works well enough for clean recordings such as
+// microphone input.
+type Segmenter struct {
+	SampleRate int
+	// Threshold is the RMS energy, between 0 and 1, above which a frame is considered speech
+	Threshold float64
+	// Silence is how long the speaker must be silent for an utterance to end
+	Silence time.Duration
+	// MaxLength forces an utterance to end when it gets longer than this
+	MaxLength time.Duration
+
+	frame       []float32
+	utterance   []float32
+	start       int // sample at which the current utterance starts
+	silent      int // samples of silence at the end of the current utterance
+	processed   int
+	onUtterance func(Utterance)
+}
+
+// Utterance is a chunk of speech
+type Utterance struct {
+	Samples []float32
+	Start   time.Duration
+}
+
+const frameLength = 30 * time.Millisecond
+
+func NewSegmenter(sampleRate int, onUtterance func(Utterance)) *Segmenter {
+	return &Segmenter{
+		SampleRate:  sampleRate,
+		Threshold:   0.01,
+		Silence:     600 * time.Millisecond,
+		MaxLength:   30 * time.Second,
+		onUtterance: onUtterance,
+	}
+}
+
+func (s *Segmenter) samples(d time.Duration) int {
+	return int(d.Seconds() * float64(s.SampleRate))
+}
+
+// Write feeds samples to the segmenter, calling back for each utterance that ends
+func (s *Segmenter) Write(samples []float32) {
+	frameSize := s.samples(frameLength)
+	for _, sample := range samples {
+		s.frame = append(s.frame, sample)
+		if len(s.frame) == frameSize {
+			s.processFrame()
+			s.frame = s.frame[:0]
+		}
+	}
+}
+
+// Flush ends the current utterance, if any, including the samples of an incomplete frame
+func (s *Segmenter) Flush() {
+	if len(s.utterance) > 0 {
+		s.utterance = append(s.utterance, s.frame...)
+	}
+	s.processed += len(s.frame)
+	s.frame = s.frame[:0]
+	s.end()
+}
+
+func (s *Segmenter) processFrame() {
+	speech := rms(s.frame) >= s.Threshold
+
+	switch {
+	case speech:
+		if len(s.utterance) == 0 {
+			s.start = s.processed
+		}
+		s.utterance = append(s.utterance, s.frame...)
+		s.silent = 0
+	case len(s.utterance) > 0:
+		s.utterance = append(s.utterance, s.frame...)
+		s.silent += len(s.frame)
+	}
+	s.processed += len(s.frame)
+
+	if len(s.utterance) > 0 && (s.silent >= s.samples(s.Silence) || len(s.utterance) >= s.samples(s.MaxLength)) {
+		s.end()
+	}
+}
+
+func (s *Segmenter) end() {
+	if len(s.utterance) > 0 && len(s.utterance) > s.silent {
+		s.onUtterance(Utterance{
+			Samples: s.utterance,
+			Start:   time.Duration(float64(s.start) / float64(s.SampleRate) * float64(time.Second)),
+		})
+	}
+	s.utterance = nil
+	s.silent = 0
+}
+
+func rms(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// PCM16ToFloat32 converts signed 16-bit little endian PCM to samples between -1 and 1
+func PCM16ToFloat32(pcm []byte) []float32 {
+	samples := make([]float32, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float32(int16(uint16(pcm[2*i])|uint16(pcm[2*i+1])<<8)) / 32768
+	}
+	return samples
+}
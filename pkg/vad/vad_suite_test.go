@@ -0,0 +1,13 @@
+package vad_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestVAD(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VAD test suite")
+}
@@ -0,0 +1,74 @@
+package vad_test
+
+import (
+	"math"
+	"time"
+
+	. "github.com/mudler/LocalAI/pkg/vad"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const sampleRate = 16000
+
+func tone(d time.Duration) []float32 {
+	samples := make([]float32, int(d.Seconds()*sampleRate))
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+	}
+	return samples
+}
+
+func silence(d time.Duration) []float32 {
+	return make([]float32, int(d.Seconds()*sampleRate))
+}
+
+var _ = Describe("Segmenter", func() {
+	var utterances []Utterance
+	var s *Segmenter
+
+	BeforeEach(func() {
+		utterances = nil
+		s = NewSegmenter(sampleRate, func(u Utterance) {
+			utterances = append(utterances, u)
+		})
+	})
+
+	It("splits speech on silences", func() {
+		s.Write(silence(300 * time.Millisecond))
+		s.Write(tone(time.Second))
+		s.Write(silence(time.Second))
+		s.Write(tone(500 * time.Millisecond))
+		Expect(utterances).To(HaveLen(1))
+
+		s.Flush()
+		Expect(utterances).To(HaveLen(2))
+		Expect(utterances[0].Start).To(BeNumerically("~", 300*time.Millisecond, 30*time.Millisecond))
+		Expect(utterances[1].Start).To(BeNumerically("~", 2300*time.Millisecond, 30*time.Millisecond))
+	})
+
+	It("does not split on short pauses", func() {
+		s.Write(tone(time.Second))
+		s.Write(silence(200 * time.Millisecond))
+		s.Write(tone(time.Second))
+		s.Flush()
+		Expect(utterances).To(HaveLen(1))
+	})
+
+	It("splits utterances longer than the maximum length", func() {
+		s.MaxLength = time.Second
+		s.Write(tone(2500 * time.Millisecond))
+		s.Flush()
+		Expect(utterances).To(HaveLen(3))
+	})
+
+	It("ignores silence", func() {
+		s.Write(silence(2 * time.Second))
+		s.Flush()
+		Expect(utterances).To(BeEmpty())
+	})
+
+	It("converts PCM to samples", func() {
+		Expect(PCM16ToFloat32([]byte{0x00, 0x40, 0x00, 0xc0})).To(Equal([]float32{0.5, -0.5}))
+	})
+})
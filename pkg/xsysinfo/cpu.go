@@ -1,7 +1,10 @@
 package xsysinfo
 
 import (
+	"fmt"
+	"os"
 	"sort"
+	"strings"
 
 	"github.com/jaypipes/ghw"
 	"github.com/klauspost/cpuid/v2"
@@ -43,3 +46,12 @@ func CPUPhysicalCores() int {
 	}
 	return cpuid.CPU.PhysicalCores
 }
+
+// NUMANodeCPUs returns the CPUs of the NUMA node, as "0-15,32-47". It relies on the sysfs of Linux
+func NUMANodeCPUs(node int) (string, error) {
+	cpus, err := os.ReadFile(fmt.Sprintf("/sys/devices/system/node/node%d/cpulist", node))
+	if err != nil {
+		return "", fmt.Errorf("NUMA node %d not found: %w", node, err)
+	}
+	return strings.TrimSpace(string(cpus)), nil
+}
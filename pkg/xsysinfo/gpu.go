@@ -1,6 +1,11 @@
 package xsysinfo
 
 import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
 	"github.com/jaypipes/ghw"
 	"github.com/jaypipes/ghw/pkg/gpu"
 )
@@ -13,3 +18,54 @@ func GPUs() ([]*gpu.GraphicsCard, error) {
 
 	return gpu.GraphicsCards, nil
 }
+
+// GPUMemoryUsed returns the memory in use on all the NVIDIA GPUs, in bytes.
+// It relies on nvidia-smi, and returns an error if it is not available
+func GPUMemoryUsed() (uint64, error) {
+	out, err := exec.Command("nvidia-smi", "--query-gpu=memory.used", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, err
+	}
+
+	var used uint64
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		mib, err := strconv.ParseUint(strings.TrimSpace(line), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected nvidia-smi output %q: %w", line, err)
+		}
+		used += mib * 1024 * 1024
+	}
+	return used, nil
+}
+
+// GPUMemory is the memory of a NVIDIA GPU, in bytes, by the index of the GPU in CUDA_VISIBLE_DEVICES
+type GPUMemory struct {
+	Index int
+	Total uint64
+	Free  uint64
+}
+
+// GPUsMemory returns the memory of each NVIDIA GPU.
+// It relies on nvidia-smi, and returns an error if it is not available
+func GPUsMemory() ([]GPUMemory, error) {
+	out, err := exec.Command("nvidia-smi", "--query-gpu=index,memory.total,memory.free", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	gpus := []GPUMemory{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected nvidia-smi output %q", line)
+		}
+		values := make([]uint64, len(fields))
+		for i, field := range fields {
+			if values[i], err = strconv.ParseUint(strings.TrimSpace(field), 10, 64); err != nil {
+				return nil, fmt.Errorf("unexpected nvidia-smi output %q: %w", line, err)
+			}
+		}
+		gpus = append(gpus, GPUMemory{Index: int(values[0]), Total: values[1] * 1024 * 1024, Free: values[2] * 1024 * 1024})
+	}
+	return gpus, nil
+}